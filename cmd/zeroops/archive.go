@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/archive"
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/pg"
+)
+
+const archiveUsage = `usage: zeroops archive <run|restore> [-f config.json] [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-id ID,...]
+
+  run       maintain the alert_issues partitions and archive the closed issues past
+            their retention once, as the server does every archive interval
+  restore   move the archived issues whose alert started from -from to -to (inclusive,
+            default today) back to alert_issues, only those of -id when given
+`
+
+// runArchive implements the `zeroops archive` subcommand and returns the exit code.
+func runArchive(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, archiveUsage)
+		return 2
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("archive "+action, flag.ContinueOnError)
+	configFile := fs.String("f", "", "Path to configuration file")
+	from := fs.String("from", "", "First day of the alerts to restore (restore only)")
+	to := fs.String("to", time.Now().UTC().Format(time.DateOnly), "Last day of the alerts to restore (restore only)")
+	ids := fs.String("id", "", "Comma-separated issue ids to restore (restore only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	var q archive.RestoreQuery
+	if action == "restore" {
+		start, err := time.Parse(time.DateOnly, *from)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "-from must be a date YYYY-MM-DD")
+			return 2
+		}
+		end, err := time.Parse(time.DateOnly, *to)
+		if err != nil || end.Before(start) {
+			fmt.Fprintln(os.Stderr, "-to must be a date YYYY-MM-DD not before -from")
+			return 2
+		}
+		q = archive.RestoreQuery{From: start, To: end.AddDate(0, 0, 1)}
+		if *ids != "" {
+			q.IDs = strings.Split(*ids, ",")
+		}
+	}
+
+	cfg, err := config.LoadFile(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return 1
+	}
+	pool, err := pg.Open(&cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "connect database: %v\n", err)
+		return 1
+	}
+	defer pool.Close()
+
+	svc, err := archive.NewService(adb.New(pool), &cfg.Archive)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "archive config: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "run":
+		created, dropped, err := svc.MaintainPartitions(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "maintain partitions: %v\n", err)
+			return 1
+		}
+		fmt.Printf("created %d partitions\n", created)
+		for _, name := range dropped {
+			fmt.Printf("dropped empty partition %s\n", name)
+		}
+		n, err := svc.Archive(ctx)
+		fmt.Printf("archived %d issues to %s\n", n, cfg.Archive.Target)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "archive: %v\n", err)
+			return 1
+		}
+	case "restore":
+		n, err := svc.Restore(ctx, q)
+		fmt.Printf("restored %d issues\n", n)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "restore: %v\n", err)
+			return 1
+		}
+	default:
+		fmt.Fprint(os.Stderr, archiveUsage)
+		return 2
+	}
+	return 0
+}
@@ -2,90 +2,214 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/fox-gonic/fox"
 	alertapi "github.com/qiniu/zeroops/internal/alerting/api"
 	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/analysis"
+	"github.com/qiniu/zeroops/internal/alerting/service/analytics"
+	"github.com/qiniu/zeroops/internal/alerting/service/anomaly"
+	"github.com/qiniu/zeroops/internal/alerting/service/archive"
+	"github.com/qiniu/zeroops/internal/alerting/service/enrich"
 	"github.com/qiniu/zeroops/internal/alerting/service/healthcheck"
+	"github.com/qiniu/zeroops/internal/alerting/service/receiver"
 	"github.com/qiniu/zeroops/internal/alerting/service/remediation"
+	"github.com/qiniu/zeroops/internal/alerting/service/report"
+	"github.com/qiniu/zeroops/internal/alerting/service/slo"
+	"github.com/qiniu/zeroops/internal/apierror"
 	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/lifecycle"
 	"github.com/qiniu/zeroops/internal/middleware"
+	"github.com/qiniu/zeroops/internal/migrate"
+	"github.com/qiniu/zeroops/internal/observability"
+	"github.com/qiniu/zeroops/internal/openapi"
+	"github.com/qiniu/zeroops/internal/pg"
 	servicemanager "github.com/qiniu/zeroops/internal/service_manager"
+	smservice "github.com/qiniu/zeroops/internal/service_manager/service"
 
 	// releasesystem "github.com/qiniu/zeroops/internal/release_system/api"
 	"github.com/rs/zerolog/log"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "archive" {
+		os.Exit(runArchive(os.Args[2:]))
+	}
+
 	log.Info().Msg("Starting zeroops api server")
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to load config")
 	}
 
-	serviceManagerSrv, err := servicemanager.NewServiceManagerServer(cfg)
+	// components start in registration order and stop in reverse: HTTP drains first,
+	// then the background loops, and the clients are closed last
+	lc := lifecycle.New(time.Duration(cfg.Server.ShutdownTimeoutSeconds) * time.Second)
+
+	telemetry, err := observability.NewProviders(&cfg.Telemetry)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to init telemetry")
+	}
+	lc.Append(lifecycle.Hook("telemetry", nil, telemetry.Shutdown))
+
+	// one connection pool shared by service_manager and alerting
+	pool, err := pg.Open(&cfg.Database)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to connect database")
+	}
+	lc.Append(lifecycle.Closer("postgres", pool))
+	lc.AddCheck("postgres", pool.Ping)
+
+	applied, err := migrate.EnsureCompatible(context.Background(), pool.SQL(), cfg.Database.AutoMigrate)
+	for _, mig := range applied {
+		log.Info().Int("version", mig.Version).Str("name", mig.Name).Msg("applied schema migration")
+	}
+	if err != nil {
+		pool.Close()
+		log.Fatal().Err(err).Msg("refusing to start")
+	}
+
+	// one Redis client shared by the receiver cache, issue API, scheduler and consumer
+	rdb := healthcheck.NewRedisClientFromEnv()
+	lc.Append(lifecycle.Closer("redis", rdb))
+	lc.AddCheck("redis", func(ctx context.Context) error { return rdb.Ping(ctx).Err() })
+
+	serviceManagerSrv, err := servicemanager.NewServiceManagerServer(cfg, pool)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to create release system api")
 	}
-	defer func() {
-		serviceManagerSrv.Close()
-	}()
-
-	// optional alerting DB for healthcheck and remediation
-	var alertDB *adb.Database
-	{
-		dsn := func() string {
-			return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-				cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.DBName, cfg.Database.SSLMode)
-		}()
-		if db, derr := adb.New(dsn); derr == nil {
-			alertDB = db
-		} else {
-			log.Error().Err(derr).Msg("healthcheck alerting DB init failed; scheduler/consumer will run without DB")
-		}
-	}
-
-	// start healthcheck scheduler and remediation consumer
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	lc.Append(lifecycle.Closer("service-manager", serviceManagerSrv))
+
+	alertDB := adb.New(pool)
+
+	// remediation consumer starts before the scheduler that feeds it
 	interval := parseDuration(os.Getenv("HC_SCAN_INTERVAL"), 10*time.Second)
 	batch := parseInt(os.Getenv("HC_SCAN_BATCH"), 200)
 	workers := parseInt(os.Getenv("HC_WORKERS"), 1)
-	if workers < 1 {
-		workers = 1
-	}
 	alertChSize := parseInt(os.Getenv("REMEDIATION_ALERT_CHAN_SIZE"), 1024)
 	alertCh := make(chan healthcheck.AlertMessage, alertChSize)
 
-	for i := 0; i < workers; i++ {
-		go healthcheck.StartScheduler(ctx, healthcheck.Deps{
+	rem := remediation.NewConsumer(alertDB, rdb)
+	rem.Analyzer = analysis.New(&cfg.Analysis, serviceManagerSrv.AnalysisDeployments())
+	rem.AnalysisTimeout = time.Duration(cfg.Analysis.TimeoutSeconds) * time.Second
+	lc.Append(lifecycle.Loop("remediation-consumer", 1, func(ctx context.Context) {
+		rem.Start(ctx, alertCh)
+	}))
+	lc.Append(lifecycle.Loop("healthcheck-scheduler", workers, func(ctx context.Context) {
+		healthcheck.StartScheduler(ctx, healthcheck.Deps{
 			DB:       alertDB,
-			Redis:    healthcheck.NewRedisClientFromEnv(),
+			Redis:    rdb,
 			AlertCh:  alertCh,
 			Batch:    batch,
 			Interval: interval,
 		})
+	}))
+
+	// one receiver serves the webhook and the instance liveness reaper, which raises
+	// InstanceLost issues; both go through the enrichment stage, and issues of SLO
+	// burn-rate alerts get the error budget from Prometheus
+	enricher, err := enrich.New(&cfg.Enrichment, serviceManagerSrv.Catalog())
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid alert enrichment config")
+	}
+	slos := slo.NewService(alertDB, serviceManagerSrv.SLOQuerier())
+	if cfg.Webhook.MaxBodyBytes <= 0 || cfg.Webhook.ReplayWindowSeconds <= 0 {
+		log.Fatal().Msg("ALERT_WEBHOOK_MAX_BODY_BYTES and ALERT_WEBHOOK_REPLAY_WINDOW_SECONDS must be positive")
+	}
+	admins := alertapi.Admins(cfg.Webhook.AdminTokens)
+	if err := admins.Check(); err != nil {
+		log.Fatal().Err(err).Msg("invalid ALERT_WEBHOOK_ADMIN_TOKENS")
+	}
+	if len(admins) == 0 {
+		log.Warn().Msg("no ALERT_WEBHOOK_ADMIN_TOKENS; webhook credentials cannot be managed over the API")
+	}
+	alerter := receiver.NewHandlerWithCache(receiver.NewPgDAO(alertDB), &receiver.Cache{R: rdb}).
+		WithEnricher(enricher).
+		WithBudgets(slos).
+		WithAuth(receiver.NewAuthenticator(alertDB, rdb, &cfg.Webhook))
+	lc.Append(serviceManagerSrv.Components(instanceAlerter{alerter})...)
+
+	// the anomaly detector raises AnomalyDetected issues through the same receiver
+	anomalies, err := anomaly.NewService(alertDB, serviceManagerSrv.AnomalySource(), &cfg.Anomaly)
+	if err != nil {
+		log.Fatal().Err(err).Msg("invalid anomaly detection config")
+	}
+	lc.Append(lifecycle.Loop("anomaly-detector", 1, func(ctx context.Context) {
+		anomalies.Run(ctx, time.Duration(cfg.Anomaly.IntervalSeconds)*time.Second, alerter)
+	}))
+
+	// rollups are read by the analytics routes built in NewApiWithReceiver
+	stats := analytics.NewService(alertDB)
+	lc.Append(lifecycle.Loop("analytics-rollup", 1, func(ctx context.Context) {
+		stats.Run(ctx, time.Duration(cfg.Analytics.IntervalSeconds)*time.Second, cfg.Analytics.RefreshDays)
+	}))
+
+	// archived issues are gone from the rollup refresh, so they must be older than it
+	archiver, err := archive.NewService(alertDB, &cfg.Archive)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to init issue archive")
+	}
+	if days := archiver.Policy.Min(); days > 0 && days <= cfg.Analytics.RefreshDays {
+		log.Fatal().Int("retentionDays", days).Int("refreshDays", cfg.Analytics.RefreshDays).
+			Msg("issue retention must be longer than the analytics refresh")
 	}
-	rem := remediation.NewConsumer(alertDB, healthcheck.NewRedisClientFromEnv())
-	go rem.Start(ctx, alertCh)
+	lc.Append(lifecycle.Loop("issue-archive", 1, func(ctx context.Context) {
+		archiver.Run(ctx, time.Duration(cfg.Archive.IntervalSeconds)*time.Second)
+	}))
+
+	reports := report.NewService(alertDB, serviceManagerSrv.Catalog(), serviceManagerSrv.AnalysisDeployments(),
+		serviceManagerSrv.AnomalySource(), cfg.Analysis.MetricQueries)
 
 	router := fox.New()
+	router.Use(apierror.RequestID)
+	router.Use(observability.HTTPMiddleware)
 	router.Use(middleware.Authentication)
-	alertapi.NewApiWithConfig(router, cfg)
+	lc.RegisterHealthRoutes(router)
+	observability.RegisterMetricsRoute(router)
+	openapi.RegisterRoute(router)
+	alertapi.NewApiWithReceiver(router, alerter, slos, anomalies, reports, alertDB, rdb, admins)
 	if err := serviceManagerSrv.UseApi(router); err != nil {
 		log.Fatal().Err(err).Msg("bind serviceManagerApi failed.")
 	}
-	log.Info().Msgf("Starting server on %s", cfg.Server.BindAddr)
-	if err := router.Run(cfg.Server.BindAddr); err != nil {
-		log.Fatal().Err(err).Msg("start zeroops api server failed.")
+	lc.Append(lifecycle.HTTPServer(&http.Server{
+		Addr:              cfg.Server.BindAddr,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}))
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := lc.Run(ctx); err != nil {
+		log.Fatal().Err(err).Msg("zeroops api server stopped with error")
 	}
 	log.Info().Msg("zeroops api server exit...")
 }
 
+// instanceAlerter raises the alerts of the service manager, such as lost instances,
+// through the alert receiver.
+type instanceAlerter struct {
+	receiver *receiver.Handler
+}
+
+func (a instanceAlerter) IngestAlert(ctx context.Context, alert smservice.Alert) (bool, error) {
+	return a.receiver.IngestAlert(ctx, receiver.AMAlert{
+		Status:      "firing",
+		Labels:      alert.Labels,
+		Annotations: alert.Annotations,
+		StartsAt:    alert.StartsAt,
+		Fingerprint: alert.Fingerprint,
+	})
+}
+
 func parseDuration(s string, d time.Duration) time.Duration {
 	if s == "" {
 		return d
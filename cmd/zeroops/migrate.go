@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/migrate"
+	"github.com/qiniu/zeroops/internal/pg"
+)
+
+const migrateUsage = `usage: zeroops migrate <up|down|status> [-f config.json] [-steps N]
+
+  up       apply all pending migrations
+  down     roll back the last N applied migrations (default 1)
+  status   list migrations and whether they are applied
+`
+
+// runMigrate implements the `zeroops migrate` subcommand and returns the exit code.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	configFile := fs.String("f", "", "Path to configuration file")
+	steps := fs.Int("steps", 1, "Number of migrations to roll back (down only)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, err := config.LoadFile(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load config: %v\n", err)
+		return 1
+	}
+	pool, err := pg.Open(&cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "connect database: %v\n", err)
+		return 1
+	}
+	defer pool.Close()
+
+	m, err := migrate.New(pool.SQL())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load migrations: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	switch action {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			fmt.Printf("applied %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate up: %v\n", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		if *steps < 1 {
+			fmt.Fprintln(os.Stderr, "-steps must be at least 1")
+			return 2
+		}
+		reverted, err := m.Down(ctx, *steps)
+		for _, mig := range reverted {
+			fmt.Printf("reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate down: %v\n", err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("no applied migrations")
+		}
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate status: %v\n", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", st.Version, st.Name, applied)
+		}
+		w.Flush()
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+const analyticsUsage = `usage: zeroopsctl analytics <subcommand>
+
+  issues [-group-by day,service,alertname,level]
+                      issue counts, time to acknowledge (TTA) and to restore (TTR) and
+                      the auto-remediation success rate
+  flapping [-min-issues N] [-limit N]
+                      alerts that opened at least N issues (default 3)
+  noisy [-limit N]    alert rules that opened the most issues
+  rollup              recomputes the stored daily rollups, e.g. to backfill old days
+
+Every subcommand takes -from and -to (inclusive days like 2006-01-02, UTC; default the
+last 7 days); all but rollup also take -service, -alertname and -level.
+`
+
+var analyticsCommand = command{
+	usage: analyticsUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"issues":   analyticsIssues,
+		"flapping": analyticsFlapping,
+		"noisy":    analyticsNoisy,
+		"rollup":   analyticsRollup,
+	},
+}
+
+// analyticsFlags registers the range flags and, with filters, the filter flags.
+func analyticsFlags(fs *flag.FlagSet, filters bool) *client.AnalyticsQuery {
+	q := &client.AnalyticsQuery{}
+	fs.StringVar(&q.From, "from", "", "first day, YYYY-MM-DD (UTC)")
+	fs.StringVar(&q.To, "to", "", "last day, YYYY-MM-DD (UTC)")
+	if filters {
+		fs.StringVar(&q.Service, "service", "", "only issues of this service")
+		fs.StringVar(&q.AlertName, "alertname", "", "only issues of this alert rule")
+		fs.StringVar(&q.Level, "level", "", "only issues of this level")
+	}
+	return q
+}
+
+func analyticsIssues(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("analytics issues", flag.ContinueOnError)
+	q := analyticsFlags(fs, true)
+	groupBy := fs.String("group-by", "", "comma separated dimensions (default service,alertname,level)")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	var dims []string
+	if *groupBy != "" {
+		dims = strings.Split(*groupBy, ",")
+	}
+	stats, err := c.IssueAnalytics(ctx, *q, dims...)
+	if err != nil {
+		return err
+	}
+	return a.out.print(stats, func(t *table) {
+		t.header("DAY", "SERVICE", "ALERTNAME", "LEVEL", "ISSUES", "TTA MEAN", "TTA P90", "TTR MEAN", "TTR P90", "REMEDIATED")
+		for _, g := range stats.Groups {
+			statsRow(t, orDash(g.Day), orDash(g.Service), orDash(g.AlertName), orDash(g.Level), g.Stats)
+		}
+		statsRow(t, stats.From+".."+stats.To, "(total)", "", "", stats.Total)
+	})
+}
+
+func statsRow(t *table, day, service, alertName, level string, s client.AnalyticsStats) {
+	remediated := "-"
+	if r := s.Remediation; r.SuccessRate != nil {
+		remediated = fmt.Sprintf("%d/%d", r.Succeeded, r.Attempted)
+	}
+	t.row(day, service, alertName, level, fmt.Sprint(s.Issues),
+		formatFloatSeconds(s.TimeToAcknowledge.Mean), formatFloatSeconds(s.TimeToAcknowledge.P90),
+		formatFloatSeconds(s.TimeToRestore.Mean), formatFloatSeconds(s.TimeToRestore.P90), remediated)
+}
+
+func analyticsFlapping(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("analytics flapping", flag.ContinueOnError)
+	q := analyticsFlags(fs, true)
+	minIssues := fs.Int("min-issues", 0, "issues from which an alert counts as flapping (default 3)")
+	limit := fs.Int("limit", 0, "number of alerts, 1-100 (default 10)")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	items, err := c.FlappingAlerts(ctx, *q, *minIssues, *limit)
+	if err != nil {
+		return err
+	}
+	return a.out.print(items, func(t *table) {
+		t.header("FINGERPRINT", "SERVICE", "ALERTNAME", "LEVEL", "ISSUES", "DAYS")
+		for _, f := range items {
+			t.row(f.Fingerprint, orDash(f.Service), orDash(f.AlertName), f.Level, fmt.Sprint(f.Issues), fmt.Sprint(f.Days))
+		}
+	})
+}
+
+func analyticsNoisy(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("analytics noisy", flag.ContinueOnError)
+	q := analyticsFlags(fs, true)
+	limit := fs.Int("limit", 0, "number of rules, 1-100 (default 10)")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	items, err := c.NoisyRules(ctx, *q, *limit)
+	if err != nil {
+		return err
+	}
+	return a.out.print(items, func(t *table) {
+		t.header("ALERTNAME", "ISSUES", "SERVICES", "UNACKED", "AUTO RESTORED", "FLAPPING", "TTR MEAN")
+		for _, r := range items {
+			t.row(orDash(r.AlertName), fmt.Sprint(r.Issues), fmt.Sprint(r.Services),
+				fmt.Sprintf("%d (%.0f%%)", r.Unacknowledged, r.UnacknowledgedRatio*100), fmt.Sprint(r.AutoRestored),
+				fmt.Sprint(r.FlappingAlerts), formatFloatSeconds(r.MeanTimeToRestore))
+		}
+	})
+}
+
+func analyticsRollup(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("analytics rollup", flag.ContinueOnError)
+	q := analyticsFlags(fs, false)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	days, err := c.RollUpAnalytics(ctx, q.From, q.To)
+	if err != nil {
+		return err
+	}
+	if a.out.format != "table" {
+		return a.out.print(map[string]int{"days": days}, nil)
+	}
+	return a.out.line(fmt.Sprintf("rolled up %d days", days))
+}
+
+func formatFloatSeconds(s *float64) string {
+	if s == nil {
+		return "-"
+	}
+	return fmt.Sprint(time.Duration(*s * float64(time.Second)).Round(time.Second))
+}
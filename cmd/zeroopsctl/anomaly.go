@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+const anomalyUsage = `usage: zeroopsctl anomaly <subcommand>
+
+  list [-service NAME]
+  set SERVICE SERIES -detector ewma|mad|seasonal [parameter flags]
+  delete SERVICE SERIES
+  backtest SERVICE SERIES [-since DURATION | -start RFC3339 [-end RFC3339]] [parameter flags]
+         replays the stored detector, or the one given by -detector and the parameter flags;
+         the table lists the anomalies found, -o json adds every scored point
+
+Parameter flags:
+  -threshold SCORE -persist POINTS -direction up|down|both -window DURATION -alpha RATIO
+  -period DURATION -seasons N -min-scale VALUE -severity P0|P1|P2|Warning
+`
+
+var anomalyCommand = command{
+	usage: anomalyUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"list":     anomalyList,
+		"set":      anomalySet,
+		"delete":   anomalyDelete,
+		"backtest": anomalyBacktest,
+	},
+}
+
+// anomalyParamFlags defines the detector parameter flags on fs. The returned function
+// gives the parameters once fs is parsed, nil when -detector is not set.
+func anomalyParamFlags(fs *flag.FlagSet) func() *client.AnomalyParams {
+	var p client.AnomalyParams
+	fs.StringVar(&p.Detector, "detector", "", "ewma, mad or seasonal")
+	fs.Float64Var(&p.Threshold, "threshold", 0, "score above which a point is anomalous (default 3)")
+	fs.IntVar(&p.Persist, "persist", 0, "consecutive anomalous points that make an anomaly (default 3)")
+	fs.StringVar(&p.Direction, "direction", "", "up, down or both (default)")
+	fs.StringVar(&p.Window, "window", "", "history of ewma and mad, e.g. 1h (default)")
+	fs.Float64Var(&p.Alpha, "alpha", 0, "ewma smoothing factor (default 0.3)")
+	fs.StringVar(&p.Period, "period", "", "seasonal period, e.g. 1d (default) or 1w")
+	fs.IntVar(&p.Seasons, "seasons", 0, "seasonal periods compared (default 7)")
+	fs.Float64Var(&p.MinScale, "min-scale", 0, "smallest standard deviation assumed")
+	fs.StringVar(&p.Severity, "severity", "", "level of the issues raised (default P2)")
+	return func() *client.AnomalyParams {
+		if p.Detector == "" {
+			return nil
+		}
+		return &p
+	}
+}
+
+func anomalyList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("anomaly list", flag.ContinueOnError)
+	service := fs.String("service", "", "only detectors of this service")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	items, err := c.ListAnomalyDetectors(ctx, *service)
+	if err != nil {
+		return err
+	}
+	return a.out.print(items, func(t *table) {
+		t.header("SERVICE", "SERIES", "DETECTOR", "THRESHOLD", "PERSIST", "DIRECTION", "SEVERITY")
+		for _, d := range items {
+			detector := d.Params.Detector
+			if d.Query == "" {
+				detector += " (series not configured)"
+			}
+			t.row(d.Service, d.Series, detector, formatFloat(d.Params.Threshold), strconv.Itoa(d.Params.Persist),
+				d.Params.Direction, d.Params.Severity)
+		}
+	})
+}
+
+func anomalySet(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("anomaly set", flag.ContinueOnError)
+	params := anomalyParamFlags(fs)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 2); err != nil {
+		return err
+	}
+	p := params()
+	if p == nil {
+		return errors.New("-detector is required")
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	d, err := c.PutAnomalyDetector(ctx, args[0], args[1], p)
+	if err != nil {
+		return err
+	}
+	if a.out.format != "table" {
+		return a.out.print(d, nil)
+	}
+	return a.out.line(fmt.Sprintf("%s detector of %s/%s saved", d.Params.Detector, d.Service, d.Series))
+}
+
+func anomalyDelete(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 2); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	if err := c.DeleteAnomalyDetector(ctx, args[0], args[1]); err != nil {
+		return err
+	}
+	return a.out.line("anomaly detector " + args[0] + "/" + args[1] + " deleted")
+}
+
+func anomalyBacktest(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("anomaly backtest", flag.ContinueOnError)
+	since := fs.Duration("since", 24*time.Hour, "replay this long up to now")
+	start := fs.String("start", "", "start time in RFC3339, instead of -since")
+	end := fs.String("end", "", "end time in RFC3339 (default now)")
+	params := anomalyParamFlags(fs)
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 2); err != nil {
+		return err
+	}
+
+	req := client.AnomalyBacktest{Service: args[0], Series: args[1], End: time.Now(), Params: params()}
+	if *end != "" {
+		if req.End, err = time.Parse(time.RFC3339, *end); err != nil {
+			return fmt.Errorf("-end: %w", err)
+		}
+	}
+	req.Start = req.End.Add(-*since)
+	if *start != "" {
+		if req.Start, err = time.Parse(time.RFC3339, *start); err != nil {
+			return fmt.Errorf("-start: %w", err)
+		}
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	res, err := c.BacktestAnomalyDetector(ctx, &req)
+	if err != nil {
+		return err
+	}
+	if a.out.format != "table" {
+		return a.out.print(res, nil)
+	}
+	return a.out.print(res.Events, func(t *table) {
+		t.header("START", "END", "POINTS", "PEAK SCORE", "VALUE", "EXPECTED")
+		for _, ev := range res.Events {
+			t.row(formatTime(ev.Start), formatTime(ev.End), strconv.Itoa(ev.Points),
+				strconv.FormatFloat(ev.Peak, 'f', 1, 64), formatFloat(ev.PeakValue), formatFloat(ev.Expected))
+		}
+	})
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
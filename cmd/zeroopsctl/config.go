@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const configUsage = `usage: zeroopsctl config <subcommand>
+
+  get-contexts                 list contexts; * marks the current one
+  current-context              print the current context name
+  use-context NAME             make NAME the current context
+  set-context NAME [-server URL] [-token TOKEN] [-operator NAME] [-region NAME]
+                               create or update a context
+  delete-context NAME          remove a context
+`
+
+// Config is the zeroopsctl config file: named endpoints such as dev, staging and prod.
+type Config struct {
+	CurrentContext string    `yaml:"current-context"`
+	Contexts       []Context `yaml:"contexts"`
+}
+
+type Context struct {
+	Name     string `yaml:"name"`
+	Server   string `yaml:"server"`
+	Token    string `yaml:"token,omitempty"`
+	Operator string `yaml:"operator,omitempty"` // sent as X-Operator; defaults to $USER
+	Region   string `yaml:"region,omitempty"`   // default -region
+}
+
+func defaultConfigPath() string {
+	if p := os.Getenv("ZEROOPSCTL_CONFIG"); p != "" {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".zeroops.yaml"
+	}
+	return filepath.Join(home, ".zeroops", "config.yaml")
+}
+
+// loadConfig reads the config file; a missing file is an empty config.
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// save writes the config with owner-only permissions since it holds tokens.
+func (cfg *Config) save(path string) error {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func (cfg *Config) find(name string) *Context {
+	for i := range cfg.Contexts {
+		if cfg.Contexts[i].Name == name {
+			return &cfg.Contexts[i]
+		}
+	}
+	return nil
+}
+
+// resolve returns the named context, or the current one when name is empty.
+func (cfg *Config) resolve(name string) (Context, error) {
+	if name == "" {
+		name = cfg.CurrentContext
+	}
+	if name == "" {
+		return Context{}, errors.New("no context selected: run `zeroopsctl config set-context` or pass -server")
+	}
+	c := cfg.find(name)
+	if c == nil {
+		return Context{}, fmt.Errorf("context %q not found", name)
+	}
+	return *c, nil
+}
+
+var configCommand = command{
+	usage: configUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"get-contexts":    configGetContexts,
+		"current-context": configCurrentContext,
+		"use-context":     configUseContext,
+		"set-context":     configSetContext,
+		"delete-context":  configDeleteContext,
+	},
+}
+
+func configGetContexts(_ context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(a.configPath)
+	if err != nil {
+		return err
+	}
+	type row struct {
+		Name     string `json:"name"`
+		Server   string `json:"server"`
+		Operator string `json:"operator,omitempty"`
+		Region   string `json:"region,omitempty"`
+		Current  bool   `json:"current"`
+	}
+	rows := make([]row, 0, len(cfg.Contexts))
+	for _, c := range cfg.Contexts {
+		rows = append(rows, row{Name: c.Name, Server: c.Server, Operator: c.Operator, Region: c.Region, Current: c.Name == cfg.CurrentContext})
+	}
+	return a.out.print(rows, func(t *table) {
+		t.header("CURRENT", "NAME", "SERVER", "OPERATOR", "REGION")
+		for _, r := range rows {
+			current := ""
+			if r.Current {
+				current = "*"
+			}
+			t.row(current, r.Name, r.Server, r.Operator, r.Region)
+		}
+	})
+}
+
+func configCurrentContext(_ context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(a.configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.CurrentContext == "" {
+		return errors.New("current-context is not set")
+	}
+	return a.out.line(cfg.CurrentContext)
+}
+
+func configUseContext(_ context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(a.configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.find(args[0]) == nil {
+		return fmt.Errorf("context %q not found", args[0])
+	}
+	cfg.CurrentContext = args[0]
+	if err := cfg.save(a.configPath); err != nil {
+		return err
+	}
+	return a.out.line("switched to context " + args[0])
+}
+
+func configSetContext(_ context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("set-context", flag.ContinueOnError)
+	server := fs.String("server", "", "server URL")
+	token := fs.String("token", "", "bearer token")
+	operator := fs.String("operator", "", "operator name sent as X-Operator")
+	regionName := fs.String("region", "", "default region; empty means all")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(a.configPath)
+	if err != nil {
+		return err
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	c := cfg.find(args[0])
+	if c == nil {
+		if *server == "" {
+			return errors.New("-server is required for a new context")
+		}
+		cfg.Contexts = append(cfg.Contexts, Context{Name: args[0]})
+		c = &cfg.Contexts[len(cfg.Contexts)-1]
+	}
+	if set["server"] {
+		c.Server = *server
+	}
+	if set["token"] {
+		c.Token = *token
+	}
+	if set["operator"] {
+		c.Operator = *operator
+	}
+	if set["region"] {
+		c.Region = *regionName
+	}
+	if cfg.CurrentContext == "" {
+		cfg.CurrentContext = c.Name
+	}
+	if err := cfg.save(a.configPath); err != nil {
+		return err
+	}
+	return a.out.line("context " + c.Name + " saved")
+}
+
+func configDeleteContext(_ context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	cfg, err := loadConfig(a.configPath)
+	if err != nil {
+		return err
+	}
+	kept := cfg.Contexts[:0]
+	for _, c := range cfg.Contexts {
+		if c.Name != args[0] {
+			kept = append(kept, c)
+		}
+	}
+	if len(kept) == len(cfg.Contexts) {
+		return fmt.Errorf("context %q not found", args[0])
+	}
+	cfg.Contexts = kept
+	if cfg.CurrentContext == args[0] {
+		cfg.CurrentContext = ""
+	}
+	if err := cfg.save(a.configPath); err != nil {
+		return err
+	}
+	return a.out.line("context " + args[0] + " deleted")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+const credentialsUsage = `usage: zeroopsctl credentials <subcommand>
+
+  list [-integration NAME] [-expired]
+  create INTEGRATION [-kind hmac|token]
+         prints the secret, which cannot be read again
+  rotate ID [-overlap DURATION]
+         the old credential keeps working for the overlap (default 24h; 0 revokes it now)
+  revoke ID
+`
+
+var credentialsCommand = command{
+	usage: credentialsUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"list":   credentialsList,
+		"create": credentialsCreate,
+		"rotate": credentialsRotate,
+		"revoke": credentialsRevoke,
+	},
+}
+
+func credentialStatus(c *client.Credential) string {
+	if c.Active {
+		return "active"
+	}
+	return "expired"
+}
+
+func credentialsList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("credentials list", flag.ContinueOnError)
+	integration := fs.String("integration", "", "only credentials of this integration")
+	expired := fs.Bool("expired", false, "include expired credentials")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	items, err := c.ListCredentials(ctx, *integration, *expired)
+	if err != nil {
+		return err
+	}
+	return a.out.print(items, func(t *table) {
+		t.header("ID", "INTEGRATION", "KIND", "STATUS", "CREATED", "CREATED BY", "EXPIRES")
+		for i := range items {
+			cr := &items[i]
+			t.row(cr.ID, cr.Integration, cr.Kind, credentialStatus(cr), formatTime(cr.CreatedAt), orDash(cr.CreatedBy), formatTime(cr.ExpiresAt))
+		}
+	})
+}
+
+// printSecret shows a new credential; the secret is only returned once.
+func printSecret(a *app, cr *client.Credential, verb string) error {
+	if a.out.format != "table" {
+		return a.out.print(cr, nil)
+	}
+	return a.out.line(fmt.Sprintf("%s credential %s %s for %s, secret (shown once):\n%s", cr.Kind, cr.ID, verb, cr.Integration, cr.Secret))
+}
+
+func credentialsCreate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("credentials create", flag.ContinueOnError)
+	kind := fs.String("kind", "hmac", "hmac (signs the body) or token (Bearer or Basic password)")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	cr, err := c.CreateCredential(ctx, &client.CreateCredentialRequest{Integration: args[0], Kind: *kind})
+	if err != nil {
+		return err
+	}
+	return printSecret(a, cr, "created")
+}
+
+func credentialsRotate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("credentials rotate", flag.ContinueOnError)
+	overlap := fs.Duration("overlap", 24*time.Hour, "how long the old credential keeps working")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	if *overlap < 0 {
+		return errors.New("-overlap must not be negative")
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	seconds := int(overlap.Seconds())
+	cr, err := c.RotateCredential(ctx, args[0], &client.RotateCredentialRequest{OverlapSeconds: &seconds})
+	if err != nil {
+		return err
+	}
+	return printSecret(a, cr, "replaces "+args[0]+" after "+overlap.String()+",")
+}
+
+func credentialsRevoke(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	if err := c.RevokeCredential(ctx, args[0]); err != nil {
+		return err
+	}
+	return a.out.line("credential " + args[0] + " revoked")
+}
+
+const auditUsage = `usage: zeroopsctl audit <subcommand>
+
+  list [-action ACTION] [-since DURATION] [-limit N]
+       actions include webhook.auth_failed and credential.create/rotate/revoke
+`
+
+var auditCommand = command{
+	usage: auditUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"list": auditList,
+	},
+}
+
+func auditList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("audit list", flag.ContinueOnError)
+	action := fs.String("action", "", "only entries of this action")
+	since := fs.Duration("since", 0, "only entries of this long ago until now, e.g. 24h")
+	limit := fs.Int("limit", 0, "max entries (default 100)")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	q := client.AuditQuery{Action: *action, Limit: *limit}
+	if *since > 0 {
+		q.From = time.Now().Add(-*since)
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	items, err := c.ListAuditLogs(ctx, q)
+	if err != nil {
+		return err
+	}
+	return a.out.print(items, func(t *table) {
+		t.header("AT", "ACTION", "ACTOR", "TARGET", "REMOTE", "DETAIL")
+		for _, e := range items {
+			t.row(formatTime(e.At), e.Action, orDash(e.Actor), orDash(e.Target), orDash(e.RemoteAddr), orDash(e.Detail))
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+const deploymentsUsage = `usage: zeroopsctl deployments <subcommand>
+
+  list [-service NAME] [-state STATE] [-limit N]
+  get ID
+  create -service NAME -version VERSION [-batches N] [-window SECONDS] [-at RFC3339]
+  pause ID
+  continue ID
+  rollback ID
+  events ID [-f] [-after EVENT_ID]   print the event timeline; -f follows until the deployment ends
+`
+
+var deploymentsCommand = command{
+	usage: deploymentsUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"list":     deploymentsList,
+		"get":      deploymentsGet,
+		"create":   deploymentsCreate,
+		"pause":    deploymentAction("paused", (*client.Client).PauseDeployment),
+		"continue": deploymentAction("continued", (*client.Client).ContinueDeployment),
+		"rollback": deploymentAction("rolled back", (*client.Client).RollbackDeployment),
+		"events":   deploymentsEvents,
+	},
+}
+
+func printDeployments(a *app, items []client.Deployment) error {
+	return a.out.print(items, func(t *table) {
+		t.header("ID", "SERVICE", "VERSION", "REGION", "STATUS", "SCHEDULED", "FINISHED")
+		for _, d := range items {
+			t.row(d.ID, d.Service, d.Version, d.Region, string(d.Status), formatTime(d.ScheduleTime), formatTime(d.FinishTime))
+		}
+	})
+}
+
+func deploymentsList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("deployments list", flag.ContinueOnError)
+	var opts client.DeploymentListOptions
+	fs.StringVar(&opts.Service, "service", "", "only deployments of this service")
+	state := fs.String("state", "", "deploying, stop, rollback, completed or unrelease")
+	fs.IntVar(&opts.Limit, "limit", 0, "maximum number of deployments")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	opts.State = client.DeployState(*state)
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	opts.Region = a.region
+	items, err := c.ListDeployments(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return printDeployments(a, items)
+}
+
+func deploymentsGet(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	d, err := c.GetDeployment(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if a.out.format != "table" {
+		return a.out.print(d, nil)
+	}
+	return printDeployments(a, []client.Deployment{*d})
+}
+
+func deploymentsCreate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("deployments create", flag.ContinueOnError)
+	var req client.CreateDeploymentRequest
+	fs.StringVar(&req.Service, "service", "", "service to deploy (required)")
+	fs.StringVar(&req.Version, "version", "", "target version (required)")
+	fs.IntVar(&req.TotalBatches, "batches", 0, "number of canary batches (server default 1)")
+	fs.IntVar(&req.ObservationWindow, "window", 0, "observation window per batch in seconds (server default 300)")
+	at := fs.String("at", "", "schedule time in RFC3339; empty deploys now")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	if req.Service == "" || req.Version == "" {
+		return errors.New("-service and -version are required")
+	}
+	if *at != "" {
+		t, err := time.Parse(time.RFC3339, *at)
+		if err != nil {
+			return fmt.Errorf("-at: %w", err)
+		}
+		req.ScheduleTime = &t
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	req.Region = a.region
+	id, err := c.CreateDeployment(ctx, &req)
+	if err != nil {
+		return err
+	}
+	if a.out.format != "table" {
+		return a.out.print(map[string]string{"id": id}, nil)
+	}
+	return a.out.line("deployment " + id + " created")
+}
+
+// deploymentAction builds the pause, continue and rollback subcommands.
+func deploymentAction(done string, call func(*client.Client, context.Context, string) error) func(context.Context, *app, []string) error {
+	return func(ctx context.Context, a *app, args []string) error {
+		if err := exactArgs(args, 1); err != nil {
+			return err
+		}
+		c, err := a.client()
+		if err != nil {
+			return err
+		}
+		if err := call(c, ctx, args[0]); err != nil {
+			return err
+		}
+		return a.out.line("deployment " + args[0] + " " + done)
+	}
+}
+
+func deploymentsEvents(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("deployments events", flag.ContinueOnError)
+	follow := fs.Bool("f", false, "stream new events until the deployment completes or rolls back")
+	after := fs.Int64("after", 0, "only events after this event ID")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+
+	if !*follow {
+		events, err := c.ListDeploymentEvents(ctx, args[0], *after, 0)
+		if err != nil {
+			return err
+		}
+		return a.out.print(events, func(t *table) {
+			eventHeader(t)
+			for i := range events {
+				eventRow(t, &events[i])
+			}
+		})
+	}
+
+	// tabwriter needs every row before aligning, so follow mode uses fixed widths in
+	// table format and one JSON object or YAML document per event otherwise
+	const followFormat = "%-8s  %-19s  %-16s  %-24s  %-10s  %s\n"
+	if a.out.format == "table" {
+		fmt.Fprintf(a.out.w, followFormat, "ID", "TIME", "TYPE", "DETAIL", "OPERATOR", "MESSAGE")
+	}
+	err = c.StreamDeploymentEvents(ctx, args[0], *after, func(ev client.DeployEvent) error {
+		switch a.out.format {
+		case "table":
+			cols := eventColumns(&ev)
+			_, err := fmt.Fprintf(a.out.w, followFormat, cols[0], cols[1], cols[2], cols[3], cols[4], cols[5])
+			return err
+		case "yaml":
+			fmt.Fprintln(a.out.w, "---")
+		}
+		return a.out.print(ev, nil)
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+func eventHeader(t *table) {
+	t.header("ID", "TIME", "TYPE", "DETAIL", "OPERATOR", "MESSAGE")
+}
+
+func eventRow(t *table, ev *client.DeployEvent) {
+	t.row(eventColumns(ev)...)
+}
+
+func eventColumns(ev *client.DeployEvent) []string {
+	detail := "-"
+	switch {
+	case ev.FromState != "" || ev.ToState != "":
+		detail = orDash(string(ev.FromState)) + " -> " + string(ev.ToState)
+	case ev.Batch != nil:
+		detail = "batch " + strconv.Itoa(*ev.Batch)
+	case ev.Instance != "":
+		detail = ev.Instance
+	case ev.Verdict != "":
+		detail = string(ev.Verdict)
+	}
+	return []string{strconv.FormatInt(ev.ID, 10), formatTime(ev.CreatedAt), string(ev.Type), detail, orDash(ev.Operator), orDash(ev.Message)}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+const issuesUsage = `usage: zeroopsctl issues <subcommand>
+
+  list [-state Open|Closed] [-service NAME] [-level LEVEL] [-limit N] [-all]
+  get ID
+  ack ID             acknowledge as the context operator
+  comment ID TEXT    add a comment
+  watch [-service NAME] [-level LEVEL]
+                     print issues as they are created, updated and closed until interrupted
+`
+
+var issuesCommand = command{
+	usage: issuesUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"list":    issuesList,
+		"get":     issuesGet,
+		"ack":     issuesAck,
+		"comment": issuesComment,
+		"watch":   issuesWatch,
+	},
+}
+
+func issuesList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("issues list", flag.ContinueOnError)
+	var opts client.IssueListOptions
+	fs.StringVar(&opts.State, "state", "", "Open (default) or Closed")
+	fs.StringVar(&opts.Service, "service", "", "only issues of this service")
+	fs.StringVar(&opts.Level, "level", "", "only issues of this level, e.g. P0")
+	fs.IntVar(&opts.Limit, "limit", 50, "page size, 1-100")
+	all := fs.Bool("all", false, "follow pages until the end")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	opts.Region = a.region
+
+	var items []client.IssueListItem
+	for {
+		page, err := c.ListIssues(ctx, opts)
+		if err != nil {
+			return err
+		}
+		items = append(items, page.Items...)
+		if !*all || page.Next == "" {
+			break
+		}
+		opts.Start = page.Next
+	}
+	if items == nil {
+		items = []client.IssueListItem{}
+	}
+	return a.out.print(items, func(t *table) {
+		t.header("ID", "LEVEL", "STATE", "ALERT STATE", "SERVICE", "REGION", "SINCE", "ACKED BY", "TITLE")
+		for _, it := range items {
+			t.row(it.ID, it.Level, it.State, it.AlertState, orDash(labelValue(it.Labels, "service")), it.Region,
+				formatTime(it.AlertSince), orDash(it.AckedBy), it.Title)
+		}
+	})
+}
+
+func labelValue(labels []client.Label, key string) string {
+	for _, l := range labels {
+		if l.Key == key {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+func printIssue(a *app, issue *client.Issue) error {
+	return a.out.print(issue, func(t *table) {
+		t.row("ID:", issue.ID)
+		t.row("Title:", issue.Title)
+		t.row("Level:", issue.Level)
+		t.row("State:", issue.State+" / "+issue.AlertState)
+		t.row("Since:", formatTime(issue.AlertSince))
+		if issue.AckedBy != "" {
+			t.row("Acked:", issue.AckedBy+" at "+formatTime(issue.AckedAt))
+		}
+		labels := make([]string, 0, len(issue.Labels))
+		for _, l := range issue.Labels {
+			labels = append(labels, l.Key+"="+l.Value)
+		}
+		t.row("Labels:", orDash(strings.Join(labels, ", ")))
+		if len(issue.Comments) > 0 {
+			t.row("")
+			t.row("COMMENTED", "CONTENT")
+			for _, cm := range issue.Comments {
+				t.row(formatTime(cm.CreatedAt), strings.ReplaceAll(cm.Content, "\n", " "))
+			}
+		}
+	})
+}
+
+func issuesGet(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	issue, err := c.GetIssue(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return printIssue(a, issue)
+}
+
+func issuesAck(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	issue, err := c.AckIssue(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if a.out.format != "table" {
+		return a.out.print(issue, nil)
+	}
+	return a.out.line("issue " + issue.ID + " acknowledged by " + issue.AckedBy)
+}
+
+func issuesComment(ctx context.Context, a *app, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	comment, err := c.CommentIssue(ctx, args[0], strings.Join(args[1:], " "))
+	if err != nil {
+		return err
+	}
+	if a.out.format != "table" {
+		return a.out.print(comment, nil)
+	}
+	return a.out.line("comment added to issue " + args[0])
+}
+
+func issuesWatch(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("issues watch", flag.ContinueOnError)
+	var opts client.IssueStreamOptions
+	fs.StringVar(&opts.Service, "service", "", "only issues of this service")
+	fs.StringVar(&opts.Level, "level", "", "only issues of this level, e.g. P0")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	opts.Region = a.region
+
+	// fixed widths in table format as in deployments events -f
+	const watchFormat = "%-8s  %-24s  %-5s  %-12s  %-12s  %-19s  %-10s  %s\n"
+	if a.out.format == "table" {
+		fmt.Fprintf(a.out.w, watchFormat, "EVENT", "ID", "LEVEL", "ALERT STATE", "SERVICE", "SINCE", "ACKED BY", "TITLE")
+	}
+	err = c.StreamIssues(ctx, opts, func(ev client.IssueEvent) error {
+		switch a.out.format {
+		case "table":
+			it := &ev.Issue
+			_, err := fmt.Fprintf(a.out.w, watchFormat, ev.Type, it.ID, it.Level, it.AlertState,
+				orDash(labelValue(it.Labels, "service")), formatTime(it.AlertSince), orDash(it.AckedBy), it.Title)
+			return err
+		case "yaml":
+			fmt.Fprintln(a.out.w, "---")
+		}
+		return a.out.print(ev, nil)
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	if err == nil {
+		return errors.New("the server ended the stream because the client fell behind; list issues and watch again")
+	}
+	return err
+}
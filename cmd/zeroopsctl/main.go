@@ -0,0 +1,200 @@
+// Command zeroopsctl is the operator CLI for the zeroops API.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+const usage = `usage: zeroopsctl [global flags] <command> <subcommand> [args] [flags]
+
+Commands:
+  services     list | describe NAME | tree [NAME]
+  deployments  list | get ID | create | pause ID | continue ID | rollback ID | events ID [-f]
+  issues       list | get ID | ack ID | comment ID TEXT | watch
+  silences     list | create | expire ID
+  slos         list | set SERVICE NAME | delete SERVICE NAME | budget | rules
+  anomaly      list | set SERVICE SERIES | delete SERVICE SERIES | backtest SERVICE SERIES
+  reports      generate ISSUE_ID [-save] | get ISSUE_ID | list
+  analytics    issues | flapping | noisy | rollup
+  credentials  list | create INTEGRATION | rotate ID | revoke ID
+  audit        list
+  config       get-contexts | current-context | use-context NAME | set-context NAME | delete-context NAME
+
+Global flags:
+  -config PATH    config file (default $ZEROOPSCTL_CONFIG or ~/.zeroops/config.yaml)
+  -context NAME   context to use instead of current-context
+  -server URL     server URL, overrides the context
+  -token TOKEN    bearer token, overrides the context
+  -region NAME    region to list and deploy in, overrides the context; empty means all
+  -o FORMAT       output format: table (default), json or yaml
+
+Run "zeroopsctl <command> -h" for the flags of a command.
+`
+
+// errUsage makes run print the command usage and exit with code 2.
+var errUsage = errors.New("usage")
+
+// app holds the global flags shared by every command.
+type app struct {
+	configPath string
+	context    string
+	server     string
+	token      string
+	region     string
+	out        *printer
+}
+
+type command struct {
+	usage string
+	subs  map[string]func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = map[string]command{
+	"services":    servicesCommand,
+	"deployments": deploymentsCommand,
+	"issues":      issuesCommand,
+	"silences":    silencesCommand,
+	"slos":        slosCommand,
+	"anomaly":     anomalyCommand,
+	"reports":     reportsCommand,
+	"analytics":   analyticsCommand,
+	"credentials": credentialsCommand,
+	"audit":       auditCommand,
+	"config":      configCommand,
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run executes one command line and returns the exit code.
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	a := &app{}
+	fs := flag.NewFlagSet("zeroopsctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(stderr, usage) }
+	fs.StringVar(&a.configPath, "config", defaultConfigPath(), "config file")
+	fs.StringVar(&a.context, "context", "", "context name")
+	fs.StringVar(&a.server, "server", "", "server URL")
+	fs.StringVar(&a.token, "token", "", "bearer token")
+	fs.StringVar(&a.region, "region", "", "region")
+	format := fs.String("o", "table", "output format")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	out, err := newPrinter(stdout, *format)
+	if err != nil {
+		fmt.Fprintf(stderr, "zeroopsctl: %v\n", err)
+		return 2
+	}
+	a.out = out
+
+	rest := fs.Args()
+	if len(rest) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, ok := commands[rest[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "zeroopsctl: unknown command %q\n\n%s", rest[0], usage)
+		return 2
+	}
+	if len(rest) < 2 || cmd.subs[rest[1]] == nil {
+		fmt.Fprint(stderr, cmd.usage)
+		return 2
+	}
+
+	err = cmd.subs[rest[1]](ctx, a, rest[2:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		fmt.Fprint(stderr, cmd.usage)
+		return 2
+	default:
+		fmt.Fprintf(stderr, "zeroopsctl: %v\n", err)
+		return 1
+	}
+}
+
+// client builds an API client from the selected context and the global overrides.
+func (a *app) client() (*client.Client, error) {
+	cfg, err := loadConfig(a.configPath)
+	if err != nil {
+		return nil, err
+	}
+	// -server alone works without a config file; an explicit -context must exist
+	c, err := cfg.resolve(a.context)
+	if err != nil && (a.server == "" || a.context != "") {
+		return nil, err
+	}
+	if a.server != "" {
+		c.Server = a.server
+	}
+	if a.token != "" {
+		c.Token = a.token
+	}
+	if a.region == "" {
+		a.region = c.Region
+	}
+	operator := c.Operator
+	if operator == "" {
+		operator = os.Getenv("USER")
+	}
+	return client.New(c.Server, client.WithToken(c.Token), client.WithOperator(operator)), nil
+}
+
+// parseFlags parses flags that may appear before, between or after positional
+// arguments, e.g. "events ID -f", and returns the positional ones.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// exactArgs checks the number of positional arguments.
+func exactArgs(args []string, n int) error {
+	if len(args) != n {
+		return errUsage
+	}
+	return nil
+}
+
+// stringsFlag collects a repeatable string flag.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string     { return strings.Join(*s, ",") }
+func (s *stringsFlag) Set(v string) error { *s = append(*s, v); return nil }
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
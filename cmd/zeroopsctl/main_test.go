@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+func runCLI(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr)
+	if code != 0 {
+		t.Logf("stderr: %s", stderr.String())
+	}
+	return stdout.String(), code
+}
+
+func TestContextsAndSilenceCreate(t *testing.T) {
+	var got client.CreateSilenceRequest
+	var operator string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/silences" {
+			http.NotFound(w, r)
+			return
+		}
+		operator = r.Header.Get("X-Operator")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		now := time.Now()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(client.Silence{ID: "sil-1", Matchers: got.Matchers, StartsAt: now, EndsAt: now.Add(time.Hour), Status: "active"})
+	}))
+	defer srv.Close()
+
+	cfg := "-config=" + filepath.Join(t.TempDir(), "config.yaml")
+	if _, code := runCLI(t, cfg, "config", "set-context", "dev", "-server", srv.URL, "-operator", "alice"); code != 0 {
+		t.Fatalf("set-context exit %d", code)
+	}
+	out, code := runCLI(t, cfg, "config", "current-context")
+	if code != 0 || strings.TrimSpace(out) != "dev" {
+		t.Fatalf("current-context = %q (exit %d)", out, code)
+	}
+
+	out, code = runCLI(t, cfg, "-o", "yaml", "silences", "create", "-m", "service=api", "-m", "level=~P[01]", "-d", "1h", "-comment", "maintenance")
+	if code != 0 {
+		t.Fatalf("silences create exit %d", code)
+	}
+	if operator != "alice" || got.Duration != "1h" || len(got.Matchers) != 2 || got.Matchers[1].Op != "=~" {
+		t.Fatalf("unexpected request %+v from %q", got, operator)
+	}
+	if !strings.Contains(out, "id: sil-1") {
+		t.Fatalf("yaml output missing id:\n%s", out)
+	}
+
+	if _, code := runCLI(t, cfg, "silences", "create", "-m", "service=api", "-comment", "x"); code == 0 {
+		t.Fatal("create without -d or -end should fail")
+	}
+	if _, code := runCLI(t, cfg, "-context", "prod", "silences", "list"); code == 0 {
+		t.Fatal("unknown context should fail")
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	items := []client.ServiceItem{
+		{Name: "gateway", Deps: []string{"api"}},
+		{Name: "api", Deps: []string{"storage", "cache"}, Health: "Warning"},
+		{Name: "storage"},
+		{Name: "cache"},
+	}
+	roots, err := buildTree(items, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || roots[0].Name != "gateway" {
+		t.Fatalf("roots = %+v", roots)
+	}
+	var buf bytes.Buffer
+	printTree(&buf, roots[0], "", "")
+	want := "gateway\n└── api [Warning]\n    ├── storage\n    └── cache\n"
+	if buf.String() != want {
+		t.Fatalf("tree:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if _, err := buildTree(items, []string{"missing"}); err == nil {
+		t.Fatal("unknown service should fail")
+	}
+}
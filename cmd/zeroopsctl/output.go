@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// printer renders command results as a table or as the API's JSON, optionally
+// converted to YAML.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "table", "json", "yaml":
+		return &printer{w: w, format: format}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q (want table, json or yaml)", format)
+}
+
+// print writes v as JSON or YAML, or calls fill to build a table.
+func (p *printer) print(v any, fill func(t *table)) error {
+	switch p.format {
+	case "json":
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		return writeYAML(p.w, v)
+	}
+	t := &table{tw: tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)}
+	fill(t)
+	return t.tw.Flush()
+}
+
+// line prints a confirmation message; it is suppressed for json and yaml so the
+// output stays machine-readable.
+func (p *printer) line(msg string) error {
+	if p.format != "table" {
+		return nil
+	}
+	_, err := fmt.Fprintln(p.w, msg)
+	return err
+}
+
+// writeYAML converts through JSON so field names and order match the API.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle undoes the flow style and double quoting yaml keeps from the JSON
+// input; the encoder still quotes strings that would otherwise change type.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+type table struct {
+	tw *tabwriter.Writer
+}
+
+func (t *table) header(cols ...string) { t.row(cols...) }
+
+func (t *table) row(cols ...string) {
+	fmt.Fprintln(t.tw, strings.Join(cols, "\t"))
+}
+
+// formatTime renders API times in local time; zero and unparsable values pass through.
+func formatTime(v any) string {
+	switch tv := v.(type) {
+	case time.Time:
+		if tv.IsZero() {
+			return "-"
+		}
+		return tv.Local().Format("2006-01-02 15:04:05")
+	case *time.Time:
+		if tv == nil {
+			return "-"
+		}
+		return formatTime(*tv)
+	case string:
+		if tv == "" {
+			return "-"
+		}
+		if t, err := time.Parse(time.RFC3339Nano, tv); err == nil {
+			return formatTime(t)
+		}
+		return tv
+	}
+	return fmt.Sprint(v)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
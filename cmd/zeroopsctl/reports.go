@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+const reportsUsage = `usage: zeroopsctl reports <subcommand>
+
+  generate ISSUE_ID [-save]   builds the postmortem report from the issue history; -save
+                              stores it, replacing an earlier one
+  get ISSUE_ID                prints a stored report
+  list [-service NAME] [-limit N]
+
+generate and get print the report as Markdown, or as the API's JSON with -o json|yaml.
+`
+
+var reportsCommand = command{
+	usage: reportsUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"generate": reportsGenerate,
+		"get":      reportsGet,
+		"list":     reportsList,
+	},
+}
+
+func reportsGenerate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("reports generate", flag.ContinueOnError)
+	save := fs.Bool("save", false, "store the report")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	if *save {
+		stored, err := c.SaveIssueReport(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		return printReport(a, stored, stored.Report)
+	}
+	r, err := c.IssueReport(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return printReport(a, r, r)
+}
+
+func reportsGet(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	stored, err := c.GetReport(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return printReport(a, stored, stored.Report)
+}
+
+// printReport writes v as JSON or YAML, and r as Markdown for the table format.
+func printReport(a *app, v any, r *client.Report) error {
+	if a.out.format != "table" {
+		return a.out.print(v, nil)
+	}
+	_, err := io.WriteString(a.out.w, r.Markdown())
+	return err
+}
+
+func reportsList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("reports list", flag.ContinueOnError)
+	service := fs.String("service", "", "only reports of this service")
+	limit := fs.Int("limit", 0, "number of reports, 1-200 (default 50)")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	items, err := c.ListReports(ctx, *service, *limit)
+	if err != nil {
+		return err
+	}
+	return a.out.print(items, func(t *table) {
+		t.header("ISSUE", "SERVICE", "LEVEL", "ALERT SINCE", "TTD", "TTR", "CREATED", "BY", "TITLE")
+		for _, s := range items {
+			t.row(s.IssueID, orDash(s.Service), s.Level, formatTime(s.AlertSince),
+				formatSeconds(s.Figures.TimeToDetect), formatSeconds(s.Figures.TimeToResolve),
+				formatTime(s.CreatedAt), orDash(s.CreatedBy), s.Title)
+		}
+	})
+}
+
+func formatSeconds(s *int64) string {
+	if s == nil {
+		return "-"
+	}
+	return fmt.Sprint(time.Duration(*s) * time.Second)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+const servicesUsage = `usage: zeroopsctl services <subcommand>
+
+  list             list services with health and deploy state
+  describe NAME    show dependencies, dependents, active versions and instances
+  tree [NAME]      print the dependency tree of NAME, or of every top-level service
+`
+
+var servicesCommand = command{
+	usage: servicesUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"list":     servicesList,
+		"describe": servicesDescribe,
+		"tree":     servicesTree,
+	},
+}
+
+func servicesList(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.ListServices(ctx, a.region)
+	if err != nil {
+		return err
+	}
+	return a.out.print(resp.Items, func(t *table) {
+		t.header("NAME", "HEALTH", "DEPLOY STATE", "PROGRESS", "DEPLOYMENT", "DEPS")
+		for _, s := range resp.Items {
+			progress := "-"
+			if s.DeployProgress != nil {
+				progress = strconv.Itoa(int(*s.DeployProgress*100)) + "%"
+			}
+			t.row(s.Name, string(s.Health), string(s.DeployState), progress, orDash(s.ActiveDeployID), orDash(strings.Join(s.Deps, ",")))
+		}
+	})
+}
+
+// serviceDescription is the describe output for json and yaml.
+type serviceDescription struct {
+	Service        client.ServiceItem         `json:"service"`
+	Dependents     []client.ServiceImpactItem `json:"dependents"`
+	ActiveVersions []client.ActiveVersion     `json:"activeVersions"`
+	Instances      []client.Instance          `json:"instances"`
+}
+
+func servicesDescribe(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	name := args[0]
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	all, err := c.ListServices(ctx, a.region)
+	if err != nil {
+		return err
+	}
+	var desc serviceDescription
+	found := false
+	for _, s := range all.Items {
+		if s.Name == name {
+			desc.Service, found = s, true
+		}
+	}
+	if !found {
+		return fmt.Errorf("service %q not found", name)
+	}
+	impact, err := c.ServiceImpact(ctx, name, a.region)
+	if err != nil {
+		return err
+	}
+	desc.Dependents = impact.Items
+	if desc.ActiveVersions, err = c.ActiveVersions(ctx, name, a.region); err != nil {
+		return err
+	}
+	if desc.Instances, err = c.ListInstances(ctx, name, "", a.region); err != nil {
+		return err
+	}
+
+	return a.out.print(desc, func(t *table) {
+		s := desc.Service
+		t.row("Name:", s.Name)
+		t.row("Owner:", orDash(s.Owner))
+		t.row("Health:", string(s.Health))
+		t.row("Deploy state:", string(s.DeployState))
+		if s.ActiveDeployID != "" {
+			t.row("Deployment:", s.ActiveDeployID)
+		}
+		t.row("Regions:", orDash(strings.Join(s.Regions, ", ")))
+		t.row("Depends on:", orDash(strings.Join(s.Deps, ", ")))
+		dependents := make([]string, 0, len(desc.Dependents))
+		for _, d := range desc.Dependents {
+			dependents = append(dependents, fmt.Sprintf("%s (depth %d, %s)", d.Name, d.Depth, d.Health))
+		}
+		t.row("Dependents:", orDash(strings.Join(dependents, ", ")))
+		t.row("")
+		t.row("VERSION", "DEPLOYMENT", "STATE", "INSTANCES", "HEALTH", "STARTED")
+		for _, v := range desc.ActiveVersions {
+			t.row(v.Version, orDash(v.DeployID), orDash(string(v.DeployState)), strconv.Itoa(v.Instances), string(v.Health), formatTime(v.StartTime))
+		}
+		t.row("")
+		t.row("INSTANCE", "REGION", "VERSION", "STATUS", "ADDRESS", "LAST HEARTBEAT")
+		for _, in := range desc.Instances {
+			addr := "-"
+			if in.IP != "" {
+				addr = in.IP
+				if in.Port > 0 {
+					addr += ":" + strconv.Itoa(in.Port)
+				}
+			}
+			t.row(in.ID, in.Region, in.Version, string(in.Status), addr, formatTime(in.LastHeartbeat))
+		}
+	})
+}
+
+// treeNode is the tree output for json and yaml.
+type treeNode struct {
+	Name   string      `json:"name"`
+	Health string      `json:"health"`
+	Deps   []*treeNode `json:"deps,omitempty"`
+}
+
+func servicesTree(ctx context.Context, a *app, args []string) error {
+	if len(args) > 1 {
+		return errUsage
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	resp, err := c.ListServices(ctx, a.region)
+	if err != nil {
+		return err
+	}
+	roots, err := buildTree(resp.Items, args)
+	if err != nil {
+		return err
+	}
+	if a.out.format != "table" {
+		return a.out.print(roots, nil)
+	}
+	for _, r := range roots {
+		printTree(a.out.w, r, "", "")
+	}
+	return nil
+}
+
+// buildTree expands dependencies from the named services, or from every service no
+// other service depends on. The graph is acyclic; shared dependencies are repeated.
+func buildTree(items []client.ServiceItem, names []string) ([]*treeNode, error) {
+	byName := make(map[string]client.ServiceItem, len(items))
+	dependedOn := map[string]bool{}
+	for _, s := range items {
+		byName[s.Name] = s
+		for _, d := range s.Deps {
+			dependedOn[d] = true
+		}
+	}
+	if len(names) == 0 {
+		for _, name := range sortedKeys(byName) {
+			if !dependedOn[name] {
+				names = append(names, name)
+			}
+		}
+	}
+
+	var expand func(name string, path map[string]bool) *treeNode
+	expand = func(name string, path map[string]bool) *treeNode {
+		s := byName[name]
+		n := &treeNode{Name: name, Health: string(s.Health)}
+		if path[name] {
+			return n // defensive: the server rejects cycles
+		}
+		path[name] = true
+		for _, d := range s.Deps {
+			n.Deps = append(n.Deps, expand(d, path))
+		}
+		delete(path, name)
+		return n
+	}
+
+	roots := make([]*treeNode, 0, len(names))
+	for _, name := range names {
+		if _, ok := byName[name]; !ok {
+			return nil, fmt.Errorf("service %q not found", name)
+		}
+		roots = append(roots, expand(name, map[string]bool{}))
+	}
+	return roots, nil
+}
+
+func printTree(w io.Writer, n *treeNode, prefix, branch string) {
+	health := ""
+	if n.Health != "" && n.Health != "Normal" {
+		health = " [" + n.Health + "]"
+	}
+	fmt.Fprintf(w, "%s%s%s%s\n", prefix, branch, n.Name, health)
+	switch branch {
+	case "├── ":
+		prefix += "│   "
+	case "└── ":
+		prefix += "    "
+	}
+	for i, d := range n.Deps {
+		next := "├── "
+		if i == len(n.Deps)-1 {
+			next = "└── "
+		}
+		printTree(w, d, prefix, next)
+	}
+}
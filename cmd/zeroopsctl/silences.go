@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+const silencesUsage = `usage: zeroopsctl silences <subcommand>
+
+  list [-expired]
+  create -m MATCHER [-m MATCHER ...] (-d DURATION | -end RFC3339) [-start RFC3339] -comment TEXT
+         matchers are name=value, name!=value, name=~regex or name!~regex and must all match
+  expire ID
+`
+
+var silencesCommand = command{
+	usage: silencesUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"list":   silencesList,
+		"create": silencesCreate,
+		"expire": silencesExpire,
+	},
+}
+
+func printSilences(a *app, items []client.Silence) error {
+	return a.out.print(items, func(t *table) {
+		t.header("ID", "STATUS", "MATCHERS", "STARTS", "ENDS", "CREATED BY", "COMMENT")
+		for _, s := range items {
+			matchers := make([]string, 0, len(s.Matchers))
+			for _, m := range s.Matchers {
+				matchers = append(matchers, m.String())
+			}
+			t.row(s.ID, s.Status, strings.Join(matchers, ","), formatTime(s.StartsAt), formatTime(s.EndsAt), orDash(s.CreatedBy), s.Comment)
+		}
+	})
+}
+
+func silencesList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("silences list", flag.ContinueOnError)
+	expired := fs.Bool("expired", false, "include expired silences")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	items, err := c.ListSilences(ctx, *expired)
+	if err != nil {
+		return err
+	}
+	return printSilences(a, items)
+}
+
+func silencesCreate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("silences create", flag.ContinueOnError)
+	var matchers stringsFlag
+	fs.Var(&matchers, "m", "label matcher, repeatable")
+	duration := fs.String("d", "", "how long the silence lasts, e.g. 2h")
+	start := fs.String("start", "", "start time in RFC3339 (default now)")
+	end := fs.String("end", "", "end time in RFC3339")
+	comment := fs.String("comment", "", "why the alerts are silenced (required)")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	if len(matchers) == 0 || *comment == "" || (*duration == "") == (*end == "") {
+		return errors.New("need at least one -m, -comment, and exactly one of -d or -end")
+	}
+
+	req := client.CreateSilenceRequest{Duration: *duration, Comment: *comment}
+	for _, s := range matchers {
+		m, err := client.ParseMatcher(s)
+		if err != nil {
+			return err
+		}
+		req.Matchers = append(req.Matchers, m)
+	}
+	for flagName, p := range map[string]struct {
+		v   string
+		dst **time.Time
+	}{"start": {*start, &req.StartsAt}, "end": {*end, &req.EndsAt}} {
+		if p.v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, p.v)
+		if err != nil {
+			return fmt.Errorf("-%s: %w", flagName, err)
+		}
+		*p.dst = &t
+	}
+
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	sil, err := c.CreateSilence(ctx, &req)
+	if err != nil {
+		return err
+	}
+	if a.out.format != "table" {
+		return a.out.print(sil, nil)
+	}
+	return a.out.line(fmt.Sprintf("silence %s created, %s until %s", sil.ID, sil.Status, formatTime(sil.EndsAt)))
+}
+
+func silencesExpire(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 1); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	if err := c.ExpireSilence(ctx, args[0]); err != nil {
+		return err
+	}
+	return a.out.line("silence " + args[0] + " expired")
+}
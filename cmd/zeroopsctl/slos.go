@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/qiniu/zeroops/pkg/client"
+)
+
+const slosUsage = `usage: zeroopsctl slos <subcommand>
+
+  list [-service NAME]
+  set SERVICE NAME -good QUERY -total QUERY -target RATIO [-window DAYS] [-description TEXT]
+         queries are PromQL with $window as the range, e.g. sum(rate(requests_total[$window]))
+  delete SERVICE NAME
+  budget [-service NAME]
+  rules  prints the Prometheus rule file of every SLO as YAML (JSON with -o json)
+`
+
+var slosCommand = command{
+	usage: slosUsage,
+	subs: map[string]func(ctx context.Context, a *app, args []string) error{
+		"list":   slosList,
+		"set":    slosSet,
+		"delete": slosDelete,
+		"budget": slosBudget,
+		"rules":  slosRules,
+	},
+}
+
+func slosList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("slos list", flag.ContinueOnError)
+	service := fs.String("service", "", "only SLOs of this service")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	items, err := c.ListSLOs(ctx, *service)
+	if err != nil {
+		return err
+	}
+	return a.out.print(items, func(t *table) {
+		t.header("SERVICE", "NAME", "TARGET", "WINDOW", "DESCRIPTION")
+		for _, s := range items {
+			t.row(s.Service, s.Name, formatRatio(s.Target), strconv.Itoa(s.WindowDays)+"d", orDash(s.Description))
+		}
+	})
+}
+
+func slosSet(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("slos set", flag.ContinueOnError)
+	good := fs.String("good", "", "PromQL counting good events over $window (required)")
+	total := fs.String("total", "", "PromQL counting all events over $window (required)")
+	target := fs.Float64("target", 0, "objective as a ratio, e.g. 0.999 (required)")
+	window := fs.Int("window", 0, "window in days (default 30)")
+	description := fs.String("description", "", "what the SLO measures")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 2); err != nil {
+		return err
+	}
+	if *good == "" || *total == "" || *target == 0 {
+		return errors.New("-good, -total and -target are required")
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	s, err := c.PutSLO(ctx, args[0], args[1], &client.PutSLORequest{
+		Description: *description,
+		GoodQuery:   *good,
+		TotalQuery:  *total,
+		Target:      *target,
+		WindowDays:  *window,
+	})
+	if err != nil {
+		return err
+	}
+	if a.out.format != "table" {
+		return a.out.print(s, nil)
+	}
+	return a.out.line(fmt.Sprintf("slo %s/%s saved: %s over %dd", s.Service, s.Name, formatRatio(s.Target), s.WindowDays))
+}
+
+func slosDelete(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 2); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	if err := c.DeleteSLO(ctx, args[0], args[1]); err != nil {
+		return err
+	}
+	return a.out.line("slo " + args[0] + "/" + args[1] + " deleted")
+}
+
+func slosBudget(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("slos budget", flag.ContinueOnError)
+	service := fs.String("service", "", "only SLOs of this service")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	items, err := c.SLOBudgets(ctx, *service)
+	if err != nil {
+		return err
+	}
+	return a.out.print(items, func(t *table) {
+		t.header("SERVICE", "SLO", "TARGET", "SLI", "BUDGET LEFT", "ERROR")
+		for _, b := range items {
+			sli, left := "-", "-"
+			if b.SLI != nil {
+				sli = formatRatio(*b.SLI)
+			}
+			if b.Remaining != nil {
+				left = strconv.FormatFloat(*b.Remaining*100, 'f', 1, 64) + "%"
+			}
+			t.row(b.Service, b.SLO, formatRatio(b.Target), sli, left, orDash(b.Error))
+		}
+	})
+}
+
+// slosRules prints YAML regardless of -o, so the output can be written to a Prometheus
+// rule file directly.
+func slosRules(ctx context.Context, a *app, args []string) error {
+	if err := exactArgs(args, 0); err != nil {
+		return err
+	}
+	c, err := a.client()
+	if err != nil {
+		return err
+	}
+	rules, err := c.SLORules(ctx)
+	if err != nil {
+		return err
+	}
+	if a.out.format == "json" {
+		return a.out.print(rules, nil)
+	}
+	return writeYAML(a.out.w, rules)
+}
+
+func formatRatio(v float64) string {
+	return strconv.FormatFloat(v*100, 'g', 6, 64) + "%"
+}
@@ -0,0 +1,34 @@
+-- ZeroOps Service Manager 演示数据
+-- 表结构由 internal/migrate/migrations 中的版本化迁移维护，先执行 `zeroops migrate up` 再导入本文件
+
+-- 插入Mock S3项目的真实服务数据
+-- 服务及其依赖关系（基于实际业务流程）
+INSERT INTO services (name, deps) VALUES 
+    ('storage', '[]'::jsonb),                     -- 存储服务：基础服务
+    ('metadata', '["storage"]'::jsonb),           -- 元数据服务：依赖存储服务
+    ('queue', '["storage"]'::jsonb),              -- 队列服务：依赖存储服务
+    ('third-party', '[]'::jsonb),                 -- 第三方服务：独立
+    ('mock-error', '[]'::jsonb)                   -- 错误模拟服务：独立
+ON CONFLICT (name) DO NOTHING;
+
+-- 服务版本：metadata, storage, queue, third-party 各有3个版本，mock-error只有1个版本
+INSERT INTO service_versions (version, service, create_time) VALUES 
+    -- metadata service versions
+    ('v1.0.0', 'metadata', CURRENT_TIMESTAMP - INTERVAL '60 days'),
+    ('v1.1.0', 'metadata', CURRENT_TIMESTAMP - INTERVAL '30 days'),
+    ('v1.2.0', 'metadata', CURRENT_TIMESTAMP - INTERVAL '7 days'),
+    -- storage service versions  
+    ('v1.0.0', 'storage', CURRENT_TIMESTAMP - INTERVAL '55 days'),
+    ('v1.1.0', 'storage', CURRENT_TIMESTAMP - INTERVAL '25 days'),
+    ('v1.2.0', 'storage', CURRENT_TIMESTAMP - INTERVAL '5 days'),
+    -- queue service versions
+    ('v1.0.0', 'queue', CURRENT_TIMESTAMP - INTERVAL '50 days'),
+    ('v1.1.0', 'queue', CURRENT_TIMESTAMP - INTERVAL '20 days'),
+    ('v1.2.0', 'queue', CURRENT_TIMESTAMP - INTERVAL '3 days'),
+    -- third-party service versions
+    ('v1.0.0', 'third-party', CURRENT_TIMESTAMP - INTERVAL '45 days'),
+    ('v1.1.0', 'third-party', CURRENT_TIMESTAMP - INTERVAL '15 days'),
+    ('v1.2.0', 'third-party', CURRENT_TIMESTAMP - INTERVAL '1 day'),
+    -- mock-error service version
+    ('v1.0.0', 'mock-error', CURRENT_TIMESTAMP - INTERVAL '40 days')
+ON CONFLICT (version, service) DO NOTHING;
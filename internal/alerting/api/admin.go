@@ -0,0 +1,54 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/fox-gonic/fox"
+	receiver "github.com/qiniu/zeroops/internal/alerting/service/receiver"
+	"github.com/qiniu/zeroops/internal/apierror"
+)
+
+// Admins maps operator names to the bearer tokens that may manage webhook credentials
+// and read the audit log. They are separate from the webhook credentials, so a sender's
+// secret cannot mint or revoke others; without any those routes reject every request.
+type Admins map[string]string
+
+// minAdminTokenLength keeps admin tokens from being guessable.
+const minAdminTokenLength = 16
+
+var errAdminRequired = apierror.New(apierror.Unauthorized, "admin token required")
+
+// Check rejects short tokens and tokens that are also webhook environment credentials.
+func (a Admins) Check() error {
+	for name, token := range a {
+		if len(token) < minAdminTokenLength {
+			return fmt.Errorf("admin token of %s is shorter than %d characters", name, minAdminTokenLength)
+		}
+		if receiver.IsEnvCredential(token) {
+			return fmt.Errorf("admin token of %s is also a webhook credential", name)
+		}
+	}
+	return nil
+}
+
+// authenticate returns the name of the admin whose token is the request's bearer token,
+// which is recorded as the actor of the change, or writes 401.
+func (a Admins) authenticate(c *fox.Context) (string, bool) {
+	token, ok := strings.CutPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+	name := ""
+	if ok && token != "" {
+		// every token is compared so the time taken does not tell which one matched
+		for n, t := range a {
+			if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+				name = n
+			}
+		}
+	}
+	if name == "" {
+		writeError(c, errAdminRequired)
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fox-gonic/fox"
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/analytics"
+	"github.com/qiniu/zeroops/internal/apierror"
+)
+
+type AnalyticsAPI struct {
+	svc *analytics.Service
+}
+
+// FlappingList is the response of GET /v1/analytics/flapping.
+type FlappingList struct {
+	Items []analytics.FlappingAlert `json:"items"`
+}
+
+// NoisyRuleList is the response of GET /v1/analytics/noisy-rules.
+type NoisyRuleList struct {
+	Items []analytics.NoisyRule `json:"items"`
+}
+
+// RollupResult is the response of POST /v1/analytics/rollups.
+type RollupResult struct {
+	Days int `json:"days"`
+}
+
+// RegisterAnalyticsRoutes registers issue analytics routes. svc can be nil; the routes
+// then return 503.
+func RegisterAnalyticsRoutes(router *fox.Engine, svc *analytics.Service) {
+	api := &AnalyticsAPI{svc: svc}
+	router.GET("/v1/analytics/issues", api.IssueStats)
+	router.GET("/v1/analytics/flapping", api.Flapping)
+	router.GET("/v1/analytics/noisy-rules", api.NoisyRules)
+	router.POST("/v1/analytics/rollups", api.Rollup)
+}
+
+// query reads the range and filter parameters shared by the analytics routes. It
+// writes the error response and returns false when they are invalid or the routes are
+// not configured.
+func (api *AnalyticsAPI) query(c *fox.Context, p *apierror.ParamReader) (analytics.Query, bool) {
+	from, to := p.String("from", false), p.String("to", false)
+	f := adb.RollupFilter{Service: p.String("service", false), AlertName: p.String("alertname", false), Level: p.String("level", false)}
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return analytics.Query{}, false
+	}
+	if api.svc == nil {
+		writeError(c, errAnalyticsStoreMissing)
+		return analytics.Query{}, false
+	}
+	start, end, err := analytics.ParseRange(from, to, api.svc.Now())
+	if err != nil {
+		writeError(c, err)
+		return analytics.Query{}, false
+	}
+	return analytics.Query{From: start, To: end, Filter: f}, true
+}
+
+// IssueStats returns issue counts, time to acknowledge and to restore and the
+// auto-remediation success rate of the range, grouped by ?groupBy=.
+func (api *AnalyticsAPI) IssueStats(c *fox.Context) {
+	p := apierror.Params(c)
+	groupBy, err := analytics.ParseGroupBy(p.String("groupBy", false))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	q, ok := api.query(c, p)
+	if !ok {
+		return
+	}
+	stats, err := api.svc.Issues(c.Request.Context(), q, groupBy)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// Flapping lists the alert fingerprints that opened at least ?minIssues= issues in the
+// range.
+func (api *AnalyticsAPI) Flapping(c *fox.Context) {
+	p := apierror.Params(c)
+	minIssues := p.Int("minIssues", analytics.DefaultFlappingMin, 1, 1000)
+	limit := p.Int("limit", analytics.DefaultLimit, 1, analytics.MaxLimit)
+	q, ok := api.query(c, p)
+	if !ok {
+		return
+	}
+	items, err := api.svc.Flapping(c.Request.Context(), q, minIssues, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, FlappingList{Items: items})
+}
+
+// NoisyRules lists the alert rules that opened the most issues in the range.
+func (api *AnalyticsAPI) NoisyRules(c *fox.Context) {
+	p := apierror.Params(c)
+	limit := p.Int("limit", analytics.DefaultLimit, 1, analytics.MaxLimit)
+	q, ok := api.query(c, p)
+	if !ok {
+		return
+	}
+	items, err := api.svc.NoisyRules(c.Request.Context(), q, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, NoisyRuleList{Items: items})
+}
+
+// Rollup recomputes the stored rollups of the range, e.g. to backfill days older than
+// the periodic refresh covers.
+func (api *AnalyticsAPI) Rollup(c *fox.Context) {
+	p := apierror.Params(c)
+	q, ok := api.query(c, p)
+	if !ok {
+		return
+	}
+	days, err := api.svc.Rollup(c.Request.Context(), q.From, q.To)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, RollupResult{Days: days})
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/alerting/service/anomaly"
+	"github.com/qiniu/zeroops/internal/apierror"
+)
+
+type AnomalyAPI struct {
+	svc *anomaly.Service
+}
+
+// DetectorList is the response of GET /v1/anomaly/detectors.
+type DetectorList struct {
+	Items []anomaly.Detector `json:"items"`
+}
+
+// RegisterAnomalyRoutes registers anomaly detector management and backtest routes. svc
+// can be nil; the routes then return 503.
+func RegisterAnomalyRoutes(router *fox.Engine, svc *anomaly.Service) {
+	api := &AnomalyAPI{svc: svc}
+	router.GET("/v1/anomaly/detectors", api.ListDetectors)
+	router.PUT("/v1/anomaly/detectors/:service/:series", api.PutDetector)
+	router.DELETE("/v1/anomaly/detectors/:service/:series", api.DeleteDetector)
+	router.POST("/v1/anomaly/backtest", api.Backtest)
+}
+
+// ListDetectors lists anomaly detectors, of one service with ?service=.
+func (api *AnomalyAPI) ListDetectors(c *fox.Context) {
+	p := apierror.Params(c)
+	service := p.String("service", false)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errAnomalyStoreMissing)
+		return
+	}
+	items, err := api.svc.List(c.Request.Context(), service)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, DetectorList{Items: items})
+}
+
+// PutDetector sets the detector of a configured series for a service
+// (PUT /v1/anomaly/detectors/:service/:series); it runs from the next evaluation.
+func (api *AnomalyAPI) PutDetector(c *fox.Context) {
+	p := apierror.Params(c)
+	service, series := p.Path("service"), p.Path("series")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	var req anomaly.Params
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errAnomalyStoreMissing)
+		return
+	}
+	d, err := api.svc.Put(c.Request.Context(), service, series, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// DeleteDetector stops anomaly detection of a series for a service.
+func (api *AnomalyAPI) DeleteDetector(c *fox.Context) {
+	p := apierror.Params(c)
+	service, series := p.Path("service"), p.Path("series")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errAnomalyStoreMissing)
+		return
+	}
+	if err := api.svc.Delete(c.Request.Context(), service, series); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Backtest replays a detector over a past range, with the stored or the given parameters.
+// It returns 503 when Prometheus is not configured.
+func (api *AnomalyAPI) Backtest(c *fox.Context) {
+	var req anomaly.BacktestRequest
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errAnomalyStoreMissing)
+		return
+	}
+	res, err := api.svc.Backtest(c.Request.Context(), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
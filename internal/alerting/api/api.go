@@ -1,42 +1,112 @@
 package api
 
 import (
-	"fmt"
-
 	"github.com/fox-gonic/fox"
 	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/analytics"
+	"github.com/qiniu/zeroops/internal/alerting/service/anomaly"
+	"github.com/qiniu/zeroops/internal/alerting/service/credential"
 	"github.com/qiniu/zeroops/internal/alerting/service/healthcheck"
 	receiver "github.com/qiniu/zeroops/internal/alerting/service/receiver"
-	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/alerting/service/report"
+	"github.com/qiniu/zeroops/internal/alerting/service/silence"
+	"github.com/qiniu/zeroops/internal/alerting/service/slo"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/redis/go-redis/v9"
 )
 
 type Api struct{}
 
-func NewApi(router *fox.Engine) *Api { return NewApiWithConfig(router, nil) }
+// NewApi registers alerting routes without persistence: webhooks are accepted but not stored.
+func NewApi(router *fox.Engine) *Api { return NewApiWithDB(router, nil, nil) }
+
+// NewApiWithDB registers alerting routes backed by the shared store and Redis client.
+// db may be nil; a nil rdb is created from env. The caller owns rdb and closes it. No
+// admins are configured, so credential management and the audit log are unreachable.
+func NewApiWithDB(router *fox.Engine, db adb.Store, rdb *redis.Client) *Api {
+	return NewApiWithReceiver(router, nil, nil, nil, nil, db, rdb, nil)
+}
 
-func NewApiWithConfig(router *fox.Engine, cfg *config.Config) *Api {
+// NewApiWithReceiver is NewApiWithDB with the webhook served by h, so in-process alert
+// sources and the webhook share one receiver, the SLO and anomaly routes served by slos
+// and anomalies, which can query Prometheus, and the report routes by reports, which can
+// also look up service_manager. A nil h, slos, anomalies or reports is built from db and
+// rdb. admins may manage the webhook credentials and read the audit log.
+func NewApiWithReceiver(router *fox.Engine, h *receiver.Handler, slos *slo.Service, anomalies *anomaly.Service, reports *report.Service, db adb.Store, rdb *redis.Client, admins Admins) *Api {
+	if rdb == nil {
+		rdb = healthcheck.NewRedisClientFromEnv()
+	}
 	api := &Api{}
-	api.setupRouters(router, cfg)
+	api.setupRouters(router, h, slos, anomalies, reports, db, rdb, admins)
 	return api
 }
 
-func (api *Api) setupRouters(router *fox.Engine, cfg *config.Config) {
-	var h *receiver.Handler
-	var alertDB *adb.Database
-	if cfg != nil {
-		dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-			cfg.Database.Host, cfg.Database.Port, cfg.Database.User, cfg.Database.Password, cfg.Database.DBName, cfg.Database.SSLMode)
-		if db, err := adb.New(dsn); err == nil {
-			alertDB = db
-			h = receiver.NewHandlerWithCache(receiver.NewPgDAO(db), receiver.NewCacheFromEnv())
-		} else {
-			h = receiver.NewHandler(receiver.NewNoopDAO())
-		}
-	} else {
+func (api *Api) setupRouters(router *fox.Engine, h *receiver.Handler, slos *slo.Service, anomalies *anomaly.Service, reports *report.Service, db adb.Store, rdb *redis.Client, admins Admins) {
+	switch {
+	case h != nil:
+	case db != nil:
+		h = receiver.NewHandlerWithCache(receiver.NewPgDAO(db), &receiver.Cache{R: rdb}).
+			WithAuth(receiver.NewAuthenticator(db, rdb, nil))
+	default:
 		h = receiver.NewHandler(receiver.NewNoopDAO())
 	}
 	receiver.RegisterReceiverRoutes(router, h)
 
-	// Issues query API (reads from Redis cache and loads comments from DB)
-	RegisterIssueRoutes(router, healthcheck.NewRedisClientFromEnv(), alertDB)
+	// Issues API (reads from Redis cache, loads comments and writes acks/comments to DB)
+	RegisterIssueRoutes(router, rdb, db)
+	RegisterSilenceRoutes(router, db)
+	if slos == nil && db != nil {
+		slos = slo.NewService(db, nil)
+	}
+	RegisterSLORoutes(router, slos)
+	if anomalies == nil && db != nil {
+		// cannot fail without a series config; Put and Backtest then reject every series
+		anomalies, _ = anomaly.NewService(db, nil, nil)
+	}
+	RegisterAnomalyRoutes(router, anomalies)
+	if reports == nil && db != nil {
+		reports = report.NewService(db, nil, nil, nil, nil)
+	}
+	RegisterReportRoutes(router, reports)
+	var stats *analytics.Service
+	if db != nil {
+		stats = analytics.NewService(db)
+	}
+	RegisterAnalyticsRoutes(router, stats)
+	RegisterCredentialRoutes(router, db, admins)
+	RegisterAuditRoutes(router, db, admins)
+}
+
+// alertingErrors maps domain errors of the alerting services to error codes.
+var alertingErrors = []apierror.Mapping{
+	{Target: silence.ErrInvalid, Code: apierror.InvalidParameter},
+	{Target: silence.ErrNotFound, Code: apierror.NotFound, Message: "silence not found"},
+	{Target: slo.ErrInvalid, Code: apierror.InvalidParameter},
+	{Target: slo.ErrNotFound, Code: apierror.NotFound, Message: "slo not found"},
+	{Target: slo.ErrNoPrometheus, Code: apierror.Unavailable},
+	{Target: anomaly.ErrInvalid, Code: apierror.InvalidParameter},
+	{Target: anomaly.ErrNotFound, Code: apierror.NotFound, Message: "anomaly detector not found"},
+	{Target: anomaly.ErrNoPrometheus, Code: apierror.Unavailable},
+	{Target: report.ErrInvalid, Code: apierror.InvalidParameter},
+	{Target: report.ErrNotFound, Code: apierror.NotFound},
+	{Target: analytics.ErrInvalid, Code: apierror.InvalidParameter},
+	{Target: credential.ErrInvalid, Code: apierror.InvalidParameter},
+	{Target: credential.ErrNotFound, Code: apierror.NotFound},
+}
+
+var (
+	errIssueNotFound          = apierror.New(apierror.NotFound, "issue not found")
+	errIssueStoreMissing      = apierror.New(apierror.Unavailable, "issue store is not configured")
+	errSilenceStoreMissing    = apierror.New(apierror.Unavailable, "silence store is not configured")
+	errSLOStoreMissing        = apierror.New(apierror.Unavailable, "slo store is not configured")
+	errAnomalyStoreMissing    = apierror.New(apierror.Unavailable, "anomaly detector store is not configured")
+	errReportStoreMissing     = apierror.New(apierror.Unavailable, "report store is not configured")
+	errAnalyticsStoreMissing  = apierror.New(apierror.Unavailable, "analytics store is not configured")
+	errCredentialStoreMissing = apierror.New(apierror.Unavailable, "credential store is not configured")
+	errAuditStoreMissing      = apierror.New(apierror.Unavailable, "audit log store is not configured")
+)
+
+// writeError responds with the shared error body, translating alerting domain errors.
+func writeError(c *fox.Context, err error) {
+	apierror.WriteError(c, apierror.Translate(err, alertingErrors))
 }
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fox-gonic/fox"
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/apierror"
+)
+
+type AuditAPI struct {
+	DB     adb.AuditRepository
+	Admins Admins
+}
+
+// AuditEntry is one audit_logs row, e.g. a rejected webhook or a credential change.
+type AuditEntry struct {
+	ID         int64     `json:"id"`
+	At         time.Time `json:"at"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target"`
+	RemoteAddr string    `json:"remoteAddr"`
+	Detail     string    `json:"detail"`
+}
+
+// AuditLogList is the response of GET /v1/audit-logs.
+type AuditLogList struct {
+	Items []AuditEntry `json:"items"`
+}
+
+// RegisterAuditRoutes registers the audit log route, which requires the bearer token of
+// one of admins. db can be nil; it then returns 503.
+func RegisterAuditRoutes(router *fox.Engine, db adb.Store, admins Admins) {
+	api := &AuditAPI{Admins: admins}
+	if db != nil {
+		api.DB = db
+	}
+	router.GET("/v1/audit-logs", api.ListAuditLogs)
+}
+
+// ListAuditLogs returns the newest entries, optionally of ?action= and in the RFC 3339
+// range [?from=, ?to=).
+func (api *AuditAPI) ListAuditLogs(c *fox.Context) {
+	if _, ok := api.Admins.authenticate(c); !ok {
+		return
+	}
+	p := apierror.Params(c)
+	f := adb.AuditFilter{Action: p.String("action", false)}
+	from, to := p.String("from", false), p.String("to", false)
+	limit := p.Int("limit", 100, 1, 1000)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	for _, b := range []struct {
+		name, value string
+		dst         *time.Time
+	}{{"from", from, &f.From}, {"to", to, &f.To}} {
+		if b.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, b.value)
+		if err != nil {
+			writeError(c, apierror.Invalid(b.name, b.value, b.name+" must be an RFC 3339 time"))
+			return
+		}
+		*b.dst = t.UTC()
+	}
+	if api.DB == nil {
+		writeError(c, errAuditStoreMissing)
+		return
+	}
+	rows, err := api.DB.ListAuditLogs(c.Request.Context(), f, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	items := make([]AuditEntry, 0, len(rows))
+	for _, l := range rows {
+		items = append(items, AuditEntry{ID: l.ID, At: l.At, Actor: l.Actor, Action: l.Action, Target: l.Target, RemoteAddr: l.RemoteAddr, Detail: l.Detail})
+	}
+	c.JSON(http.StatusOK, AuditLogList{Items: items})
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fox-gonic/fox"
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/credential"
+	receiver "github.com/qiniu/zeroops/internal/alerting/service/receiver"
+	"github.com/qiniu/zeroops/internal/apierror"
+)
+
+type CredentialAPI struct {
+	svc    *credential.Service
+	admins Admins
+}
+
+// CredentialList is the response of GET /v1/integrations/credentials.
+type CredentialList struct {
+	Items []credential.Credential `json:"items"`
+}
+
+// RegisterCredentialRoutes registers webhook credential management routes, which require
+// the bearer token of one of admins. db can be nil; the routes then return 503.
+func RegisterCredentialRoutes(router *fox.Engine, db adb.Store, admins Admins) {
+	api := &CredentialAPI{admins: admins}
+	if db != nil {
+		api.svc = credential.NewService(db)
+	}
+	router.GET("/v1/integrations/credentials", api.ListCredentials)
+	router.POST("/v1/integrations/credentials", api.CreateCredential)
+	router.POST("/v1/integrations/credentials/:credentialID/rotate", api.RotateCredential)
+	router.DELETE("/v1/integrations/credentials/:credentialID", api.RevokeCredential)
+}
+
+// ListCredentials lists the active credentials of ?integration=, or of every integration,
+// and expired ones with ?expired=true. Secrets are never listed.
+func (api *CredentialAPI) ListCredentials(c *fox.Context) {
+	if _, ok := api.admins.authenticate(c); !ok {
+		return
+	}
+	p := apierror.Params(c)
+	integration := p.String("integration", false)
+	includeExpired := p.Bool("expired", false)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errCredentialStoreMissing)
+		return
+	}
+	items, err := api.svc.List(c.Request.Context(), integration, includeExpired)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, CredentialList{Items: items})
+}
+
+// CreateCredential creates a credential as created by the admin and returns its secret,
+// which cannot be read again.
+func (api *CredentialAPI) CreateCredential(c *fox.Context) {
+	admin, ok := api.admins.authenticate(c)
+	if !ok {
+		return
+	}
+	var req credential.CreateRequest
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		writeError(c, err)
+		return
+	}
+	req.CreatedBy = admin
+	req.RemoteAddr = receiver.RemoteIP(c.Request)
+	if api.svc == nil {
+		writeError(c, errCredentialStoreMissing)
+		return
+	}
+	cred, err := api.svc.Create(c.Request.Context(), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, cred)
+}
+
+// RotateCredential replaces a credential with a new one of the same integration and
+// kind, keeping the old one valid for the overlap, and returns the new secret.
+func (api *CredentialAPI) RotateCredential(c *fox.Context) {
+	admin, ok := api.admins.authenticate(c)
+	if !ok {
+		return
+	}
+	p := apierror.Params(c)
+	id := p.Path("credentialID")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	var req credential.RotateRequest
+	if err := apierror.BindJSON(c, &req, true); err != nil {
+		writeError(c, err)
+		return
+	}
+	req.CreatedBy = admin
+	req.RemoteAddr = receiver.RemoteIP(c.Request)
+	if api.svc == nil {
+		writeError(c, errCredentialStoreMissing)
+		return
+	}
+	cred, err := api.svc.Rotate(c.Request.Context(), id, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, cred)
+}
+
+// RevokeCredential makes a credential expire now
+// (DELETE /v1/integrations/credentials/:credentialID).
+func (api *CredentialAPI) RevokeCredential(c *fox.Context) {
+	admin, ok := api.admins.authenticate(c)
+	if !ok {
+		return
+	}
+	p := apierror.Params(c)
+	id := p.Path("credentialID")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errCredentialStoreMissing)
+		return
+	}
+	if err := api.svc.Revoke(c.Request.Context(), id, admin, receiver.RemoteIP(c.Request)); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/qiniu/zeroops/internal/lifecycle"
+	"github.com/qiniu/zeroops/internal/region"
+)
+
+// streamHeartbeatInterval is the interval of the keep-alive comments of the issue stream.
+const streamHeartbeatInterval = 15 * time.Second
+
+// StreamIssues pushes issue changes as server-sent events (GET /v1/issues/stream): an
+// event named created, updated or closed per change, with the issue in its list form as
+// data, optionally only of one service, level or region. Changes are not replayed, so
+// clients list issues after connecting and again after reconnecting. The stream ends
+// when the client falls behind and when the server shuts down.
+func (api *IssueAPI) StreamIssues(c *fox.Context) {
+	p := apierror.Params(c)
+	svc := p.String("service", false)
+	level := p.String("level", false)
+	regionName := p.String("region", false)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if regionName != "" {
+		regionName = region.Normalize(regionName)
+		if err := region.Validate(regionName); err != nil {
+			writeError(c, apierror.Invalid("region", regionName, err.Error()))
+			return
+		}
+	}
+	ctx := c.Request.Context()
+	sub, cancel, err := api.Feed.Subscribe(ctx)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	defer cancel()
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+	shutdown := lifecycle.ShuttingDown(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-shutdown:
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		case msg, ok := <-sub:
+			if !ok {
+				return
+			}
+			var rec issueCacheRecord
+			if json.Unmarshal(msg.Issue, &rec) != nil {
+				continue
+			}
+			item := rec.listItem()
+			if svc != "" && issueService(&rec, item.Labels) != svc ||
+				level != "" && !strings.EqualFold(item.Level, level) ||
+				regionName != "" && item.Region != regionName {
+				continue
+			}
+			data, err := json.Marshal(item)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.Type, data); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
+
+// issueService is the cached service, or the service label of issues cached without it.
+func issueService(rec *issueCacheRecord, labels []Label) string {
+	if rec.Service != "" {
+		return rec.Service
+	}
+	for _, l := range labels {
+		if l.Key == "service" {
+			return l.Value
+		}
+	}
+	return ""
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"strconv"
@@ -11,23 +12,32 @@ import (
 
 	"github.com/fox-gonic/fox"
 	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/analysis"
+	"github.com/qiniu/zeroops/internal/alerting/service/feed"
+	"github.com/qiniu/zeroops/internal/alerting/service/slo"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/qiniu/zeroops/internal/region"
 	"github.com/redis/go-redis/v9"
 )
 
 type IssueAPI struct {
-	R  *redis.Client
-	DB *adb.Database
+	R    *redis.Client
+	DB   adb.Store
+	Feed *feed.Hub
 }
 
-// RegisterIssueRoutes registers issue query routes. If rdb is nil, a client is created from env.
-// db can be nil; when nil, comments will be empty.
-func RegisterIssueRoutes(router *fox.Engine, rdb *redis.Client, db *adb.Database) {
+// RegisterIssueRoutes registers issue routes. If rdb is nil, a client is created from env.
+// db can be nil; when nil, comments will be empty and ack/comment return 503.
+func RegisterIssueRoutes(router *fox.Engine, rdb *redis.Client, db adb.Store) {
 	if rdb == nil {
 		rdb = newRedisFromEnv()
 	}
-	api := &IssueAPI{R: rdb, DB: db}
+	api := &IssueAPI{R: rdb, DB: db, Feed: feed.NewHub(rdb)}
+	router.GET("/v1/issues/stream", api.StreamIssues)
 	router.GET("/v1/issues/:issueID", api.GetIssueByID)
 	router.GET("/v1/issues", api.ListIssues)
+	router.POST("/v1/issues/:issueID/ack", api.AckIssue)
+	router.POST("/v1/issues/:issueID/comments", api.CreateComment)
 }
 
 func newRedisFromEnv() *redis.Client {
@@ -45,7 +55,8 @@ func newRedisFromEnv() *redis.Client {
 	return redis.NewClient(&redis.Options{Addr: addr, Password: pass, DB: db})
 }
 
-type labelKV struct {
+// Label is one issue label.
+type Label struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
 }
@@ -58,64 +69,105 @@ type issueCacheRecord struct {
 	Title      string          `json:"title"`
 	Labels     json.RawMessage `json:"labels"`
 	AlertSince string          `json:"alertSince"`
+	AckedBy    string          `json:"ackedBy"`
+	AckedAt    string          `json:"ackedAt"`
+	Region     string          `json:"region"`
+	Service    string          `json:"service"`
+	SLOBudget  *slo.Budget     `json:"sloBudget"`
 }
 
-type issueDetailResponse struct {
-	ID         string    `json:"id"`
-	State      string    `json:"state"`
-	Level      string    `json:"level"`
-	AlertState string    `json:"alertState"`
-	Title      string    `json:"title"`
-	Labels     []labelKV `json:"labels"`
-	AlertSince string    `json:"alertSince"`
-	Comments   []comment `json:"comments"`
+// issueRegion is the cached region, or the one derived from labels for issues
+// cached before regions existed.
+func issueRegion(cached string, labels []Label) string {
+	if cached != "" {
+		return cached
+	}
+	m := make(map[string]string, len(labels))
+	for _, l := range labels {
+		m[l.Key] = l.Value
+	}
+	return region.FromLabels(m)
 }
 
-type comment struct {
+// IssueDetail is the response of GET /v1/issues/:issueID.
+type IssueDetail struct {
+	ID         string  `json:"id"`
+	State      string  `json:"state"`
+	Level      string  `json:"level"`
+	AlertState string  `json:"alertState"`
+	Title      string  `json:"title"`
+	Labels     []Label `json:"labels"`
+	Region     string  `json:"region"`
+	AlertSince string  `json:"alertSince"`
+	AckedBy    string  `json:"ackedBy,omitempty"`
+	AckedAt    string  `json:"ackedAt,omitempty"`
+	// SLOBudget is the error budget when the issue was opened by an SLO burn-rate alert.
+	SLOBudget *slo.Budget    `json:"sloBudget,omitempty"`
+	Comments  []IssueComment `json:"comments"`
+}
+
+// IssueComment is one comment on an issue.
+type IssueComment struct {
 	CreatedAt string `json:"createdAt"`
 	Content   string `json:"content"`
+	// Analysis is the structured analysis an AI analysis comment was rendered from.
+	Analysis *analysis.Result `json:"analysis,omitempty"`
 }
 
 func (api *IssueAPI) GetIssueByID(c *fox.Context) {
-	issueID := c.Param("issueID")
-	if issueID == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{"error": map[string]any{"code": "INVALID_PARAMETER", "message": "missing issueID"}})
+	p := apierror.Params(c)
+	issueID := p.Path("issueID")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
 		return
 	}
-	ctx := context.Background()
-	key := "alert:issue:" + issueID
-	val, err := api.R.Get(ctx, key).Result()
-	if err == redis.Nil || val == "" {
-		c.JSON(http.StatusNotFound, map[string]any{"error": map[string]any{"code": "NOT_FOUND", "message": "issue not found"}})
+	resp, found, err := api.loadIssue(c.Request.Context(), issueID)
+	if err != nil {
+		writeError(c, err)
 		return
 	}
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, map[string]any{"error": map[string]any{"code": "INTERNAL_ERROR", "message": err.Error()}})
+	if !found {
+		writeError(c, errIssueNotFound)
 		return
 	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// loadIssue reads an issue from the cache together with its comments; found is false
+// when the cache has no such issue.
+func (api *IssueAPI) loadIssue(ctx context.Context, issueID string) (*IssueDetail, bool, error) {
+	val, err := api.R.Get(ctx, "alert:issue:"+issueID).Result()
+	if errors.Is(err, redis.Nil) || (err == nil && val == "") {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
 
 	var record issueCacheRecord
 	if uerr := json.Unmarshal([]byte(val), &record); uerr != nil {
-		c.JSON(http.StatusInternalServerError, map[string]any{"error": map[string]any{"code": "INTERNAL_ERROR", "message": "invalid cache format"}})
-		return
+		return nil, false, errors.New("invalid cache format")
 	}
 
-	var labels []labelKV
+	var labels []Label
 	if len(record.Labels) > 0 {
 		_ = json.Unmarshal(record.Labels, &labels)
 	}
 
-	resp := issueDetailResponse{
+	return &IssueDetail{
 		ID:         record.ID,
 		State:      record.State,
 		Level:      record.Level,
 		AlertState: record.AlertState,
 		Title:      record.Title,
 		Labels:     labels,
+		Region:     issueRegion(record.Region, labels),
 		AlertSince: normalizeTimeString(record.AlertSince),
-		Comments:   api.fetchComments(c.Request.Context(), record.ID),
-	}
-	c.JSON(http.StatusOK, resp)
+		AckedBy:    record.AckedBy,
+		AckedAt:    normalizeTimeString(record.AckedAt),
+		SLOBudget:  record.SLOBudget,
+		Comments:   api.fetchComments(ctx, record.ID),
+	}, true, nil
 }
 
 func normalizeTimeString(s string) string {
@@ -131,85 +183,118 @@ func normalizeTimeString(s string) string {
 	return s
 }
 
-func (api *IssueAPI) fetchComments(ctx context.Context, issueID string) []comment {
+func (api *IssueAPI) fetchComments(ctx context.Context, issueID string) []IssueComment {
 	if api.DB == nil || issueID == "" {
-		return []comment{}
+		return []IssueComment{}
 	}
-	const q = `SELECT create_at, content FROM alert_issue_comments WHERE issue_id=$1 ORDER BY create_at ASC`
-	rows, err := api.DB.QueryContext(ctx, q, issueID)
+	rows, err := api.DB.ListComments(ctx, issueID)
 	if err != nil {
-		return []comment{}
-	}
-	defer rows.Close()
-	out := make([]comment, 0, 4)
-	for rows.Next() {
-		var t time.Time
-		var content string
-		if err := rows.Scan(&t, &content); err != nil {
-			continue
+		return []IssueComment{}
+	}
+	out := make([]IssueComment, 0, len(rows))
+	for _, c := range rows {
+		comment := IssueComment{CreatedAt: c.CreatedAt.UTC().Format(time.RFC3339Nano), Content: c.Content}
+		if len(c.Analysis) > 0 {
+			var res analysis.Result
+			if json.Unmarshal(c.Analysis, &res) == nil {
+				comment.Analysis = &res
+			}
 		}
-		out = append(out, comment{CreatedAt: t.UTC().Format(time.RFC3339Nano), Content: content})
+		out = append(out, comment)
 	}
 	return out
 }
 
-type listResponse struct {
-	Items []issueListItem `json:"items"`
+// listItem converts a cached issue to its list form.
+func (rec *issueCacheRecord) listItem() IssueListItem {
+	var labels []Label
+	if len(rec.Labels) > 0 {
+		_ = json.Unmarshal(rec.Labels, &labels)
+	}
+	return IssueListItem{
+		ID:         rec.ID,
+		State:      rec.State,
+		Level:      rec.Level,
+		AlertState: rec.AlertState,
+		Title:      rec.Title,
+		Labels:     labels,
+		Region:     issueRegion(rec.Region, labels),
+		AlertSince: normalizeTimeString(rec.AlertSince),
+		AckedBy:    rec.AckedBy,
+		AckedAt:    normalizeTimeString(rec.AckedAt),
+	}
+}
+
+// IssueList is the response of GET /v1/issues. Next is the cursor of the following page.
+type IssueList struct {
+	Items []IssueListItem `json:"items"`
 	Next  string          `json:"next,omitempty"`
 }
 
-type issueListItem struct {
-	ID         string    `json:"id"`
-	State      string    `json:"state"`
-	Level      string    `json:"level"`
-	AlertState string    `json:"alertState"`
-	Title      string    `json:"title"`
-	Labels     []labelKV `json:"labels"`
-	AlertSince string    `json:"alertSince"`
+// IssueListItem is one issue in IssueList.
+type IssueListItem struct {
+	ID         string  `json:"id"`
+	State      string  `json:"state"`
+	Level      string  `json:"level"`
+	AlertState string  `json:"alertState"`
+	Title      string  `json:"title"`
+	Labels     []Label `json:"labels"`
+	Region     string  `json:"region"`
+	AlertSince string  `json:"alertSince"`
+	AckedBy    string  `json:"ackedBy,omitempty"`
+	AckedAt    string  `json:"ackedAt,omitempty"`
 }
 
 func (api *IssueAPI) ListIssues(c *fox.Context) {
-	start := strings.TrimSpace(c.Query("start"))
-	limitStr := strings.TrimSpace(c.Query("limit"))
-	if limitStr == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{"error": map[string]any{"code": "INVALID_PARAMETER", "message": "limit is required"}})
-		return
-	}
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 || limit > 100 {
-		c.JSON(http.StatusBadRequest, map[string]any{"error": map[string]any{"code": "INVALID_PARAMETER", "message": "limit must be 1-100"}})
+	p := apierror.Params(c)
+	p.String("limit", true) // limit has no default
+	limit := p.Int("limit", 0, 1, 100)
+	state := p.Enum("state", "Open", "Open", "Closed")
+	svc := p.String("service", false)
+	regionName := p.String("region", false)
+	level := p.String("level", false)
+	start := p.String("start", false)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
 		return
 	}
-
-	state := strings.TrimSpace(c.Query("state"))
-	idxKey := "alert:index:open"
-	if state != "" {
-		if strings.EqualFold(state, "Open") {
-			idxKey = "alert:index:open"
-		} else if strings.EqualFold(state, "Closed") {
-			idxKey = "alert:index:closed"
-		} else {
-			c.JSON(http.StatusBadRequest, map[string]any{"error": map[string]any{"code": "INVALID_PARAMETER", "message": "state must be Open or Closed"}})
+	if regionName != "" {
+		regionName = region.Normalize(regionName)
+		if err := region.Validate(regionName); err != nil {
+			writeError(c, apierror.Invalid("region", regionName, err.Error()))
 			return
 		}
 	}
-
 	var cursor uint64
 	if start != "" {
-		if cv, err := strconv.ParseUint(start, 10, 64); err == nil {
-			cursor = cv
+		cv, err := strconv.ParseUint(start, 10, 64)
+		if err != nil {
+			writeError(c, apierror.Invalid("start", start, "start must be a cursor returned as next"))
+			return
 		}
+		cursor = cv
+	}
+
+	idxKey := "alert:index:" + strings.ToLower(state)
+	// service and region have their own open/closed indexes; level, and region when a
+	// service is given too, are filtered after loading, so a page may hold fewer than
+	// limit items while next is non-empty
+	switch {
+	case svc != "":
+		idxKey = "alert:index:svc:" + svc + ":" + strings.ToLower(state)
+	case regionName != "":
+		idxKey = "alert:index:region:" + regionName + ":" + strings.ToLower(state)
 	}
 
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	ids, nextCursor, err := api.R.SScan(ctx, idxKey, cursor, "", int64(limit)).Result()
-	if err != nil && err != redis.Nil {
-		c.JSON(http.StatusInternalServerError, map[string]any{"error": map[string]any{"code": "INTERNAL_ERROR", "message": err.Error()}})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		writeError(c, err)
 		return
 	}
 
 	if len(ids) == 0 {
-		c.JSON(http.StatusOK, listResponse{Items: []issueListItem{}, Next: ""})
+		c.JSON(http.StatusOK, IssueList{Items: []IssueListItem{}, Next: ""})
 		return
 	}
 
@@ -222,12 +307,12 @@ func (api *IssueAPI) ListIssues(c *fox.Context) {
 	}
 
 	vals, err := api.R.MGet(ctx, keys...).Result()
-	if err != nil && err != redis.Nil {
-		c.JSON(http.StatusInternalServerError, map[string]any{"error": map[string]any{"code": "INTERNAL_ERROR", "message": err.Error()}})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		writeError(c, err)
 		return
 	}
 
-	items := make([]issueListItem, 0, len(vals))
+	items := make([]IssueListItem, 0, len(vals))
 	for _, v := range vals {
 		if v == nil {
 			continue
@@ -242,24 +327,131 @@ func (api *IssueAPI) ListIssues(c *fox.Context) {
 			b, _ := json.Marshal(t)
 			_ = json.Unmarshal(b, &rec)
 		}
-		var labels []labelKV
-		if len(rec.Labels) > 0 {
-			_ = json.Unmarshal(rec.Labels, &labels)
+		if level != "" && !strings.EqualFold(rec.Level, level) {
+			continue
+		}
+		item := rec.listItem()
+		if regionName != "" && item.Region != regionName {
+			continue
 		}
-		items = append(items, issueListItem{
-			ID:         rec.ID,
-			State:      rec.State,
-			Level:      rec.Level,
-			AlertState: rec.AlertState,
-			Title:      rec.Title,
-			Labels:     labels,
-			AlertSince: normalizeTimeString(rec.AlertSince),
-		})
-	}
-
-	resp := listResponse{Items: items}
+		items = append(items, item)
+	}
+
+	resp := IssueList{Items: items}
 	if nextCursor != 0 {
 		resp.Next = strconv.FormatUint(nextCursor, 10)
 	}
 	c.JSON(http.StatusOK, resp)
 }
+
+// ackScript stamps the acknowledgement on the cached issue, keeping its TTL, and
+// publishes it on the issue feed.
+var ackScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if not v then return 0 end
+local obj = cjson.decode(v)
+obj.ackedBy = ARGV[1]
+obj.ackedAt = ARGV[2]
+redis.call('SET', KEYS[1], cjson.encode(obj), 'KEEPTTL')
+` + feed.Lua(feed.EventUpdated) + `
+return 1
+`)
+
+// AckIssue records that the operator in X-Operator is handling the issue
+// (POST /v1/issues/:issueID/ack) and returns the updated issue.
+func (api *IssueAPI) AckIssue(c *fox.Context) {
+	p := apierror.Params(c)
+	issueID := p.Path("issueID")
+	operator := p.Header("X-Operator", true)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.DB == nil {
+		writeError(c, errIssueStoreMissing)
+		return
+	}
+	ctx := c.Request.Context()
+	now := time.Now().UTC()
+	var found bool
+	// the acknowledgement and its history event are written together
+	err := api.DB.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		if found, err = api.DB.AckIssue(ctx, issueID, operator, now); err != nil || !found {
+			return err
+		}
+		_, err = api.DB.AddIssueEvent(ctx, &adb.IssueEvent{IssueID: issueID, At: now, Type: adb.EventAcked, Actor: operator})
+		return err
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if !found {
+		writeError(c, errIssueNotFound)
+		return
+	}
+	_, _ = ackScript.Run(ctx, api.R, []string{"alert:issue:" + issueID}, operator, now.Format(time.RFC3339Nano)).Result()
+
+	resp, found, err := api.loadIssue(ctx, issueID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if !found {
+		// acked in the database but already evicted from the cache
+		writeError(c, errIssueNotFound)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateCommentRequest is the body of POST /v1/issues/:issueID/comments.
+type CreateCommentRequest struct {
+	Content string `json:"content"`
+}
+
+// Validate rejects blank comments.
+func (r *CreateCommentRequest) Validate() error {
+	if strings.TrimSpace(r.Content) == "" {
+		return apierror.Invalid("content", "", "content is required")
+	}
+	return nil
+}
+
+// CreateComment adds a comment to an issue (POST /v1/issues/:issueID/comments).
+func (api *IssueAPI) CreateComment(c *fox.Context) {
+	p := apierror.Params(c)
+	issueID := p.Path("issueID")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	var req CreateCommentRequest
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.DB == nil {
+		writeError(c, errIssueStoreMissing)
+		return
+	}
+	ctx := c.Request.Context()
+	if n, err := api.R.Exists(ctx, "alert:issue:"+issueID).Result(); err != nil {
+		writeError(c, err)
+		return
+	} else if n == 0 {
+		writeError(c, errIssueNotFound)
+		return
+	}
+	added, err := api.DB.AddComment(ctx, issueID, req.Content)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if !added {
+		writeError(c, apierror.New(apierror.Conflict, "issue already has this comment"))
+		return
+	}
+	c.JSON(http.StatusCreated, IssueComment{CreatedAt: time.Now().UTC().Format(time.RFC3339Nano), Content: req.Content})
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/alerting/service/report"
+	"github.com/qiniu/zeroops/internal/apierror"
+)
+
+type ReportAPI struct {
+	svc *report.Service
+}
+
+// ReportList is the response of GET /v1/reports.
+type ReportList struct {
+	Items []report.Summary `json:"items"`
+}
+
+const markdownContentType = "text/markdown; charset=utf-8"
+
+// RegisterReportRoutes registers postmortem report routes. svc can be nil; the routes
+// then return 503.
+func RegisterReportRoutes(router *fox.Engine, svc *report.Service) {
+	api := &ReportAPI{svc: svc}
+	router.GET("/v1/issues/:issueID/report", api.GenerateReport)
+	router.POST("/v1/issues/:issueID/report", api.SaveReport)
+	router.GET("/v1/reports", api.ListReports)
+	router.GET("/v1/reports/:issueID", api.GetReport)
+}
+
+// GenerateReport builds the report of an issue from its current history, as JSON or
+// with ?format=markdown as a Markdown postmortem draft.
+func (api *ReportAPI) GenerateReport(c *fox.Context) {
+	p := apierror.Params(c)
+	issueID := p.Path("issueID")
+	format := p.Enum("format", "json", "json", "markdown")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errReportStoreMissing)
+		return
+	}
+	r, err := api.svc.Generate(c.Request.Context(), issueID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if format == "markdown" {
+		c.Data(http.StatusOK, markdownContentType, []byte(r.Markdown()))
+		return
+	}
+	c.JSON(http.StatusOK, r)
+}
+
+// SaveReport generates the report of an issue and stores it as created by X-Operator,
+// replacing an earlier one.
+func (api *ReportAPI) SaveReport(c *fox.Context) {
+	p := apierror.Params(c)
+	issueID := p.Path("issueID")
+	operator := p.Header("X-Operator", false)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errReportStoreMissing)
+		return
+	}
+	stored, err := api.svc.Save(c.Request.Context(), issueID, operator)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, stored)
+}
+
+// ListReports lists stored reports newest first, of one service with ?service=.
+func (api *ReportAPI) ListReports(c *fox.Context) {
+	p := apierror.Params(c)
+	service := p.String("service", false)
+	limit := p.Int("limit", report.DefaultListLimit, 1, report.MaxListLimit)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errReportStoreMissing)
+		return
+	}
+	items, err := api.svc.List(c.Request.Context(), service, limit)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ReportList{Items: items})
+}
+
+// GetReport returns the stored report of an issue; ?format=markdown renders the report.
+func (api *ReportAPI) GetReport(c *fox.Context) {
+	p := apierror.Params(c)
+	issueID := p.Path("issueID")
+	format := p.Enum("format", "json", "json", "markdown")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errReportStoreMissing)
+		return
+	}
+	stored, err := api.svc.Get(c.Request.Context(), issueID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	if format == "markdown" {
+		c.Data(http.StatusOK, markdownContentType, []byte(stored.Report.Markdown()))
+		return
+	}
+	c.JSON(http.StatusOK, stored)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fox-gonic/fox"
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/silence"
+	"github.com/qiniu/zeroops/internal/apierror"
+)
+
+type SilenceAPI struct {
+	svc *silence.Service
+}
+
+// SilenceList is the response of GET /v1/silences.
+type SilenceList struct {
+	Items []silence.Silence `json:"items"`
+}
+
+// RegisterSilenceRoutes registers silence management routes. db can be nil; the routes
+// then return 503.
+func RegisterSilenceRoutes(router *fox.Engine, db adb.Store) {
+	api := &SilenceAPI{}
+	if db != nil {
+		api.svc = silence.NewService(db)
+	}
+	router.GET("/v1/silences", api.ListSilences)
+	router.POST("/v1/silences", api.CreateSilence)
+	router.DELETE("/v1/silences/:silenceID", api.ExpireSilence)
+}
+
+// ListSilences lists pending and active silences, and expired ones with ?expired=true.
+func (api *SilenceAPI) ListSilences(c *fox.Context) {
+	p := apierror.Params(c)
+	includeExpired := p.Bool("expired", false)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errSilenceStoreMissing)
+		return
+	}
+	items, err := api.svc.List(c.Request.Context(), includeExpired)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, SilenceList{Items: items})
+}
+
+// CreateSilence creates a silence; createdBy defaults to the X-Operator header.
+func (api *SilenceAPI) CreateSilence(c *fox.Context) {
+	var req silence.CreateRequest
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		writeError(c, err)
+		return
+	}
+	if req.CreatedBy == "" {
+		req.CreatedBy = apierror.Params(c).Header("X-Operator", false)
+	}
+	if api.svc == nil {
+		writeError(c, errSilenceStoreMissing)
+		return
+	}
+	sil, err := api.svc.Create(c.Request.Context(), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, sil)
+}
+
+// ExpireSilence ends a silence now (DELETE /v1/silences/:silenceID).
+func (api *SilenceAPI) ExpireSilence(c *fox.Context) {
+	p := apierror.Params(c)
+	id := p.Path("silenceID")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errSilenceStoreMissing)
+		return
+	}
+	if err := api.svc.Expire(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
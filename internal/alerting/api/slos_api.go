@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/alerting/service/slo"
+	"github.com/qiniu/zeroops/internal/apierror"
+)
+
+type SLOAPI struct {
+	svc *slo.Service
+}
+
+// SLOList is the response of GET /v1/slos.
+type SLOList struct {
+	Items []slo.SLO `json:"items"`
+}
+
+// BudgetList is the response of GET /v1/slos/budget.
+type BudgetList struct {
+	Items []slo.Budget `json:"items"`
+}
+
+// RegisterSLORoutes registers SLO management, rule generation and error budget routes.
+// svc can be nil; the routes then return 503.
+func RegisterSLORoutes(router *fox.Engine, svc *slo.Service) {
+	api := &SLOAPI{svc: svc}
+	router.GET("/v1/slos", api.ListSLOs)
+	router.GET("/v1/slos/rules", api.GetSLORules)
+	router.GET("/v1/slos/budget", api.GetSLOBudgets)
+	router.PUT("/v1/slos/:service/:name", api.PutSLO)
+	router.DELETE("/v1/slos/:service/:name", api.DeleteSLO)
+}
+
+// ListSLOs lists SLOs, of one service with ?service=.
+func (api *SLOAPI) ListSLOs(c *fox.Context) {
+	p := apierror.Params(c)
+	service := p.String("service", false)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errSLOStoreMissing)
+		return
+	}
+	items, err := api.svc.List(c.Request.Context(), service)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, SLOList{Items: items})
+}
+
+// PutSLO creates or replaces an SLO (PUT /v1/slos/:service/:name).
+func (api *SLOAPI) PutSLO(c *fox.Context) {
+	p := apierror.Params(c)
+	service, name := p.Path("service"), p.Path("name")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	var req slo.PutRequest
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errSLOStoreMissing)
+		return
+	}
+	s, err := api.svc.Put(c.Request.Context(), service, name, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, s)
+}
+
+// DeleteSLO removes an SLO; its rules disappear from the next rule generation.
+func (api *SLOAPI) DeleteSLO(c *fox.Context) {
+	p := apierror.Params(c)
+	service, name := p.Path("service"), p.Path("name")
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errSLOStoreMissing)
+		return
+	}
+	if err := api.svc.Delete(c.Request.Context(), service, name); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetSLORules returns the recording and burn-rate alerting rules of every SLO as a
+// Prometheus rule file in JSON, which is also valid YAML.
+func (api *SLOAPI) GetSLORules(c *fox.Context) {
+	if api.svc == nil {
+		writeError(c, errSLOStoreMissing)
+		return
+	}
+	rules, err := api.svc.Rules(c.Request.Context())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// GetSLOBudgets reports the remaining error budget of the SLOs, of one service with
+// ?service=. It returns 503 when Prometheus is not configured.
+func (api *SLOAPI) GetSLOBudgets(c *fox.Context) {
+	p := apierror.Params(c)
+	service := p.String("service", false)
+	if err := p.Err(); err != nil {
+		writeError(c, err)
+		return
+	}
+	if api.svc == nil {
+		writeError(c, errSLOStoreMissing)
+		return
+	}
+	items, err := api.svc.Budgets(c.Request.Context(), service)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, BudgetList{Items: items})
+}
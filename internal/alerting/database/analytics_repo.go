@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (d *Database) ListIssueFacts(ctx context.Context, from, to time.Time) ([]IssueFact, error) {
+	const q = `
+SELECT i.id, i.level, i.labels, i.fingerprint, i.alert_since, c.at, COALESCE(a.at, i.acked_at), r.at, COALESCE(r.actor, ''),
+	EXISTS (SELECT 1 FROM alert_issue_events e WHERE e.issue_id = i.id AND e.type = $6)
+FROM alert_issues i
+LEFT JOIN LATERAL (SELECT at FROM alert_issue_events e WHERE e.issue_id = i.id AND e.type = $3 ORDER BY at LIMIT 1) c ON TRUE
+LEFT JOIN LATERAL (SELECT at FROM alert_issue_events e WHERE e.issue_id = i.id AND e.type = $4 ORDER BY at LIMIT 1) a ON TRUE
+LEFT JOIN LATERAL (SELECT at, actor FROM alert_issue_events e
+	WHERE e.issue_id = i.id AND e.type = $5 AND e.to_state IN ('Restored', 'AutoRestored') ORDER BY at LIMIT 1) r ON TRUE
+WHERE i.alert_since >= $1 AND i.alert_since < $2`
+	rows, err := d.QueryContext(ctx, q, from, to, EventCreated, EventAcked, EventState, EventRemediation)
+	if err != nil {
+		return nil, fmt.Errorf("list issue facts: %w", err)
+	}
+	defer rows.Close()
+	var out []IssueFact
+	for rows.Next() {
+		var f IssueFact
+		var labels string
+		if err := rows.Scan(&f.ID, &f.Level, &labels, &f.Fingerprint, &f.AlertSince, &f.CreatedAt, &f.AckedAt,
+			&f.RestoredAt, &f.RestoredBy, &f.Remediated); err != nil {
+			return nil, err
+		}
+		f.Labels = []byte(labels)
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
+
+// rollupInsertBatch bounds the rows of one INSERT, like issueInsertBatch.
+const rollupInsertBatch = 500
+
+func (d *Database) ReplaceRollups(ctx context.Context, from, to time.Time, issues []IssueRollup, fingerprints []FingerprintRollup) error {
+	return d.InTx(ctx, func(ctx context.Context) error {
+		for _, table := range []string{"alert_issue_rollups", "alert_fingerprint_rollups"} {
+			if _, err := d.ExecContext(ctx, `DELETE FROM `+table+` WHERE day >= $1 AND day < $2`, from, to); err != nil {
+				return fmt.Errorf("delete %s: %w", table, err)
+			}
+		}
+		err := d.insertRows(ctx, `INSERT INTO alert_issue_rollups (day, service, alertname, level, issues, acked, ack_seconds,
+	ack_buckets, restored, restore_seconds, restore_buckets, remediated, auto_restored, updated_at) VALUES `, 14, len(issues),
+			func(i int) []any {
+				r := &issues[i]
+				return []any{r.Day, r.Service, r.AlertName, r.Level, r.Issues, r.Acked, r.AckSeconds, bucketsJSON(r.AckBuckets),
+					r.Restored, r.RestoreSeconds, bucketsJSON(r.RestoreBuckets), r.Remediated, r.AutoRestored, r.UpdatedAt}
+			})
+		if err != nil {
+			return fmt.Errorf("insert alert_issue_rollups: %w", err)
+		}
+		err = d.insertRows(ctx, `INSERT INTO alert_fingerprint_rollups (day, fingerprint, service, alertname, level, issues) VALUES `,
+			6, len(fingerprints), func(i int) []any {
+				r := &fingerprints[i]
+				return []any{r.Day, r.Fingerprint, r.Service, r.AlertName, r.Level, r.Issues}
+			})
+		if err != nil {
+			return fmt.Errorf("insert alert_fingerprint_rollups: %w", err)
+		}
+		return nil
+	})
+}
+
+// insertRows inserts n rows of the given number of columns in batches.
+func (d *Database) insertRows(ctx context.Context, insert string, columns, n int, args func(i int) []any) error {
+	for start := 0; start < n; start += rollupInsertBatch {
+		end := min(start+rollupInsertBatch, n)
+		var q strings.Builder
+		q.WriteString(insert)
+		values := make([]any, 0, (end-start)*columns)
+		for i := start; i < end; i++ {
+			if i > start {
+				q.WriteString(", ")
+			}
+			q.WriteByte('(')
+			for j := range columns {
+				if j > 0 {
+					q.WriteString(", ")
+				}
+				fmt.Fprintf(&q, "$%d", len(values)+j+1)
+			}
+			q.WriteByte(')')
+			values = append(values, args(i)...)
+		}
+		if _, err := d.ExecContext(ctx, q.String(), values...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Database) ListIssueRollups(ctx context.Context, from, to time.Time, f RollupFilter) ([]IssueRollup, error) {
+	where, args := rollupWhere(from, to, f)
+	q := `SELECT day, service, alertname, level, issues, acked, ack_seconds, ack_buckets, restored, restore_seconds,
+	restore_buckets, remediated, auto_restored, updated_at
+FROM alert_issue_rollups` + where + ` ORDER BY day, service, alertname, level`
+	rows, err := d.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list alert_issue_rollups: %w", err)
+	}
+	defer rows.Close()
+	var out []IssueRollup
+	for rows.Next() {
+		var r IssueRollup
+		var ack, restore string
+		if err := rows.Scan(&r.Day, &r.Service, &r.AlertName, &r.Level, &r.Issues, &r.Acked, &r.AckSeconds, &ack,
+			&r.Restored, &r.RestoreSeconds, &restore, &r.Remediated, &r.AutoRestored, &r.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(ack), &r.AckBuckets); err != nil {
+			return nil, fmt.Errorf("decode ack_buckets: %w", err)
+		}
+		if err := json.Unmarshal([]byte(restore), &r.RestoreBuckets); err != nil {
+			return nil, fmt.Errorf("decode restore_buckets: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (d *Database) ListFingerprintRollups(ctx context.Context, from, to time.Time, f RollupFilter) ([]FingerprintRollup, error) {
+	where, args := rollupWhere(from, to, f)
+	q := `SELECT day, fingerprint, service, alertname, level, issues FROM alert_fingerprint_rollups` + where +
+		` ORDER BY day, fingerprint`
+	rows, err := d.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list alert_fingerprint_rollups: %w", err)
+	}
+	defer rows.Close()
+	var out []FingerprintRollup
+	for rows.Next() {
+		var r FingerprintRollup
+		if err := rows.Scan(&r.Day, &r.Fingerprint, &r.Service, &r.AlertName, &r.Level, &r.Issues); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func rollupWhere(from, to time.Time, f RollupFilter) (string, []any) {
+	where := ` WHERE day >= $1 AND day < $2`
+	args := []any{from, to}
+	for _, c := range []struct{ column, value string }{{"service", f.Service}, {"alertname", f.AlertName}, {"level", f.Level}} {
+		if c.value != "" {
+			args = append(args, c.value)
+			where += ` AND ` + c.column + ` = $` + strconv.Itoa(len(args))
+		}
+	}
+	return where, args
+}
+
+func bucketsJSON(b []int64) string {
+	if b == nil {
+		return "[]"
+	}
+	data, _ := json.Marshal(b)
+	return string(data)
+}
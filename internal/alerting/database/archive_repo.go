@@ -0,0 +1,234 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ErrAlertConflict is returned by RestoreIssue when another issue is stored for the same alert.
+var ErrAlertConflict = errors.New("another issue is stored for the alert")
+
+func (d *Database) ListClosedIssues(ctx context.Context, f ClosedIssueFilter, limit int) ([]Issue, error) {
+	q := issueSelect + ` WHERE state = 'Closed' AND alert_since < $1`
+	args := []any{f.Before}
+	if len(f.Levels) > 0 {
+		args = append(args, f.Levels)
+		q += ` AND level = ANY($` + strconv.Itoa(len(args)) + `)`
+	}
+	if len(f.ExceptLevels) > 0 {
+		args = append(args, f.ExceptLevels)
+		q += ` AND NOT (level = ANY($` + strconv.Itoa(len(args)) + `))`
+	}
+	args = append(args, limit)
+	q += ` ORDER BY alert_since ASC, id ASC LIMIT $` + strconv.Itoa(len(args)) + ` FOR UPDATE SKIP LOCKED`
+	rows, err := d.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list closed alert_issues: %w", err)
+	}
+	defer rows.Close()
+	var out []Issue
+	for rows.Next() {
+		it, err := scanIssue(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *it)
+	}
+	return out, rows.Err()
+}
+
+// DeleteIssues also deletes the rows of the issues in the tables that referenced
+// alert_issues before it was partitioned.
+func (d *Database) DeleteIssues(ctx context.Context, ids []string) (int, error) {
+	var n int64
+	err := d.InTx(ctx, func(ctx context.Context) error {
+		for _, table := range []string{"alert_issue_comments", "alert_issue_events", "alert_issue_reports"} {
+			if _, err := d.ExecContext(ctx, `DELETE FROM `+table+` WHERE issue_id = ANY($1)`, ids); err != nil {
+				return fmt.Errorf("delete %s: %w", table, err)
+			}
+		}
+		res, err := d.ExecContext(ctx, `DELETE FROM alert_issues WHERE id = ANY($1)`, ids)
+		if err != nil {
+			return fmt.Errorf("delete alert_issues: %w", err)
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	return int(n), err
+}
+
+func (d *Database) RestoreIssue(ctx context.Context, r *IssueRecord) (bool, error) {
+	const q = `
+	INSERT INTO alert_issues
+		(id, state, level, alert_state, title, labels, alert_since, trace_parent, region, fingerprint, starts_at, slo_budget,
+		acked_by, acked_at)
+	SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+	WHERE NOT EXISTS (SELECT 1 FROM alert_issues WHERE id = $1)
+	ON CONFLICT DO NOTHING`
+	var restored bool
+	err := d.InTx(ctx, func(ctx context.Context) error {
+		res, err := d.ExecContext(ctx, q, append(issueArgs(&r.Issue), r.Issue.AckedBy, r.Issue.AckedAt)...)
+		if err != nil {
+			return fmt.Errorf("restore alert_issue: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// either the issue is stored already or the insert hit the unique alert index
+			var exists bool
+			if err := d.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM alert_issues WHERE id = $1)`, r.Issue.ID).Scan(&exists); err != nil {
+				return fmt.Errorf("check alert_issue: %w", err)
+			}
+			if !exists {
+				return ErrAlertConflict
+			}
+			return nil
+		}
+		restored = true
+
+		err = d.insertRows(ctx, `INSERT INTO alert_issue_comments (issue_id, create_at, content, analysis) VALUES `, 4,
+			len(r.Comments), func(i int) []any {
+				c := &r.Comments[i]
+				var analysis any
+				if len(c.Analysis) > 0 {
+					analysis = string(c.Analysis)
+				}
+				return []any{r.Issue.ID, c.CreatedAt, c.Content, analysis}
+			})
+		if err != nil {
+			return fmt.Errorf("restore alert_issue_comments: %w", err)
+		}
+		err = d.insertRows(ctx, `INSERT INTO alert_issue_events (issue_id, at, type, actor, from_state, to_state, detail) VALUES `, 7,
+			len(r.Events), func(i int) []any {
+				e := &r.Events[i]
+				return []any{r.Issue.ID, e.At, e.Type, e.Actor, e.From, e.To, e.Detail}
+			})
+		if err != nil {
+			return fmt.Errorf("restore alert_issue_events: %w", err)
+		}
+		if r.Report != nil {
+			return d.UpsertIssueReport(ctx, r.Report)
+		}
+		return nil
+	})
+	return restored, err
+}
+
+func (d *Database) InsertArchivedIssues(ctx context.Context, issues []ArchivedIssue) error {
+	const q = `
+	INSERT INTO alert_issue_archive (id, level, alert_since, archived_at, record)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (id) DO UPDATE SET
+		level = EXCLUDED.level,
+		alert_since = EXCLUDED.alert_since,
+		archived_at = EXCLUDED.archived_at,
+		record = EXCLUDED.record`
+	return d.InTx(ctx, func(ctx context.Context) error {
+		for _, a := range issues {
+			if _, err := d.ExecContext(ctx, q, a.ID, a.Level, a.AlertSince, a.ArchivedAt, string(a.Record)); err != nil {
+				return fmt.Errorf("insert alert_issue_archive: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (d *Database) ListArchivedIssues(ctx context.Context, from, to time.Time, afterID string, limit int) ([]ArchivedIssue, error) {
+	const q = `SELECT id, level, alert_since, archived_at, record FROM alert_issue_archive
+WHERE alert_since >= $1 AND alert_since < $2 AND id > $3
+ORDER BY id ASC
+LIMIT $4`
+	rows, err := d.QueryContext(ctx, q, from, to, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list alert_issue_archive: %w", err)
+	}
+	defer rows.Close()
+	var out []ArchivedIssue
+	for rows.Next() {
+		var a ArchivedIssue
+		var record string
+		if err := rows.Scan(&a.ID, &a.Level, &a.AlertSince, &a.ArchivedAt, &record); err != nil {
+			return nil, err
+		}
+		a.Record = []byte(record)
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+func (d *Database) DeleteArchivedIssues(ctx context.Context, ids []string) error {
+	if _, err := d.ExecContext(ctx, `DELETE FROM alert_issue_archive WHERE id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("delete alert_issue_archive: %w", err)
+	}
+	return nil
+}
+
+// EnsureIssuePartition creates the alert_issues partition of the month of t unless it
+// exists, see alert_issues_ensure_partition, and reports whether it was created.
+func (d *Database) EnsureIssuePartition(ctx context.Context, t time.Time) (bool, error) {
+	var created bool
+	err := d.QueryRowContext(ctx, `SELECT alert_issues_ensure_partition($1::date)`, t.UTC().Format(time.DateOnly)).Scan(&created)
+	if err != nil {
+		return false, fmt.Errorf("ensure alert_issues partition: %w", err)
+	}
+	return created, nil
+}
+
+// issuePartition matches the names of the monthly partitions of alert_issues.
+var issuePartition = regexp.MustCompile(`^alert_issues_[0-9]{6}$`)
+
+// DropEmptyIssuePartitions drops the empty monthly partitions of alert_issues before the
+// month of t and returns their names. Issues of those months inserted later, e.g. when
+// restored, go to the default partition.
+func (d *Database) DropEmptyIssuePartitions(ctx context.Context, t time.Time) ([]string, error) {
+	const q = `SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'alert_issues'::regclass ORDER BY c.relname`
+	rows, err := d.QueryContext(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("list alert_issues partitions: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if issuePartition.MatchString(name) && name < "alert_issues_"+t.UTC().Format("200601") {
+			names = append(names, name)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var dropped []string
+	for _, name := range names {
+		err := d.InTx(ctx, func(ctx context.Context) error {
+			// the lock keeps inserts out between the check and the drop
+			if _, err := d.ExecContext(ctx, `LOCK TABLE `+name+` IN ACCESS EXCLUSIVE MODE`); err != nil {
+				return err
+			}
+			var used bool
+			if err := d.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM `+name+`)`).Scan(&used); err != nil || used {
+				return err
+			}
+			if _, err := d.ExecContext(ctx, `DROP TABLE `+name); err != nil {
+				return err
+			}
+			dropped = append(dropped, name)
+			return nil
+		})
+		if err != nil {
+			return dropped, fmt.Errorf("drop partition %s: %w", name, err)
+		}
+	}
+	return dropped, nil
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+func (d *Database) InsertAuditLog(ctx context.Context, l *AuditLog) error {
+	const q = `
+	INSERT INTO audit_logs (at, actor, action, target, remote_addr, detail)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	if _, err := d.ExecContext(ctx, q, l.At, l.Actor, l.Action, l.Target, l.RemoteAddr, l.Detail); err != nil {
+		return fmt.Errorf("insert audit_log: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) ListAuditLogs(ctx context.Context, f AuditFilter, limit int) ([]AuditLog, error) {
+	q := `SELECT id, at, actor, action, target, remote_addr, detail FROM audit_logs WHERE TRUE`
+	var args []any
+	if f.Action != "" {
+		args = append(args, f.Action)
+		q += ` AND action = $` + strconv.Itoa(len(args))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		q += ` AND at >= $` + strconv.Itoa(len(args))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		q += ` AND at < $` + strconv.Itoa(len(args))
+	}
+	args = append(args, limit)
+	q += ` ORDER BY at DESC, id DESC LIMIT $` + strconv.Itoa(len(args))
+	rows, err := d.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list audit_logs: %w", err)
+	}
+	defer rows.Close()
+	var out []AuditLog
+	for rows.Next() {
+		var l AuditLog
+		if err := rows.Scan(&l.ID, &l.At, &l.Actor, &l.Action, &l.Target, &l.RemoteAddr, &l.Detail); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
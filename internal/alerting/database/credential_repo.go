@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const credentialSelect = `SELECT id, integration, kind, secret, created_by, created_at, expires_at
+FROM alert_webhook_credentials`
+
+func scanCredential(row interface{ Scan(dest ...any) error }) (*WebhookCredential, error) {
+	var c WebhookCredential
+	if err := row.Scan(&c.ID, &c.Integration, &c.Kind, &c.Secret, &c.CreatedBy, &c.CreatedAt, &c.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (d *Database) InsertWebhookCredential(ctx context.Context, c *WebhookCredential) error {
+	const q = `
+	INSERT INTO alert_webhook_credentials (id, integration, kind, secret, created_by, created_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	if _, err := d.ExecContext(ctx, q, c.ID, c.Integration, c.Kind, c.Secret, c.CreatedBy, c.CreatedAt, c.ExpiresAt); err != nil {
+		return fmt.Errorf("insert alert_webhook_credential: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) ListWebhookCredentials(ctx context.Context, integration string, activeAt time.Time) ([]WebhookCredential, error) {
+	const q = credentialSelect + `
+WHERE ($1 = '' OR integration = $1)
+  AND ($2::timestamp IS NULL OR expires_at IS NULL OR expires_at > $2)
+ORDER BY integration ASC, created_at ASC`
+	var at any
+	if !activeAt.IsZero() {
+		at = activeAt
+	}
+	rows, err := d.QueryContext(ctx, q, integration, at)
+	if err != nil {
+		return nil, fmt.Errorf("list alert_webhook_credentials: %w", err)
+	}
+	defer rows.Close()
+	var out []WebhookCredential
+	for rows.Next() {
+		c, err := scanCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return out, rows.Err()
+}
+
+func (d *Database) GetWebhookCredential(ctx context.Context, id string) (*WebhookCredential, error) {
+	c, err := scanCredential(d.QueryRowContext(ctx, credentialSelect+` WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get alert_webhook_credential: %w", err)
+	}
+	return c, nil
+}
+
+func (d *Database) ExpireWebhookCredential(ctx context.Context, id string, at time.Time) (bool, error) {
+	const q = `UPDATE alert_webhook_credentials SET expires_at = LEAST(COALESCE(expires_at, $2), $2) WHERE id = $1`
+	res, err := d.ExecContext(ctx, q, id, at)
+	if err != nil {
+		return false, fmt.Errorf("expire alert_webhook_credential: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+func (d *Database) AddIssueEvent(ctx context.Context, e *IssueEvent) (bool, error) {
+	const q = `INSERT INTO alert_issue_events (issue_id, at, type, actor, from_state, to_state, detail)
+SELECT $1, $2, $3, $4, $5, $6, $7
+WHERE NOT EXISTS (SELECT 1 FROM alert_issue_events WHERE issue_id=$1 AND type=$3 AND to_state=$6 AND detail=$7)`
+	res, err := d.ExecContext(ctx, q, e.IssueID, e.At, e.Type, e.Actor, e.From, e.To, e.Detail)
+	if err != nil {
+		return false, fmt.Errorf("insert alert_issue_event: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (d *Database) ListIssueEvents(ctx context.Context, issueID string) ([]IssueEvent, error) {
+	const q = `SELECT at, type, actor, from_state, to_state, detail FROM alert_issue_events
+WHERE issue_id=$1 ORDER BY at ASC, id ASC`
+	rows, err := d.QueryContext(ctx, q, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []IssueEvent
+	for rows.Next() {
+		e := IssueEvent{IssueID: issueID}
+		if err := rows.Scan(&e.At, &e.Type, &e.Actor, &e.From, &e.To, &e.Detail); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func (d *Database) UpsertIssueReport(ctx context.Context, r *IssueReport) error {
+	const q = `
+	INSERT INTO alert_issue_reports (issue_id, service, created_at, created_by, report)
+	VALUES ($1, $2, $3, $4, $5)
+	ON CONFLICT (issue_id) DO UPDATE SET
+		service = EXCLUDED.service,
+		created_at = EXCLUDED.created_at,
+		created_by = EXCLUDED.created_by,
+		report = EXCLUDED.report`
+	if _, err := d.ExecContext(ctx, q, r.IssueID, r.Service, r.CreatedAt, r.CreatedBy, string(r.Report)); err != nil {
+		return fmt.Errorf("upsert alert_issue_report: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) GetIssueReport(ctx context.Context, issueID string) (*IssueReport, error) {
+	const q = `SELECT issue_id, service, created_at, created_by, report FROM alert_issue_reports WHERE issue_id=$1`
+	var r IssueReport
+	var report string
+	err := d.QueryRowContext(ctx, q, issueID).Scan(&r.IssueID, &r.Service, &r.CreatedAt, &r.CreatedBy, &report)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get alert_issue_report: %w", err)
+	}
+	r.Report = []byte(report)
+	return &r, nil
+}
+
+func (d *Database) ListIssueReports(ctx context.Context, service string, limit int) ([]IssueReport, error) {
+	q := `SELECT issue_id, service, created_at, created_by, report FROM alert_issue_reports`
+	args := []any{}
+	if service != "" {
+		args = append(args, service)
+		q += ` WHERE service = $1`
+	}
+	args = append(args, limit)
+	q += ` ORDER BY created_at DESC, issue_id ASC LIMIT $` + strconv.Itoa(len(args))
+	rows, err := d.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []IssueReport
+	for rows.Next() {
+		var r IssueReport
+		var report string
+		if err := rows.Scan(&r.IssueID, &r.Service, &r.CreatedAt, &r.CreatedBy, &report); err != nil {
+			return nil, err
+		}
+		r.Report = []byte(report)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,277 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/region"
+)
+
+func (d *Database) InsertIssue(ctx context.Context, issue *Issue) error {
+	const q = `
+	INSERT INTO alert_issues
+		(id, state, level, alert_state, title, labels, alert_since, trace_parent, region, fingerprint, starts_at, slo_budget)
+	VALUES
+		($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	if _, err := d.ExecContext(ctx, q, issueArgs(issue)...); err != nil {
+		return fmt.Errorf("insert alert_issue: %w", err)
+	}
+	return nil
+}
+
+// issueInsertBatch bounds the rows of one INSERT to stay well below the 65535 bind
+// parameters Postgres allows per statement.
+const issueInsertBatch = 500
+
+func (d *Database) InsertIssues(ctx context.Context, issues []*Issue) ([]bool, error) {
+	inserted := make([]bool, len(issues))
+	for start := 0; start < len(issues); start += issueInsertBatch {
+		batch := issues[start:min(start+issueInsertBatch, len(issues))]
+		var q strings.Builder
+		q.WriteString(`INSERT INTO alert_issues
+	(id, state, level, alert_state, title, labels, alert_since, trace_parent, region, fingerprint, starts_at, slo_budget)
+VALUES `)
+		args := make([]any, 0, len(batch)*issueColumns)
+		for i, issue := range batch {
+			if i > 0 {
+				q.WriteString(", ")
+			}
+			q.WriteByte('(')
+			for j := range issueColumns {
+				if j > 0 {
+					q.WriteString(", ")
+				}
+				fmt.Fprintf(&q, "$%d", len(args)+j+1)
+			}
+			q.WriteByte(')')
+			args = append(args, issueArgs(issue)...)
+		}
+		q.WriteString(`
+ON CONFLICT (fingerprint, starts_at, alert_since) WHERE fingerprint <> '' DO NOTHING
+RETURNING id`)
+
+		rows, err := d.QueryContext(ctx, q.String(), args...)
+		if err != nil {
+			return nil, fmt.Errorf("insert alert_issues: %w", err)
+		}
+		ids := make(map[string]bool, len(batch))
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("insert alert_issues: %w", err)
+			}
+			ids[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("insert alert_issues: %w", err)
+		}
+		for i, issue := range batch {
+			inserted[start+i] = ids[issue.ID]
+		}
+	}
+	return inserted, nil
+}
+
+// issueColumns is the number of values issueArgs returns.
+const issueColumns = 12
+
+func issueArgs(issue *Issue) []any {
+	startsAt := issue.StartsAt
+	if startsAt.IsZero() {
+		startsAt = issue.AlertSince
+	}
+	var budget any
+	if len(issue.SLOBudget) > 0 {
+		budget = string(issue.SLOBudget)
+	}
+	return []any{issue.ID, issue.State, issue.Level, issue.AlertState, issue.Title, string(issue.Labels),
+		issue.AlertSince, issue.TraceParent, issueRegion(issue), issue.Fingerprint, startsAt, budget}
+}
+
+func (d *Database) ListPendingIssues(ctx context.Context, limit int) ([]Issue, error) {
+	const q = `SELECT id, state, level, alert_state, title, labels, alert_since, trace_parent, region
+FROM alert_issues
+WHERE alert_state = 'Pending'
+ORDER BY alert_since ASC
+LIMIT $1`
+	rows, err := d.QueryContext(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Issue, 0, limit)
+	for rows.Next() {
+		var it Issue
+		var labels string
+		if err := rows.Scan(&it.ID, &it.State, &it.Level, &it.AlertState, &it.Title, &labels, &it.AlertSince, &it.TraceParent, &it.Region); err != nil {
+			return nil, err
+		}
+		it.Labels = []byte(labels)
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+// issueSelect selects the columns scanIssue reads.
+const issueSelect = `SELECT id, state, level, alert_state, title, labels, alert_since, trace_parent, region,
+	fingerprint, starts_at, acked_by, acked_at, slo_budget
+FROM alert_issues`
+
+func scanIssue(row interface{ Scan(dest ...any) error }) (*Issue, error) {
+	var it Issue
+	var labels string
+	var startsAt sql.NullTime
+	var budget []byte
+	if err := row.Scan(&it.ID, &it.State, &it.Level, &it.AlertState, &it.Title, &labels, &it.AlertSince,
+		&it.TraceParent, &it.Region, &it.Fingerprint, &startsAt, &it.AckedBy, &it.AckedAt, &budget); err != nil {
+		return nil, err
+	}
+	it.Labels = []byte(labels)
+	it.StartsAt = startsAt.Time
+	if len(budget) > 0 {
+		it.SLOBudget = budget
+	}
+	return &it, nil
+}
+
+func (d *Database) GetIssue(ctx context.Context, id string) (*Issue, error) {
+	it, err := scanIssue(d.QueryRowContext(ctx, issueSelect+` WHERE id = $1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get alert_issue: %w", err)
+	}
+	return it, nil
+}
+
+func (d *Database) ListIssuesBetween(ctx context.Context, from, to time.Time, limit int) ([]Issue, error) {
+	const q = `SELECT id, state, level, alert_state, title, labels, alert_since, trace_parent, region
+FROM alert_issues
+WHERE alert_since >= $1 AND alert_since <= $2
+ORDER BY alert_since ASC
+LIMIT $3`
+	rows, err := d.QueryContext(ctx, q, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Issue
+	for rows.Next() {
+		var it Issue
+		var labels string
+		if err := rows.Scan(&it.ID, &it.State, &it.Level, &it.AlertState, &it.Title, &labels, &it.AlertSince, &it.TraceParent, &it.Region); err != nil {
+			return nil, err
+		}
+		it.Labels = []byte(labels)
+		out = append(out, it)
+	}
+	return out, rows.Err()
+}
+
+func (d *Database) UpdateIssueState(ctx context.Context, id, state, alertState string) error {
+	_, err := d.ExecContext(ctx, `UPDATE alert_issues SET alert_state = $1, state = $2 WHERE id = $3`, alertState, state, id)
+	return err
+}
+
+func (d *Database) AckIssue(ctx context.Context, id, by string, at time.Time) (bool, error) {
+	res, err := d.ExecContext(ctx, `UPDATE alert_issues SET acked_by = $1, acked_at = $2 WHERE id = $3`, by, at, id)
+	if err != nil {
+		return false, fmt.Errorf("ack alert_issue: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (d *Database) ListComments(ctx context.Context, issueID string) ([]Comment, error) {
+	const q = `SELECT create_at, content, analysis FROM alert_issue_comments WHERE issue_id=$1 ORDER BY create_at ASC`
+	rows, err := d.QueryContext(ctx, q, issueID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Comment, 0, 4)
+	for rows.Next() {
+		c := Comment{IssueID: issueID}
+		var analysis []byte
+		if err := rows.Scan(&c.CreatedAt, &c.Content, &analysis); err != nil {
+			return nil, err
+		}
+		if len(analysis) > 0 {
+			c.Analysis = analysis
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (d *Database) AddComment(ctx context.Context, issueID, content string) (bool, error) {
+	return d.AddAnalysisComment(ctx, issueID, content, nil)
+}
+
+func (d *Database) AddAnalysisComment(ctx context.Context, issueID, content string, analysis json.RawMessage) (bool, error) {
+	const q = `INSERT INTO alert_issue_comments (issue_id, create_at, content, analysis)
+SELECT $1, NOW(), $2, $3
+WHERE NOT EXISTS (SELECT 1 FROM alert_issue_comments WHERE issue_id=$1 AND content=$2)`
+	var value any
+	if len(analysis) > 0 {
+		value = string(analysis)
+	}
+	res, err := d.ExecContext(ctx, q, issueID, content, value)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// issueRegion is the region column value; issues without one belong to region.Default.
+func issueRegion(issue *Issue) string {
+	if issue.Region == "" {
+		return region.Default
+	}
+	return issue.Region
+}
+
+func (d *Database) UpsertServiceState(ctx context.Context, service, version, regionName string, reportAt *time.Time, healthState, issueID string) error {
+	const q = `
+	INSERT INTO service_states (service, version, region, report_at, health_state, alert_issue_ids)
+	VALUES ($1, $2, $6, $3, $4, ARRAY[$5]::text[])
+	ON CONFLICT (service, version, region) DO UPDATE
+	SET health_state = EXCLUDED.health_state,
+		alert_issue_ids = CASE
+			WHEN NOT ($5 = ANY(service_states.alert_issue_ids)) THEN array_append(service_states.alert_issue_ids, $5)
+			ELSE service_states.alert_issue_ids
+		END
+	`
+	var reportAtVal any
+	if reportAt != nil {
+		reportAtVal = *reportAt
+	}
+	if _, err := d.ExecContext(ctx, q, service, version, reportAtVal, healthState, issueID, region.Normalize(regionName)); err != nil {
+		return fmt.Errorf("upsert service_state: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) ResolveServiceState(ctx context.Context, service, version, regionName, issueID string) error {
+	const q = `
+INSERT INTO service_states (service, version, region, report_at, resolved_at, health_state, alert_issue_ids)
+VALUES ($1, $2, $4, NULL, NOW(), 'Normal', ARRAY[$3]::text[])
+ON CONFLICT (service, version, region) DO UPDATE
+SET health_state = 'Normal',
+    resolved_at = NOW();
+`
+	if _, err := d.ExecContext(ctx, q, service, version, issueID, region.Normalize(regionName)); err != nil {
+		return fmt.Errorf("resolve service_state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,726 @@
+// Package memory is an in-memory database.Store for unit tests that should not need Postgres.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/region"
+)
+
+var _ adb.Store = (*Store)(nil)
+
+// ServiceState mirrors one row of service_states.
+type ServiceState struct {
+	Service       string
+	Version       string
+	Region        string
+	ReportAt      *time.Time
+	ResolvedAt    *time.Time
+	HealthState   string
+	AlertIssueIDs []string
+}
+
+// Store keeps issues, comments, service states, silences, SLOs, service alert metas,
+// issue history, reports, rollups, archived issues, webhook credentials and the audit log
+// guarded by a mutex.
+type Store struct {
+	mu       sync.Mutex
+	issues   map[string]adb.Issue
+	comments map[string][]adb.Comment
+	states   map[[3]string]ServiceState // service, version, region
+	silences map[string]adb.Silence
+	slos     map[[2]string]adb.SLO // service, name
+	metas    map[[2]string]string  // service, key
+	events   map[string][]adb.IssueEvent
+	reports  map[string]adb.IssueReport
+	rollups  []adb.IssueRollup
+	fprints  []adb.FingerprintRollup
+	archive  map[string]adb.ArchivedIssue
+	creds    map[string]adb.WebhookCredential
+	audit    []adb.AuditLog
+	now      func() time.Time
+}
+
+func New() *Store {
+	return &Store{
+		issues:   make(map[string]adb.Issue),
+		comments: make(map[string][]adb.Comment),
+		states:   make(map[[3]string]ServiceState),
+		silences: make(map[string]adb.Silence),
+		slos:     make(map[[2]string]adb.SLO),
+		metas:    make(map[[2]string]string),
+		events:   make(map[string][]adb.IssueEvent),
+		reports:  make(map[string]adb.IssueReport),
+		archive:  make(map[string]adb.ArchivedIssue),
+		creds:    make(map[string]adb.WebhookCredential),
+		now:      time.Now,
+	}
+}
+
+type txKey struct{}
+
+// InTx restores the state from before the call when fn returns an error. Nested calls
+// join the outer transaction.
+func (s *Store) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+	s.mu.Lock()
+	issues, comments, states, silences := maps.Clone(s.issues), maps.Clone(s.comments), maps.Clone(s.states), maps.Clone(s.silences)
+	slos, metas, events, reports := maps.Clone(s.slos), maps.Clone(s.metas), maps.Clone(s.events), maps.Clone(s.reports)
+	rollups, fprints, archive := s.rollups, s.fprints, maps.Clone(s.archive)
+	creds, audit := maps.Clone(s.creds), s.audit
+	s.mu.Unlock()
+
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		s.mu.Lock()
+		s.issues, s.comments, s.states, s.silences = issues, comments, states, silences
+		s.slos, s.metas, s.events, s.reports = slos, metas, events, reports
+		s.rollups, s.fprints, s.archive = rollups, fprints, archive
+		s.creds, s.audit = creds, audit
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (s *Store) InsertIssue(ctx context.Context, issue *adb.Issue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.issues[issue.ID]; ok {
+		return fmt.Errorf("insert alert_issue: duplicate id %s", issue.ID)
+	}
+	if s.duplicateAlert(issue) {
+		return fmt.Errorf("insert alert_issue: duplicate alert %s", issue.Fingerprint)
+	}
+	s.putIssue(issue)
+	return nil
+}
+
+// InsertIssues skips issues matching a stored fingerprint and start time, like the
+// partial unique index on alert_issues.
+func (s *Store) InsertIssues(ctx context.Context, issues []*adb.Issue) ([]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, issue := range issues {
+		if _, ok := s.issues[issue.ID]; ok {
+			return nil, fmt.Errorf("insert alert_issues: duplicate id %s", issue.ID)
+		}
+	}
+	inserted := make([]bool, len(issues))
+	for i, issue := range issues {
+		if s.duplicateAlert(issue) {
+			continue
+		}
+		s.putIssue(issue)
+		inserted[i] = true
+	}
+	return inserted, nil
+}
+
+func (s *Store) duplicateAlert(issue *adb.Issue) bool {
+	if issue.Fingerprint == "" {
+		return false
+	}
+	startsAt := issueStartsAt(issue)
+	for _, it := range s.issues {
+		if it.Fingerprint == issue.Fingerprint && it.StartsAt.Equal(startsAt) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) putIssue(issue *adb.Issue) {
+	it := *issue
+	it.Labels = slices.Clone(issue.Labels)
+	it.SLOBudget = slices.Clone(issue.SLOBudget)
+	it.StartsAt = issueStartsAt(issue)
+	if it.Region == "" {
+		it.Region = region.Default
+	}
+	s.issues[it.ID] = it
+}
+
+func issueStartsAt(issue *adb.Issue) time.Time {
+	if issue.StartsAt.IsZero() {
+		return issue.AlertSince
+	}
+	return issue.StartsAt
+}
+
+// Issue returns a stored issue by id.
+func (s *Store) Issue(id string) (adb.Issue, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.issues[id]
+	return it, ok
+}
+
+func (s *Store) ListPendingIssues(ctx context.Context, limit int) ([]adb.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]adb.Issue, 0, limit)
+	for _, it := range s.issues {
+		if it.AlertState == "Pending" {
+			out = append(out, it)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AlertSince.Before(out[j].AlertSince) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *Store) UpdateIssueState(ctx context.Context, id, state, alertState string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if it, ok := s.issues[id]; ok {
+		it.State, it.AlertState = state, alertState
+		s.issues[id] = it
+	}
+	return nil
+}
+
+func (s *Store) AckIssue(ctx context.Context, id, by string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.issues[id]
+	if !ok {
+		return false, nil
+	}
+	it.AckedBy, it.AckedAt = by, &at
+	s.issues[id] = it
+	return true, nil
+}
+
+func (s *Store) GetIssue(ctx context.Context, id string) (*adb.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, ok := s.issues[id]
+	if !ok {
+		return nil, nil
+	}
+	return &it, nil
+}
+
+func (s *Store) ListIssuesBetween(ctx context.Context, from, to time.Time, limit int) ([]adb.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []adb.Issue
+	for _, it := range s.issues {
+		if !it.AlertSince.Before(from) && !it.AlertSince.After(to) {
+			out = append(out, it)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].AlertSince.Before(out[j].AlertSince) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *Store) AddIssueEvent(ctx context.Context, e *adb.IssueEvent) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, old := range s.events[e.IssueID] {
+		if old.Type == e.Type && old.To == e.To && old.Detail == e.Detail {
+			return false, nil
+		}
+	}
+	events := append(slices.Clip(s.events[e.IssueID]), *e)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].At.Before(events[j].At) })
+	s.events[e.IssueID] = events
+	return true, nil
+}
+
+func (s *Store) ListIssueEvents(ctx context.Context, issueID string) ([]adb.IssueEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.events[issueID]), nil
+}
+
+func (s *Store) UpsertIssueReport(ctx context.Context, r *adb.IssueReport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *r
+	stored.Report = slices.Clone(r.Report)
+	s.reports[r.IssueID] = stored
+	return nil
+}
+
+func (s *Store) GetIssueReport(ctx context.Context, issueID string) (*adb.IssueReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.reports[issueID]
+	if !ok {
+		return nil, nil
+	}
+	return &r, nil
+}
+
+func (s *Store) ListIssueReports(ctx context.Context, service string, limit int) ([]adb.IssueReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []adb.IssueReport
+	for _, r := range s.reports {
+		if service == "" || r.Service == service {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].IssueID < out[j].IssueID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *Store) ListIssueFacts(ctx context.Context, from, to time.Time) ([]adb.IssueFact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []adb.IssueFact
+	for _, it := range s.issues {
+		if it.AlertSince.Before(from) || !it.AlertSince.Before(to) {
+			continue
+		}
+		f := adb.IssueFact{ID: it.ID, Level: it.Level, Labels: it.Labels, Fingerprint: it.Fingerprint, AlertSince: it.AlertSince}
+		for _, e := range s.events[it.ID] {
+			at := e.At
+			switch {
+			case e.Type == adb.EventCreated && f.CreatedAt == nil:
+				f.CreatedAt = &at
+			case e.Type == adb.EventAcked && f.AckedAt == nil:
+				f.AckedAt = &at
+			case e.Type == adb.EventState && f.RestoredAt == nil && (e.To == "Restored" || e.To == "AutoRestored"):
+				f.RestoredAt, f.RestoredBy = &at, e.Actor
+			case e.Type == adb.EventRemediation:
+				f.Remediated = true
+			}
+		}
+		if f.AckedAt == nil {
+			f.AckedAt = it.AckedAt
+		}
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *Store) ReplaceRollups(ctx context.Context, from, to time.Time, issues []adb.IssueRollup, fingerprints []adb.FingerprintRollup) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inRange := func(day time.Time) bool { return !day.Before(from) && day.Before(to) }
+	s.rollups = append(slices.DeleteFunc(slices.Clone(s.rollups), func(r adb.IssueRollup) bool { return inRange(r.Day) }), issues...)
+	s.fprints = append(slices.DeleteFunc(slices.Clone(s.fprints), func(r adb.FingerprintRollup) bool { return inRange(r.Day) }), fingerprints...)
+	return nil
+}
+
+func (s *Store) ListIssueRollups(ctx context.Context, from, to time.Time, f adb.RollupFilter) ([]adb.IssueRollup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []adb.IssueRollup
+	for _, r := range s.rollups {
+		if !r.Day.Before(from) && r.Day.Before(to) && matchRollup(f, r.Service, r.AlertName, r.Level) {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if !a.Day.Equal(b.Day) {
+			return a.Day.Before(b.Day)
+		}
+		return strings.Join([]string{a.Service, a.AlertName, a.Level}, "\x00") < strings.Join([]string{b.Service, b.AlertName, b.Level}, "\x00")
+	})
+	return out, nil
+}
+
+func (s *Store) ListFingerprintRollups(ctx context.Context, from, to time.Time, f adb.RollupFilter) ([]adb.FingerprintRollup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []adb.FingerprintRollup
+	for _, r := range s.fprints {
+		if !r.Day.Before(from) && r.Day.Before(to) && matchRollup(f, r.Service, r.AlertName, r.Level) {
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Day.Equal(out[j].Day) {
+			return out[i].Day.Before(out[j].Day)
+		}
+		return out[i].Fingerprint < out[j].Fingerprint
+	})
+	return out, nil
+}
+
+func matchRollup(f adb.RollupFilter, service, alertName, level string) bool {
+	return (f.Service == "" || f.Service == service) && (f.AlertName == "" || f.AlertName == alertName) &&
+		(f.Level == "" || f.Level == level)
+}
+
+func (s *Store) ListClosedIssues(ctx context.Context, f adb.ClosedIssueFilter, limit int) ([]adb.Issue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []adb.Issue
+	for _, it := range s.issues {
+		if it.State != "Closed" || !it.AlertSince.Before(f.Before) || slices.Contains(f.ExceptLevels, it.Level) ||
+			len(f.Levels) > 0 && !slices.Contains(f.Levels, it.Level) {
+			continue
+		}
+		out = append(out, it)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].AlertSince.Equal(out[j].AlertSince) {
+			return out[i].AlertSince.Before(out[j].AlertSince)
+		}
+		return out[i].ID < out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *Store) DeleteIssues(ctx context.Context, ids []string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, id := range ids {
+		if _, ok := s.issues[id]; ok {
+			n++
+		}
+		delete(s.issues, id)
+		delete(s.comments, id)
+		delete(s.events, id)
+		delete(s.reports, id)
+	}
+	return n, nil
+}
+
+func (s *Store) RestoreIssue(ctx context.Context, r *adb.IssueRecord) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.issues[r.Issue.ID]; ok {
+		return false, nil
+	}
+	if s.duplicateAlert(&r.Issue) {
+		return false, adb.ErrAlertConflict
+	}
+	s.putIssue(&r.Issue)
+	s.comments[r.Issue.ID] = slices.Clone(r.Comments)
+	s.events[r.Issue.ID] = slices.Clone(r.Events)
+	if r.Report != nil {
+		s.reports[r.Issue.ID] = *r.Report
+	}
+	return true, nil
+}
+
+func (s *Store) InsertArchivedIssues(ctx context.Context, issues []adb.ArchivedIssue) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range issues {
+		a.Record = slices.Clone(a.Record)
+		s.archive[a.ID] = a
+	}
+	return nil
+}
+
+func (s *Store) ListArchivedIssues(ctx context.Context, from, to time.Time, afterID string, limit int) ([]adb.ArchivedIssue, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []adb.ArchivedIssue
+	for _, a := range s.archive {
+		if a.ID > afterID && !a.AlertSince.Before(from) && a.AlertSince.Before(to) {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *Store) DeleteArchivedIssues(ctx context.Context, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.archive, id)
+	}
+	return nil
+}
+
+func (s *Store) ListComments(ctx context.Context, issueID string) ([]adb.Comment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append(make([]adb.Comment, 0, 4), s.comments[issueID]...), nil
+}
+
+func (s *Store) AddComment(ctx context.Context, issueID, content string) (bool, error) {
+	return s.AddAnalysisComment(ctx, issueID, content, nil)
+}
+
+func (s *Store) AddAnalysisComment(ctx context.Context, issueID, content string, analysis json.RawMessage) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.comments[issueID] {
+		if c.Content == content {
+			return false, nil
+		}
+	}
+	s.comments[issueID] = append(slices.Clip(s.comments[issueID]), adb.Comment{
+		IssueID: issueID, CreatedAt: s.now(), Content: content, Analysis: slices.Clone(analysis),
+	})
+	return true, nil
+}
+
+func (s *Store) UpsertServiceState(ctx context.Context, service, version, regionName string, reportAt *time.Time, healthState, issueID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	regionName = region.Normalize(regionName)
+	key := [3]string{service, version, regionName}
+	st, ok := s.states[key]
+	if !ok {
+		s.states[key] = ServiceState{Service: service, Version: version, Region: regionName, ReportAt: reportAt, HealthState: healthState, AlertIssueIDs: []string{issueID}}
+		return nil
+	}
+	st.HealthState = healthState
+	if !slices.Contains(st.AlertIssueIDs, issueID) {
+		st.AlertIssueIDs = append(slices.Clip(st.AlertIssueIDs), issueID)
+	}
+	s.states[key] = st
+	return nil
+}
+
+func (s *Store) ResolveServiceState(ctx context.Context, service, version, regionName, issueID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	regionName = region.Normalize(regionName)
+	key := [3]string{service, version, regionName}
+	now := s.now()
+	st, ok := s.states[key]
+	if !ok {
+		st = ServiceState{Service: service, Version: version, Region: regionName, AlertIssueIDs: []string{issueID}}
+	}
+	st.HealthState = "Normal"
+	st.ResolvedAt = &now
+	s.states[key] = st
+	return nil
+}
+
+// ServiceState returns the stored row for service/version/region.
+func (s *Store) ServiceState(service, version, regionName string) (ServiceState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[[3]string{service, version, region.Normalize(regionName)}]
+	return st, ok
+}
+
+func (s *Store) InsertSilence(ctx context.Context, sil *adb.Silence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, dup := s.silences[sil.ID]; dup {
+		return fmt.Errorf("insert alert_silence: duplicate id %s", sil.ID)
+	}
+	s.silences[sil.ID] = *sil
+	return nil
+}
+
+func (s *Store) ListSilences(ctx context.Context, since time.Time) ([]adb.Silence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]adb.Silence, 0, len(s.silences))
+	for _, sil := range s.silences {
+		if sil.EndsAt.After(since) {
+			out = append(out, sil)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *Store) ExpireSilence(ctx context.Context, id string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sil, ok := s.silences[id]
+	if !ok {
+		return false, nil
+	}
+	if at.Before(sil.EndsAt) {
+		sil.EndsAt = at
+		s.silences[id] = sil
+	}
+	return true, nil
+}
+
+func (s *Store) UpsertSLO(ctx context.Context, slo *adb.SLO) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := [2]string{slo.Service, slo.Name}
+	v := *slo
+	if old, ok := s.slos[key]; ok {
+		v.CreatedAt = old.CreatedAt
+	}
+	s.slos[key] = v
+	return nil
+}
+
+func (s *Store) ListSLOs(ctx context.Context, service string) ([]adb.SLO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]adb.SLO, 0, len(s.slos))
+	for _, slo := range s.slos {
+		if service == "" || slo.Service == service {
+			out = append(out, slo)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Service != out[j].Service {
+			return out[i].Service < out[j].Service
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+func (s *Store) GetSLO(ctx context.Context, service, name string) (*adb.SLO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slo, ok := s.slos[[2]string{service, name}]
+	if !ok {
+		return nil, nil
+	}
+	return &slo, nil
+}
+
+func (s *Store) DeleteSLO(ctx context.Context, service, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := [2]string{service, name}
+	_, ok := s.slos[key]
+	delete(s.slos, key)
+	return ok, nil
+}
+
+func (s *Store) ListServiceAlertMetas(ctx context.Context, service, prefix string) ([]adb.ServiceAlertMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]adb.ServiceAlertMeta, 0, len(s.metas))
+	for k, v := range s.metas {
+		if (service == "" || k[0] == service) && strings.HasPrefix(k[1], prefix) {
+			out = append(out, adb.ServiceAlertMeta{Service: k[0], Key: k[1], Value: v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Service != out[j].Service {
+			return out[i].Service < out[j].Service
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out, nil
+}
+
+func (s *Store) ReplaceServiceAlertMetas(ctx context.Context, service, prefix string, values map[string]string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := 0
+	for k := range s.metas {
+		if k[0] == service && strings.HasPrefix(k[1], prefix) {
+			delete(s.metas, k)
+			deleted++
+		}
+	}
+	for k, v := range values {
+		s.metas[[2]string{service, k}] = v
+	}
+	return deleted, nil
+}
+
+func (s *Store) InsertWebhookCredential(ctx context.Context, c *adb.WebhookCredential) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, dup := s.creds[c.ID]; dup {
+		return fmt.Errorf("insert alert_webhook_credential: duplicate id %s", c.ID)
+	}
+	s.creds[c.ID] = *c
+	return nil
+}
+
+func (s *Store) ListWebhookCredentials(ctx context.Context, integration string, activeAt time.Time) ([]adb.WebhookCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]adb.WebhookCredential, 0, len(s.creds))
+	for _, c := range s.creds {
+		if (integration == "" || c.Integration == integration) && (activeAt.IsZero() || c.Active(activeAt)) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Integration != out[j].Integration {
+			return out[i].Integration < out[j].Integration
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+func (s *Store) GetWebhookCredential(ctx context.Context, id string) (*adb.WebhookCredential, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.creds[id]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (s *Store) ExpireWebhookCredential(ctx context.Context, id string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.creds[id]
+	if !ok {
+		return false, nil
+	}
+	if c.ExpiresAt == nil || at.Before(*c.ExpiresAt) {
+		c.ExpiresAt = &at
+		s.creds[id] = c
+	}
+	return true, nil
+}
+
+// InsertAuditLog numbers entries like the BIGSERIAL id of audit_logs.
+func (s *Store) InsertAuditLog(ctx context.Context, l *adb.AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := *l
+	v.ID = int64(len(s.audit)) + 1
+	s.audit = append(slices.Clip(s.audit), v)
+	return nil
+}
+
+func (s *Store) ListAuditLogs(ctx context.Context, f adb.AuditFilter, limit int) ([]adb.AuditLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []adb.AuditLog
+	for i := len(s.audit) - 1; i >= 0 && len(out) < limit; i-- {
+		l := s.audit[i]
+		if (f.Action == "" || l.Action == f.Action) && (f.From.IsZero() || !l.At.Before(f.From)) && (f.To.IsZero() || l.At.Before(f.To)) {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// keyPrefixClause matches keys starting with $2 without treating it as a LIKE pattern.
+const keyPrefixClause = `left(key, length($2)) = $2`
+
+func (d *Database) ListServiceAlertMetas(ctx context.Context, service, prefix string) ([]ServiceAlertMeta, error) {
+	q := `SELECT service, key, value FROM service_alert_metas
+WHERE ($1 = '' OR service = $1) AND ` + keyPrefixClause + `
+ORDER BY service, key`
+	rows, err := d.QueryContext(ctx, q, service, prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]ServiceAlertMeta, 0, 16)
+	for rows.Next() {
+		var m ServiceAlertMeta
+		if err := rows.Scan(&m.Service, &m.Key, &m.Value); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (d *Database) ReplaceServiceAlertMetas(ctx context.Context, service, prefix string, values map[string]string) (int, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var deleted int64
+	err := d.InTx(ctx, func(ctx context.Context) error {
+		res, err := d.ExecContext(ctx, `DELETE FROM service_alert_metas WHERE service = $1 AND `+keyPrefixClause, service, prefix)
+		if err != nil {
+			return fmt.Errorf("delete service_alert_metas: %w", err)
+		}
+		if deleted, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if _, err := d.ExecContext(ctx, `INSERT INTO service_alert_metas (service, key, value) VALUES ($1, $2, $3)`,
+				service, k, values[k]); err != nil {
+				return fmt.Errorf("insert service_alert_meta: %w", err)
+			}
+		}
+		return nil
+	})
+	return int(deleted), err
+}
@@ -0,0 +1,428 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/pg"
+)
+
+// Issue is one row of alert_issues.
+type Issue struct {
+	ID         string
+	State      string // Open/Closed
+	Level      string // P0/P1/P2/Warning
+	AlertState string // Pending/InProcessing/Restored/AutoRestored
+	Title      string
+	Labels     json.RawMessage // [{key, value}]
+	AlertSince time.Time
+	// Region is derived from the labels on ingest, see region.FromLabels.
+	Region string
+	// Fingerprint and StartsAt identify the Alertmanager alert. Issues with the same
+	// non-empty fingerprint and start time are duplicates, see InsertIssues.
+	Fingerprint string
+	StartsAt    time.Time
+	// TraceParent is the W3C traceparent of the span that created the issue, "" if untraced.
+	TraceParent string
+	// AckedBy and AckedAt are set by AckIssue; the list queries do not load them.
+	AckedBy string
+	AckedAt *time.Time
+	// SLOBudget is the error budget snapshot of the SLO whose burn-rate alert opened the
+	// issue, nil for other alerts.
+	SLOBudget json.RawMessage
+}
+
+// LabelMap decodes labels in the [{key, value}] form of Issue.Labels into a map. A flat
+// {"key": "value"} object is accepted as well; anything else gives an empty map.
+func LabelMap(raw json.RawMessage) map[string]string {
+	var pairs []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(raw, &pairs) == nil {
+		out := make(map[string]string, len(pairs))
+		for _, p := range pairs {
+			out[p.Key] = p.Value
+		}
+		return out
+	}
+	out := make(map[string]string)
+	if json.Unmarshal(raw, &out) != nil {
+		return map[string]string{}
+	}
+	return out
+}
+
+// Comment is one row of alert_issue_comments.
+type Comment struct {
+	IssueID   string
+	CreatedAt time.Time
+	Content   string
+	// Analysis is the structured root-cause analysis an AI analysis comment was rendered
+	// from, nil for other comments.
+	Analysis json.RawMessage
+}
+
+// Issue event types.
+const (
+	EventCreated     = "created"
+	EventAcked       = "acked"
+	EventState       = "state" // alert state change, From and To are set
+	EventRemediation = "remediation"
+)
+
+// ActorRemediation is the actor of the events auto-remediation records.
+const ActorRemediation = "remediation"
+
+// IssueEvent is one row of alert_issue_events, the history of an issue.
+type IssueEvent struct {
+	IssueID string
+	At      time.Time
+	Type    string
+	// Actor is the operator or component behind the event, e.g. remediation.
+	Actor  string
+	From   string
+	To     string
+	Detail string
+}
+
+// IssueReport is one row of alert_issue_reports.
+type IssueReport struct {
+	IssueID   string
+	Service   string
+	CreatedAt time.Time
+	CreatedBy string
+	Report    json.RawMessage
+}
+
+// IssueFact is an issue with the times of its history that analytics are computed from.
+type IssueFact struct {
+	ID          string
+	Level       string
+	Labels      json.RawMessage
+	Fingerprint string
+	AlertSince  time.Time
+	// CreatedAt is the first created event, AckedAt the first acked event or else
+	// alert_issues.acked_at, and RestoredAt the first change to Restored or AutoRestored,
+	// by RestoredBy. They are nil when not recorded.
+	CreatedAt  *time.Time
+	AckedAt    *time.Time
+	RestoredAt *time.Time
+	RestoredBy string
+	// Remediated reports whether the issue has a remediation event.
+	Remediated bool
+}
+
+// IssueRollup is one row of alert_issue_rollups: the issues of a service, alertname and
+// level whose alert started on Day (UTC). AckBuckets and RestoreBuckets count durations
+// in seconds per histogram bucket, see analytics.Buckets.
+type IssueRollup struct {
+	Day            time.Time
+	Service        string
+	AlertName      string
+	Level          string
+	Issues         int
+	Acked          int
+	AckSeconds     float64
+	AckBuckets     []int64
+	Restored       int
+	RestoreSeconds float64
+	RestoreBuckets []int64
+	Remediated     int
+	AutoRestored   int
+	UpdatedAt      time.Time
+}
+
+// FingerprintRollup is one row of alert_fingerprint_rollups.
+type FingerprintRollup struct {
+	Day         time.Time
+	Fingerprint string
+	Service     string
+	AlertName   string
+	Level       string
+	Issues      int
+}
+
+// RollupFilter selects rollups; empty fields match everything.
+type RollupFilter struct {
+	Service   string
+	AlertName string
+	Level     string
+}
+
+// IssueRecord is an issue with its comments, history and report, the unit the retention
+// job archives and restores.
+type IssueRecord struct {
+	Issue    Issue
+	Comments []Comment
+	Events   []IssueEvent
+	Report   *IssueReport
+}
+
+// ArchivedIssue is one row of alert_issue_archive. Record is the archived document, see
+// archive.Record.
+type ArchivedIssue struct {
+	ID         string
+	Level      string
+	AlertSince time.Time
+	ArchivedAt time.Time
+	Record     json.RawMessage
+}
+
+// ClosedIssueFilter selects the closed issues whose alert started before Before, of one
+// of Levels when set and of none of ExceptLevels.
+type ClosedIssueFilter struct {
+	Before       time.Time
+	Levels       []string
+	ExceptLevels []string
+}
+
+// Silence is one row of alert_silences. Alerts whose labels match every matcher are
+// dropped by the receiver while StartsAt <= now < EndsAt.
+type Silence struct {
+	ID        string
+	Matchers  json.RawMessage // [{name, op, value}]
+	StartsAt  time.Time
+	EndsAt    time.Time
+	CreatedBy string
+	Comment   string
+	CreatedAt time.Time
+}
+
+// Webhook credential kinds.
+const (
+	CredentialHMAC  = "hmac"  // signs the request body, see receiver.Authenticator
+	CredentialToken = "token" // sent as a Bearer token or the Basic password
+)
+
+// WebhookCredential is one row of alert_webhook_credentials. Secret is the HMAC key, or
+// the hex SHA-256 of a token; tokens are not stored.
+type WebhookCredential struct {
+	ID          string
+	Integration string
+	Kind        string
+	Secret      string
+	CreatedBy   string
+	CreatedAt   time.Time
+	// ExpiresAt is set when the credential is rotated or revoked, nil before.
+	ExpiresAt *time.Time
+}
+
+// Active reports whether the credential is usable at t.
+func (c *WebhookCredential) Active(t time.Time) bool {
+	return c.ExpiresAt == nil || t.Before(*c.ExpiresAt)
+}
+
+// AuditLog is one row of audit_logs.
+type AuditLog struct {
+	ID         int64
+	At         time.Time
+	Actor      string
+	Action     string
+	Target     string
+	RemoteAddr string
+	Detail     string
+}
+
+// AuditFilter selects audit logs with At in [From, To) and, when set, the given action.
+// A zero From or To leaves that end open.
+type AuditFilter struct {
+	Action string
+	From   time.Time
+	To     time.Time
+}
+
+// SLO is one row of service_slos: the objective that GoodQuery/TotalQuery stays at or
+// above Target over the last WindowDays days.
+type SLO struct {
+	Service     string
+	Name        string
+	Description string
+	GoodQuery   string
+	TotalQuery  string
+	Target      float64
+	WindowDays  int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ServiceAlertMeta is one row of service_alert_metas, a per-service alerting setting such
+// as the parameters of an anomaly detector.
+type ServiceAlertMeta struct {
+	Service string
+	Key     string
+	Value   string
+}
+
+// IssueRepository persists alert issues.
+type IssueRepository interface {
+	InsertIssue(ctx context.Context, issue *Issue) error
+	// InsertIssues inserts issues in one statement, skipping those whose fingerprint and
+	// start time are already stored. inserted[i] reports whether issues[i] was written.
+	InsertIssues(ctx context.Context, issues []*Issue) (inserted []bool, err error)
+	// ListPendingIssues returns up to limit issues in Pending alert state, oldest first.
+	ListPendingIssues(ctx context.Context, limit int) ([]Issue, error)
+	UpdateIssueState(ctx context.Context, id, state, alertState string) error
+	// AckIssue records who acknowledged the issue and reports whether it exists.
+	AckIssue(ctx context.Context, id, by string, at time.Time) (bool, error)
+	// GetIssue returns nil when the issue does not exist.
+	GetIssue(ctx context.Context, id string) (*Issue, error)
+	// ListIssuesBetween returns up to limit issues with alert_since in [from, to], oldest
+	// first.
+	ListIssuesBetween(ctx context.Context, from, to time.Time, limit int) ([]Issue, error)
+}
+
+// IssueEventRepository persists the history of issues.
+type IssueEventRepository interface {
+	// AddIssueEvent records an event unless the issue already has one with the same type,
+	// target state and detail, e.g. when a message is redelivered, and reports whether it
+	// was added.
+	AddIssueEvent(ctx context.Context, e *IssueEvent) (bool, error)
+	// ListIssueEvents returns the events of an issue oldest first.
+	ListIssueEvents(ctx context.Context, issueID string) ([]IssueEvent, error)
+}
+
+// IssueReportRepository persists postmortem reports.
+type IssueReportRepository interface {
+	// UpsertIssueReport stores the report of an issue, replacing an earlier one.
+	UpsertIssueReport(ctx context.Context, r *IssueReport) error
+	// GetIssueReport returns nil when the issue has no stored report.
+	GetIssueReport(ctx context.Context, issueID string) (*IssueReport, error)
+	// ListIssueReports returns up to limit reports of service, newest first; an empty
+	// service returns those of every service.
+	ListIssueReports(ctx context.Context, service string, limit int) ([]IssueReport, error)
+}
+
+// AnalyticsRepository reads issue facts and persists their daily rollups. Days are UTC
+// midnights and ranges [from, to) are half-open.
+type AnalyticsRepository interface {
+	// ListIssueFacts returns the issues with alert_since in [from, to).
+	ListIssueFacts(ctx context.Context, from, to time.Time) ([]IssueFact, error)
+	// ReplaceRollups atomically replaces the rollups of the days in [from, to).
+	ReplaceRollups(ctx context.Context, from, to time.Time, issues []IssueRollup, fingerprints []FingerprintRollup) error
+	// ListIssueRollups returns the rollups of the days in [from, to) ordered by day,
+	// service, alertname and level.
+	ListIssueRollups(ctx context.Context, from, to time.Time, f RollupFilter) ([]IssueRollup, error)
+	// ListFingerprintRollups returns the fingerprint rollups of the days in [from, to)
+	// ordered by day and fingerprint.
+	ListFingerprintRollups(ctx context.Context, from, to time.Time, f RollupFilter) ([]FingerprintRollup, error)
+}
+
+// ArchiveRepository moves closed issues out of alert_issues and back, and persists
+// those archived to alert_issue_archive.
+type ArchiveRepository interface {
+	// ListClosedIssues returns up to limit closed issues matching f, oldest first. Within
+	// InTx the rows stay locked until the transaction ends and rows locked by another
+	// transaction are skipped.
+	ListClosedIssues(ctx context.Context, f ClosedIssueFilter, limit int) ([]Issue, error)
+	// DeleteIssues deletes issues with their comments, events and reports and returns the
+	// number of issues deleted.
+	DeleteIssues(ctx context.Context, ids []string) (int, error)
+	// RestoreIssue inserts an issue with its comments, events and report and reports whether
+	// it was inserted. It returns false when an issue with the same id is stored and
+	// ErrAlertConflict when another issue is stored for the same alert.
+	RestoreIssue(ctx context.Context, r *IssueRecord) (bool, error)
+	// InsertArchivedIssues stores archived issues, replacing those with the same id.
+	InsertArchivedIssues(ctx context.Context, issues []ArchivedIssue) error
+	// ListArchivedIssues returns up to limit archived issues with alert_since in
+	// [from, to) and an id after afterID, ordered by id.
+	ListArchivedIssues(ctx context.Context, from, to time.Time, afterID string, limit int) ([]ArchivedIssue, error)
+	DeleteArchivedIssues(ctx context.Context, ids []string) error
+}
+
+// CommentRepository persists issue comments.
+type CommentRepository interface {
+	// ListComments returns comments of an issue in creation order.
+	ListComments(ctx context.Context, issueID string) ([]Comment, error)
+	// AddComment appends a comment unless the issue already has one with the same content,
+	// and reports whether it was added.
+	AddComment(ctx context.Context, issueID, content string) (bool, error)
+	// AddAnalysisComment is AddComment for an AI analysis comment, storing the structured
+	// analysis next to its rendered content.
+	AddAnalysisComment(ctx context.Context, issueID, content string, analysis json.RawMessage) (bool, error)
+}
+
+// ServiceStateRepository persists per service/version/region health in service_states.
+type ServiceStateRepository interface {
+	// UpsertServiceState records an issue against the service version in a region and sets its health.
+	UpsertServiceState(ctx context.Context, service, version, region string, reportAt *time.Time, healthState, issueID string) error
+	// ResolveServiceState marks the service version in a region Normal and stamps resolved_at.
+	ResolveServiceState(ctx context.Context, service, version, region, issueID string) error
+}
+
+// SilenceRepository persists silences.
+type SilenceRepository interface {
+	InsertSilence(ctx context.Context, s *Silence) error
+	// ListSilences returns silences ending after since, newest first; a zero since
+	// returns all of them.
+	ListSilences(ctx context.Context, since time.Time) ([]Silence, error)
+	// ExpireSilence ends a silence at the given time unless it already ended, and
+	// reports whether the silence exists.
+	ExpireSilence(ctx context.Context, id string, at time.Time) (bool, error)
+}
+
+// SLORepository persists service level objectives.
+type SLORepository interface {
+	// UpsertSLO creates or replaces the SLO identified by service and name, keeping the
+	// CreatedAt of an existing one.
+	UpsertSLO(ctx context.Context, s *SLO) error
+	// ListSLOs returns the SLOs of service ordered by service and name; an empty service
+	// returns all of them.
+	ListSLOs(ctx context.Context, service string) ([]SLO, error)
+	// GetSLO returns nil when the SLO does not exist.
+	GetSLO(ctx context.Context, service, name string) (*SLO, error)
+	// DeleteSLO reports whether the SLO existed.
+	DeleteSLO(ctx context.Context, service, name string) (bool, error)
+}
+
+// ServiceAlertMetaRepository persists per-service alerting settings.
+type ServiceAlertMetaRepository interface {
+	// ListServiceAlertMetas returns the metas of service whose key starts with prefix,
+	// ordered by service and key; an empty service returns those of every service.
+	ListServiceAlertMetas(ctx context.Context, service, prefix string) ([]ServiceAlertMeta, error)
+	// ReplaceServiceAlertMetas atomically deletes the metas of service whose key starts
+	// with prefix, inserts values in their place and returns the number deleted.
+	ReplaceServiceAlertMetas(ctx context.Context, service, prefix string, values map[string]string) (int, error)
+}
+
+// WebhookCredentialRepository persists the credentials webhook senders authenticate with.
+type WebhookCredentialRepository interface {
+	InsertWebhookCredential(ctx context.Context, c *WebhookCredential) error
+	// ListWebhookCredentials returns the credentials of integration active at activeAt,
+	// ordered by integration and creation time; an empty integration returns those of
+	// every integration and a zero activeAt includes expired ones.
+	ListWebhookCredentials(ctx context.Context, integration string, activeAt time.Time) ([]WebhookCredential, error)
+	// GetWebhookCredential returns nil when the credential does not exist.
+	GetWebhookCredential(ctx context.Context, id string) (*WebhookCredential, error)
+	// ExpireWebhookCredential makes a credential expire at the given time unless it
+	// expires earlier, and reports whether the credential exists.
+	ExpireWebhookCredential(ctx context.Context, id string, at time.Time) (bool, error)
+}
+
+// AuditRepository persists the audit log.
+type AuditRepository interface {
+	InsertAuditLog(ctx context.Context, l *AuditLog) error
+	// ListAuditLogs returns up to limit entries matching f, newest first.
+	ListAuditLogs(ctx context.Context, f AuditFilter, limit int) ([]AuditLog, error)
+}
+
+// Store groups the alerting repositories. Calls made with the ctx passed to InTx share
+// one transaction.
+type Store interface {
+	pg.Transactor
+	IssueRepository
+	IssueEventRepository
+	IssueReportRepository
+	AnalyticsRepository
+	ArchiveRepository
+	CommentRepository
+	ServiceStateRepository
+	SilenceRepository
+	SLORepository
+	ServiceAlertMetaRepository
+	WebhookCredentialRepository
+	AuditRepository
+}
+
+var _ Store = (*Database)(nil)
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+func (d *Database) InsertSilence(ctx context.Context, s *Silence) error {
+	const q = `
+	INSERT INTO alert_silences (id, matchers, starts_at, ends_at, created_by, comment, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	if _, err := d.ExecContext(ctx, q, s.ID, string(s.Matchers), s.StartsAt, s.EndsAt, s.CreatedBy, s.Comment, s.CreatedAt); err != nil {
+		return fmt.Errorf("insert alert_silence: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) ListSilences(ctx context.Context, since time.Time) ([]Silence, error) {
+	const q = `SELECT id, matchers, starts_at, ends_at, created_by, comment, created_at
+FROM alert_silences
+WHERE ends_at > $1
+ORDER BY created_at DESC`
+	rows, err := d.QueryContext(ctx, q, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]Silence, 0, 8)
+	for rows.Next() {
+		var s Silence
+		var matchers string
+		if err := rows.Scan(&s.ID, &matchers, &s.StartsAt, &s.EndsAt, &s.CreatedBy, &s.Comment, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.Matchers = []byte(matchers)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (d *Database) ExpireSilence(ctx context.Context, id string, at time.Time) (bool, error) {
+	const q = `UPDATE alert_silences SET ends_at = LEAST(ends_at, $2) WHERE id = $1`
+	res, err := d.ExecContext(ctx, q, id, at)
+	if err != nil {
+		return false, fmt.Errorf("expire alert_silence: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
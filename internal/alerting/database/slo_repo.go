@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func (d *Database) UpsertSLO(ctx context.Context, s *SLO) error {
+	const q = `
+	INSERT INTO service_slos (service, name, description, good_query, total_query, target, window_days, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	ON CONFLICT (service, name) DO UPDATE
+	SET description = EXCLUDED.description,
+		good_query = EXCLUDED.good_query,
+		total_query = EXCLUDED.total_query,
+		target = EXCLUDED.target,
+		window_days = EXCLUDED.window_days,
+		updated_at = EXCLUDED.updated_at
+	`
+	if _, err := d.ExecContext(ctx, q, s.Service, s.Name, s.Description, s.GoodQuery, s.TotalQuery,
+		s.Target, s.WindowDays, s.CreatedAt, s.UpdatedAt); err != nil {
+		return fmt.Errorf("upsert service_slo: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) ListSLOs(ctx context.Context, service string) ([]SLO, error) {
+	const q = `SELECT service, name, description, good_query, total_query, target, window_days, created_at, updated_at
+FROM service_slos
+WHERE $1 = '' OR service = $1
+ORDER BY service, name`
+	rows, err := d.QueryContext(ctx, q, service)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]SLO, 0, 8)
+	for rows.Next() {
+		var s SLO
+		if err := rows.Scan(&s.Service, &s.Name, &s.Description, &s.GoodQuery, &s.TotalQuery,
+			&s.Target, &s.WindowDays, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (d *Database) GetSLO(ctx context.Context, service, name string) (*SLO, error) {
+	const q = `SELECT service, name, description, good_query, total_query, target, window_days, created_at, updated_at
+FROM service_slos
+WHERE service = $1 AND name = $2`
+	var s SLO
+	err := d.QueryRowContext(ctx, q, service, name).Scan(&s.Service, &s.Name, &s.Description, &s.GoodQuery,
+		&s.TotalQuery, &s.Target, &s.WindowDays, &s.CreatedAt, &s.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (d *Database) DeleteSLO(ctx context.Context, service, name string) (bool, error) {
+	res, err := d.ExecContext(ctx, `DELETE FROM service_slos WHERE service = $1 AND name = $2`, service, name)
+	if err != nil {
+		return false, fmt.Errorf("delete service_slo: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,82 @@
+// Package analysis writes the root-cause analysis of an issue, which remediation stores
+// as a comment when the issue enters InProcessing.
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrInvalidResult is returned when the model answer is not a usable analysis.
+var ErrInvalidResult = errors.New("invalid analysis result")
+
+// Input is the issue being analysed.
+type Input struct {
+	IssueID    string
+	Service    string
+	Version    string
+	Region     string
+	Level      string
+	Title      string
+	AlertSince time.Time
+	Labels     map[string]string
+}
+
+// Result is a structured root-cause analysis.
+type Result struct {
+	// ProblemType classifies the issue, e.g. 发版本导致的问题 or 非发版本导致的问题.
+	ProblemType string   `json:"problemType"`
+	RootCause   string   `json:"rootCause"`
+	Suggestions []string `json:"suggestions"`
+	// Confidence is between 0 and 1.
+	Confidence float64 `json:"confidence"`
+	// Model names the model that wrote the analysis.
+	Model string `json:"model,omitempty"`
+}
+
+// Analyzer analyses an issue. Implementations gather their own context.
+type Analyzer interface {
+	Analyze(ctx context.Context, in *Input) (*Result, error)
+}
+
+// Markdown renders the result as the content of the analysis comment.
+func (r *Result) Markdown() string {
+	var b strings.Builder
+	b.WriteString("## AI分析结果\n")
+	fmt.Fprintf(&b, "**问题类型**：%s\n", r.ProblemType)
+	fmt.Fprintf(&b, "**根因分析**：%s\n", r.RootCause)
+	b.WriteString("**处理建议**：\n")
+	for _, s := range r.Suggestions {
+		fmt.Fprintf(&b, "- %s\n", s)
+	}
+	fmt.Fprintf(&b, "**置信度**：%d%%", int(math.Round(r.Confidence*100)))
+	return b.String()
+}
+
+// normalize trims the fields and checks the required ones. A confidence given as a
+// percentage is scaled to [0, 1].
+func (r *Result) normalize() error {
+	r.ProblemType = strings.TrimSpace(r.ProblemType)
+	r.RootCause = strings.TrimSpace(r.RootCause)
+	if r.ProblemType == "" || r.RootCause == "" {
+		return fmt.Errorf("%w: problemType and rootCause are required", ErrInvalidResult)
+	}
+	suggestions := r.Suggestions[:0]
+	for _, s := range r.Suggestions {
+		if s = strings.TrimSpace(s); s != "" {
+			suggestions = append(suggestions, s)
+		}
+	}
+	r.Suggestions = suggestions
+	if r.Confidence > 1 && r.Confidence <= 100 {
+		r.Confidence /= 100
+	}
+	if math.IsNaN(r.Confidence) || r.Confidence < 0 || r.Confidence > 1 {
+		return fmt.Errorf("%w: confidence %v is not between 0 and 1", ErrInvalidResult, r.Confidence)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/config"
+)
+
+var t0 = time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+type fakeTools struct {
+	calls []map[string]any
+	text  string
+	err   error
+}
+
+func (f *fakeTools) CallTool(_ context.Context, name string, args map[string]any) (string, error) {
+	args["tool"] = name
+	f.calls = append(f.calls, args)
+	return f.text, f.err
+}
+
+type fakeDeployments []Deployment
+
+func (f fakeDeployments) RecentDeployments(_ context.Context, _ string, limit int) ([]Deployment, error) {
+	return f[:min(limit, len(f))], nil
+}
+
+func TestLLMAnalyzer(t *testing.T) {
+	ctx := context.Background()
+	started := t0.Add(-time.Hour)
+	prom := &fakeTools{text: `{"resultType":"vector","result":[{"value":[0,"0.12"]}]}`}
+	es := &fakeTools{err: errors.New("es down")}
+	provider := &FakeProvider{Response: "```json\n" +
+		`{"problemType":"发版本导致的问题","rootCause":"v1.2.0 引入慢查询","suggestions":["回滚到 v1.1.0"," "],"confidence":85}` + "\n```"}
+	a := &LLMAnalyzer{
+		Provider:      provider,
+		Prometheus:    prom,
+		Elasticsearch: es,
+		Deployments:   fakeDeployments{{ID: "deploy-1", Version: "v1.2.0", Region: "cn-east-1", Status: "deploying", Start: &started}},
+		Region:        "mock",
+		Queries:       []config.AnalysisQuery{{Name: "error_rate", Query: `rate(errors_total{service="$service"}[5m])`}},
+		Lookback:      30 * time.Minute,
+		Now:           func() time.Time { return t0 },
+	}
+	in := &Input{IssueID: "issue-1", Service: "storage", Version: "v1.2.0", Level: "P1", Title: "latency",
+		AlertSince: t0.Add(-10 * time.Minute), Labels: map[string]string{"instance": "10.0.0.1:9100", "alertname": "HighLatency"}}
+
+	res, err := a.Analyze(ctx, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ProblemType != "发版本导致的问题" || res.Confidence != 0.85 || len(res.Suggestions) != 1 || res.Model != "fake" {
+		t.Fatalf("result = %+v", res)
+	}
+	want := "## AI分析结果\n**问题类型**：发版本导致的问题\n**根因分析**：v1.2.0 引入慢查询\n**处理建议**：\n- 回滚到 v1.1.0\n**置信度**：85%"
+	if md := res.Markdown(); md != want {
+		t.Fatalf("markdown = %q", md)
+	}
+
+	if len(prom.calls) != 1 || prom.calls[0]["promql"] != `rate(errors_total{service="storage"}[5m])` || prom.calls[0]["regionCode"] != "mock" {
+		t.Fatalf("prometheus calls = %v", prom.calls)
+	}
+	if len(es.calls) != 1 || es.calls[0]["host_id"] != "10.0.0.1" || es.calls[0]["start_time"] != "2026-03-02T07:20:00Z" ||
+		es.calls[0]["end_time"] != "2026-03-02T08:00:00Z" {
+		t.Fatalf("elasticsearch calls = %v", es.calls)
+	}
+	prompt := provider.Chats()[0][1].Content
+	for _, part := range []string{"- alertname=HighLatency", "deploy-1 版本v1.2.0", `"0.12"`, "## 日志 10.0.0.1\n获取失败"} {
+		if !strings.Contains(prompt, part) {
+			t.Errorf("prompt lacks %q:\n%s", part, prompt)
+		}
+	}
+
+	for name, answer := range map[string]string{
+		"not json":        "the database is slow",
+		"no root cause":   `{"problemType":"非发版本导致的问题","confidence":0.3}`,
+		"bad confidence":  `{"problemType":"x","rootCause":"y","confidence":-1}`,
+		"huge confidence": `{"problemType":"x","rootCause":"y","confidence":300}`,
+	} {
+		provider.Response = answer
+		if _, err := a.Analyze(ctx, in); !errors.Is(err, ErrInvalidResult) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+// mcpServer emulates the streamable HTTP transport of mcp-server, answering tool calls
+// with an event stream and expiring the first session after one call.
+func mcpServer(t *testing.T) (*httptest.Server, *int) {
+	initialized := 0
+	sessions := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg struct {
+			ID     *int64 `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Name      string         `json:"name"`
+				Arguments map[string]any `json:"arguments"`
+			} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		session := r.Header.Get("Mcp-Session-Id")
+		switch msg.Method {
+		case "initialize":
+			initialized++
+			session = fmt.Sprintf("session-%d", initialized)
+			sessions[session] = 0
+			w.Header().Set("Mcp-Session-Id", session)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":{"protocolVersion":"2025-03-26"}}`, *msg.ID)
+			return
+		case "notifications/initialized":
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		calls, ok := sessions[session]
+		if !ok || (session == "session-1" && calls == 1) {
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+		sessions[session]++
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+		text, isError := "unknown tool", true
+		if msg.Params.Name == PrometheusTool {
+			text, isError = fmt.Sprint("up=1 for ", msg.Params.Arguments["promql"]), false
+		}
+		result, _ := json.Marshal(map[string]any{"content": []map[string]any{{"type": "text", "text": text}}, "isError": isError})
+		fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":%d,\"result\":%s}\n\n", *msg.ID, result)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &initialized
+}
+
+func TestMCPClient(t *testing.T) {
+	ctx := context.Background()
+	srv, initialized := mcpServer(t)
+	c := NewMCPClient(srv.URL, 5*time.Second)
+	for i := 0; i < 2; i++ {
+		text, err := c.CallTool(ctx, PrometheusTool, map[string]any{"promql": "up"})
+		if err != nil || text != "up=1 for up" {
+			t.Fatalf("call %d = %q, %v", i, text, err)
+		}
+	}
+	// the second call found the first session expired and opened another
+	if *initialized != 2 || c.session != "session-2" {
+		t.Fatalf("initialized %d times, session %q", *initialized, c.session)
+	}
+	if _, err := c.CallTool(ctx, "elasticsearch_fetch_logs", nil); err == nil || !strings.Contains(err.Error(), "unknown tool") {
+		t.Fatalf("tool error = %v", err)
+	}
+	if NewMCPClient("", time.Second) != nil {
+		t.Fatal("client without url")
+	}
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	var got chatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"message":"invalid api key"}}`)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"{\"rootCause\":\"x\"}"}}]}`)
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProvider(srv.URL+"/v1/", "key", "qwen-plus", 5*time.Second)
+	answer, err := p.Complete(context.Background(), []Message{{Role: "user", Content: "hi"}})
+	if err != nil || answer != `{"rootCause":"x"}` {
+		t.Fatalf("answer = %q, %v", answer, err)
+	}
+	if got.Model != "qwen-plus" || got.ResponseFormat.Type != "json_object" || len(got.Messages) != 1 {
+		t.Fatalf("request = %+v", got)
+	}
+
+	p.APIKey = "wrong"
+	if _, err := p.Complete(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "invalid api key") {
+		t.Fatalf("err = %v", err)
+	}
+	if New(&config.AnalysisConfig{}, nil) != nil {
+		t.Fatal("analyzer without a model")
+	}
+}
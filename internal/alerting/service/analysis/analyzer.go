@@ -0,0 +1,228 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxToolOutput bounds the text each metric query or log fetch adds to the prompt.
+	maxToolOutput = 4000
+	// maxDeployments is the number of recent deployments given to the model.
+	maxDeployments = 5
+)
+
+// Tools of mcp-server used for the context.
+const (
+	PrometheusTool    = "prometheus_query"
+	ElasticsearchTool = "elasticsearch_fetch_logs"
+)
+
+// Deployment is a deployment of the analysed service.
+type Deployment struct {
+	ID      string
+	Version string
+	Region  string
+	Status  string
+	Start   *time.Time
+	Finish  *time.Time
+}
+
+// DeploymentSource lists deployments from service_manager.
+type DeploymentSource interface {
+	// RecentDeployments returns the latest deployments of service, newest first.
+	RecentDeployments(ctx context.Context, service string, limit int) ([]Deployment, error)
+}
+
+// ToolCaller calls an MCP tool, see MCPClient.
+type ToolCaller interface {
+	CallTool(ctx context.Context, name string, args map[string]any) (string, error)
+}
+
+// LLMAnalyzer asks a model for the analysis, giving it the alert labels, recent
+// deployments, metrics from the Prometheus MCP tool and logs from the Elasticsearch MCP
+// tool. A context source that is not set or fails is left out of the prompt.
+type LLMAnalyzer struct {
+	Provider      Provider
+	Prometheus    ToolCaller
+	Elasticsearch ToolCaller
+	Deployments   DeploymentSource
+	// Region is the regionCode passed to the Prometheus tool.
+	Region  string
+	Queries []config.AnalysisQuery
+	// Lookback is how long before the alert logs are fetched from.
+	Lookback time.Duration
+	Now      func() time.Time
+}
+
+// New returns the analyzer configured by cfg, nil when no model is configured.
+func New(cfg *config.AnalysisConfig, deployments DeploymentSource) Analyzer {
+	if cfg == nil || cfg.LLMBaseURL == "" {
+		return nil
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	a := &LLMAnalyzer{
+		Provider:    NewOpenAIProvider(cfg.LLMBaseURL, cfg.LLMAPIKey, cfg.LLMModel, timeout),
+		Deployments: deployments,
+		Region:      cfg.PrometheusRegion,
+		Queries:     cfg.MetricQueries,
+		Lookback:    time.Duration(cfg.LookbackMinutes) * time.Minute,
+		Now:         time.Now,
+	}
+	// typed nil clients would not compare equal to nil in the interface fields
+	if c := NewMCPClient(cfg.PrometheusMCPURL, timeout); c != nil {
+		a.Prometheus = c
+	}
+	if c := NewMCPClient(cfg.ElasticsearchMCPURL, timeout); c != nil {
+		a.Elasticsearch = c
+	}
+	return a
+}
+
+const systemPrompt = `你是一名资深SRE，负责分析线上告警的根因。根据用户提供的告警、发布记录、指标和日志，判断问题是否由发版本导致，给出根因和处理建议。
+只输出一个JSON对象，不要输出其他内容，格式如下：
+{"problemType": "发版本导致的问题 或 非发版本导致的问题", "rootCause": "一到两句话的根因", "suggestions": ["处理建议"], "confidence": 0到1之间的小数}
+信息不足时如实说明并降低confidence。`
+
+// Analyze gathers the context of the issue and asks the model for the analysis.
+func (a *LLMAnalyzer) Analyze(ctx context.Context, in *Input) (*Result, error) {
+	messages := []Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: a.prompt(ctx, in)},
+	}
+	answer, err := a.Provider.Complete(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+	var res Result
+	if err := json.Unmarshal([]byte(stripFence(answer)), &res); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidResult, err)
+	}
+	if err := res.normalize(); err != nil {
+		return nil, err
+	}
+	res.Model = a.Provider.Model()
+	return &res, nil
+}
+
+// prompt describes the issue and its context in Markdown sections.
+func (a *LLMAnalyzer) prompt(ctx context.Context, in *Input) string {
+	var b strings.Builder
+	b.WriteString("## 告警\n")
+	fmt.Fprintf(&b, "- 标题：%s\n- 等级：%s\n- 服务：%s\n- 版本：%s\n- 区域：%s\n- 开始时间：%s\n",
+		in.Title, in.Level, in.Service, in.Version, in.Region, in.AlertSince.UTC().Format(time.RFC3339))
+	b.WriteString("\n## 告警标签\n")
+	keys := make([]string, 0, len(in.Labels))
+	for k := range in.Labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "- %s=%s\n", k, in.Labels[k])
+	}
+
+	if a.Deployments != nil && in.Service != "" {
+		b.WriteString("\n## 最近发布\n")
+		deployments, err := a.Deployments.RecentDeployments(ctx, in.Service, maxDeployments)
+		switch {
+		case err != nil:
+			a.unavailable(&b, in, "deployments", err)
+		case len(deployments) == 0:
+			b.WriteString("无\n")
+		}
+		for _, d := range deployments {
+			fmt.Fprintf(&b, "- %s 版本%s 区域%s 状态%s 开始%s 结束%s\n",
+				d.ID, d.Version, d.Region, d.Status, formatTime(d.Start), formatTime(d.Finish))
+		}
+	}
+
+	if a.Prometheus != nil && in.Service != "" {
+		for _, q := range a.Queries {
+			promql := strings.ReplaceAll(q.Query, "$service", in.Service)
+			fmt.Fprintf(&b, "\n## 指标 %s\n`%s`\n", q.Name, promql)
+			text, err := a.Prometheus.CallTool(ctx, PrometheusTool, map[string]any{"promql": promql, "regionCode": a.Region})
+			if err != nil {
+				a.unavailable(&b, in, "metric "+q.Name, err)
+				continue
+			}
+			writeBlock(&b, text)
+		}
+	}
+
+	if host := hostOf(in.Labels); a.Elasticsearch != nil && host != "" {
+		end := a.Now().UTC()
+		start := in.AlertSince.Add(-a.Lookback).UTC()
+		fmt.Fprintf(&b, "\n## 日志 %s\n", host)
+		text, err := a.Elasticsearch.CallTool(ctx, ElasticsearchTool, map[string]any{
+			"service":    in.Service,
+			"host_id":    host,
+			"start_time": start.Format(time.RFC3339),
+			"end_time":   end.Format(time.RFC3339),
+		})
+		if err != nil {
+			a.unavailable(&b, in, "logs", err)
+		} else {
+			writeBlock(&b, text)
+		}
+	}
+	return b.String()
+}
+
+// unavailable logs a context source that failed and tells the model so.
+func (a *LLMAnalyzer) unavailable(b *strings.Builder, in *Input, source string, err error) {
+	log.Warn().Err(err).Str("issue", in.IssueID).Str("source", source).Msg("analysis context unavailable")
+	b.WriteString("获取失败\n")
+}
+
+// hostOf returns the host the alert fired on, from host_id or the instance label.
+func hostOf(labels map[string]string) string {
+	if h := labels["host_id"]; h != "" {
+		return h
+	}
+	instance := labels["instance"]
+	if host, _, err := net.SplitHostPort(instance); err == nil {
+		return host
+	}
+	return instance
+}
+
+func writeBlock(b *strings.Builder, text string) {
+	if len(text) > maxToolOutput {
+		cut := maxToolOutput
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "\n…(已截断)"
+	}
+	b.WriteString("```\n")
+	b.WriteString(text)
+	b.WriteString("\n```\n")
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// stripFence removes a Markdown code fence some models wrap JSON answers in.
+func stripFence(s string) string {
+	s = strings.TrimSpace(s)
+	if rest, ok := strings.CutPrefix(s, "```"); ok {
+		if i := strings.IndexByte(rest, '\n'); i >= 0 {
+			rest = rest[i+1:]
+		}
+		s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(rest), "```"))
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is one chat message sent to a model.
+type Message struct {
+	Role    string `json:"role"` // system or user
+	Content string `json:"content"`
+}
+
+// Provider completes a chat with a model asked to answer in JSON.
+type Provider interface {
+	// Complete returns the content of the model's answer.
+	Complete(ctx context.Context, messages []Message) (string, error)
+	// Model names the model, recorded in Result.Model.
+	Model() string
+}
+
+// OpenAIProvider calls the chat completions endpoint of an OpenAI-compatible API.
+type OpenAIProvider struct {
+	BaseURL string
+	APIKey  string
+	Name    string
+	Client  *http.Client
+}
+
+// NewOpenAIProvider returns a provider for model at baseURL, e.g. https://api.openai.com/v1.
+func NewOpenAIProvider(baseURL, apiKey, model string, timeout time.Duration) *OpenAIProvider {
+	return &OpenAIProvider{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		Name:    model,
+		Client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *OpenAIProvider) Model() string { return p.Name }
+
+type chatRequest struct {
+	Model          string    `json:"model"`
+	Messages       []Message `json:"messages"`
+	Temperature    float64   `json:"temperature"`
+	ResponseFormat struct {
+		Type string `json:"type"`
+	} `json:"response_format"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message Message `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, messages []Message) (string, error) {
+	req := chatRequest{Model: p.Name, Messages: messages}
+	req.ResponseFormat.Type = "json_object"
+	body, err := json.Marshal(&req)
+	if err != nil {
+		return "", err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+	resp, err := p.Client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("chat completions: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return "", fmt.Errorf("chat completions: %w", err)
+	}
+
+	var out chatResponse
+	jsonErr := json.Unmarshal(data, &out)
+	if resp.StatusCode/100 != 2 {
+		if jsonErr == nil && out.Error != nil {
+			return "", fmt.Errorf("chat completions: %s: %s", resp.Status, out.Error.Message)
+		}
+		return "", fmt.Errorf("chat completions: %s", resp.Status)
+	}
+	if jsonErr != nil {
+		return "", fmt.Errorf("chat completions: decode response: %w", jsonErr)
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("chat completions: no choices in response")
+	}
+	return out.Choices[0].Message.Content, nil
+}
+
+// FakeResponse is the answer of a FakeProvider without Response.
+const FakeResponse = `{"problemType":"非发版本导致的问题","rootCause":"测试分析：告警期间错误率升高","suggestions":["检查服务日志","观察指标是否恢复"],"confidence":0.5}`
+
+// FakeProvider answers every chat with Response, or FakeResponse when it is empty, and
+// records the chats it was sent. It is meant for tests and local runs without a model.
+type FakeProvider struct {
+	Response string
+	Err      error
+
+	mu    sync.Mutex
+	chats [][]Message
+}
+
+func (p *FakeProvider) Model() string { return "fake" }
+
+func (p *FakeProvider) Complete(_ context.Context, messages []Message) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chats = append(p.chats, append([]Message(nil), messages...))
+	if p.Err != nil {
+		return "", p.Err
+	}
+	if p.Response == "" {
+		return FakeResponse, nil
+	}
+	return p.Response, nil
+}
+
+// Chats returns the chats sent so far.
+func (p *FakeProvider) Chats() [][]Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]Message(nil), p.chats...)
+}
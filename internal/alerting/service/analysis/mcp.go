@@ -0,0 +1,226 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxResponseBytes bounds the responses read from models and MCP servers.
+const maxResponseBytes = 4 << 20
+
+// mcpProtocolVersion is the MCP revision that introduced the streamable HTTP transport.
+const mcpProtocolVersion = "2025-03-26"
+
+// errSessionExpired is returned when the server no longer knows the session.
+var errSessionExpired = errors.New("mcp session expired")
+
+// MCPClient calls the tools of an MCP server over the streamable HTTP transport, as served
+// by mcp-server. The session is opened on the first call and reopened when it expires.
+type MCPClient struct {
+	URL    string
+	Client *http.Client
+
+	mu      sync.Mutex
+	session string
+	nextID  int64
+}
+
+// NewMCPClient returns a client of the MCP endpoint at url, nil when url is empty.
+func NewMCPClient(url string, timeout time.Duration) *MCPClient {
+	if url == "" {
+		return nil
+	}
+	return &MCPClient{URL: url, Client: &http.Client{Timeout: timeout}}
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      *int64 `json:"id,omitempty"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     *int64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type toolResult struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	IsError bool `json:"isError"`
+}
+
+// CallTool calls the tool name and returns the text of its result. A result flagged as
+// an error is returned as an error.
+func (c *MCPClient) CallTool(ctx context.Context, name string, args map[string]any) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	params := map[string]any{"name": name, "arguments": args}
+	var raw json.RawMessage
+	err := errSessionExpired
+	for attempt := 0; attempt < 2 && errors.Is(err, errSessionExpired); attempt++ {
+		if c.session == "" {
+			if err = c.initialize(ctx); err != nil {
+				return "", err
+			}
+		}
+		raw, err = c.call(ctx, "tools/call", params)
+		if errors.Is(err, errSessionExpired) {
+			c.session = ""
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("mcp %s: %w", name, err)
+	}
+
+	var res toolResult
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return "", fmt.Errorf("mcp %s: decode result: %w", name, err)
+	}
+	var texts []string
+	for _, content := range res.Content {
+		if content.Type == "text" {
+			texts = append(texts, content.Text)
+		}
+	}
+	text := strings.Join(texts, "\n")
+	if res.IsError {
+		return "", fmt.Errorf("mcp %s: %s", name, text)
+	}
+	return text, nil
+}
+
+func (c *MCPClient) initialize(ctx context.Context) error {
+	_, err := c.call(ctx, "initialize", map[string]any{
+		"protocolVersion": mcpProtocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": "zeroops", "version": "1.0.0"},
+	})
+	if err != nil {
+		return fmt.Errorf("mcp initialize: %w", err)
+	}
+	if _, err := c.post(ctx, &rpcRequest{JSONRPC: "2.0", Method: "notifications/initialized"}); err != nil {
+		return fmt.Errorf("mcp initialized: %w", err)
+	}
+	return nil
+}
+
+// call sends a request and returns its result.
+func (c *MCPClient) call(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	c.nextID++
+	id := c.nextID
+	resp, err := c.post(ctx, &rpcRequest{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil || resp.ID == nil || *resp.ID != id {
+		return nil, errors.New("no response to the request")
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("error %d: %s", resp.Error.Code, resp.Error.Message)
+	}
+	return resp.Result, nil
+}
+
+// post sends one message. The answer to a request is either a JSON body or an event
+// stream carrying it; a notification gets no answer.
+func (c *MCPClient) post(ctx context.Context, msg *rpcRequest) (*rpcResponse, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if c.session != "" {
+		req.Header.Set("Mcp-Session-Id", c.session)
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound && c.session != "" {
+		return nil, errSessionExpired
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	if session := resp.Header.Get("Mcp-Session-Id"); session != "" {
+		c.session = session
+	}
+	if msg.ID == nil {
+		return nil, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		return readEvents(io.LimitReader(resp.Body, maxResponseBytes), *msg.ID)
+	}
+	var out rpcResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return &out, nil
+}
+
+// readEvents returns the response with id from a server-sent event stream, skipping the
+// notifications the server sends before it.
+func readEvents(r io.Reader, id int64) (*rpcResponse, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxResponseBytes)
+	var data strings.Builder
+	flush := func() (*rpcResponse, error) {
+		defer data.Reset()
+		if data.Len() == 0 {
+			return nil, nil
+		}
+		var out rpcResponse
+		if err := json.Unmarshal([]byte(data.String()), &out); err != nil {
+			return nil, fmt.Errorf("decode event: %w", err)
+		}
+		if out.ID == nil || *out.ID != id {
+			return nil, nil
+		}
+		return &out, nil
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if out, err := flush(); out != nil || err != nil {
+				return out, err
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return flush()
+}
@@ -0,0 +1,457 @@
+// Package analytics aggregates alert issues over time: issue counts, time to acknowledge
+// and to restore, auto-remediation success, flapping alerts and noisy rules per service,
+// alertname and level. Past days are read from daily rollups that Run keeps up to date;
+// the current day is computed from the issues on every query.
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+)
+
+var ErrInvalid = errors.New("invalid analytics request")
+
+const (
+	// DefaultRangeDays is the number of days queried when no range is given.
+	DefaultRangeDays = 7
+	// MaxRangeDays bounds the days of one query or backfill.
+	MaxRangeDays = 366
+	// DefaultFlappingMin is the number of issues in the range from which an alert
+	// fingerprint counts as flapping.
+	DefaultFlappingMin = 3
+	DefaultLimit       = 10
+	MaxLimit           = 100
+)
+
+// Dimensions are the values of groupBy.
+var Dimensions = []string{"day", "service", "alertname", "level"}
+
+// DefaultGroupBy groups issue statistics when no groupBy is given.
+var DefaultGroupBy = []string{"service", "alertname", "level"}
+
+// Query selects the issues whose alert started on a day in [From, To), UTC midnights.
+type Query struct {
+	From   time.Time
+	To     time.Time
+	Filter adb.RollupFilter
+}
+
+// Durations summarizes durations in seconds. Percentiles are estimated from histogram
+// buckets; all but Count are null without durations.
+type Durations struct {
+	Count int      `json:"count"`
+	Mean  *float64 `json:"mean"`
+	P50   *float64 `json:"p50"`
+	P90   *float64 `json:"p90"`
+	P99   *float64 `json:"p99"`
+}
+
+// Remediation counts issues with an auto-remediation attempt and those that
+// auto-remediation restored.
+type Remediation struct {
+	Attempted   int      `json:"attempted"`
+	Succeeded   int      `json:"succeeded"`
+	SuccessRate *float64 `json:"successRate"`
+}
+
+type Stats struct {
+	Issues            int         `json:"issues"`
+	TimeToAcknowledge Durations   `json:"timeToAcknowledge"`
+	TimeToRestore     Durations   `json:"timeToRestore"`
+	Remediation       Remediation `json:"remediation"`
+}
+
+// Group is the statistics of the issues sharing the groupBy dimensions; other
+// dimensions are omitted.
+type Group struct {
+	Day       string `json:"day,omitempty"`
+	Service   string `json:"service,omitempty"`
+	AlertName string `json:"alertname,omitempty"`
+	Level     string `json:"level,omitempty"`
+	Stats     Stats  `json:"stats"`
+}
+
+// IssueStats is the result of Issues. From and To are inclusive days.
+type IssueStats struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	GroupBy []string `json:"groupBy"`
+	Total   Stats    `json:"total"`
+	Groups  []Group  `json:"groups"`
+}
+
+// FlappingAlert is an alert fingerprint that opened many issues in the range.
+type FlappingAlert struct {
+	Fingerprint string `json:"fingerprint"`
+	Service     string `json:"service"`
+	AlertName   string `json:"alertname"`
+	Level       string `json:"level"`
+	Issues      int    `json:"issues"`
+	// Days is the number of days with an issue.
+	Days int `json:"days"`
+}
+
+// NoisyRule is an alert rule ranked by the issues it opened.
+type NoisyRule struct {
+	AlertName           string   `json:"alertname"`
+	Issues              int      `json:"issues"`
+	Services            int      `json:"services"`
+	Unacknowledged      int      `json:"unacknowledged"`
+	UnacknowledgedRatio float64  `json:"unacknowledgedRatio"`
+	AutoRestored        int      `json:"autoRestored"`
+	FlappingAlerts      int      `json:"flappingAlerts"`
+	MeanTimeToRestore   *float64 `json:"meanTimeToRestore"`
+}
+
+type Service struct {
+	DB  adb.AnalyticsRepository
+	Now func() time.Time
+}
+
+func NewService(db adb.AnalyticsRepository) *Service {
+	return &Service{DB: db, Now: time.Now}
+}
+
+// ParseRange parses the inclusive days from and to (YYYY-MM-DD, UTC) into a query range.
+// to defaults to today and from to DefaultRangeDays days up to to.
+func ParseRange(from, to string, now time.Time) (time.Time, time.Time, error) {
+	today := day(now)
+	end := today
+	if to != "" {
+		t, err := time.Parse(time.DateOnly, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be a date like 2006-01-02", ErrInvalid)
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, 1-DefaultRangeDays)
+	if from != "" {
+		t, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be a date like 2006-01-02", ErrInvalid)
+		}
+		start = t
+	}
+	switch {
+	case end.After(today):
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: to is in the future", ErrInvalid)
+	case start.After(end):
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from is after to", ErrInvalid)
+	case end.Sub(start) >= MaxRangeDays*24*time.Hour:
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: range exceeds %d days", ErrInvalid, MaxRangeDays)
+	}
+	return start, end.AddDate(0, 0, 1), nil
+}
+
+// ParseGroupBy parses a comma separated list of Dimensions, DefaultGroupBy when empty.
+func ParseGroupBy(s string) ([]string, error) {
+	if s == "" {
+		return DefaultGroupBy, nil
+	}
+	var out []string
+	for d := range strings.SplitSeq(s, ",") {
+		d = strings.TrimSpace(d)
+		if !slices.Contains(Dimensions, d) {
+			return nil, fmt.Errorf("%w: groupBy must be a list of %s", ErrInvalid, strings.Join(Dimensions, ", "))
+		}
+		if !slices.Contains(out, d) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+// Issues returns the statistics of the issues of q, in total and per group of groupBy.
+func (s *Service) Issues(ctx context.Context, q Query, groupBy []string) (*IssueStats, error) {
+	rollups, _, err := s.load(ctx, q, false)
+	if err != nil {
+		return nil, err
+	}
+	total := newAccumulator()
+	groups := make(map[Group]*accumulator)
+	for i := range rollups {
+		r := &rollups[i]
+		total.add(r)
+		var g Group
+		for _, d := range groupBy {
+			switch d {
+			case "day":
+				g.Day = r.Day.Format(time.DateOnly)
+			case "service":
+				g.Service = r.Service
+			case "alertname":
+				g.AlertName = r.AlertName
+			case "level":
+				g.Level = r.Level
+			}
+		}
+		a := groups[g]
+		if a == nil {
+			a = newAccumulator()
+			groups[g] = a
+		}
+		a.add(r)
+	}
+	out := &IssueStats{From: q.From.Format(time.DateOnly), To: q.To.AddDate(0, 0, -1).Format(time.DateOnly),
+		GroupBy: groupBy, Total: total.stats(), Groups: make([]Group, 0, len(groups))}
+	for g, a := range groups {
+		g.Stats = a.stats()
+		out.Groups = append(out.Groups, g)
+	}
+	sort.Slice(out.Groups, func(i, j int) bool {
+		a, b := out.Groups[i], out.Groups[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.Stats.Issues != b.Stats.Issues {
+			return a.Stats.Issues > b.Stats.Issues
+		}
+		return a.Service+"\x00"+a.AlertName+"\x00"+a.Level < b.Service+"\x00"+b.AlertName+"\x00"+b.Level
+	})
+	return out, nil
+}
+
+// Flapping returns up to limit alert fingerprints with at least minIssues issues in q,
+// most issues first.
+func (s *Service) Flapping(ctx context.Context, q Query, minIssues, limit int) ([]FlappingAlert, error) {
+	if minIssues < 1 || limit < 1 || limit > MaxLimit {
+		return nil, fmt.Errorf("%w: minIssues must be positive and limit within 1..%d", ErrInvalid, MaxLimit)
+	}
+	_, fingerprints, err := s.load(ctx, q, true)
+	if err != nil {
+		return nil, err
+	}
+	out := flapping(fingerprints, minIssues)
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// NoisyRules returns up to limit alert rules of q, most issues first.
+func (s *Service) NoisyRules(ctx context.Context, q Query, limit int) ([]NoisyRule, error) {
+	if limit < 1 || limit > MaxLimit {
+		return nil, fmt.Errorf("%w: limit must be within 1..%d", ErrInvalid, MaxLimit)
+	}
+	rollups, fingerprints, err := s.load(ctx, q, true)
+	if err != nil {
+		return nil, err
+	}
+	type rule struct {
+		NoisyRule
+		services        map[string]bool
+		restored        int
+		restoredSeconds float64
+	}
+	rules := make(map[string]*rule)
+	for _, r := range rollups {
+		n := rules[r.AlertName]
+		if n == nil {
+			n = &rule{NoisyRule: NoisyRule{AlertName: r.AlertName}, services: make(map[string]bool)}
+			rules[r.AlertName] = n
+		}
+		n.Issues += r.Issues
+		n.Unacknowledged += r.Issues - r.Acked
+		n.AutoRestored += r.AutoRestored
+		n.services[r.Service] = true
+		n.restored += r.Restored
+		n.restoredSeconds += r.RestoreSeconds
+	}
+	for _, f := range flapping(fingerprints, DefaultFlappingMin) {
+		if n := rules[f.AlertName]; n != nil {
+			n.FlappingAlerts++
+		}
+	}
+	out := make([]NoisyRule, 0, len(rules))
+	for _, n := range rules {
+		n.Services = len(n.services)
+		if n.Issues > 0 {
+			n.UnacknowledgedRatio = float64(n.Unacknowledged) / float64(n.Issues)
+		}
+		if n.restored > 0 {
+			mean := n.restoredSeconds / float64(n.restored)
+			n.MeanTimeToRestore = &mean
+		}
+		out = append(out, n.NoisyRule)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Issues != out[j].Issues {
+			return out[i].Issues > out[j].Issues
+		}
+		return out[i].AlertName < out[j].AlertName
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// load returns the rollups of q: stored ones for the days before today and computed
+// ones for today. Fingerprint rollups are only loaded when fingerprints is set.
+func (s *Service) load(ctx context.Context, q Query, fingerprints bool) ([]adb.IssueRollup, []adb.FingerprintRollup, error) {
+	today := day(s.Now())
+	storedTo := q.To
+	if storedTo.After(today) {
+		storedTo = today
+	}
+	var issues []adb.IssueRollup
+	var fps []adb.FingerprintRollup
+	if storedTo.After(q.From) {
+		var err error
+		if issues, err = s.DB.ListIssueRollups(ctx, q.From, storedTo, q.Filter); err != nil {
+			return nil, nil, err
+		}
+		if fingerprints {
+			if fps, err = s.DB.ListFingerprintRollups(ctx, q.From, storedTo, q.Filter); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if q.To.After(today) {
+		liveFrom := q.From
+		if liveFrom.Before(today) {
+			liveFrom = today
+		}
+		facts, err := s.DB.ListIssueFacts(ctx, liveFrom, q.To)
+		if err != nil {
+			return nil, nil, err
+		}
+		live, liveFps := rollup(facts, s.Now().UTC())
+		for _, r := range live {
+			if match(q.Filter, r.Service, r.AlertName, r.Level) {
+				issues = append(issues, r)
+			}
+		}
+		for _, r := range liveFps {
+			if fingerprints && match(q.Filter, r.Service, r.AlertName, r.Level) {
+				fps = append(fps, r)
+			}
+		}
+	}
+	return issues, fps, nil
+}
+
+func flapping(fingerprints []adb.FingerprintRollup, minIssues int) []FlappingAlert {
+	byFingerprint := make(map[string]*FlappingAlert)
+	for _, r := range fingerprints {
+		f := byFingerprint[r.Fingerprint]
+		if f == nil {
+			f = &FlappingAlert{Fingerprint: r.Fingerprint}
+			byFingerprint[r.Fingerprint] = f
+		}
+		// the labels of the latest day win
+		f.Service, f.AlertName, f.Level = r.Service, r.AlertName, r.Level
+		f.Issues += r.Issues
+		f.Days++
+	}
+	out := []FlappingAlert{}
+	for _, f := range byFingerprint {
+		if f.Issues >= minIssues {
+			out = append(out, *f)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Issues != out[j].Issues {
+			return out[i].Issues > out[j].Issues
+		}
+		return out[i].Fingerprint < out[j].Fingerprint
+	})
+	return out
+}
+
+func match(f adb.RollupFilter, service, alertName, level string) bool {
+	return (f.Service == "" || f.Service == service) && (f.AlertName == "" || f.AlertName == alertName) &&
+		(f.Level == "" || f.Level == level)
+}
+
+// accumulator sums rollups.
+type accumulator struct {
+	issues, acked, restored, remediated, autoRestored int
+	ackSeconds, restoreSeconds                        float64
+	ackBuckets, restoreBuckets                        []int64
+}
+
+func newAccumulator() *accumulator {
+	return &accumulator{ackBuckets: make([]int64, len(Buckets)+1), restoreBuckets: make([]int64, len(Buckets)+1)}
+}
+
+func (a *accumulator) add(r *adb.IssueRollup) {
+	a.issues += r.Issues
+	a.acked += r.Acked
+	a.restored += r.Restored
+	a.remediated += r.Remediated
+	a.autoRestored += r.AutoRestored
+	a.ackSeconds += r.AckSeconds
+	a.restoreSeconds += r.RestoreSeconds
+	for i := range min(len(r.AckBuckets), len(a.ackBuckets)) {
+		a.ackBuckets[i] += r.AckBuckets[i]
+	}
+	for i := range min(len(r.RestoreBuckets), len(a.restoreBuckets)) {
+		a.restoreBuckets[i] += r.RestoreBuckets[i]
+	}
+}
+
+func (a *accumulator) stats() Stats {
+	st := Stats{
+		Issues:            a.issues,
+		TimeToAcknowledge: durations(a.acked, a.ackSeconds, a.ackBuckets),
+		TimeToRestore:     durations(a.restored, a.restoreSeconds, a.restoreBuckets),
+		Remediation:       Remediation{Attempted: a.remediated, Succeeded: a.autoRestored},
+	}
+	if a.remediated > 0 {
+		rate := float64(a.autoRestored) / float64(a.remediated)
+		st.Remediation.SuccessRate = &rate
+	}
+	return st
+}
+
+func durations(count int, seconds float64, buckets []int64) Durations {
+	d := Durations{Count: count}
+	if count == 0 {
+		return d
+	}
+	mean := seconds / float64(count)
+	d.Mean = &mean
+	d.P50, d.P90, d.P99 = quantile(0.5, buckets), quantile(0.9, buckets), quantile(0.99, buckets)
+	return d
+}
+
+// quantile estimates the q-quantile of a histogram over Buckets by linear interpolation
+// within the bucket holding it, like Prometheus' histogram_quantile. Durations beyond the
+// last bound are reported as that bound.
+func quantile(q float64, buckets []int64) *float64 {
+	var total int64
+	for _, n := range buckets {
+		total += n
+	}
+	if total == 0 {
+		return nil
+	}
+	rank := q * float64(total)
+	var cumulative int64
+	for i, n := range buckets {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		if i >= len(Buckets) {
+			v := Buckets[len(Buckets)-1]
+			return &v
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = Buckets[i-1]
+		}
+		v := lower + (Buckets[i]-lower)*(rank-float64(cumulative))/float64(n)
+		return &v
+	}
+	v := Buckets[len(Buckets)-1]
+	return &v
+}
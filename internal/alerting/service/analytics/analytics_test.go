@@ -0,0 +1,124 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/database/memory"
+)
+
+var now = time.Date(2026, 3, 5, 12, 0, 0, 0, time.UTC)
+
+func addIssue(t *testing.T, db *memory.Store, id, service, alertName, fingerprint string, since time.Time, events ...adb.IssueEvent) {
+	t.Helper()
+	ctx := context.Background()
+	it := &adb.Issue{ID: id, State: "Open", Level: "P1", AlertState: "Pending", Fingerprint: fingerprint, AlertSince: since,
+		Labels: []byte(`[{"key":"service","value":"` + service + `"},{"key":"alertname","value":"` + alertName + `"}]`)}
+	if err := db.InsertIssue(ctx, it); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range events {
+		e.IssueID = id
+		if _, err := db.AddIssueEvent(ctx, &e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRollupAndQuery(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	day1 := time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	// two issues acked after 60s and 600s, the first restored by auto-remediation
+	addIssue(t, db, "issue-1", "storage", "HighErrorRate", "fp-1", day1,
+		adb.IssueEvent{Type: adb.EventCreated, At: day1},
+		adb.IssueEvent{Type: adb.EventAcked, At: day1.Add(time.Minute)},
+		adb.IssueEvent{Type: adb.EventRemediation, At: day1.Add(2 * time.Minute), Actor: adb.ActorRemediation},
+		adb.IssueEvent{Type: adb.EventState, At: day1.Add(5 * time.Minute), Actor: adb.ActorRemediation, To: "Restored"})
+	addIssue(t, db, "issue-2", "storage", "HighErrorRate", "fp-1", day2,
+		adb.IssueEvent{Type: adb.EventCreated, At: day2},
+		adb.IssueEvent{Type: adb.EventAcked, At: day2.Add(10 * time.Minute)},
+		adb.IssueEvent{Type: adb.EventRemediation, At: day2.Add(11 * time.Minute), Actor: adb.ActorRemediation},
+		adb.IssueEvent{Type: adb.EventState, At: day2.Add(time.Hour), Actor: "alice", To: "Restored"})
+	addIssue(t, db, "issue-3", "gateway", "HighLatency", "fp-2", day2)
+	// today's issue is computed live
+	addIssue(t, db, "issue-4", "storage", "HighErrorRate", "fp-1", now.Add(-time.Hour))
+
+	s := NewService(db)
+	s.Now = func() time.Time { return now }
+	from, to, err := ParseRange("", "", now)
+	if err != nil || from.Format(time.DateOnly) != "2026-02-27" || to.Format(time.DateOnly) != "2026-03-06" {
+		t.Fatalf("range = %v..%v, %v", from, to, err)
+	}
+	if days, err := s.Rollup(ctx, from, now); err != nil || days != 6 {
+		t.Fatalf("rollup = %d, %v", days, err)
+	}
+
+	stats, err := s.Issues(ctx, Query{From: from, To: to}, []string{"service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := stats.Total
+	if total.Issues != 4 || total.TimeToAcknowledge.Count != 2 || *total.TimeToAcknowledge.Mean != 330 {
+		t.Fatalf("total = %+v", total)
+	}
+	if total.Remediation.Attempted != 2 || total.Remediation.Succeeded != 1 || *total.Remediation.SuccessRate != 0.5 {
+		t.Fatalf("remediation = %+v", total.Remediation)
+	}
+	// 300s and 3600s fall into the (120, 300] and (1800, 3600] buckets
+	if ttr := total.TimeToRestore; ttr.Count != 2 || *ttr.P50 != 300 || *ttr.P99 < 1800 || *ttr.P99 > 3600 {
+		t.Fatalf("time to restore = %+v", ttr)
+	}
+	if len(stats.Groups) != 2 || stats.Groups[0].Service != "storage" || stats.Groups[0].Stats.Issues != 3 || stats.Groups[0].AlertName != "" {
+		t.Fatalf("groups = %+v", stats.Groups)
+	}
+
+	filtered, err := s.Issues(ctx, Query{From: from, To: to, Filter: adb.RollupFilter{Service: "gateway"}}, DefaultGroupBy)
+	if err != nil || filtered.Total.Issues != 1 || filtered.Total.TimeToAcknowledge.Mean != nil {
+		t.Fatalf("filtered = %+v, %v", filtered, err)
+	}
+
+	flaps, err := s.Flapping(ctx, Query{From: from, To: to}, DefaultFlappingMin, DefaultLimit)
+	if err != nil || len(flaps) != 1 || flaps[0].Fingerprint != "fp-1" || flaps[0].Issues != 3 || flaps[0].Days != 3 {
+		t.Fatalf("flapping = %+v, %v", flaps, err)
+	}
+	rules, err := s.NoisyRules(ctx, Query{From: from, To: to}, DefaultLimit)
+	if err != nil || len(rules) != 2 {
+		t.Fatalf("noisy rules = %+v, %v", rules, err)
+	}
+	if r := rules[0]; r.AlertName != "HighErrorRate" || r.Issues != 3 || r.Unacknowledged != 1 || r.AutoRestored != 1 || r.FlappingAlerts != 1 {
+		t.Fatalf("noisiest rule = %+v", r)
+	}
+
+	// acks recorded after the refresh only show up once the day is rolled up again
+	if _, err := db.AckIssue(ctx, "issue-3", "bob", day2.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := s.Issues(ctx, Query{From: from, To: to}, nil); stats.Total.TimeToAcknowledge.Count != 2 {
+		t.Fatalf("ack before refresh = %+v", stats.Total)
+	}
+	if _, err := s.Rollup(ctx, from, now); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := s.Issues(ctx, Query{From: from, To: to}, nil); stats.Total.TimeToAcknowledge.Count != 3 {
+		t.Fatalf("ack after refresh = %+v", stats.Total)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, c := range [][2]string{{"2026-03-06", ""}, {"2026-03-05", "2026-03-04"}, {"2025-01-01", "2026-03-05"}, {"03/01", ""}} {
+		if _, _, err := ParseRange(c[0], c[1], now); !errors.Is(err, ErrInvalid) {
+			t.Errorf("ParseRange(%q, %q) = %v", c[0], c[1], err)
+		}
+	}
+	if g, err := ParseGroupBy("day, level,day"); err != nil || len(g) != 2 || g[1] != "level" {
+		t.Fatalf("groupBy = %v, %v", g, err)
+	}
+	if _, err := ParseGroupBy("region"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("groupBy region = %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package analytics
+
+import (
+	"context"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/rs/zerolog/log"
+)
+
+// Buckets are the upper bounds in seconds of the duration histograms kept in the
+// rollups; one more bucket counts longer durations. Changing them requires rolling up
+// every stored day again.
+var Buckets = []float64{30, 60, 120, 300, 600, 900, 1800, 3600, 7200, 14400, 28800, 86400, 259200, 604800}
+
+type groupKey struct {
+	day                       time.Time
+	service, alertName, level string
+}
+
+// rollup aggregates issue facts into daily rollups stamped with now.
+func rollup(facts []adb.IssueFact, now time.Time) ([]adb.IssueRollup, []adb.FingerprintRollup) {
+	groups := make(map[groupKey]*adb.IssueRollup)
+	var order []groupKey
+	fingerprints := make(map[[2]string]*adb.FingerprintRollup)
+	var fpOrder [][2]string
+	for i := range facts {
+		f := &facts[i]
+		labels := adb.LabelMap(f.Labels)
+		k := groupKey{day: day(f.AlertSince), service: labels["service"], alertName: labels["alertname"], level: f.Level}
+		r := groups[k]
+		if r == nil {
+			r = &adb.IssueRollup{Day: k.day, Service: k.service, AlertName: k.alertName, Level: k.level,
+				AckBuckets: make([]int64, len(Buckets)+1), RestoreBuckets: make([]int64, len(Buckets)+1), UpdatedAt: now}
+			groups[k] = r
+			order = append(order, k)
+		}
+		r.Issues++
+		if f.AckedAt != nil {
+			detected := f.AlertSince
+			if f.CreatedAt != nil {
+				detected = *f.CreatedAt
+			}
+			seconds := max(f.AckedAt.Sub(detected).Seconds(), 0)
+			r.Acked++
+			r.AckSeconds += seconds
+			r.AckBuckets[bucket(seconds)]++
+		}
+		if f.RestoredAt != nil {
+			seconds := max(f.RestoredAt.Sub(f.AlertSince).Seconds(), 0)
+			r.Restored++
+			r.RestoreSeconds += seconds
+			r.RestoreBuckets[bucket(seconds)]++
+		}
+		if f.Remediated {
+			r.Remediated++
+			if f.RestoredAt != nil && f.RestoredBy == adb.ActorRemediation {
+				r.AutoRestored++
+			}
+		}
+
+		if f.Fingerprint == "" {
+			continue
+		}
+		fk := [2]string{k.day.Format(time.DateOnly), f.Fingerprint}
+		fr := fingerprints[fk]
+		if fr == nil {
+			fr = &adb.FingerprintRollup{Day: k.day, Fingerprint: f.Fingerprint, Service: k.service, AlertName: k.alertName, Level: k.level}
+			fingerprints[fk] = fr
+			fpOrder = append(fpOrder, fk)
+		}
+		fr.Issues++
+	}
+
+	issues := make([]adb.IssueRollup, 0, len(order))
+	for _, k := range order {
+		issues = append(issues, *groups[k])
+	}
+	fps := make([]adb.FingerprintRollup, 0, len(fpOrder))
+	for _, k := range fpOrder {
+		fps = append(fps, *fingerprints[k])
+	}
+	return issues, fps
+}
+
+// Rollup recomputes and stores the rollups of the days in [from, to), truncated to UTC
+// days, and returns the number of days.
+func (s *Service) Rollup(ctx context.Context, from, to time.Time) (int, error) {
+	from, to = day(from), day(to)
+	if !to.After(from) {
+		return 0, nil
+	}
+	facts, err := s.DB.ListIssueFacts(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	issues, fingerprints := rollup(facts, s.Now().UTC())
+	if err := s.DB.ReplaceRollups(ctx, from, to, issues, fingerprints); err != nil {
+		return 0, err
+	}
+	return int(to.Sub(from) / (24 * time.Hour)), nil
+}
+
+// Run rolls up the days days before today at start and then every interval, so acks and
+// restores that happen after an issue's day are picked up. It returns when ctx is done.
+func (s *Service) Run(ctx context.Context, interval time.Duration, days int) {
+	if interval <= 0 || days <= 0 {
+		return
+	}
+	refresh := func() {
+		today := day(s.Now())
+		if _, err := s.Rollup(ctx, today.AddDate(0, 0, -days), today); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("failed to roll up issue analytics")
+		}
+	}
+	refresh()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// bucket returns the index of the histogram bucket of seconds.
+func bucket(seconds float64) int {
+	for i, b := range Buckets {
+		if seconds <= b {
+			return i
+		}
+	}
+	return len(Buckets)
+}
+
+func day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
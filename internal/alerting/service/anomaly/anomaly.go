@@ -0,0 +1,399 @@
+// Package anomaly detects statistical anomalies in per-service metric series. The series
+// are PromQL queries from the config; detector parameters are set per service and series
+// and stored as service alert metas. A detector scores every point against an expected
+// value, learnt with an EWMA, a rolling median or a seasonal baseline, and an anomaly is a
+// run of consecutive points beyond the threshold. Anomalies that persist up to now are
+// raised through the alert receiver, and a backtest replays a historic range so the
+// parameters can be tuned.
+package anomaly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrInvalid      = errors.New("invalid anomaly detector")
+	ErrNotFound     = errors.New("anomaly detector not found")
+	ErrNoPrometheus = errors.New("prometheus is not configured")
+)
+
+// ServiceVar stands for the service name in the query of a series.
+const ServiceVar = "$service"
+
+// MetaPrefix starts the service alert meta keys of the detectors, which are
+// anomaly.<series>.<param>, e.g. anomaly.latency_p99.threshold.
+const MetaPrefix = "anomaly."
+
+// Detectors.
+const (
+	EWMA     = "ewma"     // exponentially weighted moving average and variance
+	MAD      = "mad"      // median and median absolute deviation of the last Window
+	Seasonal = "seasonal" // median and MAD of the same time in the previous Seasons periods
+)
+
+// Directions of the deviations that count as anomalous.
+const (
+	Up   = "up"
+	Down = "down"
+	Both = "both"
+)
+
+const defaultStep = time.Minute
+
+// seriesPattern keeps series names free of the '.' separating the parts of meta keys.
+var seriesPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+var levels = map[string]bool{"P0": true, "P1": true, "P2": true, "Warning": true}
+
+// Params are the parameters of the detector of one series of a service. Zero values take
+// the defaults.
+type Params struct {
+	Detector string `json:"detector"` // ewma, mad or seasonal
+	// Threshold is the score above which a point is anomalous. The score is the deviation
+	// from the expected value in standard deviations, estimated robustly. Default 3.
+	Threshold float64 `json:"threshold,omitempty"`
+	// Persist is the number of consecutive anomalous points that make an anomaly. Default 3.
+	Persist   int    `json:"persist,omitempty"`
+	Direction string `json:"direction,omitempty"` // up, down or both (default)
+	// Window is the history the ewma and mad detectors learn from, default 1h.
+	Window string `json:"window,omitempty"`
+	// Alpha is the smoothing factor of ewma, default 0.3.
+	Alpha float64 `json:"alpha,omitempty"`
+	// The seasonal detector compares a point with the points Period, 2*Period, ...,
+	// Seasons*Period earlier. Defaults 1d and 7.
+	Period  string `json:"period,omitempty"`
+	Seasons int    `json:"seasons,omitempty"`
+	// MinScale bounds the standard deviation from below, so small wobbles of a flat series
+	// do not score high. The bound is at least 1% of the expected value.
+	MinScale float64 `json:"minScale,omitempty"`
+	// Severity is the level of the issues raised, default P2.
+	Severity string `json:"severity,omitempty"`
+}
+
+// Detector is the detector of one series of a service.
+type Detector struct {
+	Service string `json:"service"`
+	Series  string `json:"series"`
+	// Query is the PromQL of the series for the service; "" when the series is no longer
+	// configured, and the detector does not run.
+	Query  string `json:"query"`
+	Params Params `json:"params"`
+}
+
+// Service manages the detectors and runs them against Prometheus.
+type Service struct {
+	DB adb.ServiceAlertMetaRepository
+	// Source queries the series; nil when Prometheus is not configured.
+	Source Source
+	Series map[string]string // name to query
+	Step   time.Duration
+	Now    func() time.Time
+
+	mu sync.Mutex
+	// active holds the StartsAt of the alert raised for each firing service/series, so
+	// the alert is deduplicated while the anomaly lasts.
+	active map[string]time.Time
+}
+
+// NewService validates the series of cfg, which can be nil when only the stored detectors
+// are managed.
+func NewService(db adb.ServiceAlertMetaRepository, source Source, cfg *config.AnomalyConfig) (*Service, error) {
+	s := &Service{
+		DB:     db,
+		Source: source,
+		Series: make(map[string]string),
+		Step:   defaultStep,
+		Now:    time.Now,
+		active: make(map[string]time.Time),
+	}
+	if cfg == nil {
+		return s, nil
+	}
+	if cfg.StepSeconds > 0 {
+		s.Step = time.Duration(cfg.StepSeconds) * time.Second
+	}
+	for i, series := range cfg.Series {
+		if !seriesPattern.MatchString(series.Name) {
+			return nil, fmt.Errorf("anomaly series %d: name %q must be 1-64 letters, digits, '_' or '-'", i, series.Name)
+		}
+		if _, ok := s.Series[series.Name]; ok {
+			return nil, fmt.Errorf("anomaly series %s is defined twice", series.Name)
+		}
+		if !strings.Contains(series.Query, ServiceVar) {
+			return nil, fmt.Errorf("anomaly series %s: query must select the service with %s", series.Name, ServiceVar)
+		}
+		s.Series[series.Name] = series.Query
+	}
+	return s, nil
+}
+
+// query returns the query of series for service, "" when the series is not configured.
+func (s *Service) query(service, series string) string {
+	q, ok := s.Series[series]
+	if !ok {
+		return ""
+	}
+	quoted := strconv.Quote(service)
+	return strings.ReplaceAll(q, ServiceVar, quoted[1:len(quoted)-1])
+}
+
+// List returns the detectors of service, or of every service when it is "", ordered by
+// service and series. Detectors whose stored parameters are invalid are skipped.
+func (s *Service) List(ctx context.Context, service string) ([]Detector, error) {
+	metas, err := s.DB.ListServiceAlertMetas(ctx, strings.TrimSpace(service), MetaPrefix)
+	if err != nil {
+		return nil, err
+	}
+	grouped := make(map[[2]string]map[string]string)
+	for _, m := range metas {
+		series, param, ok := strings.Cut(strings.TrimPrefix(m.Key, MetaPrefix), ".")
+		if !ok {
+			continue
+		}
+		key := [2]string{m.Service, series}
+		if grouped[key] == nil {
+			grouped[key] = make(map[string]string)
+		}
+		grouped[key][param] = m.Value
+	}
+
+	out := make([]Detector, 0, len(grouped))
+	for key, values := range grouped {
+		p, err := paramsFromMetas(values)
+		if err == nil {
+			err = p.normalize(s.Step)
+		}
+		if err != nil {
+			log.Warn().Err(err).Str("service", key[0]).Str("series", key[1]).Msg("skipping anomaly detector with invalid parameters")
+			continue
+		}
+		out = append(out, Detector{Service: key[0], Series: key[1], Query: s.query(key[0], key[1]), Params: p})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Service != out[j].Service {
+			return out[i].Service < out[j].Service
+		}
+		return out[i].Series < out[j].Series
+	})
+	return out, nil
+}
+
+// get returns the detector of series for service, nil when none is set.
+func (s *Service) get(ctx context.Context, service, series string) (*Detector, error) {
+	detectors, err := s.List(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	for i := range detectors {
+		if detectors[i].Series == series {
+			return &detectors[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Put validates p and sets it as the detector of series for service.
+func (s *Service) Put(ctx context.Context, service, series string, p *Params) (*Detector, error) {
+	service = strings.TrimSpace(service)
+	if service == "" {
+		return nil, fmt.Errorf("%w: service is required", ErrInvalid)
+	}
+	query := s.query(service, series)
+	if query == "" {
+		return nil, fmt.Errorf("%w: series %q is not configured", ErrInvalid, series)
+	}
+	params := *p
+	if err := params.normalize(s.Step); err != nil {
+		return nil, err
+	}
+	if _, err := s.DB.ReplaceServiceAlertMetas(ctx, service, metaPrefix(series), params.metas(series)); err != nil {
+		return nil, err
+	}
+	return &Detector{Service: service, Series: series, Query: query, Params: params}, nil
+}
+
+// Delete removes the detector of series for service.
+func (s *Service) Delete(ctx context.Context, service, series string) error {
+	n, err := s.DB.ReplaceServiceAlertMetas(ctx, service, metaPrefix(series), nil)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func metaPrefix(series string) string { return MetaPrefix + series + "." }
+
+// normalize applies the defaults and validates p against the query step.
+func (p *Params) normalize(step time.Duration) error {
+	switch p.Detector {
+	case EWMA, MAD, Seasonal:
+	default:
+		return fmt.Errorf("%w: detector must be %s, %s or %s", ErrInvalid, EWMA, MAD, Seasonal)
+	}
+	if p.Threshold == 0 {
+		p.Threshold = 3
+	}
+	if p.Persist == 0 {
+		p.Persist = 3
+	}
+	if p.Direction == "" {
+		p.Direction = Both
+	}
+	if p.Window == "" {
+		p.Window = "1h"
+	}
+	if p.Alpha == 0 {
+		p.Alpha = 0.3
+	}
+	if p.Period == "" {
+		p.Period = "1d"
+	}
+	if p.Seasons == 0 {
+		p.Seasons = 7
+	}
+	if p.Severity == "" {
+		p.Severity = "P2"
+	}
+
+	if p.Threshold < 0 {
+		return fmt.Errorf("%w: threshold must be positive", ErrInvalid)
+	}
+	if p.Persist < 1 || p.Persist > 100 {
+		return fmt.Errorf("%w: persist must be between 1 and 100", ErrInvalid)
+	}
+	if p.Direction != Up && p.Direction != Down && p.Direction != Both {
+		return fmt.Errorf("%w: direction must be %s, %s or %s", ErrInvalid, Up, Down, Both)
+	}
+	window, err := parseDuration(p.Window)
+	if err != nil {
+		return fmt.Errorf("%w: window: %v", ErrInvalid, err)
+	}
+	if window < minHistory*step || window > 7*24*time.Hour {
+		return fmt.Errorf("%w: window must cover at least %d steps of %s and at most 7d", ErrInvalid, minHistory, step)
+	}
+	if p.Alpha < 0 || p.Alpha > 1 {
+		return fmt.Errorf("%w: alpha must be between 0 and 1", ErrInvalid)
+	}
+	period, err := parseDuration(p.Period)
+	if err != nil {
+		return fmt.Errorf("%w: period: %v", ErrInvalid, err)
+	}
+	if period < time.Hour || period%step != 0 {
+		return fmt.Errorf("%w: period must be at least 1h and a multiple of the step %s", ErrInvalid, step)
+	}
+	if p.Seasons < 1 || p.Seasons > 12 {
+		return fmt.Errorf("%w: seasons must be between 1 and 12", ErrInvalid)
+	}
+	if p.MinScale < 0 {
+		return fmt.Errorf("%w: minScale must not be negative", ErrInvalid)
+	}
+	if !levels[p.Severity] {
+		return fmt.Errorf("%w: severity must be P0, P1, P2 or Warning", ErrInvalid)
+	}
+	return nil
+}
+
+func (p *Params) window() time.Duration {
+	d, _ := parseDuration(p.Window)
+	return d
+}
+
+func (p *Params) period() time.Duration {
+	d, _ := parseDuration(p.Period)
+	return d
+}
+
+// metas returns the service alert metas of p as the detector of series, by key.
+func (p *Params) metas(series string) map[string]string {
+	values := map[string]string{
+		"detector":  p.Detector,
+		"threshold": strconv.FormatFloat(p.Threshold, 'g', -1, 64),
+		"persist":   strconv.Itoa(p.Persist),
+		"direction": p.Direction,
+		"window":    p.Window,
+		"alpha":     strconv.FormatFloat(p.Alpha, 'g', -1, 64),
+		"period":    p.Period,
+		"seasons":   strconv.Itoa(p.Seasons),
+		"severity":  p.Severity,
+	}
+	if p.MinScale != 0 {
+		values["minScale"] = strconv.FormatFloat(p.MinScale, 'g', -1, 64)
+	}
+	prefix := metaPrefix(series)
+	out := make(map[string]string, len(values))
+	for param, v := range values {
+		out[prefix+param] = v
+	}
+	return out
+}
+
+// paramsFromMetas parses the meta values of one detector by parameter name. Unknown
+// parameters are ignored.
+func paramsFromMetas(values map[string]string) (Params, error) {
+	p := Params{
+		Detector:  values["detector"],
+		Direction: values["direction"],
+		Window:    values["window"],
+		Period:    values["period"],
+		Severity:  values["severity"],
+	}
+	var err error
+	parseFloat := func(name string, dst *float64) {
+		if v, ok := values[name]; ok && err == nil {
+			if *dst, err = strconv.ParseFloat(v, 64); err != nil {
+				err = fmt.Errorf("%w: %s: %v", ErrInvalid, name, err)
+			}
+		}
+	}
+	parseInt := func(name string, dst *int) {
+		if v, ok := values[name]; ok && err == nil {
+			if *dst, err = strconv.Atoi(v); err != nil {
+				err = fmt.Errorf("%w: %s: %v", ErrInvalid, name, err)
+			}
+		}
+	}
+	parseFloat("threshold", &p.Threshold)
+	parseInt("persist", &p.Persist)
+	parseFloat("alpha", &p.Alpha)
+	parseInt("seasons", &p.Seasons)
+	parseFloat("minScale", &p.MinScale)
+	return p, err
+}
+
+// parseDuration parses a Go duration, or a whole number of days or weeks such as 1d or 2w.
+func parseDuration(s string) (time.Duration, error) {
+	if n, ok := strings.CutSuffix(s, "d"); ok {
+		days, err := strconv.Atoi(n)
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	if n, ok := strings.CutSuffix(s, "w"); ok {
+		weeks, err := strconv.Atoi(n)
+		if err != nil || weeks <= 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(weeks) * 7 * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,198 @@
+package anomaly
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/alerting/database/memory"
+	"github.com/qiniu/zeroops/internal/alerting/service/receiver"
+	"github.com/qiniu/zeroops/internal/config"
+)
+
+var t0 = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+// fakeSource serves a function of time at every step.
+type fakeSource struct {
+	value func(t time.Time) float64
+}
+
+func (f *fakeSource) QueryRange(_ context.Context, _ string, start, end time.Time, step time.Duration) ([]Point, error) {
+	var out []Point
+	for t := start; !t.After(end); t = t.Add(step) {
+		out = append(out, Point{Time: t, Value: f.value(t)})
+	}
+	return out, nil
+}
+
+type recordingIngester struct{ alerts []receiver.AMAlert }
+
+func (r *recordingIngester) IngestAlert(_ context.Context, a receiver.AMAlert) (bool, error) {
+	r.alerts = append(r.alerts, a)
+	return true, nil
+}
+
+// noisy is a level of 100 with a deterministic wobble of +-1.
+func noisy(t time.Time) float64 { return 100 + math.Sin(float64(t.Unix()/60)) }
+
+func newService(t *testing.T, source Source) *Service {
+	t.Helper()
+	s, err := NewService(memory.New(), source, &config.AnomalyConfig{
+		StepSeconds: 60,
+		Series:      []config.AnomalySeries{{Name: "qps", Query: `sum(rate(http_requests_total{service="$service"}[5m]))`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestDetectors(t *testing.T) {
+	spikeAt := t0.Add(-10 * time.Minute)
+	value := func(t time.Time) float64 {
+		if !t.Before(spikeAt) && t.Before(spikeAt.Add(4*time.Minute)) {
+			return 130
+		}
+		return noisy(t)
+	}
+	for _, detector := range []string{EWMA, MAD, Seasonal} {
+		s := newService(t, &fakeSource{value: value})
+		p := Params{Detector: detector}
+		if err := p.normalize(s.Step); err != nil {
+			t.Fatal(err)
+		}
+		points, err := s.evaluate(context.Background(), "q", &p, t0.Add(-30*time.Minute), t0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != 31 || points[0].Score == nil {
+			t.Fatalf("%s: %d points, first %+v", detector, len(points), points[0])
+		}
+		events := p.events(points)
+		if len(events) != 1 || !events[0].Start.Equal(spikeAt) || events[0].Points != 4 || events[0].Peak < 3 {
+			t.Fatalf("%s: events = %+v", detector, events)
+		}
+		if math.Abs(events[0].Expected-100) > 2 {
+			t.Errorf("%s: expected %v, want about 100", detector, events[0].Expected)
+		}
+
+		p.Direction = Down
+		if events := p.events(p.score(nil, t0, nil)); len(events) != 0 {
+			t.Errorf("%s: events of an empty series = %+v", detector, events)
+		}
+	}
+}
+
+func TestPutListDelete(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, nil)
+	for name, p := range map[string]Params{
+		"unknown detector": {Detector: "prophet"},
+		"short window":     {Detector: MAD, Window: "2m"},
+		"bad period":       {Detector: Seasonal, Period: "90s"},
+		"bad severity":     {Detector: EWMA, Severity: "P5"},
+	} {
+		if _, err := s.Put(ctx, "api", "qps", &p); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+	if _, err := s.Put(ctx, "api", "latency", &Params{Detector: EWMA}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("unconfigured series: err = %v", err)
+	}
+
+	d, err := s.Put(ctx, "api", "qps", &Params{Detector: Seasonal, Period: "1w", Seasons: 4, MinScale: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Query != `sum(rate(http_requests_total{service="api"}[5m]))` || d.Params.Threshold != 3 || d.Params.Severity != "P2" {
+		t.Fatalf("detector = %+v", d)
+	}
+	list, err := s.List(ctx, "")
+	if err != nil || len(list) != 1 || list[0].Params != d.Params {
+		t.Fatalf("list = %+v, %v", list, err)
+	}
+
+	// replacing drops parameters that are no longer set
+	if _, err := s.Put(ctx, "api", "qps", &Params{Detector: MAD}); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := s.List(ctx, "api"); list[0].Params.MinScale != 0 || list[0].Params.Detector != MAD {
+		t.Fatalf("replaced = %+v", list[0].Params)
+	}
+	if err := s.Delete(ctx, "api", "qps"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "api", "qps"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second delete = %v", err)
+	}
+}
+
+func TestEvaluateRaisesPersistentAnomalies(t *testing.T) {
+	ctx := context.Background()
+	shiftAt := t0.Add(-3 * time.Minute)
+	source := &fakeSource{value: func(t time.Time) float64 {
+		if !t.Before(shiftAt) {
+			return 200
+		}
+		return noisy(t)
+	}}
+	s := newService(t, source)
+	if _, err := s.Put(ctx, "api", "qps", &Params{Detector: MAD, Direction: Up, Severity: "P1"}); err != nil {
+		t.Fatal(err)
+	}
+	in := &recordingIngester{}
+	for _, now := range []time.Time{t0, t0.Add(10 * time.Minute)} {
+		if n, err := s.Evaluate(ctx, now, in); err != nil || n != 1 {
+			t.Fatalf("evaluate at %s = %d, %v", now, n, err)
+		}
+	}
+	if len(in.alerts) != 2 {
+		t.Fatalf("%d alerts", len(in.alerts))
+	}
+	a := in.alerts[0]
+	if a.Labels["alertname"] != AlertName || a.Labels["service"] != "api" || a.Labels["series"] != "qps" ||
+		a.Labels["severity"] != "P1" || !a.StartsAt.Equal(shiftAt) {
+		t.Fatalf("alert = %+v", a)
+	}
+	// the second evaluation no longer sees the start of the run, but the anomaly is still
+	// the same one and is deduplicated by the receiver
+	if b := in.alerts[1]; b.Fingerprint != a.Fingerprint || !b.StartsAt.Equal(a.StartsAt) {
+		t.Fatalf("repeated alert = %+v", b)
+	}
+
+	source.value = noisy
+	if n, err := s.Evaluate(ctx, t0.Add(2*time.Hour), in); err != nil || n != 0 || len(s.active) != 0 {
+		t.Fatalf("evaluate after recovery = %d, %v, active %v", n, err, s.active)
+	}
+}
+
+func TestBacktest(t *testing.T) {
+	ctx := context.Background()
+	s := newService(t, nil)
+	s.Now = func() time.Time { return t0 }
+	req := &BacktestRequest{Service: "api", Series: "qps", Start: t0.Add(-2 * time.Hour), End: t0}
+	if _, err := s.Backtest(ctx, req); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("backtest without detector = %v", err)
+	}
+	req.Params = &Params{Detector: EWMA}
+	if _, err := s.Backtest(ctx, req); !errors.Is(err, ErrNoPrometheus) {
+		t.Fatalf("backtest without prometheus = %v", err)
+	}
+
+	s.Source = &fakeSource{value: noisy}
+	res, err := s.Backtest(ctx, req)
+	if err != nil || len(res.Points) != 121 || len(res.Events) != 0 || res.Params.Alpha != 0.3 || res.Step != "1m0s" {
+		t.Fatalf("backtest = %+v, %v", res, err)
+	}
+
+	req.End = t0.Add(time.Hour)
+	if _, err := s.Backtest(ctx, req); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("backtest into the future = %v", err)
+	}
+	req.Start, req.End = t0.Add(-30*24*time.Hour), t0
+	if _, err := s.Backtest(ctx, req); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("backtest of too many points = %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package anomaly
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxBacktestPoints bounds the points of a backtest, below the Prometheus limit of 11000
+// points per series.
+const maxBacktestPoints = 10000
+
+// Source queries the series of the detectors.
+type Source interface {
+	// QueryRange evaluates query over [start, end] at step and returns the samples of its
+	// only series, oldest first; none when the result is empty.
+	QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]Point, error)
+}
+
+// BacktestRequest is the body of POST /v1/anomaly/backtest.
+type BacktestRequest struct {
+	Service string    `json:"service"`
+	Series  string    `json:"series"`
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	// Params replace the stored parameters of the detector, so candidates can be tried
+	// before they are saved. Without a stored detector they are required.
+	Params *Params `json:"params,omitempty"`
+}
+
+// BacktestResult is the series of a backtest, scored as the detector would have scored it
+// live, and the anomalies found.
+type BacktestResult struct {
+	Service string        `json:"service"`
+	Series  string        `json:"series"`
+	Query   string        `json:"query"`
+	Params  Params        `json:"params"`
+	Step    string        `json:"step"`
+	Points  []ScoredPoint `json:"points"`
+	Events  []Event       `json:"events"`
+}
+
+// Backtest replays the detector of a series over a past range.
+func (s *Service) Backtest(ctx context.Context, req *BacktestRequest) (*BacktestResult, error) {
+	service := strings.TrimSpace(req.Service)
+	query := s.query(service, req.Series)
+	if service == "" || query == "" {
+		return nil, fmt.Errorf("%w: service and a configured series are required", ErrInvalid)
+	}
+	start, end := req.Start.Truncate(s.Step), req.End.Truncate(s.Step)
+	if !end.After(start) {
+		return nil, fmt.Errorf("%w: end must be after start", ErrInvalid)
+	}
+	if end.After(s.Now()) {
+		return nil, fmt.Errorf("%w: end must not be in the future", ErrInvalid)
+	}
+	if end.Sub(start)/s.Step > maxBacktestPoints {
+		return nil, fmt.Errorf("%w: the range covers more than %d steps of %s", ErrInvalid, maxBacktestPoints, s.Step)
+	}
+
+	var params Params
+	if req.Params != nil {
+		params = *req.Params
+		if err := params.normalize(s.Step); err != nil {
+			return nil, err
+		}
+	} else {
+		d, err := s.get(ctx, service, req.Series)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			return nil, ErrNotFound
+		}
+		params = d.Params
+	}
+	if s.Source == nil {
+		return nil, ErrNoPrometheus
+	}
+
+	points, err := s.evaluate(ctx, query, &params, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return &BacktestResult{
+		Service: service,
+		Series:  req.Series,
+		Query:   query,
+		Params:  params,
+		Step:    s.Step.String(),
+		Points:  points,
+		Events:  params.events(points),
+	}, nil
+}
+
+// evaluate fetches the series with the history its detector needs and scores the points
+// in [start, end].
+func (s *Service) evaluate(ctx context.Context, query string, p *Params, start, end time.Time) ([]ScoredPoint, error) {
+	if p.Detector != Seasonal {
+		series, err := s.Source.QueryRange(ctx, query, start.Add(-p.window()), end, s.Step)
+		if err != nil {
+			return nil, err
+		}
+		return p.score(series, start, nil), nil
+	}
+
+	series, err := s.Source.QueryRange(ctx, query, start, end, s.Step)
+	if err != nil {
+		return nil, err
+	}
+	seasons := make([][]Point, p.Seasons)
+	for k := range seasons {
+		shift := time.Duration(k+1) * p.period()
+		season, err := s.Source.QueryRange(ctx, query, start.Add(-shift), end.Add(-shift), s.Step)
+		if err != nil {
+			return nil, err
+		}
+		for i := range season {
+			season[i].Time = season[i].Time.Add(shift)
+		}
+		seasons[k] = season
+	}
+	return p.score(series, start, seasons), nil
+}
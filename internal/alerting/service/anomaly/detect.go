@@ -0,0 +1,218 @@
+package anomaly
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// minHistory is the number of earlier points a detector needs before it scores a point.
+const minHistory = 5
+
+// madScale turns a median absolute deviation into a standard deviation of normal data.
+const madScale = 1.4826
+
+// Point is one sample of a series.
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// ScoredPoint is a point with the value its detector expected.
+type ScoredPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+	// Expected and Score are nil while the detector lacks history for the point.
+	Expected *float64 `json:"expected"`
+	// Score is the deviation from Expected in standard deviations.
+	Score *float64 `json:"score"`
+	// Anomalous is set when the score is beyond the threshold in the watched direction.
+	Anomalous bool `json:"anomalous"`
+}
+
+// Event is a run of at least Persist consecutive anomalous points.
+type Event struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"` // time of the last point of the run
+	Points int       `json:"points"`
+	// Peak is the score furthest from zero, of the point with PeakValue and Expected.
+	Peak      float64 `json:"peak"`
+	PeakValue float64 `json:"peakValue"`
+	Expected  float64 `json:"expected"`
+}
+
+// score scores the points of series from from on. series starts Window before from for
+// the ewma and mad detectors; seasons[k] are the points (k+1) periods earlier, moved
+// forward by as much, for the seasonal detector.
+func (p *Params) score(series []Point, from time.Time, seasons [][]Point) []ScoredPoint {
+	var out []ScoredPoint
+	switch p.Detector {
+	case EWMA:
+		out = p.scoreEWMA(series, from)
+	case MAD:
+		out = p.scoreMAD(series, from)
+	default:
+		out = p.scoreSeasonal(series, seasons)
+	}
+	for i := range out {
+		if s := out[i].Score; s != nil {
+			out[i].Anomalous = p.anomalous(*s)
+		}
+	}
+	return out
+}
+
+// scoreEWMA compares each point with the exponentially weighted mean and variance of the
+// points before it. Points update the averages clamped to the threshold, so an anomaly
+// does not hide its own next points, while a lasting level shift is still learnt.
+func (p *Params) scoreEWMA(series []Point, from time.Time) []ScoredPoint {
+	out := make([]ScoredPoint, 0, len(series))
+	var mean, variance float64
+	for i, pt := range series {
+		if i == 0 {
+			mean = pt.Value
+		}
+		limit := p.Threshold * bound(math.Sqrt(variance), mean, p.MinScale)
+		if !pt.Time.Before(from) {
+			sp := ScoredPoint{Time: pt.Time, Value: pt.Value}
+			if i >= minHistory {
+				sp.Expected, sp.Score = scored(pt.Value, mean, math.Sqrt(variance), p.MinScale)
+			}
+			out = append(out, sp)
+		}
+		d := pt.Value - mean
+		if i >= minHistory {
+			d = max(-limit, min(limit, d))
+		}
+		mean += p.Alpha * d
+		variance = (1 - p.Alpha) * (variance + p.Alpha*d*d)
+	}
+	return out
+}
+
+// scoreMAD compares each point with the median and MAD of the points in the Window
+// before it.
+func (p *Params) scoreMAD(series []Point, from time.Time) []ScoredPoint {
+	window := p.window()
+	out := make([]ScoredPoint, 0, len(series))
+	ref := make([]float64, 0, len(series))
+	lo := 0
+	for i, pt := range series {
+		if pt.Time.Before(from) {
+			continue
+		}
+		for lo < i && series[lo].Time.Before(pt.Time.Add(-window)) {
+			lo++
+		}
+		sp := ScoredPoint{Time: pt.Time, Value: pt.Value}
+		if i-lo >= minHistory {
+			ref = ref[:0]
+			for _, r := range series[lo:i] {
+				ref = append(ref, r.Value)
+			}
+			median, dev := robust(ref)
+			sp.Expected, sp.Score = scored(pt.Value, median, dev, p.MinScale)
+		}
+		out = append(out, sp)
+	}
+	return out
+}
+
+// scoreSeasonal compares each point with the median and MAD of the points at the same
+// time of the previous seasons. A point is scored when at least half of them exist.
+func (p *Params) scoreSeasonal(series []Point, seasons [][]Point) []ScoredPoint {
+	byTime := make([]map[int64]float64, len(seasons))
+	for k, season := range seasons {
+		byTime[k] = make(map[int64]float64, len(season))
+		for _, pt := range season {
+			byTime[k][pt.Time.Unix()] = pt.Value
+		}
+	}
+	need := max(1, len(seasons)/2)
+	out := make([]ScoredPoint, 0, len(series))
+	ref := make([]float64, 0, len(seasons))
+	for _, pt := range series {
+		ref = ref[:0]
+		for _, season := range byTime {
+			if v, ok := season[pt.Time.Unix()]; ok {
+				ref = append(ref, v)
+			}
+		}
+		sp := ScoredPoint{Time: pt.Time, Value: pt.Value}
+		if len(ref) >= need {
+			median, dev := robust(ref)
+			sp.Expected, sp.Score = scored(pt.Value, median, dev, p.MinScale)
+		}
+		out = append(out, sp)
+	}
+	return out
+}
+
+// robust returns the median of values and their standard deviation estimated from the
+// median absolute deviation. values is reordered.
+func robust(values []float64) (median, dev float64) {
+	median = medianOf(values)
+	for i, v := range values {
+		values[i] = math.Abs(v - median)
+	}
+	return median, madScale * medianOf(values)
+}
+
+func medianOf(values []float64) float64 {
+	slices.Sort(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// scored returns the expected value and the score of value, with the deviation bounded
+// from below by minScale and 1% of the expected value.
+func scored(value, expected, dev, minScale float64) (*float64, *float64) {
+	score := (value - expected) / bound(dev, expected, minScale)
+	return &expected, &score
+}
+
+func bound(dev, expected, minScale float64) float64 {
+	return max(dev, minScale, 0.01*math.Abs(expected), 1e-9)
+}
+
+func (p *Params) anomalous(score float64) bool {
+	switch p.Direction {
+	case Up:
+		return score > p.Threshold
+	case Down:
+		return score < -p.Threshold
+	default:
+		return math.Abs(score) > p.Threshold
+	}
+}
+
+// events returns the runs of at least Persist consecutive anomalous points.
+func (p *Params) events(points []ScoredPoint) []Event {
+	var out []Event
+	var cur *Event
+	for i := range points {
+		pt := &points[i]
+		if !pt.Anomalous {
+			if cur != nil && cur.Points >= p.Persist {
+				out = append(out, *cur)
+			}
+			cur = nil
+			continue
+		}
+		if cur == nil {
+			cur = &Event{Start: pt.Time}
+		}
+		cur.End = pt.Time
+		cur.Points++
+		if cur.Points == 1 || math.Abs(*pt.Score) > math.Abs(cur.Peak) {
+			cur.Peak, cur.PeakValue, cur.Expected = *pt.Score, pt.Value, *pt.Expected
+		}
+	}
+	if cur != nil && cur.Points >= p.Persist {
+		out = append(out, *cur)
+	}
+	return out
+}
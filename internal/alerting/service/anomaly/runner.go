@@ -0,0 +1,138 @@
+package anomaly
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/alerting/service/receiver"
+	"github.com/rs/zerolog/log"
+)
+
+// AlertName is the alertname of the alerts raised for anomalies.
+const AlertName = "AnomalyDetected"
+
+// Ingester runs an alert through the receiver, see receiver.Handler.IngestAlert.
+type Ingester interface {
+	IngestAlert(ctx context.Context, alert receiver.AMAlert) (bool, error)
+}
+
+// Run evaluates the detectors every interval until ctx ends. It returns at once without
+// Prometheus or configured series.
+func (s *Service) Run(ctx context.Context, interval time.Duration, alerter Ingester) {
+	if s.Source == nil || len(s.Series) == 0 || interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Evaluate(ctx, s.Now(), alerter); err != nil {
+				log.Error().Err(err).Msg("failed to evaluate anomaly detectors")
+			}
+		}
+	}
+}
+
+// Evaluate scores the latest points of every detector and raises an alert for each whose
+// anomaly lasts up to the last point. It returns the number of firing detectors. A
+// detector whose series cannot be queried is logged and keeps its state.
+func (s *Service) Evaluate(ctx context.Context, now time.Time, alerter Ingester) (int, error) {
+	detectors, err := s.List(ctx, "")
+	if err != nil {
+		return 0, err
+	}
+	end := now.Truncate(s.Step)
+	firing := make(map[string]bool, len(detectors))
+	for i := range detectors {
+		d := &detectors[i]
+		if d.Query == "" {
+			continue
+		}
+		key := d.Service + "/" + d.Series
+		// twice Persist steps are enough to see a run ending at the last point; the start
+		// of longer runs is remembered in s.active
+		span := time.Duration(2*d.Params.Persist) * s.Step
+		points, err := s.evaluate(ctx, d.Query, &d.Params, end.Add(-span), end)
+		if err != nil {
+			log.Warn().Err(err).Str("service", d.Service).Str("series", d.Series).Msg("failed to query anomaly series")
+			firing[key] = s.isActive(key)
+			continue
+		}
+		events := d.Params.events(points)
+		if len(events) == 0 || !events[len(events)-1].End.Equal(points[len(points)-1].Time) {
+			continue
+		}
+		firing[key] = true
+		ev := &events[len(events)-1]
+		if _, err := alerter.IngestAlert(ctx, anomalyAlert(d, ev, s.activate(key, ev.Start))); err != nil {
+			log.Error().Err(err).Str("service", d.Service).Str("series", d.Series).Msg("failed to raise anomaly alert")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.active {
+		if !firing[key] {
+			delete(s.active, key)
+		}
+	}
+	n := 0
+	for _, f := range firing {
+		if f {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Service) isActive(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.active[key]
+	return ok
+}
+
+// activate returns the StartsAt of the alert of key, start unless the anomaly was already
+// firing.
+func (s *Service) activate(key string, start time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if at, ok := s.active[key]; ok {
+		return at
+	}
+	s.active[key] = start
+	return start
+}
+
+// anomalyAlert builds the alert of an anomaly. The fingerprint is fixed per service and
+// series and StartsAt per anomaly, so repeated evaluations are deduplicated by the receiver.
+func anomalyAlert(d *Detector, ev *Event, startsAt time.Time) receiver.AMAlert {
+	sum := sha256.Sum256([]byte(AlertName + "/" + d.Service + "/" + d.Series))
+	return receiver.AMAlert{
+		Status: "firing",
+		Labels: receiver.KV{
+			"alertname": AlertName,
+			"severity":  d.Params.Severity,
+			"service":   d.Service,
+			"series":    d.Series,
+			"detector":  d.Params.Detector,
+		},
+		Annotations: receiver.KV{
+			"summary": fmt.Sprintf("%s %s is anomalous", d.Service, d.Series),
+			"description": fmt.Sprintf("%s detector: value %s where %s was expected (score %s, threshold %s) for %d points",
+				d.Params.Detector, formatFloat(ev.PeakValue), formatFloat(ev.Expected), strconv.FormatFloat(ev.Peak, 'f', 1, 64),
+				formatFloat(d.Params.Threshold), ev.Points),
+		},
+		StartsAt:    startsAt.UTC(),
+		Fingerprint: hex.EncodeToString(sum[:8]),
+	}
+}
+
+func formatFloat(v float64) string { return strconv.FormatFloat(v, 'g', 6, 64) }
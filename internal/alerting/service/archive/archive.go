@@ -0,0 +1,404 @@
+// Package archive enforces the retention of closed issues in Postgres. Closed issues whose
+// alert started more than the retention of their level ago are moved, together with
+// their comments, history and report, to a Sink: the alert_issue_archive table or
+// gzip-compressed JSON lines in a directory or an object store. Restore moves them back.
+// The monthly partitions of alert_issues are created ahead of time and dropped once a
+// past month has been emptied.
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrInvalid = errors.New("invalid archive request")
+
+// Targets.
+const (
+	TargetTable = "table"
+	TargetDir   = "dir"
+	TargetS3    = "s3"
+)
+
+const (
+	defaultBatchSize = 100
+	// partitionsAhead is the number of months after the current one that get a partition.
+	partitionsAhead = 2
+)
+
+// Record is an archived issue with its comments, history and report: one line of an
+// export file or the record column of alert_issue_archive.
+type Record struct {
+	ID          string          `json:"id"`
+	State       string          `json:"state"`
+	Level       string          `json:"level"`
+	AlertState  string          `json:"alertState"`
+	Title       string          `json:"title"`
+	Labels      json.RawMessage `json:"labels"`
+	AlertSince  time.Time       `json:"alertSince"`
+	Region      string          `json:"region"`
+	Fingerprint string          `json:"fingerprint,omitempty"`
+	StartsAt    time.Time       `json:"startsAt"`
+	TraceParent string          `json:"traceParent,omitempty"`
+	AckedBy     string          `json:"ackedBy,omitempty"`
+	AckedAt     *time.Time      `json:"ackedAt,omitempty"`
+	SLOBudget   json.RawMessage `json:"sloBudget,omitempty"`
+	Comments    []Comment       `json:"comments"`
+	Events      []Event         `json:"events"`
+	Report      *Report         `json:"report,omitempty"`
+	ArchivedAt  time.Time       `json:"archivedAt"`
+}
+
+type Comment struct {
+	CreatedAt time.Time       `json:"createdAt"`
+	Content   string          `json:"content"`
+	Analysis  json.RawMessage `json:"analysis,omitempty"`
+}
+
+type Event struct {
+	At     time.Time `json:"at"`
+	Type   string    `json:"type"`
+	Actor  string    `json:"actor,omitempty"`
+	From   string    `json:"from,omitempty"`
+	To     string    `json:"to,omitempty"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+type Report struct {
+	Service   string          `json:"service"`
+	CreatedAt time.Time       `json:"createdAt"`
+	CreatedBy string          `json:"createdBy"`
+	Report    json.RawMessage `json:"report"`
+}
+
+func newRecord(r *adb.IssueRecord, at time.Time) Record {
+	it := &r.Issue
+	rec := Record{
+		ID: it.ID, State: it.State, Level: it.Level, AlertState: it.AlertState, Title: it.Title, Labels: it.Labels,
+		AlertSince: it.AlertSince, Region: it.Region, Fingerprint: it.Fingerprint, StartsAt: it.StartsAt,
+		TraceParent: it.TraceParent, AckedBy: it.AckedBy, AckedAt: it.AckedAt, SLOBudget: it.SLOBudget,
+		Comments: make([]Comment, 0, len(r.Comments)), Events: make([]Event, 0, len(r.Events)), ArchivedAt: at,
+	}
+	for _, c := range r.Comments {
+		rec.Comments = append(rec.Comments, Comment{CreatedAt: c.CreatedAt, Content: c.Content, Analysis: c.Analysis})
+	}
+	for _, e := range r.Events {
+		rec.Events = append(rec.Events, Event{At: e.At, Type: e.Type, Actor: e.Actor, From: e.From, To: e.To, Detail: e.Detail})
+	}
+	if r.Report != nil {
+		rec.Report = &Report{Service: r.Report.Service, CreatedAt: r.Report.CreatedAt, CreatedBy: r.Report.CreatedBy, Report: r.Report.Report}
+	}
+	return rec
+}
+
+func (rec *Record) issueRecord() *adb.IssueRecord {
+	r := &adb.IssueRecord{Issue: adb.Issue{
+		ID: rec.ID, State: rec.State, Level: rec.Level, AlertState: rec.AlertState, Title: rec.Title, Labels: rec.Labels,
+		AlertSince: rec.AlertSince, Region: rec.Region, Fingerprint: rec.Fingerprint, StartsAt: rec.StartsAt,
+		TraceParent: rec.TraceParent, AckedBy: rec.AckedBy, AckedAt: rec.AckedAt, SLOBudget: rec.SLOBudget,
+	}}
+	for _, c := range rec.Comments {
+		r.Comments = append(r.Comments, adb.Comment{IssueID: rec.ID, CreatedAt: c.CreatedAt, Content: c.Content, Analysis: c.Analysis})
+	}
+	for _, e := range rec.Events {
+		r.Events = append(r.Events, adb.IssueEvent{IssueID: rec.ID, At: e.At, Type: e.Type, Actor: e.Actor, From: e.From, To: e.To, Detail: e.Detail})
+	}
+	if rec.Report != nil {
+		r.Report = &adb.IssueReport{IssueID: rec.ID, Service: rec.Report.Service, CreatedAt: rec.Report.CreatedAt,
+			CreatedBy: rec.Report.CreatedBy, Report: rec.Report.Report}
+	}
+	return r
+}
+
+// Policy is the retention of closed issues in days per level. Default applies to the
+// other levels; 0 keeps issues forever.
+type Policy struct {
+	Default int
+	Levels  map[string]int
+}
+
+// Min returns the shortest retention, 0 when issues are kept forever.
+func (p Policy) Min() int {
+	m := p.Default
+	for _, days := range p.Levels {
+		if days > 0 && (m == 0 || days < m) {
+			m = days
+		}
+	}
+	return m
+}
+
+// filters returns the closed issues past their retention at now, one filter per level
+// with its own retention and one for the others.
+func (p Policy) filters(now time.Time) []adb.ClosedIssueFilter {
+	levels := make([]string, 0, len(p.Levels))
+	for level := range p.Levels {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+	cutoff := func(days int) time.Time { return now.Add(-time.Duration(days) * 24 * time.Hour) }
+
+	var out []adb.ClosedIssueFilter
+	for _, level := range levels {
+		if days := p.Levels[level]; days > 0 {
+			out = append(out, adb.ClosedIssueFilter{Before: cutoff(days), Levels: []string{level}})
+		}
+	}
+	if p.Default > 0 {
+		out = append(out, adb.ClosedIssueFilter{Before: cutoff(p.Default), ExceptLevels: levels})
+	}
+	return out
+}
+
+// Partitioner maintains the monthly partitions of alert_issues; *database.Database
+// implements it.
+type Partitioner interface {
+	EnsureIssuePartition(ctx context.Context, t time.Time) (bool, error)
+	DropEmptyIssuePartitions(ctx context.Context, t time.Time) ([]string, error)
+}
+
+type Service struct {
+	DB     adb.Store
+	Sink   Sink
+	Policy Policy
+	// Partitions is nil when the store is not partitioned, e.g. the in-memory store.
+	Partitions Partitioner
+	BatchSize  int
+	Now        func() time.Time
+}
+
+// NewService builds the service and its sink from cfg.
+func NewService(db adb.Store, cfg *config.ArchiveConfig) (*Service, error) {
+	s := &Service{DB: db, BatchSize: defaultBatchSize, Now: time.Now}
+	if p, ok := db.(Partitioner); ok {
+		s.Partitions = p
+	}
+	if cfg.RetentionDays < 0 {
+		return nil, fmt.Errorf("archive retentionDays must not be negative")
+	}
+	s.Policy = Policy{Default: cfg.RetentionDays, Levels: make(map[string]int)}
+	for level, days := range cfg.LevelRetentionDays {
+		if days < 0 {
+			return nil, fmt.Errorf("archive retention of level %s must not be negative", level)
+		}
+		s.Policy.Levels[level] = days
+	}
+	if cfg.BatchSize > 0 {
+		s.BatchSize = cfg.BatchSize
+	}
+
+	switch cfg.Target {
+	case TargetTable, "":
+		s.Sink = &TableSink{DB: db}
+	case TargetDir:
+		if cfg.Dir == "" {
+			return nil, fmt.Errorf("archive target dir needs a directory")
+		}
+		s.Sink = &ExportSink{Bucket: DirBucket(cfg.Dir)}
+	case TargetS3:
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+			return nil, fmt.Errorf("archive target s3 needs an endpoint and a bucket")
+		}
+		s.Sink = &ExportSink{Bucket: NewObjectBucket(cfg.S3Endpoint, cfg.S3Bucket)}
+	default:
+		return nil, fmt.Errorf("unknown archive target %q, want table, dir or s3", cfg.Target)
+	}
+	return s, nil
+}
+
+// Archive moves the closed issues past their retention to the sink and returns the
+// number moved. Each batch is moved in one transaction; an export written before its
+// transaction failed is written again by the next run.
+func (s *Service) Archive(ctx context.Context) (int, error) {
+	total := 0
+	for _, f := range s.Policy.filters(s.Now()) {
+		for {
+			n, err := s.archiveBatch(ctx, f)
+			total += n
+			if err != nil {
+				return total, err
+			}
+			if n < s.BatchSize {
+				break
+			}
+		}
+	}
+	return total, nil
+}
+
+func (s *Service) archiveBatch(ctx context.Context, f adb.ClosedIssueFilter) (int, error) {
+	n := 0
+	err := s.DB.InTx(ctx, func(ctx context.Context) error {
+		issues, err := s.DB.ListClosedIssues(ctx, f, s.BatchSize)
+		if err != nil || len(issues) == 0 {
+			return err
+		}
+		at := s.Now().UTC()
+		records := make([]Record, 0, len(issues))
+		ids := make([]string, 0, len(issues))
+		for _, it := range issues {
+			r, err := s.load(ctx, it)
+			if err != nil {
+				return err
+			}
+			records = append(records, newRecord(r, at))
+			ids = append(ids, it.ID)
+		}
+		if err := s.Sink.Put(ctx, records); err != nil {
+			return fmt.Errorf("archive issues: %w", err)
+		}
+		if _, err := s.DB.DeleteIssues(ctx, ids); err != nil {
+			return err
+		}
+		n = len(issues)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *Service) load(ctx context.Context, it adb.Issue) (*adb.IssueRecord, error) {
+	r := &adb.IssueRecord{Issue: it}
+	var err error
+	if r.Comments, err = s.DB.ListComments(ctx, it.ID); err != nil {
+		return nil, err
+	}
+	if r.Events, err = s.DB.ListIssueEvents(ctx, it.ID); err != nil {
+		return nil, err
+	}
+	if r.Report, err = s.DB.GetIssueReport(ctx, it.ID); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RestoreQuery selects the archived issues whose alert started in [From, To), and only
+// those with one of IDs when set.
+type RestoreQuery struct {
+	From time.Time
+	To   time.Time
+	IDs  []string
+}
+
+// Restore moves the archived issues matching q back to alert_issues and returns the
+// number restored. Issues that are stored already are skipped. An issue whose alert
+// has been opened again as another issue stays in the archive, and Restore then
+// returns an error wrapping adb.ErrAlertConflict after restoring the others.
+func (s *Service) Restore(ctx context.Context, q RestoreQuery) (int, error) {
+	if !q.To.After(q.From) {
+		return 0, fmt.Errorf("%w: empty range", ErrInvalid)
+	}
+	n := 0
+	var conflicts []string
+	partitions := make(map[string]bool)
+	err := s.Sink.Scan(ctx, q.From, q.To, func(records []Record) error {
+		return s.DB.InTx(ctx, func(ctx context.Context) error {
+			done := make([]string, 0, len(records))
+			for i := range records {
+				r := &records[i]
+				if len(q.IDs) > 0 && !slices.Contains(q.IDs, r.ID) {
+					continue
+				}
+				// a month whose partition was dropped gets it back rather than filling
+				// the default partition
+				if month := r.AlertSince.UTC().Format("2006-01"); s.Partitions != nil && !partitions[month] {
+					if _, err := s.Partitions.EnsureIssuePartition(ctx, r.AlertSince); err != nil {
+						return err
+					}
+					partitions[month] = true
+				}
+				restored, err := s.DB.RestoreIssue(ctx, r.issueRecord())
+				if errors.Is(err, adb.ErrAlertConflict) {
+					log.Warn().Str("issue", r.ID).Str("fingerprint", r.Fingerprint).
+						Msg("kept archived issue whose alert is stored as another issue")
+					conflicts = append(conflicts, r.ID)
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("restore issue %s: %w", r.ID, err)
+				}
+				if restored {
+					n++
+				}
+				done = append(done, r.ID)
+			}
+			if len(done) == 0 {
+				return nil
+			}
+			return s.Sink.Delete(ctx, done)
+		})
+	})
+	if err == nil && len(conflicts) > 0 {
+		err = fmt.Errorf("%d issues kept in the archive (%s): %w", len(conflicts), strings.Join(conflicts, ", "), adb.ErrAlertConflict)
+	}
+	return n, err
+}
+
+// MaintainPartitions creates the partitions of alert_issues up to partitionsAhead months
+// ahead and drops the empty ones of past months.
+func (s *Service) MaintainPartitions(ctx context.Context) (created int, dropped []string, err error) {
+	if s.Partitions == nil {
+		return 0, nil, nil
+	}
+	now := s.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for i := range partitionsAhead + 1 {
+		ok, err := s.Partitions.EnsureIssuePartition(ctx, month.AddDate(0, i, 0))
+		if err != nil {
+			return created, nil, err
+		}
+		if ok {
+			created++
+		}
+	}
+	dropped, err = s.Partitions.DropEmptyIssuePartitions(ctx, month)
+	return created, dropped, err
+}
+
+// RunOnce maintains the partitions and archives, logging what it did.
+func (s *Service) RunOnce(ctx context.Context) {
+	created, dropped, err := s.MaintainPartitions(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Error().Err(err).Msg("failed to maintain alert_issues partitions")
+	}
+	if created > 0 || len(dropped) > 0 {
+		log.Info().Int("created", created).Strs("dropped", dropped).Msg("maintained alert_issues partitions")
+	}
+	n, err := s.Archive(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Error().Err(err).Msg("failed to archive closed issues")
+	}
+	if n > 0 {
+		log.Info().Int("issues", n).Msg("archived closed issues")
+	}
+}
+
+// Run calls RunOnce at start and then every interval. It returns when ctx is done.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	s.RunOnce(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.RunOnce(ctx)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/database/memory"
+	"github.com/qiniu/zeroops/internal/config"
+)
+
+var now = time.Date(2026, 6, 10, 12, 0, 0, 0, time.UTC)
+
+// objectServer serves the object routes of the MockS3 storage service from memory.
+func objectServer(t *testing.T) *httptest.Server {
+	var mu sync.Mutex
+	objects := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/objects":
+			var req struct {
+				Bucket, Key string
+				Data        []byte
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			objects[req.Bucket+"/"+req.Key] = req.Data
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/objects":
+			type object struct {
+				Key string `json:"key"`
+			}
+			resp := struct {
+				Objects []object `json:"objects"`
+			}{Objects: []object{}}
+			for k := range objects {
+				if key, ok := strings.CutPrefix(k, r.URL.Query().Get("bucket")+"/"); ok && strings.HasPrefix(key, r.URL.Query().Get("prefix")) {
+					resp.Objects = append(resp.Objects, object{Key: key})
+				}
+			}
+			_ = json.NewEncoder(w).Encode(resp)
+		case r.Method == http.MethodGet:
+			data, ok := objects[strings.TrimPrefix(r.URL.Path, "/api/v1/objects/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestArchiveAndRestore(t *testing.T) {
+	cfgs := map[string]config.ArchiveConfig{
+		"table": {Target: TargetTable},
+		"dir":   {Target: TargetDir, Dir: t.TempDir()},
+		"s3":    {Target: TargetS3, S3Endpoint: objectServer(t).URL, S3Bucket: "archive"},
+	}
+	for name, cfg := range cfgs {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			db := memory.New()
+			add := func(id, state, level string, since time.Time) {
+				t.Helper()
+				if err := db.InsertIssue(ctx, &adb.Issue{ID: id, State: state, Level: level, AlertState: "Restored",
+					Labels: []byte(`[]`), AlertSince: since, Fingerprint: "fp-" + id}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			old := now.AddDate(0, 0, -100)
+			add("old-p2", "Closed", "P2", old)
+			add("old-p0", "Closed", "P0", old)
+			add("open-p2", "Open", "P2", old)
+			add("new-p2", "Closed", "P2", now.AddDate(0, 0, -10))
+			if _, err := db.AddComment(ctx, "old-p2", "rolled back"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := db.AddIssueEvent(ctx, &adb.IssueEvent{IssueID: "old-p2", At: old, Type: adb.EventCreated}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.UpsertIssueReport(ctx, &adb.IssueReport{IssueID: "old-p2", Service: "storage", CreatedAt: old,
+				Report: []byte(`{"title":"x"}`)}); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg.RetentionDays = 30
+			cfg.LevelRetentionDays = map[string]int{"P0": 365}
+			cfg.BatchSize = 1
+			s, err := NewService(db, &cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.Now = func() time.Time { return now }
+			if n, err := s.Archive(ctx); err != nil || n != 1 {
+				t.Fatalf("archive = %d, %v", n, err)
+			}
+			for id, kept := range map[string]bool{"old-p2": false, "old-p0": true, "open-p2": true, "new-p2": true} {
+				if _, ok := db.Issue(id); ok != kept {
+					t.Errorf("issue %s kept = %v", id, ok)
+				}
+			}
+			if comments, _ := db.ListComments(ctx, "old-p2"); len(comments) != 0 {
+				t.Fatalf("comments left = %v", comments)
+			}
+
+			// the range must cover the alert; other ids are not restored
+			if n, err := s.Restore(ctx, RestoreQuery{From: old.Add(time.Hour), To: now}); err != nil || n != 0 {
+				t.Fatalf("restore outside range = %d, %v", n, err)
+			}
+			if n, err := s.Restore(ctx, RestoreQuery{From: old.AddDate(0, -1, 0), To: now, IDs: []string{"other"}}); err != nil || n != 0 {
+				t.Fatalf("restore other id = %d, %v", n, err)
+			}
+			// while the alert is stored as another issue the archived one is kept
+			if err := db.InsertIssue(ctx, &adb.Issue{ID: "reopened", State: "Open", Level: "P2", AlertState: "Restored",
+				Labels: []byte(`[]`), AlertSince: old, Fingerprint: "fp-old-p2"}); err != nil {
+				t.Fatal(err)
+			}
+			if n, err := s.Restore(ctx, RestoreQuery{From: old.AddDate(0, -1, 0), To: now, IDs: []string{"old-p2"}}); !errors.Is(err, adb.ErrAlertConflict) || n != 0 {
+				t.Fatalf("restore conflicting issue = %d, %v", n, err)
+			}
+			if _, ok := db.Issue("old-p2"); ok {
+				t.Fatal("conflicting issue restored")
+			}
+			if _, err := db.DeleteIssues(ctx, []string{"reopened"}); err != nil {
+				t.Fatal(err)
+			}
+			if n, err := s.Restore(ctx, RestoreQuery{From: old.AddDate(0, -1, 0), To: now, IDs: []string{"old-p2"}}); err != nil || n != 1 {
+				t.Fatalf("restore = %d, %v", n, err)
+			}
+			it, ok := db.Issue("old-p2")
+			if !ok || it.State != "Closed" || !it.AlertSince.Equal(old) || it.Fingerprint != "fp-old-p2" {
+				t.Fatalf("restored issue = %+v", it)
+			}
+			comments, _ := db.ListComments(ctx, "old-p2")
+			events, _ := db.ListIssueEvents(ctx, "old-p2")
+			report, _ := db.GetIssueReport(ctx, "old-p2")
+			if len(comments) != 1 || comments[0].Content != "rolled back" || len(events) != 1 || report == nil || report.Service != "storage" {
+				t.Fatalf("restored history = %v %v %v", comments, events, report)
+			}
+			// restoring again skips the stored issue
+			if n, err := s.Restore(ctx, RestoreQuery{From: old.AddDate(0, -1, 0), To: now}); err != nil || n != 0 {
+				t.Fatalf("restore again = %d, %v", n, err)
+			}
+		})
+	}
+}
+
+func TestNewService(t *testing.T) {
+	db := memory.New()
+	for _, cfg := range []config.ArchiveConfig{
+		{Target: "ftp"},
+		{Target: TargetDir},
+		{Target: TargetS3, S3Bucket: "archive"},
+		{Target: TargetTable, RetentionDays: -1},
+		{Target: TargetTable, LevelRetentionDays: map[string]int{"P0": -1}},
+	} {
+		if _, err := NewService(db, &cfg); err == nil {
+			t.Errorf("NewService(%+v) succeeded", cfg)
+		}
+	}
+	s, err := NewService(db, &config.ArchiveConfig{RetentionDays: 90, LevelRetentionDays: map[string]int{"P0": 0, "Warning": 14}})
+	if err != nil || s.Policy.Min() != 14 {
+		t.Fatalf("policy = %+v, %v", s, err)
+	}
+	filters := s.Policy.filters(now)
+	if len(filters) != 2 || filters[0].Levels[0] != "Warning" || len(filters[1].ExceptLevels) != 2 {
+		t.Fatalf("filters = %+v", filters)
+	}
+}
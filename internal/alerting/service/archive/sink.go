@@ -0,0 +1,298 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+)
+
+// Sink stores archived issues.
+type Sink interface {
+	// Put stores records. It is called in the transaction deleting the issues.
+	Put(ctx context.Context, records []Record) error
+	// Scan calls fn with batches of the stored records whose alert started in [from, to).
+	Scan(ctx context.Context, from, to time.Time, fn func(records []Record) error) error
+	// Delete forgets the records of restored issues.
+	Delete(ctx context.Context, ids []string) error
+}
+
+// scanBatch is the number of archived rows read at a time.
+const scanBatch = 100
+
+// TableSink stores records in alert_issue_archive, in the transaction deleting the
+// issues.
+type TableSink struct {
+	DB adb.ArchiveRepository
+}
+
+func (t *TableSink) Put(ctx context.Context, records []Record) error {
+	rows := make([]adb.ArchivedIssue, 0, len(records))
+	for i := range records {
+		r := &records[i]
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, adb.ArchivedIssue{ID: r.ID, Level: r.Level, AlertSince: r.AlertSince, ArchivedAt: r.ArchivedAt, Record: b})
+	}
+	return t.DB.InsertArchivedIssues(ctx, rows)
+}
+
+func (t *TableSink) Scan(ctx context.Context, from, to time.Time, fn func(records []Record) error) error {
+	after := ""
+	for {
+		rows, err := t.DB.ListArchivedIssues(ctx, from, to, after, scanBatch)
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+		records := make([]Record, 0, len(rows))
+		for _, row := range rows {
+			var r Record
+			if err := json.Unmarshal(row.Record, &r); err != nil {
+				return fmt.Errorf("decode archived issue %s: %w", row.ID, err)
+			}
+			records = append(records, r)
+		}
+		if err := fn(records); err != nil {
+			return err
+		}
+		after = rows[len(rows)-1].ID
+	}
+}
+
+func (t *TableSink) Delete(ctx context.Context, ids []string) error {
+	return t.DB.DeleteArchivedIssues(ctx, ids)
+}
+
+// Bucket stores export files by name.
+type Bucket interface {
+	Put(ctx context.Context, name string, data []byte) error
+	// List returns the names starting with prefix in order.
+	List(ctx context.Context, prefix string) ([]string, error)
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// filePrefix starts the names of export files, which are
+// alert_issues-<YYYY-MM of alert_since>-<archived at, Unix ns>.jsonl.gz.
+const filePrefix = "alert_issues-"
+
+const fileSuffix = ".jsonl.gz"
+
+// ExportSink writes one gzip-compressed JSON lines file per month of alert_since and Put
+// call to a Bucket. Files are kept after a restore; restoring them again skips the
+// issues already stored.
+type ExportSink struct {
+	Bucket Bucket
+}
+
+func (e *ExportSink) Put(ctx context.Context, records []Record) error {
+	months := make(map[string][]*Record)
+	for i := range records {
+		month := records[i].AlertSince.UTC().Format("2006-01")
+		months[month] = append(months[month], &records[i])
+	}
+	for month, rs := range months {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		enc := json.NewEncoder(zw)
+		for _, r := range rs {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		name := filePrefix + month + "-" + strconv.FormatInt(rs[0].ArchivedAt.UnixNano(), 10) + fileSuffix
+		if err := e.Bucket.Put(ctx, name, buf.Bytes()); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (e *ExportSink) Scan(ctx context.Context, from, to time.Time, fn func(records []Record) error) error {
+	from, to = from.UTC(), to.UTC()
+	for month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC); month.Before(to); month = month.AddDate(0, 1, 0) {
+		names, err := e.Bucket.List(ctx, filePrefix+month.Format("2006-01")+"-")
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			records, err := e.read(ctx, name, from, to)
+			if err != nil {
+				return fmt.Errorf("read %s: %w", name, err)
+			}
+			if len(records) == 0 {
+				continue
+			}
+			if err := fn(records); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *ExportSink) read(ctx context.Context, name string, from, to time.Time) ([]Record, error) {
+	data, err := e.Bucket.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	var out []Record
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	for {
+		var r Record
+		if err := dec.Decode(&r); err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if !r.AlertSince.Before(from) && r.AlertSince.Before(to) {
+			out = append(out, r)
+		}
+	}
+}
+
+func (e *ExportSink) Delete(ctx context.Context, ids []string) error {
+	return nil
+}
+
+// DirBucket is a local directory.
+type DirBucket string
+
+func (d DirBucket) Put(ctx context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(string(d), 0o755); err != nil {
+		return err
+	}
+	// a partly written file is never listed
+	tmp := filepath.Join(string(d), "."+name+".tmp")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(string(d), name))
+}
+
+func (d DirBucket) List(ctx context.Context, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(string(d))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), prefix) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (d DirBucket) Get(ctx context.Context, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(d), name))
+}
+
+// objectListLimit is the max_keys of one listing; the storage service does not page.
+const objectListLimit = 10000
+
+// ObjectBucket is a bucket of the MockS3 storage service (mock/s3), e.g. at
+// http://localhost:8082.
+type ObjectBucket struct {
+	Endpoint string
+	Bucket   string
+	Client   *http.Client
+}
+
+func NewObjectBucket(endpoint, bucket string) *ObjectBucket {
+	return &ObjectBucket{Endpoint: strings.TrimRight(endpoint, "/"), Bucket: bucket, Client: &http.Client{Timeout: time.Minute}}
+}
+
+func (o *ObjectBucket) Put(ctx context.Context, name string, data []byte) error {
+	body, err := json.Marshal(map[string]any{"bucket": o.Bucket, "key": name, "data": data, "content_type": "application/gzip"})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.Endpoint+"/api/v1/objects", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	_, err = o.do(req)
+	return err
+}
+
+func (o *ObjectBucket) List(ctx context.Context, prefix string) ([]string, error) {
+	q := url.Values{"bucket": {o.Bucket}, "prefix": {prefix}, "max_keys": {strconv.Itoa(objectListLimit)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.Endpoint+"/api/v1/objects?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := o.do(req)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Objects []struct {
+			Key string `json:"key"`
+		} `json:"objects"`
+		IsTruncated bool `json:"is_truncated"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode object list: %w", err)
+	}
+	if resp.IsTruncated {
+		return nil, fmt.Errorf("more than %d objects start with %s", objectListLimit, prefix)
+	}
+	names := make([]string, 0, len(resp.Objects))
+	for _, obj := range resp.Objects {
+		names = append(names, obj.Key)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (o *ObjectBucket) Get(ctx context.Context, name string) ([]byte, error) {
+	u := o.Endpoint + "/api/v1/objects/" + url.PathEscape(o.Bucket) + "/" + url.PathEscape(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	return o.do(req)
+}
+
+func (o *ObjectBucket) do(req *http.Request) ([]byte, error) {
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(data))
+	}
+	return data, nil
+}
@@ -0,0 +1,237 @@
+// Package credential manages the per-integration credentials webhook senders
+// authenticate with, stored in alert_webhook_credentials and checked by
+// receiver.Authenticator. Every change is recorded in the audit log.
+package credential
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+)
+
+var (
+	ErrInvalid  = errors.New("invalid credential")
+	ErrNotFound = errors.New("credential not found")
+)
+
+// Audit log actions of credential changes.
+const (
+	ActionCreate = "credential.create"
+	ActionRotate = "credential.rotate"
+	ActionRevoke = "credential.revoke"
+)
+
+// DefaultOverlap is how long a rotated credential keeps working next to its successor.
+const DefaultOverlap = 24 * time.Hour
+
+// maxOverlap keeps forgotten rotations from leaving the old secret valid indefinitely.
+const maxOverlap = 30 * 24 * time.Hour
+
+// integrationName is also the Basic auth user name, so it cannot contain ':'.
+var integrationName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+// Credential is the API representation of an alert_webhook_credentials row.
+type Credential struct {
+	ID          string     `json:"id"`
+	Integration string     `json:"integration"`
+	Kind        string     `json:"kind"` // hmac or token
+	CreatedBy   string     `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	Active      bool       `json:"active"` // computed when read
+	// Secret is the HMAC key or token, only returned when the credential is created.
+	Secret string `json:"secret,omitempty"`
+}
+
+// CreateRequest is the body of POST /v1/integrations/credentials.
+type CreateRequest struct {
+	Integration string `json:"integration"`
+	Kind        string `json:"kind"`
+	// CreatedBy and RemoteAddr identify the authenticated caller in the audit log.
+	CreatedBy  string `json:"-"`
+	RemoteAddr string `json:"-"`
+}
+
+// RotateRequest is the body of POST /v1/integrations/credentials/:id/rotate.
+type RotateRequest struct {
+	// OverlapSeconds is how long the old credential keeps working, DefaultOverlap when
+	// unset; 0 revokes it at once.
+	OverlapSeconds *int   `json:"overlapSeconds,omitempty"`
+	CreatedBy      string `json:"-"`
+	RemoteAddr     string `json:"-"`
+}
+
+// HashToken returns the hex SHA-256 stored for a token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Sign returns the X-Zeroops-Signature of a webhook body sent at the Unix time ts:
+// "sha256=" followed by the hex HMAC-SHA256 of "<ts>.<body>" keyed with secret.
+func Sign(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Service manages webhook credentials in the alerting store.
+type Service struct {
+	DB  adb.Store
+	Now func() time.Time
+}
+
+func NewService(db adb.Store) *Service {
+	return &Service{DB: db, Now: time.Now}
+}
+
+// Create stores a new credential of an integration and returns it with its secret.
+func (s *Service) Create(ctx context.Context, req *CreateRequest) (*Credential, error) {
+	if !integrationName.MatchString(req.Integration) {
+		return nil, fmt.Errorf("%w: integration must be 1-64 letters, digits, '_', '.' or '-'", ErrInvalid)
+	}
+	if req.Kind != adb.CredentialHMAC && req.Kind != adb.CredentialToken {
+		return nil, fmt.Errorf("%w: kind must be %s or %s", ErrInvalid, adb.CredentialHMAC, adb.CredentialToken)
+	}
+	var out *Credential
+	err := s.DB.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = s.insert(ctx, req.Integration, req.Kind, req.CreatedBy)
+		if err != nil {
+			return err
+		}
+		return s.audit(ctx, ActionCreate, req.CreatedBy, req.RemoteAddr, out.ID,
+			"integration="+out.Integration+" kind="+out.Kind)
+	})
+	return out, err
+}
+
+// List returns the credentials of integration ordered by integration and creation time,
+// expired ones only when includeExpired is set; an empty integration returns those of
+// every integration. Secrets are not returned.
+func (s *Service) List(ctx context.Context, integration string, includeExpired bool) ([]Credential, error) {
+	now := s.Now().UTC()
+	activeAt := now
+	if includeExpired {
+		activeAt = time.Time{}
+	}
+	rows, err := s.DB.ListWebhookCredentials(ctx, integration, activeAt)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Credential, 0, len(rows))
+	for i := range rows {
+		out = append(out, *fromRow(&rows[i], now))
+	}
+	return out, nil
+}
+
+// Rotate creates a successor of an active credential with the same integration and kind,
+// lets the old one expire after the overlap, and returns the successor with its secret.
+func (s *Service) Rotate(ctx context.Context, id string, req *RotateRequest) (*Credential, error) {
+	overlap := DefaultOverlap
+	if req.OverlapSeconds != nil {
+		overlap = time.Duration(*req.OverlapSeconds) * time.Second
+	}
+	if overlap < 0 || overlap > maxOverlap {
+		return nil, fmt.Errorf("%w: overlapSeconds must be in 0-%d", ErrInvalid, int(maxOverlap.Seconds()))
+	}
+	var out *Credential
+	err := s.DB.InTx(ctx, func(ctx context.Context) error {
+		old, err := s.DB.GetWebhookCredential(ctx, id)
+		if err != nil {
+			return err
+		}
+		now := s.Now().UTC()
+		if old == nil || !old.Active(now) {
+			return ErrNotFound
+		}
+		if out, err = s.insert(ctx, old.Integration, old.Kind, req.CreatedBy); err != nil {
+			return err
+		}
+		expiresAt := now.Add(overlap)
+		if _, err := s.DB.ExpireWebhookCredential(ctx, id, expiresAt); err != nil {
+			return err
+		}
+		return s.audit(ctx, ActionRotate, req.CreatedBy, req.RemoteAddr, id,
+			"integration="+old.Integration+" successor="+out.ID+" expiresAt="+expiresAt.Format(time.RFC3339))
+	})
+	return out, err
+}
+
+// Revoke makes a credential expire now.
+func (s *Service) Revoke(ctx context.Context, id, by, remoteAddr string) error {
+	return s.DB.InTx(ctx, func(ctx context.Context) error {
+		old, err := s.DB.GetWebhookCredential(ctx, id)
+		if err != nil {
+			return err
+		}
+		if old == nil {
+			return ErrNotFound
+		}
+		if _, err := s.DB.ExpireWebhookCredential(ctx, id, s.Now().UTC()); err != nil {
+			return err
+		}
+		return s.audit(ctx, ActionRevoke, by, remoteAddr, id, "integration="+old.Integration)
+	})
+}
+
+func (s *Service) insert(ctx context.Context, integration, kind, by string) (*Credential, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	secret := hex.EncodeToString(b)
+	row := &adb.WebhookCredential{
+		ID:          uuid.NewString(),
+		Integration: integration,
+		Kind:        kind,
+		Secret:      secret,
+		CreatedBy:   by,
+		CreatedAt:   s.Now().UTC(),
+	}
+	if kind == adb.CredentialToken {
+		row.Secret = HashToken(secret)
+	}
+	if err := s.DB.InsertWebhookCredential(ctx, row); err != nil {
+		return nil, err
+	}
+	c := fromRow(row, row.CreatedAt)
+	c.Secret = secret
+	return c, nil
+}
+
+func (s *Service) audit(ctx context.Context, action, actor, remoteAddr, target, detail string) error {
+	return s.DB.InsertAuditLog(ctx, &adb.AuditLog{
+		At:         s.Now().UTC(),
+		Actor:      actor,
+		Action:     action,
+		Target:     target,
+		RemoteAddr: remoteAddr,
+		Detail:     detail,
+	})
+}
+
+func fromRow(row *adb.WebhookCredential, now time.Time) *Credential {
+	return &Credential{
+		ID:          row.ID,
+		Integration: row.Integration,
+		Kind:        row.Kind,
+		CreatedBy:   row.CreatedBy,
+		CreatedAt:   row.CreatedAt,
+		ExpiresAt:   row.ExpiresAt,
+		Active:      row.Active(now),
+	}
+}
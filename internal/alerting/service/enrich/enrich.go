@@ -0,0 +1,215 @@
+// Package enrich prepares incoming alerts before the receiver turns them into issues:
+// labels are rewritten with Prometheus-style relabel rules, the service is resolved from
+// the instance catalog when the alert does not name one, and the service owner,
+// dependencies, active deployment and a runbook URL are attached as labels.
+package enrich
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/region"
+	"github.com/rs/zerolog/log"
+)
+
+// Labels attached by the enricher. Labels already set on the alert are kept.
+const (
+	LabelTeam       = "team"
+	LabelDeps       = "service_deps"       // comma-separated services the service depends on
+	LabelDependents = "service_dependents" // comma-separated services depending on it directly
+	LabelDeployID   = "deploy_id"
+	LabelRunbookURL = "runbook_url"
+)
+
+// anyAlert is the Runbooks key used for alerts without their own template.
+const anyAlert = "*"
+
+// ServiceInfo is what service_manager knows about the service of an alert.
+type ServiceInfo struct {
+	Owner      string
+	Deps       []string
+	Dependents []string
+	// DeployID is the deployment in progress in the alert's region, "" if none.
+	DeployID string
+}
+
+// Catalog looks up services in service_manager.
+type Catalog interface {
+	// InstanceService returns the service of the instance with the given ID, host name or
+	// address (a port is ignored), or "" when no instance matches.
+	InstanceService(ctx context.Context, instance string) (string, error)
+	// AlertServiceInfo returns nil when the service is not registered.
+	AlertServiceInfo(ctx context.Context, service, region string) (*ServiceInfo, error)
+}
+
+// Enricher applies the enrichment stage to alert labels. It is safe for concurrent use.
+type Enricher struct {
+	rules         []rule
+	serviceLabels []string
+	runbooks      map[string]*template.Template
+	catalog       Catalog
+	ttl           time.Duration
+	now           func() time.Time
+
+	mu        sync.Mutex
+	instances map[string]cached[string]
+	services  map[[2]string]cached[*ServiceInfo]
+}
+
+// maxCached is the cache size above which expired entries are purged on insert.
+const maxCached = 1024
+
+type cached[T any] struct {
+	value   T
+	expires time.Time
+}
+
+// New compiles cfg. catalog may be nil, which disables the service lookups.
+func New(cfg *config.EnrichmentConfig, catalog Catalog) (*Enricher, error) {
+	rules, err := compileRules(cfg.RelabelConfigs)
+	if err != nil {
+		return nil, err
+	}
+	runbooks := make(map[string]*template.Template, len(cfg.Runbooks))
+	for name, text := range cfg.Runbooks {
+		t, err := template.New(name).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("runbooks[%s]: %w", name, err)
+		}
+		runbooks[name] = t
+	}
+	return &Enricher{
+		rules:         rules,
+		serviceLabels: cfg.ServiceLabels,
+		runbooks:      runbooks,
+		catalog:       catalog,
+		ttl:           time.Duration(cfg.CatalogCacheSeconds) * time.Second,
+		now:           time.Now,
+		instances:     make(map[string]cached[string]),
+		services:      make(map[[2]string]cached[*ServiceInfo]),
+	}, nil
+}
+
+// Enrich returns the enriched copy of labels, or false when a relabel rule drops the
+// alert. Catalog errors are logged and leave the catalog labels unset, so a
+// service_manager outage never blocks ingestion.
+func (e *Enricher) Enrich(ctx context.Context, labels map[string]string) (map[string]string, bool) {
+	out := maps.Clone(labels)
+	if out == nil {
+		out = make(map[string]string)
+	}
+	if !relabel(out, e.rules) {
+		return nil, false
+	}
+
+	if e.catalog != nil {
+		if strings.TrimSpace(out["service"]) == "" {
+			if service := e.resolveService(ctx, out); service != "" {
+				out["service"] = service
+			}
+		}
+		if service := strings.TrimSpace(out["service"]); service != "" {
+			e.attachServiceInfo(ctx, out, service)
+		}
+	}
+
+	if out[LabelRunbookURL] == "" {
+		if url := e.runbookURL(out); url != "" {
+			out[LabelRunbookURL] = url
+		}
+	}
+	return out, true
+}
+
+func (e *Enricher) resolveService(ctx context.Context, labels map[string]string) string {
+	for _, key := range e.serviceLabels {
+		instance := strings.TrimSpace(labels[key])
+		if instance == "" {
+			continue
+		}
+		service, err := lookup(e, e.instances, instance, func() (string, error) {
+			return e.catalog.InstanceService(ctx, instance)
+		})
+		if err != nil {
+			log.Warn().Err(err).Str("instance", instance).Msg("alert enrichment: instance lookup failed")
+			return ""
+		}
+		if service != "" {
+			return service
+		}
+	}
+	return ""
+}
+
+func (e *Enricher) attachServiceInfo(ctx context.Context, labels map[string]string, service string) {
+	regionName := region.FromLabels(labels)
+	info, err := lookup(e, e.services, [2]string{service, regionName}, func() (*ServiceInfo, error) {
+		return e.catalog.AlertServiceInfo(ctx, service, regionName)
+	})
+	if err != nil {
+		log.Warn().Err(err).Str("service", service).Msg("alert enrichment: service lookup failed")
+		return
+	}
+	if info == nil {
+		return
+	}
+	setDefault(labels, LabelTeam, info.Owner)
+	setDefault(labels, LabelDeps, strings.Join(info.Deps, ","))
+	setDefault(labels, LabelDependents, strings.Join(info.Dependents, ","))
+	setDefault(labels, LabelDeployID, info.DeployID)
+}
+
+func (e *Enricher) runbookURL(labels map[string]string) string {
+	t, ok := e.runbooks[labels["alertname"]]
+	if !ok {
+		if t, ok = e.runbooks[anyAlert]; !ok {
+			return ""
+		}
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, struct{ Labels map[string]string }{labels}); err != nil {
+		log.Warn().Err(err).Str("alertname", labels["alertname"]).Msg("alert enrichment: runbook template failed")
+		return ""
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// lookup returns the cached value of key or loads it. Errors are not cached.
+func lookup[K comparable, V any](e *Enricher, cache map[K]cached[V], key K, load func() (V, error)) (V, error) {
+	now := e.now()
+	e.mu.Lock()
+	c, ok := cache[key]
+	e.mu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.value, nil
+	}
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	if e.ttl > 0 {
+		e.mu.Lock()
+		if len(cache) >= maxCached {
+			for k, c := range cache {
+				if !now.Before(c.expires) {
+					delete(cache, k)
+				}
+			}
+		}
+		cache[key] = cached[V]{value: v, expires: now.Add(e.ttl)}
+		e.mu.Unlock()
+	}
+	return v, nil
+}
+
+func setDefault(labels map[string]string, key, value string) {
+	if value != "" && labels[key] == "" {
+		labels[key] = value
+	}
+}
@@ -0,0 +1,171 @@
+package enrich
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/qiniu/zeroops/internal/config"
+)
+
+func ptr(s string) *string { return &s }
+
+func TestRelabel(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []config.RelabelConfig
+		in    map[string]string
+		want  map[string]string // nil means dropped
+	}{
+		{
+			name: "rename with labelmap and labeldrop",
+			rules: []config.RelabelConfig{
+				{Action: ActionLabelMap, Regex: "app_(.+)", Replacement: ptr("$1")},
+				{Action: ActionLabelDrop, Regex: "app_.+"},
+			},
+			in:   map[string]string{"app_service": "api", "alertname": "HighLatency"},
+			want: map[string]string{"service": "api", "alertname": "HighLatency"},
+		},
+		{
+			name: "regex replace",
+			rules: []config.RelabelConfig{{
+				SourceLabels: []string{"instance"}, Regex: `([^:]+):\d+`, TargetLabel: "host_id",
+			}},
+			in:   map[string]string{"instance": "10.0.0.1:9100"},
+			want: map[string]string{"instance": "10.0.0.1:9100", "host_id": "10.0.0.1"},
+		},
+		{
+			name: "join source labels",
+			rules: []config.RelabelConfig{{
+				SourceLabels: []string{"idc", "service"}, Separator: "/", TargetLabel: "scope",
+			}},
+			in:   map[string]string{"idc": "yzh", "service": "api"},
+			want: map[string]string{"idc": "yzh", "service": "api", "scope": "yzh/api"},
+		},
+		{
+			name:  "empty replacement deletes the target",
+			rules: []config.RelabelConfig{{SourceLabels: []string{"env"}, TargetLabel: "env", Replacement: ptr("")}},
+			in:    map[string]string{"env": "prod", "service": "api"},
+			want:  map[string]string{"service": "api"},
+		},
+		{
+			name:  "drop",
+			rules: []config.RelabelConfig{{Action: ActionDrop, SourceLabels: []string{"env"}, Regex: "staging|dev"}},
+			in:    map[string]string{"env": "staging"},
+		},
+		{
+			name:  "keep",
+			rules: []config.RelabelConfig{{Action: ActionKeep, SourceLabels: []string{"env"}, Regex: "prod"}},
+			in:    map[string]string{"env": "prod-canary"}, // anchored
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := New(&config.EnrichmentConfig{RelabelConfigs: tc.rules}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, keep := e.Enrich(context.Background(), tc.in)
+			if tc.want == nil {
+				if keep {
+					t.Fatalf("kept %v, want dropped", got)
+				}
+				return
+			}
+			if !keep || !maps.Equal(got, tc.want) {
+				t.Fatalf("got %v (keep=%v), want %v", got, keep, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	for _, rules := range [][]config.RelabelConfig{
+		{{Action: "rename"}},
+		{{Action: ActionReplace, SourceLabels: []string{"a"}}},
+		{{Action: ActionDrop}},
+		{{Action: ActionLabelDrop, Regex: "("}},
+	} {
+		if _, err := New(&config.EnrichmentConfig{RelabelConfigs: rules}, nil); err == nil {
+			t.Fatalf("rules %+v accepted", rules)
+		}
+	}
+}
+
+type fakeCatalog struct {
+	instances map[string]string
+	info      map[string]*ServiceInfo
+	err       error
+	calls     int
+}
+
+func (f *fakeCatalog) InstanceService(_ context.Context, instance string) (string, error) {
+	f.calls++
+	return f.instances[instance], f.err
+}
+
+func (f *fakeCatalog) AlertServiceInfo(_ context.Context, service, region string) (*ServiceInfo, error) {
+	f.calls++
+	return f.info[service+"/"+region], f.err
+}
+
+func TestEnrichFromCatalog(t *testing.T) {
+	catalog := &fakeCatalog{
+		instances: map[string]string{"host-7": "storage"},
+		info: map[string]*ServiceInfo{"storage/cn-east-1": {
+			Owner: "storage-team", Deps: []string{"mq", "mysql"}, Dependents: []string{"api"}, DeployID: "deploy-9",
+		}},
+	}
+	e, err := New(&config.EnrichmentConfig{
+		ServiceLabels:       []string{"instance", "host_id"},
+		CatalogCacheSeconds: 30,
+		Runbooks: map[string]string{
+			"DiskFull": "https://runbooks.example.com/{{ .Labels.service }}/disk-full",
+			"*":        "https://runbooks.example.com/{{ .Labels.alertname }}",
+		},
+	}, catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := map[string]string{"alertname": "DiskFull", "host_id": "host-7", "region": "cn-east-1", "team": "oncall-b"}
+	got, keep := e.Enrich(context.Background(), labels)
+	want := map[string]string{
+		"alertname": "DiskFull", "host_id": "host-7", "region": "cn-east-1",
+		"service":            "storage",
+		"team":               "oncall-b", // set by the alert, kept
+		"service_deps":       "mq,mysql",
+		"service_dependents": "api",
+		"deploy_id":          "deploy-9",
+		"runbook_url":        "https://runbooks.example.com/storage/disk-full",
+	}
+	if !keep || !maps.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if labels["service"] != "" {
+		t.Fatal("input labels were modified")
+	}
+
+	calls := catalog.calls
+	e.Enrich(context.Background(), labels)
+	if catalog.calls != calls {
+		t.Fatalf("lookups not cached: %d calls, want %d", catalog.calls, calls)
+	}
+
+	got, _ = e.Enrich(context.Background(), map[string]string{"alertname": "HighLatency", "service": "unknown"})
+	if got["runbook_url"] != "https://runbooks.example.com/HighLatency" || got["team"] != "" {
+		t.Fatalf("unregistered service: %v", got)
+	}
+}
+
+func TestEnrichIgnoresCatalogErrors(t *testing.T) {
+	e, err := New(&config.EnrichmentConfig{ServiceLabels: []string{"instance"}}, &fakeCatalog{err: errors.New("down")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, keep := e.Enrich(context.Background(), map[string]string{"alertname": "Down", "instance": "host-1"})
+	if !keep || len(got) != 2 {
+		t.Fatalf("got %v (keep=%v)", got, keep)
+	}
+}
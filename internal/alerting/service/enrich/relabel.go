@@ -0,0 +1,136 @@
+package enrich
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/qiniu/zeroops/internal/config"
+)
+
+// Relabel actions, as in Prometheus relabel_config.
+const (
+	ActionReplace   = "replace"
+	ActionKeep      = "keep"
+	ActionDrop      = "drop"
+	ActionLabelMap  = "labelmap"
+	ActionLabelDrop = "labeldrop"
+	ActionLabelKeep = "labelkeep"
+)
+
+// rule is a compiled RelabelConfig.
+type rule struct {
+	sourceLabels []string
+	separator    string
+	regex        *regexp.Regexp
+	targetLabel  string
+	replacement  string
+	action       string
+}
+
+// compileRules validates cfgs and fills in the Prometheus defaults: separator ";",
+// regex "(.*)", replacement "$1" and action replace.
+func compileRules(cfgs []config.RelabelConfig) ([]rule, error) {
+	rules := make([]rule, 0, len(cfgs))
+	for i, c := range cfgs {
+		r := rule{
+			sourceLabels: c.SourceLabels,
+			separator:    c.Separator,
+			targetLabel:  c.TargetLabel,
+			replacement:  "$1",
+			action:       strings.ToLower(strings.TrimSpace(c.Action)),
+		}
+		if r.separator == "" {
+			r.separator = ";"
+		}
+		if c.Replacement != nil {
+			r.replacement = *c.Replacement
+		}
+		if r.action == "" {
+			r.action = ActionReplace
+		}
+		expr := c.Regex
+		if expr == "" {
+			expr = "(.*)"
+		}
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("relabelConfigs[%d]: invalid regex %q: %w", i, c.Regex, err)
+		}
+		r.regex = re
+
+		switch r.action {
+		case ActionReplace:
+			if r.targetLabel == "" {
+				return nil, fmt.Errorf("relabelConfigs[%d]: replace requires targetLabel", i)
+			}
+		case ActionKeep, ActionDrop:
+			if len(r.sourceLabels) == 0 {
+				return nil, fmt.Errorf("relabelConfigs[%d]: %s requires sourceLabels", i, r.action)
+			}
+		case ActionLabelMap, ActionLabelDrop, ActionLabelKeep:
+		default:
+			return nil, fmt.Errorf("relabelConfigs[%d]: unknown action %q", i, c.Action)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// relabel applies rules to labels in place and reports whether the alert is kept.
+func relabel(labels map[string]string, rules []rule) bool {
+	for _, r := range rules {
+		values := make([]string, len(r.sourceLabels))
+		for i, name := range r.sourceLabels {
+			values[i] = labels[name]
+		}
+		value := strings.Join(values, r.separator)
+
+		switch r.action {
+		case ActionReplace:
+			m := r.regex.FindStringSubmatchIndex(value)
+			if m == nil {
+				continue
+			}
+			target := string(r.regex.ExpandString(nil, r.targetLabel, value, m))
+			res := string(r.regex.ExpandString(nil, r.replacement, value, m))
+			if res == "" {
+				delete(labels, target)
+			} else {
+				labels[target] = res
+			}
+		case ActionKeep:
+			if !r.regex.MatchString(value) {
+				return false
+			}
+		case ActionDrop:
+			if r.regex.MatchString(value) {
+				return false
+			}
+		case ActionLabelMap:
+			// Collect first so labels added here are not mapped again.
+			mapped := make(map[string]string)
+			for name, v := range labels {
+				if m := r.regex.FindStringSubmatchIndex(name); m != nil {
+					mapped[string(r.regex.ExpandString(nil, r.replacement, name, m))] = v
+				}
+			}
+			for name, v := range mapped {
+				labels[name] = v
+			}
+		case ActionLabelDrop:
+			for name := range labels {
+				if r.regex.MatchString(name) {
+					delete(labels, name)
+				}
+			}
+		case ActionLabelKeep:
+			for name := range labels {
+				if !r.regex.MatchString(name) {
+					delete(labels, name)
+				}
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+// Package feed publishes changes of the cached issues (alert:issue:*) on a Redis pub/sub
+// channel and fans them out to subscribers, so clients of any replica see the changes
+// written by every replica. Writers publish in the same script or pipeline as the cache
+// write; messages are not stored, so subscribers missing one must list issues again.
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// Channel is the Redis pub/sub channel of issue changes.
+const Channel = "alert:issue:events"
+
+// Message types.
+const (
+	// EventCreated is published when the receiver caches a new issue.
+	EventCreated = "created"
+	// EventUpdated is published when an issue changes and stays open, e.g. on ack or
+	// when the health check moves it to InProcessing.
+	EventUpdated = "updated"
+	// EventClosed is published when an issue is closed.
+	EventClosed = "closed"
+)
+
+// subscriberBuffer bounds the messages queued for a subscriber.
+const subscriberBuffer = 64
+
+// Message is one change: the cached issue after it.
+type Message struct {
+	Type  string          `json:"type"`
+	Issue json.RawMessage `json:"issue"`
+}
+
+// Publish queues a message of the cached issue on c, which can be a pipeline or a
+// transaction writing the issue.
+func Publish(ctx context.Context, c redis.Cmdable, typ string, issue json.RawMessage) *redis.IntCmd {
+	b, _ := json.Marshal(Message{Type: typ, Issue: issue})
+	return c.Publish(ctx, Channel, b)
+}
+
+// Lua returns the statement publishing a message of type typ from a Lua script that
+// holds the updated issue in obj.
+func Lua(typ string) string {
+	return `redis.call('PUBLISH', '` + Channel + `', cjson.encode({type = '` + typ + `', issue = obj}))`
+}
+
+// Hub fans Channel out to the subscribers of this process. It holds one Redis
+// subscription while it has subscribers.
+type Hub struct {
+	R *redis.Client
+
+	mu   sync.Mutex
+	subs map[chan Message]struct{}
+	ps   *redis.PubSub
+}
+
+func NewHub(rdb *redis.Client) *Hub {
+	return &Hub{R: rdb, subs: make(map[chan Message]struct{})}
+}
+
+// Subscribe returns a channel receiving every message published after it returns and
+// a cancel func that must be called. The channel is closed when the subscriber falls
+// more than a buffer behind, after which it should list issues and subscribe again.
+func (h *Hub) Subscribe(ctx context.Context) (<-chan Message, func(), error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ps == nil {
+		ps := h.R.Subscribe(context.Background(), Channel)
+		// wait for the subscription so no message published after Subscribe is missed
+		if _, err := ps.Receive(ctx); err != nil {
+			_ = ps.Close()
+			return nil, nil, err
+		}
+		h.ps = ps
+		go h.pump(ps)
+	}
+	ch := make(chan Message, subscriberBuffer)
+	h.subs[ch] = struct{}{}
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if _, ok := h.subs[ch]; ok {
+				delete(h.subs, ch)
+				close(ch)
+			}
+			if len(h.subs) == 0 && h.ps != nil {
+				_ = h.ps.Close()
+				h.ps = nil
+			}
+		})
+	}
+	return ch, cancel, nil
+}
+
+func (h *Hub) pump(ps *redis.PubSub) {
+	for m := range ps.Channel() {
+		var msg Message
+		if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+			log.Warn().Err(err).Msg("dropping malformed issue feed message")
+			continue
+		}
+		h.mu.Lock()
+		if h.ps != ps {
+			h.mu.Unlock()
+			return
+		}
+		for ch := range h.subs {
+			select {
+			case ch <- msg:
+			default:
+				delete(h.subs, ch)
+				close(ch)
+			}
+		}
+		h.mu.Unlock()
+	}
+}
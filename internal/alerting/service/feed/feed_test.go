@@ -0,0 +1,84 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func receive(t *testing.T, ch <-chan Message) Message {
+	t.Helper()
+	select {
+	case m, ok := <-ch:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return m
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message")
+	}
+	return Message{}
+}
+
+func TestHub(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+
+	h := NewHub(rdb)
+	a, cancelA, err := h.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, cancelB, err := h.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pipe := rdb.TxPipeline()
+	pipe.Set(ctx, "alert:issue:issue-1", `{"id":"issue-1"}`, 0)
+	Publish(ctx, pipe, EventCreated, json.RawMessage(`{"id":"issue-1"}`))
+	if _, err := pipe.Exec(ctx); err != nil {
+		t.Fatal(err)
+	}
+	script := redis.NewScript(`
+local obj = cjson.decode(redis.call('GET', KEYS[1]))
+obj.state = 'Closed'
+` + Lua(EventClosed))
+	if err := script.Run(ctx, rdb, []string{"alert:issue:issue-1"}).Err(); err != nil && err != redis.Nil {
+		t.Fatal(err)
+	}
+
+	for _, ch := range []<-chan Message{a, b} {
+		if m := receive(t, ch); m.Type != EventCreated || string(m.Issue) != `{"id":"issue-1"}` {
+			t.Fatalf("created = %s %s", m.Type, m.Issue)
+		}
+		var issue struct{ ID, State string }
+		if m := receive(t, ch); m.Type != EventClosed || json.Unmarshal(m.Issue, &issue) != nil || issue.State != "Closed" {
+			t.Fatalf("closed = %s %s", m.Type, m.Issue)
+		}
+	}
+
+	// a subscriber that falls behind is dropped
+	cancelA()
+	for range 2 * subscriberBuffer {
+		Publish(ctx, rdb, EventUpdated, json.RawMessage(`{}`))
+	}
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-b:
+			if !ok {
+				cancelB()
+				return
+			}
+		case <-deadline:
+			t.Fatal("slow subscriber was not dropped")
+		}
+	}
+}
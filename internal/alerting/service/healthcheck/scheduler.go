@@ -2,18 +2,22 @@ package healthcheck
 
 import (
 	"context"
-	"encoding/json"
 	"os"
 	"strconv"
 	"time"
 
 	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/feed"
+	"github.com/qiniu/zeroops/internal/observability"
+	"github.com/qiniu/zeroops/internal/region"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Deps struct {
-	DB       *adb.Database
+	DB       adb.IssueRepository // nil disables the scan
 	Redis    *redis.Client
 	AlertCh  chan<- AlertMessage
 	Batch    int
@@ -44,73 +48,75 @@ func StartScheduler(ctx context.Context, deps Deps) {
 		case <-ctx.Done():
 			return
 		case <-t.C:
-			if err := runOnce(ctx, deps.DB, deps.Redis, deps.AlertCh, deps.Batch); err != nil {
+			start := time.Now()
+			n, err := runOnce(ctx, deps.DB, deps.Redis, deps.AlertCh, deps.Batch)
+			if err != nil && ctx.Err() == nil {
 				log.Error().Err(err).Msg("healthcheck runOnce failed")
 			}
+			observability.Metrics().SchedulerTick(ctx, time.Since(start), n)
 		}
 	}
 }
 
-type pendingRow struct {
-	ID         string
-	Level      string
-	Title      string
-	AlertSince time.Time
-	LabelsJSON string
-}
-
-func runOnce(ctx context.Context, db *adb.Database, rdb *redis.Client, ch chan<- AlertMessage, batch int) error {
+// runOnce dispatches one batch of pending issues and returns how many were fetched.
+func runOnce(ctx context.Context, db adb.IssueRepository, rdb *redis.Client, ch chan<- AlertMessage, batch int) (int, error) {
 	rows, err := queryPendingFromDB(ctx, db, batch)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	for _, it := range rows {
-		labels := parseLabels(it.LabelsJSON)
-		svc := labels["service"]
-		ver := labels["service_version"]
-		// 1) publish to channel (non-blocking)
-		if ch != nil {
-			select {
-			case ch <- AlertMessage{ID: it.ID, Service: svc, Version: ver, Level: it.Level, Title: it.Title, AlertSince: it.AlertSince, Labels: labels}:
-			default:
-				// channel full, skip state change
-				continue
-			}
-		}
-		// 2) alert state CAS: Pending -> InProcessing
-		_ = alertStateCAS(ctx, rdb, it.ID, "Pending", "InProcessing")
-		// 3) service state CAS by derived level
-		if svc != "" {
-			target := deriveHealth(it.Level)
-			_ = serviceStateCAS(ctx, rdb, svc, ver, target)
+	// a row already handed to the channel finishes its CAS updates even if shutdown
+	// starts meanwhile; remaining rows are left Pending for the next run
+	rowCtx := context.WithoutCancel(ctx)
+	for i := range rows {
+		if ctx.Err() != nil {
+			break
 		}
+		dispatch(rowCtx, rdb, ch, &rows[i])
 	}
-	return nil
+	return len(rows), nil
 }
 
-func queryPendingFromDB(ctx context.Context, db *adb.Database, limit int) ([]pendingRow, error) {
-	if db == nil {
-		return []pendingRow{}, nil
-	}
-	const q = `SELECT id, level, title, labels, alert_since
-FROM alert_issues
-WHERE alert_state = 'Pending'
-ORDER BY alert_since ASC
-LIMIT $1`
-	rows, err := db.QueryContext(ctx, q, limit)
-	if err != nil {
-		return nil, err
+// dispatch hands one pending issue to remediation. Its span links to the webhook trace
+// that created the issue and is passed on to the remediation consumer.
+func dispatch(ctx context.Context, rdb *redis.Client, ch chan<- AlertMessage, it *adb.Issue) {
+	ctx, span := observability.Tracer().Start(ctx, "healthcheck.dispatch", append(observability.LinkTo(it.TraceParent),
+		trace.WithAttributes(attribute.String("issue.id", it.ID), attribute.String("issue.level", it.Level)))...)
+	defer span.End()
+
+	labels := adb.LabelMap(it.Labels)
+	svc := labels["service"]
+	ver := labels["service_version"]
+	reg := it.Region
+	if reg == "" {
+		reg = region.FromLabels(labels)
 	}
-	defer rows.Close()
-	out := make([]pendingRow, 0, limit)
-	for rows.Next() {
-		var it pendingRow
-		if err := rows.Scan(&it.ID, &it.Level, &it.Title, &it.LabelsJSON, &it.AlertSince); err != nil {
-			return nil, err
+	// 1) publish to channel (non-blocking)
+	if ch != nil {
+		msg := AlertMessage{ID: it.ID, Service: svc, Version: ver, Region: reg, Level: it.Level, Title: it.Title,
+			AlertSince: it.AlertSince, Labels: labels, TraceParent: observability.TraceParent(ctx)}
+		select {
+		case ch <- msg:
+		default:
+			// channel full, skip state change
+			observability.Metrics().ChannelDrop(ctx)
+			span.AddEvent("remediation channel full, dropped")
+			return
 		}
-		out = append(out, it)
 	}
-	return out, rows.Err()
+	// 2) alert state CAS: Pending -> InProcessing
+	_ = alertStateCAS(ctx, rdb, it.ID, "Pending", "InProcessing")
+	// 3) service state CAS by derived level
+	if svc != "" {
+		target := deriveHealth(it.Level)
+		_ = serviceStateCAS(ctx, rdb, svc, ver, reg, target)
+	}
+}
+
+func queryPendingFromDB(ctx context.Context, db adb.IssueRepository, limit int) ([]adb.Issue, error) {
+	if db == nil {
+		return []adb.Issue{}, nil
+	}
+	return db.ListPendingIssues(ctx, limit)
 }
 
 func alertStateCAS(ctx context.Context, rdb *redis.Client, id, expected, next string) error {
@@ -127,17 +133,18 @@ obj.alertState = ARGV[2]
 redis.call('SET', KEYS[1], cjson.encode(obj), 'KEEPTTL')
 redis.call('SREM', KEYS[2], ARGV[3])
 redis.call('SADD', KEYS[3], ARGV[3])
+` + feed.Lua(feed.EventUpdated) + `
 return 1
 `)
 	_, _ = script.Run(ctx, rdb, []string{key, "alert:index:alert_state:Pending", "alert:index:alert_state:InProcessing"}, expected, next, id).Result()
 	return nil
 }
 
-func serviceStateCAS(ctx context.Context, rdb *redis.Client, service, version, target string) error {
+func serviceStateCAS(ctx context.Context, rdb *redis.Client, service, version, regionName, target string) error {
 	if rdb == nil {
 		return nil
 	}
-	key := "service_state:" + service + ":" + version
+	key := "service_state:" + service + ":" + version + ":" + regionName
 	script := redis.NewScript(`
 local v = redis.call('GET', KEYS[1])
 if not v then v = '{}'; end
@@ -150,25 +157,3 @@ return 1
 	_, _ = script.Run(ctx, rdb, []string{key, "", "service_state:index:health:" + target}, "", target, key).Result()
 	return nil
 }
-
-// parseLabels supports either flat map {"k":"v"} or array [{"key":"k","value":"v"}]
-func parseLabels(s string) map[string]string {
-	m := map[string]string{}
-	if s == "" {
-		return m
-	}
-	// try map form
-	if json.Unmarshal([]byte(s), &m) == nil && len(m) > 0 {
-		return m
-	}
-	// try array form
-	var arr []struct{ Key, Value string }
-	if json.Unmarshal([]byte(s), &arr) == nil {
-		out := make(map[string]string, len(arr))
-		for _, kv := range arr {
-			out[kv.Key] = kv.Value
-		}
-		return out
-	}
-	return map[string]string{}
-}
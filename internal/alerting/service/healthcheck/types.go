@@ -9,10 +9,13 @@ type AlertMessage struct {
 	ID         string            `json:"id"`
 	Service    string            `json:"service"`
 	Version    string            `json:"version,omitempty"`
+	Region     string            `json:"region"`
 	Level      string            `json:"level"`
 	Title      string            `json:"title"`
 	AlertSince time.Time         `json:"alert_since"`
 	Labels     map[string]string `json:"labels"`
+	// TraceParent is the dispatch span that handed the alert to remediation.
+	TraceParent string `json:"trace_parent,omitempty"`
 }
 
 // deriveHealth maps alert level to service health state.
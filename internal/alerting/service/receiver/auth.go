@@ -1,42 +1,343 @@
 package receiver
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fox-gonic/fox"
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/credential"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/observability"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
 )
 
-func authEnabled() bool {
-	return os.Getenv("ALERT_WEBHOOK_BASIC_USER") != "" ||
-		os.Getenv("ALERT_WEBHOOK_BASIC_PASS") != "" ||
-		os.Getenv("ALERT_WEBHOOK_BEARER") != ""
+// Headers of signed webhook requests, see credential.Sign.
+const (
+	HeaderIntegration = "X-Zeroops-Integration"
+	HeaderTimestamp   = "X-Zeroops-Timestamp"
+	HeaderSignature   = "X-Zeroops-Signature"
+)
+
+// ActionAuthFailed is the audit log action of a rejected webhook credential.
+const ActionAuthFailed = "webhook.auth_failed"
+
+// SourceEnv is the source of requests authenticated with the environment credentials.
+const SourceEnv = "env"
+
+var (
+	errUnauthorized = apierror.New(apierror.Unauthorized, "unauthorized")
+	errRateLimited  = apierror.New(apierror.RateLimited, "too many webhook requests")
+)
+
+// envCredentials are the single credential set of ALERT_WEBHOOK_BASIC_USER/PASS and
+// ALERT_WEBHOOK_BEARER, read on every request.
+type envCredentials struct {
+	user, pass, bearer string
+}
+
+func readEnvCredentials() envCredentials {
+	return envCredentials{
+		user:   os.Getenv("ALERT_WEBHOOK_BASIC_USER"),
+		pass:   os.Getenv("ALERT_WEBHOOK_BASIC_PASS"),
+		bearer: os.Getenv("ALERT_WEBHOOK_BEARER"),
+	}
 }
 
-// AuthMiddleware returns false if unauthorized and writes a 401 response.
-func AuthMiddleware(c *fox.Context) bool {
-	if !authEnabled() {
-		return true
+func (e envCredentials) basic() bool   { return e.user != "" || e.pass != "" }
+func (e envCredentials) enabled() bool { return e.basic() || e.bearer != "" }
+
+// IsEnvCredential reports whether secret is the Basic password or bearer token of the
+// environment credentials, which every webhook sender holds.
+func IsEnvCredential(secret string) bool {
+	e := readEnvCredentials()
+	return secret != "" && (equal(secret, e.pass) || equal(secret, e.bearer))
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Authenticator checks webhook requests before their alerts are read. A request is
+// accepted with
+//   - the signature of an hmac credential of the integration named by
+//     X-Zeroops-Integration in X-Zeroops-Signature, see credential.Sign, made within
+//     the replay window of now and not seen before;
+//   - a token credential as "Authorization: Bearer <token>", or as the Basic password
+//     with the integration as user name;
+//   - the environment credentials.
+//
+// Requests pass unauthenticated only while neither environment nor database credentials
+// are configured. Bodies over the size limit are rejected, and the Limiter bounds the
+// requests of every integration and the failed attempts of every remote address.
+// Failures are counted in zeroops_webhook_rejected and recorded in the audit log.
+type Authenticator struct {
+	// Credentials is nil when only the environment credentials are used.
+	Credentials adb.WebhookCredentialRepository
+	// Audit is nil when failures are only logged.
+	Audit adb.AuditRepository
+	// R remembers seen signatures across replicas; nil remembers them in process.
+	R            *redis.Client
+	MaxBodyBytes int64
+	ReplayWindow time.Duration
+	CacheTTL     time.Duration
+	// Limiter is nil when requests are not rate limited.
+	Limiter *Limiter
+	Now     func() time.Time
+
+	mu       sync.Mutex
+	creds    []adb.WebhookCredential
+	loadedAt time.Time
+	seen     map[string]time.Time // signature -> expiry, when R is nil
+}
+
+// NewAuthenticator checks requests against the credentials of db and the environment;
+// db and rdb may be nil and a nil cfg uses the defaults of config.LoadFile.
+func NewAuthenticator(db adb.Store, rdb *redis.Client, cfg *config.WebhookConfig) *Authenticator {
+	if cfg == nil {
+		cfg = &config.WebhookConfig{MaxBodyBytes: 1 << 20, ReplayWindowSeconds: 300, RateLimit: 20, RateBurst: 100, CredentialCacheSeconds: 10}
+	}
+	a := &Authenticator{
+		R:            rdb,
+		MaxBodyBytes: cfg.MaxBodyBytes,
+		ReplayWindow: time.Duration(cfg.ReplayWindowSeconds) * time.Second,
+		CacheTTL:     time.Duration(cfg.CredentialCacheSeconds) * time.Second,
+		Limiter:      NewLimiter(cfg.RateLimit, cfg.RateBurst),
+		Now:          time.Now,
 	}
+	if db != nil {
+		a.Credentials, a.Audit = db, db
+	}
+	return a
+}
 
-	user := os.Getenv("ALERT_WEBHOOK_BASIC_USER")
-	pass := os.Getenv("ALERT_WEBHOOK_BASIC_PASS")
-	bearer := os.Getenv("ALERT_WEBHOOK_BEARER")
+// Authenticate returns the source of the request, the integration or SourceEnv, or ""
+// when authentication is off. It leaves the body readable again, and returns false after
+// writing the error response.
+func (a *Authenticator) Authenticate(c *fox.Context) (string, bool) {
+	ctx := c.Request.Context()
+	now := a.Now()
+	ip := RemoteIP(c.Request)
+	if wait := a.Limiter.Wait("ip:"+ip, now); wait > 0 {
+		a.reject(c, observability.WebhookRateLimited, wait)
+		return "", false
+	}
 
-	if user != "" || pass != "" {
-		u, p, ok := c.Request.BasicAuth()
-		if !ok || u != user || p != pass {
-			c.JSON(http.StatusUnauthorized, map[string]any{"ok": false, "error": "unauthorized"})
-			return false
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, a.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			observability.Metrics().WebhookRejected(ctx, observability.WebhookTooLarge)
+			apierror.WriteError(c, apierror.New(apierror.PayloadTooLarge, fmt.Sprintf("request body exceeds %d bytes", a.MaxBodyBytes)))
+			return "", false
 		}
-		return true
+		apierror.WriteError(c, apierror.Wrap(apierror.InvalidParameter, err, "failed to read request body"))
+		return "", false
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	source, reason, err := a.check(ctx, c.Request, body, now)
+	if err != nil {
+		apierror.WriteError(c, apierror.Wrap(apierror.Unavailable, err, "failed to check webhook credentials"))
+		return "", false
 	}
+	if reason != "" {
+		a.fail(c, ip, reason, now)
+		return "", false
+	}
+	key := source
+	if key == "" {
+		key = "ip:" + ip
+	}
+	if wait := a.Limiter.Take(key, now); wait > 0 {
+		a.reject(c, observability.WebhookRateLimited, wait)
+		return "", false
+	}
+	return source, true
+}
 
-	if bearer != "" {
-		if c.GetHeader("Authorization") != "Bearer "+bearer {
-			c.JSON(http.StatusUnauthorized, map[string]any{"ok": false, "error": "unauthorized"})
-			return false
+// check returns the source of an authenticated request or the reason it is rejected.
+func (a *Authenticator) check(ctx context.Context, r *http.Request, body []byte, now time.Time) (source, reason string, err error) {
+	env := readEnvCredentials()
+	creds, err := a.credentials(ctx, now)
+	if err != nil {
+		return "", "", err
+	}
+	if sig := r.Header.Get(HeaderSignature); sig != "" {
+		return a.checkSignature(ctx, r, sig, body, creds, now)
+	}
+	if user, pass, ok := r.BasicAuth(); ok {
+		if env.basic() && equal(user, env.user) && equal(pass, env.pass) {
+			return SourceEnv, "", nil
+		}
+		if c := matchToken(creds, user, pass, now); c != nil {
+			return c.Integration, "", nil
+		}
+		return "", observability.WebhookBadCredentials, nil
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		if env.bearer != "" && equal(token, env.bearer) {
+			return SourceEnv, "", nil
 		}
+		if c := matchToken(creds, "", token, now); c != nil {
+			return c.Integration, "", nil
+		}
+		return "", observability.WebhookBadCredentials, nil
+	}
+	if !env.enabled() && len(creds) == 0 {
+		return "", "", nil
+	}
+	return "", observability.WebhookMissingAuth, nil
+}
+
+// checkSignature verifies the signature before remembering it, so only signed requests
+// fill the replay cache.
+func (a *Authenticator) checkSignature(ctx context.Context, r *http.Request, sig string, body []byte, creds []adb.WebhookCredential, now time.Time) (string, string, error) {
+	integration := r.Header.Get(HeaderIntegration)
+	ts, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if integration == "" || err != nil {
+		return "", observability.WebhookBadCredentials, nil
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > a.ReplayWindow || d < -a.ReplayWindow {
+		return "", observability.WebhookStaleSignature, nil
+	}
+	signed := false
+	for i := range creds {
+		c := &creds[i]
+		if c.Integration == integration && c.Kind == adb.CredentialHMAC && c.Active(now) &&
+			equal(credential.Sign(c.Secret, ts, body), sig) {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return "", observability.WebhookBadCredentials, nil
+	}
+	// a signature stays fresh for a window on either side of its timestamp
+	fresh, err := a.remember(ctx, integration+":"+sig, 2*a.ReplayWindow, now)
+	if err != nil {
+		return "", "", err
+	}
+	if !fresh {
+		return "", observability.WebhookReplay, nil
+	}
+	return integration, "", nil
+}
+
+// matchToken returns the active token credential of token, of integration when it is
+// not empty.
+func matchToken(creds []adb.WebhookCredential, integration, token string, now time.Time) *adb.WebhookCredential {
+	hash := credential.HashToken(token)
+	for i := range creds {
+		c := &creds[i]
+		if c.Kind == adb.CredentialToken && (integration == "" || c.Integration == integration) &&
+			c.Active(now) && equal(c.Secret, hash) {
+			return c
+		}
+	}
+	return nil
+}
+
+// credentials returns the credentials active when they were last read, reading them
+// again after CacheTTL.
+func (a *Authenticator) credentials(ctx context.Context, now time.Time) ([]adb.WebhookCredential, error) {
+	if a.Credentials == nil {
+		return nil, nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.creds != nil && now.Sub(a.loadedAt) < a.CacheTTL {
+		return a.creds, nil
+	}
+	creds, err := a.Credentials.ListWebhookCredentials(ctx, "", now)
+	if err != nil {
+		return nil, err
+	}
+	if creds == nil {
+		creds = []adb.WebhookCredential{}
+	}
+	a.creds, a.loadedAt = creds, now
+	return creds, nil
+}
+
+// remember reports whether key was not seen within ttl, and marks it seen.
+func (a *Authenticator) remember(ctx context.Context, key string, ttl time.Duration, now time.Time) (bool, error) {
+	if a.R != nil {
+		return a.R.SetNX(ctx, "alert:webhook:sig:"+key, 1, ttl).Result()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.seen == nil {
+		a.seen = make(map[string]time.Time)
+	}
+	if exp, ok := a.seen[key]; ok && now.Before(exp) {
+		return false, nil
+	}
+	if len(a.seen) >= maxSources {
+		for k, exp := range a.seen {
+			if !now.Before(exp) {
+				delete(a.seen, k)
+			}
+		}
+	}
+	a.seen[key] = now.Add(ttl)
+	return true, nil
+}
+
+// fail rejects a request whose credentials were not accepted. Failures count against the
+// remote address, so once it is rate limited they are no longer audited.
+func (a *Authenticator) fail(c *fox.Context, ip, reason string, now time.Time) {
+	ctx := c.Request.Context()
+	observability.Metrics().WebhookRejected(ctx, reason)
+	claimed := c.Request.Header.Get(HeaderIntegration)
+	if user, _, ok := c.Request.BasicAuth(); ok {
+		claimed = user
+	}
+	if len(claimed) > 64 {
+		claimed = claimed[:64]
+	}
+	log.Warn().Str("remote", ip).Str("integration", claimed).Str("reason", reason).Msg("webhook authentication failed")
+	if a.Limiter.Take("ip:"+ip, now) == 0 && a.Audit != nil {
+		if err := a.Audit.InsertAuditLog(ctx, &adb.AuditLog{
+			At:         now.UTC(),
+			Actor:      claimed,
+			Action:     ActionAuthFailed,
+			Target:     c.Request.URL.Path,
+			RemoteAddr: ip,
+			Detail:     reason,
+		}); err != nil {
+			log.Error().Err(err).Msg("failed to audit webhook authentication failure")
+		}
+	}
+	apierror.WriteError(c, errUnauthorized)
+}
+
+func (a *Authenticator) reject(c *fox.Context, reason string, wait time.Duration) {
+	observability.Metrics().WebhookRejected(c.Request.Context(), reason)
+	c.Writer.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	apierror.WriteError(c, errRateLimited)
+}
+
+// RemoteIP is the peer address; forwarding headers are not trusted, since they are set by
+// the caller.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
 	}
-	return true
+	return host
 }
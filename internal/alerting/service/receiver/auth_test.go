@@ -0,0 +1,171 @@
+package receiver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fox-gonic/fox"
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/database/memory"
+	"github.com/qiniu/zeroops/internal/alerting/service/credential"
+	"github.com/qiniu/zeroops/internal/config"
+)
+
+// authFixture serves the webhook with an Authenticator over an in-memory store whose
+// clock is now.
+type authFixture struct {
+	t      *testing.T
+	db     *memory.Store
+	creds  *credential.Service
+	auth   *Authenticator
+	router *fox.Engine
+	now    time.Time
+	body   []byte
+}
+
+func newAuthFixture(t *testing.T, cfg config.WebhookConfig) *authFixture {
+	f := &authFixture{t: t, db: memory.New(), now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	f.creds = credential.NewService(f.db)
+	f.creds.Now = func() time.Time { return f.now }
+	f.auth = NewAuthenticator(f.db, nil, &cfg)
+	f.auth.Now = func() time.Time { return f.now }
+	f.router = fox.New()
+	RegisterReceiverRoutes(f.router, NewHandler(&mockDAO{}).WithAuth(f.auth))
+	f.body, _ = json.Marshal(AMWebhook{Status: "firing", Alerts: []AMAlert{{Status: "firing", StartsAt: f.now}}})
+	return f
+}
+
+func (f *authFixture) create(integration, kind string) *credential.Credential {
+	f.t.Helper()
+	c, err := f.creds.Create(context.Background(), &credential.CreateRequest{Integration: integration, Kind: kind, CreatedBy: "alice"})
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return c
+}
+
+func (f *authFixture) post(body []byte, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/integrations/alertmanager/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp := httptest.NewRecorder()
+	f.router.ServeHTTP(resp, req)
+	return resp
+}
+
+func signed(integration, secret string, ts int64, body []byte) map[string]string {
+	return map[string]string{
+		HeaderIntegration: integration,
+		HeaderTimestamp:   strconv.FormatInt(ts, 10),
+		HeaderSignature:   credential.Sign(secret, ts, body),
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	f := newAuthFixture(t, config.WebhookConfig{MaxBodyBytes: 4096, ReplayWindowSeconds: 300})
+	if resp := f.post(f.body, nil); resp.Code != http.StatusOK {
+		t.Fatalf("open webhook = %d", resp.Code)
+	}
+	key := f.create("alertmanager", adb.CredentialHMAC)
+	token := f.create("grafana", adb.CredentialToken)
+	ts := f.now.Unix()
+
+	for _, tc := range []struct {
+		name   string
+		body   []byte
+		header map[string]string
+		status int
+	}{
+		{"no credentials", f.body, nil, http.StatusUnauthorized},
+		{"signed", f.body, signed("alertmanager", key.Secret, ts, f.body), http.StatusOK},
+		{"replayed", f.body, signed("alertmanager", key.Secret, ts, f.body), http.StatusUnauthorized},
+		{"signed again later", f.body, signed("alertmanager", key.Secret, ts+1, f.body), http.StatusOK},
+		{"stale", f.body, signed("alertmanager", key.Secret, ts-301, f.body), http.StatusUnauthorized},
+		{"other body", []byte(`{"status":"resolved","alerts":[]}`), signed("alertmanager", key.Secret, ts+2, f.body), http.StatusUnauthorized},
+		{"other integration", f.body, signed("grafana", key.Secret, ts+3, f.body), http.StatusUnauthorized},
+		{"bearer token", f.body, map[string]string{"Authorization": "Bearer " + token.Secret}, http.StatusOK},
+		{"basic token", f.body, map[string]string{"Authorization": basic("grafana", token.Secret)}, http.StatusOK},
+		{"basic token of other integration", f.body, map[string]string{"Authorization": basic("alertmanager", token.Secret)}, http.StatusUnauthorized},
+		{"hmac key as token", f.body, map[string]string{"Authorization": "Bearer " + key.Secret}, http.StatusUnauthorized},
+		{"too large", bytes.Repeat([]byte(" "), 4097), map[string]string{"Authorization": "Bearer " + token.Secret}, http.StatusRequestEntityTooLarge},
+	} {
+		if resp := f.post(tc.body, tc.header); resp.Code != tc.status {
+			t.Errorf("%s: status = %d, want %d; %s", tc.name, resp.Code, tc.status, resp.Body.String())
+		}
+	}
+
+	// the rotated key works until the overlap ends, the new one at once
+	next, err := f.creds.Rotate(context.Background(), key.ID, &credential.RotateRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := f.post(f.body, signed("alertmanager", key.Secret, ts+10, f.body)); resp.Code != http.StatusOK {
+		t.Errorf("old key during overlap = %d", resp.Code)
+	}
+	if resp := f.post(f.body, signed("alertmanager", next.Secret, ts+10, f.body)); resp.Code != http.StatusOK {
+		t.Errorf("new key = %d", resp.Code)
+	}
+	f.now = f.now.Add(credential.DefaultOverlap)
+	ts = f.now.Unix()
+	if resp := f.post(f.body, signed("alertmanager", key.Secret, ts, f.body)); resp.Code != http.StatusUnauthorized {
+		t.Errorf("old key after overlap = %d", resp.Code)
+	}
+	if err := f.creds.Revoke(context.Background(), token.ID, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if resp := f.post(f.body, map[string]string{"Authorization": "Bearer " + token.Secret}); resp.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token = %d", resp.Code)
+	}
+
+	failed, _ := f.db.ListAuditLogs(context.Background(), adb.AuditFilter{Action: ActionAuthFailed}, 100)
+	if len(failed) != 9 || failed[0].Detail != "bad_credentials" || failed[0].RemoteAddr != "192.0.2.1" {
+		t.Fatalf("audited failures = %+v", failed)
+	}
+	revoked, _ := f.db.ListAuditLogs(context.Background(), adb.AuditFilter{Action: credential.ActionRevoke}, 100)
+	if len(revoked) != 1 || revoked[0].Target != token.ID || revoked[0].Actor != "alice" {
+		t.Fatalf("audited revocations = %+v", revoked)
+	}
+}
+
+func TestAuthenticatorRateLimit(t *testing.T) {
+	f := newAuthFixture(t, config.WebhookConfig{MaxBodyBytes: 4096, ReplayWindowSeconds: 300, RateLimit: 1, RateBurst: 2})
+	token := f.create("grafana", adb.CredentialToken)
+	ok := map[string]string{"Authorization": "Bearer " + token.Secret}
+	bad := map[string]string{"Authorization": "Bearer wrong"}
+
+	// failures are limited per remote address, and stop being audited once limited
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if resp := f.post(f.body, bad); resp.Code != want {
+			t.Fatalf("failure %d = %d, want %d", i, resp.Code, want)
+		}
+	}
+	resp := f.post(f.body, ok)
+	if resp.Code != http.StatusTooManyRequests || resp.Header().Get("Retry-After") != "1" {
+		t.Fatalf("valid token from limited address = %d, Retry-After %q", resp.Code, resp.Header().Get("Retry-After"))
+	}
+	if failed, _ := f.db.ListAuditLogs(context.Background(), adb.AuditFilter{Action: ActionAuthFailed}, 100); len(failed) != 2 {
+		t.Fatalf("audited failures = %d", len(failed))
+	}
+
+	// requests are limited per integration once the address recovered
+	f.now = f.now.Add(time.Second)
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if resp := f.post(f.body, ok); resp.Code != want {
+			t.Fatalf("request %d = %d, want %d", i, resp.Code, want)
+		}
+	}
+}
+
+func basic(user, pass string) string {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth(user, pass)
+	return req.Header.Get("Authorization")
+}
@@ -8,22 +8,22 @@ import (
 	"strings"
 	"time"
 
+	"github.com/qiniu/zeroops/internal/alerting/service/feed"
+	"github.com/qiniu/zeroops/internal/region"
 	"github.com/redis/go-redis/v9"
 )
 
 // AlertIssueCache defines the minimal cache contract used by the handler.
 type AlertIssueCache interface {
 	WriteIssue(ctx context.Context, r *AlertIssueRow, a AMAlert) error
-	TryMarkIdempotent(ctx context.Context, a AMAlert) (bool, error)
-	WriteServiceState(ctx context.Context, service, version string, reportAt time.Time, healthState string) error
+	WriteServiceState(ctx context.Context, service, version, region string, reportAt time.Time, healthState string) error
 }
 
 // NoopCache is a no-op implementation of AlertIssueCache.
 type NoopCache struct{}
 
 func (NoopCache) WriteIssue(ctx context.Context, r *AlertIssueRow, a AMAlert) error { return nil }
-func (NoopCache) TryMarkIdempotent(ctx context.Context, a AMAlert) (bool, error)    { return true, nil }
-func (NoopCache) WriteServiceState(ctx context.Context, service, version string, reportAt time.Time, healthState string) error {
+func (NoopCache) WriteServiceState(ctx context.Context, service, version, region string, reportAt time.Time, healthState string) error {
 	return nil
 }
 
@@ -46,8 +46,8 @@ func NewCacheFromEnv() *Cache {
 	return &Cache{R: c}
 }
 
-// WriteIssue writes the alert issue into Redis as a JSON blob and updates a few indices.
-// Best-effort: failure should not block the main flow.
+// WriteIssue writes the alert issue into Redis as a JSON blob, updates a few indices and
+// publishes it on the issue feed. Best-effort: failure should not block the main flow.
 func (c *Cache) WriteIssue(ctx context.Context, r *AlertIssueRow, a AMAlert) error {
 	if c == nil || c.R == nil {
 		return nil
@@ -64,6 +64,10 @@ func (c *Cache) WriteIssue(ctx context.Context, r *AlertIssueRow, a AMAlert) err
 		"fingerprint": a.Fingerprint,
 		"service":     a.Labels["service"],
 		"alertname":   a.Labels["alertname"],
+		"region":      r.Region,
+	}
+	if len(r.SLOBudget) > 0 {
+		payload["sloBudget"] = r.SLOBudget
 	}
 	b, _ := json.Marshal(payload)
 	svc := strings.TrimSpace(a.Labels["service"])
@@ -73,36 +77,28 @@ func (c *Cache) WriteIssue(ctx context.Context, r *AlertIssueRow, a AMAlert) err
 	if svc != "" {
 		pipe.SAdd(ctx, "alert:index:svc:"+svc+":open", r.ID)
 	}
+	if r.Region != "" {
+		pipe.SAdd(ctx, "alert:index:region:"+r.Region+":open", r.ID)
+	}
+	feed.Publish(ctx, pipe, feed.EventCreated, b)
 	_, err := pipe.Exec(ctx)
 	return err
 }
 
-// TryMarkIdempotent marks an alert event as processed using Redis SETNX + TTL.
-// Returns false if the key already exists (duplicate).
-func (c *Cache) TryMarkIdempotent(ctx context.Context, a AMAlert) (bool, error) {
-	if c == nil || c.R == nil {
-		return true, nil
-	}
-	k := "alert:idemp:" + a.Fingerprint + "|" + a.StartsAt.UTC().Format(time.RFC3339Nano)
-	ok, err := c.R.SetNX(ctx, k, "1", 10*time.Minute).Result()
-	if err != nil {
-		// Best-effort: treat Redis errors as non-blocking and allow processing
-		return true, nil
-	}
-	return ok, nil
-}
-
 // WriteServiceState writes the service state snapshot into Redis and maintains simple indices.
-func (c *Cache) WriteServiceState(ctx context.Context, service, version string, reportAt time.Time, healthState string) error {
+// The key is service_state:{service}:{version}:{region}.
+func (c *Cache) WriteServiceState(ctx context.Context, service, version, regionName string, reportAt time.Time, healthState string) error {
 	if c == nil || c.R == nil {
 		return nil
 	}
 	s := strings.TrimSpace(service)
 	v := strings.TrimSpace(version)
-	key := "service_state:" + s + ":" + v
+	r := region.Normalize(regionName)
+	key := "service_state:" + s + ":" + v + ":" + r
 	payload := map[string]any{
 		"service":      s,
 		"version":      v,
+		"region":       r,
 		"report_at":    reportAt,
 		"health_state": healthState,
 	}
@@ -2,69 +2,116 @@ package receiver
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/silence"
+	"github.com/qiniu/zeroops/internal/observability"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// AlertIssueDAO persists the issues of a webhook. A DAO that also implements
+// pg.Transactor has the issues and service states of a webhook written in one transaction.
 type AlertIssueDAO interface {
-	InsertAlertIssue(ctx context.Context, r *AlertIssueRow) error
+	// InsertAlertIssues writes rows in one statement. A row whose fingerprint and startsAt
+	// are already stored is skipped; inserted[i] reports whether rows[i] was written.
+	InsertAlertIssues(ctx context.Context, rows []*AlertIssueRow) (inserted []bool, err error)
 }
 
 // ServiceStateWriter optionally allows writing to service_states table.
 type ServiceStateWriter interface {
-	UpsertServiceState(ctx context.Context, service, version string, reportAt *time.Time, healthState string, issueID string) error
+	UpsertServiceState(ctx context.Context, service, version, region string, reportAt *time.Time, healthState string, issueID string) error
+}
+
+// Silencer optionally reports whether an alert is muted by an active silence.
+type Silencer interface {
+	// Silenced returns the ID of a silence matching labels, or "".
+	Silenced(ctx context.Context, labels map[string]string) (string, error)
 }
 
 type NoopDAO struct{}
 
 func NewNoopDAO() *NoopDAO { return &NoopDAO{} }
 
-func (d *NoopDAO) InsertAlertIssue(ctx context.Context, r *AlertIssueRow) error { return nil }
+func (d *NoopDAO) InsertAlertIssues(ctx context.Context, rows []*AlertIssueRow) ([]bool, error) {
+	inserted := make([]bool, len(rows))
+	for i := range inserted {
+		inserted[i] = true
+	}
+	return inserted, nil
+}
 
-func (d *NoopDAO) UpsertServiceState(ctx context.Context, service, version string, reportAt *time.Time, healthState string, issueID string) error {
+func (d *NoopDAO) UpsertServiceState(ctx context.Context, service, version, region string, reportAt *time.Time, healthState string, issueID string) error {
 	return nil
 }
 
-type PgDAO struct{ DB *adb.Database }
+// PgDAO writes issues and service states through the alerting store. Despite the name it
+// accepts any adb.Store, including the in-memory one used in tests.
+type PgDAO struct{ DB adb.Store }
 
-func NewPgDAO(db *adb.Database) *PgDAO { return &PgDAO{DB: db} }
+func NewPgDAO(db adb.Store) *PgDAO { return &PgDAO{DB: db} }
+
+func (d *PgDAO) InsertAlertIssues(ctx context.Context, rows []*AlertIssueRow) ([]bool, error) {
+	ctx, span := observability.Tracer().Start(ctx, "alert_issues.insert",
+		trace.WithAttributes(attribute.Int("issues.count", len(rows))))
+	defer span.End()
+	issues := make([]*adb.Issue, len(rows))
+	for i, r := range rows {
+		issues[i] = &adb.Issue{
+			ID:          r.ID,
+			State:       r.State,
+			Level:       r.Level,
+			AlertState:  r.AlertState,
+			Title:       r.Title,
+			Labels:      r.LabelJSON,
+			AlertSince:  r.AlertSince,
+			Region:      r.Region,
+			TraceParent: r.TraceParent,
+			Fingerprint: r.Fingerprint,
+			StartsAt:    r.StartsAt,
+			SLOBudget:   r.SLOBudget,
+		}
+	}
+	inserted, err := d.DB.InsertIssues(ctx, issues)
+	if err == nil {
+		err = d.recordCreated(ctx, issues, inserted)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "insert failed")
+	}
+	return inserted, err
+}
 
-func (d *PgDAO) InsertAlertIssue(ctx context.Context, r *AlertIssueRow) error {
-	const q = `
-	INSERT INTO alert_issues
-		(id, state, level, alert_state, title, labels, alert_since)
-	VALUES
-		($1, $2, $3, $4, $5, $6, $7)
-	`
-	if _, err := d.DB.ExecContext(ctx, q, r.ID, r.State, r.Level, r.AlertState, r.Title, r.LabelJSON, r.AlertSince); err != nil {
-		return fmt.Errorf("insert alert_issue: %w", err)
+// recordCreated starts the history of the inserted issues, used by postmortem reports.
+func (d *PgDAO) recordCreated(ctx context.Context, issues []*adb.Issue, inserted []bool) error {
+	now := time.Now().UTC()
+	for i, issue := range issues {
+		if !inserted[i] {
+			continue
+		}
+		e := &adb.IssueEvent{IssueID: issue.ID, At: now, Type: adb.EventCreated, Actor: "receiver", To: issue.AlertState}
+		if _, err := d.DB.AddIssueEvent(ctx, e); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// InTx runs fn in a transaction of the underlying store.
+func (d *PgDAO) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return d.DB.InTx(ctx, fn)
+}
+
+// Silenced checks the alert labels against the active silences in alert_silences.
+func (d *PgDAO) Silenced(ctx context.Context, labels map[string]string) (string, error) {
+	return silence.NewService(d.DB).Match(ctx, labels)
+}
+
 // UpsertServiceState inserts or updates service_states with health_state and alert_issue_ids.
 // report_at is not updated here except at insert-time if provided (may be NULL).
-func (d *PgDAO) UpsertServiceState(ctx context.Context, service, version string, reportAt *time.Time, healthState string, issueID string) error {
-	const q = `
-	INSERT INTO service_states (service, version, report_at, health_state, alert_issue_ids)
-	VALUES ($1, $2, $3, $4, ARRAY[$5]::text[])
-	ON CONFLICT (service, version) DO UPDATE
-	SET health_state = EXCLUDED.health_state,
-		alert_issue_ids = CASE
-			WHEN NOT ($5 = ANY(service_states.alert_issue_ids)) THEN array_append(service_states.alert_issue_ids, $5)
-			ELSE service_states.alert_issue_ids
-		END
-	`
-	var reportAtVal any
-	if reportAt != nil {
-		reportAtVal = *reportAt
-	} else {
-		reportAtVal = nil
-	}
-	if _, err := d.DB.ExecContext(ctx, q, service, version, reportAtVal, healthState, issueID); err != nil {
-		return fmt.Errorf("upsert service_state: %w", err)
-	}
-	return nil
+func (d *PgDAO) UpsertServiceState(ctx context.Context, service, version, region string, reportAt *time.Time, healthState string, issueID string) error {
+	return d.DB.UpsertServiceState(ctx, service, version, region, reportAt, healthState, issueID)
 }
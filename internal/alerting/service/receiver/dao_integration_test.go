@@ -4,61 +4,71 @@ package receiver
 
 import (
 	"context"
-	"os"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/migrate"
+	"github.com/qiniu/zeroops/internal/pg"
 )
 
 func ensureSchema(t *testing.T, db *adb.Database) {
 	t.Helper()
-	const schema = `
-CREATE TABLE IF NOT EXISTS alert_issues (
-  id           varchar(64)  PRIMARY KEY,
-  state        varchar(16)  NOT NULL,
-  level        varchar(32)  NOT NULL,
-  alert_state  varchar(32)  NOT NULL,
-  title        varchar(255) NOT NULL,
-  labels       json          NOT NULL,
-  alert_since  timestamp(6)  NOT NULL
-);
-CREATE INDEX IF NOT EXISTS idx_alert_issues_state_level_since ON alert_issues(state, level, alert_since);
-CREATE INDEX IF NOT EXISTS idx_alert_issues_alertstate_since ON alert_issues(alert_state, alert_since);
-`
-	if _, err := db.ExecContext(context.Background(), schema); err != nil {
+	m, err := migrate.New(db.SQL())
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
 		t.Fatalf("init schema: %v", err)
 	}
 }
 
-func TestPgDAO_InsertAlertIssue(t *testing.T) {
-	dsn := "host=" + os.Getenv("DB_HOST") +
-		" port=" + os.Getenv("DB_PORT") +
-		" user=" + os.Getenv("DB_USER") +
-		" password=" + os.Getenv("DB_PASSWORD") +
-		" dbname=" + os.Getenv("DB_NAME") +
-		" sslmode=" + os.Getenv("DB_SSLMODE")
-
-	db, err := adb.New(dsn)
+func TestPgDAO_InsertAlertIssues(t *testing.T) {
+	cfg, err := config.LoadFile("")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	pool, err := pg.Open(&cfg.Database)
 	if err != nil {
 		t.Fatalf("db connect: %v", err)
 	}
-	defer db.Close()
+	defer pool.Close()
+	db := adb.New(pool)
 
 	ensureSchema(t, db)
 
 	dao := NewPgDAO(db)
-	row := &AlertIssueRow{
-		ID:         uuid.NewString(),
-		State:      "Open",
-		Level:      "P1",
-		AlertState: "Pending",
-		Title:      "integration insert",
-		LabelJSON:  []byte(`[{"key":"k","value":"v"}]`),
-		AlertSince: time.Now().UTC(),
+	now := time.Now().UTC()
+	newRow := func(fingerprint string) *AlertIssueRow {
+		return &AlertIssueRow{
+			ID:          uuid.NewString(),
+			State:       "Open",
+			Level:       "P1",
+			AlertState:  "Pending",
+			Title:       "integration insert",
+			LabelJSON:   []byte(`[{"key":"k","value":"v"}]`),
+			AlertSince:  now.Truncate(time.Second),
+			Fingerprint: fingerprint,
+			StartsAt:    now.Truncate(time.Microsecond),
+		}
 	}
-	if err := dao.InsertAlertIssue(context.Background(), row); err != nil {
+	fp := uuid.NewString()[:16]
+	inserted, err := dao.InsertAlertIssues(context.Background(), []*AlertIssueRow{newRow(fp), newRow(""), newRow("")})
+	if err != nil {
 		t.Fatalf("insert: %v", err)
 	}
+	if len(inserted) != 3 || !inserted[0] || !inserted[1] || !inserted[2] {
+		t.Fatalf("inserted = %v, want all", inserted)
+	}
+
+	// A redelivered alert is skipped by the unique (fingerprint, starts_at, alert_since) index.
+	inserted, err = dao.InsertAlertIssues(context.Background(), []*AlertIssueRow{newRow(fp)})
+	if err != nil {
+		t.Fatalf("insert duplicate: %v", err)
+	}
+	if inserted[0] {
+		t.Fatal("duplicate alert was inserted")
+	}
 }
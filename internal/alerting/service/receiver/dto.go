@@ -40,4 +40,34 @@ type AlertIssueRow struct {
 	Title      string
 	LabelJSON  json.RawMessage
 	AlertSince time.Time
+	// Region is derived from the alert labels, see region.FromLabels.
+	Region string
+	// TraceParent links the issue to the webhook trace that created it.
+	TraceParent string
+	// Fingerprint and StartsAt identify the alert; the store skips a row whose pair is
+	// already stored.
+	Fingerprint string
+	StartsAt    time.Time
+	// SLOBudget is the error budget snapshot of a burn-rate alert's SLO, nil otherwise.
+	SLOBudget json.RawMessage
+}
+
+// AlertResult is the outcome of one alert of a webhook.
+type AlertResult struct {
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	StartsAt    time.Time `json:"startsAt"`
+	// Status is created, deduped (already stored or repeated in the payload), silenced or
+	// dropped (by a relabel rule).
+	Status string `json:"status"`
+	// IssueID is the issue opened for a created alert.
+	IssueID string `json:"issueId,omitempty"`
+}
+
+// WebhookResponse is the success body of the Alertmanager webhook. Results follow the
+// order of the alerts in the payload.
+type WebhookResponse struct {
+	OK      bool          `json:"ok"`
+	Created int           `json:"created,omitempty"`
+	Msg     string        `json:"msg,omitempty"`
+	Results []AlertResult `json:"results,omitempty"`
 }
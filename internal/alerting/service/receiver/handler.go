@@ -1,16 +1,40 @@
 package receiver
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/qiniu/zeroops/internal/observability"
+	"github.com/qiniu/zeroops/internal/pg"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Handler struct {
-	dao   AlertIssueDAO
-	cache AlertIssueCache
+	dao      AlertIssueDAO
+	cache    AlertIssueCache
+	enricher Enricher
+	budgets  BudgetSource
+	auth     *Authenticator
+}
+
+// Enricher rewrites the labels of an alert before silences are matched and the issue is
+// mapped; see package enrich. keep is false when the alert is dropped.
+type Enricher interface {
+	Enrich(ctx context.Context, labels map[string]string) (out map[string]string, keep bool)
+}
+
+// BudgetSource snapshots the error budget of an SLO; see package slo.
+type BudgetSource interface {
+	// BudgetSnapshot returns nil when the SLO is unknown.
+	BudgetSnapshot(ctx context.Context, service, name string) (json.RawMessage, error)
 }
 
 // NewHandler keeps backward compatibility and uses a NoopCache by default.
@@ -24,63 +48,251 @@ func NewHandlerWithCache(dao AlertIssueDAO, cache AlertIssueCache) *Handler {
 	return &Handler{dao: dao, cache: cache}
 }
 
+// WithEnricher sets the enrichment stage applied to every alert and returns h.
+func (h *Handler) WithEnricher(e Enricher) *Handler {
+	h.enricher = e
+	return h
+}
+
+// WithBudgets attaches the error budget of the SLO named by the slo label to the issues of
+// burn-rate alerts, and returns h.
+func (h *Handler) WithBudgets(b BudgetSource) *Handler {
+	h.budgets = b
+	return h
+}
+
+// WithAuth sets the authenticator of the webhook and returns h. Without one only the
+// environment credentials and the default body size limit are checked. Credentials are
+// cached for a's CacheTTL, so a revoked one keeps working until the cache expires.
+func (h *Handler) WithAuth(a *Authenticator) *Handler {
+	h.auth = a
+	return h
+}
+
+// envAuth authenticates the webhook of handlers without an Authenticator.
+var envAuth = &Authenticator{MaxBodyBytes: 1 << 20, Now: time.Now}
+
 func (h *Handler) AlertmanagerWebhook(c *fox.Context) {
-	if !AuthMiddleware(c) {
+	auth := h.auth
+	if auth == nil {
+		auth = envAuth
+	}
+	if _, ok := auth.Authenticate(c); !ok {
 		return
 	}
 	var req AMWebhook
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]any{"ok": false, "error": "invalid JSON"})
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
-	if err := ValidateAMWebhook(&req); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]any{"ok": false, "error": err.Error()})
+	ctx := c.Request.Context()
+	observability.Metrics().AlertsReceived(ctx, "alertmanager", len(req.Alerts))
+	if strings.ToLower(req.Status) != "firing" {
+		observability.Metrics().AlertsProcessed(ctx, observability.AlertIgnored, len(req.Alerts))
+		c.JSON(http.StatusOK, map[string]any{"ok": true, "msg": "ignored (not firing)"})
 		return
 	}
 
-	if strings.ToLower(req.Status) != "firing" {
-		c.JSON(http.StatusOK, map[string]any{"ok": true, "msg": "ignored (not firing)"})
+	results, err := h.ingest(ctx, &req)
+	if err != nil {
+		// A 5xx makes Alertmanager retry the whole notification; nothing was stored.
+		apierror.Write(c, apierror.Wrap(apierror.Unavailable, err, "failed to persist alerts"))
 		return
 	}
+	resp := WebhookResponse{OK: true, Results: results}
+	for _, r := range results {
+		if r.Status == observability.AlertCreated {
+			resp.Created++
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
 
-	created := 0
-	for _, a := range req.Alerts {
-		key := BuildIdempotencyKey(a)
-		// Distributed idempotency (best-effort). If key exists, skip.
-		if ok, _ := h.cache.TryMarkIdempotent(c.Request.Context(), a); !ok {
-			continue
+// IngestAlert runs a single synthetic alert through the same path as the webhook
+// (enrichment, silences, deduplication, issue insert, service state, cache). It is used by
+// in-process alert sources such as the instance liveness reaper.
+func (h *Handler) IngestAlert(ctx context.Context, a AMAlert) (bool, error) {
+	req := AMWebhook{Receiver: "zeroops", Status: "firing", Alerts: []AMAlert{a}}
+	observability.Metrics().AlertsReceived(ctx, "internal", 1)
+	if err := ValidateAMWebhook(&req); err != nil {
+		observability.Metrics().AlertsProcessed(ctx, observability.AlertFailed, 1)
+		return false, err
+	}
+	results, err := h.ingest(ctx, &req)
+	if err != nil {
+		return false, err
+	}
+	return results[0].Status == observability.AlertCreated, nil
+}
+
+// ingest stores the alerts of req and returns one result per alert. Issues and service
+// states are written in one transaction, so on error nothing is stored and the whole
+// batch can be retried.
+func (h *Handler) ingest(ctx context.Context, req *AMWebhook) ([]AlertResult, error) {
+	ctx, span := observability.Tracer().Start(ctx, "receiver.ingest", trace.WithAttributes(
+		attribute.Int("alerts.count", len(req.Alerts)),
+		attribute.String("alert.group_key", req.GroupKey),
+	))
+	defer span.End()
+
+	results, err := h.ingestAlerts(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "ingest failed")
+		observability.Metrics().AlertsProcessed(ctx, observability.AlertFailed, len(req.Alerts))
+		return nil, err
+	}
+	outcomes := make(map[string]int, 4)
+	for _, r := range results {
+		outcomes[r.Status]++
+	}
+	for outcome, n := range outcomes {
+		observability.Metrics().AlertsProcessed(ctx, outcome, n)
+	}
+	span.SetAttributes(attribute.Int("alerts.created", outcomes[observability.AlertCreated]))
+	return results, nil
+}
+
+func (h *Handler) ingestAlerts(ctx context.Context, req *AMWebhook) ([]AlertResult, error) {
+	results := make([]AlertResult, len(req.Alerts))
+	rows := make([]*AlertIssueRow, 0, len(req.Alerts))
+	alerts := make([]int, 0, len(req.Alerts)) // index in req.Alerts of each row
+	seen := make(map[[2]string]bool, len(req.Alerts))
+	traceParent := observability.TraceParent(ctx)
+	for i := range req.Alerts {
+		a := &req.Alerts[i]
+		results[i] = AlertResult{Fingerprint: a.Fingerprint, StartsAt: a.StartsAt}
+		if h.enricher != nil {
+			labels, keep := h.enricher.Enrich(ctx, a.Labels)
+			if !keep {
+				results[i].Status = observability.AlertDropped
+				continue
+			}
+			a.Labels = labels
+		}
+		// Silences are checked before deduplication so the alert is taken if it is
+		// redelivered after the silence ends.
+		silenced, err := h.silenced(ctx, *a)
+		if err != nil {
+			return nil, err
 		}
-		if AlreadySeen(key) {
+		if silenced {
+			results[i].Status = observability.AlertSilenced
 			continue
 		}
-		row, err := MapToAlertIssueRow(&req, &a)
+		row, err := MapToAlertIssueRow(req, a)
 		if err != nil {
-			continue
+			return nil, err
 		}
-		if err := h.dao.InsertAlertIssue(c.Request.Context(), row); err != nil {
-			continue
+		if row.Fingerprint != "" {
+			key := [2]string{row.Fingerprint, row.StartsAt.Format(time.RFC3339Nano)}
+			if seen[key] {
+				results[i].Status = observability.AlertDeduped
+				continue
+			}
+			seen[key] = true
 		}
+		row.TraceParent = traceParent
+		row.SLOBudget = h.budgetSnapshot(ctx, a.Labels)
+		rows = append(rows, row)
+		alerts = append(alerts, i)
+	}
+	if len(rows) == 0 {
+		return results, nil
+	}
 
-		if w, ok := h.dao.(ServiceStateWriter); ok {
+	var inserted []bool
+	persist := func(ctx context.Context) error {
+		var err error
+		if inserted, err = h.dao.InsertAlertIssues(ctx, rows); err != nil {
+			return err
+		}
+		w, ok := h.dao.(ServiceStateWriter)
+		if !ok {
+			return nil
+		}
+		for j, row := range rows {
+			a := req.Alerts[alerts[j]]
 			service := strings.TrimSpace(a.Labels["service"])
+			if !inserted[j] || service == "" {
+				continue
+			}
 			version := strings.TrimSpace(a.Labels["service_version"]) // optional
-			if service != "" {
-				derived := "Warning"
-				if row.Level == "P0" {
-					derived = "Error"
-				} else if row.Level == "P1" || row.Level == "P2" {
-					derived = "Warning"
-				}
-				_ = w.UpsertServiceState(c.Request.Context(), service, version, nil, derived, row.ID)
-				_ = h.cache.WriteServiceState(c.Request.Context(), service, version, time.Time{}, derived)
+			if err := w.UpsertServiceState(ctx, service, version, row.Region, nil, derivedHealth(row.Level), row.ID); err != nil {
+				return err
 			}
 		}
-		// Write-through to cache. Errors are ignored to avoid impacting webhook ack.
-		_ = h.cache.WriteIssue(c.Request.Context(), row, a)
-		MarkSeen(key)
-		created++
+		return nil
+	}
+	var err error
+	if tx, ok := h.dao.(pg.Transactor); ok {
+		err = tx.InTx(ctx, persist)
+	} else {
+		err = persist(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Write-through to cache after commit. Errors are ignored to avoid impacting the
+	// webhook ack; the database is the source of truth.
+	for j, row := range rows {
+		r := &results[alerts[j]]
+		if !inserted[j] {
+			r.Status = observability.AlertDeduped
+			continue
+		}
+		r.Status = observability.AlertCreated
+		r.IssueID = row.ID
+		a := req.Alerts[alerts[j]]
+		if service := strings.TrimSpace(a.Labels["service"]); service != "" {
+			version := strings.TrimSpace(a.Labels["service_version"])
+			_ = h.cache.WriteServiceState(ctx, service, version, row.Region, time.Time{}, derivedHealth(row.Level))
+		}
+		_ = h.cache.WriteIssue(ctx, row, a)
 	}
+	return results, nil
+}
 
-	c.JSON(http.StatusOK, map[string]any{"ok": true, "created": created})
+// silenced reports whether an active silence matches the alert labels.
+func (h *Handler) silenced(ctx context.Context, a AMAlert) (bool, error) {
+	s, ok := h.dao.(Silencer)
+	if !ok {
+		return false, nil
+	}
+	id, err := s.Silenced(ctx, a.Labels)
+	if err != nil {
+		return false, err
+	}
+	if id != "" {
+		trace.SpanFromContext(ctx).AddEvent("silenced", trace.WithAttributes(
+			attribute.String("alert.fingerprint", a.Fingerprint),
+			attribute.String("silence.id", id),
+		))
+	}
+	return id != "", nil
+}
+
+// budgetSnapshot returns the budget of the SLO an alert is about, or nil. Errors are
+// logged and leave the snapshot out, so Prometheus outages never block ingestion.
+func (h *Handler) budgetSnapshot(ctx context.Context, labels map[string]string) json.RawMessage {
+	service, name := strings.TrimSpace(labels["service"]), strings.TrimSpace(labels["slo"])
+	if h.budgets == nil || service == "" || name == "" {
+		return nil
+	}
+	b, err := h.budgets.BudgetSnapshot(ctx, service, name)
+	if err != nil {
+		log.Warn().Err(err).Str("service", service).Str("slo", name).Msg("alert receiver: slo budget snapshot failed")
+		return nil
+	}
+	return b
+}
+
+// derivedHealth is the service health implied by an issue level.
+func derivedHealth(level string) string {
+	if level == "P0" {
+		return "Error"
+	}
+	return "Warning"
 }
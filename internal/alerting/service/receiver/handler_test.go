@@ -4,34 +4,211 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/alerting/database/memory"
+	"github.com/qiniu/zeroops/internal/alerting/service/silence"
 )
 
-type mockDAO struct{ calls int }
+type mockDAO struct {
+	calls int
+	err   error
+}
 
-func (m *mockDAO) InsertAlertIssue(_ context.Context, _ *AlertIssueRow) error { m.calls++; return nil }
+func (m *mockDAO) InsertAlertIssues(_ context.Context, rows []*AlertIssueRow) ([]bool, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	inserted := make([]bool, len(rows))
+	for i := range inserted {
+		inserted[i] = true
+	}
+	return inserted, nil
+}
 
-func TestHandlerCreatesIssues(t *testing.T) {
+func postWebhook(t *testing.T, h *Handler, payload AMWebhook) (*httptest.ResponseRecorder, WebhookResponse) {
+	t.Helper()
 	r := fox.New()
-	m := &mockDAO{}
-	h := NewHandler(m)
 	RegisterReceiverRoutes(r, h)
-
-	payload := AMWebhook{
-		Status: "firing",
-		Alerts: []AMAlert{{Status: "firing", StartsAt: time.Now()}},
-	}
 	b, _ := json.Marshal(payload)
 	req := httptest.NewRequest(http.MethodPost, "/v1/integrations/alertmanager/webhook", bytes.NewReader(b))
 	req.Header.Set("Content-Type", "application/json")
 	resp := httptest.NewRecorder()
 	r.ServeHTTP(resp, req)
+	var out WebhookResponse
+	if resp.Code == http.StatusOK {
+		if err := json.Unmarshal(resp.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+	}
+	return resp, out
+}
+
+func TestHandlerCreatesIssues(t *testing.T) {
+	m := &mockDAO{}
+	resp, out := postWebhook(t, NewHandler(m), AMWebhook{
+		Status: "firing",
+		Alerts: []AMAlert{{Status: "firing", StartsAt: time.Now()}},
+	})
 	if resp.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", resp.Code)
 	}
+	if m.calls != 1 || out.Created != 1 || len(out.Results) != 1 || out.Results[0].IssueID == "" {
+		t.Fatalf("calls=%d response=%+v", m.calls, out)
+	}
+}
+
+func TestHandlerDeduplicatesAlerts(t *testing.T) {
+	store := memory.New()
+	h := NewHandler(NewPgDAO(store))
+	startsAt := time.Now()
+	alert := AMAlert{Status: "firing", Fingerprint: "fp-1", StartsAt: startsAt,
+		Labels: KV{"alertname": "HighLatency", "service": "api", "severity": "P0"}}
+	other := AMAlert{Status: "firing", Fingerprint: "fp-2", StartsAt: startsAt,
+		Labels: KV{"alertname": "HighErrorRate", "service": "api", "severity": "P1"}}
+
+	// The second fp-1 repeats the first within the payload.
+	resp, out := postWebhook(t, h, AMWebhook{Status: "firing", Alerts: []AMAlert{alert, alert, other}})
+	if resp.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.Code, resp.Body)
+	}
+	want := []string{"created", "deduped", "created"}
+	for i, r := range out.Results {
+		if r.Status != want[i] {
+			t.Fatalf("results = %+v, want statuses %v", out.Results, want)
+		}
+	}
+	if out.Created != 2 {
+		t.Fatalf("created = %d, want 2", out.Created)
+	}
+	st, ok := store.ServiceState("api", "", "default")
+	if !ok || len(st.AlertIssueIDs) != 2 {
+		t.Fatalf("service state = %+v, %v", st, ok)
+	}
+
+	// Alertmanager redelivers the notification.
+	_, out = postWebhook(t, h, AMWebhook{Status: "firing", Alerts: []AMAlert{alert}})
+	if out.Created != 0 || out.Results[0].Status != "deduped" || out.Results[0].IssueID != "" {
+		t.Fatalf("redelivery: %+v", out)
+	}
+}
+
+func TestHandlerFailsWhenStoreFails(t *testing.T) {
+	resp, _ := postWebhook(t, NewHandler(&mockDAO{err: errors.New("connection refused")}), AMWebhook{
+		Status: "firing",
+		Alerts: []AMAlert{{Status: "firing", Fingerprint: "fp-1", StartsAt: time.Now()}},
+	})
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 so Alertmanager retries, got %d", resp.Code)
+	}
+}
+
+func TestHandlerSkipsSilencedAlerts(t *testing.T) {
+	store := memory.New()
+	ctx := context.Background()
+	if _, err := silence.NewService(store).Create(ctx, &silence.CreateRequest{
+		Matchers: []silence.Matcher{{Name: "service", Value: "api"}},
+		Duration: "1h",
+		Comment:  "planned maintenance",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(NewPgDAO(store))
+
+	startsAt := time.Now()
+	silenced := AMAlert{Status: "firing", Fingerprint: "fp-silenced", StartsAt: startsAt,
+		Labels: KV{"alertname": "HighLatency", "service": "api", "severity": "P1"}}
+	if created, err := h.IngestAlert(ctx, silenced); err != nil || created {
+		t.Fatalf("silenced alert: created=%v err=%v", created, err)
+	}
+	other := AMAlert{Status: "firing", Fingerprint: "fp-other", StartsAt: startsAt,
+		Labels: KV{"alertname": "HighLatency", "service": "storage", "severity": "P1"}}
+	if created, err := h.IngestAlert(ctx, other); err != nil || !created {
+		t.Fatalf("unsilenced alert: created=%v err=%v", created, err)
+	}
+}
+
+type failingStateDAO struct{ *PgDAO }
+
+func (failingStateDAO) UpsertServiceState(context.Context, string, string, string, *time.Time, string, string) error {
+	return errors.New("service_states unavailable")
+}
+
+func TestHandlerRollsBackIssuesOnStateFailure(t *testing.T) {
+	store := memory.New()
+	h := NewHandler(failingStateDAO{NewPgDAO(store)})
+	alert := AMAlert{Status: "firing", Fingerprint: "fp-1", StartsAt: time.Now(),
+		Labels: KV{"alertname": "HighLatency", "service": "api", "severity": "P1"}}
+	if created, err := h.IngestAlert(context.Background(), alert); err == nil || created {
+		t.Fatalf("created=%v err=%v, want error", created, err)
+	}
+	if pending, _ := store.ListPendingIssues(context.Background(), 10); len(pending) != 0 {
+		t.Fatalf("issues kept after rollback: %+v", pending)
+	}
+}
+
+type stubEnricher struct{}
+
+func (stubEnricher) Enrich(_ context.Context, labels map[string]string) (map[string]string, bool) {
+	if labels["env"] == "dev" {
+		return nil, false
+	}
+	out := map[string]string{"team": "storage-team"}
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out, true
+}
+
+func TestHandlerEnrichesAlerts(t *testing.T) {
+	store := memory.New()
+	h := NewHandler(NewPgDAO(store)).WithEnricher(stubEnricher{})
+	startsAt := time.Now()
+	_, out := postWebhook(t, h, AMWebhook{Status: "firing", Alerts: []AMAlert{
+		{Status: "firing", Fingerprint: "fp-dev", StartsAt: startsAt, Labels: KV{"alertname": "DiskFull", "env": "dev"}},
+		{Status: "firing", Fingerprint: "fp-prod", StartsAt: startsAt, Labels: KV{"alertname": "DiskFull", "env": "prod"}},
+	}})
+	if len(out.Results) != 2 || out.Results[0].Status != "dropped" || out.Results[1].Status != "created" {
+		t.Fatalf("results = %+v", out.Results)
+	}
+	issue, ok := store.Issue(out.Results[1].IssueID)
+	if !ok || !bytes.Contains(issue.Labels, []byte(`{"key":"team","value":"storage-team"}`)) {
+		t.Fatalf("issue labels = %s", issue.Labels)
+	}
+}
+
+type stubBudgets struct{}
+
+func (stubBudgets) BudgetSnapshot(_ context.Context, service, name string) (json.RawMessage, error) {
+	if service == "api" && name == "availability" {
+		return json.RawMessage(`{"remaining":0.25}`), nil
+	}
+	return nil, errors.New("unknown slo")
+}
+
+func TestHandlerAttachesSLOBudget(t *testing.T) {
+	store := memory.New()
+	h := NewHandler(NewPgDAO(store)).WithBudgets(stubBudgets{})
+	startsAt := time.Now()
+	_, out := postWebhook(t, h, AMWebhook{Status: "firing", Alerts: []AMAlert{
+		{Status: "firing", Fingerprint: "fp-burn", StartsAt: startsAt, Labels: KV{"alertname": "SLOErrorBudgetBurn", "service": "api", "slo": "availability"}},
+		{Status: "firing", Fingerprint: "fp-gone", StartsAt: startsAt, Labels: KV{"alertname": "SLOErrorBudgetBurn", "service": "api", "slo": "deleted"}},
+		{Status: "firing", Fingerprint: "fp-disk", StartsAt: startsAt, Labels: KV{"alertname": "DiskFull", "service": "api"}},
+	}})
+	if len(out.Results) != 3 {
+		t.Fatalf("results = %+v", out.Results)
+	}
+	want := []string{`{"remaining":0.25}`, "", ""}
+	for i, r := range out.Results {
+		issue, ok := store.Issue(r.IssueID)
+		if !ok || string(issue.SLOBudget) != want[i] {
+			t.Fatalf("alert %d: issue %+v, budget %s, want %s", i, r, issue.SLOBudget, want[i])
+		}
+	}
 }
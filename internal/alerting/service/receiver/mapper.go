@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/qiniu/zeroops/internal/region"
 )
 
 func MapToAlertIssueRow(w *AMWebhook, a *AMAlert) (*AlertIssueRow, error) {
@@ -40,12 +41,15 @@ func MapToAlertIssueRow(w *AMWebhook, a *AMAlert) (*AlertIssueRow, error) {
 	b, _ := json.Marshal(flat)
 
 	return &AlertIssueRow{
-		ID:         uuid.NewString(),
-		State:      "Open",
-		AlertState: "Pending",
-		Level:      level,
-		Title:      title,
-		LabelJSON:  b,
-		AlertSince: a.StartsAt.UTC().Truncate(time.Second),
+		ID:          uuid.NewString(),
+		State:       "Open",
+		AlertState:  "Pending",
+		Level:       level,
+		Title:       title,
+		LabelJSON:   b,
+		AlertSince:  a.StartsAt.UTC().Truncate(time.Second),
+		Region:      region.FromLabels(a.Labels),
+		Fingerprint: a.Fingerprint,
+		StartsAt:    a.StartsAt.UTC().Truncate(time.Microsecond), // alert_issues.starts_at precision
 	}, nil
 }
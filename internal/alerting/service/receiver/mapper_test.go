@@ -22,6 +22,9 @@ func TestMapToAlertIssueRow(t *testing.T) {
 	if row.State != "Open" || row.AlertState != "Pending" {
 		t.Fatal("unexpected state mapping")
 	}
+	if row.Region != "idc" {
+		t.Fatalf("expected region from idc label, got %q", row.Region)
+	}
 	var flat []map[string]string
 	if err := json.Unmarshal(row.LabelJSON, &flat); err != nil {
 		t.Fatalf("invalid label json: %v", err)
@@ -0,0 +1,80 @@
+package receiver
+
+import (
+	"sync"
+	"time"
+)
+
+// maxSources bounds the rate limit buckets and remembered signatures kept in process;
+// past it the idle ones are dropped.
+const maxSources = 10000
+
+// Limiter is a token bucket per source refilled with Rate tokens per second up to Burst.
+// A nil Limiter allows everything.
+type Limiter struct {
+	Rate  float64
+	Burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// NewLimiter returns nil when rate is not positive.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &Limiter{Rate: rate, Burst: max(float64(burst), 1), buckets: make(map[string]*bucket)}
+}
+
+// Take spends a token of key and returns 0, or returns how long until a token is
+// available without spending one.
+func (l *Limiter) Take(key string, now time.Time) time.Duration {
+	return l.acquire(key, now, true)
+}
+
+// Wait returns how long until key has a token, 0 when it has one now.
+func (l *Limiter) Wait(key string, now time.Time) time.Duration {
+	return l.acquire(key, now, false)
+}
+
+func (l *Limiter) acquire(key string, now time.Time, spend bool) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxSources {
+			l.prune(now)
+		}
+		b = &bucket{tokens: l.Burst, at: now}
+		l.buckets[key] = b
+	}
+	if now.After(b.at) {
+		b.tokens = min(l.Burst, b.tokens+now.Sub(b.at).Seconds()*l.Rate)
+		b.at = now
+	}
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.Rate * float64(time.Second))
+	}
+	if spend {
+		b.tokens--
+	}
+	return 0
+}
+
+// prune drops the buckets that have refilled completely, which behave like new ones.
+func (l *Limiter) prune(now time.Time) {
+	for k, b := range l.buckets {
+		if b.tokens+now.Sub(b.at).Seconds()*l.Rate >= l.Burst {
+			delete(l.buckets, k)
+		}
+	}
+}
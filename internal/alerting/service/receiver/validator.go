@@ -6,6 +6,12 @@ import (
 	"strings"
 )
 
+// maxFingerprintLen matches alert_issues.fingerprint.
+const maxFingerprintLen = 64
+
+// Validate lets apierror.BindJSON check the payload right after decoding.
+func (w *AMWebhook) Validate() error { return ValidateAMWebhook(w) }
+
 func ValidateAMWebhook(w *AMWebhook) error {
 	if w == nil {
 		return ErrInvalidPayload
@@ -18,6 +24,9 @@ func ValidateAMWebhook(w *AMWebhook) error {
 		if a.StartsAt.IsZero() {
 			return fmt.Errorf("alerts[%d].startsAt empty", i)
 		}
+		if len(a.Fingerprint) > maxFingerprintLen {
+			return fmt.Errorf("alerts[%d].fingerprint longer than %d characters", i, maxFingerprintLen)
+		}
 		if a.Status == "" {
 			a.Status = "firing"
 		}
@@ -2,27 +2,49 @@ package remediation
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"time"
 
 	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/analysis"
+	"github.com/qiniu/zeroops/internal/alerting/service/feed"
 	"github.com/qiniu/zeroops/internal/alerting/service/healthcheck"
+	"github.com/qiniu/zeroops/internal/observability"
+	"github.com/qiniu/zeroops/internal/region"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Consumer struct {
-	DB    *adb.Database
+	DB    adb.Store // nil skips DB writes
 	Redis *redis.Client
+	// Analyzer writes the AI analysis comment when an issue enters InProcessing; nil skips it
+	Analyzer        analysis.Analyzer
+	AnalysisTimeout time.Duration // zero leaves the analysis unbounded
 
-	// sleepFn allows overriding for tests
-	sleepFn func(time.Duration)
+	// sleepFn allows overriding for tests; it returns early with ctx.Err() on shutdown
+	sleepFn func(ctx context.Context, d time.Duration) error
 }
 
-func NewConsumer(db *adb.Database, rdb *redis.Client) *Consumer {
-	return &Consumer{DB: db, Redis: rdb, sleepFn: time.Sleep}
+func NewConsumer(db adb.Store, rdb *redis.Client) *Consumer {
+	return &Consumer{DB: db, Redis: rdb, sleepFn: sleepCtx}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
 }
 
 // Start consumes alert messages and performs a mocked rollback then marks restored.
@@ -37,26 +59,71 @@ func (c *Consumer) Start(ctx context.Context, ch <-chan healthcheck.AlertMessage
 		case <-ctx.Done():
 			return
 		case m := <-ch:
-			// 1) Mock rollback: optional URL composition (unused)
-			_ = fmt.Sprintf(os.Getenv("REMEDIATION_ROLLBACK_URL"), deriveDeployID(&m))
-			// 2) Sleep to simulate rollback time
-			if c.sleepFn != nil {
-				c.sleepFn(sleepDur)
-			}
-			// 3) On success: add AI analysis comment, update DB and cache
-			if err := c.addAIAnalysisComment(ctx, &m); err != nil {
-				log.Error().Err(err).Str("issue", m.ID).Msg("addAIAnalysisComment failed")
-			}
-			if err := c.markRestoredInDB(ctx, &m); err != nil {
-				log.Error().Err(err).Str("issue", m.ID).Msg("markRestoredInDB failed")
-			}
-			if err := c.markRestoredInCache(ctx, &m); err != nil {
-				log.Error().Err(err).Str("issue", m.ID).Msg("markRestoredInCache failed")
+			if !c.handle(ctx, &m, sleepDur) {
+				return
 			}
 		}
 	}
 }
 
+// handle remediates one alert and reports false when shutdown interrupted it. The span
+// continues the scheduler's dispatch span carried in the message.
+func (c *Consumer) handle(ctx context.Context, m *healthcheck.AlertMessage, sleepDur time.Duration) bool {
+	ctx, span := observability.Tracer().Start(observability.ContextWithTraceParent(ctx, m.TraceParent),
+		"remediation.restore", trace.WithAttributes(attribute.String("issue.id", m.ID), attribute.String("service", m.Service)))
+	defer span.End()
+
+	c.recordEvent(ctx, &adb.IssueEvent{IssueID: m.ID, Type: adb.EventState, From: "Pending", To: "InProcessing"})
+
+	// 0) Analyse the issue as it enters InProcessing, before remediation changes its metrics.
+	if err := c.addAIAnalysisComment(ctx, m); err != nil {
+		log.Error().Err(err).Str("issue", m.ID).Msg("addAIAnalysisComment failed")
+		span.RecordError(err)
+	}
+
+	// 1) Mock rollback: optional URL composition (unused)
+	deployID := deriveDeployID(m)
+	_ = fmt.Sprintf(os.Getenv("REMEDIATION_ROLLBACK_URL"), deployID)
+	c.recordEvent(ctx, &adb.IssueEvent{IssueID: m.ID, Type: adb.EventRemediation, Detail: "rollback " + deployID})
+	// 2) Sleep to simulate rollback time. Shutdown abandons the message here: the
+	// issue is still Pending in the DB and is picked up again after restart.
+	if c.sleepFn != nil {
+		if err := c.sleepFn(ctx, sleepDur); err != nil {
+			log.Warn().Str("issue", m.ID).Msg("remediation interrupted by shutdown")
+			observability.Metrics().RemediationAction(ctx, observability.RemediationInterrupted)
+			span.SetAttributes(attribute.String("outcome", observability.RemediationInterrupted))
+			return false
+		}
+	}
+	// 3) On success: update DB and cache. These writes run to completion so
+	// shutdown never leaves DB and cache half updated.
+	wctx := context.WithoutCancel(ctx)
+	outcome := observability.RemediationRestored
+	if err := c.markRestoredInDB(wctx, m); err != nil {
+		log.Error().Err(err).Str("issue", m.ID).Msg("markRestoredInDB failed")
+		outcome = observability.RemediationFailed
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "markRestoredInDB failed")
+	}
+	if err := c.markRestoredInCache(wctx, m); err != nil {
+		log.Error().Err(err).Str("issue", m.ID).Msg("markRestoredInCache failed")
+	}
+	observability.Metrics().RemediationAction(wctx, outcome)
+	span.SetAttributes(attribute.String("outcome", outcome))
+	return true
+}
+
+// recordEvent adds a remediation event to the issue history; failures are only logged.
+func (c *Consumer) recordEvent(ctx context.Context, e *adb.IssueEvent) {
+	if c.DB == nil {
+		return
+	}
+	e.At, e.Actor = time.Now().UTC(), adb.ActorRemediation
+	if _, err := c.DB.AddIssueEvent(context.WithoutCancel(ctx), e); err != nil {
+		log.Error().Err(err).Str("issue", e.IssueID).Str("event", e.Type).Msg("failed to record issue event")
+	}
+}
+
 func deriveDeployID(m *healthcheck.AlertMessage) string {
 	if m == nil {
 		return ""
@@ -67,27 +134,47 @@ func deriveDeployID(m *healthcheck.AlertMessage) string {
 	return fmt.Sprintf("%s:%s", m.Service, m.Version)
 }
 
+// addAIAnalysisComment stores the analysis of the issue as a comment. An issue that
+// already has one, e.g. when the message is redelivered after a restart, is not analysed
+// again.
 func (c *Consumer) addAIAnalysisComment(ctx context.Context, m *healthcheck.AlertMessage) error {
-	if c.DB == nil || m == nil {
+	if c.DB == nil || c.Analyzer == nil || m == nil {
 		return nil
 	}
-	const existsQ = `SELECT 1 FROM alert_issue_comments WHERE issue_id=$1 AND content=$2 LIMIT 1`
-	const insertQ = `INSERT INTO alert_issue_comments (issue_id, create_at, content) VALUES ($1, NOW(), $2)`
-	content := "## AI分析结果\n" +
-		"**问题类型**：非发版本导致的问题\n" +
-		"**根因分析**：数据库连接池配置不足，导致大量请求无法获取数据库连接\n" +
-		"**处理建议**：\n" +
-		"- 增加数据库连接池大小\n" +
-		"- 优化数据库连接管理\n" +
-		"- 考虑读写分离缓解压力\n" +
-		"**执行状态**：正在处理中，等待指标恢复正常"
-	if rows, err := c.DB.QueryContext(ctx, existsQ, m.ID, content); err == nil {
-		defer rows.Close()
-		if rows.Next() {
+	comments, err := c.DB.ListComments(ctx, m.ID)
+	if err != nil {
+		return err
+	}
+	for _, cm := range comments {
+		if cm.Analysis != nil {
 			return nil
 		}
 	}
-	_, err := c.DB.ExecContext(ctx, insertQ, m.ID, content)
+
+	actx := ctx
+	if c.AnalysisTimeout > 0 {
+		var cancel context.CancelFunc
+		actx, cancel = context.WithTimeout(ctx, c.AnalysisTimeout)
+		defer cancel()
+	}
+	res, err := c.Analyzer.Analyze(actx, &analysis.Input{
+		IssueID:    m.ID,
+		Service:    m.Service,
+		Version:    m.Version,
+		Region:     m.Region,
+		Level:      m.Level,
+		Title:      m.Title,
+		AlertSince: m.AlertSince,
+		Labels:     m.Labels,
+	})
+	if err != nil {
+		return fmt.Errorf("analyze: %w", err)
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	_, err = c.DB.AddAnalysisComment(context.WithoutCancel(ctx), m.ID, res.Markdown(), data)
 	return err
 }
 
@@ -95,24 +182,20 @@ func (c *Consumer) markRestoredInDB(ctx context.Context, m *healthcheck.AlertMes
 	if c.DB == nil || m == nil {
 		return nil
 	}
-	// alert_issues
-	if _, err := c.DB.ExecContext(ctx, `UPDATE alert_issues SET alert_state = 'Restored' , state = 'Closed' WHERE id = $1`, m.ID); err != nil {
-		return err
-	}
-	// service_states (upsert)
-	if m.Service != "" {
-		const upsert = `
-INSERT INTO service_states (service, version, report_at, resolved_at, health_state, alert_issue_ids)
-VALUES ($1, $2, NULL, NOW(), 'Normal', ARRAY[$3]::text[])
-ON CONFLICT (service, version) DO UPDATE
-SET health_state = 'Normal',
-    resolved_at = NOW();
-`
-		if _, err := c.DB.ExecContext(ctx, upsert, m.Service, m.Version, m.ID); err != nil {
+	// alert_issues, its history and service_states change together
+	return c.DB.InTx(ctx, func(ctx context.Context) error {
+		if err := c.DB.UpdateIssueState(ctx, m.ID, "Closed", "Restored"); err != nil {
 			return err
 		}
-	}
-	return nil
+		e := &adb.IssueEvent{IssueID: m.ID, At: time.Now().UTC(), Type: adb.EventState, Actor: adb.ActorRemediation, From: "InProcessing", To: "Restored"}
+		if _, err := c.DB.AddIssueEvent(ctx, e); err != nil {
+			return err
+		}
+		if m.Service != "" {
+			return c.DB.ResolveServiceState(ctx, m.Service, m.Version, m.Region, m.ID)
+		}
+		return nil
+	})
 }
 
 func (c *Consumer) markRestoredInCache(ctx context.Context, m *healthcheck.AlertMessage) error {
@@ -134,7 +217,7 @@ if KEYS[4] ~= '' then redis.call('SADD', KEYS[4], ARGV[2]) end
 -- move open→closed indices
 if KEYS[5] ~= '' then redis.call('SREM', KEYS[5], ARGV[2]) end
 if KEYS[6] ~= '' then redis.call('SADD', KEYS[6], ARGV[2]) end
--- service scoped indices if service exists in payload
+-- service and region scoped indices if they exist in payload
 local svc = obj['service']
 if svc and svc ~= '' then
   local openSvcKey = 'alert:index:svc:' .. svc .. ':open'
@@ -142,13 +225,19 @@ if svc and svc ~= '' then
   redis.call('SREM', openSvcKey, ARGV[2])
   redis.call('SADD', closedSvcKey, ARGV[2])
 end
+local region = obj['region']
+if region and region ~= '' then
+  redis.call('SREM', 'alert:index:region:' .. region .. ':open', ARGV[2])
+  redis.call('SADD', 'alert:index:region:' .. region .. ':closed', ARGV[2])
+end
+` + feed.Lua(feed.EventClosed) + `
 return 1
 `)
 	_, _ = script.Run(ctx, c.Redis, []string{alertKey, "alert:index:alert_state:Pending", "alert:index:alert_state:InProcessing", "alert:index:alert_state:Restored", "alert:index:open", "alert:index:closed"}, "Restored", m.ID, "Closed").Result()
 
-	// 2) service_state:{service}:{version} → health_state=Normal; resolved_at=now; add to Normal index
+	// 2) service_state:{service}:{version}:{region} → health_state=Normal; resolved_at=now; add to Normal index
 	if m.Service != "" {
-		svcKey := "service_state:" + m.Service + ":" + m.Version
+		svcKey := "service_state:" + m.Service + ":" + m.Version + ":" + region.Normalize(m.Region)
 		now := time.Now().UTC().Format(time.RFC3339Nano)
 		svcScript := redis.NewScript(`
 local v = redis.call('GET', KEYS[1])
@@ -0,0 +1,93 @@
+package remediation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/database/memory"
+	"github.com/qiniu/zeroops/internal/alerting/service/analysis"
+	"github.com/qiniu/zeroops/internal/alerting/service/healthcheck"
+)
+
+type failingStateStore struct {
+	*memory.Store
+}
+
+func (failingStateStore) ResolveServiceState(ctx context.Context, service, version, region, issueID string) error {
+	return errors.New("boom")
+}
+
+func seedIssue(t *testing.T, store *memory.Store) *healthcheck.AlertMessage {
+	t.Helper()
+	err := store.InsertIssue(context.Background(), &adb.Issue{
+		ID: "issue-1", State: "Open", Level: "P1", AlertState: "InProcessing",
+		Title: "latency", Labels: []byte(`[]`), AlertSince: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &healthcheck.AlertMessage{ID: "issue-1", Service: "storage", Version: "v1.0.0", Region: "cn-east-1", Level: "P1"}
+}
+
+func TestMarkRestoredInDB(t *testing.T) {
+	store := memory.New()
+	m := seedIssue(t, store)
+
+	c := NewConsumer(store, nil)
+	if err := c.markRestoredInDB(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	it, _ := store.Issue("issue-1")
+	if it.State != "Closed" || it.AlertState != "Restored" {
+		t.Fatalf("expected issue closed and restored, got %s/%s", it.State, it.AlertState)
+	}
+	st, ok := store.ServiceState("storage", "v1.0.0", "cn-east-1")
+	if !ok || st.HealthState != "Normal" || st.ResolvedAt == nil {
+		t.Fatalf("expected service state resolved, got %+v", st)
+	}
+}
+
+func TestMarkRestoredInDBRollsBack(t *testing.T) {
+	store := memory.New()
+	m := seedIssue(t, store)
+
+	c := NewConsumer(failingStateStore{store}, nil)
+	if err := c.markRestoredInDB(context.Background(), m); err == nil {
+		t.Fatal("expected error")
+	}
+	it, _ := store.Issue("issue-1")
+	if it.State != "Open" || it.AlertState != "InProcessing" {
+		t.Fatalf("expected issue update rolled back, got %s/%s", it.State, it.AlertState)
+	}
+}
+
+func TestHandleAddsAnalysisCommentOnce(t *testing.T) {
+	store := memory.New()
+	m := seedIssue(t, store)
+	provider := &analysis.FakeProvider{}
+
+	c := NewConsumer(store, nil)
+	c.sleepFn = nil
+	c.Analyzer = &analysis.LLMAnalyzer{Provider: provider, Now: time.Now}
+	for i := 0; i < 2; i++ {
+		if !c.handle(context.Background(), m, 0) {
+			t.Fatal("handle interrupted")
+		}
+	}
+	comments, _ := store.ListComments(context.Background(), m.ID)
+	if len(comments) != 1 || len(provider.Chats()) != 1 {
+		t.Fatalf("%d comments after %d analyses", len(comments), len(provider.Chats()))
+	}
+	var res analysis.Result
+	if err := json.Unmarshal(comments[0].Analysis, &res); err != nil || res.Model != "fake" || res.Confidence != 0.5 {
+		t.Fatalf("analysis = %s, %v", comments[0].Analysis, err)
+	}
+	if !strings.HasPrefix(comments[0].Content, "## AI分析结果\n**问题类型**：非发版本导致的问题") {
+		t.Fatalf("content = %q", comments[0].Content)
+	}
+}
@@ -0,0 +1,115 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Markdown renders the report as a postmortem draft.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# 故障复盘：%s\n\n", r.Title)
+
+	b.WriteString("## 概要\n\n")
+	fmt.Fprintf(&b, "- 告警ID：%s\n- 等级：%s\n- 服务：%s\n- 区域：%s\n- 状态：%s / %s\n",
+		r.IssueID, r.Level, dash(r.Service), dash(r.Region), r.State, r.AlertState)
+	fmt.Fprintf(&b, "- 告警开始：%s\n- 发现时间：%s\n- 确认时间：%s", formatTime(&r.AlertSince), formatTime(r.DetectedAt), formatTime(r.AckedAt))
+	if r.AckedBy != "" {
+		fmt.Fprintf(&b, "（%s）", r.AckedBy)
+	}
+	fmt.Fprintf(&b, "\n- 恢复时间：%s\n\n", formatTime(r.ResolvedAt))
+
+	b.WriteString("## 关键指标\n\n| 指标 | 时长 |\n| --- | --- |\n")
+	fmt.Fprintf(&b, "| 发现耗时（TTD） | %s |\n", duration(r.Figures.TimeToDetect))
+	fmt.Fprintf(&b, "| 确认耗时（TTA） | %s |\n", duration(r.Figures.TimeToAcknowledge))
+	fmt.Fprintf(&b, "| 恢复耗时（TTR） | %s |\n\n", duration(r.Figures.TimeToResolve))
+
+	b.WriteString("## 时间线\n\n| 时间 | 事件 | 操作人 | 内容 |\n| --- | --- | --- | --- |\n")
+	for _, e := range r.Timeline {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", formatTime(&e.Time), e.Type, dash(e.Actor), cell(e.Summary))
+	}
+
+	b.WriteString("\n## 关联告警\n\n")
+	if len(r.Correlated) == 0 {
+		b.WriteString("无\n")
+	} else {
+		b.WriteString("| 告警ID | 服务 | 关系 | 等级 | 开始时间 | 标题 |\n| --- | --- | --- | --- | --- | --- |\n")
+		for _, c := range r.Correlated {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", c.ID, c.Service, relationName(c.Relation), c.Level, formatTime(&c.AlertSince), cell(c.Title))
+		}
+	}
+
+	b.WriteString("\n## 相关发布\n\n")
+	if len(r.Deployments) == 0 {
+		b.WriteString("无\n")
+	} else {
+		b.WriteString("| 发布ID | 版本 | 区域 | 状态 | 开始 | 结束 |\n| --- | --- | --- | --- | --- | --- |\n")
+		for _, d := range r.Deployments {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n", d.ID, d.Version, dash(d.Region), d.Status, formatTime(d.Start), formatTime(d.Finish))
+		}
+	}
+
+	b.WriteString("\n## 指标快照\n\n")
+	if len(r.Metrics) == 0 {
+		b.WriteString("无\n")
+	} else {
+		b.WriteString("| 指标 | 告警时 | 最小 | 最大 | 点数 |\n| --- | --- | --- | --- | --- |\n")
+		for _, m := range r.Metrics {
+			if m.Error != "" {
+				fmt.Fprintf(&b, "| %s | 查询失败：%s | - | - | - |\n", m.Name, cell(m.Error))
+				continue
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %d |\n", m.Name, number(m.AtAlert), number(m.Min), number(m.Max), len(m.Points))
+		}
+	}
+
+	fmt.Fprintf(&b, "\n_生成于 %s_\n", formatTime(&r.GeneratedAt))
+	return b.String()
+}
+
+func relationName(rel string) string {
+	switch rel {
+	case RelationSameService:
+		return "同服务"
+	case RelationDependency:
+		return "下游依赖"
+	case RelationDependent:
+		return "上游调用方"
+	}
+	return rel
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func duration(s *int64) string {
+	if s == nil {
+		return "-"
+	}
+	return (time.Duration(*s) * time.Second).String()
+}
+
+func number(v *float64) string {
+	if v == nil {
+		return "-"
+	}
+	return strconv.FormatFloat(*v, 'g', 6, 64)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// cell escapes text for a table cell.
+func cell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", " ")
+}
@@ -0,0 +1,492 @@
+// Package report builds postmortem reports of alert issues from their stored history:
+// the timeline of events and comments, issues correlated through the service dependency
+// graph, the deployments active during the incident, metric snapshots around the alert
+// and the time-to-detect, -acknowledge and -resolve figures. Reports can be stored per
+// issue and listed per service.
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/service/analysis"
+	"github.com/qiniu/zeroops/internal/alerting/service/anomaly"
+	"github.com/qiniu/zeroops/internal/alerting/service/enrich"
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrInvalid  = errors.New("invalid report request")
+	ErrNotFound = errors.New("not found")
+)
+
+const (
+	// correlationWindow widens the incident when looking for correlated issues and
+	// active deployments.
+	correlationWindow = 30 * time.Minute
+	// metricsBefore and metricsAfter widen the range of the metric snapshots.
+	metricsBefore = 30 * time.Minute
+	metricsAfter  = 15 * time.Minute
+	// metricPoints is about the number of points in a snapshot.
+	metricPoints = 60
+	minStep      = 15 * time.Second
+	// maxCandidates bounds the issues scanned for correlation.
+	maxCandidates = 500
+	// maxDeployments is the number of recent deployments checked for overlap.
+	maxDeployments = 20
+	// maxSummary bounds the text of a comment in the timeline.
+	maxSummary = 300
+
+	DefaultListLimit = 50
+	MaxListLimit     = 200
+)
+
+// Timeline entry types besides the adb.Event* ones.
+const (
+	EntryAlert   = "alert"
+	EntryComment = "comment"
+)
+
+// Correlation relations.
+const (
+	RelationSameService = "same_service"
+	RelationDependency  = "dependency" // the correlated service is a dependency of the issue's
+	RelationDependent   = "dependent"  // the correlated service depends on the issue's
+)
+
+// Report is the postmortem report of an issue.
+type Report struct {
+	IssueID    string    `json:"issueId"`
+	Title      string    `json:"title"`
+	Level      string    `json:"level"`
+	State      string    `json:"state"`
+	AlertState string    `json:"alertState"`
+	Service    string    `json:"service"`
+	Region     string    `json:"region"`
+	AlertSince time.Time `json:"alertSince"`
+	// DetectedAt is when the issue was created, AckedAt when it was acknowledged and
+	// ResolvedAt when it was restored; nil when that has not happened or was not recorded.
+	DetectedAt  *time.Time        `json:"detectedAt"`
+	AckedAt     *time.Time        `json:"ackedAt"`
+	AckedBy     string            `json:"ackedBy,omitempty"`
+	ResolvedAt  *time.Time        `json:"resolvedAt"`
+	Figures     Figures           `json:"figures"`
+	Timeline    []Entry           `json:"timeline"`
+	Correlated  []CorrelatedIssue `json:"correlatedIssues"`
+	Deployments []Deployment      `json:"deployments"`
+	Metrics     []MetricSnapshot  `json:"metrics"`
+	GeneratedAt time.Time         `json:"generatedAt"`
+}
+
+// Figures are durations in seconds, nil when a time they are measured between is unknown.
+type Figures struct {
+	// TimeToDetect is from the alert starting to the issue being created.
+	TimeToDetect *int64 `json:"timeToDetectSeconds"`
+	// TimeToAcknowledge is from the issue being created to its acknowledgement.
+	TimeToAcknowledge *int64 `json:"timeToAcknowledgeSeconds"`
+	// TimeToResolve is from the alert starting to the issue being restored.
+	TimeToResolve *int64 `json:"timeToResolveSeconds"`
+}
+
+// Entry is one point of the timeline.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"` // alert, created, acked, state, remediation or comment
+	Actor   string    `json:"actor,omitempty"`
+	Summary string    `json:"summary"`
+}
+
+// CorrelatedIssue is another issue that started around the incident on the same service
+// or on a service it depends on or that depends on it.
+type CorrelatedIssue struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	Level      string    `json:"level"`
+	AlertState string    `json:"alertState"`
+	Service    string    `json:"service"`
+	AlertSince time.Time `json:"alertSince"`
+	Relation   string    `json:"relation"`
+}
+
+// Deployment is a deployment of the service that overlapped the incident.
+type Deployment struct {
+	ID      string     `json:"id"`
+	Version string     `json:"version"`
+	Region  string     `json:"region"`
+	Status  string     `json:"status"`
+	Start   *time.Time `json:"start"`
+	Finish  *time.Time `json:"finish"`
+}
+
+// MetricSnapshot is a configured metric of the service around the incident.
+type MetricSnapshot struct {
+	Name   string          `json:"name"`
+	Query  string          `json:"query"`
+	Points []anomaly.Point `json:"points"`
+	// Min, Max and AtAlert, the last value at or before alert_since, are nil without points.
+	Min     *float64 `json:"min"`
+	Max     *float64 `json:"max"`
+	AtAlert *float64 `json:"atAlert"`
+	// Error is set when the query failed.
+	Error string `json:"error,omitempty"`
+}
+
+// Stored is a report stored by Save.
+type Stored struct {
+	CreatedAt time.Time `json:"createdAt"`
+	CreatedBy string    `json:"createdBy"`
+	Report    *Report   `json:"report"`
+}
+
+// Summary is a stored report in a list.
+type Summary struct {
+	IssueID    string    `json:"issueId"`
+	Service    string    `json:"service"`
+	Title      string    `json:"title"`
+	Level      string    `json:"level"`
+	AlertSince time.Time `json:"alertSince"`
+	CreatedAt  time.Time `json:"createdAt"`
+	CreatedBy  string    `json:"createdBy"`
+	Figures    Figures   `json:"figures"`
+}
+
+// Store is the part of the alerting store reports are built from and kept in.
+type Store interface {
+	adb.IssueRepository
+	adb.IssueEventRepository
+	adb.IssueReportRepository
+	adb.CommentRepository
+}
+
+// Service generates and stores reports. The sources are optional; a report leaves out
+// what an unset source would provide.
+type Service struct {
+	DB          Store
+	Catalog     enrich.Catalog
+	Deployments analysis.DeploymentSource
+	Metrics     anomaly.Source
+	// Queries are the metrics snapshotted, $service is replaced by the service.
+	Queries []config.AnalysisQuery
+	Now     func() time.Time
+}
+
+func NewService(db Store, catalog enrich.Catalog, deployments analysis.DeploymentSource, metrics anomaly.Source, queries []config.AnalysisQuery) *Service {
+	return &Service{DB: db, Catalog: catalog, Deployments: deployments, Metrics: metrics, Queries: queries, Now: time.Now}
+}
+
+// Generate builds the report of an issue from its current history.
+func (s *Service) Generate(ctx context.Context, issueID string) (*Report, error) {
+	issue, err := s.DB.GetIssue(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if issue == nil {
+		return nil, fmt.Errorf("%w: issue %s", ErrNotFound, issueID)
+	}
+	events, err := s.DB.ListIssueEvents(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.DB.ListComments(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := adb.LabelMap(issue.Labels)
+	now := s.Now().UTC()
+	r := &Report{
+		IssueID:     issue.ID,
+		Title:       issue.Title,
+		Level:       issue.Level,
+		State:       issue.State,
+		AlertState:  issue.AlertState,
+		Service:     labels["service"],
+		Region:      issue.Region,
+		AlertSince:  issue.AlertSince.UTC(),
+		GeneratedAt: now,
+	}
+	r.Timeline = append(r.Timeline, Entry{Time: r.AlertSince, Type: EntryAlert, Summary: "告警开始：" + issue.Title})
+	for _, e := range events {
+		at := e.At.UTC()
+		switch {
+		case e.Type == adb.EventCreated && r.DetectedAt == nil:
+			r.DetectedAt = &at
+		case e.Type == adb.EventAcked && r.AckedAt == nil:
+			r.AckedAt, r.AckedBy = &at, e.Actor
+		case e.Type == adb.EventState && r.ResolvedAt == nil && (e.To == "Restored" || e.To == "AutoRestored"):
+			r.ResolvedAt = &at
+		}
+		r.Timeline = append(r.Timeline, Entry{Time: at, Type: e.Type, Actor: e.Actor, Summary: eventSummary(&e)})
+	}
+	// the first acknowledgement is recorded as an event; issues acknowledged before their
+	// history was recorded only have the latest one
+	if r.AckedAt == nil && issue.AckedAt != nil {
+		r.AckedAt, r.AckedBy = utc(issue.AckedAt), issue.AckedBy
+		r.Timeline = append(r.Timeline, Entry{Time: *r.AckedAt, Type: adb.EventAcked, Actor: r.AckedBy, Summary: r.AckedBy + " 确认告警"})
+	}
+	for _, c := range comments {
+		r.Timeline = append(r.Timeline, Entry{Time: c.CreatedAt.UTC(), Type: EntryComment, Summary: commentSummary(c.Content)})
+	}
+	slices.SortStableFunc(r.Timeline, func(a, b Entry) int { return a.Time.Compare(b.Time) })
+	r.Figures = Figures{
+		TimeToDetect:      seconds(&r.AlertSince, r.DetectedAt),
+		TimeToAcknowledge: seconds(r.DetectedAt, r.AckedAt),
+		TimeToResolve:     seconds(&r.AlertSince, r.ResolvedAt),
+	}
+
+	end := now
+	if r.ResolvedAt != nil {
+		end = *r.ResolvedAt
+	}
+	if r.Correlated, err = s.correlated(ctx, issue, r.Service, end); err != nil {
+		return nil, err
+	}
+	r.Deployments = s.deployments(ctx, r, end)
+	r.Metrics = s.metrics(ctx, r, end, now)
+	return r, nil
+}
+
+// Save generates the report of an issue and stores it, replacing an earlier one.
+func (s *Service) Save(ctx context.Context, issueID, by string) (*Stored, error) {
+	r, err := s.Generate(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	stored := &Stored{CreatedAt: r.GeneratedAt, CreatedBy: by, Report: r}
+	err = s.DB.UpsertIssueReport(ctx, &adb.IssueReport{
+		IssueID:   r.IssueID,
+		Service:   r.Service,
+		CreatedAt: stored.CreatedAt,
+		CreatedBy: by,
+		Report:    data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// Get returns the stored report of an issue.
+func (s *Service) Get(ctx context.Context, issueID string) (*Stored, error) {
+	row, err := s.DB.GetIssueReport(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, fmt.Errorf("%w: no report of issue %s", ErrNotFound, issueID)
+	}
+	return decode(row)
+}
+
+// List returns up to limit stored reports of service, newest first; an empty service
+// lists those of every service.
+func (s *Service) List(ctx context.Context, service string, limit int) ([]Summary, error) {
+	if limit == 0 {
+		limit = DefaultListLimit
+	}
+	if limit < 0 || limit > MaxListLimit {
+		return nil, fmt.Errorf("%w: limit must be within 1..%d", ErrInvalid, MaxListLimit)
+	}
+	rows, err := s.DB.ListIssueReports(ctx, strings.TrimSpace(service), limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Summary, 0, len(rows))
+	for i := range rows {
+		st, err := decode(&rows[i])
+		if err != nil {
+			return nil, err
+		}
+		r := st.Report
+		out = append(out, Summary{
+			IssueID:    r.IssueID,
+			Service:    r.Service,
+			Title:      r.Title,
+			Level:      r.Level,
+			AlertSince: r.AlertSince,
+			CreatedAt:  st.CreatedAt,
+			CreatedBy:  st.CreatedBy,
+			Figures:    r.Figures,
+		})
+	}
+	return out, nil
+}
+
+func decode(row *adb.IssueReport) (*Stored, error) {
+	var r Report
+	if err := json.Unmarshal(row.Report, &r); err != nil {
+		return nil, fmt.Errorf("decode report of issue %s: %w", row.IssueID, err)
+	}
+	return &Stored{CreatedAt: row.CreatedAt.UTC(), CreatedBy: row.CreatedBy, Report: &r}, nil
+}
+
+// correlated returns the issues that started within correlationWindow of the incident on
+// the service or a service next to it in the dependency graph.
+func (s *Service) correlated(ctx context.Context, issue *adb.Issue, service string, end time.Time) ([]CorrelatedIssue, error) {
+	out := []CorrelatedIssue{}
+	if service == "" {
+		return out, nil
+	}
+	relation := map[string]string{service: RelationSameService}
+	if s.Catalog != nil {
+		info, err := s.Catalog.AlertServiceInfo(ctx, service, issue.Region)
+		if err != nil {
+			log.Warn().Err(err).Str("service", service).Msg("report: service catalog lookup failed")
+		} else if info != nil {
+			for _, d := range info.Deps {
+				relation[d] = RelationDependency
+			}
+			for _, d := range info.Dependents {
+				if _, ok := relation[d]; !ok {
+					relation[d] = RelationDependent
+				}
+			}
+		}
+	}
+	candidates, err := s.DB.ListIssuesBetween(ctx, issue.AlertSince.Add(-correlationWindow), end.Add(correlationWindow), maxCandidates)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range candidates {
+		if c.ID == issue.ID {
+			continue
+		}
+		svc := adb.LabelMap(c.Labels)["service"]
+		rel, ok := relation[svc]
+		if !ok || svc == "" {
+			continue
+		}
+		out = append(out, CorrelatedIssue{
+			ID:         c.ID,
+			Title:      c.Title,
+			Level:      c.Level,
+			AlertState: c.AlertState,
+			Service:    svc,
+			AlertSince: c.AlertSince.UTC(),
+			Relation:   rel,
+		})
+	}
+	return out, nil
+}
+
+// deployments returns the recent deployments of the service that ran during the incident
+// or finished within correlationWindow before it.
+func (s *Service) deployments(ctx context.Context, r *Report, end time.Time) []Deployment {
+	out := []Deployment{}
+	if s.Deployments == nil || r.Service == "" {
+		return out
+	}
+	recent, err := s.Deployments.RecentDeployments(ctx, r.Service, maxDeployments)
+	if err != nil {
+		log.Warn().Err(err).Str("issue", r.IssueID).Msg("report: deployments unavailable")
+		return out
+	}
+	from := r.AlertSince.Add(-correlationWindow)
+	for _, d := range recent {
+		if d.Start == nil || d.Start.After(end) || (d.Finish != nil && d.Finish.Before(from)) {
+			continue
+		}
+		if r.Region != "" && d.Region != "" && d.Region != r.Region {
+			continue
+		}
+		out = append(out, Deployment{ID: d.ID, Version: d.Version, Region: d.Region, Status: d.Status, Start: utc(d.Start), Finish: utc(d.Finish)})
+	}
+	return out
+}
+
+// metrics snapshots the configured queries from metricsBefore the alert to metricsAfter
+// the end of the incident.
+func (s *Service) metrics(ctx context.Context, r *Report, end, now time.Time) []MetricSnapshot {
+	out := []MetricSnapshot{}
+	if s.Metrics == nil || r.Service == "" {
+		return out
+	}
+	start := r.AlertSince.Add(-metricsBefore)
+	end = end.Add(metricsAfter)
+	if end.After(now) {
+		end = now
+	}
+	step := max(end.Sub(start)/metricPoints, minStep).Truncate(time.Second)
+	for _, q := range s.Queries {
+		snap := MetricSnapshot{Name: q.Name, Query: strings.ReplaceAll(q.Query, "$service", r.Service), Points: []anomaly.Point{}}
+		points, err := s.Metrics.QueryRange(ctx, snap.Query, start, end, step)
+		if err != nil {
+			snap.Error = err.Error()
+			out = append(out, snap)
+			continue
+		}
+		for _, p := range points {
+			p.Time = p.Time.UTC()
+			snap.Points = append(snap.Points, p)
+			v := p.Value
+			if snap.Min == nil || v < *snap.Min {
+				snap.Min = &v
+			}
+			if snap.Max == nil || v > *snap.Max {
+				snap.Max = &v
+			}
+			if !p.Time.After(r.AlertSince) {
+				snap.AtAlert = &v
+			}
+		}
+		out = append(out, snap)
+	}
+	return out
+}
+
+func eventSummary(e *adb.IssueEvent) string {
+	switch e.Type {
+	case adb.EventCreated:
+		return "创建告警工单，告警状态 " + e.To
+	case adb.EventAcked:
+		return e.Actor + " 确认告警"
+	case adb.EventState:
+		return fmt.Sprintf("告警状态 %s → %s", e.From, e.To)
+	case adb.EventRemediation:
+		return "自动治愈：" + e.Detail
+	}
+	return e.Detail
+}
+
+// commentSummary puts a comment on one line, cut to maxSummary characters.
+func commentSummary(content string) string {
+	var parts []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+		if line != "" {
+			parts = append(parts, line)
+		}
+	}
+	s := strings.Join(parts, " ")
+	if r := []rune(s); len(r) > maxSummary {
+		s = string(r[:maxSummary]) + "…"
+	}
+	return s
+}
+
+func seconds(from, to *time.Time) *int64 {
+	if from == nil || to == nil {
+		return nil
+	}
+	d := int64(to.Sub(*from) / time.Second)
+	return &d
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
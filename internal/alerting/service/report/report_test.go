@@ -0,0 +1,186 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	"github.com/qiniu/zeroops/internal/alerting/database/memory"
+	"github.com/qiniu/zeroops/internal/alerting/service/analysis"
+	"github.com/qiniu/zeroops/internal/alerting/service/anomaly"
+	"github.com/qiniu/zeroops/internal/alerting/service/enrich"
+	"github.com/qiniu/zeroops/internal/config"
+)
+
+var t0 = time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+
+type fakeCatalog struct{}
+
+func (fakeCatalog) InstanceService(context.Context, string) (string, error) { return "", nil }
+
+func (fakeCatalog) AlertServiceInfo(_ context.Context, service, _ string) (*enrich.ServiceInfo, error) {
+	if service != "storage" {
+		return nil, nil
+	}
+	return &enrich.ServiceInfo{Deps: []string{"metadata"}, Dependents: []string{"gateway"}}, nil
+}
+
+type fakeDeployments []analysis.Deployment
+
+func (f fakeDeployments) RecentDeployments(context.Context, string, int) ([]analysis.Deployment, error) {
+	return f, nil
+}
+
+type fakeMetrics struct{ queries []string }
+
+func (f *fakeMetrics) QueryRange(_ context.Context, query string, start, end time.Time, step time.Duration) ([]anomaly.Point, error) {
+	f.queries = append(f.queries, query)
+	if strings.Contains(query, "broken") {
+		return nil, errors.New("bad query")
+	}
+	var out []anomaly.Point
+	for t := start; !t.After(end); t = t.Add(step) {
+		v := 0.01
+		if !t.Before(t0) {
+			v = 0.2
+		}
+		out = append(out, anomaly.Point{Time: t, Value: v})
+	}
+	return out, nil
+}
+
+func issue(id, service string, since time.Time) *adb.Issue {
+	return &adb.Issue{ID: id, State: "Open", Level: "P1", AlertState: "Pending", Title: service + " error rate",
+		Labels: []byte(`[{"key":"service","value":"` + service + `"}]`), AlertSince: since, Region: "cn-east-1"}
+}
+
+func TestGenerateAndStore(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	for _, it := range []*adb.Issue{
+		issue("issue-1", "storage", t0),
+		issue("issue-2", "metadata", t0.Add(-10*time.Minute)),
+		issue("issue-3", "gateway", t0.Add(5*time.Minute)),
+		issue("issue-4", "billing", t0.Add(5*time.Minute)),
+		issue("issue-5", "storage", t0.Add(-2*time.Hour)),
+	} {
+		if err := db.InsertIssue(ctx, it); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, e := range []adb.IssueEvent{
+		{Type: adb.EventCreated, At: t0.Add(time.Minute), Actor: "receiver", To: "Pending"},
+		{Type: adb.EventState, At: t0.Add(3 * time.Minute), Actor: "remediation", From: "Pending", To: "InProcessing"},
+		{Type: adb.EventRemediation, At: t0.Add(4 * time.Minute), Actor: "remediation", Detail: "rollback deploy-2"},
+		{Type: adb.EventState, At: t0.Add(9 * time.Minute), Actor: "remediation", From: "InProcessing", To: "Restored"},
+	} {
+		e.IssueID = "issue-1"
+		if _, err := db.AddIssueEvent(ctx, &e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.AckIssue(ctx, "issue-1", "alice", t0.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AddComment(ctx, "issue-1", "## 处理记录\n已回滚 | 观察中"); err != nil {
+		t.Fatal(err)
+	}
+
+	started, finished := t0.Add(-5*time.Minute), t0.Add(-time.Minute)
+	old := t0.Add(-24 * time.Hour)
+	metrics := &fakeMetrics{}
+	s := NewService(db, fakeCatalog{}, fakeDeployments{
+		{ID: "deploy-2", Version: "v1.2.0", Region: "cn-east-1", Status: "completed", Start: &started, Finish: &finished},
+		{ID: "deploy-1", Version: "v1.1.0", Region: "cn-east-1", Status: "completed", Start: &old, Finish: &old},
+	}, metrics, []config.AnalysisQuery{
+		{Name: "error_rate", Query: `rate(errors_total{service="$service"}[5m])`},
+		{Name: "broken", Query: "broken("},
+	})
+	s.Now = func() time.Time { return t0.Add(time.Hour) }
+
+	r, err := s.Generate(ctx, "issue-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *r.Figures.TimeToDetect != 60 || *r.Figures.TimeToAcknowledge != 60 || *r.Figures.TimeToResolve != 540 || r.AckedBy != "alice" {
+		t.Fatalf("figures = %+v, acked by %q", r.Figures, r.AckedBy)
+	}
+	var types []string
+	for _, e := range r.Timeline {
+		types = append(types, e.Type)
+	}
+	// the comment was added at the real time, after the fixed events
+	if got := strings.Join(types, ","); got != "alert,created,acked,state,remediation,state,comment" {
+		t.Fatalf("timeline = %s", got)
+	}
+	if c := r.Timeline[6].Summary; c != "处理记录 已回滚 | 观察中" {
+		t.Fatalf("comment summary = %q", c)
+	}
+	if len(r.Correlated) != 2 || r.Correlated[0].Relation != RelationDependency || r.Correlated[1].Relation != RelationDependent {
+		t.Fatalf("correlated = %+v", r.Correlated)
+	}
+	if len(r.Deployments) != 1 || r.Deployments[0].ID != "deploy-2" {
+		t.Fatalf("deployments = %+v", r.Deployments)
+	}
+	m := r.Metrics[0]
+	if m.Query != `rate(errors_total{service="storage"}[5m])` || *m.AtAlert != 0.01 || *m.Min != 0.01 || *m.Max != 0.2 {
+		t.Fatalf("metric = %+v", m)
+	}
+	if m.Points[len(m.Points)-1].Time.After(t0.Add(24*time.Minute)) || r.Metrics[1].Error == "" {
+		t.Fatalf("metrics = %+v", r.Metrics)
+	}
+	md := r.Markdown()
+	for _, part := range []string{"# 故障复盘：storage error rate", "| 恢复耗时（TTR） | 9m0s |", `已回滚 \| 观察中`, "| issue-2 | metadata | 下游依赖 |", "| broken | 查询失败"} {
+		if !strings.Contains(md, part) {
+			t.Errorf("markdown lacks %q:\n%s", part, md)
+		}
+	}
+
+	if _, err := s.Get(ctx, "issue-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get before save = %v", err)
+	}
+	if _, err := s.Save(ctx, "issue-1", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := s.Get(ctx, "issue-1")
+	if err != nil || stored.CreatedBy != "bob" || stored.Report.Figures.TimeToResolve == nil || len(stored.Report.Timeline) != 7 {
+		t.Fatalf("stored = %+v, %v", stored, err)
+	}
+	if list, err := s.List(ctx, "storage", 0); err != nil || len(list) != 1 || list[0].IssueID != "issue-1" {
+		t.Fatalf("list = %+v, %v", list, err)
+	}
+	if list, err := s.List(ctx, "gateway", 0); err != nil || len(list) != 0 {
+		t.Fatalf("list of other service = %+v, %v", list, err)
+	}
+	if _, err := s.List(ctx, "", MaxListLimit+1); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("list limit = %v", err)
+	}
+	if _, err := s.Generate(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing issue = %v", err)
+	}
+}
+
+func TestGenerateOpenIssueWithoutSources(t *testing.T) {
+	ctx := context.Background()
+	db := memory.New()
+	if err := db.InsertIssue(ctx, issue("issue-1", "storage", t0)); err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(db, nil, nil, nil, nil)
+	r, err := s.Generate(ctx, "issue-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ResolvedAt != nil || r.Figures.TimeToResolve != nil || r.Figures.TimeToDetect != nil || len(r.Timeline) != 1 {
+		t.Fatalf("report = %+v", r)
+	}
+	if r.Correlated == nil || r.Deployments == nil || r.Metrics == nil {
+		t.Fatal("empty sections must encode as []")
+	}
+	if md := r.Markdown(); !strings.Contains(md, "| 发现耗时（TTD） | - |") {
+		t.Fatalf("markdown:\n%s", md)
+	}
+}
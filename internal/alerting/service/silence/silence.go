@@ -0,0 +1,298 @@
+// Package silence mutes incoming alerts whose labels match an active silence, in the
+// spirit of Alertmanager silences. Silences are stored in alert_silences and checked by
+// the receiver before an issue is created.
+package silence
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+)
+
+var (
+	ErrInvalid  = errors.New("invalid silence")
+	ErrNotFound = errors.New("silence not found")
+)
+
+// Matcher operators.
+const (
+	OpEqual    = "="
+	OpNotEqual = "!="
+	OpRegex    = "=~"
+	OpNotRegex = "!~"
+)
+
+// maxDuration keeps forgotten silences from muting a service indefinitely.
+const maxDuration = 30 * 24 * time.Hour
+
+// Status of a silence relative to now.
+const (
+	StatusPending = "pending"
+	StatusActive  = "active"
+	StatusExpired = "expired"
+)
+
+// Matcher tests one label. Regular expressions are anchored like Alertmanager's.
+type Matcher struct {
+	Name  string `json:"name"`
+	Op    string `json:"op,omitempty"` // =, !=, =~ or !~; empty means =
+	Value string `json:"value"`
+
+	re *regexp.Regexp
+}
+
+// ParseMatcher parses "name=value", "name!=value", "name=~regex" or "name!~regex".
+func ParseMatcher(s string) (Matcher, error) {
+	for _, op := range []string{OpNotRegex, OpRegex, OpNotEqual, OpEqual} {
+		if name, value, ok := strings.Cut(s, op); ok {
+			m := Matcher{Name: strings.TrimSpace(name), Op: op, Value: strings.TrimSpace(value)}
+			return m, m.compile()
+		}
+	}
+	return Matcher{}, fmt.Errorf("%w: matcher %q has no operator", ErrInvalid, s)
+}
+
+func (m *Matcher) compile() error {
+	if m.Name == "" {
+		return fmt.Errorf("%w: matcher without label name", ErrInvalid)
+	}
+	if m.Op == "" {
+		m.Op = OpEqual
+	}
+	switch m.Op {
+	case OpEqual, OpNotEqual:
+	case OpRegex, OpNotRegex:
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return fmt.Errorf("%w: matcher %s: %v", ErrInvalid, m.Name, err)
+		}
+		m.re = re
+	default:
+		return fmt.Errorf("%w: matcher %s: unknown operator %q", ErrInvalid, m.Name, m.Op)
+	}
+	return nil
+}
+
+// Matches reports whether labels satisfy the matcher; a missing label is "".
+func (m *Matcher) Matches(labels map[string]string) bool {
+	v := labels[m.Name]
+	switch m.Op {
+	case OpNotEqual:
+		return v != m.Value
+	case OpRegex:
+		return m.re.MatchString(v)
+	case OpNotRegex:
+		return !m.re.MatchString(v)
+	default:
+		return v == m.Value
+	}
+}
+
+func (m Matcher) String() string {
+	return m.Name + m.Op + m.Value
+}
+
+// Silence is the API representation of an alert_silences row.
+type Silence struct {
+	ID        string    `json:"id"`
+	Matchers  []Matcher `json:"matchers"`
+	StartsAt  time.Time `json:"startsAt"`
+	EndsAt    time.Time `json:"endsAt"`
+	CreatedBy string    `json:"createdBy"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"createdAt"`
+	Status    string    `json:"status"` // pending/active/expired, computed when read
+}
+
+// CreateRequest is the body of POST /v1/silences. StartsAt defaults to now and either
+// EndsAt or Duration (e.g. "2h") is required.
+type CreateRequest struct {
+	Matchers  []Matcher  `json:"matchers"`
+	StartsAt  *time.Time `json:"startsAt,omitempty"`
+	EndsAt    *time.Time `json:"endsAt,omitempty"`
+	Duration  string     `json:"duration,omitempty"`
+	CreatedBy string     `json:"createdBy,omitempty"`
+	Comment   string     `json:"comment"`
+}
+
+// Matches reports whether every matcher accepts labels.
+func (s *Silence) Matches(labels map[string]string) bool {
+	for i := range s.Matchers {
+		if !s.Matchers[i].Matches(labels) {
+			return false
+		}
+	}
+	return len(s.Matchers) > 0
+}
+
+func (s *Silence) status(now time.Time) string {
+	switch {
+	case !now.Before(s.EndsAt):
+		return StatusExpired
+	case now.Before(s.StartsAt):
+		return StatusPending
+	default:
+		return StatusActive
+	}
+}
+
+// Service manages silences in the alerting store.
+type Service struct {
+	DB  adb.SilenceRepository
+	Now func() time.Time
+}
+
+func NewService(db adb.SilenceRepository) *Service {
+	return &Service{DB: db, Now: time.Now}
+}
+
+// Create validates and stores a silence.
+func (s *Service) Create(ctx context.Context, req *CreateRequest) (*Silence, error) {
+	if len(req.Matchers) == 0 {
+		return nil, fmt.Errorf("%w: at least one matcher is required", ErrInvalid)
+	}
+	for i := range req.Matchers {
+		if err := req.Matchers[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(req.Comment) == "" {
+		return nil, fmt.Errorf("%w: comment is required", ErrInvalid)
+	}
+
+	now := s.Now().UTC()
+	startsAt := now
+	if req.StartsAt != nil {
+		startsAt = req.StartsAt.UTC()
+	}
+	var endsAt time.Time
+	switch {
+	case req.EndsAt != nil:
+		endsAt = req.EndsAt.UTC()
+	case req.Duration != "":
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: duration %q", ErrInvalid, req.Duration)
+		}
+		endsAt = startsAt.Add(d)
+	default:
+		return nil, fmt.Errorf("%w: endsAt or duration is required", ErrInvalid)
+	}
+	if !endsAt.After(startsAt) || !endsAt.After(now) {
+		return nil, fmt.Errorf("%w: endsAt must be after startsAt and in the future", ErrInvalid)
+	}
+	if endsAt.Sub(startsAt) > maxDuration {
+		return nil, fmt.Errorf("%w: silences last at most %s", ErrInvalid, maxDuration)
+	}
+
+	sil := &Silence{
+		ID:        uuid.NewString(),
+		Matchers:  req.Matchers,
+		StartsAt:  startsAt,
+		EndsAt:    endsAt,
+		CreatedBy: req.CreatedBy,
+		Comment:   req.Comment,
+		CreatedAt: now,
+	}
+	matchers, err := json.Marshal(sil.Matchers)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.DB.InsertSilence(ctx, &adb.Silence{
+		ID:        sil.ID,
+		Matchers:  matchers,
+		StartsAt:  sil.StartsAt,
+		EndsAt:    sil.EndsAt,
+		CreatedBy: sil.CreatedBy,
+		Comment:   sil.Comment,
+		CreatedAt: sil.CreatedAt,
+	}); err != nil {
+		return nil, err
+	}
+	sil.Status = sil.status(now)
+	return sil, nil
+}
+
+// List returns silences newest first; expired ones only when includeExpired is set.
+func (s *Service) List(ctx context.Context, includeExpired bool) ([]Silence, error) {
+	now := s.Now()
+	var since time.Time
+	if !includeExpired {
+		since = now
+	}
+	rows, err := s.DB.ListSilences(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Silence, 0, len(rows))
+	for _, row := range rows {
+		sil, err := fromRow(&row)
+		if err != nil {
+			return nil, err
+		}
+		sil.Status = sil.status(now)
+		out = append(out, *sil)
+	}
+	return out, nil
+}
+
+// Expire ends a silence now.
+func (s *Service) Expire(ctx context.Context, id string) error {
+	ok, err := s.DB.ExpireSilence(ctx, id, s.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Match returns the ID of an active silence matching labels, or "".
+func (s *Service) Match(ctx context.Context, labels map[string]string) (string, error) {
+	now := s.Now()
+	rows, err := s.DB.ListSilences(ctx, now)
+	if err != nil {
+		return "", err
+	}
+	for _, row := range rows {
+		if now.Before(row.StartsAt) {
+			continue
+		}
+		sil, err := fromRow(&row)
+		if err != nil {
+			return "", err
+		}
+		if sil.Matches(labels) {
+			return sil.ID, nil
+		}
+	}
+	return "", nil
+}
+
+func fromRow(row *adb.Silence) (*Silence, error) {
+	sil := &Silence{
+		ID:        row.ID,
+		StartsAt:  row.StartsAt.UTC(),
+		EndsAt:    row.EndsAt.UTC(),
+		CreatedBy: row.CreatedBy,
+		Comment:   row.Comment,
+		CreatedAt: row.CreatedAt.UTC(),
+	}
+	if err := json.Unmarshal(row.Matchers, &sil.Matchers); err != nil {
+		return nil, fmt.Errorf("silence %s: decode matchers: %w", row.ID, err)
+	}
+	for i := range sil.Matchers {
+		if err := sil.Matchers[i].compile(); err != nil {
+			return nil, fmt.Errorf("silence %s: %w", row.ID, err)
+		}
+	}
+	return sil, nil
+}
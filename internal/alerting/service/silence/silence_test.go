@@ -0,0 +1,95 @@
+package silence
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/alerting/database/memory"
+)
+
+func TestParseMatcher(t *testing.T) {
+	cases := []struct {
+		in     string
+		labels map[string]string
+		want   bool
+	}{
+		{"service=api", map[string]string{"service": "api"}, true},
+		{"service=api", map[string]string{"service": "api-gw"}, false},
+		{"service!=api", map[string]string{"service": "storage"}, true},
+		{"service=~api|storage", map[string]string{"service": "storage"}, true},
+		{"service=~api", map[string]string{"service": "api-gw"}, false}, // anchored
+		{"env!~prod.*", map[string]string{}, true},
+	}
+	for _, tc := range cases {
+		m, err := ParseMatcher(tc.in)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.in, err)
+		}
+		if got := m.Matches(tc.labels); got != tc.want {
+			t.Errorf("%s on %v = %v, want %v", tc.in, tc.labels, got, tc.want)
+		}
+	}
+	for _, bad := range []string{"service", "=api", "service=~("} {
+		if _, err := ParseMatcher(bad); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q: err = %v, want ErrInvalid", bad, err)
+		}
+	}
+}
+
+func TestServiceLifecycle(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 5, 5, 12, 0, 0, 0, time.UTC)
+	svc := NewService(memory.New())
+	svc.Now = func() time.Time { return now }
+
+	if _, err := svc.Create(ctx, &CreateRequest{Matchers: []Matcher{{Name: "service", Value: "api"}}, Comment: "x"}); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("missing end: err = %v, want ErrInvalid", err)
+	}
+
+	later := now.Add(time.Hour)
+	pending, err := svc.Create(ctx, &CreateRequest{
+		Matchers: []Matcher{{Name: "service", Value: "storage"}},
+		StartsAt: &later,
+		Duration: "1h",
+		Comment:  "tomorrow's window",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Status != StatusPending {
+		t.Fatalf("status = %s, want pending", pending.Status)
+	}
+	active, err := svc.Create(ctx, &CreateRequest{
+		Matchers: []Matcher{{Name: "service", Value: "api"}, {Name: "alertname", Op: OpRegex, Value: "High.*"}},
+		Duration: "30m",
+		Comment:  "deploying api",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id, _ := svc.Match(ctx, map[string]string{"service": "api", "alertname": "HighLatency"}); id != active.ID {
+		t.Fatalf("match = %q, want %q", id, active.ID)
+	}
+	if id, _ := svc.Match(ctx, map[string]string{"service": "storage", "alertname": "HighLatency"}); id != "" {
+		t.Fatalf("pending silence matched: %q", id)
+	}
+
+	if err := svc.Expire(ctx, active.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Expire(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expire missing: err = %v, want ErrNotFound", err)
+	}
+	if id, _ := svc.Match(ctx, map[string]string{"service": "api", "alertname": "HighLatency"}); id != "" {
+		t.Fatalf("expired silence matched: %q", id)
+	}
+
+	current, _ := svc.List(ctx, false)
+	all, _ := svc.List(ctx, true)
+	if len(current) != 1 || len(all) != 2 {
+		t.Fatalf("list = %d current, %d all; want 1, 2", len(current), len(all))
+	}
+}
@@ -0,0 +1,99 @@
+package slo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Querier evaluates PromQL instant queries.
+type Querier interface {
+	// QueryScalar evaluates query at ts and returns the value of its only sample; ok is
+	// false when the result is empty or NaN.
+	QueryScalar(ctx context.Context, query string, ts time.Time) (value float64, ok bool, err error)
+}
+
+// Budget is the state of an SLO over its window at EvaluatedAt.
+type Budget struct {
+	Service    string  `json:"service"`
+	SLO        string  `json:"slo"`
+	Target     float64 `json:"target"`
+	WindowDays int     `json:"windowDays"`
+	// SLI is the ratio of good to total events over the window, nil without events.
+	SLI *float64 `json:"sli"`
+	// Remaining is the unspent fraction of the error budget, 1 - (1-SLI)/(1-Target). It
+	// is negative once the budget is exhausted and nil without events.
+	Remaining   *float64  `json:"remaining"`
+	EvaluatedAt time.Time `json:"evaluatedAt"`
+	// Error is set when Prometheus failed to evaluate this SLO.
+	Error string `json:"error,omitempty"`
+}
+
+// Budgets evaluates the error budget of the SLOs of service, or of every service when it
+// is "". A failed query is reported in the Error of its budget.
+func (s *Service) Budgets(ctx context.Context, service string) ([]Budget, error) {
+	if s.Prom == nil {
+		return nil, ErrNoPrometheus
+	}
+	slos, err := s.List(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	now := s.Now().UTC()
+	out := make([]Budget, len(slos))
+	for i := range slos {
+		b, err := s.budget(ctx, &slos[i], now)
+		if err != nil {
+			b.Error = err.Error()
+		}
+		out[i] = *b
+	}
+	return out, nil
+}
+
+// BudgetSnapshot returns the JSON encoded Budget of an SLO for the issue opened by its
+// burn-rate alert. It returns nil when the SLO does not exist or Prometheus is not
+// configured.
+func (s *Service) BudgetSnapshot(ctx context.Context, service, name string) (json.RawMessage, error) {
+	if s.Prom == nil {
+		return nil, nil
+	}
+	row, err := s.DB.GetSLO(ctx, service, name)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	slo := fromRow(row)
+	b, err := s.budget(ctx, &slo, s.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(b)
+}
+
+func (s *Service) budget(ctx context.Context, slo *SLO, now time.Time) (*Budget, error) {
+	b := &Budget{
+		Service:     slo.Service,
+		SLO:         slo.Name,
+		Target:      slo.Target,
+		WindowDays:  slo.WindowDays,
+		EvaluatedAt: now,
+	}
+	window := strconv.Itoa(slo.WindowDays) + "d"
+	query := fmt.Sprintf("sum(%s) / sum(%s)",
+		strings.ReplaceAll(slo.GoodQuery, WindowVar, window),
+		strings.ReplaceAll(slo.TotalQuery, WindowVar, window))
+	sli, ok, err := s.Prom.QueryScalar(ctx, query, now)
+	if err != nil {
+		return b, fmt.Errorf("slo %s/%s: %w", slo.Service, slo.Name, err)
+	}
+	if !ok || math.IsInf(sli, 0) {
+		return b, nil
+	}
+	remaining := 1 - (1-sli)/(1-slo.Target)
+	b.SLI, b.Remaining = &sli, &remaining
+	return b, nil
+}
@@ -0,0 +1,133 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Names of the generated rules. The recording rules carry the service and slo labels,
+// and so do the alerts, which the receiver uses to attach the budget snapshot.
+const (
+	AlertName      = "SLOErrorBudgetBurn"
+	RecordPrefix   = "slo:sli_error:ratio_rate"
+	LabelService   = "service"
+	LabelSLO       = "slo"
+	LabelBurnAlert = "slo_severity" // page or ticket
+)
+
+// recordWindows are the windows the SLI error ratio is recorded over, covering both
+// windows of every burnWindow.
+var recordWindows = []string{"5m", "30m", "1h", "2h", "6h", "1d", "3d"}
+
+// burnWindow is one condition of a burn-rate alert: the error ratio over the long window
+// measures the burn rate, and the short window makes the alert resolve soon after the
+// burn stops.
+type burnWindow struct {
+	long, short string
+	longHours   float64
+	// budget is the fraction of the error budget spent over long at the threshold.
+	budget float64
+}
+
+// The windows recommended by the SRE workbook ("Alerting on SLOs"). For a 30 day SLO
+// they burn at 14.4, 6, 3 and 1 times the sustainable rate.
+var (
+	pageWindows   = []burnWindow{{"1h", "5m", 1, 0.02}, {"6h", "30m", 6, 0.05}}
+	ticketWindows = []burnWindow{{"1d", "2h", 24, 0.10}, {"3d", "6h", 72, 0.10}}
+)
+
+// RuleFile is a Prometheus rule file. Marshalled as YAML it can be loaded with
+// rule_files; the API serves it as JSON.
+type RuleFile struct {
+	Groups []RuleGroup `json:"groups" yaml:"groups"`
+}
+
+type RuleGroup struct {
+	Name  string `json:"name" yaml:"name"`
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule is a recording rule when Record is set and an alerting rule when Alert is.
+type Rule struct {
+	Record      string            `json:"record,omitempty" yaml:"record,omitempty"`
+	Alert       string            `json:"alert,omitempty" yaml:"alert,omitempty"`
+	Expr        string            `json:"expr" yaml:"expr"`
+	Labels      map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+}
+
+// Rules returns the rules of every stored SLO.
+func (s *Service) Rules(ctx context.Context) (*RuleFile, error) {
+	slos, err := s.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return GenerateRules(slos), nil
+}
+
+// GenerateRules returns one rule group per SLO with the error ratio recording rules and
+// two burn-rate alerts: a P1 page on fast burns and a P2 ticket on slow ones.
+func GenerateRules(slos []SLO) *RuleFile {
+	file := &RuleFile{Groups: make([]RuleGroup, 0, len(slos))}
+	for i := range slos {
+		slo := &slos[i]
+		labels := map[string]string{LabelService: slo.Service, LabelSLO: slo.Name}
+		group := RuleGroup{Name: "slo:" + slo.Service + ":" + slo.Name}
+		for _, w := range recordWindows {
+			group.Rules = append(group.Rules, Rule{
+				Record: RecordPrefix + w,
+				Expr:   slo.errorRatio(w),
+				Labels: labels,
+			})
+		}
+		group.Rules = append(group.Rules,
+			slo.burnAlert("page", "P1", pageWindows),
+			slo.burnAlert("ticket", "P2", ticketWindows))
+		file.Groups = append(file.Groups, group)
+	}
+	return file
+}
+
+// errorRatio is the PromQL ratio of bad to total events over window.
+func (slo *SLO) errorRatio(window string) string {
+	good := strings.ReplaceAll(slo.GoodQuery, WindowVar, window)
+	total := strings.ReplaceAll(slo.TotalQuery, WindowVar, window)
+	return fmt.Sprintf("1 - (sum(%s) / sum(%s))", good, total)
+}
+
+func (slo *SLO) burnAlert(severity, level string, windows []burnWindow) Rule {
+	selector := fmt.Sprintf("{%s=%q, %s=%q}", LabelService, slo.Service, LabelSLO, slo.Name)
+	budget := "(1 - " + strconv.FormatFloat(slo.Target, 'f', -1, 64) + ")"
+	conds := make([]string, len(windows))
+	for i, w := range windows {
+		threshold := fmt.Sprintf("(%s * %s)", strconv.FormatFloat(w.burnRate(slo.WindowDays), 'f', -1, 64), budget)
+		conds[i] = fmt.Sprintf("(%s%s%s > %s and %s%s%s > %s)",
+			RecordPrefix, w.long, selector, threshold, RecordPrefix, w.short, selector, threshold)
+	}
+	return Rule{
+		Alert: AlertName,
+		Expr:  strings.Join(conds, "\nor\n"),
+		Labels: map[string]string{
+			LabelService:   slo.Service,
+			LabelSLO:       slo.Name,
+			LabelBurnAlert: severity,
+			"severity":     level,
+		},
+		Annotations: map[string]string{
+			"summary": fmt.Sprintf("%s SLO %s is burning its error budget too fast", slo.Service, slo.Name),
+			"description": fmt.Sprintf("The error ratio of %s/%s is above the %s burn-rate thresholds for a %s target over %d days.",
+				slo.Service, slo.Name, severity, strconv.FormatFloat(slo.Target, 'f', -1, 64), slo.WindowDays),
+		},
+	}
+}
+
+// burnRate is the multiple of the sustainable error rate that spends w.budget of a
+// windowDays budget within the long window. Rates below 1 are raised to 1, since they
+// would not exhaust the budget at all.
+func (w burnWindow) burnRate(windowDays int) float64 {
+	rate := w.budget * float64(windowDays) * 24 / w.longHours
+	return max(1, math.Round(rate*100)/100)
+}
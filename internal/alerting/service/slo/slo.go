@@ -0,0 +1,179 @@
+// Package slo manages per-service service level objectives. An SLO is the ratio of good
+// to total events, both given as PromQL, that must stay at or above a target over a
+// rolling window of days. From the stored SLOs the package generates Prometheus recording
+// rules and multi-window multi-burn-rate alerting rules, and it evaluates the error
+// budget left in the window.
+package slo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+)
+
+var (
+	ErrInvalid      = errors.New("invalid slo")
+	ErrNotFound     = errors.New("slo not found")
+	ErrNoPrometheus = errors.New("prometheus is not configured")
+)
+
+// WindowVar stands for the range of the range vectors in GoodQuery and TotalQuery, e.g.
+// sum(rate(http_requests_total{service="api",code!~"5.."}[$window])). It is replaced by
+// each window the rules and the budget are evaluated over.
+const WindowVar = "$window"
+
+// Window bounds in days. The longest alert window is 3d, so shorter SLO windows make
+// little sense.
+const (
+	DefaultWindowDays = 30
+	minWindowDays     = 7
+	maxWindowDays     = 90
+)
+
+// namePattern keeps SLO names usable as label values, URL segments and rule group names.
+var namePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,127}$`)
+
+// SLO is the API representation of a service_slos row.
+type SLO struct {
+	Service     string    `json:"service"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	GoodQuery   string    `json:"goodQuery"`
+	TotalQuery  string    `json:"totalQuery"`
+	Target      float64   `json:"target"` // e.g. 0.999
+	WindowDays  int       `json:"windowDays"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// PutRequest is the body of PUT /v1/slos/:service/:name.
+type PutRequest struct {
+	Description string  `json:"description,omitempty"`
+	GoodQuery   string  `json:"goodQuery"`
+	TotalQuery  string  `json:"totalQuery"`
+	Target      float64 `json:"target"`
+	WindowDays  int     `json:"windowDays,omitempty"` // defaults to 30
+}
+
+// Service manages SLOs in the alerting store.
+type Service struct {
+	DB adb.SLORepository
+	// Prom evaluates error budgets; nil when Prometheus is not configured.
+	Prom Querier
+	Now  func() time.Time
+}
+
+func NewService(db adb.SLORepository, prom Querier) *Service {
+	return &Service{DB: db, Prom: prom, Now: time.Now}
+}
+
+// Put validates and creates or replaces the SLO name of service.
+func (s *Service) Put(ctx context.Context, service, name string, req *PutRequest) (*SLO, error) {
+	service, name = strings.TrimSpace(service), strings.TrimSpace(name)
+	if service == "" {
+		return nil, fmt.Errorf("%w: service is required", ErrInvalid)
+	}
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: name %q must be 1-128 letters, digits, '_', '.' or '-'", ErrInvalid, name)
+	}
+	slo := &SLO{
+		Service:     service,
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		GoodQuery:   strings.TrimSpace(req.GoodQuery),
+		TotalQuery:  strings.TrimSpace(req.TotalQuery),
+		Target:      req.Target,
+		WindowDays:  req.WindowDays,
+	}
+	if slo.WindowDays == 0 {
+		slo.WindowDays = DefaultWindowDays
+	}
+	if err := slo.validate(); err != nil {
+		return nil, err
+	}
+
+	now := s.Now().UTC()
+	slo.CreatedAt, slo.UpdatedAt = now, now
+	if old, err := s.DB.GetSLO(ctx, service, name); err != nil {
+		return nil, err
+	} else if old != nil {
+		slo.CreatedAt = old.CreatedAt.UTC()
+	}
+	if err := s.DB.UpsertSLO(ctx, toRow(slo)); err != nil {
+		return nil, err
+	}
+	return slo, nil
+}
+
+func (slo *SLO) validate() error {
+	for _, q := range [][2]string{{"goodQuery", slo.GoodQuery}, {"totalQuery", slo.TotalQuery}} {
+		if !strings.Contains(q[1], WindowVar) {
+			return fmt.Errorf("%w: %s must use %s as the range of its range vectors", ErrInvalid, q[0], WindowVar)
+		}
+	}
+	if !(slo.Target > 0 && slo.Target < 1) {
+		return fmt.Errorf("%w: target must be between 0 and 1, e.g. 0.999", ErrInvalid)
+	}
+	if slo.WindowDays < minWindowDays || slo.WindowDays > maxWindowDays {
+		return fmt.Errorf("%w: windowDays must be between %d and %d", ErrInvalid, minWindowDays, maxWindowDays)
+	}
+	return nil
+}
+
+// List returns the SLOs of service, or of every service when it is "".
+func (s *Service) List(ctx context.Context, service string) ([]SLO, error) {
+	rows, err := s.DB.ListSLOs(ctx, strings.TrimSpace(service))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SLO, len(rows))
+	for i := range rows {
+		out[i] = fromRow(&rows[i])
+	}
+	return out, nil
+}
+
+// Delete removes an SLO.
+func (s *Service) Delete(ctx context.Context, service, name string) error {
+	ok, err := s.DB.DeleteSLO(ctx, service, name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func toRow(slo *SLO) *adb.SLO {
+	return &adb.SLO{
+		Service:     slo.Service,
+		Name:        slo.Name,
+		Description: slo.Description,
+		GoodQuery:   slo.GoodQuery,
+		TotalQuery:  slo.TotalQuery,
+		Target:      slo.Target,
+		WindowDays:  slo.WindowDays,
+		CreatedAt:   slo.CreatedAt,
+		UpdatedAt:   slo.UpdatedAt,
+	}
+}
+
+func fromRow(row *adb.SLO) SLO {
+	return SLO{
+		Service:     row.Service,
+		Name:        row.Name,
+		Description: row.Description,
+		GoodQuery:   row.GoodQuery,
+		TotalQuery:  row.TotalQuery,
+		Target:      row.Target,
+		WindowDays:  row.WindowDays,
+		CreatedAt:   row.CreatedAt.UTC(),
+		UpdatedAt:   row.UpdatedAt.UTC(),
+	}
+}
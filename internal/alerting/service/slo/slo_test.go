@@ -0,0 +1,147 @@
+package slo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/alerting/database/memory"
+)
+
+const (
+	goodQuery  = `sum(rate(http_requests_total{service="api",code!~"5.."}[$window]))`
+	totalQuery = `sum(rate(http_requests_total{service="api"}[$window]))`
+)
+
+func TestPutValidates(t *testing.T) {
+	s := NewService(memory.New(), nil)
+	valid := PutRequest{GoodQuery: goodQuery, TotalQuery: totalQuery, Target: 0.999}
+	for name, tc := range map[string]struct {
+		service, name string
+		mutate        func(r *PutRequest)
+	}{
+		"missing service":   {"", "availability", func(*PutRequest) {}},
+		"bad name":          {"api", "avail ability", func(*PutRequest) {}},
+		"no window in good": {"api", "availability", func(r *PutRequest) { r.GoodQuery = "sum(up)" }},
+		"target of 1":       {"api", "availability", func(r *PutRequest) { r.Target = 1 }},
+		"short window":      {"api", "availability", func(r *PutRequest) { r.WindowDays = 3 }},
+	} {
+		req := valid
+		tc.mutate(&req)
+		if _, err := s.Put(context.Background(), tc.service, tc.name, &req); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: err = %v, want ErrInvalid", name, err)
+		}
+	}
+
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Now = func() time.Time { return created }
+	got, err := s.Put(context.Background(), "api", "availability", &valid)
+	if err != nil || got.WindowDays != DefaultWindowDays {
+		t.Fatalf("put = %+v, %v", got, err)
+	}
+	s.Now = func() time.Time { return created.Add(time.Hour) }
+	valid.Target = 0.995
+	if got, err = s.Put(context.Background(), "api", "availability", &valid); err != nil {
+		t.Fatal(err)
+	}
+	if !got.CreatedAt.Equal(created) || !got.UpdatedAt.Equal(created.Add(time.Hour)) || got.Target != 0.995 {
+		t.Fatalf("replaced = %+v", got)
+	}
+	if err := s.Delete(context.Background(), "api", "latency"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("delete unknown = %v", err)
+	}
+}
+
+func TestGenerateRules(t *testing.T) {
+	file := GenerateRules([]SLO{{
+		Service: "api", Name: "availability", GoodQuery: goodQuery, TotalQuery: totalQuery, Target: 0.999, WindowDays: 30,
+	}})
+	if len(file.Groups) != 1 || file.Groups[0].Name != "slo:api:availability" {
+		t.Fatalf("groups = %+v", file.Groups)
+	}
+	rules := file.Groups[0].Rules
+	if len(rules) != len(recordWindows)+2 {
+		t.Fatalf("%d rules", len(rules))
+	}
+	rec := rules[0]
+	if rec.Record != "slo:sli_error:ratio_rate5m" || rec.Labels["slo"] != "availability" ||
+		rec.Expr != `1 - (sum(sum(rate(http_requests_total{service="api",code!~"5.."}[5m]))) / sum(sum(rate(http_requests_total{service="api"}[5m]))))` {
+		t.Fatalf("recording rule = %+v", rec)
+	}
+
+	page, ticket := rules[len(rules)-2], rules[len(rules)-1]
+	if page.Alert != AlertName || page.Labels["severity"] != "P1" || ticket.Labels["severity"] != "P2" {
+		t.Fatalf("alerts = %+v, %+v", page, ticket)
+	}
+	sel := `{service="api", slo="availability"}`
+	for _, want := range []string{
+		"slo:sli_error:ratio_rate1h" + sel + " > (14.4 * (1 - 0.999)) and slo:sli_error:ratio_rate5m" + sel,
+		"slo:sli_error:ratio_rate6h" + sel + " > (6 * (1 - 0.999))",
+	} {
+		if !strings.Contains(page.Expr, want) {
+			t.Errorf("page expr %q lacks %q", page.Expr, want)
+		}
+	}
+	for _, want := range []string{"ratio_rate1d" + sel + " > (3 * (1 - 0.999))", "ratio_rate3d" + sel + " > (1 * (1 - 0.999))"} {
+		if !strings.Contains(ticket.Expr, want) {
+			t.Errorf("ticket expr %q lacks %q", ticket.Expr, want)
+		}
+	}
+}
+
+type fakeQuerier struct {
+	values map[string]float64 // by query
+	err    error
+}
+
+func (f *fakeQuerier) QueryScalar(_ context.Context, query string, _ time.Time) (float64, bool, error) {
+	v, ok := f.values[query]
+	return v, ok, f.err
+}
+
+func TestBudgets(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	s := NewService(store, nil)
+	if _, err := s.Budgets(ctx, ""); !errors.Is(err, ErrNoPrometheus) {
+		t.Fatalf("budgets without prometheus = %v", err)
+	}
+	for _, name := range []string{"availability", "idle"} {
+		req := PutRequest{GoodQuery: strings.ReplaceAll(goodQuery, "api", name), TotalQuery: totalQuery, Target: 0.999}
+		if _, err := s.Put(ctx, "api", name, &req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ratio := "sum(" + strings.ReplaceAll(strings.ReplaceAll(goodQuery, "api", "availability"), WindowVar, "30d") +
+		") / sum(" + strings.ReplaceAll(totalQuery, WindowVar, "30d") + ")"
+	s.Prom = &fakeQuerier{values: map[string]float64{ratio: 0.99975}}
+
+	budgets, err := s.Budgets(ctx, "api")
+	if err != nil || len(budgets) != 2 {
+		t.Fatalf("budgets = %+v, %v", budgets, err)
+	}
+	b := budgets[0]
+	if b.SLO != "availability" || b.SLI == nil || b.Remaining == nil || *b.Remaining < 0.7499 || *b.Remaining > 0.7501 {
+		t.Fatalf("budget = %+v", b)
+	}
+	if idle := budgets[1]; idle.SLI != nil || idle.Remaining != nil || idle.Error != "" {
+		t.Fatalf("budget without events = %+v", idle)
+	}
+
+	snapshot, err := s.BudgetSnapshot(ctx, "api", "availability")
+	var decoded Budget
+	if err != nil || json.Unmarshal(snapshot, &decoded) != nil || decoded.Remaining == nil {
+		t.Fatalf("snapshot = %s, %v", snapshot, err)
+	}
+	if snapshot, err := s.BudgetSnapshot(ctx, "api", "unknown"); snapshot != nil || err != nil {
+		t.Fatalf("snapshot of unknown slo = %s, %v", snapshot, err)
+	}
+
+	s.Prom = &fakeQuerier{err: errors.New("connection refused")}
+	if budgets, err := s.Budgets(ctx, "api"); err != nil || !strings.Contains(budgets[0].Error, "connection refused") {
+		t.Fatalf("budgets with failing prometheus = %+v, %v", budgets, err)
+	}
+}
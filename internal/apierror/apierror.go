@@ -0,0 +1,146 @@
+// Package apierror is the error model shared by every HTTP handler: stable error
+// codes with their HTTP status, a single response body carrying the request ID, and
+// helpers that validate path, query, header and body parameters.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code is a stable, machine-readable error code. Clients switch on the code; the
+// message is for humans and may change.
+type Code string
+
+const (
+	InvalidParameter Code = "INVALID_PARAMETER" // malformed or missing input
+	Unauthorized     Code = "UNAUTHORIZED"      // missing or wrong credentials
+	Forbidden        Code = "FORBIDDEN"         // authenticated but not allowed
+	NotFound         Code = "NOT_FOUND"         // the addressed resource does not exist
+	Conflict         Code = "CONFLICT"          // duplicate, or the resource is in use
+	InvalidState     Code = "INVALID_STATE"     // the resource's state does not allow the operation
+	PayloadTooLarge  Code = "PAYLOAD_TOO_LARGE" // request body over the limit
+	RateLimited      Code = "RATE_LIMITED"      // too many requests from this caller
+	Internal         Code = "INTERNAL_ERROR"    // unexpected server failure
+	Upstream         Code = "UPSTREAM_ERROR"    // a backend such as Prometheus rejected the call
+	Unavailable      Code = "UNAVAILABLE"       // a dependency is not configured or is down
+)
+
+var statuses = map[Code]int{
+	InvalidParameter: http.StatusBadRequest,
+	Unauthorized:     http.StatusUnauthorized,
+	Forbidden:        http.StatusForbidden,
+	NotFound:         http.StatusNotFound,
+	Conflict:         http.StatusConflict,
+	InvalidState:     http.StatusConflict,
+	PayloadTooLarge:  http.StatusRequestEntityTooLarge,
+	RateLimited:      http.StatusTooManyRequests,
+	Internal:         http.StatusInternalServerError,
+	Upstream:         http.StatusBadGateway,
+	Unavailable:      http.StatusServiceUnavailable,
+}
+
+// Codes lists every code, for documentation.
+func Codes() []Code {
+	return []Code{InvalidParameter, Unauthorized, Forbidden, NotFound, Conflict, InvalidState,
+		PayloadTooLarge, RateLimited, Internal, Upstream, Unavailable}
+}
+
+// Status is the HTTP status of the code; unknown codes map to 500.
+func (c Code) Status() int {
+	if s, ok := statuses[c]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an error with a code. Message is returned to the client; Err is the
+// cause, kept for logs and errors.Is/As but never sent.
+type Error struct {
+	Code    Code
+	Message string
+	Details map[string]any
+	Err     error
+}
+
+// New returns an error with the given code and client-facing message.
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf is New with a formatted message.
+func Newf(code Code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap attaches a code to err. An empty message uses err's text, except for
+// Internal where the cause is not exposed.
+func Wrap(code Code, err error, message string) *Error {
+	if message == "" && code != Internal && err != nil {
+		message = err.Error()
+	}
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// Invalid reports a bad parameter; the field and the offending value go to details.
+func Invalid(field, value, message string) *Error {
+	d := map[string]any{"field": field}
+	if value != "" {
+		d["value"] = value
+	}
+	return &Error{Code: InvalidParameter, Message: message, Details: d}
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.Err != nil && e.Message != "" && e.Message != e.Err.Error():
+		return e.Message + ": " + e.Err.Error()
+	case e.Err != nil:
+		return e.Err.Error()
+	case e.Message != "":
+		return e.Message
+	}
+	return string(e.Code)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is matches another *Error with the same code, so errors.Is(err, &Error{Code: NotFound})
+// works without a sentinel per resource.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && t.Message == "" && t.Err == nil
+}
+
+// CodeOf returns the code of the first *Error in err's chain, or Internal.
+func CodeOf(err error) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return Internal
+}
+
+// Mapping translates a domain error, matched with errors.Is, into a code. Message
+// overrides the error text when set.
+type Mapping struct {
+	Target  error
+	Code    Code
+	Message string
+}
+
+// Translate returns err as an *Error using the first matching mapping. Errors that
+// already carry a code are returned as is; anything else becomes Internal.
+func Translate(err error, mappings []Mapping) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	for _, m := range mappings {
+		if errors.Is(err, m.Target) {
+			return Wrap(m.Code, err, m.Message)
+		}
+	}
+	return Wrap(Internal, err, "")
+}
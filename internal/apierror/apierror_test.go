@@ -0,0 +1,184 @@
+package apierror_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/apierror"
+)
+
+var errMissing = errors.New("store: row missing")
+
+func TestTranslate(t *testing.T) {
+	mappings := []apierror.Mapping{
+		{Target: errMissing, Code: apierror.NotFound, Message: "deployment not found"},
+	}
+	tests := []struct {
+		name    string
+		err     error
+		code    apierror.Code
+		message string
+	}{
+		{"mapped", fmt.Errorf("get: %w", errMissing), apierror.NotFound, "deployment not found"},
+		{"coded error kept", fmt.Errorf("ctx: %w", apierror.New(apierror.Conflict, "in use")), apierror.Conflict, "in use"},
+		{"unknown is internal", errors.New("connection reset"), apierror.Internal, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := apierror.Translate(tt.err, mappings)
+			if e.Code != tt.code || e.Message != tt.message {
+				t.Fatalf("Translate = %s %q, want %s %q", e.Code, e.Message, tt.code, tt.message)
+			}
+			if !errors.Is(e, &apierror.Error{Code: tt.code}) {
+				t.Errorf("errors.Is by code failed")
+			}
+			if apierror.CodeOf(e) != tt.code {
+				t.Errorf("CodeOf = %s", apierror.CodeOf(e))
+			}
+		})
+	}
+}
+
+func TestCodesHaveStatus(t *testing.T) {
+	for _, c := range apierror.Codes() {
+		if c != apierror.Internal && c.Status() == http.StatusInternalServerError {
+			t.Errorf("%s has no status", c)
+		}
+	}
+}
+
+type body struct {
+	Name string `json:"name" binding:"required"`
+	Size int    `json:"size"`
+}
+
+func (b *body) Validate() error {
+	if b.Size < 0 {
+		return errors.New("size must not be negative")
+	}
+	return nil
+}
+
+func TestParamsAndBody(t *testing.T) {
+	router := fox.New()
+	router.Use(apierror.RequestID)
+	router.POST("/items/:id", func(c *fox.Context) {
+		p := apierror.Params(c)
+		p.Path("id")
+		p.Int("limit", 10, 1, 100)
+		p.Bool("all", false)
+		p.Enum("state", "open", "open", "closed")
+		p.Header("X-Operator", c.Query("op") == "required")
+		if err := p.Err(); err != nil {
+			apierror.Write(c, err)
+			return
+		}
+		var b body
+		if err := apierror.BindJSON(c, &b, c.Query("optional") == "true"); err != nil {
+			apierror.Write(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name    string
+		query   string
+		body    string
+		status  int
+		field   string
+		message string
+	}{
+		{"ok", "?limit=5&state=CLOSED&all=true", `{"name":"a"}`, http.StatusNoContent, "", ""},
+		{"malformed int", "?limit=five", `{"name":"a"}`, http.StatusBadRequest, "limit", "limit must be an integer in 1-100"},
+		{"int out of range", "?limit=0", `{"name":"a"}`, http.StatusBadRequest, "limit", "limit must be an integer in 1-100"},
+		{"malformed bool", "?all=yes!", `{"name":"a"}`, http.StatusBadRequest, "all", "all must be true or false"},
+		{"unknown enum", "?state=pending", `{"name":"a"}`, http.StatusBadRequest, "state", "state must be one of open, closed"},
+		{"first error wins", "?limit=0&state=pending", `{"name":"a"}`, http.StatusBadRequest, "limit", "limit must be an integer in 1-100"},
+		{"required header", "?op=required", `{"name":"a"}`, http.StatusBadRequest, "X-Operator", "X-Operator header is required"},
+		{"missing body", "", "", http.StatusBadRequest, "body", "request body is required"},
+		// An absent optional body skips decoding, so binding tags do not apply.
+		{"optional body", "?optional=true", "", http.StatusNoContent, "", ""},
+		{"binding tag", "", `{"size":1}`, http.StatusBadRequest, "name", "name is required"},
+		{"validator", "", `{"name":"a","size":-1}`, http.StatusBadRequest, "", "size must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/items/1"+tt.query, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.status == http.StatusNoContent {
+				return
+			}
+			var resp apierror.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Code != apierror.InvalidParameter {
+				t.Errorf("code = %s", resp.Error.Code)
+			}
+			if tt.message != "" && resp.Error.Message != tt.message {
+				t.Errorf("message = %q, want %q", resp.Error.Message, tt.message)
+			}
+			if tt.field != "" && resp.Error.Details["field"] != tt.field {
+				t.Errorf("details = %v, want field %s", resp.Error.Details, tt.field)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	router := fox.New()
+	router.Use(apierror.RequestID)
+	router.GET("/fail", func(c *fox.Context) {
+		apierror.Write(c, errors.New("db password leaked"))
+	})
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{"kept", "abc-123", true},
+		{"generated", "", false},
+		{"replaced when not printable", "bad id\n", false},
+		{"replaced when too long", strings.Repeat("x", 200), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/fail", nil)
+			if tt.incoming != "" {
+				req.Header.Set(apierror.RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(apierror.RequestIDHeader)
+			if id == "" || (id == tt.incoming) != tt.keep {
+				t.Fatalf("%s = %q for incoming %q", apierror.RequestIDHeader, id, tt.incoming)
+			}
+			var resp apierror.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != http.StatusInternalServerError || resp.Error.Code != apierror.Internal {
+				t.Fatalf("got %d %s", w.Code, resp.Error.Code)
+			}
+			if resp.Error.RequestID != id {
+				t.Errorf("requestId = %q, header %q", resp.Error.RequestID, id)
+			}
+			if strings.Contains(resp.Error.Message, "password") {
+				t.Errorf("internal cause leaked: %q", resp.Error.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package apierror
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fox-gonic/fox"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds a caller-supplied request ID.
+const maxRequestIDLen = 128
+
+// Response is the body of every error response:
+//
+//	{"error":{"code":"NOT_FOUND","message":"deployment not found","requestId":"..."}}
+type Response struct {
+	Error Body `json:"error"`
+}
+
+// Body is the error object of Response.
+type Body struct {
+	Code      Code           `json:"code"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+	RequestID string         `json:"requestId,omitempty"`
+}
+
+type requestIDKey struct{}
+
+// RequestID is a middleware that keeps the caller's X-Request-ID, or generates one,
+// echoes it in the response header and stores it in the request context.
+func RequestID(c *fox.Context) {
+	id := c.GetHeader(RequestIDHeader)
+	if !validRequestID(id) {
+		id = uuid.NewString()
+	}
+	c.Writer.Header().Set(RequestIDHeader, id)
+	c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+	c.Next()
+}
+
+// validRequestID accepts printable ASCII only, so IDs are safe to log and echo.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// WithRequestID returns ctx carrying the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID set by the middleware, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Write sends err as a Response with the status of its code. Errors without a code
+// are internal: they are logged with the request ID and the client only sees a
+// generic message.
+func Write(c *fox.Context, err error) {
+	WriteError(c, Translate(err, nil))
+}
+
+// WriteError sends e as a Response with the status of its code.
+func WriteError(c *fox.Context, e *Error) {
+	reqID := RequestIDFromContext(c.Request.Context())
+	status := e.Code.Status()
+	msg := e.Message
+	if status >= http.StatusInternalServerError {
+		log.Error().Err(e).
+			Str("requestID", reqID).
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Str("code", string(e.Code)).
+			Msg("request failed")
+		if msg == "" {
+			msg = http.StatusText(status)
+		}
+	}
+	c.JSON(status, Response{Error: Body{Code: e.Code, Message: msg, Details: e.Details, RequestID: reqID}})
+}
@@ -0,0 +1,202 @@
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/fox-gonic/fox"
+	"github.com/go-playground/validator/v10"
+)
+
+// Validator is implemented by request bodies that check themselves after decoding.
+// Returned errors without a code are reported as INVALID_PARAMETER.
+type Validator interface {
+	Validate() error
+}
+
+// Params reads path, query and header parameters and keeps the first validation
+// error, so a handler can read everything and check once:
+//
+//	p := apierror.Params(c)
+//	id := p.Path("deployID")
+//	limit := p.Int("limit", 0, 1, 100)
+//	if p.Err() != nil {
+//		apierror.Write(c, p.Err())
+//		return
+//	}
+type ParamReader struct {
+	c   *fox.Context
+	err *Error
+}
+
+// Params returns a reader for the request's parameters.
+func Params(c *fox.Context) *ParamReader {
+	return &ParamReader{c: c}
+}
+
+// Err is the first validation error, or nil.
+func (p *ParamReader) Err() error {
+	if p.err == nil {
+		return nil
+	}
+	return p.err
+}
+
+func (p *ParamReader) fail(e *Error) {
+	if p.err == nil {
+		p.err = e
+	}
+}
+
+// Path returns a required path parameter.
+func (p *ParamReader) Path(name string) string {
+	v := strings.TrimSpace(p.c.Param(name))
+	if v == "" {
+		p.fail(Invalid(name, "", name+" is required"))
+	}
+	return v
+}
+
+// Header returns a header; required headers must be non-blank.
+func (p *ParamReader) Header(name string, required bool) string {
+	v := strings.TrimSpace(p.c.GetHeader(name))
+	if v == "" && required {
+		p.fail(Invalid(name, "", name+" header is required"))
+	}
+	return v
+}
+
+// String returns a trimmed query parameter; required ones must be non-blank.
+func (p *ParamReader) String(name string, required bool) string {
+	v := strings.TrimSpace(p.c.Query(name))
+	if v == "" && required {
+		p.fail(Invalid(name, "", name+" is required"))
+	}
+	return v
+}
+
+// Int returns an integer query parameter in [min, max], or def when absent.
+func (p *ParamReader) Int(name string, def, min, max int) int {
+	return int(p.Int64(name, int64(def), int64(min), int64(max)))
+}
+
+// Int64 returns an integer query parameter in [min, max], or def when absent.
+func (p *ParamReader) Int64(name string, def, min, max int64) int64 {
+	raw := strings.TrimSpace(p.c.Query(name))
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || n < min || n > max {
+		p.fail(Invalid(name, raw, rangeMessage(name, min, max)))
+		return def
+	}
+	return n
+}
+
+func rangeMessage(name string, min, max int64) string {
+	const unbounded = 1<<31 - 1
+	switch {
+	case max >= unbounded && min == 0:
+		return name + " must be a non-negative integer"
+	case max >= unbounded && min == 1:
+		return name + " must be a positive integer"
+	case max >= unbounded:
+		return fmt.Sprintf("%s must be an integer >= %d", name, min)
+	}
+	return fmt.Sprintf("%s must be an integer in %d-%d", name, min, max)
+}
+
+// Bool returns a boolean query parameter, or def when absent.
+func (p *ParamReader) Bool(name string, def bool) bool {
+	raw := strings.TrimSpace(p.c.Query(name))
+	if raw == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		p.fail(Invalid(name, raw, name+" must be true or false"))
+		return def
+	}
+	return b
+}
+
+// Enum returns a query parameter that must be one of allowed (case-insensitive),
+// normalised to the allowed spelling, or def when absent.
+func (p *ParamReader) Enum(name, def string, allowed ...string) string {
+	raw := strings.TrimSpace(p.c.Query(name))
+	if raw == "" {
+		return def
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(raw, a) {
+			return a
+		}
+	}
+	p.fail(Invalid(name, raw, name+" must be one of "+strings.Join(allowed, ", ")))
+	return def
+}
+
+// BindJSON decodes the JSON body into v and runs v.Validate when v is a Validator.
+// An empty body is an error unless optional is set.
+func BindJSON(c *fox.Context, v any, optional bool) error {
+	if c.Request.ContentLength == 0 && optional {
+		return validate(v)
+	}
+	if err := c.ShouldBindJSON(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return Invalid("body", "", "request body is required")
+		}
+		var fields validator.ValidationErrors
+		if errors.As(err, &fields) && len(fields) > 0 {
+			f := fields[0]
+			name := jsonName(v, f.StructField())
+			if f.Tag() == "required" {
+				return Invalid(name, "", name+" is required")
+			}
+			return Invalid(name, fmt.Sprint(f.Value()), fmt.Sprintf("%s failed %q validation", name, f.Tag()))
+		}
+		return Wrap(InvalidParameter, err, "invalid request body: "+err.Error())
+	}
+	return validate(v)
+}
+
+// jsonName returns the JSON key of a top-level struct field so messages name
+// the field the client sent rather than the Go identifier.
+func jsonName(v any, field string) string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return field
+	}
+	sf, ok := t.FieldByName(field)
+	if !ok {
+		return field
+	}
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field
+}
+
+func validate(v any) error {
+	val, ok := v.(Validator)
+	if !ok {
+		return nil
+	}
+	err := val.Validate()
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Wrap(InvalidParameter, err, "")
+}
@@ -6,17 +6,30 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 )
 
 type Config struct {
-	Server   ServerConfig   `json:"server"`
-	Database DatabaseConfig `json:"database"`
+	Server     ServerConfig     `json:"server"`
+	Database   DatabaseConfig   `json:"database"`
+	Prometheus PrometheusConfig `json:"prometheus"`
+	Instance   InstanceConfig   `json:"instance"`
+	Version    VersionConfig    `json:"version"`
+	Telemetry  TelemetryConfig  `json:"telemetry"`
+	Enrichment EnrichmentConfig `json:"enrichment"`
+	Anomaly    AnomalyConfig    `json:"anomaly"`
+	Analysis   AnalysisConfig   `json:"analysis"`
+	Analytics  AnalyticsConfig  `json:"analytics"`
+	Archive    ArchiveConfig    `json:"archive"`
+	Webhook    WebhookConfig    `json:"webhook"`
 }
 
 type ServerConfig struct {
 	BindAddr string `json:"bindAddr"`
+	// ShutdownTimeoutSeconds bounds draining HTTP and stopping each background loop on SIGTERM
+	ShutdownTimeoutSeconds int `json:"shutdownTimeoutSeconds"`
 }
 
 type DatabaseConfig struct {
@@ -26,28 +39,260 @@ type DatabaseConfig struct {
 	Password string `json:"password"`
 	DBName   string `json:"dbname"`
 	SSLMode  string `json:"sslmode"`
+	// AutoMigrate applies pending schema migrations at startup instead of refusing to start.
+	AutoMigrate bool `json:"autoMigrate"`
+	// Pool limits for the shared connection pool; zero keeps the database/sql default.
+	MaxOpenConns           int `json:"maxOpenConns"`
+	MaxIdleConns           int `json:"maxIdleConns"`
+	ConnMaxLifetimeSeconds int `json:"connMaxLifetimeSeconds"`
+	ConnMaxIdleTimeSeconds int `json:"connMaxIdleTimeSeconds"`
+}
+
+// PrometheusConfig configures the Prometheus HTTP API used for metric time series.
+// An empty URL disables metric queries.
+type PrometheusConfig struct {
+	URL             string `json:"url"`
+	TimeoutSeconds  int    `json:"timeoutSeconds"`
+	CacheTTLSeconds int    `json:"cacheTTLSeconds"`
+}
+
+// InstanceConfig configures instance liveness tracking and the optional Consul catalog sync.
+// An empty ConsulAddr disables the sync.
+type InstanceConfig struct {
+	HeartbeatTimeoutSeconds   int    `json:"heartbeatTimeoutSeconds"`
+	ReapIntervalSeconds       int    `json:"reapIntervalSeconds"`
+	ConsulAddr                string `json:"consulAddr"`
+	ConsulSyncIntervalSeconds int    `json:"consulSyncIntervalSeconds"`
+}
+
+// VersionConfig configures the version registry. The package URL of a new version is
+// only requested, to check the package exists, when PackageHosts lists its host; with
+// none listed only the URL format is checked, so callers cannot make the server probe
+// internal hosts.
+type VersionConfig struct {
+	PackageHosts []string `json:"packageHosts"`
+}
+
+// TelemetryConfig configures zeroops' own metrics and traces. Metrics are always served
+// on /metrics; traces are exported over OTLP/HTTP only when OTLPEndpoint is set.
+type TelemetryConfig struct {
+	ServiceName   string  `json:"serviceName"`
+	Environment   string  `json:"environment"`
+	OTLPEndpoint  string  `json:"otlpEndpoint"`
+	SamplingRatio float64 `json:"samplingRatio"`
+}
+
+// EnrichmentConfig configures how the alert receiver rewrites and annotates alerts before
+// they become issues. Relabel rules and runbooks are only read from the config file.
+type EnrichmentConfig struct {
+	// RelabelConfigs are applied in order, with Prometheus relabel_config semantics.
+	RelabelConfigs []RelabelConfig `json:"relabelConfigs"`
+	// ServiceLabels name the labels looked up in the instance catalog, in order, when an
+	// alert has no service label.
+	ServiceLabels []string `json:"serviceLabels"`
+	// Runbooks maps an alertname to a runbook URL template; "*" applies to any other alert.
+	// Templates see the alert labels as .Labels.
+	Runbooks map[string]string `json:"runbooks"`
+	// CatalogCacheSeconds is how long service_manager lookups are reused.
+	CatalogCacheSeconds int `json:"catalogCacheSeconds"`
+}
+
+// RelabelConfig is one Prometheus relabel_config rule. Action is replace (default), keep,
+// drop, labelmap, labeldrop or labelkeep.
+type RelabelConfig struct {
+	SourceLabels []string `json:"sourceLabels"`
+	Separator    string   `json:"separator"`
+	Regex        string   `json:"regex"`
+	TargetLabel  string   `json:"targetLabel"`
+	Replacement  *string  `json:"replacement"`
+	Action       string   `json:"action"`
+}
+
+// AnomalyConfig configures the built-in anomaly detector. Series are only read from the
+// config file; a service is checked for a series once detector parameters are set for it.
+type AnomalyConfig struct {
+	IntervalSeconds int `json:"intervalSeconds"`
+	// StepSeconds is the resolution the series are queried and scored at.
+	StepSeconds int             `json:"stepSeconds"`
+	Series      []AnomalySeries `json:"series"`
+}
+
+// AnomalySeries is a PromQL query returning one series per service, with $service
+// standing for the service name, e.g. sum(rate(http_requests_total{service="$service"}[5m])).
+type AnomalySeries struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// AnalyticsConfig configures the daily issue rollups behind /v1/analytics. Every
+// IntervalSeconds the RefreshDays days before today are rolled up again, so acks and
+// restores after an issue's day are counted; older days are backfilled through the API.
+type AnalyticsConfig struct {
+	IntervalSeconds int `json:"intervalSeconds"`
+	RefreshDays     int `json:"refreshDays"`
+}
+
+// ArchiveConfig configures the retention of closed issues in Postgres. Every
+// IntervalSeconds the closed issues whose alert started more than the retention of their
+// level ago are moved to Target together with their comments, history and report, and the
+// monthly partitions of alert_issues are maintained. 0 days keeps issues forever.
+type ArchiveConfig struct {
+	IntervalSeconds int `json:"intervalSeconds"`
+	// RetentionDays applies to the levels missing from LevelRetentionDays.
+	RetentionDays      int            `json:"retentionDays"`
+	LevelRetentionDays map[string]int `json:"levelRetentionDays"`
+	// Target is table (alert_issue_archive), dir (gzip-compressed JSON lines in Dir) or
+	// s3 (the same files in S3Bucket of the MockS3 storage service at S3Endpoint).
+	Target     string `json:"target"`
+	Dir        string `json:"dir"`
+	S3Endpoint string `json:"s3Endpoint"`
+	S3Bucket   string `json:"s3Bucket"`
+	// BatchSize is the number of issues moved per transaction.
+	BatchSize int `json:"batchSize"`
+}
+
+// WebhookConfig configures the checks of the Alertmanager webhook beyond its credentials.
+// A zero RateLimit disables rate limiting.
+type WebhookConfig struct {
+	// MaxBodyBytes is the largest accepted request body.
+	MaxBodyBytes int64 `json:"maxBodyBytes"`
+	// ReplayWindowSeconds is how far the timestamp of a signed request may be from now.
+	ReplayWindowSeconds int `json:"replayWindowSeconds"`
+	// RateLimit is the requests per second allowed per integration, and the failed
+	// attempts per second per remote address, with bursts of RateBurst.
+	RateLimit float64 `json:"rateLimit"`
+	RateBurst int     `json:"rateBurst"`
+	// CredentialCacheSeconds is how long credentials read from the database are reused,
+	// bounding how long a revoked credential keeps working.
+	CredentialCacheSeconds int `json:"credentialCacheSeconds"`
+	// AdminTokens maps operator names to the bearer tokens allowed to manage the
+	// credentials and read the audit log. Without any those routes reject every request.
+	AdminTokens map[string]string `json:"adminTokens"`
+}
+
+// AnalysisConfig configures the AI root-cause analysis written as a comment when an issue
+// enters InProcessing. An empty LLMBaseURL disables it; an empty MCP URL skips that
+// context source. Metric queries are only read from the config file.
+type AnalysisConfig struct {
+	// LLMBaseURL is an OpenAI-compatible API, e.g. https://api.openai.com/v1.
+	LLMBaseURL string `json:"llmBaseURL"`
+	LLMAPIKey  string `json:"llmAPIKey"`
+	LLMModel   string `json:"llmModel"`
+	// PrometheusMCPURL and ElasticsearchMCPURL are the streamable HTTP endpoints of the
+	// mcp-server tools, e.g. http://127.0.0.1:8090/prometheus.
+	PrometheusMCPURL    string `json:"prometheusMCPURL"`
+	PrometheusRegion    string `json:"prometheusRegion"`
+	ElasticsearchMCPURL string `json:"elasticsearchMCPURL"`
+	// MetricQueries are PromQL queries with $service standing for the service name.
+	MetricQueries []AnalysisQuery `json:"metricQueries"`
+	// LookbackMinutes is how long before the alert logs and deployments are looked at.
+	LookbackMinutes int `json:"lookbackMinutes"`
+	TimeoutSeconds  int `json:"timeoutSeconds"`
+}
+
+// AnalysisQuery is one metric given to the analysis.
+type AnalysisQuery struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
 }
 
 func Load() (*Config, error) {
 	configFile := flag.String("f", "", "Path to configuration file")
 	flag.Parse()
 
+	return LoadFile(*configFile)
+}
+
+// LoadFile builds the config from environment variables, overridden by the JSON file
+// at filePath when it is not empty.
+func LoadFile(filePath string) (*Config, error) {
 	cfg := &Config{
 		Server: ServerConfig{
-			BindAddr: getEnv("SERVER_BIND_ADDR", "0.0.0.0:8080"),
+			BindAddr:               getEnv("SERVER_BIND_ADDR", "0.0.0.0:8080"),
+			ShutdownTimeoutSeconds: getEnvInt("SERVER_SHUTDOWN_TIMEOUT_SECONDS", 30),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getEnvInt("DB_PORT", 5432),
-			User:     getEnv("DB_USER", "admin"),
-			Password: getEnv("DB_PASSWORD", "password"),
-			DBName:   getEnv("DB_NAME", "zeroops"),
-			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+			Host:        getEnv("DB_HOST", "localhost"),
+			Port:        getEnvInt("DB_PORT", 5432),
+			User:        getEnv("DB_USER", "admin"),
+			Password:    getEnv("DB_PASSWORD", "password"),
+			DBName:      getEnv("DB_NAME", "zeroops"),
+			SSLMode:     getEnv("DB_SSLMODE", "disable"),
+			AutoMigrate: getEnv("DB_AUTO_MIGRATE", "false") == "true",
+
+			MaxOpenConns:           getEnvInt("DB_MAX_OPEN_CONNS", 20),
+			MaxIdleConns:           getEnvInt("DB_MAX_IDLE_CONNS", 10),
+			ConnMaxLifetimeSeconds: getEnvInt("DB_CONN_MAX_LIFETIME_SECONDS", 1800),
+			ConnMaxIdleTimeSeconds: getEnvInt("DB_CONN_MAX_IDLE_TIME_SECONDS", 300),
+		},
+		Prometheus: PrometheusConfig{
+			URL:             getEnv("PROMETHEUS_URL", ""),
+			TimeoutSeconds:  getEnvInt("PROMETHEUS_TIMEOUT_SECONDS", 10),
+			CacheTTLSeconds: getEnvInt("PROMETHEUS_CACHE_TTL_SECONDS", 15),
+		},
+		Instance: InstanceConfig{
+			HeartbeatTimeoutSeconds:   getEnvInt("INSTANCE_HEARTBEAT_TIMEOUT_SECONDS", 90),
+			ReapIntervalSeconds:       getEnvInt("INSTANCE_REAP_INTERVAL_SECONDS", 30),
+			ConsulAddr:                getEnv("CONSUL_ADDR", ""),
+			ConsulSyncIntervalSeconds: getEnvInt("CONSUL_SYNC_INTERVAL_SECONDS", 30),
+		},
+		Version: VersionConfig{
+			PackageHosts: getEnvList("VERSION_PACKAGE_HOSTS"),
+		},
+		Telemetry: TelemetryConfig{
+			ServiceName:   getEnv("OTEL_SERVICE_NAME", "zeroops"),
+			Environment:   getEnv("OTEL_ENVIRONMENT", "development"),
+			OTLPEndpoint:  getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			SamplingRatio: getEnvFloat("OTEL_SAMPLING_RATIO", 1.0),
+		},
+		Enrichment: EnrichmentConfig{
+			ServiceLabels:       []string{"instance", "host_id"},
+			CatalogCacheSeconds: getEnvInt("ALERT_CATALOG_CACHE_SECONDS", 30),
+		},
+		Anomaly: AnomalyConfig{
+			IntervalSeconds: getEnvInt("ALERT_ANOMALY_INTERVAL_SECONDS", 60),
+			StepSeconds:     getEnvInt("ALERT_ANOMALY_STEP_SECONDS", 60),
+		},
+		Analysis: AnalysisConfig{
+			LLMBaseURL:          getEnv("ANALYSIS_LLM_BASE_URL", ""),
+			LLMAPIKey:           getEnv("ANALYSIS_LLM_API_KEY", ""),
+			LLMModel:            getEnv("ANALYSIS_LLM_MODEL", "gpt-4o-mini"),
+			PrometheusMCPURL:    getEnv("ANALYSIS_PROMETHEUS_MCP_URL", ""),
+			PrometheusRegion:    getEnv("ANALYSIS_PROMETHEUS_REGION", "mock"),
+			ElasticsearchMCPURL: getEnv("ANALYSIS_ELASTICSEARCH_MCP_URL", ""),
+			LookbackMinutes:     getEnvInt("ANALYSIS_LOOKBACK_MINUTES", 30),
+			TimeoutSeconds:      getEnvInt("ANALYSIS_TIMEOUT_SECONDS", 60),
+		},
+		Analytics: AnalyticsConfig{
+			IntervalSeconds: getEnvInt("ALERT_ANALYTICS_INTERVAL_SECONDS", 300),
+			RefreshDays:     getEnvInt("ALERT_ANALYTICS_REFRESH_DAYS", 7),
+		},
+		Archive: ArchiveConfig{
+			IntervalSeconds:    getEnvInt("ALERT_ARCHIVE_INTERVAL_SECONDS", 3600),
+			RetentionDays:      getEnvInt("ALERT_RETENTION_DAYS", 0),
+			LevelRetentionDays: levelRetentionDays(),
+			Target:             getEnv("ALERT_ARCHIVE_TARGET", "table"),
+			Dir:                getEnv("ALERT_ARCHIVE_DIR", "./archive"),
+			S3Endpoint:         getEnv("ALERT_ARCHIVE_S3_ENDPOINT", ""),
+			S3Bucket:           getEnv("ALERT_ARCHIVE_S3_BUCKET", "zeroops-archive"),
+			BatchSize:          getEnvInt("ALERT_ARCHIVE_BATCH_SIZE", 100),
+		},
+		Webhook: WebhookConfig{
+			MaxBodyBytes:           int64(getEnvInt("ALERT_WEBHOOK_MAX_BODY_BYTES", 1<<20)),
+			ReplayWindowSeconds:    getEnvInt("ALERT_WEBHOOK_REPLAY_WINDOW_SECONDS", 300),
+			RateLimit:              getEnvFloat("ALERT_WEBHOOK_RATE_LIMIT", 20),
+			RateBurst:              getEnvInt("ALERT_WEBHOOK_RATE_BURST", 100),
+			CredentialCacheSeconds: getEnvInt("ALERT_WEBHOOK_CREDENTIAL_CACHE_SECONDS", 10),
 		},
 	}
+	admins, err := ParseAdminTokens(os.Getenv("ALERT_WEBHOOK_ADMIN_TOKENS"))
+	if err != nil {
+		return nil, fmt.Errorf("ALERT_WEBHOOK_ADMIN_TOKENS: %w", err)
+	}
+	cfg.Webhook.AdminTokens = admins
 
-	if *configFile != "" {
-		if err := loadFromFile(cfg, *configFile); err != nil {
+	if filePath != "" {
+		if err := loadFromFile(cfg, filePath); err != nil {
 			log.Err(err)
 			return nil, err
 		}
@@ -84,3 +329,54 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvList reads a comma-separated list, nil when unset.
+func getEnvList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// levelRetentionDays reads ALERT_RETENTION_DAYS_<LEVEL> of the issue levels that set it.
+func levelRetentionDays() map[string]int {
+	days := make(map[string]int)
+	for _, level := range []string{"P0", "P1", "P2", "Warning"} {
+		if v := getEnvInt("ALERT_RETENTION_DAYS_"+strings.ToUpper(level), -1); v >= 0 {
+			days[level] = v
+		}
+	}
+	return days
+}
+
+// ParseAdminTokens parses comma-separated name:token pairs, e.g. "alice:<token>,bob:<token>".
+func ParseAdminTokens(s string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, token, ok := strings.Cut(pair, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q is not name:token", pair)
+		}
+		if _, dup := tokens[name]; dup {
+			return nil, fmt.Errorf("%s is listed twice", name)
+		}
+		tokens[name] = token
+	}
+	return tokens, nil
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
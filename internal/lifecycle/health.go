@@ -0,0 +1,81 @@
+package lifecycle
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fox-gonic/fox"
+)
+
+// checkTimeout bounds each dependency probe so a hung backend fails the check instead
+// of the probe request.
+const checkTimeout = 2 * time.Second
+
+type check struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// AddCheck registers a dependency probe reported by /healthz and /readyz.
+func (m *Manager) AddCheck(name string, fn func(ctx context.Context) error) {
+	m.checks = append(m.checks, check{name: name, fn: fn})
+}
+
+// runChecks probes every dependency concurrently and returns "ok" or the error text
+// per check.
+func (m *Manager) runChecks(ctx context.Context) (map[string]string, bool) {
+	results := make(map[string]string, len(m.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	healthy := true
+	for _, c := range m.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			status := "ok"
+			if err := c.fn(ctx); err != nil {
+				status = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results[c.name] = status
+			if status != "ok" {
+				healthy = false
+			}
+		}(c)
+	}
+	wg.Wait()
+	return results, healthy
+}
+
+// RegisterHealthRoutes mounts GET /healthz and GET /readyz. Both probe the registered
+// dependencies and answer 503 when one fails; /readyz additionally fails before
+// startup completes and once shutdown begins, so load balancers stop routing to a
+// draining instance.
+func (m *Manager) RegisterHealthRoutes(router *fox.Engine) {
+	router.GET("/healthz", func(c *fox.Context) {
+		m.writeHealth(c, true)
+	})
+	router.GET("/readyz", func(c *fox.Context) {
+		m.writeHealth(c, m.Ready())
+	})
+}
+
+func (m *Manager) writeHealth(c *fox.Context, ready bool) {
+	checks, healthy := m.runChecks(c.Request.Context())
+	status, code := "ok", http.StatusOK
+	switch {
+	case !healthy:
+		status, code = "unavailable", http.StatusServiceUnavailable
+	case !ready:
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+	c.JSON(code, map[string]any{
+		"status": status,
+		"checks": checks,
+	})
+}
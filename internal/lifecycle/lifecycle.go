@@ -0,0 +1,269 @@
+// Package lifecycle starts the server's components in order and stops them in reverse
+// order on shutdown, so HTTP is drained before the loops feeding it stop and the
+// clients they use are closed last.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultStopTimeout bounds the stop of each component when Manager.StopTimeout is zero.
+const DefaultStopTimeout = 30 * time.Second
+
+// Component is one unit of the process lifecycle. Start must not block; long running
+// work belongs in a goroutine that Stop waits for.
+type Component interface {
+	Name() string
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// failer is implemented by components that can fail after Start returned, e.g. an
+// HTTP server whose Serve loop exits. The first error triggers shutdown.
+type failer interface {
+	Err() <-chan error
+}
+
+// Manager owns the ordered component list and the health checks.
+type Manager struct {
+	// StopTimeout bounds the stop of each component, so an HTTP drain that times out
+	// does not leave the loops stopped after it without time to exit.
+	StopTimeout time.Duration
+
+	components []Component
+	checks     []check
+	ready      atomic.Bool
+}
+
+func New(stopTimeout time.Duration) *Manager {
+	return &Manager{StopTimeout: stopTimeout}
+}
+
+// Append adds components in start order.
+func (m *Manager) Append(components ...Component) {
+	m.components = append(m.components, components...)
+}
+
+// Run starts every component and blocks until ctx is done or a component fails, then
+// stops the started components in reverse order, each within StopTimeout. A start
+// failure stops what was already started and is returned.
+func (m *Manager) Run(ctx context.Context) error {
+	failed := make(chan error, 1)
+	var started []Component
+	for _, c := range m.components {
+		if err := c.Start(ctx); err != nil {
+			m.stop(started)
+			return fmt.Errorf("start %s: %w", c.Name(), err)
+		}
+		log.Info().Str("component", c.Name()).Msg("component started")
+		started = append(started, c)
+		if f, ok := c.(failer); ok {
+			go forwardErr(c.Name(), f.Err(), failed)
+		}
+	}
+	m.ready.Store(true)
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		log.Info().Msg("shutdown requested")
+	case runErr = <-failed:
+		log.Error().Err(runErr).Msg("component failed, shutting down")
+	}
+	if err := m.stop(started); err != nil && runErr == nil {
+		runErr = err
+	}
+	return runErr
+}
+
+// Ready reports whether all components started and shutdown has not begun.
+func (m *Manager) Ready() bool { return m.ready.Load() }
+
+func (m *Manager) stop(started []Component) error {
+	m.ready.Store(false)
+	timeout := m.StopTimeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := c.Stop(ctx)
+		cancel()
+		if err != nil {
+			log.Error().Err(err).Str("component", c.Name()).Msg("component stop failed")
+			errs = append(errs, fmt.Errorf("stop %s: %w", c.Name(), err))
+			continue
+		}
+		log.Info().Str("component", c.Name()).Msg("component stopped")
+	}
+	return errors.Join(errs...)
+}
+
+func forwardErr(name string, src <-chan error, dst chan<- error) {
+	if err, ok := <-src; ok && err != nil {
+		select {
+		case dst <- fmt.Errorf("%s: %w", name, err):
+		default:
+		}
+	}
+}
+
+// ===== component adapters =====
+
+type hook struct {
+	name  string
+	start func(ctx context.Context) error
+	stop  func(ctx context.Context) error
+}
+
+// Hook builds a component from start and stop functions; either may be nil.
+func Hook(name string, start, stop func(ctx context.Context) error) Component {
+	return &hook{name: name, start: start, stop: stop}
+}
+
+func (h *hook) Name() string { return h.name }
+
+func (h *hook) Start(ctx context.Context) error {
+	if h.start == nil {
+		return nil
+	}
+	return h.start(ctx)
+}
+
+func (h *hook) Stop(ctx context.Context) error {
+	if h.stop == nil {
+		return nil
+	}
+	return h.stop(ctx)
+}
+
+// Closer closes c on shutdown. Register clients first so they are closed last.
+func Closer(name string, c io.Closer) Component {
+	return Hook(name, nil, func(context.Context) error { return c.Close() })
+}
+
+type loop struct {
+	name   string
+	run    func(ctx context.Context)
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Loop runs fn in n goroutines until Stop cancels their context, and waits for them
+// to return. fn should finish the unit of work in hand before honouring cancellation.
+func Loop(name string, n int, fn func(ctx context.Context)) Component {
+	if n < 1 {
+		n = 1
+	}
+	l := &loop{name: name}
+	l.run = func(ctx context.Context) {
+		for i := 0; i < n; i++ {
+			l.wg.Add(1)
+			go func() {
+				defer l.wg.Done()
+				fn(ctx)
+			}()
+		}
+	}
+	return l
+}
+
+func (l *loop) Name() string { return l.name }
+
+// Start detaches from ctx: the loop lives until Stop, not until the start context ends.
+func (l *loop) Start(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	l.cancel = cancel
+	l.run(runCtx)
+	return nil
+}
+
+func (l *loop) Stop(ctx context.Context) error {
+	l.cancel()
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("loop did not exit: %w", ctx.Err())
+	}
+}
+
+type httpServer struct {
+	srv *http.Server
+	err chan error
+}
+
+type shutdownKey struct{}
+
+// ShuttingDown returns a channel that is closed when the HTTP server serving the request
+// with ctx begins to shut down. Shutdown waits for every handler, so long-lived ones such
+// as event streams must return once it is closed. It is nil, and blocks forever, for
+// requests of other servers.
+func ShuttingDown(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(shutdownKey{}).(<-chan struct{})
+	return ch
+}
+
+// HTTPServer listens on srv.Addr at Start, so bind errors fail startup, and drains
+// in-flight requests with Shutdown at Stop. Requests can watch for the shutdown with
+// ShuttingDown.
+func HTTPServer(srv *http.Server) Component {
+	shutdown := make(chan struct{})
+	base := srv.BaseContext
+	srv.BaseContext = func(ln net.Listener) context.Context {
+		ctx := context.Background()
+		if base != nil {
+			ctx = base(ln)
+		}
+		return context.WithValue(ctx, shutdownKey{}, (<-chan struct{})(shutdown))
+	}
+	var once sync.Once
+	srv.RegisterOnShutdown(func() { once.Do(func() { close(shutdown) }) })
+	return &httpServer{srv: srv, err: make(chan error, 1)}
+}
+
+func (h *httpServer) Name() string { return "http" }
+
+func (h *httpServer) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", h.srv.Addr)
+	if err != nil {
+		return err
+	}
+	log.Info().Msgf("Starting server on %s", ln.Addr())
+	go func() {
+		if err := h.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			h.err <- err
+		}
+		close(h.err)
+	}()
+	return nil
+}
+
+func (h *httpServer) Stop(ctx context.Context) error {
+	if err := h.srv.Shutdown(ctx); err != nil {
+		// drain timed out; drop the remaining connections
+		_ = h.srv.Close()
+		return err
+	}
+	return nil
+}
+
+func (h *httpServer) Err() <-chan error { return h.err }
@@ -0,0 +1,224 @@
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fox-gonic/fox"
+)
+
+func recorder(name string, events *[]string, startErr error) Component {
+	return Hook(name,
+		func(context.Context) error {
+			*events = append(*events, "start "+name)
+			return startErr
+		},
+		func(context.Context) error {
+			*events = append(*events, "stop "+name)
+			return nil
+		})
+}
+
+func TestRunStopsInReverseOrder(t *testing.T) {
+	var events []string
+	m := New(time.Second)
+	m.Append(recorder("db", &events, nil), recorder("loop", &events, nil), recorder("http", &events, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := m.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"start db", "start loop", "start http", "stop http", "stop loop", "stop db"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("got %v, want %v", events, want)
+	}
+	if m.Ready() {
+		t.Fatal("expected not ready after shutdown")
+	}
+}
+
+func TestRunStartFailureStopsStarted(t *testing.T) {
+	var events []string
+	m := New(time.Second)
+	m.Append(recorder("db", &events, nil), recorder("http", &events, errors.New("bind")), recorder("never", &events, nil))
+
+	if err := m.Run(context.Background()); err == nil {
+		t.Fatal("expected start error")
+	}
+	want := []string{"start db", "start http", "stop db"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("got %v, want %v", events, want)
+	}
+}
+
+func TestLoopStopWaitsForExit(t *testing.T) {
+	exited := make(chan struct{}, 2)
+	l := Loop("worker", 2, func(ctx context.Context) {
+		<-ctx.Done()
+		exited <- struct{}{}
+	})
+	if err := l.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(exited) != 2 {
+		t.Fatalf("expected both workers to exit before Stop returned, got %d", len(exited))
+	}
+
+	stuck := Loop("stuck", 1, func(ctx context.Context) { select {} })
+	_ = stuck.Start(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := stuck.Stop(ctx); err == nil {
+		t.Fatal("expected timeout stopping a loop that ignores cancellation")
+	}
+}
+
+func TestStopGivesEachComponentItsBudget(t *testing.T) {
+	// the first component to stop uses up its whole budget
+	slow := Hook("slow", nil, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	exited := make(chan struct{})
+	worker := Loop("worker", 1, func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond) // finishing the work in hand
+		close(exited)
+	})
+	m := New(50 * time.Millisecond)
+	m.Append(worker, slow)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := m.Run(ctx)
+	if err == nil || !strings.Contains(err.Error(), "stop slow") || strings.Contains(err.Error(), "stop worker") {
+		t.Fatalf("expected only slow to fail, got %v", err)
+	}
+	select {
+	case <-exited:
+	default:
+		t.Fatal("expected the loop to exit before Run returned")
+	}
+}
+
+func TestStopEndsOpenStreams(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	streaming := make(chan struct{})
+	streamDone := make(chan struct{})
+	srv := &http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer close(streamDone)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		close(streaming)
+		// like the SSE handlers: ends with the client or the shutdown
+		select {
+		case <-r.Context().Done():
+		case <-ShuttingDown(r.Context()):
+		}
+	})}
+	loopExited := make(chan struct{})
+	m := New(5 * time.Second)
+	m.Append(Loop("worker", 1, func(ctx context.Context) {
+		<-ctx.Done()
+		close(loopExited)
+	}), HTTPServer(srv))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- m.Run(ctx) }()
+
+	var resp *http.Response
+	for i := 0; ; i++ {
+		if resp, err = http.Get("http://" + addr); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer resp.Body.Close()
+	<-streaming
+
+	start := time.Now()
+	cancel()
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Fatalf("expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("shutdown waited for the open stream")
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("shutdown took %s", time.Since(start))
+	}
+	for name, ch := range map[string]chan struct{}{"stream": streamDone, "loop": loopExited} {
+		select {
+		case <-ch:
+		default:
+			t.Fatalf("expected the %s to have exited", name)
+		}
+	}
+	if ShuttingDown(context.Background()) != nil {
+		t.Fatal("expected no shutdown signal outside the server")
+	}
+}
+
+func TestHealthRoutes(t *testing.T) {
+	m := New(time.Second)
+	var redisErr error
+	m.AddCheck("postgres", func(context.Context) error { return nil })
+	m.AddCheck("redis", func(context.Context) error { return redisErr })
+	router := fox.New()
+	m.RegisterHealthRoutes(router)
+
+	get := func(path string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var body map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body
+	}
+
+	if code, _ := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("healthz: expected 200, got %d", code)
+	}
+	// not started yet
+	if code, body := get("/readyz"); code != http.StatusServiceUnavailable || body["status"] != "not ready" {
+		t.Fatalf("readyz before start: got %d %v", code, body)
+	}
+
+	m.ready.Store(true)
+	if code, _ := get("/readyz"); code != http.StatusOK {
+		t.Fatalf("readyz: expected 200, got %d", code)
+	}
+
+	redisErr = errors.New("connection refused")
+	code, body := get("/healthz")
+	if code != http.StatusServiceUnavailable {
+		t.Fatalf("healthz with redis down: expected 503, got %d", code)
+	}
+	checks := body["checks"].(map[string]any)
+	if checks["redis"] != "connection refused" || checks["postgres"] != "ok" {
+		t.Fatalf("unexpected checks: %v", checks)
+	}
+}
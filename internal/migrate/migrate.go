@@ -0,0 +1,313 @@
+// Package migrate applies the embedded, versioned Postgres schema migrations shared by
+// service_manager and alerting, and records the applied versions in schema_migrations.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+// advisoryLockKey serializes concurrent migrators on the same database.
+const advisoryLockKey = 7241061
+
+var (
+	// ErrSchemaBehind means the database is missing migrations this binary expects.
+	ErrSchemaBehind = errors.New("database schema is behind")
+	// ErrSchemaAhead means the database was migrated by a newer binary.
+	ErrSchemaAhead = errors.New("database schema is ahead of this binary")
+)
+
+// Migration is one numbered schema change with its rollback.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes whether a migration has been applied.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+// Migrator runs migrations against a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New creates a Migrator using the embedded migrations.
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := load(migrationFS)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// load reads NNNN_name.up.sql / NNNN_name.down.sql pairs ordered by version.
+func load(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, file := range files {
+		base := path.Base(file)
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql suffix", base)
+		}
+		stem := strings.TrimSuffix(base, "."+direction+".sql")
+		num, name, ok := strings.Cut(stem, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name prefix", base)
+		}
+
+		body, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: both up and down files are required", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be contiguous from 1, found %d at position %d", m.Version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// Latest returns the highest version known to this binary.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) ensureVersionTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	    version INTEGER PRIMARY KEY,
+	    name VARCHAR(255) NOT NULL,
+	    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// applied returns applied versions with their timestamps.
+func (m *Migrator) applied(ctx context.Context, q interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}) (map[int]time.Time, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		out[version] = at
+	}
+	return out, rows.Err()
+}
+
+// Current returns the highest applied version, 0 for an empty database.
+func (m *Migrator) Current(ctx context.Context) (int, error) {
+	if err := m.ensureVersionTable(ctx); err != nil {
+		return 0, err
+	}
+	var version sql.NullInt64
+	if err := m.db.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// Status lists every known migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := m.ensureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := Status{Version: mig.Version, Name: mig.Name}
+		if at, ok := applied[mig.Version]; ok {
+			at := at
+			st.AppliedAt = &at
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// Up applies all pending migrations in order, each in its own transaction.
+// It returns the migrations that were applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.ensureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, mig := range m.migrations {
+		ran, err := m.step(ctx, mig, true)
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s up: %w", mig.Version, mig.Name, err)
+		}
+		if ran {
+			done = append(done, mig)
+		}
+	}
+	return done, nil
+}
+
+// Down rolls back the most recent steps applied migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if err := m.ensureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		mig := m.migrations[i]
+		ran, err := m.step(ctx, mig, false)
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s down: %w", mig.Version, mig.Name, err)
+		}
+		if ran {
+			done = append(done, mig)
+		}
+	}
+	return done, nil
+}
+
+// step applies or reverts one migration under an advisory lock, skipping it when the
+// recorded state already matches. It reports whether the migration ran.
+func (m *Migrator) step(ctx context.Context, mig Migration, up bool) (bool, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, advisoryLockKey); err != nil {
+		return false, err
+	}
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, mig.Version).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists == up {
+		return false, nil
+	}
+
+	if up {
+		if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+			return false, err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, mig.Version, mig.Name); err != nil {
+			return false, err
+		}
+	} else {
+		if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+			return false, err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// Check verifies that every migration this binary knows has been applied and that the
+// database has not been migrated past it.
+func (m *Migrator) Check(ctx context.Context) error {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			return fmt.Errorf("%w: migration %d_%s is not applied, run `zeroops migrate up`", ErrSchemaBehind, st.Version, st.Name)
+		}
+	}
+	current, err := m.Current(ctx)
+	if err != nil {
+		return err
+	}
+	if current > m.Latest() {
+		return fmt.Errorf("%w: database is at version %d, binary supports up to %d", ErrSchemaAhead, current, m.Latest())
+	}
+	return nil
+}
+
+// EnsureCompatible is called at server startup. When autoMigrate is set, pending migrations
+// are applied first; otherwise an outdated or newer schema is reported as an error.
+func EnsureCompatible(ctx context.Context, db *sql.DB, autoMigrate bool) ([]Migration, error) {
+	m, err := New(db)
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+	var applied []Migration
+	if autoMigrate {
+		if applied, err = m.Up(ctx); err != nil {
+			return applied, fmt.Errorf("apply migrations: %w", err)
+		}
+	}
+	if err := m.Check(ctx); err != nil {
+		return applied, fmt.Errorf("incompatible database schema: %w", err)
+	}
+	return applied, nil
+}
@@ -0,0 +1,90 @@
+//go:build integration
+
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/pg"
+)
+
+// TestUpgradeFromBaseline migrates a database created with the DDL documented before
+// versioned migrations, in a schema of its own, and checks the columns the services use.
+func TestUpgradeFromBaseline(t *testing.T) {
+	ctx := context.Background()
+	cfg, err := config.LoadFile("")
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	admin, err := sql.Open("pgx", pg.DSN(&cfg.Database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer admin.Close()
+	schema := fmt.Sprintf("migrate_baseline_%d", time.Now().UnixNano())
+	if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	defer admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE")
+
+	db, err := sql.Open("pgx", pg.DSN(&cfg.Database)+" search_path="+schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, file := range []string{"testdata/baseline_service_manager.sql", "testdata/baseline_alerting.sql"} {
+		ddl, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.ExecContext(ctx, string(ddl)); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+
+	m, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for table, columns := range map[string][]string{
+		"service_versions":  {"package_url", "checksum", "changelog", "build_meta", "deprecated_at"},
+		"service_instances": {"status", "host", "ip", "port", "source", "registered_at", "last_heartbeat", "region"},
+		"service_states":    {"correlation_id", "alert_issue_ids", "region"},
+		"deploy_tasks":      {"service", "version", "total_batches", "observation_window", "region"},
+		"deploy_events":     {"deploy_id", "event_type", "batch"},
+		"alert_issues":      {"fingerprint", "starts_at", "acked_by", "region"},
+	} {
+		for _, column := range columns {
+			var n int
+			err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.columns
+				WHERE table_schema = $1 AND table_name = $2 AND column_name = $3`, schema, table, column).Scan(&n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != 1 {
+				t.Errorf("%s.%s is missing after the upgrade", table, column)
+			}
+		}
+	}
+	// the demo data of the baseline survives
+	var versions int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM service_versions WHERE package_url = '' AND deprecated_at IS NULL`).Scan(&versions); err != nil {
+		t.Fatal(err)
+	}
+	if versions != 13 {
+		t.Errorf("service_versions = %d, want the 13 seeded", versions)
+	}
+}
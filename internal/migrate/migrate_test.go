@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := load(migrationFS)
+	if err != nil {
+		t.Fatalf("load embedded migrations: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatalf("expected embedded migrations")
+	}
+
+	// every table the services read or write must be created by some migration
+	var all strings.Builder
+	for _, m := range migrations {
+		all.WriteString(m.Up)
+	}
+	for _, table := range []string{
+		"services", "service_versions", "service_instances", "service_states", "deploy_tasks", "deploy_events",
+		"alert_issues", "alert_issue_comments", "alert_rules", "service_alert_metas", "metric_alert_changes", "service_metrics",
+	} {
+		if !strings.Contains(all.String(), "CREATE TABLE IF NOT EXISTS "+table+" (") {
+			t.Errorf("no migration creates table %s", table)
+		}
+	}
+}
+
+func TestLoadRejectsBrokenSets(t *testing.T) {
+	cases := map[string]fstest.MapFS{
+		"missing down": {
+			"migrations/0001_a.up.sql": {Data: []byte("SELECT 1")},
+		},
+		"gap in versions": {
+			"migrations/0001_a.up.sql":   {Data: []byte("SELECT 1")},
+			"migrations/0001_a.down.sql": {Data: []byte("SELECT 1")},
+			"migrations/0003_c.up.sql":   {Data: []byte("SELECT 1")},
+			"migrations/0003_c.down.sql": {Data: []byte("SELECT 1")},
+		},
+		"bad name": {
+			"migrations/init.up.sql": {Data: []byte("SELECT 1")},
+		},
+	}
+	for name, fsys := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := load(fsys); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+// TestBaseline checks that 0001 creates exactly the tables and indexes of the original
+// schema.sql, so a database created from it is adopted unchanged and upgraded by later
+// migrations.
+func TestBaseline(t *testing.T) {
+	migrations, err := load(migrationFS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := os.ReadFile("testdata/baseline_service_manager.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var creates []string
+	for _, stmt := range strings.Split(string(baseline), ";") {
+		var lines []string
+		for _, line := range strings.Split(stmt, "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 && strings.HasPrefix(lines[0], "CREATE ") {
+			creates = append(creates, strings.Join(lines, " "))
+		}
+	}
+	var up []string
+	for _, line := range strings.Split(migrations[0].Up, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "--") {
+			up = append(up, line)
+		}
+	}
+	got := strings.Join(up, " ")
+	if len(creates) == 0 || strings.Count(got, "CREATE ") != len(creates) {
+		t.Fatalf("0001 has %d CREATE statements, the baseline %d", strings.Count(got, "CREATE "), len(creates))
+	}
+	for _, stmt := range creates {
+		if !strings.Contains(got, stmt+";") {
+			t.Errorf("0001 does not contain the baseline statement %q", stmt)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS deploy_tasks;
+DROP TABLE IF EXISTS service_states;
+DROP TABLE IF EXISTS service_instances;
+DROP TABLE IF EXISTS service_versions;
+DROP TABLE IF EXISTS services;
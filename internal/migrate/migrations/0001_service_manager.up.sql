@@ -0,0 +1,54 @@
+-- Service manager baseline: the tables of the original docs/service_manager/model/schema.sql,
+-- without its drops and demo data, so databases created from it are adopted as they are.
+-- Columns added since then are in 0014.
+
+-- 服务表
+CREATE TABLE IF NOT EXISTS services (
+    name VARCHAR(255) PRIMARY KEY,
+    deps JSONB DEFAULT '[]'::jsonb
+);
+
+-- 服务版本表
+CREATE TABLE IF NOT EXISTS service_versions (
+    version VARCHAR(255),
+    service VARCHAR(255),
+    create_time TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (version, service),
+    FOREIGN KEY (service) REFERENCES services(name) ON DELETE CASCADE
+);
+
+-- 服务实例表
+CREATE TABLE IF NOT EXISTS service_instances (
+    id VARCHAR(255) PRIMARY KEY,
+    service VARCHAR(255),
+    version VARCHAR(255),
+    FOREIGN KEY (service) REFERENCES services(name) ON DELETE CASCADE
+);
+
+-- 服务状态表
+CREATE TABLE IF NOT EXISTS service_states (
+    service VARCHAR(255),
+    version VARCHAR(255),
+    report_at TIMESTAMP,
+    resolved_at TIMESTAMP,
+    health_state VARCHAR(50),
+    correlation_id VARCHAR(255),
+    PRIMARY KEY (service, version),
+    FOREIGN KEY (service) REFERENCES services(name) ON DELETE CASCADE
+);
+
+-- 部署任务表 (deploy_tasks)
+CREATE TABLE IF NOT EXISTS deploy_tasks (
+    id VARCHAR(32) PRIMARY KEY,
+    start_time TIMESTAMP,
+    end_time TIMESTAMP,
+    target_ratio DOUBLE PRECISION,
+    instances JSONB DEFAULT '[]'::jsonb,
+    deploy_state VARCHAR(50)
+);
+
+-- 创建索引以提高查询性能
+CREATE INDEX IF NOT EXISTS idx_service_states_service ON service_states(service);
+CREATE INDEX IF NOT EXISTS idx_service_states_report_at ON service_states(service, report_at DESC);
+CREATE INDEX IF NOT EXISTS idx_deploy_tasks_state ON deploy_tasks(deploy_state);
+CREATE INDEX IF NOT EXISTS idx_service_instances_service ON service_instances(service);
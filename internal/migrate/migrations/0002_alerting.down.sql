@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS service_metrics;
+DROP TABLE IF EXISTS metric_alert_changes;
+DROP TABLE IF EXISTS service_alert_metas;
+DROP TABLE IF EXISTS alert_rules;
+DROP TABLE IF EXISTS alert_issue_comments;
+DROP TABLE IF EXISTS alert_issues;
@@ -0,0 +1,53 @@
+-- Alerting tables: issues, comments and the ruleset (rules, per-service metas, change log, metric lists).
+
+CREATE TABLE IF NOT EXISTS alert_issues (
+    id VARCHAR(64) PRIMARY KEY,
+    state VARCHAR(16) NOT NULL,        -- Open/Closed
+    level VARCHAR(32) NOT NULL,        -- P0/P1/P2/Warning
+    alert_state VARCHAR(32) NOT NULL,  -- Pending/InProcessing/Restored/AutoRestored
+    title VARCHAR(255) NOT NULL,
+    labels JSON NOT NULL,              -- [{key, value}]
+    alert_since TIMESTAMP(6) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_issues_state_level_since ON alert_issues(state, level, alert_since);
+CREATE INDEX IF NOT EXISTS idx_alert_issues_alertstate_since ON alert_issues(alert_state, alert_since);
+
+CREATE TABLE IF NOT EXISTS alert_issue_comments (
+    issue_id VARCHAR(64) NOT NULL REFERENCES alert_issues(id) ON DELETE CASCADE,
+    create_at TIMESTAMP(6) NOT NULL,
+    content TEXT NOT NULL,
+    PRIMARY KEY (issue_id, create_at)
+);
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    scopes VARCHAR(255) NOT NULL DEFAULT '', -- e.g. services:svc1,svc2
+    expr TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_name ON alert_rules(name);
+CREATE INDEX IF NOT EXISTS idx_alert_rules_scopes ON alert_rules(scopes);
+
+CREATE TABLE IF NOT EXISTS service_alert_metas (
+    service VARCHAR(255) NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    value VARCHAR(255) NOT NULL,
+    PRIMARY KEY (service, key)
+);
+
+CREATE TABLE IF NOT EXISTS metric_alert_changes (
+    id VARCHAR(64) PRIMARY KEY,
+    change_time TIMESTAMP(6) NOT NULL,
+    alert_name VARCHAR(255) NOT NULL,
+    change_items JSON NOT NULL -- [{key, old_value, new_value}]
+);
+
+CREATE INDEX IF NOT EXISTS idx_metric_alert_changes_time ON metric_alert_changes(change_time);
+CREATE INDEX IF NOT EXISTS idx_metric_alert_changes_name_time ON metric_alert_changes(alert_name, change_time);
+
+CREATE TABLE IF NOT EXISTS service_metrics (
+    service VARCHAR(255) PRIMARY KEY,
+    metrics JSON NOT NULL DEFAULT '[]' -- ["metric1", "metric2"]
+);
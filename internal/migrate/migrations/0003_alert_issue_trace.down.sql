@@ -0,0 +1 @@
+ALTER TABLE alert_issues DROP COLUMN IF EXISTS trace_parent;
@@ -0,0 +1,3 @@
+-- W3C traceparent of the webhook span that created the issue, so the scheduler and
+-- remediation spans can link back to it.
+ALTER TABLE alert_issues ADD COLUMN IF NOT EXISTS trace_parent VARCHAR(64) NOT NULL DEFAULT '';
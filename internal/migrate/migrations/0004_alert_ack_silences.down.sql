@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS alert_silences;
+ALTER TABLE alert_issues DROP COLUMN IF EXISTS acked_at;
+ALTER TABLE alert_issues DROP COLUMN IF EXISTS acked_by;
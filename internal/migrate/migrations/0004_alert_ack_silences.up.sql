@@ -0,0 +1,15 @@
+-- Operator acknowledgement of issues and Alertmanager-style silences.
+ALTER TABLE alert_issues ADD COLUMN IF NOT EXISTS acked_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE alert_issues ADD COLUMN IF NOT EXISTS acked_at TIMESTAMP(6);
+
+CREATE TABLE IF NOT EXISTS alert_silences (
+    id VARCHAR(64) PRIMARY KEY,
+    matchers JSON NOT NULL,            -- [{name, op, value}], all must match
+    starts_at TIMESTAMP(6) NOT NULL,
+    ends_at TIMESTAMP(6) NOT NULL,     -- set to NOW() when expired early
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP(6) NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_silences_ends_at ON alert_silences(ends_at);
@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS idx_alert_issues_region_state_since;
+ALTER TABLE alert_issues DROP COLUMN IF EXISTS region;
+
+-- Keep the most recent row of each service version before narrowing the key.
+DELETE FROM service_states s USING service_states o
+WHERE s.service = o.service AND s.version = o.version AND s.region <> o.region
+  AND (COALESCE(s.report_at, '-infinity'), s.region) < (COALESCE(o.report_at, '-infinity'), o.region);
+ALTER TABLE service_states DROP CONSTRAINT IF EXISTS service_states_pkey;
+ALTER TABLE service_states DROP COLUMN IF EXISTS region;
+ALTER TABLE service_states ADD PRIMARY KEY (service, version);
+
+DROP INDEX IF EXISTS idx_deploy_tasks_region_state;
+ALTER TABLE deploy_tasks DROP COLUMN IF EXISTS region;
+
+DROP INDEX IF EXISTS idx_service_instances_service_region;
+ALTER TABLE service_instances DROP COLUMN IF EXISTS region;
+
+ALTER TABLE services DROP COLUMN IF EXISTS regions;
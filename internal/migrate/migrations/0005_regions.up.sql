@@ -0,0 +1,27 @@
+-- Region (IDC, cloud region or staging cluster) as a dimension of services, instances,
+-- deployments, service health and alert issues. Existing rows belong to 'default'.
+
+-- Regions the service is deployed in; empty means it is not restricted to any.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS regions JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+ALTER TABLE service_instances ADD COLUMN IF NOT EXISTS region VARCHAR(64) NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS idx_service_instances_service_region ON service_instances(service, region);
+
+ALTER TABLE deploy_tasks ADD COLUMN IF NOT EXISTS region VARCHAR(64) NOT NULL DEFAULT 'default';
+CREATE INDEX IF NOT EXISTS idx_deploy_tasks_region_state ON deploy_tasks(region, deploy_state);
+
+-- Health is tracked per service, version and region.
+ALTER TABLE service_states ADD COLUMN IF NOT EXISTS region VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE service_states DROP CONSTRAINT IF EXISTS service_states_pkey;
+ALTER TABLE service_states ADD PRIMARY KEY (service, version, region);
+
+-- Derived by the receiver from the region, regionCode or idc label.
+ALTER TABLE alert_issues ADD COLUMN IF NOT EXISTS region VARCHAR(64) NOT NULL DEFAULT 'default';
+UPDATE alert_issues SET region = COALESCE((
+    SELECT lower(e ->> 'value') FROM json_array_elements(alert_issues.labels) e
+    WHERE e ->> 'key' IN ('region', 'regionCode', 'idc') AND e ->> 'value' <> ''
+    ORDER BY array_position(ARRAY['region', 'regionCode', 'idc'], e ->> 'key')
+    LIMIT 1
+), 'default')
+WHERE json_typeof(labels) = 'array';
+CREATE INDEX IF NOT EXISTS idx_alert_issues_region_state_since ON alert_issues(region, state, alert_since);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS uniq_alert_issues_fingerprint_starts_at;
+ALTER TABLE alert_issues DROP COLUMN IF EXISTS starts_at;
+ALTER TABLE alert_issues DROP COLUMN IF EXISTS fingerprint;
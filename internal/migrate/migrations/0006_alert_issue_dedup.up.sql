@@ -0,0 +1,27 @@
+-- Alertmanager identity of the alert behind an issue. The receiver inserts with
+-- ON CONFLICT DO NOTHING on (fingerprint, starts_at), so a redelivered webhook does not
+-- open a second issue. Issues without a fingerprint are never deduplicated.
+ALTER TABLE alert_issues ADD COLUMN IF NOT EXISTS fingerprint VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE alert_issues ADD COLUMN IF NOT EXISTS starts_at TIMESTAMP(6);
+UPDATE alert_issues SET starts_at = alert_since WHERE starts_at IS NULL;
+
+-- Backfill from the am_fingerprint label. Only one issue per alert gets it, older
+-- duplicates keep '' so the unique index can be built without deleting them.
+UPDATE alert_issues a SET fingerprint = f.fp
+FROM (
+    SELECT DISTINCT ON (fp, alert_since) id, fp
+    FROM (
+        SELECT id, alert_since, (
+            SELECT e ->> 'value' FROM json_array_elements(labels) e
+            WHERE e ->> 'key' = 'am_fingerprint' LIMIT 1
+        ) AS fp
+        FROM alert_issues
+        WHERE json_typeof(labels) = 'array'
+    ) l
+    WHERE fp <> '' AND length(fp) <= 64
+    ORDER BY fp, alert_since, id
+) f
+WHERE a.id = f.id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_alert_issues_fingerprint_starts_at
+    ON alert_issues(fingerprint, starts_at) WHERE fingerprint <> '';
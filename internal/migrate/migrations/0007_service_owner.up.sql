@@ -0,0 +1,2 @@
+-- Team that owns a service; the alert receiver attaches it to issues as the team label.
+ALTER TABLE services ADD COLUMN IF NOT EXISTS owner VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+ALTER TABLE alert_issues DROP COLUMN IF EXISTS slo_budget;
+DROP TABLE IF EXISTS service_slos;
@@ -0,0 +1,17 @@
+-- Service level objectives, stored next to service_alert_metas. The SLI is the ratio of
+-- good to total events; both queries are PromQL with $window standing for the range.
+CREATE TABLE IF NOT EXISTS service_slos (
+    service VARCHAR(255) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    good_query TEXT NOT NULL,
+    total_query TEXT NOT NULL,
+    target DOUBLE PRECISION NOT NULL,        -- e.g. 0.999
+    window_days INT NOT NULL DEFAULT 30,
+    created_at TIMESTAMP(6) NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP(6) NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (service, name)
+);
+
+-- Error budget of the SLO when a burn-rate alert opened the issue.
+ALTER TABLE alert_issues ADD COLUMN IF NOT EXISTS slo_budget JSON;
@@ -0,0 +1 @@
+ALTER TABLE alert_issue_comments DROP COLUMN IF EXISTS analysis;
@@ -0,0 +1,3 @@
+-- Structured root-cause analysis behind an AI analysis comment; content keeps the
+-- rendered Markdown, other comments leave it NULL.
+ALTER TABLE alert_issue_comments ADD COLUMN IF NOT EXISTS analysis JSON;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS alert_issue_reports;
+DROP TABLE IF EXISTS alert_issue_events;
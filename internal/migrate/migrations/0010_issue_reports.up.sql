@@ -0,0 +1,25 @@
+-- History of an issue for postmortems: creation, acknowledgement, alert state changes
+-- and remediation actions. Comments stay in alert_issue_comments.
+CREATE TABLE IF NOT EXISTS alert_issue_events (
+    id BIGSERIAL PRIMARY KEY,
+    issue_id VARCHAR(64) NOT NULL REFERENCES alert_issues(id) ON DELETE CASCADE,
+    at TIMESTAMP(6) NOT NULL,
+    type VARCHAR(32) NOT NULL,                 -- created/acked/state/remediation
+    actor VARCHAR(255) NOT NULL DEFAULT '',    -- operator or component
+    from_state VARCHAR(32) NOT NULL DEFAULT '',
+    to_state VARCHAR(32) NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_issue_events_issue_at ON alert_issue_events(issue_id, at);
+
+-- Stored postmortem reports, one per issue; regenerating replaces it.
+CREATE TABLE IF NOT EXISTS alert_issue_reports (
+    issue_id VARCHAR(64) PRIMARY KEY REFERENCES alert_issues(id) ON DELETE CASCADE,
+    service VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP(6) NOT NULL,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    report JSON NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_issue_reports_service_created ON alert_issue_reports(service, created_at);
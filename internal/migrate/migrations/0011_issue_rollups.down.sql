@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_alert_issues_since;
+DROP TABLE IF EXISTS alert_fingerprint_rollups;
+DROP TABLE IF EXISTS alert_issue_rollups;
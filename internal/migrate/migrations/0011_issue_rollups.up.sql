@@ -0,0 +1,34 @@
+-- Daily rollups of alert_issues for the analytics API, one row per UTC day of alert_since,
+-- service, alertname and level. Durations are kept as a count, a sum and fixed histogram
+-- buckets so means and percentiles can be combined across days.
+CREATE TABLE IF NOT EXISTS alert_issue_rollups (
+    day DATE NOT NULL,
+    service VARCHAR(255) NOT NULL DEFAULT '',
+    alertname VARCHAR(255) NOT NULL DEFAULT '',
+    level VARCHAR(32) NOT NULL DEFAULT '',
+    issues INT NOT NULL,
+    acked INT NOT NULL,
+    ack_seconds DOUBLE PRECISION NOT NULL,
+    ack_buckets JSON NOT NULL,               -- counts per analytics bucket bound
+    restored INT NOT NULL,
+    restore_seconds DOUBLE PRECISION NOT NULL,
+    restore_buckets JSON NOT NULL,
+    remediated INT NOT NULL,                 -- issues auto-remediation acted on
+    auto_restored INT NOT NULL,              -- of which it restored
+    updated_at TIMESTAMP(6) NOT NULL,
+    PRIMARY KEY (day, service, alertname, level)
+);
+
+-- Issues per alert fingerprint and day, to find alerts that keep firing and resolving.
+CREATE TABLE IF NOT EXISTS alert_fingerprint_rollups (
+    day DATE NOT NULL,
+    fingerprint VARCHAR(64) NOT NULL,
+    service VARCHAR(255) NOT NULL DEFAULT '',
+    alertname VARCHAR(255) NOT NULL DEFAULT '',
+    level VARCHAR(32) NOT NULL DEFAULT '',
+    issues INT NOT NULL,
+    PRIMARY KEY (day, fingerprint)
+);
+
+-- Rollups read the issues of a day range.
+CREATE INDEX IF NOT EXISTS idx_alert_issues_since ON alert_issues(alert_since);
@@ -0,0 +1,23 @@
+DROP TABLE IF EXISTS alert_issue_archive;
+
+CREATE TABLE alert_issues_unpartitioned (LIKE alert_issues INCLUDING DEFAULTS);
+INSERT INTO alert_issues_unpartitioned SELECT * FROM alert_issues;
+DROP TABLE alert_issues;
+DROP FUNCTION IF EXISTS alert_issues_ensure_partition(DATE);
+ALTER TABLE alert_issues_unpartitioned RENAME TO alert_issues;
+ALTER TABLE alert_issues ADD PRIMARY KEY (id);
+
+CREATE INDEX IF NOT EXISTS idx_alert_issues_state_level_since ON alert_issues(state, level, alert_since);
+CREATE INDEX IF NOT EXISTS idx_alert_issues_alertstate_since ON alert_issues(alert_state, alert_since);
+CREATE INDEX IF NOT EXISTS idx_alert_issues_region_state_since ON alert_issues(region, state, alert_since);
+CREATE INDEX IF NOT EXISTS idx_alert_issues_since ON alert_issues(alert_since);
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_alert_issues_fingerprint_starts_at
+    ON alert_issues(fingerprint, starts_at) WHERE fingerprint <> '';
+
+-- fails while comments, events or reports of archived issues are left behind
+ALTER TABLE alert_issue_comments ADD CONSTRAINT alert_issue_comments_issue_id_fkey
+    FOREIGN KEY (issue_id) REFERENCES alert_issues(id) ON DELETE CASCADE;
+ALTER TABLE alert_issue_events ADD CONSTRAINT alert_issue_events_issue_id_fkey
+    FOREIGN KEY (issue_id) REFERENCES alert_issues(id) ON DELETE CASCADE;
+ALTER TABLE alert_issue_reports ADD CONSTRAINT alert_issue_reports_issue_id_fkey
+    FOREIGN KEY (issue_id) REFERENCES alert_issues(id) ON DELETE CASCADE;
@@ -0,0 +1,73 @@
+-- alert_issues is partitioned by month of alert_since, so time-range reads and the
+-- retention job only touch the months they need and emptied months can be dropped.
+-- A partitioned table's keys must include alert_since, so alert_issues(id) can no longer
+-- be referenced: comments, events and reports lose their foreign keys and are deleted
+-- together with their issue by the application (see Database.DeleteIssues).
+ALTER TABLE alert_issue_comments DROP CONSTRAINT IF EXISTS alert_issue_comments_issue_id_fkey;
+ALTER TABLE alert_issue_events DROP CONSTRAINT IF EXISTS alert_issue_events_issue_id_fkey;
+ALTER TABLE alert_issue_reports DROP CONSTRAINT IF EXISTS alert_issue_reports_issue_id_fkey;
+
+ALTER TABLE alert_issues RENAME TO alert_issues_unpartitioned;
+ALTER TABLE alert_issues_unpartitioned RENAME CONSTRAINT alert_issues_pkey TO alert_issues_unpartitioned_pkey;
+DROP INDEX IF EXISTS idx_alert_issues_state_level_since;
+DROP INDEX IF EXISTS idx_alert_issues_alertstate_since;
+DROP INDEX IF EXISTS idx_alert_issues_region_state_since;
+DROP INDEX IF EXISTS idx_alert_issues_since;
+DROP INDEX IF EXISTS uniq_alert_issues_fingerprint_starts_at;
+
+CREATE TABLE alert_issues (LIKE alert_issues_unpartitioned INCLUDING DEFAULTS) PARTITION BY RANGE (alert_since);
+ALTER TABLE alert_issues ADD PRIMARY KEY (id, alert_since);
+CREATE INDEX IF NOT EXISTS idx_alert_issues_state_level_since ON alert_issues(state, level, alert_since);
+CREATE INDEX IF NOT EXISTS idx_alert_issues_alertstate_since ON alert_issues(alert_state, alert_since);
+CREATE INDEX IF NOT EXISTS idx_alert_issues_region_state_since ON alert_issues(region, state, alert_since);
+CREATE INDEX IF NOT EXISTS idx_alert_issues_since ON alert_issues(alert_since);
+-- The receiver derives alert_since from starts_at, so adding it keeps the deduplication
+-- of (fingerprint, starts_at) from 0006.
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_alert_issues_fingerprint_starts_at
+    ON alert_issues(fingerprint, starts_at, alert_since) WHERE fingerprint <> '';
+
+-- Issues of months without a partition, e.g. alerts that started long ago.
+CREATE TABLE IF NOT EXISTS alert_issues_default PARTITION OF alert_issues DEFAULT;
+
+-- alert_issues_ensure_partition creates the partition alert_issues_YYYYMM of the month
+-- of since unless it exists, moving the rows of that month out of the default partition,
+-- and reports whether it was created. The retention job calls it ahead of time.
+CREATE OR REPLACE FUNCTION alert_issues_ensure_partition(since DATE) RETURNS BOOLEAN AS $$
+DECLARE
+    lo DATE := date_trunc('month', since);
+    hi DATE := lo + INTERVAL '1 month';
+    part TEXT := 'alert_issues_' || to_char(lo, 'YYYYMM');
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('alert_issues_ensure_partition'));
+    IF to_regclass(part) IS NOT NULL THEN
+        RETURN FALSE;
+    END IF;
+    EXECUTE format('CREATE TABLE %I (LIKE alert_issues INCLUDING DEFAULTS)', part);
+    -- attaching fails while the default partition holds rows of the month
+    EXECUTE format('WITH moved AS (DELETE FROM alert_issues_default WHERE alert_since >= %L AND alert_since < %L RETURNING *)
+        INSERT INTO %I SELECT * FROM moved', lo, hi, part);
+    EXECUTE format('ALTER TABLE alert_issues ATTACH PARTITION %I FOR VALUES FROM (%L) TO (%L)', part, lo, hi);
+    RETURN TRUE;
+END
+$$ LANGUAGE plpgsql;
+
+INSERT INTO alert_issues SELECT * FROM alert_issues_unpartitioned;
+SELECT alert_issues_ensure_partition(m::date)
+FROM generate_series(
+    date_trunc('month', COALESCE((SELECT MIN(alert_since) FROM alert_issues), now())),
+    date_trunc('month', now()) + INTERVAL '2 months',
+    INTERVAL '1 month'
+) m;
+DROP TABLE alert_issues_unpartitioned;
+
+-- Closed issues the retention job moved out of alert_issues when archiving to the
+-- database, one JSON document per issue with its comments, events and report.
+CREATE TABLE IF NOT EXISTS alert_issue_archive (
+    id VARCHAR(64) PRIMARY KEY,
+    level VARCHAR(32) NOT NULL,
+    alert_since TIMESTAMP(6) NOT NULL,
+    archived_at TIMESTAMP(6) NOT NULL,
+    record JSON NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_issue_archive_since ON alert_issue_archive(alert_since);
@@ -0,0 +1,27 @@
+-- Per-integration webhook credentials. A rotated credential keeps working until its
+-- expires_at, so senders can switch to the new one without dropped alerts.
+CREATE TABLE IF NOT EXISTS alert_webhook_credentials (
+    id VARCHAR(64) PRIMARY KEY,
+    integration VARCHAR(64) NOT NULL,
+    kind VARCHAR(16) NOT NULL,         -- hmac/token
+    secret TEXT NOT NULL,              -- HMAC key, or the hex SHA-256 of a token
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP(6) NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP(6)            -- NULL until rotated or revoked
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_webhook_credentials_integration ON alert_webhook_credentials(integration);
+
+-- Security-relevant actions such as rejected webhooks and credential changes.
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    at TIMESTAMP(6) NOT NULL DEFAULT NOW(),
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    target VARCHAR(255) NOT NULL DEFAULT '',
+    remote_addr VARCHAR(64) NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_at ON audit_logs(at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action_at ON audit_logs(action, at);
@@ -0,0 +1,26 @@
+DROP TABLE IF EXISTS deploy_events;
+DROP INDEX IF EXISTS idx_deploy_tasks_service_version;
+DROP INDEX IF EXISTS idx_service_instances_ip;
+DROP INDEX IF EXISTS idx_service_instances_host;
+DROP INDEX IF EXISTS idx_service_instances_heartbeat;
+
+ALTER TABLE deploy_tasks DROP COLUMN IF EXISTS observation_window;
+ALTER TABLE deploy_tasks DROP COLUMN IF EXISTS total_batches;
+ALTER TABLE deploy_tasks DROP COLUMN IF EXISTS version;
+ALTER TABLE deploy_tasks DROP COLUMN IF EXISTS service;
+
+ALTER TABLE service_states DROP COLUMN IF EXISTS alert_issue_ids;
+
+ALTER TABLE service_instances DROP COLUMN IF EXISTS last_heartbeat;
+ALTER TABLE service_instances DROP COLUMN IF EXISTS registered_at;
+ALTER TABLE service_instances DROP COLUMN IF EXISTS source;
+ALTER TABLE service_instances DROP COLUMN IF EXISTS port;
+ALTER TABLE service_instances DROP COLUMN IF EXISTS ip;
+ALTER TABLE service_instances DROP COLUMN IF EXISTS host;
+ALTER TABLE service_instances DROP COLUMN IF EXISTS status;
+
+ALTER TABLE service_versions DROP COLUMN IF EXISTS deprecated_at;
+ALTER TABLE service_versions DROP COLUMN IF EXISTS build_meta;
+ALTER TABLE service_versions DROP COLUMN IF EXISTS changelog;
+ALTER TABLE service_versions DROP COLUMN IF EXISTS checksum;
+ALTER TABLE service_versions DROP COLUMN IF EXISTS package_url;
@@ -0,0 +1,51 @@
+-- Service manager columns added after the 0001 baseline: version registry metadata,
+-- instance registration and heartbeats, alert links of service states, deployment
+-- batches and the deployment timeline.
+
+ALTER TABLE service_versions ADD COLUMN IF NOT EXISTS package_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE service_versions ADD COLUMN IF NOT EXISTS checksum VARCHAR(80) NOT NULL DEFAULT ''; -- sha256:<hex>
+ALTER TABLE service_versions ADD COLUMN IF NOT EXISTS changelog TEXT NOT NULL DEFAULT '';
+ALTER TABLE service_versions ADD COLUMN IF NOT EXISTS build_meta JSONB;
+ALTER TABLE service_versions ADD COLUMN IF NOT EXISTS deprecated_at TIMESTAMP;
+
+ALTER TABLE service_instances ADD COLUMN IF NOT EXISTS status VARCHAR(16) NOT NULL DEFAULT 'active'; -- active/pending/error/lost
+ALTER TABLE service_instances ADD COLUMN IF NOT EXISTS host VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE service_instances ADD COLUMN IF NOT EXISTS ip VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE service_instances ADD COLUMN IF NOT EXISTS port INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE service_instances ADD COLUMN IF NOT EXISTS source VARCHAR(16) NOT NULL DEFAULT 'api'; -- api/consul
+ALTER TABLE service_instances ADD COLUMN IF NOT EXISTS registered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE service_instances ADD COLUMN IF NOT EXISTS last_heartbeat TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+-- Written by service_manager (correlation_id) and by the alerting receiver/remediation
+-- (alert_issue_ids). correlation_id is in the baseline but missing from tables created
+-- with the minimal DDL of the alerting README.
+ALTER TABLE service_states ADD COLUMN IF NOT EXISTS correlation_id VARCHAR(255);
+ALTER TABLE service_states ADD COLUMN IF NOT EXISTS alert_issue_ids TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE deploy_tasks ADD COLUMN IF NOT EXISTS service VARCHAR(255);
+ALTER TABLE deploy_tasks ADD COLUMN IF NOT EXISTS version VARCHAR(255);
+ALTER TABLE deploy_tasks ADD COLUMN IF NOT EXISTS total_batches INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE deploy_tasks ADD COLUMN IF NOT EXISTS observation_window INTEGER NOT NULL DEFAULT 300; -- seconds per batch
+
+-- Append-only deployment timeline.
+CREATE TABLE IF NOT EXISTS deploy_events (
+    id BIGSERIAL PRIMARY KEY,
+    deploy_id VARCHAR(32) NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    from_state VARCHAR(50) NOT NULL DEFAULT '',
+    to_state VARCHAR(50) NOT NULL DEFAULT '',
+    batch INTEGER,
+    instance VARCHAR(255) NOT NULL DEFAULT '',
+    verdict VARCHAR(16) NOT NULL DEFAULT '',
+    operator VARCHAR(255) NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    detail JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_instances_heartbeat ON service_instances(status, last_heartbeat);
+-- The receiver resolves the service of an alert from its instance or host label.
+CREATE INDEX IF NOT EXISTS idx_service_instances_host ON service_instances(host);
+CREATE INDEX IF NOT EXISTS idx_service_instances_ip ON service_instances(ip);
+CREATE INDEX IF NOT EXISTS idx_deploy_tasks_service_version ON deploy_tasks(service, version, start_time DESC);
+CREATE INDEX IF NOT EXISTS idx_deploy_events_deploy_id ON deploy_events(deploy_id, id);
@@ -1,3 +1,4 @@
+-- docs/service_manager/model/schema.sql as of the baseline, before versioned migrations.
 -- ZeroOps Service Manager Database Schema
 
 -- 删除现有表（按依赖关系逆序删除）
@@ -0,0 +1,136 @@
+package observability
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Webhook alert outcomes.
+const (
+	AlertCreated  = "created"
+	AlertDeduped  = "deduped"
+	AlertIgnored  = "ignored"  // non-firing webhook payloads
+	AlertSilenced = "silenced" // matched an active silence
+	AlertDropped  = "dropped"  // dropped by a relabel rule
+	AlertFailed   = "failed"
+)
+
+// Reasons webhook requests are rejected before their alerts are read.
+const (
+	WebhookMissingAuth    = "missing_auth"    // no credentials sent although some are configured
+	WebhookBadCredentials = "bad_credentials" // unknown, expired or wrong credentials or signature
+	WebhookStaleSignature = "stale_signature" // signature timestamp outside the replay window
+	WebhookReplay         = "replay"          // signature already seen
+	WebhookTooLarge       = "too_large"       // body over the size limit
+	WebhookRateLimited    = "rate_limited"    // the source exceeded its request rate
+)
+
+// Remediation outcomes.
+const (
+	RemediationRestored    = "restored"
+	RemediationFailed      = "failed"
+	RemediationInterrupted = "interrupted"
+)
+
+// durationBuckets covers fast HTTP handlers up to slow scheduler ticks, in seconds.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// MetricCollector holds the zeroops instruments. Exported to Prometheus as e.g.
+// zeroops_webhook_alerts_total and zeroops_http_request_duration_seconds.
+type MetricCollector struct {
+	alertsReceived     metric.Int64Counter
+	alerts             metric.Int64Counter
+	webhookRejected    metric.Int64Counter
+	schedulerTick      metric.Float64Histogram
+	schedulerBacklog   metric.Int64Gauge
+	channelDrops       metric.Int64Counter
+	remediationActions metric.Int64Counter
+	httpDuration       metric.Float64Histogram
+}
+
+var (
+	metricsOnce sync.Once
+	metrics     *MetricCollector
+)
+
+// Metrics returns the process-wide collector.
+func Metrics() *MetricCollector {
+	metricsOnce.Do(func() {
+		metrics = newMetricCollector(otel.Meter(instrumentationName))
+	})
+	return metrics
+}
+
+// newMetricCollector creates the instruments. The API only fails on invalid names, and
+// returns usable no-op instruments alongside the error, so errors are ignored.
+func newMetricCollector(meter metric.Meter) *MetricCollector {
+	c := &MetricCollector{}
+	c.alertsReceived, _ = meter.Int64Counter("zeroops_webhook_alerts_received",
+		metric.WithDescription("Alerts received by the receiver, by source"))
+	c.alerts, _ = meter.Int64Counter("zeroops_webhook_alerts",
+		metric.WithDescription("Alerts processed by the receiver, by outcome (created, deduped, ignored, failed)"))
+	c.webhookRejected, _ = meter.Int64Counter("zeroops_webhook_rejected",
+		metric.WithDescription("Webhook requests rejected before their alerts were read, by reason (failed authentication, too large, rate limited)"))
+	c.schedulerTick, _ = meter.Float64Histogram("zeroops_healthcheck_tick_duration",
+		metric.WithDescription("Duration of one healthcheck scheduler run"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...))
+	c.schedulerBacklog, _ = meter.Int64Gauge("zeroops_healthcheck_pending_backlog",
+		metric.WithDescription("Pending issues fetched by the last scheduler run, capped by the batch size"))
+	c.channelDrops, _ = meter.Int64Counter("zeroops_healthcheck_channel_drops",
+		metric.WithDescription("Pending issues skipped because the remediation channel was full"))
+	c.remediationActions, _ = meter.Int64Counter("zeroops_remediation_actions",
+		metric.WithDescription("Remediation actions, by outcome (restored, failed, interrupted)"))
+	c.httpDuration, _ = meter.Float64Histogram("zeroops_http_request_duration",
+		metric.WithDescription("HTTP request latency per route"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(durationBuckets...))
+	return c
+}
+
+// AlertsReceived counts n alerts arriving from source (alertmanager or an in-process source).
+func (c *MetricCollector) AlertsReceived(ctx context.Context, source string, n int) {
+	c.alertsReceived.Add(ctx, int64(n), metric.WithAttributes(attribute.String("source", source)))
+}
+
+// AlertsProcessed counts n alerts that ended with outcome.
+func (c *MetricCollector) AlertsProcessed(ctx context.Context, outcome string, n int) {
+	c.alerts.Add(ctx, int64(n), metric.WithAttributes(attribute.String("outcome", outcome)))
+}
+
+// WebhookRejected counts one webhook request rejected for reason.
+func (c *MetricCollector) WebhookRejected(ctx context.Context, reason string) {
+	c.webhookRejected.Add(ctx, 1, metric.WithAttributes(attribute.String("reason", reason)))
+}
+
+// SchedulerTick records one scheduler run and the pending rows it fetched.
+func (c *MetricCollector) SchedulerTick(ctx context.Context, d time.Duration, backlog int) {
+	c.schedulerTick.Record(ctx, d.Seconds())
+	c.schedulerBacklog.Record(ctx, int64(backlog))
+}
+
+// ChannelDrop counts one issue not handed to remediation because the channel was full.
+func (c *MetricCollector) ChannelDrop(ctx context.Context) {
+	c.channelDrops.Add(ctx, 1)
+}
+
+// RemediationAction counts one remediation by outcome.
+func (c *MetricCollector) RemediationAction(ctx context.Context, outcome string) {
+	c.remediationActions.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", outcome)))
+}
+
+// HTTPRequest records the latency of one request. route is the registered pattern, not
+// the raw path, to keep cardinality bounded.
+func (c *MetricCollector) HTTPRequest(ctx context.Context, method, route string, status int, d time.Duration) {
+	c.httpDuration.Record(ctx, d.Seconds(), metric.WithAttributes(
+		attribute.String("method", method),
+		attribute.String("route", route),
+		attribute.String("status", strconv.Itoa(status)),
+	))
+}
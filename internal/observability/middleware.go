@@ -0,0 +1,42 @@
+package observability
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/fox-gonic/fox"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTPMiddleware starts a server span per request, continuing an incoming traceparent,
+// and records the request latency by route.
+func HTTPMiddleware(c *fox.Context) {
+	start := time.Now()
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	ctx, span := Tracer().Start(ctx, c.Request.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+		))
+	defer span.End()
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	status := c.Writer.Status()
+	span.SetAttributes(attribute.Int("http.response.status_code", status))
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	Metrics().HTTPRequest(ctx, c.Request.Method, route, status, time.Since(start))
+}
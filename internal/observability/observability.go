@@ -0,0 +1,113 @@
+// Package observability exports zeroops' own telemetry: Prometheus metrics on /metrics
+// and OpenTelemetry traces over OTLP, following mock/s3/shared/observability.
+//
+// Instruments and tracers come from the otel globals, so packages can record before
+// NewProviders runs (the globals delegate once providers are installed) and tests that
+// never install providers record into no-ops.
+package observability
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fox-gonic/fox"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/qiniu/zeroops/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// instrumentationName names the meter and tracer used across zeroops.
+const instrumentationName = "github.com/qiniu/zeroops"
+
+// Providers owns the SDK meter and tracer providers installed as otel globals.
+type Providers struct {
+	metricProvider *sdkmetric.MeterProvider
+	traceProvider  *sdktrace.TracerProvider
+}
+
+// NewProviders installs a meter provider backed by the Prometheus exporter and a tracer
+// provider that exports over OTLP/HTTP when cfg.OTLPEndpoint is set. Without an endpoint
+// spans are still created, so trace IDs propagate, but nothing is exported.
+func NewProviders(cfg *config.TelemetryConfig) (*Providers, error) {
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", cfg.ServiceName),
+		attribute.String("deployment.environment", cfg.Environment),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource: %w", err)
+	}
+
+	promExporter, err := prometheus.New(prometheus.WithoutScopeInfo())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create prometheus exporter: %w", err)
+	}
+	p := &Providers{
+		metricProvider: sdkmetric.NewMeterProvider(
+			sdkmetric.WithResource(res),
+			sdkmetric.WithReader(promExporter),
+		),
+	}
+
+	traceOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler(cfg.SamplingRatio))),
+	}
+	if cfg.OTLPEndpoint != "" {
+		exporter, err := otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpoint(cfg.OTLPEndpoint),
+			otlptracehttp.WithInsecure(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create otlp trace exporter: %w", err)
+		}
+		traceOpts = append(traceOpts, sdktrace.WithBatcher(exporter))
+	}
+	p.traceProvider = sdktrace.NewTracerProvider(traceOpts...)
+
+	otel.SetMeterProvider(p.metricProvider)
+	otel.SetTracerProvider(p.traceProvider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+	return p, nil
+}
+
+func sampler(ratio float64) sdktrace.Sampler {
+	switch {
+	case ratio <= 0:
+		return sdktrace.NeverSample()
+	case ratio >= 1:
+		return sdktrace.AlwaysSample()
+	default:
+		return sdktrace.TraceIDRatioBased(ratio)
+	}
+}
+
+// Shutdown flushes pending spans and stops both providers.
+func (p *Providers) Shutdown(ctx context.Context) error {
+	var errs []error
+	if err := p.traceProvider.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("trace provider shutdown: %w", err))
+	}
+	if err := p.metricProvider.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("metric provider shutdown: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// RegisterMetricsRoute mounts GET /metrics in the Prometheus text format.
+func RegisterMetricsRoute(router *fox.Engine) {
+	handler := promhttp.Handler()
+	router.GET("/metrics", func(c *fox.Context) {
+		handler.ServeHTTP(c.Writer, c.Request)
+	})
+}
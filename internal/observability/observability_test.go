@@ -0,0 +1,76 @@
+package observability
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestMetricCollector(t *testing.T) {
+	ctx := context.Background()
+	reader := sdkmetric.NewManualReader()
+	c := newMetricCollector(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+
+	c.AlertsProcessed(ctx, AlertCreated, 2)
+	c.AlertsProcessed(ctx, AlertDeduped, 1)
+	c.ChannelDrop(ctx)
+	c.SchedulerTick(ctx, 150*time.Millisecond, 7)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]metricdata.Aggregation{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = m.Data
+		}
+	}
+
+	alerts := got["zeroops_webhook_alerts"].(metricdata.Sum[int64])
+	byOutcome := map[string]int64{}
+	for _, dp := range alerts.DataPoints {
+		v, _ := dp.Attributes.Value(attribute.Key("outcome"))
+		byOutcome[v.AsString()] = dp.Value
+	}
+	if byOutcome[AlertCreated] != 2 || byOutcome[AlertDeduped] != 1 {
+		t.Fatalf("unexpected alert outcomes: %v", byOutcome)
+	}
+	if drops := got["zeroops_healthcheck_channel_drops"].(metricdata.Sum[int64]); drops.DataPoints[0].Value != 1 {
+		t.Fatalf("expected one channel drop, got %d", drops.DataPoints[0].Value)
+	}
+	if backlog := got["zeroops_healthcheck_pending_backlog"].(metricdata.Gauge[int64]); backlog.DataPoints[0].Value != 7 {
+		t.Fatalf("expected backlog 7, got %d", backlog.DataPoints[0].Value)
+	}
+	if tick := got["zeroops_healthcheck_tick_duration"].(metricdata.Histogram[float64]); tick.DataPoints[0].Count != 1 {
+		t.Fatalf("expected one tick sample, got %d", tick.DataPoints[0].Count)
+	}
+}
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	ctx, span := tracer.Start(context.Background(), "webhook")
+	defer span.End()
+
+	tp := TraceParent(ctx)
+	if tp == "" {
+		t.Fatal("expected traceparent for a recording span")
+	}
+	parent := trace.SpanContextFromContext(ContextWithTraceParent(context.Background(), tp))
+	if parent.TraceID() != span.SpanContext().TraceID() || parent.SpanID() != span.SpanContext().SpanID() {
+		t.Fatalf("traceparent %q did not round-trip", tp)
+	}
+
+	if TraceParent(context.Background()) != "" || len(LinkTo("")) != 0 || len(LinkTo("garbage")) != 0 {
+		t.Fatal("expected no traceparent or link without a valid span")
+	}
+	if len(LinkTo(tp)) != 1 {
+		t.Fatal("expected a link to a valid traceparent")
+	}
+}
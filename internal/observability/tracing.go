@@ -0,0 +1,45 @@
+package observability
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceContext is used directly rather than the global propagator so stored trace
+// parents round-trip even when providers are not installed.
+var traceContext = propagation.TraceContext{}
+
+// Tracer returns the zeroops tracer.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// TraceParent returns the W3C traceparent of the span in ctx, or "" when there is no
+// valid span. It is stored with issues so later stages can link back to the webhook.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+	return carrier.Get("traceparent")
+}
+
+// ContextWithTraceParent returns ctx carrying traceParent as the remote parent span.
+// Empty or malformed values leave ctx unchanged.
+func ContextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return traceContext.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
+
+// LinkTo returns span start options linking to traceParent, for work that is caused by
+// an earlier trace but runs outside it, such as a scheduler picking up a stored issue.
+func LinkTo(traceParent string) []trace.SpanStartOption {
+	sc := trace.SpanContextFromContext(ContextWithTraceParent(context.Background(), traceParent))
+	if !sc.IsValid() {
+		return nil
+	}
+	return []trace.SpanStartOption{trace.WithLinks(trace.Link{SpanContext: sc})}
+}
@@ -0,0 +1,86 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBuildRefsResolve(t *testing.T) {
+	doc := Build()
+	ids := map[string]string{}
+
+	var walk func(s *Schema, at string)
+	walk = func(s *Schema, at string) {
+		if s == nil {
+			return
+		}
+		if s.Ref != "" {
+			if doc.resolve(s) == nil {
+				t.Errorf("%s: unresolved %s", at, s.Ref)
+			}
+			return
+		}
+		walk(s.Items, at+"[]")
+		walk(s.AdditionalProperties, at+"{}")
+		for name, p := range s.Properties {
+			walk(p, at+"."+name)
+		}
+	}
+
+	for name, s := range doc.Components.Schemas {
+		walk(s, name)
+	}
+	for path, item := range doc.Paths {
+		for method, op := range *item {
+			where := strings.ToUpper(method) + " " + path
+			if prev, dup := ids[op.OperationID]; dup {
+				t.Errorf("operationId %s used by %s and %s", op.OperationID, prev, where)
+			}
+			ids[op.OperationID] = where
+			if len(op.Responses) == 0 {
+				t.Errorf("%s: no responses", where)
+			}
+			if op.RequestBody != nil {
+				walk(op.RequestBody.Content["application/json"].Schema, where+" body")
+			}
+			for status, r := range op.Responses {
+				for _, mt := range r.Content {
+					walk(mt.Schema, where+" "+status)
+				}
+			}
+		}
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc := Build()
+	cases := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+		ok     bool
+	}{
+		{"valid", "/v1/deployments/d1", http.StatusOK, `{"id":"d1","service":"s","version":"v1","region":"default","status":"deploying"}`, true},
+		{"bad enum", "/v1/deployments/d1", http.StatusOK, `{"id":"d1","service":"s","version":"v1","region":"default","status":"paused"}`, false},
+		{"missing field", "/v1/deployments/d1", http.StatusOK, `{"id":"d1","service":"s","status":"deploying"}`, false},
+		{"undocumented field", "/v1/deployments/d1", http.StatusOK, `{"id":"d1","service":"s","version":"v1","region":"default","status":"stop","extra":1}`, false},
+		{"error body", "/v1/deployments/d1", http.StatusNotFound, `{"error":{"code":"NOT_FOUND","message":"deployment not found","requestId":"r1"}}`, true},
+		{"unknown error code", "/v1/deployments/d1", http.StatusNotFound, `{"error":{"code":"MISSING","message":"deployment not found"}}`, false},
+		{"legacy error body", "/v1/deployments/d1", http.StatusNotFound, `{"error":"not found","message":"deployment not found"}`, false},
+		{"undocumented status", "/v1/deployments/d1", http.StatusTeapot, `{}`, false},
+		{"literal beats param", "/v1/services/topology", http.StatusOK, `{"order":[],"levels":null}`, true},
+	}
+	for _, tc := range cases {
+		err := doc.ValidateResponse(http.MethodGet, tc.path, tc.status, "application/json; charset=utf-8", []byte(tc.body))
+		if (err == nil) != tc.ok {
+			t.Errorf("%s: err = %v, want ok=%v", tc.name, err, tc.ok)
+		}
+	}
+}
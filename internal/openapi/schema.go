@@ -0,0 +1,136 @@
+package openapi
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaGen reflects Go types into schemas, registering named structs as components.
+type schemaGen struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	enums      map[reflect.Type][]string
+}
+
+func newSchemaGen() *schemaGen {
+	return &schemaGen{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+		enums:      make(map[reflect.Type][]string),
+	}
+}
+
+// enum declares the allowed values of a named string type.
+func (g *schemaGen) enum(v any, values ...string) {
+	g.enums[reflect.TypeOf(v)] = values
+}
+
+// schema returns the schema of v's type; v may be a typed nil pointer.
+func (g *schemaGen) schema(v any) *Schema {
+	return g.schemaOf(reflect.TypeOf(v))
+}
+
+func (g *schemaGen) schemaOf(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaOf(t.Elem())
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string", Enum: g.enums[t]}
+	case reflect.Slice, reflect.Array:
+		// Go encodes nil slices as null
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem()), Nullable: t.Kind() == reflect.Slice}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: refPrefix + g.component(t)}
+	}
+	return &Schema{}
+}
+
+// component registers a named struct once and returns its component name. Types from
+// different packages with the same name are told apart by a package prefix.
+func (g *schemaGen) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.components[name]; taken {
+		pkg := path.Base(t.PkgPath())
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	g.names[t] = name
+	g.components[name] = nil // reserve before recursing into self-references
+	g.components[name] = g.structSchema(t)
+	return name
+}
+
+func (g *schemaGen) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+// addFields follows encoding/json: embedded structs without a tag are flattened,
+// "-" and unexported fields are skipped, and fields without omitempty are required.
+func (g *schemaGen) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		prop := g.schemaOf(f.Type)
+		if f.Type.Kind() == reflect.Pointer && prop.Ref == "" {
+			prop.Nullable = true
+		}
+		s.Properties[name] = prop
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
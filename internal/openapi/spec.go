@@ -0,0 +1,496 @@
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	alertapi "github.com/qiniu/zeroops/internal/alerting/api"
+	"github.com/qiniu/zeroops/internal/alerting/service/analytics"
+	"github.com/qiniu/zeroops/internal/alerting/service/anomaly"
+	"github.com/qiniu/zeroops/internal/alerting/service/credential"
+	"github.com/qiniu/zeroops/internal/alerting/service/receiver"
+	"github.com/qiniu/zeroops/internal/alerting/service/report"
+	"github.com/qiniu/zeroops/internal/alerting/service/silence"
+	"github.com/qiniu/zeroops/internal/alerting/service/slo"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// Build returns the description of every route the zeroops binary serves. Adding a
+// route without describing it here fails the client contract tests.
+func Build() *Document {
+	b := newBuilder()
+
+	b.gen.enum(model.DeployState(""), "unrelease", "deploying", "stop", "rollback", "completed", "deleted")
+	b.gen.enum(model.HealthState(""), "Normal", "Warning", "Error")
+	b.gen.enum(model.InstanceStatus(""), "active", "pending", "error", "lost")
+	b.gen.enum(model.VersionStatus(""), "unreleased", "active", "stable", "rolledback", "deprecated")
+	b.gen.enum(model.DeployEventType(""), "state_transition", "batch_start", "batch_finish", "instance_update", "canary_verdict", "operator_action")
+	b.gen.enum(model.CanaryVerdict(""), "pass", "fail")
+
+	b.systemRoutes()
+	b.serviceRoutes()
+	b.deploymentRoutes()
+	b.alertingRoutes()
+
+	b.doc.Components.Schemas = b.gen.components
+	return b.doc
+}
+
+func (b *builder) systemRoutes() {
+	b.get("/healthz", "system", "getHealth", "Liveness probe: every dependency check passes").
+		returns(http.StatusOK, (*HealthResponse)(nil)).
+		returns(http.StatusServiceUnavailable, (*HealthResponse)(nil))
+	b.get("/readyz", "system", "getReadiness", "Readiness probe: started, not draining and healthy").
+		returns(http.StatusOK, (*HealthResponse)(nil)).
+		returns(http.StatusServiceUnavailable, (*HealthResponse)(nil))
+	b.get("/metrics", "system", "getMetrics", "Prometheus metrics in the text exposition format").
+		content(http.StatusOK, "text/plain", &Schema{Type: "string"})
+	b.get("/openapi.json", "system", "getOpenAPI", "This document").
+		content(http.StatusOK, "application/json", &Schema{Type: "object"})
+}
+
+func (b *builder) serviceRoutes() {
+	b.get("/v1/services", "services", "listServices", "List services with health, deploy state and dependency relation").
+		query("region", "string", "Only services deployed in this region, with health and deploy state of that region", false).
+		returns(http.StatusOK, (*model.ServicesResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+	b.post("/v1/services", "services", "createService", "Create a service").
+		body((*model.Service)(nil)).
+		returns(http.StatusCreated, (*MessageResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+	b.get("/v1/services/topology", "services", "getServiceTopology", "Topological order of the dependency graph").
+		returns(http.StatusOK, (*model.ServiceTopology)(nil)).
+		fails(http.StatusInternalServerError)
+	b.put("/v1/services/:service", "services", "updateService", "Replace a service's dependencies and regions").
+		body((*model.Service)(nil)).
+		returns(http.StatusOK, (*MessageResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.delete("/v1/services/:service", "services", "deleteService", "Delete a service nothing depends on").
+		returns(http.StatusOK, (*MessageResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	b.get("/v1/services/:service/impact", "services", "getServiceImpact", "Services that directly or transitively depend on this one").
+		query("region", "string", "Health of the dependents in this region", false).
+		returns(http.StatusOK, (*model.ServiceImpactResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+
+	b.get("/v1/services/:service/activeVersions", "versions", "listActiveVersions", "Versions with running instances").
+		query("region", "string", "Only instances, health and deployments of this region", false).
+		returns(http.StatusOK, (*ActiveVersionList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+	b.get("/v1/services/:service/availableVersions", "versions", "listAvailableVersions", "Registered versions").
+		query("type", "string", "Filter by status: unreleased, active, stable, rolledback or deprecated", false).
+		returns(http.StatusOK, (*ServiceVersionList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+	b.post("/v1/services/:service/versions", "versions", "createServiceVersion", "Register a version").
+		body((*model.CreateServiceVersionRequest)(nil)).
+		returns(http.StatusCreated, (*model.ServiceVersion)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	b.post("/v1/services/:service/versions/:version/deprecate", "versions", "deprecateServiceVersion", "Deprecate a version").
+		returns(http.StatusOK, (*MessageResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+
+	b.get("/v1/services/:service/instances", "instances", "listServiceInstances", "List instances").
+		query("version", "string", "Only instances running this version", false).
+		query("region", "string", "Only instances in this region", false).
+		returns(http.StatusOK, (*ServiceInstanceList)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.post("/v1/services/:service/instances", "instances", "registerServiceInstance", "Register or re-register an instance").
+		body((*model.RegisterInstanceRequest)(nil)).
+		returns(http.StatusOK, (*model.ServiceInstance)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	b.post("/v1/services/:service/instances/:instanceID/heartbeat", "instances", "heartbeatServiceInstance", "Report an instance heartbeat").
+		optionalBody((*model.InstanceHeartbeatRequest)(nil)).
+		returns(http.StatusOK, (*model.ServiceInstance)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.delete("/v1/services/:service/instances/:instanceID", "instances", "deregisterServiceInstance", "Deregister an instance").
+		returns(http.StatusOK, (*MessageResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+
+	b.get("/v1/metrics/:service/:name", "metrics", "getServiceMetric", "Time series of a registered service metric").
+		query("start", "string", "RFC3339 start time", true).
+		query("end", "string", "RFC3339 end time", true).
+		query("version", "string", "Only this version", false).
+		query("granule", "string", "Step such as 1m, 5m or 1h", false).
+		returns(http.StatusOK, (*model.PrometheusQueryRangeResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError,
+			http.StatusBadGateway, http.StatusServiceUnavailable)
+}
+
+func (b *builder) deploymentRoutes() {
+	b.get("/v1/deployments", "deployments", "listDeployments", "List deployments").
+		query("type", "string", "Filter by state: unrelease, deploying, stop, rollback or completed", false).
+		query("service", "string", "Filter by service", false).
+		query("region", "string", "Filter by target region", false).
+		query("start", "string", "Page cursor", false).
+		query("limit", "integer", "Page size, 1-1000", false).
+		returns(http.StatusOK, (*DeploymentList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+	b.post("/v1/deployments", "deployments", "createDeployment", "Create a deployment").
+		operator().
+		body((*model.CreateDeploymentRequest)(nil)).
+		returns(http.StatusCreated, (*MessageResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError)
+	b.get("/v1/deployments/:deployID", "deployments", "getDeployment", "Get a deployment").
+		returns(http.StatusOK, (*model.Deployment)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.post("/v1/deployments/:deployID", "deployments", "updateDeployment", "Change the version or schedule of a pending deployment").
+		operator().
+		body((*model.UpdateDeploymentRequest)(nil)).
+		returns(http.StatusOK, (*MessageResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	b.delete("/v1/deployments/:deployID", "deployments", "deleteDeployment", "Cancel a pending deployment").
+		operator().
+		returns(http.StatusOK, (*MessageResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	for _, action := range []string{"pause", "continue", "rollback"} {
+		b.post("/v1/deployments/:deployID/"+action, "deployments", action+"Deployment", strings.ToUpper(action[:1])+action[1:]+" a deployment").
+			operator().
+			returns(http.StatusOK, (*MessageResponse)(nil)).
+			fails(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)
+	}
+
+	b.get("/v1/deployments/:deployID/events", "deployments", "listDeploymentEvents", "Deployment event timeline").
+		query("after", "integer", "Only events with a larger id", false).
+		query("limit", "integer", "Maximum number of events", false).
+		returns(http.StatusOK, (*DeployEventList)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.post("/v1/deployments/:deployID/events", "deployments", "createDeploymentEvent", "Report a batch, instance, canary or operator event").
+		operator().
+		body((*model.CreateDeployEventRequest)(nil)).
+		returns(http.StatusCreated, (*model.DeployEvent)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.get("/v1/deployments/:deployID/events/stream", "deployments", "streamDeploymentEvents",
+		"Server-sent events: backlog after Last-Event-ID, then live events until the deployment ends").
+		header("Last-Event-ID", "Resume after this event id").
+		query("after", "integer", "Resume after this event id when Last-Event-ID is absent", false).
+		content(http.StatusOK, "text/event-stream", &Schema{Type: "string"}).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+}
+
+func (b *builder) alertingRoutes() {
+	b.get("/v1/issues", "issues", "listIssues", "List cached issues").
+		query("limit", "integer", "Page size, 1-100", true).
+		query("start", "string", "Cursor returned as next by the previous page", false).
+		query("state", "string", "Open (default) or Closed", false).
+		query("service", "string", "Only issues of this service", false).
+		query("region", "string", "Only issues of this region; applied after paging when service is set", false).
+		query("level", "string", "Only issues of this level, e.g. P0; applied after paging", false).
+		returns(http.StatusOK, (*alertapi.IssueList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+	b.get("/v1/issues/stream", "issues", "streamIssues",
+		"Server-sent events: created, updated and closed issues from every replica, not replayed; data is an issue as listed").
+		query("service", "string", "Only issues of this service", false).
+		query("level", "string", "Only issues of this level, e.g. P0", false).
+		query("region", "string", "Only issues of this region", false).
+		content(http.StatusOK, "text/event-stream", &Schema{Type: "string"}).
+		fails(http.StatusBadRequest, http.StatusInternalServerError)
+	b.get("/v1/issues/:issueID", "issues", "getIssue", "Get an issue with its comments").
+		returns(http.StatusOK, (*alertapi.IssueDetail)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)
+	b.post("/v1/issues/:issueID/ack", "issues", "ackIssue", "Acknowledge an issue as the X-Operator").
+		header("X-Operator", "Required; recorded as ackedBy").
+		returns(http.StatusOK, (*alertapi.IssueDetail)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.post("/v1/issues/:issueID/comments", "issues", "createIssueComment", "Comment on an issue").
+		body((*alertapi.CreateCommentRequest)(nil)).
+		returns(http.StatusCreated, (*alertapi.IssueComment)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError,
+			http.StatusServiceUnavailable)
+
+	b.get("/v1/issues/:issueID/report", "reports", "getIssueReport",
+		"Generate the postmortem report of an issue from its current history").
+		query("format", "string", "json (default) or markdown", false).
+		returns(http.StatusOK, (*report.Report)(nil)).
+		content(http.StatusOK, "text/markdown", &Schema{Type: "string"}).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.post("/v1/issues/:issueID/report", "reports", "saveIssueReport", "Generate and store the report of an issue, replacing an earlier one").
+		operator().
+		returns(http.StatusCreated, (*report.Stored)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.get("/v1/reports", "reports", "listReports", "List stored reports, newest first").
+		query("service", "string", "Only reports of this service", false).
+		query("limit", "integer", "Page size, 1-200, default 50", false).
+		returns(http.StatusOK, (*alertapi.ReportList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.get("/v1/reports/:issueID", "reports", "getStoredReport", "Get the stored report of an issue").
+		query("format", "string", "json (default) or markdown, which renders the report alone", false).
+		returns(http.StatusOK, (*report.Stored)(nil)).
+		content(http.StatusOK, "text/markdown", &Schema{Type: "string"}).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	b.get("/v1/analytics/issues", "analytics", "getIssueAnalytics",
+		"Issue counts, time to acknowledge and to restore and auto-remediation success of a day range").
+		analyticsQuery().
+		query("groupBy", "string", "Comma separated day, service, alertname and level; default service,alertname,level", false).
+		returns(http.StatusOK, (*analytics.IssueStats)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.get("/v1/analytics/flapping", "analytics", "listFlappingAlerts", "Alert fingerprints that opened many issues in a day range").
+		analyticsQuery().
+		query("minIssues", "integer", "Issues from which an alert counts as flapping, default 3", false).
+		query("limit", "integer", "Page size, 1-100, default 10", false).
+		returns(http.StatusOK, (*alertapi.FlappingList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.get("/v1/analytics/noisy-rules", "analytics", "listNoisyRules", "Alert rules that opened the most issues in a day range").
+		analyticsQuery().
+		query("limit", "integer", "Page size, 1-100, default 10", false).
+		returns(http.StatusOK, (*alertapi.NoisyRuleList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.post("/v1/analytics/rollups", "analytics", "rollUpAnalytics", "Recompute the daily rollups of a day range").
+		query("from", "string", "First day, YYYY-MM-DD (UTC); default 6 days before to", false).
+		query("to", "string", "Last day, YYYY-MM-DD (UTC); default today", false).
+		returns(http.StatusOK, (*alertapi.RollupResult)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	b.get("/v1/silences", "silences", "listSilences", "List pending and active silences").
+		query("expired", "boolean", "Also list expired silences", false).
+		returns(http.StatusOK, (*alertapi.SilenceList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.post("/v1/silences", "silences", "createSilence", "Mute alerts whose labels match every matcher").
+		operator().
+		body((*silence.CreateRequest)(nil)).
+		returns(http.StatusCreated, (*silence.Silence)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.delete("/v1/silences/:silenceID", "silences", "expireSilence", "Expire a silence now").
+		noContent(http.StatusNoContent).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	b.get("/v1/slos", "slos", "listSLOs", "List service level objectives").
+		query("service", "string", "Only SLOs of this service", false).
+		returns(http.StatusOK, (*alertapi.SLOList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.put("/v1/slos/:service/:name", "slos", "putSLO", "Create or replace an SLO; queries use $window as their range").
+		body((*slo.PutRequest)(nil)).
+		returns(http.StatusOK, (*slo.SLO)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.delete("/v1/slos/:service/:name", "slos", "deleteSLO", "Delete an SLO").
+		noContent(http.StatusNoContent).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.get("/v1/slos/rules", "slos", "getSLORules",
+		"Prometheus recording and multi-window multi-burn-rate alerting rules of every SLO").
+		returns(http.StatusOK, (*slo.RuleFile)(nil)).
+		fails(http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.get("/v1/slos/budget", "slos", "getSLOBudgets", "Remaining error budget of each SLO, evaluated in Prometheus").
+		query("service", "string", "Only SLOs of this service", false).
+		returns(http.StatusOK, (*alertapi.BudgetList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	b.get("/v1/anomaly/detectors", "anomaly", "listAnomalyDetectors", "List anomaly detectors").
+		query("service", "string", "Only detectors of this service", false).
+		returns(http.StatusOK, (*alertapi.DetectorList)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.put("/v1/anomaly/detectors/:service/:series", "anomaly", "putAnomalyDetector",
+		"Set the detector of a configured series for a service; zero parameters take the defaults").
+		body((*anomaly.Params)(nil)).
+		returns(http.StatusOK, (*anomaly.Detector)(nil)).
+		fails(http.StatusBadRequest, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.delete("/v1/anomaly/detectors/:service/:series", "anomaly", "deleteAnomalyDetector", "Stop detecting anomalies of a series").
+		noContent(http.StatusNoContent).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.post("/v1/anomaly/backtest", "anomaly", "backtestAnomalyDetector",
+		"Replay a detector over a past range with its stored or the given parameters").
+		body((*anomaly.BacktestRequest)(nil)).
+		returns(http.StatusOK, (*anomaly.BacktestResult)(nil)).
+		fails(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	b.post("/v1/integrations/alertmanager/webhook", "integrations", "alertmanagerWebhook", "Alertmanager webhook receiver").
+		header("Authorization", "Bearer token, or basic credentials with the integration as user name and a token as password").
+		header(receiver.HeaderIntegration, "Integration of the hmac credential that signed the request").
+		header(receiver.HeaderTimestamp, "Unix seconds the request was signed at; must be within the replay window").
+		header(receiver.HeaderSignature, "sha256= followed by the hex HMAC-SHA256 of \"<timestamp>.<body>\"").
+		body((*receiver.AMWebhook)(nil)).
+		returns(http.StatusOK, (*receiver.WebhookResponse)(nil)).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge, http.StatusTooManyRequests,
+			http.StatusServiceUnavailable)
+	b.get("/v1/integrations/credentials", "integrations", "listWebhookCredentials", "List webhook credentials without their secrets").
+		admin().
+		query("integration", "string", "Only credentials of this integration", false).
+		query("expired", "boolean", "Also list expired credentials", false).
+		returns(http.StatusOK, (*alertapi.CredentialList)(nil)).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.post("/v1/integrations/credentials", "integrations", "createWebhookCredential",
+		"Create a webhook credential; the secret is only returned here").
+		admin().
+		body((*credential.CreateRequest)(nil)).
+		returns(http.StatusCreated, (*credential.Credential)(nil)).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.post("/v1/integrations/credentials/:credentialID/rotate", "integrations", "rotateWebhookCredential",
+		"Replace a credential, keeping the old one valid for the overlap; the new secret is only returned here").
+		admin().
+		optionalBody((*credential.RotateRequest)(nil)).
+		returns(http.StatusCreated, (*credential.Credential)(nil)).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	b.delete("/v1/integrations/credentials/:credentialID", "integrations", "revokeWebhookCredential", "Revoke a credential now").
+		admin().
+		noContent(http.StatusNoContent).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError, http.StatusServiceUnavailable)
+
+	b.get("/v1/audit-logs", "audit", "listAuditLogs", "Audit log entries, newest first, e.g. rejected webhooks and credential changes").
+		admin().
+		query("action", "string", "Only entries of this action, e.g. webhook.auth_failed", false).
+		query("from", "string", "Only entries at or after this RFC 3339 time", false).
+		query("to", "string", "Only entries before this RFC 3339 time", false).
+		query("limit", "integer", "Page size, 1-1000, default 100", false).
+		returns(http.StatusOK, (*alertapi.AuditLogList)(nil)).
+		fails(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError, http.StatusServiceUnavailable)
+}
+
+type builder struct {
+	doc  *Document
+	gen  *schemaGen
+	errs *Schema // error body shared by every route
+}
+
+func newBuilder() *builder {
+	b := &builder{
+		doc: &Document{
+			OpenAPI: Version,
+			Info: Info{
+				Title:       "zeroops",
+				Version:     "v1",
+				Description: "Service management, deployment and alerting API.",
+			},
+			Paths: make(map[string]*PathItem),
+		},
+		gen: newSchemaGen(),
+	}
+	codes := make([]string, 0, len(apierror.Codes()))
+	for _, c := range apierror.Codes() {
+		codes = append(codes, string(c))
+	}
+	b.gen.enum(apierror.Code(""), codes...)
+	b.errs = b.gen.schema((*apierror.Response)(nil))
+	return b
+}
+
+type opBuilder struct {
+	b *builder
+	o *Operation
+}
+
+func (b *builder) get(path, tag, id, summary string) *opBuilder {
+	return b.add(http.MethodGet, path, tag, id, summary)
+}
+
+func (b *builder) post(path, tag, id, summary string) *opBuilder {
+	return b.add(http.MethodPost, path, tag, id, summary)
+}
+
+func (b *builder) put(path, tag, id, summary string) *opBuilder {
+	return b.add(http.MethodPut, path, tag, id, summary)
+}
+
+func (b *builder) delete(path, tag, id, summary string) *opBuilder {
+	return b.add(http.MethodDelete, path, tag, id, summary)
+}
+
+// add registers an operation for a fox route; ":name" segments become required path
+// parameters.
+func (b *builder) add(method, path, tag, id, summary string) *opBuilder {
+	o := &Operation{
+		OperationID: id,
+		Summary:     summary,
+		Tags:        []string{tag},
+		Responses:   make(map[string]*Response),
+	}
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if name, ok := strings.CutPrefix(seg, ":"); ok {
+			segs[i] = "{" + name + "}"
+			o.Parameters = append(o.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	key := strings.Join(segs, "/")
+	item := b.doc.Paths[key]
+	if item == nil {
+		item = &PathItem{}
+		b.doc.Paths[key] = item
+	}
+	(*item)[strings.ToLower(method)] = o
+	b.addTag(tag)
+	return &opBuilder{b: b, o: o}
+}
+
+func (b *builder) addTag(name string) {
+	for _, t := range b.doc.Tags {
+		if t.Name == name {
+			return
+		}
+	}
+	b.doc.Tags = append(b.doc.Tags, Tag{Name: name})
+}
+
+func (ob *opBuilder) query(name, typ, desc string, required bool) *opBuilder {
+	ob.o.Parameters = append(ob.o.Parameters, &Parameter{Name: name, In: "query", Description: desc, Required: required, Schema: &Schema{Type: typ}})
+	return ob
+}
+
+func (ob *opBuilder) header(name, desc string) *opBuilder {
+	ob.o.Parameters = append(ob.o.Parameters, &Parameter{Name: name, In: "header", Description: desc, Schema: &Schema{Type: "string"}})
+	return ob
+}
+
+// operator documents the header recorded as the operator of a write.
+func (ob *opBuilder) operator() *opBuilder {
+	return ob.header("X-Operator", "Recorded as the operator of the change")
+}
+
+// admin documents the bearer token of the admin routes, whose name is recorded as the
+// operator of a change.
+func (ob *opBuilder) admin() *opBuilder {
+	return ob.header("Authorization", "Bearer token of an admin in ALERT_WEBHOOK_ADMIN_TOKENS, recorded as the operator")
+}
+
+// analyticsQuery documents the day range and filters shared by the analytics routes.
+func (ob *opBuilder) analyticsQuery() *opBuilder {
+	return ob.query("from", "string", "First day, YYYY-MM-DD (UTC); default 6 days before to", false).
+		query("to", "string", "Last day, YYYY-MM-DD (UTC); default today", false).
+		query("service", "string", "Only issues of this service", false).
+		query("alertname", "string", "Only issues of this alert rule", false).
+		query("level", "string", "Only issues of this level, e.g. P0", false)
+}
+
+func (ob *opBuilder) body(v any) *opBuilder {
+	ob.o.RequestBody = &RequestBody{Required: true, Content: jsonContent(ob.b.gen.schema(v))}
+	return ob
+}
+
+func (ob *opBuilder) optionalBody(v any) *opBuilder {
+	ob.o.RequestBody = &RequestBody{Content: jsonContent(ob.b.gen.schema(v))}
+	return ob
+}
+
+func (ob *opBuilder) returns(status int, v any) *opBuilder {
+	return ob.content(status, "application/json", ob.b.gen.schema(v))
+}
+
+// content documents a body of the status; calling it again with another media type
+// documents an alternative representation.
+func (ob *opBuilder) content(status int, mediaType string, s *Schema) *opBuilder {
+	key := strconv.Itoa(status)
+	resp := ob.o.Responses[key]
+	if resp == nil || resp.Content == nil {
+		resp = &Response{Description: http.StatusText(status), Content: make(map[string]*MediaType)}
+		ob.o.Responses[key] = resp
+	}
+	resp.Content[mediaType] = &MediaType{Schema: s}
+	return ob
+}
+
+func (ob *opBuilder) noContent(status int) *opBuilder {
+	ob.o.Responses[strconv.Itoa(status)] = &Response{Description: http.StatusText(status)}
+	return ob
+}
+
+// fails documents error statuses carrying the shared error body.
+func (ob *opBuilder) fails(statuses ...int) *opBuilder {
+	for _, status := range statuses {
+		ob.content(status, "application/json", ob.b.errs)
+	}
+	return ob
+}
+
+func jsonContent(s *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: s}}
+}
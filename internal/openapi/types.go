@@ -0,0 +1,41 @@
+package openapi
+
+import "github.com/qiniu/zeroops/internal/service_manager/model"
+
+// The handlers build several responses from map literals; these structs give those
+// shapes a name in the spec and are reused by the typed client to decode them.
+
+// MessageResponse acknowledges a write; the identifying fields depend on the route.
+type MessageResponse struct {
+	Message  string `json:"message"`
+	ID       string `json:"id,omitempty"`
+	Service  string `json:"service,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// HealthResponse is the body of /healthz and /readyz.
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+type ActiveVersionList struct {
+	Items []model.ActiveVersionItem `json:"items"`
+}
+
+type ServiceVersionList struct {
+	Items []model.ServiceVersion `json:"items"`
+}
+
+type ServiceInstanceList struct {
+	Items []model.ServiceInstance `json:"items"`
+}
+
+type DeploymentList struct {
+	Items []model.Deployment `json:"items"`
+}
+
+type DeployEventList struct {
+	Items []model.DeployEvent `json:"items"`
+}
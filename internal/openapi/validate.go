@@ -0,0 +1,174 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Lookup finds the operation serving method and a concrete request path. Templates
+// with more literal segments win, so /v1/services/topology is not taken for
+// /v1/services/{service}.
+func (d *Document) Lookup(method, path string) (*Operation, bool) {
+	segs := strings.Split(path, "/")
+	var best *Operation
+	bestScore := -1
+	for tmpl, item := range d.Paths {
+		op := (*item)[strings.ToLower(method)]
+		if op == nil {
+			continue
+		}
+		score, ok := matchPath(strings.Split(tmpl, "/"), segs)
+		if ok && score > bestScore {
+			best, bestScore = op, score
+		}
+	}
+	return best, best != nil
+}
+
+func matchPath(tmpl, segs []string) (int, bool) {
+	if len(tmpl) != len(segs) {
+		return 0, false
+	}
+	literal := 0
+	for i, t := range tmpl {
+		switch {
+		case strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}"):
+			if segs[i] == "" {
+				return 0, false
+			}
+		case t == segs[i]:
+			literal++
+		default:
+			return 0, false
+		}
+	}
+	return literal, true
+}
+
+// ValidateResponse checks that status is documented for the route and that a JSON
+// body matches the documented schema: types, enums, required fields and no fields the
+// schema does not know about.
+func (d *Document) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	op, ok := d.Lookup(method, path)
+	if !ok {
+		return fmt.Errorf("%s %s: no such operation", method, path)
+	}
+	resp := op.Responses[strconv.Itoa(status)]
+	if resp == nil {
+		return fmt.Errorf("%s: status %d is not documented", op.OperationID, status)
+	}
+	if len(resp.Content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("%s: status %d is documented without a body", op.OperationID, status)
+		}
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return fmt.Errorf("%s: content type %q: %w", op.OperationID, contentType, err)
+	}
+	mt := resp.Content[mediaType]
+	if mt == nil {
+		return fmt.Errorf("%s: content type %s is not documented for status %d", op.OperationID, mediaType, status)
+	}
+	if mediaType != "application/json" {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("%s: decode body: %w", op.OperationID, err)
+	}
+	if err := d.validate(mt.Schema, v, "$"); err != nil {
+		return fmt.Errorf("%s %d: %w", op.OperationID, status, err)
+	}
+	return nil
+}
+
+func (d *Document) validate(s *Schema, v any, at string) error {
+	if ref := s.Ref; ref != "" {
+		if s = d.resolve(s); s == nil {
+			return fmt.Errorf("%s: unresolved %s", at, ref)
+		}
+	}
+	if v == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not a %s", at, s.Type)
+	}
+
+	switch s.Type {
+	case "":
+		return nil
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: want object, got %T", at, v)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required field %q", at, name)
+			}
+		}
+		for name, fv := range obj {
+			fs := s.Properties[name]
+			if fs == nil {
+				fs = s.AdditionalProperties
+			}
+			if fs == nil {
+				if s.Properties == nil {
+					continue // free-form object
+				}
+				return fmt.Errorf("%s: undocumented field %q", at, name)
+			}
+			if err := d.validate(fs, fv, at+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: want array, got %T", at, v)
+		}
+		for i, item := range arr {
+			if err := d.validate(s.Items, item, at+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: want string, got %T", at, v)
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			return fmt.Errorf("%s: %q is not one of %v", at, str, s.Enum)
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: want integer, got %T", at, v)
+		}
+		if _, err := n.Int64(); err != nil {
+			return fmt.Errorf("%s: %s is not an integer", at, n)
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			return fmt.Errorf("%s: want number, got %T", at, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: want boolean, got %T", at, v)
+		}
+	default:
+		return fmt.Errorf("%s: unknown schema type %q", at, s.Type)
+	}
+	return nil
+}
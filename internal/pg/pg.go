@@ -0,0 +1,153 @@
+// Package pg owns the process-wide Postgres connection pool shared by service_manager
+// and alerting, and carries transactions through context so repositories written against
+// the pool join an enclosing transaction transparently.
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // PostgreSQL driver
+	"github.com/qiniu/zeroops/internal/config"
+)
+
+// Querier is the subset of *sql.DB and *sql.Tx used by repositories.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Transactor runs fn inside a transaction. Repositories called with the ctx passed to fn
+// take part in the same transaction. In-memory stores implement it too.
+type Transactor interface {
+	InTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// DB is a shared connection pool.
+type DB struct {
+	db *sql.DB
+}
+
+// DSN builds a libpq-style connection string from the database config.
+func DSN(cfg *config.DatabaseConfig) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+}
+
+// Open connects to Postgres and applies the pool limits from cfg.
+func Open(cfg *config.DatabaseConfig) (*DB, error) {
+	db, err := sql.Open("pgx", DSN(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("open db: %w", err)
+	}
+	configure(db, cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping db: %w", err)
+	}
+	return &DB{db: db}, nil
+}
+
+func configure(db *sql.DB, cfg *config.DatabaseConfig) {
+	if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetimeSeconds > 0 {
+		db.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetimeSeconds) * time.Second)
+	}
+	if cfg.ConnMaxIdleTimeSeconds > 0 {
+		db.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTimeSeconds) * time.Second)
+	}
+}
+
+// Close closes the pool. It is safe to call on a nil DB.
+func (d *DB) Close() error {
+	if d == nil || d.db == nil {
+		return nil
+	}
+	return d.db.Close()
+}
+
+// SQL exposes the underlying pool, e.g. for schema migrations.
+func (d *DB) SQL() *sql.DB {
+	return d.db
+}
+
+// Ping checks connectivity.
+func (d *DB) Ping(ctx context.Context) error {
+	return d.db.PingContext(ctx)
+}
+
+// Stats reports pool statistics.
+func (d *DB) Stats() sql.DBStats {
+	return d.db.Stats()
+}
+
+type txKey struct{}
+
+// querier returns the transaction carried by ctx, or the pool.
+func (d *DB) querier(ctx context.Context) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return d.db
+}
+
+// ExecContext executes a statement on the transaction in ctx or on the pool.
+func (d *DB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return d.querier(ctx).ExecContext(ctx, query, args...)
+}
+
+// QueryContext runs a query on the transaction in ctx or on the pool.
+func (d *DB) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return d.querier(ctx).QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a single-row query on the transaction in ctx or on the pool.
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return d.querier(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction that is not tracked in a context.
+func (d *DB) BeginTx(ctx context.Context) (*sql.Tx, error) {
+	return d.db.BeginTx(ctx, nil)
+}
+
+// InTx runs fn in a transaction, committing when fn returns nil and rolling back on error
+// or panic. A nested InTx call joins the outer transaction.
+func (d *DB) InTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
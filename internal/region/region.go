@@ -0,0 +1,54 @@
+// Package region names the environment a service runs in: a production IDC, a cloud
+// region or a staging cluster. service_manager keys instances, deployments and health
+// by region, and alerting derives the region of an issue from its alert labels.
+package region
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Default is the region of records that do not name one, including every row written
+// before regions existed.
+const Default = "default"
+
+// maxLen bounds a region name; it matches the VARCHAR(64) region columns.
+const maxLen = 64
+
+// LabelKeys are the alert labels that carry the region, in order of precedence:
+// Prometheus rules use region, the Prometheus MCP server regionCode and the logs idc.
+var LabelKeys = []string{"region", "regionCode", "idc"}
+
+// Normalize trims and lower-cases name; an empty name is Default.
+func Normalize(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return Default
+	}
+	return name
+}
+
+// Validate checks a normalized region name: 1-64 characters of a-z, 0-9, '-' and '_'.
+func Validate(name string) error {
+	if name == "" || len(name) > maxLen {
+		return fmt.Errorf("region must be 1-%d characters", maxLen)
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return fmt.Errorf("region %q may only contain a-z, 0-9, '-' and '_'", name)
+		}
+	}
+	return nil
+}
+
+// FromLabels returns the normalized region named by the first non-empty label in
+// LabelKeys, or Default.
+func FromLabels(labels map[string]string) string {
+	for _, key := range LabelKeys {
+		if v := strings.TrimSpace(labels[key]); v != "" {
+			return Normalize(v)
+		}
+	}
+	return Default
+}
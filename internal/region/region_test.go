@@ -0,0 +1,43 @@
+package region
+
+import "testing"
+
+func TestNormalizeAndValidate(t *testing.T) {
+	cases := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"", Default, true},
+		{"  CN-East-1 ", "cn-east-1", true},
+		{"staging_2", "staging_2", true},
+		{"cn east", "cn east", false},
+		{"cn/east", "cn/east", false},
+	}
+	for _, tc := range cases {
+		got := Normalize(tc.in)
+		if got != tc.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+		if err := Validate(got); (err == nil) != tc.ok {
+			t.Errorf("Validate(%q) = %v, want ok=%v", got, err, tc.ok)
+		}
+	}
+}
+
+func TestFromLabels(t *testing.T) {
+	cases := []struct {
+		labels map[string]string
+		want   string
+	}{
+		{nil, Default},
+		{map[string]string{"idc": "HZ"}, "hz"},
+		{map[string]string{"idc": "hz", "regionCode": "cn-east-1"}, "cn-east-1"},
+		{map[string]string{"region": "staging", "regionCode": "cn-east-1", "idc": "hz"}, "staging"},
+		{map[string]string{"region": " ", "idc": "hz"}, "hz"},
+	}
+	for _, tc := range cases {
+		if got := FromLabels(tc.labels); got != tc.want {
+			t.Errorf("FromLabels(%v) = %q, want %q", tc.labels, got, tc.want)
+		}
+	}
+}
@@ -7,12 +7,12 @@ import (
 )
 
 type Api struct {
-	db      *database.Database
+	db      database.Store
 	service *service.Service
 	router  *fox.Engine
 }
 
-func NewApi(db *database.Database, service *service.Service, router *fox.Engine) (*Api, error) {
+func NewApi(db database.Store, service *service.Service, router *fox.Engine) (*Api, error) {
 	api := &Api{
 		db:      db,
 		service: service,
@@ -27,6 +27,12 @@ func (api *Api) setupRouters(router *fox.Engine) {
 	// 服务信息相关路由
 	api.setupInfoRouters(router)
 
+	// 版本管理相关路由
+	api.setupVersionRouters(router)
+
+	// 服务实例相关路由
+	api.setupInstanceRouters(router)
+
 	// 部署管理相关路由
 	api.setupDeployRouters(router)
 }
@@ -0,0 +1,184 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/qiniu/zeroops/internal/openapi"
+	"github.com/qiniu/zeroops/internal/service_manager/api"
+	"github.com/qiniu/zeroops/internal/service_manager/database/memory"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+	"github.com/qiniu/zeroops/internal/service_manager/service"
+)
+
+type routeCase struct {
+	name   string
+	method string
+	path   string
+	header map[string]string
+	body   string
+	status int
+	code   apierror.Code // expected error code; empty for success
+}
+
+// newRouter 在内存存储上注册服务管理路由，预置服务api（依赖storage）、只部署在cn-east-1的
+// 服务storage、已废弃版本v0以及一个发布中的任务，返回该任务ID
+func newRouter(t *testing.T) (*fox.Engine, string) {
+	t.Helper()
+	ctx := context.Background()
+	store := memory.New()
+	for _, svc := range []model.Service{{Name: "storage", Regions: []string{"cn-east-1"}}, {Name: "api", Deps: []string{"storage"}}} {
+		if err := store.CreateService(ctx, &svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.CreateServiceVersion(ctx, &model.ServiceVersion{Service: "api", Version: "v0"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.DeprecateServiceVersion(ctx, "api", "v0"); err != nil {
+		t.Fatal(err)
+	}
+	deployID, err := store.CreateDeployment(ctx, &model.CreateDeploymentRequest{Service: "api", Version: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := fox.New()
+	router.Use(apierror.RequestID)
+	if _, err := api.NewApi(store, service.NewService(store, nil), router); err != nil {
+		t.Fatal(err)
+	}
+	return router, deployID
+}
+
+func runRouteCases(t *testing.T, router *fox.Engine, cases []routeCase) {
+	t.Helper()
+	doc := openapi.Build()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			requestID := "req-" + strings.ReplaceAll(tc.name, " ", "-")
+			req.Header.Set(apierror.RequestIDHeader, requestID)
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.status {
+				t.Fatalf("status = %d, want %d; body %s", w.Code, tc.status, w.Body.String())
+			}
+			if err := doc.ValidateResponse(tc.method, req.URL.Path, w.Code, w.Header().Get("Content-Type"), w.Body.Bytes()); err != nil {
+				t.Errorf("contract: %v", err)
+			}
+			if tc.code == "" {
+				return
+			}
+			var resp apierror.Response
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode error body: %v", err)
+			}
+			if resp.Error.Code != tc.code {
+				t.Errorf("code = %s, want %s (%s)", resp.Error.Code, tc.code, resp.Error.Message)
+			}
+			if resp.Error.RequestID != requestID {
+				t.Errorf("requestId = %q", resp.Error.RequestID)
+			}
+		})
+	}
+}
+
+func TestServiceRoutes(t *testing.T) {
+	router, _ := newRouter(t)
+	runRouteCases(t, router, []routeCase{
+		{name: "list services", method: http.MethodGet, path: "/v1/services", status: http.StatusOK},
+		{name: "list services in region", method: http.MethodGet, path: "/v1/services?region=CN-East-1", status: http.StatusOK},
+		{name: "list services bad region", method: http.MethodGet, path: "/v1/services?region=cn/east", status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "create service with regions", method: http.MethodPost, path: "/v1/services", body: `{"name":"edge","regions":["cn-east-1","us-west-1"]}`, status: http.StatusCreated},
+		{name: "create service bad region", method: http.MethodPost, path: "/v1/services", body: `{"name":"edge2","regions":["cn east"]}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "create service", method: http.MethodPost, path: "/v1/services", body: `{"name":"web","deps":["api"]}`, status: http.StatusCreated},
+		{name: "create service without name", method: http.MethodPost, path: "/v1/services", body: `{"deps":[]}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "create service malformed body", method: http.MethodPost, path: "/v1/services", body: `{"name":`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "create service empty body", method: http.MethodPost, path: "/v1/services", status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "create service unknown dep", method: http.MethodPost, path: "/v1/services", body: `{"name":"x","deps":["nope"]}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "update missing service", method: http.MethodPut, path: "/v1/services/nope", body: `{"deps":[]}`, status: http.StatusNotFound, code: apierror.NotFound},
+		{name: "update creates cycle", method: http.MethodPut, path: "/v1/services/storage", body: `{"deps":["api"]}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "delete depended on", method: http.MethodDelete, path: "/v1/services/storage", status: http.StatusConflict, code: apierror.Conflict},
+		{name: "delete missing", method: http.MethodDelete, path: "/v1/services/nope", status: http.StatusNotFound, code: apierror.NotFound},
+		{name: "topology", method: http.MethodGet, path: "/v1/services/topology", status: http.StatusOK},
+		{name: "impact", method: http.MethodGet, path: "/v1/services/storage/impact", status: http.StatusOK},
+		{name: "impact missing", method: http.MethodGet, path: "/v1/services/nope/impact", status: http.StatusNotFound, code: apierror.NotFound},
+		{name: "active versions", method: http.MethodGet, path: "/v1/services/api/activeVersions", status: http.StatusOK},
+		{name: "active versions in region", method: http.MethodGet, path: "/v1/services/api/activeVersions?region=cn-east-1", status: http.StatusOK},
+		{name: "available versions", method: http.MethodGet, path: "/v1/services/api/availableVersions?type=deprecated", status: http.StatusOK},
+		{name: "available versions bad type", method: http.MethodGet, path: "/v1/services/api/availableVersions?type=old", status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "metric without range", method: http.MethodGet, path: "/v1/metrics/api/latency", status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "metric not registered", method: http.MethodGet, path: "/v1/metrics/api/latency?start=2025-01-01T00:00:00Z&end=2025-01-01T01:00:00Z", status: http.StatusNotFound, code: apierror.NotFound},
+	})
+}
+
+func TestVersionAndInstanceRoutes(t *testing.T) {
+	router, _ := newRouter(t)
+	runRouteCases(t, router, []routeCase{
+		{name: "create version bad semver", method: http.MethodPost, path: "/v1/services/api/versions", body: `{"version":"latest","packageURL":"https://pkg/api.tgz"}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "create version without package", method: http.MethodPost, path: "/v1/services/api/versions", body: `{"version":"v1.0.0"}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "deprecate missing version", method: http.MethodPost, path: "/v1/services/api/versions/v9/deprecate", status: http.StatusNotFound, code: apierror.NotFound},
+		{name: "register instance", method: http.MethodPost, path: "/v1/services/api/instances", body: `{"id":"i-1","version":"v1"}`, status: http.StatusOK},
+		{name: "register instance in region", method: http.MethodPost, path: "/v1/services/storage/instances", body: `{"id":"s-1","version":"v1","region":"cn-east-1"}`, status: http.StatusOK},
+		{name: "register instance outside regions", method: http.MethodPost, path: "/v1/services/storage/instances", body: `{"id":"s-2","version":"v1","region":"us-west-1"}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "register instance of another service", method: http.MethodPost, path: "/v1/services/storage/instances", body: `{"id":"i-1","version":"v1","region":"cn-east-1"}`, status: http.StatusConflict, code: apierror.Conflict},
+		{name: "register instance again", method: http.MethodPost, path: "/v1/services/api/instances", body: `{"id":"i-1","version":"v2"}`, status: http.StatusOK},
+		{name: "register instance missing service", method: http.MethodPost, path: "/v1/services/nope/instances", body: `{"id":"i-1","version":"v1"}`, status: http.StatusNotFound, code: apierror.NotFound},
+		{name: "heartbeat without body", method: http.MethodPost, path: "/v1/services/api/instances/i-1/heartbeat", status: http.StatusOK},
+		{name: "heartbeat malformed body", method: http.MethodPost, path: "/v1/services/api/instances/i-1/heartbeat", body: `[`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "heartbeat unknown instance", method: http.MethodPost, path: "/v1/services/api/instances/i-9/heartbeat", status: http.StatusNotFound, code: apierror.NotFound},
+		{name: "list instances", method: http.MethodGet, path: "/v1/services/api/instances", status: http.StatusOK},
+		{name: "list instances in region", method: http.MethodGet, path: "/v1/services/storage/instances?region=cn-east-1", status: http.StatusOK},
+		{name: "list instances missing service", method: http.MethodGet, path: "/v1/services/nope/instances", status: http.StatusNotFound, code: apierror.NotFound},
+		{name: "deregister instance", method: http.MethodDelete, path: "/v1/services/api/instances/i-1", status: http.StatusOK},
+		{name: "deregister unknown instance", method: http.MethodDelete, path: "/v1/services/api/instances/i-1", status: http.StatusNotFound, code: apierror.NotFound},
+	})
+}
+
+func TestDeploymentRoutes(t *testing.T) {
+	router, deployID := newRouter(t)
+	d := "/v1/deployments/" + deployID
+	op := map[string]string{"X-Operator": "alice"}
+	runRouteCases(t, router, []routeCase{
+		{name: "list", method: http.MethodGet, path: "/v1/deployments?type=deploying&limit=10", status: http.StatusOK},
+		{name: "list malformed limit", method: http.MethodGet, path: "/v1/deployments?limit=ten", status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "list zero limit", method: http.MethodGet, path: "/v1/deployments?limit=0", status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "list limit too large", method: http.MethodGet, path: "/v1/deployments?limit=100000", status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "list unknown type", method: http.MethodGet, path: "/v1/deployments?type=paused", status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "create without version", method: http.MethodPost, path: "/v1/deployments", header: op, body: `{"service":"api"}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "create unknown service", method: http.MethodPost, path: "/v1/deployments", header: op, body: `{"service":"nope","version":"v1"}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "create deprecated version", method: http.MethodPost, path: "/v1/deployments", header: op, body: `{"service":"api","version":"v0"}`, status: http.StatusConflict, code: apierror.InvalidState},
+		{name: "create conflicting", method: http.MethodPost, path: "/v1/deployments", header: op, body: `{"service":"api","version":"v1"}`, status: http.StatusConflict, code: apierror.Conflict},
+		{name: "create in another region", method: http.MethodPost, path: "/v1/deployments", header: op, body: `{"service":"api","version":"v1","region":"cn-east-1"}`, status: http.StatusCreated},
+		{name: "create outside service regions", method: http.MethodPost, path: "/v1/deployments", header: op, body: `{"service":"storage","version":"v1","region":"us-west-1"}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "list in region", method: http.MethodGet, path: "/v1/deployments?region=cn-east-1", status: http.StatusOK},
+		{name: "get", method: http.MethodGet, path: d, status: http.StatusOK},
+		{name: "get missing", method: http.MethodGet, path: "/v1/deployments/nope", status: http.StatusNotFound, code: apierror.NotFound},
+		{name: "update while deploying", method: http.MethodPost, path: d, header: op, body: `{"version":"v2"}`, status: http.StatusConflict, code: apierror.InvalidState},
+		{name: "delete while deploying", method: http.MethodDelete, path: d, header: op, status: http.StatusConflict, code: apierror.InvalidState},
+		{name: "continue while deploying", method: http.MethodPost, path: d + "/continue", header: op, status: http.StatusConflict, code: apierror.InvalidState},
+		{name: "pause", method: http.MethodPost, path: d + "/pause", header: op, status: http.StatusOK},
+		{name: "pause missing", method: http.MethodPost, path: "/v1/deployments/nope/pause", header: op, status: http.StatusNotFound, code: apierror.NotFound},
+		{name: "rollback", method: http.MethodPost, path: d + "/rollback", header: op, status: http.StatusOK},
+		{name: "events", method: http.MethodGet, path: d + "/events?after=0&limit=5", status: http.StatusOK},
+		{name: "events negative after", method: http.MethodGet, path: d + "/events?after=-1", status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "events missing deployment", method: http.MethodGet, path: "/v1/deployments/nope/events", status: http.StatusNotFound, code: apierror.NotFound},
+		{name: "report state transition", method: http.MethodPost, path: d + "/events", header: op, body: `{"type":"state_transition"}`, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+		{name: "report operator action", method: http.MethodPost, path: d + "/events", header: op, body: `{"type":"operator_action","message":"checked"}`, status: http.StatusCreated},
+		{name: "stream bad Last-Event-ID", method: http.MethodGet, path: d + "/events/stream", header: map[string]string{"Last-Event-ID": "x"}, status: http.StatusBadRequest, code: apierror.InvalidParameter},
+	})
+}
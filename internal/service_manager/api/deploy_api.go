@@ -1,15 +1,18 @@
 package api
 
 import (
+	"context"
 	"net/http"
-	"strconv"
 
 	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/apierror"
 	"github.com/qiniu/zeroops/internal/service_manager/model"
 	"github.com/qiniu/zeroops/internal/service_manager/service"
-	"github.com/rs/zerolog/log"
 )
 
+// maxListLimit 列表接口单页最大条数
+const maxListLimit = 1000
+
 // setupDeployRouters 设置部署管理相关路由
 func (api *Api) setupDeployRouters(router *fox.Engine) {
 	// 部署任务基本操作
@@ -23,55 +26,46 @@ func (api *Api) setupDeployRouters(router *fox.Engine) {
 	router.POST("/v1/deployments/:deployID/pause", api.PauseDeployment)
 	router.POST("/v1/deployments/:deployID/continue", api.ContinueDeployment)
 	router.POST("/v1/deployments/:deployID/rollback", api.RollbackDeployment)
+
+	// 发布事件时间线
+	router.GET("/v1/deployments/:deployID/events", api.GetDeploymentEvents)
+	router.POST("/v1/deployments/:deployID/events", api.CreateDeploymentEvent)
+	router.GET("/v1/deployments/:deployID/events/stream", api.StreamDeploymentEvents)
+}
+
+// requestContext 返回携带操作人（X-Operator请求头）的上下文
+func requestContext(c *fox.Context) context.Context {
+	return service.WithOperator(c.Request.Context(), c.GetHeader("X-Operator"))
+}
+
+// deployIDParam 读取并校验路径中的发布任务ID，校验失败时已写入错误响应
+func deployIDParam(c *fox.Context) (string, bool) {
+	p := apierror.Params(c)
+	deployID := p.Path("deployID")
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return "", false
+	}
+	return deployID, true
 }
 
 // ===== 部署管理相关API =====
 
 // CreateDeployment 创建发布任务（POST /v1/deployments）
 func (api *Api) CreateDeployment(c *fox.Context) {
-	ctx := c.Request.Context()
+	ctx := requestContext(c)
 
 	var req model.CreateDeploymentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "invalid request body: " + err.Error(),
-		})
-		return
-	}
-
-	if req.Service == "" || req.Version == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "service and version are required",
-		})
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
 	deployID, err := api.service.CreateDeployment(ctx, &req)
 	if err != nil {
-		if err == service.ErrServiceNotFound {
-			c.JSON(http.StatusBadRequest, map[string]any{
-				"error":   "bad request",
-				"message": "service not found",
-			})
-			return
-		}
-		if err == service.ErrDeploymentConflict {
-			c.JSON(http.StatusConflict, map[string]any{
-				"error":   "conflict",
-				"message": "deployment conflict: service version already in deployment",
-			})
-			return
-		}
-		log.Error().Err(err).
-			Str("service", req.Service).
-			Str("version", req.Version).
-			Msg("failed to create deployment")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to create deployment",
-		})
+		// 请求体中引用的服务不存在属于参数错误
+		writeError(c, err, "failed to create deployment",
+			apierror.Mapping{Target: service.ErrServiceNotFound, Code: apierror.InvalidParameter})
 		return
 	}
 
@@ -84,30 +78,14 @@ func (api *Api) CreateDeployment(c *fox.Context) {
 // GetDeploymentByID 获取发布任务详情（GET /v1/deployments/:deployID）
 func (api *Api) GetDeploymentByID(c *fox.Context) {
 	ctx := c.Request.Context()
-	deployID := c.Param("deployID")
-
-	if deployID == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "deployment ID is required",
-		})
+	deployID, ok := deployIDParam(c)
+	if !ok {
 		return
 	}
 
 	deployment, err := api.service.GetDeploymentByID(ctx, deployID)
 	if err != nil {
-		if err == service.ErrDeploymentNotFound {
-			c.JSON(http.StatusNotFound, map[string]any{
-				"error":   "not found",
-				"message": "deployment not found",
-			})
-			return
-		}
-		log.Error().Err(err).Str("deployID", deployID).Msg("failed to get deployment")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to get deployment",
-		})
+		writeError(c, err, "failed to get deployment")
 		return
 	}
 
@@ -118,25 +96,24 @@ func (api *Api) GetDeploymentByID(c *fox.Context) {
 func (api *Api) GetDeployments(c *fox.Context) {
 	ctx := c.Request.Context()
 
+	p := apierror.Params(c)
 	query := &model.DeploymentQuery{
-		Type:    model.DeployState(c.Query("type")),
-		Service: c.Query("service"),
-		Start:   c.Query("start"),
+		Type: model.DeployState(p.Enum("type", "",
+			string(model.StatusUnrelease), string(model.StatusDeploying), string(model.StatusStop),
+			string(model.StatusRollback), string(model.StatusCompleted))),
+		Service: p.String("service", false),
+		Region:  p.String("region", false),
+		Start:   p.String("start", false),
+		Limit:   p.Int("limit", 0, 1, maxListLimit),
 	}
-
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil {
-			query.Limit = limit
-		}
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return
 	}
 
 	deployments, err := api.service.GetDeployments(ctx, query)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to get deployments")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to get deployments",
-		})
+		writeError(c, err, "failed to get deployments")
 		return
 	}
 
@@ -147,47 +124,20 @@ func (api *Api) GetDeployments(c *fox.Context) {
 
 // UpdateDeployment 修改发布任务（POST /v1/deployments/:deployID）
 func (api *Api) UpdateDeployment(c *fox.Context) {
-	ctx := c.Request.Context()
-	deployID := c.Param("deployID")
-
-	if deployID == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "deployment ID is required",
-		})
+	ctx := requestContext(c)
+	deployID, ok := deployIDParam(c)
+	if !ok {
 		return
 	}
 
 	var req model.UpdateDeploymentRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "invalid request body: " + err.Error(),
-		})
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
-	err := api.service.UpdateDeployment(ctx, deployID, &req)
-	if err != nil {
-		if err == service.ErrDeploymentNotFound {
-			c.JSON(http.StatusNotFound, map[string]any{
-				"error":   "not found",
-				"message": "deployment not found",
-			})
-			return
-		}
-		if err == service.ErrInvalidDeployState {
-			c.JSON(http.StatusBadRequest, map[string]any{
-				"error":   "bad request",
-				"message": "invalid deployment state for update",
-			})
-			return
-		}
-		log.Error().Err(err).Str("deployID", deployID).Msg("failed to update deployment")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to update deployment",
-		})
+	if err := api.service.UpdateDeployment(ctx, deployID, &req); err != nil {
+		writeError(c, err, "failed to update deployment", invalidStateMessage("invalid deployment state for update"))
 		return
 	}
 
@@ -198,38 +148,14 @@ func (api *Api) UpdateDeployment(c *fox.Context) {
 
 // DeleteDeployment 删除发布任务（DELETE /v1/deployments/:deployID）
 func (api *Api) DeleteDeployment(c *fox.Context) {
-	ctx := c.Request.Context()
-	deployID := c.Param("deployID")
-
-	if deployID == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "deployment ID is required",
-		})
+	ctx := requestContext(c)
+	deployID, ok := deployIDParam(c)
+	if !ok {
 		return
 	}
 
-	err := api.service.DeleteDeployment(ctx, deployID)
-	if err != nil {
-		if err == service.ErrDeploymentNotFound {
-			c.JSON(http.StatusNotFound, map[string]any{
-				"error":   "not found",
-				"message": "deployment not found",
-			})
-			return
-		}
-		if err == service.ErrInvalidDeployState {
-			c.JSON(http.StatusBadRequest, map[string]any{
-				"error":   "bad request",
-				"message": "invalid deployment state for deletion",
-			})
-			return
-		}
-		log.Error().Err(err).Str("deployID", deployID).Msg("failed to delete deployment")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to delete deployment",
-		})
+	if err := api.service.DeleteDeployment(ctx, deployID); err != nil {
+		writeError(c, err, "failed to delete deployment", invalidStateMessage("invalid deployment state for deletion"))
 		return
 	}
 
@@ -240,38 +166,14 @@ func (api *Api) DeleteDeployment(c *fox.Context) {
 
 // PauseDeployment 暂停发布任务（POST /v1/deployments/:deployID/pause）
 func (api *Api) PauseDeployment(c *fox.Context) {
-	ctx := c.Request.Context()
-	deployID := c.Param("deployID")
-
-	if deployID == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "deployment ID is required",
-		})
+	ctx := requestContext(c)
+	deployID, ok := deployIDParam(c)
+	if !ok {
 		return
 	}
 
-	err := api.service.PauseDeployment(ctx, deployID)
-	if err != nil {
-		if err == service.ErrDeploymentNotFound {
-			c.JSON(http.StatusNotFound, map[string]any{
-				"error":   "not found",
-				"message": "deployment not found",
-			})
-			return
-		}
-		if err == service.ErrInvalidDeployState {
-			c.JSON(http.StatusBadRequest, map[string]any{
-				"error":   "bad request",
-				"message": "deployment cannot be paused in current state",
-			})
-			return
-		}
-		log.Error().Err(err).Str("deployID", deployID).Msg("failed to pause deployment")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to pause deployment",
-		})
+	if err := api.service.PauseDeployment(ctx, deployID); err != nil {
+		writeError(c, err, "failed to pause deployment", invalidStateMessage("deployment cannot be paused in current state"))
 		return
 	}
 
@@ -282,38 +184,14 @@ func (api *Api) PauseDeployment(c *fox.Context) {
 
 // ContinueDeployment 继续发布任务（POST /v1/deployments/:deployID/continue）
 func (api *Api) ContinueDeployment(c *fox.Context) {
-	ctx := c.Request.Context()
-	deployID := c.Param("deployID")
-
-	if deployID == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "deployment ID is required",
-		})
+	ctx := requestContext(c)
+	deployID, ok := deployIDParam(c)
+	if !ok {
 		return
 	}
 
-	err := api.service.ContinueDeployment(ctx, deployID)
-	if err != nil {
-		if err == service.ErrDeploymentNotFound {
-			c.JSON(http.StatusNotFound, map[string]any{
-				"error":   "not found",
-				"message": "deployment not found",
-			})
-			return
-		}
-		if err == service.ErrInvalidDeployState {
-			c.JSON(http.StatusBadRequest, map[string]any{
-				"error":   "bad request",
-				"message": "deployment cannot be continued in current state",
-			})
-			return
-		}
-		log.Error().Err(err).Str("deployID", deployID).Msg("failed to continue deployment")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to continue deployment",
-		})
+	if err := api.service.ContinueDeployment(ctx, deployID); err != nil {
+		writeError(c, err, "failed to continue deployment", invalidStateMessage("deployment cannot be continued in current state"))
 		return
 	}
 
@@ -324,38 +202,14 @@ func (api *Api) ContinueDeployment(c *fox.Context) {
 
 // RollbackDeployment 回滚发布任务（POST /v1/deployments/:deployID/rollback）
 func (api *Api) RollbackDeployment(c *fox.Context) {
-	ctx := c.Request.Context()
-	deployID := c.Param("deployID")
-
-	if deployID == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "deployment ID is required",
-		})
+	ctx := requestContext(c)
+	deployID, ok := deployIDParam(c)
+	if !ok {
 		return
 	}
 
-	err := api.service.RollbackDeployment(ctx, deployID)
-	if err != nil {
-		if err == service.ErrDeploymentNotFound {
-			c.JSON(http.StatusNotFound, map[string]any{
-				"error":   "not found",
-				"message": "deployment not found",
-			})
-			return
-		}
-		if err == service.ErrInvalidDeployState {
-			c.JSON(http.StatusBadRequest, map[string]any{
-				"error":   "bad request",
-				"message": "deployment cannot be rolled back in current state",
-			})
-			return
-		}
-		log.Error().Err(err).Str("deployID", deployID).Msg("failed to rollback deployment")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to rollback deployment",
-		})
+	if err := api.service.RollbackDeployment(ctx, deployID); err != nil {
+		writeError(c, err, "failed to rollback deployment", invalidStateMessage("deployment cannot be rolled back in current state"))
 		return
 	}
 
@@ -363,3 +217,8 @@ func (api *Api) RollbackDeployment(c *fox.Context) {
 		"message": "deployment rolled back successfully",
 	})
 }
+
+// invalidStateMessage 为状态不允许的操作给出具体描述
+func invalidStateMessage(msg string) apierror.Mapping {
+	return apierror.Mapping{Target: service.ErrInvalidDeployState, Code: apierror.InvalidState, Message: msg}
+}
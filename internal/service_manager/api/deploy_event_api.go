@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/qiniu/zeroops/internal/lifecycle"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// sseHeartbeatInterval SSE保活注释的发送间隔
+const sseHeartbeatInterval = 15 * time.Second
+
+// ===== 发布事件相关API =====
+
+// GetDeploymentEvents 获取发布事件时间线（GET /v1/deployments/:deployID/events）
+func (api *Api) GetDeploymentEvents(c *fox.Context) {
+	ctx := c.Request.Context()
+	p := apierror.Params(c)
+	deployID := p.Path("deployID")
+	query := &model.DeployEventQuery{
+		After: p.Int64("after", 0, 0, math.MaxInt64),
+		Limit: p.Int("limit", 0, 1, maxListLimit),
+	}
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	events, err := api.service.GetDeploymentEvents(ctx, deployID, query)
+	if err != nil {
+		writeError(c, err, "failed to get deployment events")
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"items": events,
+	})
+}
+
+// CreateDeploymentEvent 上报发布事件（POST /v1/deployments/:deployID/events）
+func (api *Api) CreateDeploymentEvent(c *fox.Context) {
+	ctx := requestContext(c)
+	deployID, ok := deployIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateDeployEventRequest
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	event, err := api.service.CreateDeployEvent(ctx, deployID, &req)
+	if err != nil {
+		writeError(c, err, "failed to create deployment event")
+		return
+	}
+
+	c.JSON(http.StatusCreated, event)
+}
+
+// StreamDeploymentEvents 以SSE推送发布事件（GET /v1/deployments/:deployID/events/stream）
+// 连接建立后先补发Last-Event-ID（或after参数）之后的历史事件，再持续推送新事件；
+// 发布任务完成、回滚或被删除后服务端主动结束流。
+func (api *Api) StreamDeploymentEvents(c *fox.Context) {
+	ctx := c.Request.Context()
+	deployID, ok := deployIDParam(c)
+	if !ok {
+		return
+	}
+
+	var lastID int64
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("after")
+	}
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			apierror.WriteError(c, apierror.Invalid("Last-Event-ID", lastEventID, "Last-Event-ID must be a non-negative integer"))
+			return
+		}
+		lastID = id
+	}
+
+	// 先订阅再补发历史，避免两者之间产生的事件丢失
+	sub, cancel := api.service.SubscribeDeploymentEvents(deployID)
+	defer cancel()
+
+	backlog, err := api.service.GetDeploymentEvents(ctx, deployID, &model.DeployEventQuery{After: lastID})
+	if err != nil {
+		writeError(c, err, "failed to get deployment events")
+		return
+	}
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range backlog {
+		if err := writeSSEEvent(w, &event); err != nil {
+			return
+		}
+		lastID = event.ID
+		if isTerminalEvent(&event) {
+			w.Flush()
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	// 停机时主动结束，客户端按Last-Event-ID重连补齐
+	shutdown := lifecycle.ShuttingDown(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-shutdown:
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		case event, ok := <-sub:
+			if !ok {
+				// 消费过慢被移出订阅，客户端按Last-Event-ID重连补齐
+				return
+			}
+			if event.ID <= lastID {
+				continue
+			}
+			if err := writeSSEEvent(w, &event); err != nil {
+				return
+			}
+			w.Flush()
+			lastID = event.ID
+			if isTerminalEvent(&event) {
+				return
+			}
+		}
+	}
+}
+
+func writeSSEEvent(w io.Writer, event *model.DeployEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// isTerminalEvent 发布任务进入终态或被删除后不会再有新事件
+func isTerminalEvent(event *model.DeployEvent) bool {
+	if event.Type != model.EventStateTransition {
+		return false
+	}
+	switch event.ToState {
+	case model.StatusCompleted, model.StatusRollback, model.StatusDeleted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/qiniu/zeroops/internal/service_manager/prometheus"
+	"github.com/qiniu/zeroops/internal/service_manager/service"
+)
+
+// serviceErrors 业务错误到统一错误码的映射，按errors.Is匹配，message取错误本身的描述
+var serviceErrors = []apierror.Mapping{
+	{Target: service.ErrServiceNotFound, Code: apierror.NotFound},
+	{Target: service.ErrDeploymentNotFound, Code: apierror.NotFound},
+	{Target: service.ErrVersionNotFound, Code: apierror.NotFound},
+	{Target: service.ErrInstanceNotFound, Code: apierror.NotFound},
+	{Target: service.ErrMetricNotAllowed, Code: apierror.NotFound},
+
+	{Target: service.ErrDeploymentConflict, Code: apierror.Conflict},
+	{Target: service.ErrVersionAlreadyExists, Code: apierror.Conflict},
+	{Target: service.ErrVersionInUse, Code: apierror.Conflict},
+	{Target: service.ErrServiceHasDependents, Code: apierror.Conflict},
+	{Target: service.ErrInstanceConflict, Code: apierror.Conflict},
+
+	{Target: service.ErrInvalidDeployState, Code: apierror.InvalidState},
+	{Target: service.ErrVersionDeprecated, Code: apierror.InvalidState},
+
+	{Target: service.ErrInvalidDeployEvent, Code: apierror.InvalidParameter},
+	{Target: service.ErrInvalidVersion, Code: apierror.InvalidParameter},
+	{Target: service.ErrInvalidVersionType, Code: apierror.InvalidParameter,
+		Message: "type must be one of unreleased, active, stable, rolledback, deprecated"},
+	{Target: service.ErrInvalidPackageURL, Code: apierror.InvalidParameter},
+	{Target: service.ErrInvalidChecksum, Code: apierror.InvalidParameter},
+	{Target: service.ErrUnknownDependency, Code: apierror.InvalidParameter},
+	{Target: service.ErrDependencyCycle, Code: apierror.InvalidParameter},
+	{Target: service.ErrInvalidInstance, Code: apierror.InvalidParameter},
+	{Target: service.ErrInvalidRegion, Code: apierror.InvalidParameter},
+	{Target: service.ErrInvalidOwner, Code: apierror.InvalidParameter},
+	{Target: prometheus.ErrInvalidQuery, Code: apierror.InvalidParameter},
+
+	{Target: prometheus.ErrNotConfigured, Code: apierror.Unavailable, Message: "metrics backend is not configured"},
+}
+
+// writeError 输出统一错误响应。overrides优先于serviceErrors，用于同一业务错误在个别接口下
+// 需要不同错误码或描述的情况；未识别的错误按INTERNAL_ERROR返回internalMsg并记录日志
+func writeError(c *fox.Context, err error, internalMsg string, overrides ...apierror.Mapping) {
+	var promErr *prometheus.APIError
+	if errors.As(err, &promErr) {
+		apierror.WriteError(c, apierror.Wrap(apierror.Upstream, err, promErr.Message))
+		return
+	}
+	e := apierror.Translate(err, append(overrides, serviceErrors...))
+	if e.Code == apierror.Internal && e.Message == "" {
+		e.Message = internalMsg
+	}
+	apierror.WriteError(c, e)
+}
@@ -4,9 +4,8 @@ import (
 	"net/http"
 
 	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/apierror"
 	"github.com/qiniu/zeroops/internal/service_manager/model"
-	"github.com/qiniu/zeroops/internal/service_manager/service"
-	"github.com/rs/zerolog/log"
 )
 
 // setupInfoRouters 设置服务信息相关路由
@@ -17,6 +16,10 @@ func (api *Api) setupInfoRouters(router *fox.Engine) {
 	router.GET("/v1/services/:service/availableVersions", api.GetServiceAvailableVersions)
 	router.GET("/v1/metrics/:service/:name", api.GetServiceMetricTimeSeries)
 
+	// 依赖关系分析
+	router.GET("/v1/services/topology", api.GetServiceTopology)
+	router.GET("/v1/services/:service/impact", api.GetServiceImpact)
+
 	// 服务管理（CRUD）
 	router.POST("/v1/services", api.CreateService)
 	router.PUT("/v1/services/:service", api.UpdateService)
@@ -25,43 +28,39 @@ func (api *Api) setupInfoRouters(router *fox.Engine) {
 
 // ===== 服务信息相关API =====
 
-// GetServices 获取所有服务列表（GET /v1/services）
+// GetServices 获取所有服务列表（GET /v1/services?region=xxx）
 func (api *Api) GetServices(c *fox.Context) {
 	ctx := c.Request.Context()
+	p := apierror.Params(c)
+	regionName := p.String("region", false)
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
 
-	response, err := api.service.GetServicesResponse(ctx)
+	response, err := api.service.GetServicesResponse(ctx, regionName)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to get services")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to get services",
-		})
+		writeError(c, err, "failed to get services")
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-// GetServiceActiveVersions 获取服务活跃版本（GET /v1/services/:service/activeVersions）
+// GetServiceActiveVersions 获取服务活跃版本（GET /v1/services/:service/activeVersions?region=xxx）
 func (api *Api) GetServiceActiveVersions(c *fox.Context) {
 	ctx := c.Request.Context()
-	serviceName := c.Param("service")
-
-	if serviceName == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "service name is required",
-		})
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	regionName := p.String("region", false)
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
-	activeVersions, err := api.service.GetServiceActiveVersions(ctx, serviceName)
+	activeVersions, err := api.service.GetServiceActiveVersions(ctx, serviceName, regionName)
 	if err != nil {
-		log.Error().Err(err).Str("service", serviceName).Msg("failed to get service active versions")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to get service active versions",
-		})
+		writeError(c, err, "failed to get service active versions")
 		return
 	}
 
@@ -73,27 +72,17 @@ func (api *Api) GetServiceActiveVersions(c *fox.Context) {
 // GetServiceAvailableVersions 获取可用服务版本（GET /v1/services/:service/availableVersions）
 func (api *Api) GetServiceAvailableVersions(c *fox.Context) {
 	ctx := c.Request.Context()
-	serviceName := c.Param("service")
-	versionType := c.Query("type")
-
-	if serviceName == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "service name is required",
-		})
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	versionType := p.String("type", false)
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
 	versions, err := api.service.GetServiceAvailableVersions(ctx, serviceName, versionType)
 	if err != nil {
-		log.Error().Err(err).
-			Str("service", serviceName).
-			Str("type", versionType).
-			Msg("failed to get service available versions")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to get service available versions",
-		})
+		writeError(c, err, "failed to get service available versions")
 		return
 	}
 
@@ -105,41 +94,23 @@ func (api *Api) GetServiceAvailableVersions(c *fox.Context) {
 // GetServiceMetricTimeSeries 获取服务时序指标数据（GET /v1/metrics/:service/:name）
 func (api *Api) GetServiceMetricTimeSeries(c *fox.Context) {
 	ctx := c.Request.Context()
-	serviceName := c.Param("service")
-	metricName := c.Param("name")
-
-	if serviceName == "" || metricName == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "service name and metric name are required",
-		})
-		return
+	p := apierror.Params(c)
+	query := model.MetricTimeSeriesQuery{
+		Service: p.Path("service"),
+		Name:    p.Path("name"),
+		Version: p.String("version", false),
+		Start:   p.String("start", true),
+		End:     p.String("end", true),
+		Granule: p.String("granule", false),
 	}
-
-	// 绑定查询参数
-	var query model.MetricTimeSeriesQuery
-	if err := c.ShouldBindQuery(&query); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "invalid query parameters: " + err.Error(),
-		})
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
-	// 设置路径参数
-	query.Service = serviceName
-	query.Name = metricName
-
-	response, err := api.service.GetServiceMetricTimeSeries(ctx, serviceName, metricName, &query)
+	response, err := api.service.GetServiceMetricTimeSeries(ctx, query.Service, query.Name, &query)
 	if err != nil {
-		log.Error().Err(err).
-			Str("service", serviceName).
-			Str("metric", metricName).
-			Msg("failed to get service metric time series")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to get service metric time series",
-		})
+		writeError(c, err, "failed to get service metric time series")
 		return
 	}
 
@@ -152,57 +123,40 @@ func (api *Api) GetServiceMetricTimeSeries(c *fox.Context) {
 func (api *Api) CreateService(c *fox.Context) {
 	ctx := c.Request.Context()
 
-	var service model.Service
-	if err := c.ShouldBindJSON(&service); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "invalid request body: " + err.Error(),
-		})
+	var svc model.Service
+	if err := apierror.BindJSON(c, &svc, false); err != nil {
+		apierror.Write(c, err)
 		return
 	}
-
-	if service.Name == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "service name is required",
-		})
+	if svc.Name == "" {
+		apierror.WriteError(c, apierror.Invalid("name", "", "service name is required"))
 		return
 	}
 
-	if err := api.service.CreateService(ctx, &service); err != nil {
-		log.Error().Err(err).Str("service", service.Name).Msg("failed to create service")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to create service",
-		})
+	if err := api.service.CreateService(ctx, &svc); err != nil {
+		writeError(c, err, "failed to create service")
 		return
 	}
 
 	c.JSON(http.StatusCreated, map[string]any{
 		"message": "service created successfully",
-		"service": service.Name,
+		"service": svc.Name,
 	})
 }
 
 // UpdateService 更新服务信息（PUT /v1/services/:service）
 func (api *Api) UpdateService(c *fox.Context) {
 	ctx := c.Request.Context()
-	serviceName := c.Param("service")
-
-	if serviceName == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "service name is required",
-		})
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
 	var svc model.Service
-	if err := c.ShouldBindJSON(&svc); err != nil {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "invalid request body: " + err.Error(),
-		})
+	if err := apierror.BindJSON(c, &svc, false); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
@@ -210,18 +164,7 @@ func (api *Api) UpdateService(c *fox.Context) {
 	svc.Name = serviceName
 
 	if err := api.service.UpdateService(ctx, &svc); err != nil {
-		if err == service.ErrServiceNotFound {
-			c.JSON(http.StatusNotFound, map[string]any{
-				"error":   "not found",
-				"message": "service not found",
-			})
-			return
-		}
-		log.Error().Err(err).Str("service", serviceName).Msg("failed to update service")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to update service",
-		})
+		writeError(c, err, "failed to update service")
 		return
 	}
 
@@ -234,29 +177,15 @@ func (api *Api) UpdateService(c *fox.Context) {
 // DeleteService 删除服务（DELETE /v1/services/:service）
 func (api *Api) DeleteService(c *fox.Context) {
 	ctx := c.Request.Context()
-	serviceName := c.Param("service")
-
-	if serviceName == "" {
-		c.JSON(http.StatusBadRequest, map[string]any{
-			"error":   "bad request",
-			"message": "service name is required",
-		})
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
 		return
 	}
 
 	if err := api.service.DeleteService(ctx, serviceName); err != nil {
-		if err == service.ErrServiceNotFound {
-			c.JSON(http.StatusNotFound, map[string]any{
-				"error":   "not found",
-				"message": "service not found",
-			})
-			return
-		}
-		log.Error().Err(err).Str("service", serviceName).Msg("failed to delete service")
-		c.JSON(http.StatusInternalServerError, map[string]any{
-			"error":   "internal server error",
-			"message": "failed to delete service",
-		})
+		writeError(c, err, "failed to delete service")
 		return
 	}
 
@@ -265,3 +194,38 @@ func (api *Api) DeleteService(c *fox.Context) {
 		"service": serviceName,
 	})
 }
+
+// ===== 依赖关系分析API =====
+
+// GetServiceTopology 获取服务依赖拓扑排序（GET /v1/services/topology）
+func (api *Api) GetServiceTopology(c *fox.Context) {
+	ctx := c.Request.Context()
+
+	topology, err := api.service.GetServiceTopology(ctx)
+	if err != nil {
+		writeError(c, err, "failed to get service topology")
+		return
+	}
+
+	c.JSON(http.StatusOK, topology)
+}
+
+// GetServiceImpact 获取服务影响面（GET /v1/services/:service/impact?region=xxx）
+func (api *Api) GetServiceImpact(c *fox.Context) {
+	ctx := c.Request.Context()
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	regionName := p.String("region", false)
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	impact, err := api.service.GetServiceImpact(ctx, serviceName, regionName)
+	if err != nil {
+		writeError(c, err, "failed to get service impact")
+		return
+	}
+
+	c.JSON(http.StatusOK, impact)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// setupInstanceRouters 设置服务实例相关路由
+func (api *Api) setupInstanceRouters(router *fox.Engine) {
+	router.GET("/v1/services/:service/instances", api.GetServiceInstances)
+	router.POST("/v1/services/:service/instances", api.RegisterServiceInstance)
+	router.POST("/v1/services/:service/instances/:instanceID/heartbeat", api.HeartbeatServiceInstance)
+	router.DELETE("/v1/services/:service/instances/:instanceID", api.DeregisterServiceInstance)
+}
+
+// ===== 服务实例相关API =====
+
+// GetServiceInstances 获取服务实例列表（GET /v1/services/:service/instances?version=xxx&region=xxx）
+func (api *Api) GetServiceInstances(c *fox.Context) {
+	ctx := c.Request.Context()
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	version := p.String("version", false)
+	regionName := p.String("region", false)
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	instances, err := api.service.GetServiceInstances(ctx, serviceName, version, regionName)
+	if err != nil {
+		writeError(c, err, "failed to get service instances")
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"items": instances,
+	})
+}
+
+// RegisterServiceInstance 注册服务实例（POST /v1/services/:service/instances）
+func (api *Api) RegisterServiceInstance(c *fox.Context) {
+	ctx := c.Request.Context()
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	var req model.RegisterInstanceRequest
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	instance, err := api.service.RegisterServiceInstance(ctx, serviceName, &req)
+	if err != nil {
+		writeError(c, err, "failed to register service instance")
+		return
+	}
+
+	c.JSON(http.StatusOK, instance)
+}
+
+// HeartbeatServiceInstance 上报实例心跳（POST /v1/services/:service/instances/:instanceID/heartbeat）
+// 返回404时实例需要重新注册
+func (api *Api) HeartbeatServiceInstance(c *fox.Context) {
+	ctx := c.Request.Context()
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	instanceID := p.Path("instanceID")
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	// 心跳请求体可以为空
+	var req model.InstanceHeartbeatRequest
+	if err := apierror.BindJSON(c, &req, true); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	instance, err := api.service.HeartbeatServiceInstance(ctx, serviceName, instanceID, &req)
+	if err != nil {
+		writeError(c, err, "failed to record instance heartbeat")
+		return
+	}
+
+	c.JSON(http.StatusOK, instance)
+}
+
+// DeregisterServiceInstance 注销服务实例（DELETE /v1/services/:service/instances/:instanceID）
+func (api *Api) DeregisterServiceInstance(c *fox.Context) {
+	ctx := c.Request.Context()
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	instanceID := p.Path("instanceID")
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	if err := api.service.DeregisterServiceInstance(ctx, serviceName, instanceID); err != nil {
+		writeError(c, err, "failed to deregister service instance")
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"message":  "service instance deregistered successfully",
+		"service":  serviceName,
+		"instance": instanceID,
+	})
+}
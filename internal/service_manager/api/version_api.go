@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/apierror"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+	"github.com/qiniu/zeroops/internal/service_manager/service"
+)
+
+// setupVersionRouters 设置版本管理相关路由
+func (api *Api) setupVersionRouters(router *fox.Engine) {
+	router.POST("/v1/services/:service/versions", api.CreateServiceVersion)
+	router.POST("/v1/services/:service/versions/:version/deprecate", api.DeprecateServiceVersion)
+}
+
+// ===== 版本管理相关API =====
+
+// CreateServiceVersion 注册服务版本（POST /v1/services/:service/versions）
+func (api *Api) CreateServiceVersion(c *fox.Context) {
+	ctx := c.Request.Context()
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	var req model.CreateServiceVersionRequest
+	if err := apierror.BindJSON(c, &req, false); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	version, err := api.service.CreateServiceVersion(ctx, serviceName, &req)
+	if err != nil {
+		writeError(c, err, "failed to create service version")
+		return
+	}
+
+	c.JSON(http.StatusCreated, version)
+}
+
+// DeprecateServiceVersion 废弃服务版本（POST /v1/services/:service/versions/:version/deprecate）
+func (api *Api) DeprecateServiceVersion(c *fox.Context) {
+	ctx := c.Request.Context()
+	p := apierror.Params(c)
+	serviceName := p.Path("service")
+	version := p.Path("version")
+	if err := p.Err(); err != nil {
+		apierror.Write(c, err)
+		return
+	}
+
+	if err := api.service.DeprecateServiceVersion(ctx, serviceName, version); err != nil {
+		writeError(c, err, "failed to deprecate service version",
+			apierror.Mapping{Target: service.ErrVersionInUse, Code: apierror.Conflict,
+				Message: "service version is still running on instances"})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]any{
+		"message": "service version deprecated successfully",
+		"service": serviceName,
+		"version": version,
+	})
+}
@@ -0,0 +1,138 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrNotConfigured = errors.New("consul is not configured")
+
+// maxResponseBytes 单次响应体大小上限
+const maxResponseBytes = 8 << 20
+
+// ServiceEntry /v1/health/service/:service 返回的单个实例
+type ServiceEntry struct {
+	Node    Node          `json:"Node"`
+	Service AgentService  `json:"Service"`
+	Checks  []HealthCheck `json:"Checks"`
+}
+
+// Node Consul节点
+type Node struct {
+	Node       string `json:"Node"`
+	Address    string `json:"Address"`
+	Datacenter string `json:"Datacenter"`
+}
+
+// AgentService 注册在Consul中的服务实例
+type AgentService struct {
+	ID      string            `json:"ID"`
+	Service string            `json:"Service"`
+	Tags    []string          `json:"Tags"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Meta    map[string]string `json:"Meta"`
+}
+
+// HealthCheck 健康检查结果
+type HealthCheck struct {
+	CheckID string `json:"CheckID"`
+	Status  string `json:"Status"` // passing/warning/critical
+}
+
+// 健康检查状态
+const (
+	HealthPassing  = "passing"
+	HealthWarning  = "warning"
+	HealthCritical = "critical"
+)
+
+// Client Consul HTTP API客户端（只读取服务目录）
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient 创建客户端，地址为空时返回nil
+func NewClient(addr string) *Client {
+	addr = strings.TrimSpace(addr)
+	if addr == "" {
+		return nil
+	}
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(addr, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// HealthService 获取服务的全部实例及其健康检查
+func (c *Client) HealthService(ctx context.Context, service string) ([]ServiceEntry, error) {
+	if c == nil {
+		return nil, ErrNotConfigured
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/v1/health/service/"+url.PathEscape(service), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("consul request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read consul response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("consul returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var entries []ServiceEntry
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("decode consul response: %w", err)
+	}
+	return entries, nil
+}
+
+// Critical 是否存在critical状态的健康检查
+func (e *ServiceEntry) Critical() bool {
+	for _, check := range e.Checks {
+		if check.Status == HealthCritical {
+			return true
+		}
+	}
+	return false
+}
+
+// Version 从Meta["version"]或"version=xxx"标签中读取版本
+func (e *ServiceEntry) Version() string {
+	if v := strings.TrimSpace(e.Service.Meta["version"]); v != "" {
+		return v
+	}
+	for _, tag := range e.Service.Tags {
+		if v, ok := strings.CutPrefix(tag, "version="); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// Region 从Meta["region"]读取区域，未设置时取节点所在的数据中心
+func (e *ServiceEntry) Region() string {
+	if v := strings.TrimSpace(e.Service.Meta["region"]); v != "" {
+		return v
+	}
+	return strings.TrimSpace(e.Node.Datacenter)
+}
@@ -1,71 +1,16 @@
 package database
 
 import (
-	"context"
-	"database/sql"
-	"fmt"
-
-	_ "github.com/lib/pq" // PostgreSQL driver
-	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/pg"
 )
 
+// Database service_manager的数据访问层，基于进程共享的连接池
+// 查询方法自动加入ctx中由InTx开启的事务
 type Database struct {
-	db     *sql.DB
-	config *config.DatabaseConfig
-}
-
-func NewDatabase(cfg *config.DatabaseConfig) (*Database, error) {
-	// 构建PostgreSQL连接字符串
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
-
-	// 连接数据库
-	db, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database connection: %w", err)
-	}
-
-	// 测试连接
-	if err := db.Ping(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to ping database: %w", err)
-	}
-
-	database := &Database{
-		db:     db,
-		config: cfg,
-	}
-
-	return database, nil
-}
-
-func (d *Database) Close() error {
-	if d.db != nil {
-		return d.db.Close()
-	}
-	return nil
-}
-
-func (d *Database) DB() *sql.DB {
-	return d.db
-}
-
-// BeginTx 开始事务
-func (d *Database) BeginTx(ctx context.Context) (*sql.Tx, error) {
-	return d.db.BeginTx(ctx, nil)
-}
-
-// QueryContext 执行查询
-func (d *Database) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	return d.db.QueryContext(ctx, query, args...)
-}
-
-// QueryRowContext 执行单行查询
-func (d *Database) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
-	return d.db.QueryRowContext(ctx, query, args...)
+	*pg.DB
 }
 
-// ExecContext 执行操作
-func (d *Database) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	return d.db.ExecContext(ctx, query, args...)
+// NewDatabase 基于共享连接池创建数据访问层
+func NewDatabase(pool *pg.DB) *Database {
+	return &Database{DB: pool}
 }
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// InsertDeployEvent 追加发布事件，回填自增ID和创建时间
+func (d *Database) InsertDeployEvent(ctx context.Context, event *model.DeployEvent) error {
+	query := `INSERT INTO deploy_events (deploy_id, event_type, from_state, to_state, batch, instance, verdict, operator, message, detail)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	          RETURNING id, created_at`
+
+	var detail any
+	if len(event.Detail) > 0 {
+		detail = string(event.Detail)
+	}
+
+	row := d.QueryRowContext(ctx, query, event.DeployID, event.Type, event.FromState, event.ToState,
+		event.Batch, event.Instance, event.Verdict, event.Operator, event.Message, detail)
+	return row.Scan(&event.ID, &event.CreatedAt)
+}
+
+// GetDeployEvents 按ID升序获取发布事件
+func (d *Database) GetDeployEvents(ctx context.Context, deployID string, query *model.DeployEventQuery) ([]model.DeployEvent, error) {
+	sql := `SELECT id, deploy_id, event_type, from_state, to_state, batch, instance, verdict, operator, message, detail, created_at
+	        FROM deploy_events WHERE deploy_id = $1`
+	args := []any{deployID}
+
+	if query != nil && query.After > 0 {
+		sql += " AND id > $" + strconv.Itoa(len(args)+1)
+		args = append(args, query.After)
+	}
+
+	sql += " ORDER BY id ASC"
+
+	if query != nil && query.Limit > 0 {
+		sql += " LIMIT $" + strconv.Itoa(len(args)+1)
+		args = append(args, query.Limit)
+	}
+
+	rows, err := d.QueryContext(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []model.DeployEvent{}
+	for rows.Next() {
+		var event model.DeployEvent
+		var detail *string
+		if err := rows.Scan(&event.ID, &event.DeployID, &event.Type, &event.FromState, &event.ToState,
+			&event.Batch, &event.Instance, &event.Verdict, &event.Operator, &event.Message,
+			&detail, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		if detail != nil && *detail != "" {
+			event.Detail = []byte(*detail)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
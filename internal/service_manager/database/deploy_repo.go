@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/qiniu/zeroops/internal/region"
 	"github.com/qiniu/zeroops/internal/service_manager/model"
 )
 
@@ -23,14 +24,35 @@ func (d *Database) CreateDeployment(ctx context.Context, req *model.CreateDeploy
 		initialStatus = model.StatusUnrelease // 计划发布
 	}
 
-	query := `INSERT INTO deploy_tasks (id, start_time, end_time, target_ratio, instances, deploy_state) 
-	          VALUES ($1, $2, $3, $4, $5, $6)`
+	// 立即发布的任务以当前时间作为开始时间
+	startTime := req.ScheduleTime
+	if startTime == nil {
+		now := time.Now()
+		startTime = &now
+	}
+
+	totalBatches := req.TotalBatches
+	if totalBatches <= 0 {
+		totalBatches = model.DefaultTotalBatches
+	}
+	observationWindow := req.ObservationWindow
+	if observationWindow <= 0 {
+		observationWindow = model.DefaultObservationWindow
+	}
+	deployRegion := req.Region
+	if deployRegion == "" {
+		deployRegion = region.Default
+	}
+
+	query := `INSERT INTO deploy_tasks (id, service, version, region, start_time, end_time, target_ratio, instances, deploy_state, total_batches, observation_window) 
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
 
 	// 默认实例为空数组
 	instances := []string{}
 	instancesJSON, _ := json.Marshal(instances)
 
-	_, err := d.ExecContext(ctx, query, deployID, req.ScheduleTime, nil, 0.0, string(instancesJSON), initialStatus)
+	_, err := d.ExecContext(ctx, query, deployID, req.Service, req.Version, deployRegion, startTime, nil, 0.0, string(instancesJSON), initialStatus,
+		totalBatches, observationWindow)
 	if err != nil {
 		return "", err
 	}
@@ -40,13 +62,13 @@ func (d *Database) CreateDeployment(ctx context.Context, req *model.CreateDeploy
 
 // GetDeploymentByID 根据ID获取发布任务详情
 func (d *Database) GetDeploymentByID(ctx context.Context, deployID string) (*model.Deployment, error) {
-	query := `SELECT id, start_time, end_time, target_ratio, instances, deploy_state 
+	query := `SELECT id, service, version, region, start_time, end_time, target_ratio, instances, deploy_state 
 	          FROM deploy_tasks WHERE id = $1`
 	row := d.QueryRowContext(ctx, query, deployID)
 
 	var task model.ServiceDeployTask
 	var instancesJSON string
-	if err := row.Scan(&task.ID, &task.StartTime, &task.EndTime, &task.TargetRatio,
+	if err := row.Scan(&task.ID, &task.Service, &task.Version, &task.Region, &task.StartTime, &task.EndTime, &task.TargetRatio,
 		&instancesJSON, &task.DeployState); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -63,6 +85,9 @@ func (d *Database) GetDeploymentByID(ctx context.Context, deployID string) (*mod
 
 	deployment := &model.Deployment{
 		ID:           task.ID,
+		Service:      task.Service,
+		Version:      task.Version,
+		Region:       task.Region,
 		Status:       task.DeployState,
 		ScheduleTime: task.StartTime,
 		FinishTime:   task.EndTime,
@@ -73,7 +98,7 @@ func (d *Database) GetDeploymentByID(ctx context.Context, deployID string) (*mod
 
 // GetDeployments 获取发布任务列表
 func (d *Database) GetDeployments(ctx context.Context, query *model.DeploymentQuery) ([]model.Deployment, error) {
-	sql := `SELECT id, start_time, end_time, target_ratio, instances, deploy_state 
+	sql := `SELECT id, service, version, region, start_time, end_time, target_ratio, instances, deploy_state 
 	        FROM deploy_tasks WHERE 1=1`
 	args := []any{}
 
@@ -82,8 +107,15 @@ func (d *Database) GetDeployments(ctx context.Context, query *model.DeploymentQu
 		args = append(args, query.Type)
 	}
 
-	// 注意：新的deploy_tasks表没有service字段，暂时忽略service过滤
-	// TODO: 需要根据业务逻辑决定如何处理service过滤
+	if query.Service != "" {
+		sql += " AND service = $" + strconv.Itoa(len(args)+1)
+		args = append(args, query.Service)
+	}
+
+	if query.Region != "" {
+		sql += " AND region = $" + strconv.Itoa(len(args)+1)
+		args = append(args, query.Region)
+	}
 
 	sql += " ORDER BY start_time DESC"
 
@@ -102,7 +134,7 @@ func (d *Database) GetDeployments(ctx context.Context, query *model.DeploymentQu
 	for rows.Next() {
 		var task model.ServiceDeployTask
 		var instancesJSON string
-		if err := rows.Scan(&task.ID, &task.StartTime, &task.EndTime, &task.TargetRatio,
+		if err := rows.Scan(&task.ID, &task.Service, &task.Version, &task.Region, &task.StartTime, &task.EndTime, &task.TargetRatio,
 			&instancesJSON, &task.DeployState); err != nil {
 			return nil, err
 		}
@@ -116,6 +148,9 @@ func (d *Database) GetDeployments(ctx context.Context, query *model.DeploymentQu
 
 		deployment := model.Deployment{
 			ID:           task.ID,
+			Service:      task.Service,
+			Version:      task.Version,
+			Region:       task.Region,
 			Status:       task.DeployState,
 			ScheduleTime: task.StartTime,
 			FinishTime:   task.EndTime,
@@ -134,8 +169,11 @@ func (d *Database) UpdateDeployment(ctx context.Context, deployID string, req *m
 	updates := []string{}
 	paramIndex := 1
 
-	// 注意：新的deploy_tasks表没有version字段，暂时忽略version更新
-	// TODO: 需要根据业务逻辑决定如何处理version更新
+	if req.Version != "" {
+		updates = append(updates, "version = $"+strconv.Itoa(paramIndex))
+		args = append(args, req.Version)
+		paramIndex++
+	}
 
 	if req.ScheduleTime != nil {
 		updates = append(updates, "start_time = $"+strconv.Itoa(paramIndex))
@@ -159,39 +197,80 @@ func (d *Database) UpdateDeployment(ctx context.Context, deployID string, req *m
 	return err
 }
 
-// DeleteDeployment 删除未开始的发布任务
-func (d *Database) DeleteDeployment(ctx context.Context, deployID string) error {
+// DeleteDeployment 删除未开始的发布任务，返回是否有记录被删除
+func (d *Database) DeleteDeployment(ctx context.Context, deployID string) (bool, error) {
 	query := `DELETE FROM deploy_tasks WHERE id = $1 AND deploy_state = $2`
-	_, err := d.ExecContext(ctx, query, deployID, model.StatusUnrelease)
-	return err
+	return d.execAffected(ctx, query, deployID, model.StatusUnrelease)
 }
 
-// PauseDeployment 暂停正在灰度的发布任务
-func (d *Database) PauseDeployment(ctx context.Context, deployID string) error {
+// PauseDeployment 暂停正在灰度的发布任务，返回是否有记录被更新
+func (d *Database) PauseDeployment(ctx context.Context, deployID string) (bool, error) {
 	query := `UPDATE deploy_tasks SET deploy_state = $1 WHERE id = $2 AND deploy_state = $3`
-	_, err := d.ExecContext(ctx, query, model.StatusStop, deployID, model.StatusDeploying)
-	return err
+	return d.execAffected(ctx, query, model.StatusStop, deployID, model.StatusDeploying)
 }
 
-// ContinueDeployment 继续发布
-func (d *Database) ContinueDeployment(ctx context.Context, deployID string) error {
+// ContinueDeployment 继续发布，返回是否有记录被更新
+func (d *Database) ContinueDeployment(ctx context.Context, deployID string) (bool, error) {
 	query := `UPDATE deploy_tasks SET deploy_state = $1 WHERE id = $2 AND deploy_state = $3`
-	_, err := d.ExecContext(ctx, query, model.StatusDeploying, deployID, model.StatusStop)
-	return err
+	return d.execAffected(ctx, query, model.StatusDeploying, deployID, model.StatusStop)
 }
 
-// RollbackDeployment 回滚发布任务
-func (d *Database) RollbackDeployment(ctx context.Context, deployID string) error {
-	query := `UPDATE deploy_tasks SET deploy_state = $1 WHERE id = $2`
-	_, err := d.ExecContext(ctx, query, model.StatusRollback, deployID)
-	return err
+// RollbackDeployment 回滚正在发布或暂停的发布任务，返回是否有记录被更新
+func (d *Database) RollbackDeployment(ctx context.Context, deployID string) (bool, error) {
+	query := `UPDATE deploy_tasks SET deploy_state = $1 WHERE id = $2 AND deploy_state IN ($3, $4)`
+	return d.execAffected(ctx, query, model.StatusRollback, deployID, model.StatusDeploying, model.StatusStop)
 }
 
-// CheckDeploymentConflict 检查发布冲突
-// 注意：新的deploy_tasks表没有service和version字段，这个方法需要重新设计
-// TODO: 需要根据业务逻辑决定如何检查部署冲突
-func (d *Database) CheckDeploymentConflict(ctx context.Context, service, version string) (bool, error) {
-	// 暂时返回false，表示没有冲突
-	// 实际业务中可能需要通过其他方式检查冲突，比如检查正在部署的任务数量
-	return false, nil
+// execAffected 执行按状态限定的写入，任务已被并发修改时不会命中记录
+func (d *Database) execAffected(ctx context.Context, query string, args ...any) (bool, error) {
+	result, err := d.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// CheckDeploymentConflict 检查发布冲突：同一服务版本在同一区域已有未结束的发布任务
+// excludeID不为空时忽略该任务，用于修改任务时排除自身
+func (d *Database) CheckDeploymentConflict(ctx context.Context, service, version, region, excludeID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM deploy_tasks
+	          WHERE service = $1 AND version = $2 AND region = $3 AND deploy_state IN ($4, $5, $6) AND id <> $7)`
+	var exists bool
+	err := d.QueryRowContext(ctx, query, service, version, region,
+		model.StatusUnrelease, model.StatusDeploying, model.StatusStop, excludeID).Scan(&exists)
+	return exists, err
+}
+
+// GetVersionDeployTasks 获取服务每个版本最近一次的发布任务及已完成批次数，region不为空时只看该区域
+// 已完成批次数按批次序号去重，重复上报的batch_finish只计一次，且不超过总批次数
+func (d *Database) GetVersionDeployTasks(ctx context.Context, service, region string) (map[string]*model.VersionDeployTask, error) {
+	query := `SELECT DISTINCT ON (t.version)
+	                 t.id, t.service, t.version, t.region, t.start_time, t.end_time, t.deploy_state,
+	                 t.total_batches, t.observation_window,
+	                 LEAST((SELECT COUNT(DISTINCT e.batch) FROM deploy_events e
+	                   WHERE e.deploy_id = t.id AND e.event_type = $2), t.total_batches) AS completed_batches
+	          FROM deploy_tasks t
+	          WHERE t.service = $1 AND t.deploy_state <> $3 AND ($4 = '' OR t.region = $4)
+	          ORDER BY t.version, t.start_time DESC NULLS LAST`
+	rows, err := d.QueryContext(ctx, query, service, model.EventBatchFinish, model.StatusRollback, region)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make(map[string]*model.VersionDeployTask)
+	for rows.Next() {
+		var task model.VersionDeployTask
+		if err := rows.Scan(&task.ID, &task.Service, &task.Version, &task.Region, &task.StartTime, &task.EndTime,
+			&task.DeployState, &task.TotalBatches, &task.ObservationWindow, &task.CompletedBatches); err != nil {
+			return nil, err
+		}
+		tasks[task.Version] = &task
+	}
+
+	return tasks, rows.Err()
 }
@@ -10,7 +10,7 @@ import (
 
 // GetServices 获取所有服务列表
 func (d *Database) GetServices(ctx context.Context) ([]model.Service, error) {
-	query := `SELECT name, deps FROM services`
+	query := `SELECT name, owner, deps, regions FROM services`
 	rows, err := d.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
@@ -20,15 +20,13 @@ func (d *Database) GetServices(ctx context.Context) ([]model.Service, error) {
 	var services []model.Service
 	for rows.Next() {
 		var service model.Service
-		var depsJSON string
-		if err := rows.Scan(&service.Name, &depsJSON); err != nil {
+		var depsJSON, regionsJSON string
+		if err := rows.Scan(&service.Name, &service.Owner, &depsJSON, &regionsJSON); err != nil {
 			return nil, err
 		}
 
-		if depsJSON != "" {
-			if err := json.Unmarshal([]byte(depsJSON), &service.Deps); err != nil {
-				return nil, err
-			}
+		if err := unmarshalServiceLists(&service, depsJSON, regionsJSON); err != nil {
+			return nil, err
 		}
 
 		services = append(services, service)
@@ -37,24 +35,106 @@ func (d *Database) GetServices(ctx context.Context) ([]model.Service, error) {
 	return services, rows.Err()
 }
 
+// unmarshalServiceLists 解析services表中的deps和regions两个JSON数组列
+func unmarshalServiceLists(service *model.Service, depsJSON, regionsJSON string) error {
+	if depsJSON != "" {
+		if err := json.Unmarshal([]byte(depsJSON), &service.Deps); err != nil {
+			return err
+		}
+	}
+	if regionsJSON != "" {
+		if err := json.Unmarshal([]byte(regionsJSON), &service.Regions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetServiceSummaries 一次查询获取所有服务及其健康状态和进行中的发布任务
+// 健康状态取各版本、区域中最差的一条（Error > Warning > Normal），同级取最近上报的
+// 同一服务存在多个进行中的任务时，优先取deploying，其次stop，最后unrelease
+// region不为空时健康状态和发布任务只取该区域的记录
+func (d *Database) GetServiceSummaries(ctx context.Context, region string) ([]model.ServiceSummary, error) {
+	query := `SELECT s.name, s.owner, s.deps, s.regions, COALESCE(st.health_state, ''),
+	                 t.id, t.version, t.region, t.deploy_state, t.start_time, t.total_batches,
+	                 LEAST((SELECT COUNT(DISTINCT e.batch) FROM deploy_events e
+	                   WHERE e.deploy_id = t.id AND e.event_type = $4), t.total_batches) AS completed_batches
+	          FROM services s
+	          LEFT JOIN LATERAL (
+	              SELECT health_state FROM service_states
+	              WHERE service = s.name AND ($5 = '' OR region = $5)
+	              ORDER BY CASE health_state WHEN $6 THEN 0 WHEN $7 THEN 1 ELSE 2 END,
+	                       report_at DESC NULLS LAST
+	              LIMIT 1
+	          ) st ON TRUE
+	          LEFT JOIN LATERAL (
+	              SELECT id, version, region, deploy_state, start_time, total_batches FROM deploy_tasks
+	              WHERE service = s.name AND deploy_state IN ($1, $2, $3) AND ($5 = '' OR region = $5)
+	              ORDER BY CASE deploy_state WHEN $1 THEN 0 WHEN $2 THEN 1 ELSE 2 END,
+	                       start_time DESC NULLS LAST
+	              LIMIT 1
+	          ) t ON TRUE
+	          ORDER BY s.name`
+	rows, err := d.QueryContext(ctx, query, model.StatusDeploying, model.StatusStop, model.StatusUnrelease,
+		model.EventBatchFinish, region, model.HealthStateError, model.HealthStateWarning)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []model.ServiceSummary
+	for rows.Next() {
+		var summary model.ServiceSummary
+		var depsJSON, regionsJSON string
+		var deployID, version, deployRegion, deployState sql.NullString
+		var startTime sql.NullTime
+		var totalBatches sql.NullInt64
+		var completedBatches int
+		if err := rows.Scan(&summary.Name, &summary.Owner, &depsJSON, &regionsJSON, &summary.Health,
+			&deployID, &version, &deployRegion, &deployState, &startTime, &totalBatches, &completedBatches); err != nil {
+			return nil, err
+		}
+
+		if err := unmarshalServiceLists(&summary.Service, depsJSON, regionsJSON); err != nil {
+			return nil, err
+		}
+
+		if deployID.Valid {
+			task := &model.VersionDeployTask{CompletedBatches: completedBatches}
+			task.ID = deployID.String
+			task.Service = summary.Name
+			task.Version = version.String
+			task.Region = deployRegion.String
+			task.DeployState = model.DeployState(deployState.String)
+			task.TotalBatches = int(totalBatches.Int64)
+			if startTime.Valid {
+				task.StartTime = &startTime.Time
+			}
+			summary.ActiveDeploy = task
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
 // GetServiceByName 根据名称获取服务信息
 func (d *Database) GetServiceByName(ctx context.Context, name string) (*model.Service, error) {
-	query := `SELECT name, deps FROM services WHERE name = $1`
+	query := `SELECT name, owner, deps, regions FROM services WHERE name = $1`
 	row := d.QueryRowContext(ctx, query, name)
 
 	var service model.Service
-	var depsJSON string
-	if err := row.Scan(&service.Name, &depsJSON); err != nil {
+	var depsJSON, regionsJSON string
+	if err := row.Scan(&service.Name, &service.Owner, &depsJSON, &regionsJSON); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
 
-	if depsJSON != "" {
-		if err := json.Unmarshal([]byte(depsJSON), &service.Deps); err != nil {
-			return nil, err
-		}
+	if err := unmarshalServiceLists(&service, depsJSON, regionsJSON); err != nil {
+		return nil, err
 	}
 
 	return &service, nil
@@ -62,28 +142,48 @@ func (d *Database) GetServiceByName(ctx context.Context, name string) (*model.Se
 
 // CreateService 创建服务
 func (d *Database) CreateService(ctx context.Context, service *model.Service) error {
-	depsJSON, err := json.Marshal(service.Deps)
+	depsJSON, regionsJSON, err := marshalServiceLists(service)
 	if err != nil {
 		return err
 	}
 
-	query := `INSERT INTO services (name, deps) VALUES ($1, $2)`
-	_, err = d.ExecContext(ctx, query, service.Name, string(depsJSON))
+	query := `INSERT INTO services (name, owner, deps, regions) VALUES ($1, $2, $3, $4)`
+	_, err = d.ExecContext(ctx, query, service.Name, service.Owner, depsJSON, regionsJSON)
 	return err
 }
 
 // UpdateService 更新服务信息
 func (d *Database) UpdateService(ctx context.Context, service *model.Service) error {
-	depsJSON, err := json.Marshal(service.Deps)
+	depsJSON, regionsJSON, err := marshalServiceLists(service)
 	if err != nil {
 		return err
 	}
 
-	query := `UPDATE services SET deps = $1 WHERE name = $2`
-	_, err = d.ExecContext(ctx, query, string(depsJSON), service.Name)
+	query := `UPDATE services SET owner = $1, deps = $2, regions = $3 WHERE name = $4`
+	_, err = d.ExecContext(ctx, query, service.Owner, depsJSON, regionsJSON, service.Name)
 	return err
 }
 
+// marshalServiceLists 将deps和regions序列化为JSON数组，nil写为空数组
+func marshalServiceLists(service *model.Service) (string, string, error) {
+	deps, regions := service.Deps, service.Regions
+	if deps == nil {
+		deps = []string{}
+	}
+	if regions == nil {
+		regions = []string{}
+	}
+	depsJSON, err := json.Marshal(deps)
+	if err != nil {
+		return "", "", err
+	}
+	regionsJSON, err := json.Marshal(regions)
+	if err != nil {
+		return "", "", err
+	}
+	return string(depsJSON), string(regionsJSON), nil
+}
+
 // DeleteService 删除服务
 func (d *Database) DeleteService(ctx context.Context, name string) error {
 	query := `DELETE FROM services WHERE name = $1`
@@ -91,11 +191,40 @@ func (d *Database) DeleteService(ctx context.Context, name string) error {
 	return err
 }
 
+// serviceGraphLockKey 服务依赖图的事务级advisory lock
+const serviceGraphLockKey = 7241062
+
+// LockServiceGraph 获取事务级advisory lock，事务结束时自动释放
+func (d *Database) LockServiceGraph(ctx context.Context) error {
+	_, err := d.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, serviceGraphLockKey)
+	return err
+}
+
 // ===== 服务版本操作 =====
 
+const serviceVersionColumns = `version, service, create_time, package_url, checksum, changelog, build_meta, deprecated_at`
+
+// scanServiceVersion 扫描一行服务版本数据
+func scanServiceVersion(scanner interface{ Scan(dest ...any) error }) (*model.ServiceVersion, error) {
+	var version model.ServiceVersion
+	var buildMetaJSON *string
+	if err := scanner.Scan(&version.Version, &version.Service, &version.CreateTime, &version.PackageURL,
+		&version.Checksum, &version.Changelog, &buildMetaJSON, &version.DeprecatedAt); err != nil {
+		return nil, err
+	}
+
+	if buildMetaJSON != nil && *buildMetaJSON != "" {
+		if err := json.Unmarshal([]byte(*buildMetaJSON), &version.BuildMeta); err != nil {
+			return nil, err
+		}
+	}
+
+	return &version, nil
+}
+
 // GetServiceVersions 获取服务版本列表
 func (d *Database) GetServiceVersions(ctx context.Context, serviceName string) ([]model.ServiceVersion, error) {
-	query := `SELECT version, service, create_time FROM service_versions WHERE service = $1 ORDER BY create_time DESC`
+	query := `SELECT ` + serviceVersionColumns + ` FROM service_versions WHERE service = $1 ORDER BY create_time DESC`
 	rows, err := d.QueryContext(ctx, query, serviceName)
 	if err != nil {
 		return nil, err
@@ -104,63 +233,124 @@ func (d *Database) GetServiceVersions(ctx context.Context, serviceName string) (
 
 	var versions []model.ServiceVersion
 	for rows.Next() {
-		var version model.ServiceVersion
-		if err := rows.Scan(&version.Version, &version.Service, &version.CreateTime); err != nil {
+		version, err := scanServiceVersion(rows)
+		if err != nil {
 			return nil, err
 		}
-		versions = append(versions, version)
+		versions = append(versions, *version)
 	}
 
 	return versions, rows.Err()
 }
 
+// GetServiceVersion 获取单个服务版本
+func (d *Database) GetServiceVersion(ctx context.Context, serviceName, version string) (*model.ServiceVersion, error) {
+	query := `SELECT ` + serviceVersionColumns + ` FROM service_versions WHERE service = $1 AND version = $2`
+	row := d.QueryRowContext(ctx, query, serviceName, version)
+
+	v, err := scanServiceVersion(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
 // CreateServiceVersion 创建服务版本
 func (d *Database) CreateServiceVersion(ctx context.Context, version *model.ServiceVersion) error {
-	query := `INSERT INTO service_versions (version, service, create_time) VALUES ($1, $2, $3)`
-	_, err := d.ExecContext(ctx, query, version.Version, version.Service, version.CreateTime)
+	var buildMeta any
+	if len(version.BuildMeta) > 0 {
+		b, err := json.Marshal(version.BuildMeta)
+		if err != nil {
+			return err
+		}
+		buildMeta = string(b)
+	}
+
+	query := `INSERT INTO service_versions (version, service, create_time, package_url, checksum, changelog, build_meta)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err := d.ExecContext(ctx, query, version.Version, version.Service, version.CreateTime,
+		version.PackageURL, version.Checksum, version.Changelog, buildMeta)
 	return err
 }
 
-// ===== 服务实例操作 =====
+// DeprecateServiceVersion 将服务版本标记为废弃，返回是否有记录被更新
+func (d *Database) DeprecateServiceVersion(ctx context.Context, serviceName, version string) (bool, error) {
+	query := `UPDATE service_versions SET deprecated_at = NOW()
+	          WHERE service = $1 AND version = $2 AND deprecated_at IS NULL`
+	result, err := d.ExecContext(ctx, query, serviceName, version)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
 
-// GetServiceInstances 获取服务实例列表
-func (d *Database) GetServiceInstances(ctx context.Context, serviceName string) ([]model.ServiceInstance, error) {
-	query := `SELECT id, service, version FROM service_instances WHERE service = $1`
-	rows, err := d.QueryContext(ctx, query, serviceName)
+// GetVersionReleaseStats 按版本统计服务的发布情况
+func (d *Database) GetVersionReleaseStats(ctx context.Context, serviceName string) (map[string]model.VersionReleaseStat, error) {
+	query := `SELECT version,
+	                 bool_or(deploy_state <> $2),
+	                 bool_or(deploy_state = $3),
+	                 bool_or(deploy_state = $4)
+	          FROM deploy_tasks WHERE service = $1 GROUP BY version`
+	rows, err := d.QueryContext(ctx, query, serviceName,
+		model.StatusUnrelease, model.StatusCompleted, model.StatusRollback)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var instances []model.ServiceInstance
+	stats := make(map[string]model.VersionReleaseStat)
 	for rows.Next() {
-		var instance model.ServiceInstance
-		if err := rows.Scan(&instance.ID, &instance.Service, &instance.Version); err != nil {
+		var version string
+		var stat model.VersionReleaseStat
+		if err := rows.Scan(&version, &stat.Released, &stat.Completed, &stat.RolledBack); err != nil {
 			return nil, err
 		}
-		instances = append(instances, instance)
+		stats[version] = stat
 	}
 
-	return instances, rows.Err()
+	return stats, rows.Err()
 }
 
-// CreateServiceInstance 创建服务实例
-func (d *Database) CreateServiceInstance(ctx context.Context, instance *model.ServiceInstance) error {
-	query := `INSERT INTO service_instances (id, service, version) VALUES ($1, $2, $3)`
-	_, err := d.ExecContext(ctx, query, instance.ID, instance.Service, instance.Version)
-	return err
+// GetRunningVersions 获取当前有实例运行（未失联）的版本集合
+func (d *Database) GetRunningVersions(ctx context.Context, serviceName string) (map[string]bool, error) {
+	query := `SELECT DISTINCT version FROM service_instances WHERE service = $1 AND status <> $2 AND version <> ''`
+	rows, err := d.QueryContext(ctx, query, serviceName, model.InstanceStatusLost)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[string]bool)
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions[version] = true
+	}
+
+	return versions, rows.Err()
 }
 
 // ===== 服务状态操作 =====
 
-// GetServiceState 获取服务状态
+const serviceStateColumns = `service, version, region, report_at, resolved_at, health_state, correlation_id`
+
+// GetServiceState 获取服务状态（所有区域中最近上报的一条）
 func (d *Database) GetServiceState(ctx context.Context, serviceName string) (*model.ServiceState, error) {
-	query := `SELECT service, version, report_at, resolved_at, health_state, correlation_id
+	query := `SELECT ` + serviceStateColumns + `
 	          FROM service_states WHERE service = $1 ORDER BY report_at DESC LIMIT 1`
 	row := d.QueryRowContext(ctx, query, serviceName)
 
 	var state model.ServiceState
-	if err := row.Scan(&state.Service, &state.Version, &state.ReportAt,
+	if err := row.Scan(&state.Service, &state.Version, &state.Region, &state.ReportAt,
 		&state.ResolvedAt, &state.HealthState, &state.CorrelationID); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -170,3 +360,80 @@ func (d *Database) GetServiceState(ctx context.Context, serviceName string) (*mo
 
 	return &state, nil
 }
+
+// GetServiceStatesByService 获取服务所有版本的状态，按版本索引
+// region为空时同一版本取各区域中最差的状态（Error > Warning > Normal），同级取最近上报的
+func (d *Database) GetServiceStatesByService(ctx context.Context, serviceName, region string) (map[string]*model.ServiceState, error) {
+	query := `SELECT DISTINCT ON (version) ` + serviceStateColumns + `
+	          FROM service_states WHERE service = $1 AND ($2 = '' OR region = $2)
+	          ORDER BY version,
+	                   CASE health_state WHEN $3 THEN 0 WHEN $4 THEN 1 ELSE 2 END,
+	                   report_at DESC NULLS LAST`
+	rows, err := d.QueryContext(ctx, query, serviceName, region, model.HealthStateError, model.HealthStateWarning)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]*model.ServiceState)
+	for rows.Next() {
+		var state model.ServiceState
+		if err := rows.Scan(&state.Service, &state.Version, &state.Region, &state.ReportAt,
+			&state.ResolvedAt, &state.HealthState, &state.CorrelationID); err != nil {
+			return nil, err
+		}
+		states[state.Version] = &state
+	}
+
+	return states, rows.Err()
+}
+
+// GetServicesHealth 批量获取每个服务最差的健康状态（Error > Warning > Normal），region不为空时只看该区域
+func (d *Database) GetServicesHealth(ctx context.Context, region string) (map[string]model.HealthState, error) {
+	query := `SELECT DISTINCT ON (service) service, health_state
+	          FROM service_states WHERE $1 = '' OR region = $1
+	          ORDER BY service,
+	                   CASE health_state WHEN $2 THEN 0 WHEN $3 THEN 1 ELSE 2 END,
+	                   report_at DESC NULLS LAST`
+	rows, err := d.QueryContext(ctx, query, region, model.HealthStateError, model.HealthStateWarning)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	health := make(map[string]model.HealthState)
+	for rows.Next() {
+		var service string
+		var state model.HealthState
+		if err := rows.Scan(&service, &state); err != nil {
+			return nil, err
+		}
+		health[service] = state
+	}
+
+	return health, rows.Err()
+}
+
+// ===== 服务指标清单操作 =====
+
+// GetServiceMetricNames 获取服务登记的指标名清单（service_metrics表），未登记时返回空
+func (d *Database) GetServiceMetricNames(ctx context.Context, serviceName string) ([]string, error) {
+	query := `SELECT metrics FROM service_metrics WHERE service = $1`
+	row := d.QueryRowContext(ctx, query, serviceName)
+
+	var metricsJSON string
+	if err := row.Scan(&metricsJSON); err != nil {
+		if err == sql.ErrNoRows {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	var metrics []string
+	if metricsJSON != "" {
+		if err := json.Unmarshal([]byte(metricsJSON), &metrics); err != nil {
+			return nil, err
+		}
+	}
+	return metrics, nil
+}
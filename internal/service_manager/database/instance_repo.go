@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/region"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// ===== 服务实例操作 =====
+
+const serviceInstanceColumns = `id, service, region, version, status, host, ip, port, source, registered_at, last_heartbeat`
+
+func scanServiceInstance(scanner interface{ Scan(dest ...any) error }) (*model.ServiceInstance, error) {
+	var instance model.ServiceInstance
+	if err := scanner.Scan(&instance.ID, &instance.Service, &instance.Region, &instance.Version, &instance.Status,
+		&instance.Host, &instance.IP, &instance.Port, &instance.Source,
+		&instance.RegisteredAt, &instance.LastHeartbeat); err != nil {
+		return nil, err
+	}
+	return &instance, nil
+}
+
+func (d *Database) queryServiceInstances(ctx context.Context, query string, args ...any) ([]model.ServiceInstance, error) {
+	rows, err := d.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var instances []model.ServiceInstance
+	for rows.Next() {
+		instance, err := scanServiceInstance(rows)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, *instance)
+	}
+
+	return instances, rows.Err()
+}
+
+// GetServiceInstances 获取服务实例列表
+func (d *Database) GetServiceInstances(ctx context.Context, serviceName string) ([]model.ServiceInstance, error) {
+	query := `SELECT ` + serviceInstanceColumns + ` FROM service_instances WHERE service = $1 ORDER BY id`
+	return d.queryServiceInstances(ctx, query, serviceName)
+}
+
+// GetServiceInstance 获取单个服务实例
+func (d *Database) GetServiceInstance(ctx context.Context, serviceName, instanceID string) (*model.ServiceInstance, error) {
+	query := `SELECT ` + serviceInstanceColumns + ` FROM service_instances WHERE service = $1 AND id = $2`
+	instance, err := scanServiceInstance(d.QueryRowContext(ctx, query, serviceName, instanceID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return instance, nil
+}
+
+// FindServiceInstance 按实例ID、主机名或IP查找实例，ID匹配优先，其次取最近有心跳的实例；找不到返回nil
+func (d *Database) FindServiceInstance(ctx context.Context, key string) (*model.ServiceInstance, error) {
+	query := `SELECT ` + serviceInstanceColumns + ` FROM service_instances
+	          WHERE id = $1 OR host = $1 OR ip = $1
+	          ORDER BY (id = $1) DESC, last_heartbeat DESC NULLS LAST, id
+	          LIMIT 1`
+	instance, err := scanServiceInstance(d.QueryRowContext(ctx, query, key))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return instance, nil
+}
+
+// CreateServiceInstance 创建服务实例
+func (d *Database) CreateServiceInstance(ctx context.Context, instance *model.ServiceInstance) error {
+	status := instance.Status
+	if status == "" {
+		status = model.InstanceStatusActive
+	}
+	source := instance.Source
+	if source == "" {
+		source = model.InstanceSourceAPI
+	}
+	instanceRegion := instance.Region
+	if instanceRegion == "" {
+		instanceRegion = region.Default
+	}
+	query := `INSERT INTO service_instances (id, service, region, version, status, host, ip, port, source, registered_at, last_heartbeat)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())`
+	_, err := d.ExecContext(ctx, query, instance.ID, instance.Service, instanceRegion, instance.Version, status,
+		instance.Host, instance.IP, instance.Port, source)
+	return err
+}
+
+// UpsertServiceInstance 注册实例：不存在则创建，存在则更新地址、版本和状态并刷新心跳
+// 实例ID已属于其他服务时不修改并返回nil
+func (d *Database) UpsertServiceInstance(ctx context.Context, instance *model.ServiceInstance) (*model.ServiceInstance, error) {
+	query := `INSERT INTO service_instances (id, service, region, version, status, host, ip, port, source, registered_at, last_heartbeat)
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+	          ON CONFLICT (id) DO UPDATE
+	          SET region = EXCLUDED.region,
+	              version = EXCLUDED.version,
+	              status = EXCLUDED.status,
+	              host = EXCLUDED.host,
+	              ip = EXCLUDED.ip,
+	              port = EXCLUDED.port,
+	              source = EXCLUDED.source,
+	              last_heartbeat = NOW()
+	          WHERE service_instances.service = EXCLUDED.service
+	          RETURNING ` + serviceInstanceColumns
+	upserted, err := scanServiceInstance(d.QueryRowContext(ctx, query, instance.ID, instance.Service, instance.Region, instance.Version,
+		instance.Status, instance.Host, instance.IP, instance.Port, instance.Source))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return upserted, nil
+}
+
+// TouchServiceInstance 记录实例心跳，version/status为空时保持原值；实例不存在时返回nil
+func (d *Database) TouchServiceInstance(ctx context.Context, serviceName, instanceID, version string, status model.InstanceStatus) (*model.ServiceInstance, error) {
+	query := `UPDATE service_instances
+	          SET last_heartbeat = NOW(),
+	              version = COALESCE(NULLIF($3, ''), version),
+	              status = COALESCE(NULLIF($4, ''), CASE WHEN status = $5 THEN $6 ELSE status END)
+	          WHERE service = $1 AND id = $2
+	          RETURNING ` + serviceInstanceColumns
+	instance, err := scanServiceInstance(d.QueryRowContext(ctx, query, serviceName, instanceID, version, status,
+		model.InstanceStatusLost, model.InstanceStatusActive))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return instance, nil
+}
+
+// DeleteServiceInstance 注销实例，返回是否有记录被删除
+func (d *Database) DeleteServiceInstance(ctx context.Context, serviceName, instanceID string) (bool, error) {
+	query := `DELETE FROM service_instances WHERE service = $1 AND id = $2`
+	result, err := d.ExecContext(ctx, query, serviceName, instanceID)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// MarkLostInstances 将心跳早于cutoff的实例标记为失联，并返回本次被标记的实例
+// 单条UPDATE完成判定和标记，多副本同时执行时每个实例只会被返回一次
+func (d *Database) MarkLostInstances(ctx context.Context, cutoff time.Time) ([]model.ServiceInstance, error) {
+	query := `UPDATE service_instances SET status = $1
+	          WHERE status <> $1 AND last_heartbeat < $2
+	          RETURNING ` + serviceInstanceColumns
+	return d.queryServiceInstances(ctx, query, model.InstanceStatusLost, cutoff)
+}
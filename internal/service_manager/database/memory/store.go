@@ -0,0 +1,789 @@
+// Package memory 提供database.Store的内存实现，用于在没有Postgres的情况下对service和api层做单元测试
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/region"
+	"github.com/qiniu/zeroops/internal/service_manager/database"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+var _ database.Store = (*Store)(nil)
+
+// Store 内存仓储，语义与Postgres实现保持一致（未找到返回nil, nil，唯一键冲突返回错误）
+type Store struct {
+	mu   sync.Mutex
+	txMu sync.Mutex // 事务串行执行，回滚时恢复的快照不会覆盖其他事务的写入
+	data *tables
+	now  func() time.Time
+}
+
+type tables struct {
+	services    map[string]model.Service
+	versions    map[string]map[string]model.ServiceVersion // service -> version
+	metrics     map[string][]string
+	instances   map[string]model.ServiceInstance           // id -> instance
+	states      map[string]map[stateKey]model.ServiceState // service -> (version, region)
+	deployments map[string]model.ServiceDeployTask
+	events      []model.DeployEvent
+	nextDeploy  int64
+	nextEvent   int64
+}
+
+// New 创建空的内存仓储
+func New() *Store {
+	return &Store{data: newTables(), now: time.Now}
+}
+
+// stateKey service_states在服务内的主键
+type stateKey struct {
+	version string
+	region  string
+}
+
+func newTables() *tables {
+	return &tables{
+		services:    make(map[string]model.Service),
+		versions:    make(map[string]map[string]model.ServiceVersion),
+		metrics:     make(map[string][]string),
+		instances:   make(map[string]model.ServiceInstance),
+		states:      make(map[string]map[stateKey]model.ServiceState),
+		deployments: make(map[string]model.ServiceDeployTask),
+	}
+}
+
+// clone 复制表结构，用于事务回滚
+func (t *tables) clone() *tables {
+	c := &tables{
+		services:    maps.Clone(t.services),
+		versions:    make(map[string]map[string]model.ServiceVersion, len(t.versions)),
+		metrics:     maps.Clone(t.metrics),
+		instances:   maps.Clone(t.instances),
+		states:      make(map[string]map[stateKey]model.ServiceState, len(t.states)),
+		deployments: maps.Clone(t.deployments),
+		events:      slices.Clone(t.events),
+		nextDeploy:  t.nextDeploy,
+		nextEvent:   t.nextEvent,
+	}
+	for k, v := range t.versions {
+		c.versions[k] = maps.Clone(v)
+	}
+	for k, v := range t.states {
+		c.states[k] = maps.Clone(v)
+	}
+	return c
+}
+
+// SetClock 替换时间源，便于测试心跳超时等逻辑
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// SetServiceMetrics 登记服务指标清单（对应service_metrics表）
+func (s *Store) SetServiceMetrics(service string, metrics []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.metrics[service] = slices.Clone(metrics)
+}
+
+// PutServiceState 写入服务状态（对应告警模块对service_states的写入），Region为空时记为默认区域
+func (s *Store) PutServiceState(state model.ServiceState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if state.Region == "" {
+		state.Region = region.Default
+	}
+	if s.data.states[state.Service] == nil {
+		s.data.states[state.Service] = make(map[stateKey]model.ServiceState)
+	}
+	s.data.states[state.Service][stateKey{state.Version, state.Region}] = state
+}
+
+type txKey struct{}
+
+// InTx fn返回错误时恢复到事务开始前的数据；嵌套调用加入外层事务
+// 事务之间串行执行，相当于Postgres的可串行化隔离
+func (s *Store) InTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+	s.mu.Lock()
+	snapshot := s.data.clone()
+	s.mu.Unlock()
+
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		s.mu.Lock()
+		s.data = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// ===== 服务 =====
+
+func (s *Store) GetServices(ctx context.Context) ([]model.Service, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sortedServices(), nil
+}
+
+func (s *Store) sortedServices() []model.Service {
+	var out []model.Service
+	for _, svc := range s.data.services {
+		out = append(out, cloneService(svc))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func cloneService(svc model.Service) model.Service {
+	return model.Service{Name: svc.Name, Owner: svc.Owner, Deps: slices.Clone(svc.Deps), Regions: slices.Clone(svc.Regions)}
+}
+
+func (s *Store) GetServiceSummaries(ctx context.Context, regionName string) ([]model.ServiceSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rank := map[model.DeployState]int{model.StatusDeploying: 0, model.StatusStop: 1, model.StatusUnrelease: 2}
+	var out []model.ServiceSummary
+	for _, svc := range s.sortedServices() {
+		summary := model.ServiceSummary{Service: svc}
+		if worst := s.worstState(svc.Name, regionName); worst != nil {
+			summary.Health = worst.HealthState
+		}
+
+		var active *model.ServiceDeployTask
+		for _, task := range s.data.deployments {
+			r, ok := rank[task.DeployState]
+			if task.Service != svc.Name || !ok || (regionName != "" && task.Region != regionName) {
+				continue
+			}
+			if active == nil || r < rank[active.DeployState] ||
+				(r == rank[active.DeployState] && startsAfter(task.StartTime, active.StartTime)) {
+				task := task
+				active = &task
+			}
+		}
+		if active != nil {
+			summary.ActiveDeploy = &model.VersionDeployTask{
+				ServiceDeployTask: *active,
+				CompletedBatches:  s.completedBatches(active),
+			}
+		}
+		out = append(out, summary)
+	}
+	return out, nil
+}
+
+func (s *Store) GetServiceByName(ctx context.Context, name string) (*model.Service, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	svc, ok := s.data.services[name]
+	if !ok {
+		return nil, nil
+	}
+	svc = cloneService(svc)
+	return &svc, nil
+}
+
+func (s *Store) CreateService(ctx context.Context, service *model.Service) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.services[service.Name]; ok {
+		return fmt.Errorf("service %s already exists", service.Name)
+	}
+	s.data.services[service.Name] = cloneService(*service)
+	return nil
+}
+
+func (s *Store) UpdateService(ctx context.Context, service *model.Service) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.services[service.Name]; ok {
+		s.data.services[service.Name] = cloneService(*service)
+	}
+	return nil
+}
+
+// DeleteService 与外键ON DELETE CASCADE一致，同时删除版本、实例和状态
+func (s *Store) DeleteService(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data.services, name)
+	delete(s.data.versions, name)
+	delete(s.data.states, name)
+	for id, instance := range s.data.instances {
+		if instance.Service == name {
+			delete(s.data.instances, id)
+		}
+	}
+	return nil
+}
+
+// LockServiceGraph 事务已串行执行，无需额外加锁
+func (s *Store) LockServiceGraph(ctx context.Context) error {
+	if ctx.Value(txKey{}) == nil {
+		return fmt.Errorf("lock service graph outside a transaction")
+	}
+	return nil
+}
+
+// ===== 服务版本 =====
+
+func (s *Store) GetServiceVersions(ctx context.Context, serviceName string) ([]model.ServiceVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []model.ServiceVersion
+	for _, v := range s.data.versions[serviceName] {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreateTime.After(out[j].CreateTime) })
+	return out, nil
+}
+
+func (s *Store) GetServiceVersion(ctx context.Context, serviceName, version string) (*model.ServiceVersion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data.versions[serviceName][version]
+	if !ok {
+		return nil, nil
+	}
+	return &v, nil
+}
+
+func (s *Store) CreateServiceVersion(ctx context.Context, version *model.ServiceVersion) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.services[version.Service]; !ok {
+		return fmt.Errorf("service %s does not exist", version.Service)
+	}
+	if _, ok := s.data.versions[version.Service][version.Version]; ok {
+		return fmt.Errorf("version %s of %s already exists", version.Version, version.Service)
+	}
+	if s.data.versions[version.Service] == nil {
+		s.data.versions[version.Service] = make(map[string]model.ServiceVersion)
+	}
+	v := *version
+	v.BuildMeta = maps.Clone(version.BuildMeta)
+	v.DeprecatedAt = nil
+	v.Status = ""
+	s.data.versions[version.Service][version.Version] = v
+	return nil
+}
+
+func (s *Store) DeprecateServiceVersion(ctx context.Context, serviceName, version string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.data.versions[serviceName][version]
+	if !ok || v.DeprecatedAt != nil {
+		return false, nil
+	}
+	now := s.now()
+	v.DeprecatedAt = &now
+	s.data.versions[serviceName][version] = v
+	return true, nil
+}
+
+func (s *Store) GetServiceMetricNames(ctx context.Context, serviceName string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.data.metrics[serviceName]), nil
+}
+
+// ===== 服务实例 =====
+
+func (s *Store) GetServiceInstances(ctx context.Context, serviceName string) ([]model.ServiceInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []model.ServiceInstance
+	for _, instance := range s.data.instances {
+		if instance.Service == serviceName {
+			out = append(out, instance)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *Store) GetServiceInstance(ctx context.Context, serviceName, instanceID string) (*model.ServiceInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	instance, ok := s.data.instances[instanceID]
+	if !ok || instance.Service != serviceName {
+		return nil, nil
+	}
+	return &instance, nil
+}
+
+func (s *Store) FindServiceInstance(ctx context.Context, key string) (*model.ServiceInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if instance, ok := s.data.instances[key]; ok {
+		return &instance, nil
+	}
+	var found *model.ServiceInstance
+	for _, instance := range s.data.instances {
+		if instance.Host != key && instance.IP != key {
+			continue
+		}
+		if found == nil || heartbeatAfter(instance.LastHeartbeat, found.LastHeartbeat) ||
+			(!heartbeatAfter(found.LastHeartbeat, instance.LastHeartbeat) && instance.ID < found.ID) {
+			found = &instance
+		}
+	}
+	return found, nil
+}
+
+// heartbeatAfter 与NULLS LAST一致，没有心跳的实例排在最后
+func heartbeatAfter(a, b *time.Time) bool {
+	if a == nil {
+		return false
+	}
+	return b == nil || a.After(*b)
+}
+
+func (s *Store) CreateServiceInstance(ctx context.Context, instance *model.ServiceInstance) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.instances[instance.ID]; ok {
+		return fmt.Errorf("instance %s already exists", instance.ID)
+	}
+	now := s.now()
+	created := *instance
+	if created.Region == "" {
+		created.Region = region.Default
+	}
+	if created.Status == "" {
+		created.Status = model.InstanceStatusActive
+	}
+	if created.Source == "" {
+		created.Source = model.InstanceSourceAPI
+	}
+	created.RegisteredAt = &now
+	created.LastHeartbeat = &now
+	s.data.instances[created.ID] = created
+	return nil
+}
+
+func (s *Store) UpsertServiceInstance(ctx context.Context, instance *model.ServiceInstance) (*model.ServiceInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	upserted := *instance
+	upserted.RegisteredAt = &now
+	if existing, ok := s.data.instances[instance.ID]; ok {
+		if existing.Service != instance.Service {
+			return nil, nil
+		}
+		upserted.RegisteredAt = existing.RegisteredAt
+	}
+	upserted.LastHeartbeat = &now
+	s.data.instances[upserted.ID] = upserted
+	return &upserted, nil
+}
+
+func (s *Store) TouchServiceInstance(ctx context.Context, serviceName, instanceID, version string, status model.InstanceStatus) (*model.ServiceInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	instance, ok := s.data.instances[instanceID]
+	if !ok || instance.Service != serviceName {
+		return nil, nil
+	}
+	now := s.now()
+	instance.LastHeartbeat = &now
+	if version != "" {
+		instance.Version = version
+	}
+	switch {
+	case status != "":
+		instance.Status = status
+	case instance.Status == model.InstanceStatusLost:
+		instance.Status = model.InstanceStatusActive
+	}
+	s.data.instances[instanceID] = instance
+	return &instance, nil
+}
+
+func (s *Store) DeleteServiceInstance(ctx context.Context, serviceName, instanceID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	instance, ok := s.data.instances[instanceID]
+	if !ok || instance.Service != serviceName {
+		return false, nil
+	}
+	delete(s.data.instances, instanceID)
+	return true, nil
+}
+
+func (s *Store) MarkLostInstances(ctx context.Context, cutoff time.Time) ([]model.ServiceInstance, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []model.ServiceInstance
+	for id, instance := range s.data.instances {
+		if instance.Status == model.InstanceStatusLost || instance.LastHeartbeat == nil || !instance.LastHeartbeat.Before(cutoff) {
+			continue
+		}
+		instance.Status = model.InstanceStatusLost
+		s.data.instances[id] = instance
+		out = append(out, instance)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *Store) GetRunningVersions(ctx context.Context, serviceName string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := make(map[string]bool)
+	for _, instance := range s.data.instances {
+		if instance.Service == serviceName && instance.Status != model.InstanceStatusLost && instance.Version != "" {
+			versions[instance.Version] = true
+		}
+	}
+	return versions, nil
+}
+
+// ===== 服务状态 =====
+
+// latestState 最近上报的状态，regionName为空时不限区域
+func (s *Store) latestState(serviceName, regionName string) *model.ServiceState {
+	var latest *model.ServiceState
+	for _, state := range s.data.states[serviceName] {
+		if regionName != "" && state.Region != regionName {
+			continue
+		}
+		if latest == nil || state.ReportAt.After(latest.ReportAt) {
+			state := state
+			latest = &state
+		}
+	}
+	return latest
+}
+
+func (s *Store) GetServiceState(ctx context.Context, serviceName string) (*model.ServiceState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latestState(serviceName, ""), nil
+}
+
+// healthRank 与Postgres实现的排序一致：Error最差，其次Warning
+func healthRank(state model.HealthState) int {
+	switch state {
+	case model.HealthStateError:
+		return 0
+	case model.HealthStateWarning:
+		return 1
+	}
+	return 2
+}
+
+// worstState 最差的状态，同级取最近上报的，regionName为空时不限区域
+func (s *Store) worstState(serviceName, regionName string) *model.ServiceState {
+	var worst *model.ServiceState
+	for _, state := range s.data.states[serviceName] {
+		if regionName != "" && state.Region != regionName {
+			continue
+		}
+		if worst != nil {
+			rank, worstRank := healthRank(state.HealthState), healthRank(worst.HealthState)
+			if rank > worstRank || (rank == worstRank && !state.ReportAt.After(worst.ReportAt)) {
+				continue
+			}
+		}
+		state := state
+		worst = &state
+	}
+	return worst
+}
+
+func (s *Store) GetServiceStatesByService(ctx context.Context, serviceName, regionName string) (map[string]*model.ServiceState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]*model.ServiceState)
+	for key, state := range s.data.states[serviceName] {
+		if regionName != "" && key.region != regionName {
+			continue
+		}
+		if current, ok := out[key.version]; ok {
+			rank, currentRank := healthRank(state.HealthState), healthRank(current.HealthState)
+			if rank > currentRank || (rank == currentRank && !state.ReportAt.After(current.ReportAt)) {
+				continue
+			}
+		}
+		state := state
+		out[key.version] = &state
+	}
+	return out, nil
+}
+
+func (s *Store) GetServicesHealth(ctx context.Context, regionName string) (map[string]model.HealthState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]model.HealthState)
+	for service := range s.data.states {
+		if worst := s.worstState(service, regionName); worst != nil {
+			out[service] = worst.HealthState
+		}
+	}
+	return out, nil
+}
+
+// ===== 发布任务 =====
+
+func (s *Store) CreateDeployment(ctx context.Context, req *model.CreateDeploymentRequest) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.nextDeploy++
+	deployID := fmt.Sprintf("deploy-%d", s.data.nextDeploy)
+
+	task := model.ServiceDeployTask{
+		ID:                deployID,
+		Service:           req.Service,
+		Version:           req.Version,
+		Region:            req.Region,
+		Instances:         []string{},
+		DeployState:       model.StatusUnrelease,
+		TotalBatches:      req.TotalBatches,
+		ObservationWindow: req.ObservationWindow,
+	}
+	if req.ScheduleTime == nil {
+		now := s.now()
+		task.StartTime = &now
+		task.DeployState = model.StatusDeploying
+	} else {
+		start := *req.ScheduleTime
+		task.StartTime = &start
+	}
+	if task.TotalBatches <= 0 {
+		task.TotalBatches = model.DefaultTotalBatches
+	}
+	if task.ObservationWindow <= 0 {
+		task.ObservationWindow = model.DefaultObservationWindow
+	}
+	if task.Region == "" {
+		task.Region = region.Default
+	}
+	s.data.deployments[deployID] = task
+	return deployID, nil
+}
+
+func toDeployment(task model.ServiceDeployTask) model.Deployment {
+	return model.Deployment{
+		ID:           task.ID,
+		Service:      task.Service,
+		Version:      task.Version,
+		Region:       task.Region,
+		Status:       task.DeployState,
+		ScheduleTime: task.StartTime,
+		FinishTime:   task.EndTime,
+	}
+}
+
+func (s *Store) GetDeploymentByID(ctx context.Context, deployID string) (*model.Deployment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.data.deployments[deployID]
+	if !ok {
+		return nil, nil
+	}
+	deployment := toDeployment(task)
+	return &deployment, nil
+}
+
+func (s *Store) GetDeployments(ctx context.Context, query *model.DeploymentQuery) ([]model.Deployment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var tasks []model.ServiceDeployTask
+	for _, task := range s.data.deployments {
+		if query.Type != "" && task.DeployState != query.Type {
+			continue
+		}
+		if query.Service != "" && task.Service != query.Service {
+			continue
+		}
+		if query.Region != "" && task.Region != query.Region {
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return startsAfter(tasks[i].StartTime, tasks[j].StartTime) })
+	if query.Limit > 0 && len(tasks) > query.Limit {
+		tasks = tasks[:query.Limit]
+	}
+
+	var out []model.Deployment
+	for _, task := range tasks {
+		out = append(out, toDeployment(task))
+	}
+	return out, nil
+}
+
+// updateState 仅当任务处于from之一时修改为to，返回是否修改
+func (s *Store) updateState(deployID string, to model.DeployState, from ...model.DeployState) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.data.deployments[deployID]
+	if !ok || !slices.Contains(from, task.DeployState) {
+		return false
+	}
+	task.DeployState = to
+	s.data.deployments[deployID] = task
+	return true
+}
+
+func (s *Store) UpdateDeployment(ctx context.Context, deployID string, req *model.UpdateDeploymentRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.data.deployments[deployID]
+	if !ok || task.DeployState != model.StatusUnrelease {
+		return nil
+	}
+	if req.Version != "" {
+		task.Version = req.Version
+	}
+	if req.ScheduleTime != nil {
+		start := *req.ScheduleTime
+		task.StartTime = &start
+	}
+	s.data.deployments[deployID] = task
+	return nil
+}
+
+func (s *Store) DeleteDeployment(ctx context.Context, deployID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	task, ok := s.data.deployments[deployID]
+	if !ok || task.DeployState != model.StatusUnrelease {
+		return false, nil
+	}
+	delete(s.data.deployments, deployID)
+	return true, nil
+}
+
+func (s *Store) PauseDeployment(ctx context.Context, deployID string) (bool, error) {
+	return s.updateState(deployID, model.StatusStop, model.StatusDeploying), nil
+}
+
+func (s *Store) ContinueDeployment(ctx context.Context, deployID string) (bool, error) {
+	return s.updateState(deployID, model.StatusDeploying, model.StatusStop), nil
+}
+
+func (s *Store) RollbackDeployment(ctx context.Context, deployID string) (bool, error) {
+	return s.updateState(deployID, model.StatusRollback, model.StatusDeploying, model.StatusStop), nil
+}
+
+func (s *Store) CheckDeploymentConflict(ctx context.Context, service, version, regionName, excludeID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, task := range s.data.deployments {
+		if task.ID == excludeID || task.Service != service || task.Version != version || task.Region != regionName {
+			continue
+		}
+		switch task.DeployState {
+		case model.StatusUnrelease, model.StatusDeploying, model.StatusStop:
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) GetVersionDeployTasks(ctx context.Context, service, regionName string) (map[string]*model.VersionDeployTask, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]*model.VersionDeployTask)
+	for _, task := range s.data.deployments {
+		if task.Service != service || task.DeployState == model.StatusRollback {
+			continue
+		}
+		if regionName != "" && task.Region != regionName {
+			continue
+		}
+		if current, ok := out[task.Version]; ok && !startsAfter(task.StartTime, current.StartTime) {
+			continue
+		}
+		out[task.Version] = &model.VersionDeployTask{
+			ServiceDeployTask: task,
+			CompletedBatches:  s.completedBatches(&task),
+		}
+	}
+	return out, nil
+}
+
+func (s *Store) GetVersionReleaseStats(ctx context.Context, serviceName string) (map[string]model.VersionReleaseStat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]model.VersionReleaseStat)
+	for _, task := range s.data.deployments {
+		if task.Service != serviceName {
+			continue
+		}
+		stat := out[task.Version]
+		stat.Released = stat.Released || task.DeployState != model.StatusUnrelease
+		stat.Completed = stat.Completed || task.DeployState == model.StatusCompleted
+		stat.RolledBack = stat.RolledBack || task.DeployState == model.StatusRollback
+		out[task.Version] = stat
+	}
+	return out, nil
+}
+
+// ===== 发布事件 =====
+
+func (s *Store) InsertDeployEvent(ctx context.Context, event *model.DeployEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.nextEvent++
+	event.ID = s.data.nextEvent
+	event.CreatedAt = s.now()
+	s.data.events = append(s.data.events, *event)
+	return nil
+}
+
+func (s *Store) GetDeployEvents(ctx context.Context, deployID string, query *model.DeployEventQuery) ([]model.DeployEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := []model.DeployEvent{}
+	for _, event := range s.data.events {
+		if event.DeployID != deployID || (query != nil && event.ID <= query.After) {
+			continue
+		}
+		events = append(events, event)
+		if query != nil && query.Limit > 0 && len(events) == query.Limit {
+			break
+		}
+	}
+	return events, nil
+}
+
+// completedBatches 与Postgres实现一致：按批次序号去重，不超过总批次数
+func (s *Store) completedBatches(task *model.ServiceDeployTask) int {
+	batches := make(map[int]bool)
+	for _, event := range s.data.events {
+		if event.DeployID == task.ID && event.Type == model.EventBatchFinish && event.Batch != nil {
+			batches[*event.Batch] = true
+		}
+	}
+	return min(len(batches), task.TotalBatches)
+}
+
+// startsAfter 按开始时间倒序比较，空值排在最后
+func startsAfter(a, b *time.Time) bool {
+	if a == nil {
+		return false
+	}
+	if b == nil {
+		return true
+	}
+	return a.After(*b)
+}
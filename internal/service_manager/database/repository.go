@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/pg"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// ===== 按聚合划分的仓储接口 =====
+// *Database 为Postgres实现，memory包提供内存实现用于单元测试
+// 带region参数的查询中，region为空表示不限区域
+
+// ServiceRepository 服务及其版本、指标清单
+type ServiceRepository interface {
+	GetServices(ctx context.Context) ([]model.Service, error)
+	GetServiceSummaries(ctx context.Context, region string) ([]model.ServiceSummary, error)
+	GetServiceByName(ctx context.Context, name string) (*model.Service, error)
+	CreateService(ctx context.Context, service *model.Service) error
+	UpdateService(ctx context.Context, service *model.Service) error
+	DeleteService(ctx context.Context, name string) error
+	// LockServiceGraph 锁定服务依赖图直到事务结束，须在InTx内调用，使依赖校验与写入串行执行
+	LockServiceGraph(ctx context.Context) error
+
+	GetServiceVersions(ctx context.Context, serviceName string) ([]model.ServiceVersion, error)
+	GetServiceVersion(ctx context.Context, serviceName, version string) (*model.ServiceVersion, error)
+	CreateServiceVersion(ctx context.Context, version *model.ServiceVersion) error
+	DeprecateServiceVersion(ctx context.Context, serviceName, version string) (bool, error)
+
+	GetServiceMetricNames(ctx context.Context, serviceName string) ([]string, error)
+}
+
+// InstanceRepository 服务实例
+type InstanceRepository interface {
+	GetServiceInstances(ctx context.Context, serviceName string) ([]model.ServiceInstance, error)
+	GetServiceInstance(ctx context.Context, serviceName, instanceID string) (*model.ServiceInstance, error)
+	FindServiceInstance(ctx context.Context, key string) (*model.ServiceInstance, error)
+	CreateServiceInstance(ctx context.Context, instance *model.ServiceInstance) error
+	// UpsertServiceInstance 实例ID已属于其他服务时返回nil
+	UpsertServiceInstance(ctx context.Context, instance *model.ServiceInstance) (*model.ServiceInstance, error)
+	TouchServiceInstance(ctx context.Context, serviceName, instanceID, version string, status model.InstanceStatus) (*model.ServiceInstance, error)
+	DeleteServiceInstance(ctx context.Context, serviceName, instanceID string) (bool, error)
+	MarkLostInstances(ctx context.Context, cutoff time.Time) ([]model.ServiceInstance, error)
+	GetRunningVersions(ctx context.Context, serviceName string) (map[string]bool, error)
+}
+
+// StateRepository 服务健康状态
+type StateRepository interface {
+	GetServiceState(ctx context.Context, serviceName string) (*model.ServiceState, error)
+	GetServiceStatesByService(ctx context.Context, serviceName, region string) (map[string]*model.ServiceState, error)
+	GetServicesHealth(ctx context.Context, region string) (map[string]model.HealthState, error)
+}
+
+// DeploymentRepository 发布任务及其事件时间线
+type DeploymentRepository interface {
+	CreateDeployment(ctx context.Context, req *model.CreateDeploymentRequest) (string, error)
+	GetDeploymentByID(ctx context.Context, deployID string) (*model.Deployment, error)
+	GetDeployments(ctx context.Context, query *model.DeploymentQuery) ([]model.Deployment, error)
+	UpdateDeployment(ctx context.Context, deployID string, req *model.UpdateDeploymentRequest) error
+	DeleteDeployment(ctx context.Context, deployID string) (bool, error)
+	PauseDeployment(ctx context.Context, deployID string) (bool, error)
+	ContinueDeployment(ctx context.Context, deployID string) (bool, error)
+	RollbackDeployment(ctx context.Context, deployID string) (bool, error)
+	CheckDeploymentConflict(ctx context.Context, service, version, region, excludeID string) (bool, error)
+	GetVersionDeployTasks(ctx context.Context, service, region string) (map[string]*model.VersionDeployTask, error)
+	GetVersionReleaseStats(ctx context.Context, serviceName string) (map[string]model.VersionReleaseStat, error)
+
+	InsertDeployEvent(ctx context.Context, event *model.DeployEvent) error
+	GetDeployEvents(ctx context.Context, deployID string, query *model.DeployEventQuery) ([]model.DeployEvent, error)
+}
+
+// Store 业务层依赖的全部仓储，InTx内的调用处于同一事务
+type Store interface {
+	pg.Transactor
+	ServiceRepository
+	InstanceRepository
+	StateRepository
+	DeploymentRepository
+}
+
+var _ Store = (*Database)(nil)
@@ -1,15 +1,22 @@
 package model
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 // ===== 服务基础信息结构体 =====
 
 // ServiceItem API响应用的服务信息（对应/v1/services接口items格式）
 type ServiceItem struct {
-	Name        string      `json:"name"`        // 服务名称
-	DeployState DeployState `json:"deployState"` // 发布状态
-	Health      HealthState `json:"health"`      // 健康状态：Normal/Warning/Error
-	Deps        []string    `json:"deps"`        // 依赖关系（直接使用Service.Deps）
+	Name           string      `json:"name"`                     // 服务名称
+	Owner          string      `json:"owner,omitempty"`          // 负责团队
+	DeployState    DeployState `json:"deployState"`              // 发布状态，没有进行中的发布任务时为completed
+	Health         HealthState `json:"health"`                   // 健康状态：Normal/Warning/Error
+	Deps           []string    `json:"deps"`                     // 依赖关系（直接使用Service.Deps）
+	Regions        []string    `json:"regions,omitempty"`        // 部署的区域，为空表示不限区域
+	ActiveDeployID string      `json:"activeDeployID,omitempty"` // 进行中的发布任务ID
+	DeployProgress *float64    `json:"deployProgress,omitempty"` // 进行中发布任务的进度（已完成批次/总批次，0~1）
 }
 
 // ServicesResponse 服务列表API响应（对应/v1/services接口）
@@ -20,12 +27,22 @@ type ServicesResponse struct {
 
 // ActiveVersionItem 活跃版本项目
 type ActiveVersionItem struct {
-	Version                 string      `json:"version"`                 // v1.0.1
-	DeployID                string      `json:"deployID"`                // 1001
-	StartTime               time.Time   `json:"startTime"`               // 开始时间
-	EstimatedCompletionTime time.Time   `json:"estimatedCompletionTime"` // 预估完成时间
-	Instances               int         `json:"instances"`               // 实例个数
-	Health                  HealthState `json:"health"`                  // 健康状态：Normal/Warning/Error
+	Version                 string           `json:"version"`                 // v1.0.1
+	DeployID                string           `json:"deployID"`                // 引入该版本的发布任务ID
+	DeployState             DeployState      `json:"deployState,omitempty"`   // 该发布任务的状态
+	StartTime               time.Time        `json:"startTime"`               // 开始时间
+	EstimatedCompletionTime time.Time        `json:"estimatedCompletionTime"` // 预估完成时间
+	Instances               int              `json:"instances"`               // 实例个数
+	Health                  HealthState      `json:"health"`                  // 健康状态：Normal/Warning/Error
+	InstanceList            []InstanceHealth `json:"instanceList"`            // 实例明细
+}
+
+// InstanceHealth 活跃版本下的实例及其健康状态
+type InstanceHealth struct {
+	ID     string         `json:"id"`
+	Region string         `json:"region"`
+	Status InstanceStatus `json:"status"`
+	Health HealthState    `json:"health"`
 }
 
 // PrometheusQueryRangeResponse Prometheus query_range接口响应格式
@@ -48,14 +65,23 @@ type PrometheusTimeSeries struct {
 
 // MetricTimeSeriesQuery 时序指标查询参数
 type MetricTimeSeriesQuery struct {
-	Service string `form:"service" binding:"required"`
-	Name    string `form:"name" binding:"required"`
+	Service string `form:"-"` // 路径参数
+	Name    string `form:"-"` // 路径参数
 	Version string `form:"version,omitempty"`
 	Start   string `form:"start" binding:"required"` // RFC3339格式时间
 	End     string `form:"end" binding:"required"`   // RFC3339格式时间
 	Granule string `form:"granule,omitempty"`        // 1m/5m/1h等
 }
 
+// CreateServiceVersionRequest 注册服务版本请求（POST /v1/services/:service/versions）
+type CreateServiceVersionRequest struct {
+	Version    string            `json:"version" binding:"required"`
+	PackageURL string            `json:"packageURL" binding:"required"`
+	Checksum   string            `json:"checksum,omitempty"`  // sha256:<hex> 或 <hex>
+	Changelog  string            `json:"changelog,omitempty"` // 变更说明
+	BuildMeta  map[string]string `json:"buildMeta,omitempty"` // 构建信息
+}
+
 // ===== 部署任务操作结构体 =====
 
 // Deployment API响应用的发布任务
@@ -63,6 +89,7 @@ type Deployment struct {
 	ID           string      `json:"id"`
 	Service      string      `json:"service"`
 	Version      string      `json:"version"`
+	Region       string      `json:"region"`
 	Status       DeployState `json:"status"`
 	ScheduleTime *time.Time  `json:"scheduleTime,omitempty"`
 	FinishTime   *time.Time  `json:"finishTime,omitempty"`
@@ -70,9 +97,20 @@ type Deployment struct {
 
 // CreateDeploymentRequest 创建发布任务请求
 type CreateDeploymentRequest struct {
-	Service      string     `json:"service" binding:"required"`
-	Version      string     `json:"version" binding:"required"`
-	ScheduleTime *time.Time `json:"scheduleTime,omitempty"` // 可选参数，不填为立即发布
+	Service           string     `json:"service" binding:"required"`
+	Version           string     `json:"version" binding:"required"`
+	Region            string     `json:"region,omitempty"`            // 可选参数，目标区域，默认default
+	ScheduleTime      *time.Time `json:"scheduleTime,omitempty"`      // 可选参数，不填为立即发布
+	TotalBatches      int        `json:"totalBatches,omitempty"`      // 可选参数，灰度批次数，默认1
+	ObservationWindow int        `json:"observationWindow,omitempty"` // 可选参数，每批观察窗口（秒），默认300
+}
+
+// Validate 校验必填字段，由API层在解析请求体后调用
+func (r *CreateDeploymentRequest) Validate() error {
+	if r.Service == "" || r.Version == "" {
+		return errors.New("service and version are required")
+	}
+	return nil
 }
 
 // UpdateDeploymentRequest 修改发布任务请求
@@ -85,6 +123,7 @@ type UpdateDeploymentRequest struct {
 type DeploymentQuery struct {
 	Type    DeployState `form:"type"`    // deploying/stop/rollback/completed
 	Service string      `form:"service"` // 服务名称过滤
+	Region  string      `form:"region"`  // 区域过滤
 	Start   string      `form:"start"`   // 分页起始
 	Limit   int         `form:"limit"`   // 分页大小
 }
@@ -18,4 +18,11 @@ const (
 	StatusStop      DeployState = "stop"      // 暂停发布
 	StatusRollback  DeployState = "rollback"  // 已回滚
 	StatusCompleted DeployState = "completed" // 发布完成
+	StatusDeleted   DeployState = "deleted"   // 已删除，只出现在状态流转事件中
+)
+
+// 发布任务默认参数
+const (
+	DefaultTotalBatches      = 1   // 默认灰度批次数
+	DefaultObservationWindow = 300 // 默认每批观察窗口（秒）
 )
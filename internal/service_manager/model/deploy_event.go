@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeployEventType 发布事件类型
+type DeployEventType string
+
+const (
+	EventStateTransition DeployEventType = "state_transition" // 发布状态流转
+	EventBatchStart      DeployEventType = "batch_start"      // 批次开始
+	EventBatchFinish     DeployEventType = "batch_finish"     // 批次结束
+	EventInstanceUpdate  DeployEventType = "instance_update"  // 实例更新
+	EventCanaryVerdict   DeployEventType = "canary_verdict"   // 灰度验证结论
+	EventOperatorAction  DeployEventType = "operator_action"  // 人工操作
+)
+
+// CanaryVerdict 灰度验证结论
+type CanaryVerdict string
+
+const (
+	VerdictPass CanaryVerdict = "pass"
+	VerdictFail CanaryVerdict = "fail"
+)
+
+// DeployEvent 发布事件（deploy_events表，只追加不修改）
+type DeployEvent struct {
+	ID        int64           `json:"id" db:"id"`                          // bigserial - 主键，单调递增
+	DeployID  string          `json:"deployID" db:"deploy_id"`             // varchar(32) - 关联deploy_tasks.id
+	Type      DeployEventType `json:"type" db:"event_type"`                // 事件类型
+	FromState DeployState     `json:"fromState,omitempty" db:"from_state"` // 流转前状态
+	ToState   DeployState     `json:"toState,omitempty" db:"to_state"`     // 流转后状态
+	Batch     *int            `json:"batch,omitempty" db:"batch"`          // 批次序号
+	Instance  string          `json:"instance,omitempty" db:"instance"`    // 实例ID
+	Verdict   CanaryVerdict   `json:"verdict,omitempty" db:"verdict"`      // 灰度验证结论
+	Operator  string          `json:"operator,omitempty" db:"operator"`    // 操作人
+	Message   string          `json:"message,omitempty" db:"message"`      // 描述信息
+	Detail    json.RawMessage `json:"detail,omitempty" db:"detail"`        // 附加信息（JSON）
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`           // 事件时间
+}
+
+// CreateDeployEventRequest 上报发布事件请求（由发布执行方调用）
+type CreateDeployEventRequest struct {
+	Type     DeployEventType `json:"type" binding:"required"`
+	Batch    *int            `json:"batch,omitempty"`
+	Instance string          `json:"instance,omitempty"`
+	Verdict  CanaryVerdict   `json:"verdict,omitempty"`
+	Message  string          `json:"message,omitempty"`
+	Detail   json.RawMessage `json:"detail,omitempty"`
+}
+
+// DeployEventQuery 发布事件查询参数
+type DeployEventQuery struct {
+	After int64 `form:"after"` // 只返回ID大于after的事件
+	Limit int   `form:"limit"` // 返回条数
+}
@@ -4,10 +4,29 @@ import "time"
 
 // ServiceDeployTask 服务部署任务信息
 type ServiceDeployTask struct {
-	ID          string      `json:"id" db:"id"`                    // varchar(32) - 主键
-	StartTime   *time.Time  `json:"startTime" db:"start_time"`     // time - 开始时间
-	EndTime     *time.Time  `json:"endTime" db:"end_time"`         // time - 结束时间
-	TargetRatio float64     `json:"targetRatio" db:"target_ratio"` // double(指导值) - 目标比例
-	Instances   []string    `json:"instances" db:"instances"`      // array(真实发布的节点列表) - 实例列表
-	DeployState DeployState `json:"deployState" db:"deploy_state"` // 部署状态
+	ID                string      `json:"id" db:"id"`                                // varchar(32) - 主键
+	Service           string      `json:"service" db:"service"`                      // varchar(255) - 发布的服务
+	Version           string      `json:"version" db:"version"`                      // varchar(255) - 发布的目标版本
+	Region            string      `json:"region" db:"region"`                        // varchar(64) - 发布的目标区域
+	StartTime         *time.Time  `json:"startTime" db:"start_time"`                 // time - 开始时间
+	EndTime           *time.Time  `json:"endTime" db:"end_time"`                     // time - 结束时间
+	TargetRatio       float64     `json:"targetRatio" db:"target_ratio"`             // double(指导值) - 目标比例
+	Instances         []string    `json:"instances" db:"instances"`                  // array(真实发布的节点列表) - 实例列表
+	DeployState       DeployState `json:"deployState" db:"deploy_state"`             // 部署状态
+	TotalBatches      int         `json:"totalBatches" db:"total_batches"`           // 总批次数
+	ObservationWindow int         `json:"observationWindow" db:"observation_window"` // 每批观察窗口（秒）
+}
+
+// VersionDeployTask 引入某个版本的发布任务及其批次进度
+type VersionDeployTask struct {
+	ServiceDeployTask
+	CompletedBatches int `json:"completedBatches"` // 已完成批次数（batch_finish事件数）
+}
+
+// RemainingBatches 剩余批次数
+func (t *VersionDeployTask) RemainingBatches() int {
+	if t.TotalBatches <= t.CompletedBatches {
+		return 0
+	}
+	return t.TotalBatches - t.CompletedBatches
 }
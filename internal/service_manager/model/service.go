@@ -2,6 +2,35 @@ package model
 
 // Service 服务基础信息
 type Service struct {
-	Name string   `json:"name" db:"name"` // varchar(255) - 主键
-	Deps []string `json:"deps" db:"deps"` // 依赖关系
+	Name    string   `json:"name" db:"name"`                 // varchar(255) - 主键
+	Owner   string   `json:"owner,omitempty" db:"owner"`     // 负责团队，告警富化时作为team标签；更新时省略则保持不变
+	Deps    []string `json:"deps" db:"deps"`                 // 依赖关系
+	Regions []string `json:"regions,omitempty" db:"regions"` // 部署的区域，为空表示不限区域；更新时省略则保持不变
+}
+
+// ServiceSummary 服务列表概览：服务信息、健康状态和当前进行中的发布任务
+type ServiceSummary struct {
+	Service
+	Health       HealthState        // 各版本、区域中最差的健康状态，未上报时为空
+	ActiveDeploy *VersionDeployTask // 进行中（deploying/stop/unrelease）的发布任务，没有时为nil
+}
+
+// ServiceTopology 服务依赖拓扑排序结果（GET /v1/services/topology）
+type ServiceTopology struct {
+	Order  []string   `json:"order"`  // 依赖在前的全序，可作为发布顺序
+	Levels [][]string `json:"levels"` // 分层结果，同层服务之间无依赖
+}
+
+// ServiceImpactItem 受影响的下游服务
+type ServiceImpactItem struct {
+	Name   string      `json:"name"`   // 服务名称
+	Depth  int         `json:"depth"`  // 与被分析服务的距离，直接依赖为1
+	Health HealthState `json:"health"` // 当前健康状态
+	Deps   []string    `json:"deps"`   // 该服务的直接依赖
+}
+
+// ServiceImpactResponse 服务影响面分析响应（GET /v1/services/:service/impact）
+type ServiceImpactResponse struct {
+	Service string              `json:"service"`
+	Items   []ServiceImpactItem `json:"items"` // 所有直接或间接依赖该服务的服务
 }
@@ -1,8 +1,51 @@
 package model
 
+import "time"
+
+// InstanceStatus 实例运行状态
+type InstanceStatus string
+
+const (
+	InstanceStatusActive  InstanceStatus = "active"  // 运行中
+	InstanceStatusPending InstanceStatus = "pending" // 发布中
+	InstanceStatusError   InstanceStatus = "error"   // 出现故障
+	InstanceStatusLost    InstanceStatus = "lost"    // 心跳超时失联（由服务端判定）
+)
+
 // ServiceInstance 服务实例信息
 type ServiceInstance struct {
-	ID      string `json:"id" db:"id"`           // 主键
-	Service string `json:"service" db:"service"` // varchar(255) - 外键引用services.name
-	Version string `json:"version" db:"version"` // varchar(255) - 外键引用service_versions.version
+	ID            string         `json:"id" db:"id"`                                  // 主键
+	Service       string         `json:"service" db:"service"`                        // varchar(255) - 外键引用services.name
+	Region        string         `json:"region" db:"region"`                          // varchar(64) - 所在区域
+	Version       string         `json:"version" db:"version"`                        // varchar(255) - 外键引用service_versions.version
+	Status        InstanceStatus `json:"status" db:"status"`                          // varchar(16) - 实例运行状态
+	Host          string         `json:"host,omitempty" db:"host"`                    // 主机名
+	IP            string         `json:"ip,omitempty" db:"ip"`                        // IP地址
+	Port          int            `json:"port,omitempty" db:"port"`                    // 端口
+	Source        string         `json:"source,omitempty" db:"source"`                // 注册来源：api/consul
+	RegisteredAt  *time.Time     `json:"registeredAt,omitempty" db:"registered_at"`   // 注册时间
+	LastHeartbeat *time.Time     `json:"lastHeartbeat,omitempty" db:"last_heartbeat"` // 最近一次心跳时间
+}
+
+// 实例注册来源
+const (
+	InstanceSourceAPI    = "api"
+	InstanceSourceConsul = "consul"
+)
+
+// RegisterInstanceRequest 实例注册请求（POST /v1/services/:service/instances）
+type RegisterInstanceRequest struct {
+	ID      string         `json:"id" binding:"required"`
+	Version string         `json:"version" binding:"required"`
+	Region  string         `json:"region,omitempty"` // 所在区域，默认default
+	Host    string         `json:"host,omitempty"`
+	IP      string         `json:"ip,omitempty"`
+	Port    int            `json:"port,omitempty"`
+	Status  InstanceStatus `json:"status,omitempty"` // active/pending/error，默认active
+}
+
+// InstanceHeartbeatRequest 实例心跳请求，字段为空时保持原值
+type InstanceHeartbeatRequest struct {
+	Version string         `json:"version,omitempty"`
+	Status  InstanceStatus `json:"status,omitempty"`
 }
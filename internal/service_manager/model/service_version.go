@@ -2,9 +2,33 @@ package model
 
 import "time"
 
+// VersionStatus 版本状态
+type VersionStatus string
+
+const (
+	VersionStatusUnreleased VersionStatus = "unreleased" // 从未发布
+	VersionStatusActive     VersionStatus = "active"     // 有实例正在运行
+	VersionStatusStable     VersionStatus = "stable"     // 曾完整发布且当前未运行，可作为回滚目标
+	VersionStatusRolledBack VersionStatus = "rolledback" // 发布过但被回滚
+	VersionStatusDeprecated VersionStatus = "deprecated" // 已废弃
+)
+
 // ServiceVersion 服务版本信息
 type ServiceVersion struct {
-	Version    string    `json:"version" db:"version"`        // varchar(255) - 主键
-	Service    string    `json:"service" db:"service"`        // varchar(255) - 外键引用services.name
-	CreateTime time.Time `json:"createTime" db:"create_time"` // 时间戳字段
+	Version      string            `json:"version" db:"version"`                      // varchar(255) - 主键
+	Service      string            `json:"service" db:"service"`                      // varchar(255) - 外键引用services.name
+	CreateTime   time.Time         `json:"createTime" db:"create_time"`               // 时间戳字段
+	PackageURL   string            `json:"packageURL,omitempty" db:"package_url"`     // 版本包下载地址
+	Checksum     string            `json:"checksum,omitempty" db:"checksum"`          // 版本包校验和（sha256）
+	Changelog    string            `json:"changelog,omitempty" db:"changelog"`        // 变更说明
+	BuildMeta    map[string]string `json:"buildMeta,omitempty" db:"build_meta"`       // 构建信息（commit、分支、流水线等）
+	DeprecatedAt *time.Time        `json:"deprecatedAt,omitempty" db:"deprecated_at"` // 废弃时间
+	Status       VersionStatus     `json:"status,omitempty" db:"-"`                   // 版本状态（查询时计算）
+}
+
+// VersionReleaseStat 版本的发布情况统计（来自deploy_tasks）
+type VersionReleaseStat struct {
+	Released   bool // 是否进入过发布流程
+	Completed  bool // 是否有完整发布完成的任务
+	RolledBack bool // 是否被回滚过
 }
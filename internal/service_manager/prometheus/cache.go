@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"sync"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// maxCacheEntries 缓存条目上限，超过后先清理过期条目
+const maxCacheEntries = 1024
+
+type cacheEntry struct {
+	resp     *model.PrometheusQueryRangeResponse
+	expireAt time.Time
+}
+
+// rangeCache query_range结果的短时内存缓存
+type rangeCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+func newRangeCache(ttl time.Duration) *rangeCache {
+	return &rangeCache{ttl: ttl, entries: make(map[string]cacheEntry), now: time.Now}
+}
+
+func (c *rangeCache) get(key string) (*model.PrometheusQueryRangeResponse, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.now().After(e.expireAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.resp, true
+}
+
+func (c *rangeCache) set(key string, resp *model.PrometheusQueryRangeResponse) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if len(c.entries) >= maxCacheEntries {
+		for k, e := range c.entries {
+			if now.After(e.expireAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			// 仍然超限时整体清空，避免无界增长
+			c.entries = make(map[string]cacheEntry)
+		}
+	}
+	c.entries[key] = cacheEntry{resp: resp, expireAt: now.Add(c.ttl)}
+}
@@ -0,0 +1,191 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+var (
+	ErrNotConfigured = errors.New("prometheus is not configured")
+	ErrInvalidQuery  = errors.New("invalid metric query")
+)
+
+// maxResponseBytes 单次响应体大小上限
+const maxResponseBytes = 32 << 20
+
+// APIError Prometheus返回的错误
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("prometheus error (status %d, %s): %s", e.StatusCode, e.Type, e.Message)
+}
+
+// Client Prometheus HTTP API客户端
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	cache      *rangeCache
+}
+
+// NewClient 根据配置创建客户端，未配置URL时返回nil
+func NewClient(cfg *config.PrometheusConfig) *Client {
+	if cfg == nil || strings.TrimSpace(cfg.URL) == "" {
+		return nil
+	}
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	c := &Client{
+		baseURL:    strings.TrimRight(cfg.URL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+	if cfg.CacheTTLSeconds > 0 {
+		c.cache = newRangeCache(time.Duration(cfg.CacheTTLSeconds) * time.Second)
+	}
+	return c
+}
+
+type apiResponse struct {
+	Status    string          `json:"status"`
+	ErrorType string          `json:"errorType"`
+	Error     string          `json:"error"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// QueryRange 执行query_range查询，成功结果会按配置短暂缓存
+func (c *Client) QueryRange(ctx context.Context, q *RangeQuery) (*model.PrometheusQueryRangeResponse, error) {
+	if c == nil {
+		return nil, ErrNotConfigured
+	}
+
+	key := q.cacheKey()
+	if resp, ok := c.cache.get(key); ok {
+		return resp, nil
+	}
+
+	params := url.Values{}
+	params.Set("query", q.Query)
+	params.Set("start", strconv.FormatInt(q.Start.Unix(), 10))
+	params.Set("end", strconv.FormatInt(q.End.Unix(), 10))
+	params.Set("step", strconv.FormatFloat(q.Step.Seconds(), 'f', -1, 64))
+
+	data, err := c.get(ctx, "/api/v1/query_range", params)
+	if err != nil {
+		return nil, err
+	}
+	result := &model.PrometheusQueryRangeResponse{Status: "success"}
+	if err := json.Unmarshal(data, &result.Data); err != nil {
+		return nil, fmt.Errorf("decode prometheus response: %w", err)
+	}
+	if result.Data.Result == nil {
+		result.Data.Result = []model.PrometheusTimeSeries{}
+	}
+	c.cache.set(key, result)
+	return result, nil
+}
+
+// QueryScalar 执行即时查询（/api/v1/query），返回结果中唯一样本的值
+// 结果为空或值为NaN时ok为false，结果包含多条序列时返回ErrInvalidQuery
+func (c *Client) QueryScalar(ctx context.Context, query string, ts time.Time) (float64, bool, error) {
+	if c == nil {
+		return 0, false, ErrNotConfigured
+	}
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", strconv.FormatInt(ts.Unix(), 10))
+	data, err := c.get(ctx, "/api/v1/query", params)
+	if err != nil {
+		return 0, false, err
+	}
+
+	var parsed struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return 0, false, fmt.Errorf("decode prometheus response: %w", err)
+	}
+	var sample []any // [timestamp, value]
+	switch parsed.ResultType {
+	case "scalar":
+		if err := json.Unmarshal(parsed.Result, &sample); err != nil {
+			return 0, false, fmt.Errorf("decode prometheus response: %w", err)
+		}
+	case "vector":
+		var vector []struct {
+			Value []any `json:"value"`
+		}
+		if err := json.Unmarshal(parsed.Result, &vector); err != nil {
+			return 0, false, fmt.Errorf("decode prometheus response: %w", err)
+		}
+		if len(vector) > 1 {
+			return 0, false, fmt.Errorf("%w: query returned %d series, want one", ErrInvalidQuery, len(vector))
+		}
+		if len(vector) == 0 {
+			return 0, false, nil
+		}
+		sample = vector[0].Value
+	default:
+		return 0, false, fmt.Errorf("%w: unexpected result type %q", ErrInvalidQuery, parsed.ResultType)
+	}
+
+	if len(sample) != 2 {
+		return 0, false, fmt.Errorf("decode prometheus response: invalid sample %v", sample)
+	}
+	str, _ := sample[1].(string)
+	v, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("decode prometheus response: %w", err)
+	}
+	if math.IsNaN(v) {
+		return 0, false, nil
+	}
+	return v, true, nil
+}
+
+// get 请求Prometheus HTTP API，返回成功响应的data字段
+func (c *Client) get(ctx context.Context, path string, params url.Values) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("query prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read prometheus response: %w", err)
+	}
+
+	var parsed apiResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, &APIError{StatusCode: resp.StatusCode, Type: "http", Message: strings.TrimSpace(string(body))}
+		}
+		return nil, fmt.Errorf("decode prometheus response: %w", err)
+	}
+	if parsed.Status != "success" {
+		return nil, &APIError{StatusCode: resp.StatusCode, Type: parsed.ErrorType, Message: parsed.Error}
+	}
+	return parsed.Data, nil
+}
@@ -0,0 +1,160 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/config"
+)
+
+// newFakePrometheus 启动一个模拟Prometheus query_range接口的httptest服务
+func newFakePrometheus(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path != "/api/v1/query_range" {
+			http.NotFound(w, r)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestBuildRangeQuery(t *testing.T) {
+	q, err := BuildRangeQuery("storage", "http_latency_ms", "v1.2.0", "2025-09-01T00:00:00Z", "2025-09-01T01:00:00Z", "1m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `http_latency_ms{service="storage",version="v1.2.0"}`; q.Query != want {
+		t.Fatalf("expected query %s, got %s", want, q.Query)
+	}
+	if q.Step != time.Minute {
+		t.Fatalf("expected 1m step, got %s", q.Step)
+	}
+
+	q, err = BuildRangeQuery("storage", "qps", "", "2025-09-01T00:00:00Z", "2025-09-02T00:00:00Z", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.Query != `qps{service="storage"}` || q.Step != 345*time.Second {
+		t.Fatalf("unexpected auto query: %s step=%s", q.Query, q.Step)
+	}
+
+	q, err = BuildRangeQuery("storage", "qps", "", "2025-08-01T00:00:00Z", "2025-09-01T00:00:00Z", "1d")
+	if err != nil || q.Step != 24*time.Hour {
+		t.Fatalf("expected 1d step, got %v (%v)", q, err)
+	}
+
+	invalid := []struct{ metric, start, end, granule string }{
+		{"up{}", "2025-09-01T00:00:00Z", "2025-09-01T01:00:00Z", ""},
+		{"up", "yesterday", "2025-09-01T01:00:00Z", ""},
+		{"up", "2025-09-01T01:00:00Z", "2025-09-01T00:00:00Z", ""},
+		{"up", "2025-09-01T00:00:00Z", "2025-09-01T01:00:00Z", "fast"},
+		{"up", "2025-09-01T00:00:00Z", "2025-09-01T01:00:00Z", "100ms"},
+		{"up", "2025-01-01T00:00:00Z", "2025-09-01T00:00:00Z", "1s"},
+	}
+	for _, tc := range invalid {
+		if _, err := BuildRangeQuery("storage", tc.metric, "", tc.start, tc.end, tc.granule); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("expected ErrInvalidQuery for %+v, got %v", tc, err)
+		}
+	}
+}
+
+func TestClientQueryRange(t *testing.T) {
+	srv, calls := newFakePrometheus(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("query"); got != `qps{service="storage"}` {
+			t.Errorf("unexpected query %q", got)
+		}
+		if got := r.URL.Query().Get("step"); got != "60" {
+			t.Errorf("unexpected step %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"__name__":"qps","service":"storage","instance":"storage-1"},"values":[[1756684800,"12.5"],[1756684860,"13"]]}
+		]}}`))
+	})
+
+	c := NewClient(&config.PrometheusConfig{URL: srv.URL, CacheTTLSeconds: 30})
+	q, _ := BuildRangeQuery("storage", "qps", "", "2025-09-01T00:00:00Z", "2025-09-01T01:00:00Z", "1m")
+
+	resp, err := c.QueryRange(context.Background(), q)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Status != "success" || len(resp.Data.Result) != 1 || len(resp.Data.Result[0].Values) != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if v := resp.Data.Result[0].Values[0][1]; v != "12.5" {
+		t.Fatalf("unexpected first value %v", v)
+	}
+
+	// 缓存命中时不再访问Prometheus
+	if _, err := c.QueryRange(context.Background(), q); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := atomic.LoadInt32(calls); n != 1 {
+		t.Fatalf("expected 1 upstream call, got %d", n)
+	}
+}
+
+func TestClientQueryRangeErrors(t *testing.T) {
+	srv, _ := newFakePrometheus(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+	})
+	q, _ := BuildRangeQuery("storage", "qps", "", "2025-09-01T00:00:00Z", "2025-09-01T01:00:00Z", "1m")
+
+	_, err := NewClient(&config.PrometheusConfig{URL: srv.URL}).QueryRange(context.Background(), q)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != "bad_data" || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected bad_data APIError, got %v", err)
+	}
+
+	var nilClient *Client
+	if _, err := nilClient.QueryRange(context.Background(), q); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("expected ErrNotConfigured, got %v", err)
+	}
+	if NewClient(&config.PrometheusConfig{}) != nil {
+		t.Fatal("expected nil client without url")
+	}
+}
+
+func TestClientQueryScalar(t *testing.T) {
+	results := map[string]string{
+		"ratio":  `{"resultType":"vector","result":[{"metric":{},"value":[1756684800,"0.9995"]}]}`,
+		"empty":  `{"resultType":"vector","result":[]}`,
+		"nan":    `{"resultType":"scalar","result":[1756684800,"NaN"]}`,
+		"series": `{"resultType":"vector","result":[{"metric":{"a":"1"},"value":[1,"1"]},{"metric":{"a":"2"},"value":[1,"2"]}]}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" || r.URL.Query().Get("time") != "1756684800" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"success","data":` + results[r.URL.Query().Get("query")] + `}`))
+	}))
+	t.Cleanup(srv.Close)
+	c := NewClient(&config.PrometheusConfig{URL: srv.URL})
+	ts := time.Unix(1756684800, 0)
+
+	if v, ok, err := c.QueryScalar(context.Background(), "ratio", ts); err != nil || !ok || v != 0.9995 {
+		t.Fatalf("ratio = %v, %v, %v", v, ok, err)
+	}
+	for _, q := range []string{"empty", "nan"} {
+		if _, ok, err := c.QueryScalar(context.Background(), q, ts); err != nil || ok {
+			t.Fatalf("%s: ok=%v err=%v, want no value", q, ok, err)
+		}
+	}
+	if _, _, err := c.QueryScalar(context.Background(), "series", ts); !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("expected ErrInvalidQuery for several series, got %v", err)
+	}
+}
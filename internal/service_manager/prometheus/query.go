@@ -0,0 +1,112 @@
+package prometheus
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPoints Prometheus单条序列允许返回的最大点数
+	maxPoints = 11000
+	// autoStepPoints 未指定粒度时期望返回的点数
+	autoStepPoints = 250
+	// minAutoStep 自动计算粒度时的最小步长
+	minAutoStep = 15 * time.Second
+)
+
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// RangeQuery query_range请求参数
+type RangeQuery struct {
+	Query string
+	Start time.Time
+	End   time.Time
+	Step  time.Duration
+}
+
+// cacheKey 查询缓存键
+func (q *RangeQuery) cacheKey() string {
+	return q.Query + "|" + strconv.FormatInt(q.Start.Unix(), 10) + "|" +
+		strconv.FormatInt(q.End.Unix(), 10) + "|" + q.Step.String()
+}
+
+// BuildRangeQuery 将服务/指标/版本/时间范围/粒度转换为PromQL query_range请求
+// start、end为RFC3339格式；granule支持Go时长格式以及d/w后缀（如1m、5m、1h、1d），为空时自动计算
+func BuildRangeQuery(service, metric, version, start, end, granule string) (*RangeQuery, error) {
+	if service == "" {
+		return nil, fmt.Errorf("%w: service is required", ErrInvalidQuery)
+	}
+	if !metricNamePattern.MatchString(metric) {
+		return nil, fmt.Errorf("%w: invalid metric name %q", ErrInvalidQuery, metric)
+	}
+
+	startTime, err := time.Parse(time.RFC3339, start)
+	if err != nil {
+		return nil, fmt.Errorf("%w: start must be RFC3339", ErrInvalidQuery)
+	}
+	endTime, err := time.Parse(time.RFC3339, end)
+	if err != nil {
+		return nil, fmt.Errorf("%w: end must be RFC3339", ErrInvalidQuery)
+	}
+	if !endTime.After(startTime) {
+		return nil, fmt.Errorf("%w: end must be after start", ErrInvalidQuery)
+	}
+
+	span := endTime.Sub(startTime)
+	var step time.Duration
+	if granule == "" {
+		step = (span / autoStepPoints).Truncate(time.Second)
+		if step < minAutoStep {
+			step = minAutoStep
+		}
+	} else {
+		step, err = parseGranule(granule)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if int64(span/step) > maxPoints {
+		return nil, fmt.Errorf("%w: granule %s is too small for the requested range", ErrInvalidQuery, step)
+	}
+
+	matchers := []string{"service=" + strconv.Quote(service)}
+	if version != "" {
+		matchers = append(matchers, "version="+strconv.Quote(version))
+	}
+
+	return &RangeQuery{
+		Query: metric + "{" + strings.Join(matchers, ",") + "}",
+		Start: startTime,
+		End:   endTime,
+		Step:  step,
+	}, nil
+}
+
+// parseGranule 解析查询粒度，最小1s
+func parseGranule(granule string) (time.Duration, error) {
+	var step time.Duration
+	switch unit := granule[len(granule)-1]; unit {
+	case 'd', 'w':
+		v, err := strconv.Atoi(granule[:len(granule)-1])
+		if err != nil {
+			return 0, fmt.Errorf("%w: invalid granule %q", ErrInvalidQuery, granule)
+		}
+		step = time.Duration(v) * 24 * time.Hour
+		if unit == 'w' {
+			step *= 7
+		}
+	default:
+		d, err := time.ParseDuration(granule)
+		if err != nil {
+			return 0, fmt.Errorf("%w: invalid granule %q", ErrInvalidQuery, granule)
+		}
+		step = d
+	}
+	if step < time.Second {
+		return 0, fmt.Errorf("%w: granule must be at least 1s", ErrInvalidQuery)
+	}
+	return step, nil
+}
@@ -1,12 +1,25 @@
 package servicemanager
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"strconv"
+	"time"
 
 	"github.com/fox-gonic/fox"
+	"github.com/qiniu/zeroops/internal/alerting/service/analysis"
+	"github.com/qiniu/zeroops/internal/alerting/service/anomaly"
+	"github.com/qiniu/zeroops/internal/alerting/service/enrich"
+	"github.com/qiniu/zeroops/internal/alerting/service/slo"
 	"github.com/qiniu/zeroops/internal/config"
+	"github.com/qiniu/zeroops/internal/lifecycle"
+	"github.com/qiniu/zeroops/internal/pg"
 	"github.com/qiniu/zeroops/internal/service_manager/api"
+	"github.com/qiniu/zeroops/internal/service_manager/consul"
 	"github.com/qiniu/zeroops/internal/service_manager/database"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+	"github.com/qiniu/zeroops/internal/service_manager/prometheus"
 	"github.com/qiniu/zeroops/internal/service_manager/service"
 	"github.com/rs/zerolog/log"
 )
@@ -15,20 +28,25 @@ type ServiceManagerServer struct {
 	config  *config.Config
 	db      *database.Database
 	service *service.Service
+	prom    *prometheus.Client
 }
 
-func NewServiceManagerServer(cfg *config.Config) (*ServiceManagerServer, error) {
-	db, err := database.NewDatabase(&cfg.Database)
-	if err != nil {
-		return nil, fmt.Errorf("failed to initialize database: %w", err)
+// NewServiceManagerServer 基于共享连接池创建服务，连接池由调用方负责关闭
+func NewServiceManagerServer(cfg *config.Config, pool *pg.DB) (*ServiceManagerServer, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("database pool is required")
 	}
+	db := database.NewDatabase(pool)
 
-	svc := service.NewService(db)
+	prom := prometheus.NewClient(&cfg.Prometheus)
+	svc := service.NewService(db, prom)
+	svc.SetPackageChecker(service.NewPackageChecker(cfg.Version.PackageHosts))
 
 	server := &ServiceManagerServer{
 		config:  cfg,
 		db:      db,
 		service: svc,
+		prom:    prom,
 	}
 
 	log.Info().Msg("api initialized successfully with database and service")
@@ -43,12 +61,115 @@ func (s *ServiceManagerServer) UseApi(router *fox.Engine) error {
 	return nil
 }
 
+// Components 返回实例存活检测和Consul同步两个后台循环，由生命周期管理器负责启停
+// alerter用于实例失联时生成告警工单，可以为nil
+func (s *ServiceManagerServer) Components(alerter service.AlertIngester) []lifecycle.Component {
+	instanceCfg := s.config.Instance
+	components := []lifecycle.Component{
+		lifecycle.Loop("instance-reaper", 1, func(ctx context.Context) {
+			s.service.RunInstanceReaper(ctx,
+				time.Duration(instanceCfg.ReapIntervalSeconds)*time.Second,
+				time.Duration(instanceCfg.HeartbeatTimeoutSeconds)*time.Second,
+				alerter)
+		}),
+	}
+
+	if client := consul.NewClient(instanceCfg.ConsulAddr); client != nil {
+		components = append(components, lifecycle.Loop("consul-sync", 1, func(ctx context.Context) {
+			s.service.RunConsulSync(ctx, client, time.Duration(instanceCfg.ConsulSyncIntervalSeconds)*time.Second)
+		}))
+		log.Info().Str("consul", instanceCfg.ConsulAddr).Msg("consul instance sync enabled")
+	}
+	return components
+}
+
+// Catalog 返回告警富化使用的服务目录（实例所属服务、负责团队、依赖和进行中的发布）
+func (s *ServiceManagerServer) Catalog() enrich.Catalog {
+	return s.service
+}
+
+// SLOQuerier 返回计算SLO错误预算使用的Prometheus即时查询接口，未配置Prometheus时返回nil
+func (s *ServiceManagerServer) SLOQuerier() slo.Querier {
+	if s.prom == nil {
+		return nil
+	}
+	return s.prom
+}
+
+// AnomalySource 返回异常检测拉取时序使用的Prometheus区间查询接口，未配置Prometheus时返回nil
+func (s *ServiceManagerServer) AnomalySource() anomaly.Source {
+	if s.prom == nil {
+		return nil
+	}
+	return anomalySource{s.prom}
+}
+
+// anomalySource 将query_range结果转换为单条序列的样本点，跳过NaN等无法解析的值
+type anomalySource struct {
+	prom *prometheus.Client
+}
+
+func (a anomalySource) QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]anomaly.Point, error) {
+	resp, err := a.prom.QueryRange(ctx, &prometheus.RangeQuery{Query: query, Start: start, End: end, Step: step})
+	if err != nil {
+		return nil, err
+	}
+	result := resp.Data.Result
+	if len(result) > 1 {
+		return nil, fmt.Errorf("%w: query returned %d series, want one", prometheus.ErrInvalidQuery, len(result))
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	points := make([]anomaly.Point, 0, len(result[0].Values))
+	for _, sample := range result[0].Values {
+		if len(sample) != 2 {
+			continue
+		}
+		ts, _ := sample[0].(float64)
+		str, _ := sample[1].(string)
+		v, err := strconv.ParseFloat(str, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		sec, frac := math.Modf(ts)
+		points = append(points, anomaly.Point{Time: time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC(), Value: v})
+	}
+	return points, nil
+}
+
+// AnalysisDeployments 返回AI根因分析使用的最近发布记录查询
+func (s *ServiceManagerServer) AnalysisDeployments() analysis.DeploymentSource {
+	return analysisDeployments{s.service}
+}
+
+// analysisDeployments 将发布任务列表转换为分析使用的发布记录，按开始时间倒序
+type analysisDeployments struct {
+	service *service.Service
+}
+
+func (a analysisDeployments) RecentDeployments(ctx context.Context, serviceName string, limit int) ([]analysis.Deployment, error) {
+	deployments, err := a.service.GetDeployments(ctx, &model.DeploymentQuery{Service: serviceName, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]analysis.Deployment, 0, len(deployments))
+	for _, d := range deployments {
+		out = append(out, analysis.Deployment{
+			ID:      d.ID,
+			Version: d.Version,
+			Region:  d.Region,
+			Status:  string(d.Status),
+			Start:   d.ScheduleTime,
+			Finish:  d.FinishTime,
+		})
+	}
+	return out, nil
+}
+
 func (s *ServiceManagerServer) Close() error {
 	if s.service != nil {
 		s.service.Close()
 	}
-	if s.db != nil {
-		return s.db.Close()
-	}
 	return nil
 }
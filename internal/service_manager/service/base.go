@@ -2,16 +2,24 @@ package service
 
 import (
 	"github.com/qiniu/zeroops/internal/service_manager/database"
+	"github.com/qiniu/zeroops/internal/service_manager/prometheus"
 	"github.com/rs/zerolog/log"
 )
 
 type Service struct {
-	db *database.Database
+	db       database.Store
+	prom     *prometheus.Client
+	events   *deployEventHub
+	packages *PackageChecker
 }
 
-func NewService(db *database.Database) *Service {
+// NewService db 可以是Postgres实现（*database.Database）或内存实现（memory.Store）
+// prom 可以为nil，此时指标查询返回 prometheus.ErrNotConfigured
+func NewService(db database.Store, prom *prometheus.Client) *Service {
 	service := &Service{
-		db: db,
+		db:     db,
+		prom:   prom,
+		events: newDeployEventHub(),
 	}
 
 	log.Info().Msg("Service initialized successfully")
@@ -22,6 +30,6 @@ func (s *Service) Close() error {
 	return nil
 }
 
-func (s *Service) GetDatabase() *database.Database {
+func (s *Service) GetDatabase() database.Store {
 	return s.db
 }
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"net"
+	"sort"
+
+	"github.com/qiniu/zeroops/internal/alerting/service/enrich"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// ===== 告警富化使用的服务目录查询 =====
+
+var _ enrich.Catalog = (*Service)(nil)
+
+// InstanceService 按实例ID、主机名或IP查找所属服务，instance带端口（如Prometheus的instance标签）时去掉端口再查一次
+func (s *Service) InstanceService(ctx context.Context, instance string) (string, error) {
+	keys := []string{instance}
+	if host, _, err := net.SplitHostPort(instance); err == nil && host != "" {
+		keys = append(keys, host)
+	}
+	for _, key := range keys {
+		found, err := s.db.FindServiceInstance(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		if found != nil {
+			return found.Service, nil
+		}
+	}
+	return "", nil
+}
+
+// AlertServiceInfo 返回告警富化所需的服务信息：负责团队、上下游依赖和该区域进行中的发布任务
+// 服务不存在时返回nil
+func (s *Service) AlertServiceInfo(ctx context.Context, serviceName, regionName string) (*enrich.ServiceInfo, error) {
+	services, err := s.db.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var service *model.Service
+	graph := make(dependencyGraph, len(services))
+	for i := range services {
+		graph[services[i].Name] = services[i].Deps
+		if services[i].Name == serviceName {
+			service = &services[i]
+		}
+	}
+	if service == nil {
+		return nil, nil
+	}
+
+	info := &enrich.ServiceInfo{Owner: service.Owner, Deps: service.Deps}
+	for dependent, depth := range graph.dependents(serviceName) {
+		if depth == 1 {
+			info.Dependents = append(info.Dependents, dependent)
+		}
+	}
+	sort.Strings(info.Dependents)
+
+	regionName, err = regionFilter(regionName)
+	if err != nil {
+		return nil, err
+	}
+	tasks, err := s.db.GetVersionDeployTasks(ctx, serviceName, regionName)
+	if err != nil {
+		return nil, err
+	}
+	if task := activeDeployTask(tasks); task != nil {
+		info.DeployID = task.ID
+	}
+	return info, nil
+}
+
+// activeDeployTask 与服务列表一致：优先取deploying，其次stop，最后unrelease，同状态取最近开始的
+func activeDeployTask(tasks map[string]*model.VersionDeployTask) *model.VersionDeployTask {
+	rank := map[model.DeployState]int{model.StatusDeploying: 0, model.StatusStop: 1, model.StatusUnrelease: 2}
+	var active *model.VersionDeployTask
+	for _, task := range tasks {
+		r, ok := rank[task.DeployState]
+		if !ok {
+			continue
+		}
+		if active == nil || r < rank[active.DeployState] ||
+			(r == rank[active.DeployState] && startedAfter(task, active)) {
+			active = task
+		}
+	}
+	return active
+}
+
+func startedAfter(a, b *model.VersionDeployTask) bool {
+	switch {
+	case a.StartTime == nil:
+		return false
+	case b.StartTime == nil:
+		return true
+	case a.StartTime.Equal(*b.StartTime):
+		return a.ID < b.ID
+	}
+	return a.StartTime.After(*b.StartTime)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/qiniu/zeroops/internal/service_manager/database/memory"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+func TestAlertCatalog(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	s := NewService(store, nil)
+	for _, svc := range []model.Service{
+		{Name: "mysql"},
+		{Name: "storage", Owner: "storage-team", Deps: []string{"mysql"}},
+		{Name: "api", Deps: []string{"storage"}},
+		{Name: "gateway", Deps: []string{"api"}},
+	} {
+		if err := s.CreateService(ctx, &svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.UpsertServiceInstance(ctx, &model.ServiceInstance{
+		ID: "storage-1", Service: "storage", Version: "v1", Region: "cn-east-1", Host: "host-7", IP: "10.0.0.7",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	deployID, err := store.CreateDeployment(ctx, &model.CreateDeploymentRequest{Service: "storage", Version: "v2", Region: "cn-east-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for instance, want := range map[string]string{
+		"storage-1": "storage", "host-7": "storage", "10.0.0.7:9100": "storage", "10.0.0.8:9100": "",
+	} {
+		if got, err := s.InstanceService(ctx, instance); err != nil || got != want {
+			t.Fatalf("InstanceService(%q) = %q, %v; want %q", instance, got, err, want)
+		}
+	}
+
+	info, err := s.AlertServiceInfo(ctx, "storage", "cn-east-1")
+	if err != nil || info == nil {
+		t.Fatalf("info = %v, %v", info, err)
+	}
+	if info.Owner != "storage-team" || !slices.Equal(info.Deps, []string{"mysql"}) ||
+		!slices.Equal(info.Dependents, []string{"api"}) || info.DeployID != deployID {
+		t.Fatalf("info = %+v, deploy %s", info, deployID)
+	}
+	if info, _ := s.AlertServiceInfo(ctx, "storage", "cn-north-1"); info.DeployID != "" {
+		t.Fatalf("deployment leaked across regions: %+v", info)
+	}
+	if info, err := s.AlertServiceInfo(ctx, "unknown", "default"); info != nil || err != nil {
+		t.Fatalf("unknown service: %v, %v", info, err)
+	}
+
+	// An update without owner keeps it.
+	if err := s.UpdateService(ctx, &model.Service{Name: "storage", Deps: []string{"mysql"}}); err != nil {
+		t.Fatal(err)
+	}
+	if svc, _ := store.GetServiceByName(ctx, "storage"); svc.Owner != "storage-team" {
+		t.Fatalf("owner = %q after update", svc.Owner)
+	}
+}
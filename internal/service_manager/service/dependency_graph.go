@@ -0,0 +1,143 @@
+package service
+
+import (
+	"sort"
+)
+
+// dependencyGraph 服务依赖图：服务名 -> 直接依赖的服务列表
+type dependencyGraph map[string][]string
+
+// unknownDeps 返回图中引用了但不存在的依赖（按字典序）
+func (g dependencyGraph) unknownDeps(name string) []string {
+	var unknown []string
+	for _, dep := range g[name] {
+		if _, ok := g[dep]; !ok {
+			unknown = append(unknown, dep)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// findCycle 查找依赖环，返回环上的服务路径（首尾相同），无环时返回nil
+func (g dependencyGraph) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(g))
+	var stack []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		stack = append(stack, name)
+		for _, dep := range g[name] {
+			switch state[dep] {
+			case visiting:
+				for i, n := range stack {
+					if n == dep {
+						return append(append([]string{}, stack[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if _, ok := g[dep]; !ok {
+					continue
+				}
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+		return nil
+	}
+
+	for _, name := range g.names() {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// topoLevels 按依赖分层：第0层没有依赖，第n层只依赖前n-1层的服务；同层按字典序
+// 存在环时返回环路径
+func (g dependencyGraph) topoLevels() ([][]string, []string) {
+	if cycle := g.findCycle(); cycle != nil {
+		return nil, cycle
+	}
+
+	remaining := make(map[string]int, len(g))
+	dependents := make(map[string][]string, len(g))
+	for name, deps := range g {
+		for _, dep := range deps {
+			if _, ok := g[dep]; !ok {
+				continue
+			}
+			remaining[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var levels [][]string
+	var current []string
+	for _, name := range g.names() {
+		if remaining[name] == 0 {
+			current = append(current, name)
+		}
+	}
+	for len(current) > 0 {
+		levels = append(levels, current)
+		var next []string
+		for _, name := range current {
+			for _, dependent := range dependents[name] {
+				remaining[dependent]--
+				if remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		sort.Strings(next)
+		current = next
+	}
+	return levels, nil
+}
+
+// dependents 返回所有直接或间接依赖name的服务及其距离（直接依赖为1）
+func (g dependencyGraph) dependents(name string) map[string]int {
+	reverse := make(map[string][]string, len(g))
+	for svc, deps := range g {
+		for _, dep := range deps {
+			reverse[dep] = append(reverse[dep], svc)
+		}
+	}
+
+	depth := make(map[string]int)
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range reverse[current] {
+			if _, seen := depth[dependent]; seen || dependent == name {
+				continue
+			}
+			depth[dependent] = depth[current] + 1
+			queue = append(queue, dependent)
+		}
+	}
+	return depth
+}
+
+func (g dependencyGraph) names() []string {
+	names := make([]string, 0, len(g))
+	for name := range g {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
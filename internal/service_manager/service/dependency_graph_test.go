@@ -0,0 +1,81 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependencyGraphFindCycle(t *testing.T) {
+	acyclic := dependencyGraph{
+		"storage":  nil,
+		"metadata": {"storage"},
+		"queue":    {"storage"},
+		"gateway":  {"metadata", "queue"},
+	}
+	if cycle := acyclic.findCycle(); cycle != nil {
+		t.Fatalf("expected no cycle, got %v", cycle)
+	}
+
+	cyclic := dependencyGraph{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"a"},
+		"d": nil,
+	}
+	if cycle := cyclic.findCycle(); !reflect.DeepEqual(cycle, []string{"a", "b", "c", "a"}) {
+		t.Fatalf("unexpected cycle %v", cycle)
+	}
+
+	self := dependencyGraph{"a": {"a"}}
+	if cycle := self.findCycle(); !reflect.DeepEqual(cycle, []string{"a", "a"}) {
+		t.Fatalf("unexpected self cycle %v", cycle)
+	}
+}
+
+func TestDependencyGraphTopoLevels(t *testing.T) {
+	g := dependencyGraph{
+		"storage":     nil,
+		"third-party": nil,
+		"metadata":    {"storage"},
+		"queue":       {"storage"},
+		"gateway":     {"metadata", "queue", "third-party"},
+	}
+	levels, cycle := g.topoLevels()
+	if cycle != nil {
+		t.Fatalf("unexpected cycle %v", cycle)
+	}
+	want := [][]string{{"storage", "third-party"}, {"metadata", "queue"}, {"gateway"}}
+	if !reflect.DeepEqual(levels, want) {
+		t.Fatalf("expected %v, got %v", want, levels)
+	}
+
+	if _, cycle := (dependencyGraph{"a": {"b"}, "b": {"a"}}).topoLevels(); cycle == nil {
+		t.Fatal("expected cycle")
+	}
+}
+
+func TestDependencyGraphDependents(t *testing.T) {
+	g := dependencyGraph{
+		"storage":  nil,
+		"metadata": {"storage"},
+		"queue":    {"storage"},
+		"gateway":  {"metadata", "queue"},
+		"billing":  {"gateway"},
+		"other":    nil,
+	}
+	got := g.dependents("storage")
+	want := map[string]int{"metadata": 1, "queue": 1, "gateway": 2, "billing": 3}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if len(g.dependents("other")) != 0 {
+		t.Fatal("expected no dependents")
+	}
+}
+
+func TestDependencyGraphUnknownDeps(t *testing.T) {
+	g := dependencyGraph{"a": {"b", "z", "y"}, "b": nil}
+	if got := g.unknownDeps("a"); !reflect.DeepEqual(got, []string{"y", "z"}) {
+		t.Fatalf("unexpected unknown deps %v", got)
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+	"github.com/rs/zerolog/log"
+)
+
+// ===== 服务管理与依赖关系业务方法 =====
+
+// CreateService 创建服务，依赖必须已存在且不能形成环
+func (s *Service) CreateService(ctx context.Context, service *model.Service) error {
+	owner, err := normalizeOwner(service.Owner)
+	if err != nil {
+		return err
+	}
+	service.Owner = owner
+	service.Deps = normalizeDeps(service.Deps)
+	regions, err := normalizeRegions(service.Regions)
+	if err != nil {
+		return err
+	}
+	service.Regions = regions
+	return s.inGraphTx(ctx, func(ctx context.Context) error {
+		if err := s.validateDeps(ctx, service, false); err != nil {
+			return err
+		}
+		return s.db.CreateService(ctx, service)
+	})
+}
+
+// UpdateService 更新服务信息，依赖必须已存在且不能形成环；未指定owner、regions时保持原值
+func (s *Service) UpdateService(ctx context.Context, service *model.Service) error {
+	owner, err := normalizeOwner(service.Owner)
+	if err != nil {
+		return err
+	}
+	service.Owner = owner
+	service.Deps = normalizeDeps(service.Deps)
+	if service.Regions != nil {
+		regions, err := normalizeRegions(service.Regions)
+		if err != nil {
+			return err
+		}
+		service.Regions = regions
+	}
+	return s.inGraphTx(ctx, func(ctx context.Context) error {
+		if err := s.validateDeps(ctx, service, true); err != nil {
+			return err
+		}
+		existing, err := s.getService(ctx, service.Name)
+		if err != nil {
+			return err
+		}
+		if service.Owner == "" {
+			service.Owner = existing.Owner
+		}
+		if service.Regions == nil {
+			service.Regions = existing.Regions
+		}
+		return s.db.UpdateService(ctx, service)
+	})
+}
+
+// DeleteService 删除服务，仍被其他服务依赖时拒绝删除
+func (s *Service) DeleteService(ctx context.Context, name string) error {
+	return s.inGraphTx(ctx, func(ctx context.Context) error {
+		graph, err := s.loadDependencyGraph(ctx)
+		if err != nil {
+			return err
+		}
+		if _, ok := graph[name]; !ok {
+			return ErrServiceNotFound
+		}
+
+		var direct []string
+		for dependent, depth := range graph.dependents(name) {
+			if depth == 1 {
+				direct = append(direct, dependent)
+			}
+		}
+		if len(direct) > 0 {
+			sort.Strings(direct)
+			return fmt.Errorf("%w: %s", ErrServiceHasDependents, strings.Join(direct, ", "))
+		}
+
+		return s.db.DeleteService(ctx, name)
+	})
+}
+
+// inGraphTx 在持有服务依赖图锁的事务内执行fn，避免并发写入各自通过校验后共同形成环
+func (s *Service) inGraphTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.db.InTx(ctx, func(ctx context.Context) error {
+		if err := s.db.LockServiceGraph(ctx); err != nil {
+			return err
+		}
+		return fn(ctx)
+	})
+}
+
+// GetServiceTopology 获取服务依赖的拓扑排序，依赖在前
+func (s *Service) GetServiceTopology(ctx context.Context) (*model.ServiceTopology, error) {
+	graph, err := s.loadDependencyGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	levels, cycle := graph.topoLevels()
+	if cycle != nil {
+		// 写入时已校验，出现环说明存在历史脏数据
+		return nil, fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+	}
+
+	topology := &model.ServiceTopology{
+		Order:  make([]string, 0, len(graph)),
+		Levels: levels,
+	}
+	for _, level := range levels {
+		topology.Order = append(topology.Order, level...)
+	}
+	if topology.Levels == nil {
+		topology.Levels = [][]string{}
+	}
+	return topology, nil
+}
+
+// GetServiceImpact 获取服务的影响面：所有直接或间接依赖它的服务及其当前健康状态
+// region不为空时只统计该区域的健康状态
+func (s *Service) GetServiceImpact(ctx context.Context, name, regionName string) (*model.ServiceImpactResponse, error) {
+	regionName, err := regionFilter(regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	graph, err := s.loadDependencyGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := graph[name]; !ok {
+		return nil, ErrServiceNotFound
+	}
+
+	health, err := s.db.GetServicesHealth(ctx, regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	dependents := graph.dependents(name)
+	items := make([]model.ServiceImpactItem, 0, len(dependents))
+	for dependent, depth := range dependents {
+		// 默认为正常状态，因为正常状态的服务不会存储在service_state表中
+		state := model.HealthStateNormal
+		if h, ok := health[dependent]; ok && h != "" {
+			state = h
+		}
+		items = append(items, model.ServiceImpactItem{
+			Name:   dependent,
+			Depth:  depth,
+			Health: state,
+			Deps:   graph[dependent],
+		})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Depth != items[j].Depth {
+			return items[i].Depth < items[j].Depth
+		}
+		return items[i].Name < items[j].Name
+	})
+
+	return &model.ServiceImpactResponse{Service: name, Items: items}, nil
+}
+
+// validateDeps 校验写入后的依赖图：服务存在性、依赖存在性和无环
+func (s *Service) validateDeps(ctx context.Context, service *model.Service, mustExist bool) error {
+	graph, err := s.loadDependencyGraph(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := graph[service.Name]; mustExist && !ok {
+		return ErrServiceNotFound
+	}
+
+	graph[service.Name] = service.Deps
+	if unknown := graph.unknownDeps(service.Name); len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownDependency, strings.Join(unknown, ", "))
+	}
+	if cycle := graph.findCycle(); cycle != nil {
+		log.Warn().Str("service", service.Name).Strs("cycle", cycle).Msg("rejected service dependency cycle")
+		return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+func (s *Service) loadDependencyGraph(ctx context.Context) (dependencyGraph, error) {
+	services, err := s.db.GetServices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	graph := make(dependencyGraph, len(services))
+	for _, svc := range services {
+		graph[svc.Name] = svc.Deps
+	}
+	return graph, nil
+}
+
+// normalizeOwner 去除首尾空白，长度不超过services.owner列宽
+func normalizeOwner(owner string) (string, error) {
+	owner = strings.TrimSpace(owner)
+	if len(owner) > 255 {
+		return "", fmt.Errorf("%w: owner must be at most 255 characters", ErrInvalidOwner)
+	}
+	return owner, nil
+}
+
+// normalizeDeps 去除空白、空值和重复依赖，保持原有顺序
+func normalizeDeps(deps []string) []string {
+	out := make([]string, 0, len(deps))
+	seen := make(map[string]bool, len(deps))
+	for _, dep := range deps {
+		dep = strings.TrimSpace(dep)
+		if dep == "" || seen[dep] {
+			continue
+		}
+		seen[dep] = true
+		out = append(out, dep)
+	}
+	return out
+}
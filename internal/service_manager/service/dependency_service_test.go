@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/service_manager/database/memory"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// slowGraphStore 读取服务列表后稍作停顿，放大校验与写入之间的竞争窗口
+type slowGraphStore struct {
+	*memory.Store
+}
+
+func (s slowGraphStore) GetServices(ctx context.Context) ([]model.Service, error) {
+	services, err := s.Store.GetServices(ctx)
+	time.Sleep(time.Millisecond)
+	return services, err
+}
+
+// TestConcurrentDependencyUpdates 并发写入各自不成环，合在一起成环或引用已删除的服务时，只能有一个成功
+func TestConcurrentDependencyUpdates(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(slowGraphStore{memory.New()}, nil)
+
+	const rounds = 20
+	for i := range rounds {
+		for _, name := range []string{"a", "b", "c"} {
+			if err := s.CreateService(ctx, &model.Service{Name: fmt.Sprintf("%s%d", name, i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make([][4]error, rounds)
+	for i := range rounds {
+		a, b, c := fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i), fmt.Sprintf("c%d", i)
+		start := make(chan struct{})
+		for j, op := range []func() error{
+			func() error { return s.UpdateService(ctx, &model.Service{Name: a, Deps: []string{b}}) },
+			func() error { return s.UpdateService(ctx, &model.Service{Name: b, Deps: []string{a}}) },
+			func() error { return s.DeleteService(ctx, c) },
+			func() error { return s.CreateService(ctx, &model.Service{Name: "d" + c, Deps: []string{c}}) },
+		} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				errs[i][j] = op()
+			}()
+		}
+		close(start)
+	}
+	wg.Wait()
+
+	for i, e := range errs {
+		if (e[0] == nil) == (e[1] == nil) {
+			t.Fatalf("round %d: updates forming a cycle returned %v, %v", i, e[0], e[1])
+		}
+		if err := errors.Join(e[0], e[1]); !errors.Is(err, ErrDependencyCycle) {
+			t.Fatalf("round %d: rejected update returned %v", i, err)
+		}
+		switch {
+		case e[2] == nil && errors.Is(e[3], ErrUnknownDependency):
+		case e[3] == nil && errors.Is(e[2], ErrServiceHasDependents):
+		default:
+			t.Fatalf("round %d: delete returned %v, create dependent returned %v", i, e[2], e[3])
+		}
+	}
+	if _, err := s.GetServiceTopology(ctx); err != nil {
+		t.Fatalf("topology after concurrent updates: %v", err)
+	}
+}
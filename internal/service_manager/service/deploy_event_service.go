@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+	"github.com/rs/zerolog/log"
+)
+
+// ===== 发布事件业务方法 =====
+
+type operatorCtxKey struct{}
+
+// WithOperator 在上下文中记录操作人，发布事件会带上该信息
+func WithOperator(ctx context.Context, operator string) context.Context {
+	if operator == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, operatorCtxKey{}, operator)
+}
+
+// OperatorFromContext 获取上下文中的操作人
+func OperatorFromContext(ctx context.Context) string {
+	if v, ok := ctx.Value(operatorCtxKey{}).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// deployEventHub 进程内发布事件广播，供SSE订阅使用
+type deployEventHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan model.DeployEvent]struct{}
+}
+
+func newDeployEventHub() *deployEventHub {
+	return &deployEventHub{subs: make(map[string]map[chan model.DeployEvent]struct{})}
+}
+
+func (h *deployEventHub) subscribe(deployID string) (<-chan model.DeployEvent, func()) {
+	ch := make(chan model.DeployEvent, 64)
+	h.mu.Lock()
+	if h.subs[deployID] == nil {
+		h.subs[deployID] = make(map[chan model.DeployEvent]struct{})
+	}
+	h.subs[deployID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	// 通道只由publish关闭，取消订阅只移除，避免重复关闭
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[deployID], ch)
+			if len(h.subs[deployID]) == 0 {
+				delete(h.subs, deployID)
+			}
+			h.mu.Unlock()
+		})
+	}
+	return ch, cancel
+}
+
+// publish 通知订阅方，缓冲已满的订阅方被移除并关闭通道，
+// 由SSE处理方结束连接，客户端重连后通过Last-Event-ID补齐，不会静默丢事件
+func (h *deployEventHub) publish(event model.DeployEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subs := h.subs[event.DeployID]
+	for ch := range subs {
+		select {
+		case ch <- event:
+		default:
+			delete(subs, ch)
+			close(ch)
+		}
+	}
+	if len(subs) == 0 {
+		delete(h.subs, event.DeployID)
+	}
+}
+
+// RecordDeployEvent 追加发布事件并通知订阅方
+func (s *Service) RecordDeployEvent(ctx context.Context, event *model.DeployEvent) error {
+	if event.Operator == "" {
+		event.Operator = OperatorFromContext(ctx)
+	}
+	if err := s.db.InsertDeployEvent(ctx, event); err != nil {
+		return err
+	}
+	s.events.publish(*event)
+	return nil
+}
+
+// recordTransition 记录状态流转事件，失败只打日志不影响主流程
+func (s *Service) recordTransition(ctx context.Context, deployID string, from, to model.DeployState, message string) {
+	event := &model.DeployEvent{
+		DeployID:  deployID,
+		Type:      model.EventStateTransition,
+		FromState: from,
+		ToState:   to,
+		Message:   message,
+	}
+	if err := s.RecordDeployEvent(ctx, event); err != nil {
+		log.Error().Err(err).
+			Str("deployID", deployID).
+			Str("from", string(from)).
+			Str("to", string(to)).
+			Msg("failed to record deploy event")
+	}
+}
+
+// CreateDeployEvent 发布执行方上报批次、实例、灰度结论等事件
+func (s *Service) CreateDeployEvent(ctx context.Context, deployID string, req *model.CreateDeployEventRequest) (*model.DeployEvent, error) {
+	switch req.Type {
+	case model.EventBatchStart, model.EventBatchFinish:
+		if req.Batch == nil {
+			return nil, ErrInvalidDeployEvent
+		}
+	case model.EventInstanceUpdate:
+		if req.Instance == "" {
+			return nil, ErrInvalidDeployEvent
+		}
+	case model.EventCanaryVerdict:
+		if req.Verdict != model.VerdictPass && req.Verdict != model.VerdictFail {
+			return nil, ErrInvalidDeployEvent
+		}
+	case model.EventOperatorAction:
+	default:
+		// 状态流转事件只能由服务端在状态变更时生成
+		return nil, ErrInvalidDeployEvent
+	}
+
+	deployment, err := s.db.GetDeploymentByID(ctx, deployID)
+	if err != nil {
+		return nil, err
+	}
+	if deployment == nil {
+		return nil, ErrDeploymentNotFound
+	}
+
+	event := &model.DeployEvent{
+		DeployID: deployID,
+		Type:     req.Type,
+		Batch:    req.Batch,
+		Instance: req.Instance,
+		Verdict:  req.Verdict,
+		Message:  req.Message,
+		Detail:   req.Detail,
+	}
+	if err := s.RecordDeployEvent(ctx, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// GetDeploymentEvents 获取发布事件时间线
+func (s *Service) GetDeploymentEvents(ctx context.Context, deployID string, query *model.DeployEventQuery) ([]model.DeployEvent, error) {
+	deployment, err := s.db.GetDeploymentByID(ctx, deployID)
+	if err != nil {
+		return nil, err
+	}
+	if deployment == nil {
+		return nil, ErrDeploymentNotFound
+	}
+	return s.db.GetDeployEvents(ctx, deployID, query)
+}
+
+// SubscribeDeploymentEvents 订阅发布任务的新事件，返回的cancel必须调用
+// 消费过慢时通道被关闭，订阅方应按最后收到的事件ID重新补齐
+func (s *Service) SubscribeDeploymentEvents(deployID string) (<-chan model.DeployEvent, func()) {
+	return s.events.subscribe(deployID)
+}
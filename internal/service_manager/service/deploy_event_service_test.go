@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qiniu/zeroops/internal/service_manager/database/memory"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// failingEventStore 写入发布事件总是失败
+type failingEventStore struct {
+	*memory.Store
+}
+
+func (s failingEventStore) InsertDeployEvent(ctx context.Context, event *model.DeployEvent) error {
+	return errors.New("insert deploy_events: connection refused")
+}
+
+// staleDeploymentStore 读到的任务状态总是status，模拟检查与写入之间被并发修改
+type staleDeploymentStore struct {
+	*memory.Store
+	status model.DeployState
+}
+
+func (s staleDeploymentStore) GetDeploymentByID(ctx context.Context, deployID string) (*model.Deployment, error) {
+	deployment, err := s.Store.GetDeploymentByID(ctx, deployID)
+	if deployment != nil {
+		deployment.Status = s.status
+	}
+	return deployment, err
+}
+
+func newDeployEventService(t *testing.T) (*Service, string) {
+	t.Helper()
+	store := memory.New()
+	deployID, err := store.CreateDeployment(context.Background(), &model.CreateDeploymentRequest{Service: "storage", Version: "v1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService(store, nil), deployID
+}
+
+func TestCreateDeployEvent(t *testing.T) {
+	batch := 2
+	cases := []struct {
+		name    string
+		deploy  string // 为空时使用已创建的发布任务
+		req     model.CreateDeployEventRequest
+		wantErr error
+	}{
+		{name: "batch start", req: model.CreateDeployEventRequest{Type: model.EventBatchStart, Batch: &batch}},
+		{name: "batch finish", req: model.CreateDeployEventRequest{Type: model.EventBatchFinish, Batch: &batch, Message: "2/4 done"}},
+		{name: "batch without number", req: model.CreateDeployEventRequest{Type: model.EventBatchFinish}, wantErr: ErrInvalidDeployEvent},
+		{name: "instance update", req: model.CreateDeployEventRequest{Type: model.EventInstanceUpdate, Instance: "storage-1"}},
+		{name: "instance update without instance", req: model.CreateDeployEventRequest{Type: model.EventInstanceUpdate}, wantErr: ErrInvalidDeployEvent},
+		{name: "canary pass", req: model.CreateDeployEventRequest{Type: model.EventCanaryVerdict, Verdict: model.VerdictPass}},
+		{name: "canary without verdict", req: model.CreateDeployEventRequest{Type: model.EventCanaryVerdict}, wantErr: ErrInvalidDeployEvent},
+		{name: "canary unknown verdict", req: model.CreateDeployEventRequest{Type: model.EventCanaryVerdict, Verdict: "maybe"}, wantErr: ErrInvalidDeployEvent},
+		{name: "operator action", req: model.CreateDeployEventRequest{Type: model.EventOperatorAction, Detail: []byte(`{"ticket":"OPS-1"}`)}},
+		{name: "state transition is server only", req: model.CreateDeployEventRequest{Type: model.EventStateTransition}, wantErr: ErrInvalidDeployEvent},
+		{name: "unknown type", req: model.CreateDeployEventRequest{Type: "restart"}, wantErr: ErrInvalidDeployEvent},
+		{name: "unknown deployment", deploy: "deploy-404", req: model.CreateDeployEventRequest{Type: model.EventOperatorAction}, wantErr: ErrDeploymentNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, deployID := newDeployEventService(t)
+			if tc.deploy != "" {
+				deployID = tc.deploy
+			}
+			ctx := WithOperator(context.Background(), "alice")
+
+			event, err := s.CreateDeployEvent(ctx, deployID, &tc.req)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected %v, got %v", tc.wantErr, err)
+			}
+			events, _ := s.db.GetDeployEvents(ctx, deployID, nil)
+			if tc.wantErr != nil {
+				if len(events) != 0 {
+					t.Fatalf("rejected event stored: %+v", events)
+				}
+				return
+			}
+			if event.ID == 0 || event.Type != tc.req.Type || event.Operator != "alice" || event.Message != tc.req.Message {
+				t.Fatalf("unexpected event: %+v", event)
+			}
+			if len(events) != 1 || events[0].ID != event.ID {
+				t.Fatalf("expected the event to be stored, got %+v", events)
+			}
+		})
+	}
+}
+
+func TestRecordTransition(t *testing.T) {
+	cases := []struct {
+		name     string
+		from, to model.DeployState
+		operator string
+		failing  bool
+	}{
+		{name: "created", to: model.StatusDeploying, operator: "alice"},
+		{name: "paused", from: model.StatusDeploying, to: model.StatusStop, operator: "bob"},
+		{name: "rolled back without operator", from: model.StatusStop, to: model.StatusRollback},
+		{name: "store failure is only logged", from: model.StatusDeploying, to: model.StatusCompleted, failing: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, deployID := newDeployEventService(t)
+			if tc.failing {
+				s.db = failingEventStore{s.db.(*memory.Store)}
+			}
+			events, cancel := s.SubscribeDeploymentEvents(deployID)
+			defer cancel()
+
+			s.recordTransition(WithOperator(context.Background(), tc.operator), deployID, tc.from, tc.to, tc.name)
+
+			if tc.failing {
+				if len(events) != 0 {
+					t.Fatalf("failed event published: %+v", <-events)
+				}
+				return
+			}
+			if len(events) != 1 {
+				t.Fatalf("expected one published event, got %d", len(events))
+			}
+			event := <-events
+			if event.Type != model.EventStateTransition || event.FromState != tc.from || event.ToState != tc.to ||
+				event.Operator != tc.operator || event.Message != tc.name || event.ID == 0 {
+				t.Fatalf("unexpected event: %+v", event)
+			}
+			stored, _ := s.GetDeploymentEvents(context.Background(), deployID, nil)
+			if len(stored) != 1 || stored[0].ID != event.ID {
+				t.Fatalf("expected the event to be stored, got %+v", stored)
+			}
+		})
+	}
+}
+
+// TestTransitionOnlyWhenApplied 状态限定的写入未命中时不记录也不推送状态流转
+func TestTransitionOnlyWhenApplied(t *testing.T) {
+	ctx := context.Background()
+	s, deployID := newDeployEventService(t)
+	store := s.db.(*memory.Store)
+	if err := s.RollbackDeployment(ctx, deployID); err != nil {
+		t.Fatal(err)
+	}
+
+	events, cancel := s.SubscribeDeploymentEvents(deployID)
+	defer cancel()
+	for _, tc := range []struct {
+		name   string
+		status model.DeployState
+		op     func(context.Context, string) error
+	}{
+		{"pause", model.StatusDeploying, s.PauseDeployment},
+		{"continue", model.StatusStop, s.ContinueDeployment},
+		{"rollback", model.StatusStop, s.RollbackDeployment},
+		{"delete", model.StatusUnrelease, s.DeleteDeployment},
+	} {
+		s.db = staleDeploymentStore{store, tc.status}
+		if err := tc.op(ctx, deployID); !errors.Is(err, ErrInvalidDeployState) {
+			t.Errorf("%s on a rolled back deployment: %v", tc.name, err)
+		}
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no published events, got %+v", <-events)
+	}
+	stored, _ := store.GetDeployEvents(ctx, deployID, nil)
+	if len(stored) != 1 || stored[0].ToState != model.StatusRollback {
+		t.Fatalf("expected only the rollback transition, got %+v", stored)
+	}
+	if deployment, _ := store.GetDeploymentByID(ctx, deployID); deployment == nil || deployment.Status != model.StatusRollback {
+		t.Fatalf("deployment changed: %+v", deployment)
+	}
+}
+
+func TestDeployEventHub(t *testing.T) {
+	h := newDeployEventHub()
+	a1, cancelA1 := h.subscribe("deploy-a")
+	a2, cancelA2 := h.subscribe("deploy-a")
+	b, cancelB := h.subscribe("deploy-b")
+	defer cancelB()
+
+	h.publish(model.DeployEvent{ID: 1, DeployID: "deploy-a"})
+	if len(a1) != 1 || len(a2) != 1 || len(b) != 0 {
+		t.Fatalf("expected the event only on deploy-a subscribers, got %d %d %d", len(a1), len(a2), len(b))
+	}
+	<-a2
+
+	// 取消订阅后不再收到事件，重复取消无副作用
+	cancelA1()
+	cancelA1()
+	h.publish(model.DeployEvent{ID: 2, DeployID: "deploy-a"})
+	if len(a1) != 1 || len(a2) != 1 {
+		t.Fatalf("expected only the remaining subscriber to receive, got %d %d", len(a1), len(a2))
+	}
+	cancelA2()
+	if _, ok := h.subs["deploy-a"]; ok {
+		t.Fatalf("expected deploy-a to be removed after its last subscriber left")
+	}
+
+	// 消费过慢的订阅方被关闭，不阻塞发布方和其他订阅方
+	fast, cancelFast := h.subscribe("deploy-b")
+	defer cancelFast()
+	for i := range cap(b) + 10 {
+		h.publish(model.DeployEvent{ID: int64(100 + i), DeployID: "deploy-b"})
+		<-fast
+	}
+	received := 0
+	for event := range b {
+		if event.ID != int64(100+received) {
+			t.Fatalf("expected events in order without gaps, got %d after %d", event.ID, received)
+		}
+		received++
+	}
+	if received != cap(b) {
+		t.Fatalf("expected the slow subscriber to keep its buffered events, got %d", received)
+	}
+	if len(h.subs["deploy-b"]) != 1 {
+		t.Fatalf("expected only the fast subscriber to remain, got %d", len(h.subs["deploy-b"]))
+	}
+	cancelB() // 已被关闭的订阅方取消订阅不会重复关闭
+}
@@ -20,8 +20,23 @@ func (s *Service) CreateDeployment(ctx context.Context, req *model.CreateDeploym
 		return "", ErrServiceNotFound
 	}
 
-	// 检查发布冲突
-	conflict, err := s.db.CheckDeploymentConflict(ctx, req.Service, req.Version)
+	// 已废弃的版本不允许再发布
+	version, err := s.db.GetServiceVersion(ctx, req.Service, req.Version)
+	if err != nil {
+		return "", err
+	}
+	if version != nil && version.DeprecatedAt != nil {
+		return "", ErrVersionDeprecated
+	}
+
+	// 目标区域必须在服务的部署区域内
+	req.Region, err = resolveRegion(service, req.Region)
+	if err != nil {
+		return "", err
+	}
+
+	// 检查发布冲突，同一版本可以同时发布到不同区域
+	conflict, err := s.db.CheckDeploymentConflict(ctx, req.Service, req.Version, req.Region, "")
 	if err != nil {
 		return "", err
 	}
@@ -35,10 +50,17 @@ func (s *Service) CreateDeployment(ctx context.Context, req *model.CreateDeploym
 		return "", err
 	}
 
+	initialState := model.StatusDeploying
+	if req.ScheduleTime != nil {
+		initialState = model.StatusUnrelease
+	}
+	s.recordTransition(ctx, deployID, "", initialState, "deployment created for "+req.Service+"@"+req.Version+" in "+req.Region)
+
 	log.Info().
 		Str("deployID", deployID).
 		Str("service", req.Service).
 		Str("version", req.Version).
+		Str("region", req.Region).
 		Msg("deployment created successfully")
 
 	return deployID, nil
@@ -58,6 +80,11 @@ func (s *Service) GetDeploymentByID(ctx context.Context, deployID string) (*mode
 
 // GetDeployments 获取发布任务列表
 func (s *Service) GetDeployments(ctx context.Context, query *model.DeploymentQuery) ([]model.Deployment, error) {
+	regionName, err := regionFilter(query.Region)
+	if err != nil {
+		return nil, err
+	}
+	query.Region = regionName
 	return s.db.GetDeployments(ctx, query)
 }
 
@@ -77,11 +104,30 @@ func (s *Service) UpdateDeployment(ctx context.Context, deployID string, req *mo
 		return ErrInvalidDeployState
 	}
 
+	// 修改版本时同样检查发布冲突，排除任务自身
+	if req.Version != "" && req.Version != deployment.Version {
+		conflict, err := s.db.CheckDeploymentConflict(ctx, deployment.Service, req.Version, deployment.Region, deployID)
+		if err != nil {
+			return err
+		}
+		if conflict {
+			return ErrDeploymentConflict
+		}
+	}
+
 	err = s.db.UpdateDeployment(ctx, deployID, req)
 	if err != nil {
 		return err
 	}
 
+	if err := s.RecordDeployEvent(ctx, &model.DeployEvent{
+		DeployID: deployID,
+		Type:     model.EventOperatorAction,
+		Message:  "deployment updated",
+	}); err != nil {
+		log.Error().Err(err).Str("deployID", deployID).Msg("failed to record deploy event")
+	}
+
 	log.Info().
 		Str("deployID", deployID).
 		Msg("deployment updated successfully")
@@ -105,10 +151,16 @@ func (s *Service) DeleteDeployment(ctx context.Context, deployID string) error {
 		return ErrInvalidDeployState
 	}
 
-	err = s.db.DeleteDeployment(ctx, deployID)
+	deleted, err := s.db.DeleteDeployment(ctx, deployID)
 	if err != nil {
 		return err
 	}
+	if !deleted {
+		// 检查之后任务已被并发修改
+		return ErrInvalidDeployState
+	}
+
+	s.recordTransition(ctx, deployID, deployment.Status, model.StatusDeleted, "deployment deleted")
 
 	log.Info().
 		Str("deployID", deployID).
@@ -131,10 +183,14 @@ func (s *Service) PauseDeployment(ctx context.Context, deployID string) error {
 		return ErrInvalidDeployState
 	}
 
-	err = s.db.PauseDeployment(ctx, deployID)
+	updated, err := s.db.PauseDeployment(ctx, deployID)
 	if err != nil {
 		return err
 	}
+	if !updated {
+		return ErrInvalidDeployState
+	}
+	s.recordTransition(ctx, deployID, deployment.Status, model.StatusStop, "deployment paused")
 
 	log.Info().
 		Str("deployID", deployID).
@@ -157,10 +213,14 @@ func (s *Service) ContinueDeployment(ctx context.Context, deployID string) error
 		return ErrInvalidDeployState
 	}
 
-	err = s.db.ContinueDeployment(ctx, deployID)
+	updated, err := s.db.ContinueDeployment(ctx, deployID)
 	if err != nil {
 		return err
 	}
+	if !updated {
+		return ErrInvalidDeployState
+	}
+	s.recordTransition(ctx, deployID, deployment.Status, model.StatusDeploying, "deployment continued")
 
 	log.Info().
 		Str("deployID", deployID).
@@ -185,10 +245,14 @@ func (s *Service) RollbackDeployment(ctx context.Context, deployID string) error
 		return ErrInvalidDeployState
 	}
 
-	err = s.db.RollbackDeployment(ctx, deployID)
+	updated, err := s.db.RollbackDeployment(ctx, deployID)
 	if err != nil {
 		return err
 	}
+	if !updated {
+		return ErrInvalidDeployState
+	}
+	s.recordTransition(ctx, deployID, deployment.Status, model.StatusRollback, "deployment rolled back")
 
 	log.Info().
 		Str("deployID", deployID).
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/service_manager/database/memory"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+func TestUpdateDeploymentConflict(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(memory.New(), nil)
+	if err := s.CreateService(ctx, &model.Service{Name: "storage"}); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	var ids []string
+	for _, version := range []string{"v1.0.0", "v1.1.0"} {
+		id, err := s.CreateDeployment(ctx, &model.CreateDeploymentRequest{Service: "storage", Version: version, ScheduleTime: &later})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+
+	for _, tc := range []struct {
+		name    string
+		version string
+		want    error
+	}{
+		{"same version as itself", "v1.1.0", nil},
+		{"version of another pending deployment", "v1.0.0", ErrDeploymentConflict},
+		{"free version", "v1.2.0", nil},
+	} {
+		if err := s.UpdateDeployment(ctx, ids[1], &model.UpdateDeploymentRequest{Version: tc.version}); !errors.Is(err, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+	deployment, err := s.GetDeploymentByID(ctx, ids[1])
+	if err != nil || deployment.Version != "v1.2.0" {
+		t.Fatalf("deployment = %+v, %v", deployment, err)
+	}
+}
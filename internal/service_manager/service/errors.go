@@ -8,4 +8,27 @@ var (
 	ErrServiceNotFound    = errors.New("service not found")
 	ErrDeploymentNotFound = errors.New("deployment not found")
 	ErrInvalidDeployState = errors.New("invalid deployment state")
+	ErrInvalidDeployEvent = errors.New("invalid deployment event")
+
+	ErrVersionNotFound      = errors.New("service version not found")
+	ErrVersionAlreadyExists = errors.New("service version already exists")
+	ErrVersionInUse         = errors.New("service version is running on instances")
+	ErrVersionDeprecated    = errors.New("service version is deprecated")
+	ErrInvalidVersion       = errors.New("invalid service version")
+	ErrInvalidVersionType   = errors.New("invalid version type")
+	ErrInvalidPackageURL    = errors.New("invalid package url")
+	ErrInvalidChecksum      = errors.New("invalid checksum")
+
+	ErrMetricNotAllowed = errors.New("metric is not registered for service")
+
+	ErrUnknownDependency    = errors.New("unknown service dependency")
+	ErrDependencyCycle      = errors.New("service dependency cycle")
+	ErrServiceHasDependents = errors.New("service has dependents")
+
+	ErrInstanceNotFound = errors.New("service instance not found")
+	ErrInstanceConflict = errors.New("service instance id is registered by another service")
+	ErrInvalidInstance  = errors.New("invalid service instance")
+
+	ErrInvalidRegion = errors.New("invalid region")
+	ErrInvalidOwner  = errors.New("invalid service owner")
 )
@@ -2,49 +2,61 @@ package service
 
 import (
 	"context"
+	"slices"
+	"time"
 
 	"github.com/qiniu/zeroops/internal/service_manager/model"
-	"github.com/rs/zerolog/log"
+	"github.com/qiniu/zeroops/internal/service_manager/prometheus"
 )
 
 // ===== 服务管理业务方法 =====
 
-// GetServicesResponse 获取服务列表响应
-func (s *Service) GetServicesResponse(ctx context.Context) (*model.ServicesResponse, error) {
-	services, err := s.db.GetServices(ctx)
+// GetServicesResponse 获取服务列表响应，region不为空时只返回部署在该区域的服务，
+// 健康状态和发布状态也只统计该区域
+func (s *Service) GetServicesResponse(ctx context.Context, regionName string) (*model.ServicesResponse, error) {
+	regionName, err := regionFilter(regionName)
 	if err != nil {
 		return nil, err
 	}
 
-	items := make([]model.ServiceItem, len(services))
+	summaries, err := s.db.GetServiceSummaries(ctx, regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.ServiceItem, 0, len(summaries))
 	relation := make(map[string][]string)
 
-	for i, service := range services {
-		// 获取服务状态来确定健康状态
-		state, err := s.db.GetServiceState(ctx, service.Name)
-		if err != nil {
-			log.Error().Err(err).Str("service", service.Name).Msg("failed to get service state")
+	for _, summary := range summaries {
+		if !servesRegion(summary.Regions, regionName) {
+			continue
 		}
 
 		// 默认为正常状态，因为正常状态的服务不会存储在service_state表中
 		health := model.HealthStateNormal
-		if state != nil {
-			health = state.HealthState
+		if summary.Health != "" {
+			health = summary.Health
 		}
 
-		// 默认设置为已完成部署状态
-		deployState := model.StatusCompleted
-
-		items[i] = model.ServiceItem{
-			Name:        service.Name,
-			DeployState: deployState,
+		item := model.ServiceItem{
+			Name:        summary.Name,
+			Owner:       summary.Owner,
+			DeployState: model.StatusCompleted,
 			Health:      health,
-			Deps:        service.Deps,
+			Deps:        summary.Deps,
+			Regions:     summary.Regions,
 		}
+		if task := summary.ActiveDeploy; task != nil {
+			progress := deployProgress(task)
+			item.DeployState = task.DeployState
+			item.ActiveDeployID = task.ID
+			item.DeployProgress = &progress
+		}
+		items = append(items, item)
 
 		// 构建依赖关系图
-		if len(service.Deps) > 0 {
-			relation[service.Name] = service.Deps
+		if len(summary.Deps) > 0 {
+			relation[summary.Name] = summary.Deps
 		}
 	}
 
@@ -54,121 +66,136 @@ func (s *Service) GetServicesResponse(ctx context.Context) (*model.ServicesRespo
 	}, nil
 }
 
+// deployProgress 发布进度：已完成批次 / 总批次，限定在[0, 1]
+func deployProgress(task *model.VersionDeployTask) float64 {
+	total := task.TotalBatches
+	if total <= 0 {
+		total = model.DefaultTotalBatches
+	}
+	if task.CompletedBatches >= total {
+		return 1
+	}
+	return float64(task.CompletedBatches) / float64(total)
+}
+
 // GetServiceActiveVersions 获取服务活跃版本
-func (s *Service) GetServiceActiveVersions(ctx context.Context, serviceName string) ([]model.ActiveVersionItem, error) {
+// 每个版本关联引入它的发布任务，健康状态按service+version从service_states计算；
+// region为空时统计所有区域，版本健康取各区域中最差的状态
+func (s *Service) GetServiceActiveVersions(ctx context.Context, serviceName, regionName string) ([]model.ActiveVersionItem, error) {
+	regionName, err := regionFilter(regionName)
+	if err != nil {
+		return nil, err
+	}
+
 	instances, err := s.db.GetServiceInstances(ctx, serviceName)
 	if err != nil {
 		return nil, err
 	}
 
-	// 按版本分组统计实例
+	states, err := s.db.GetServiceStatesByService(ctx, serviceName, regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.db.GetVersionDeployTasks(ctx, serviceName, regionName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按版本分组统计实例，保持版本首次出现的顺序
+	var versions []string
 	versionMap := make(map[string][]model.ServiceInstance)
 	for _, instance := range instances {
+		if regionName != "" && instance.Region != regionName {
+			continue
+		}
+		if _, ok := versionMap[instance.Version]; !ok {
+			versions = append(versions, instance.Version)
+		}
 		versionMap[instance.Version] = append(versionMap[instance.Version], instance)
 	}
 
-	var activeVersions []model.ActiveVersionItem
-	for version, versionInstances := range versionMap {
-		// 获取服务状态
-		state, err := s.db.GetServiceState(ctx, serviceName)
-		if err != nil {
-			log.Error().Err(err).Str("service", serviceName).Msg("failed to get service state")
-		}
+	now := time.Now()
+	activeVersions := make([]model.ActiveVersionItem, 0, len(versions))
+	for _, version := range versions {
+		versionInstances := versionMap[version]
 
 		// 默认为正常状态，因为正常状态的服务不会存储在service_state表中
 		health := model.HealthStateNormal
-		reportAt := &model.ServiceState{}
-		if state != nil {
+		if state := states[version]; state != nil && state.HealthState != "" {
 			health = state.HealthState
-			reportAt = state
 		}
 
-		activeVersion := model.ActiveVersionItem{
-			Version:                 version,
-			DeployID:                "1001", // TODO:临时值，实际需要从部署任务中获取
-			StartTime:               reportAt.ReportAt,
-			EstimatedCompletionTime: reportAt.ReportAt,
-			Instances:               len(versionInstances),
-			Health:                  health,
+		item := model.ActiveVersionItem{
+			Version:      version,
+			Instances:    len(versionInstances),
+			Health:       health,
+			InstanceList: make([]model.InstanceHealth, 0, len(versionInstances)),
+		}
+
+		if task := tasks[version]; task != nil {
+			item.DeployID = task.ID
+			item.DeployState = task.DeployState
+			if task.StartTime != nil {
+				item.StartTime = *task.StartTime
+			}
+			item.EstimatedCompletionTime = estimateCompletionTime(task, now)
+		}
+
+		for _, instance := range versionInstances {
+			item.InstanceList = append(item.InstanceList, model.InstanceHealth{
+				ID:     instance.ID,
+				Region: instance.Region,
+				Status: instance.Status,
+				Health: instanceHealth(instance.Status, health),
+			})
 		}
 
-		activeVersions = append(activeVersions, activeVersion)
+		activeVersions = append(activeVersions, item)
 	}
 
 	return activeVersions, nil
 }
 
-// GetServiceAvailableVersions 获取可用服务版本
-func (s *Service) GetServiceAvailableVersions(ctx context.Context, serviceName, versionType string) ([]model.ServiceVersion, error) {
-	// 获取所有版本
-	versions, err := s.db.GetServiceVersions(ctx, serviceName)
-	if err != nil {
-		return nil, err
+// estimateCompletionTime 预估发布完成时间：剩余批次数 × 每批观察窗口
+func estimateCompletionTime(task *model.VersionDeployTask, now time.Time) time.Time {
+	if task.DeployState == model.StatusCompleted && task.EndTime != nil {
+		return *task.EndTime
 	}
 
-	// TODO:根据类型过滤（这里简化处理，实际需要根据业务需求过滤）
-	if versionType == "unrelease" {
-		// 返回未发布的版本，这里简化返回所有版本
-		return versions, nil
+	base := now
+	if task.StartTime != nil && task.StartTime.After(now) {
+		// 计划发布尚未开始，从计划时间起算
+		base = *task.StartTime
 	}
 
-	return versions, nil
+	window := time.Duration(task.ObservationWindow) * time.Second
+	return base.Add(time.Duration(task.RemainingBatches()) * window)
 }
 
-// CreateService 创建服务
-func (s *Service) CreateService(ctx context.Context, service *model.Service) error {
-	return s.db.CreateService(ctx, service)
-}
-
-// UpdateService 更新服务信息
-func (s *Service) UpdateService(ctx context.Context, service *model.Service) error {
-	return s.db.UpdateService(ctx, service)
-}
-
-// DeleteService 删除服务
-func (s *Service) DeleteService(ctx context.Context, name string) error {
-	return s.db.DeleteService(ctx, name)
+// instanceHealth 根据实例运行状态和版本健康状态计算实例健康状态
+func instanceHealth(status model.InstanceStatus, versionHealth model.HealthState) model.HealthState {
+	if status == model.InstanceStatusError || status == model.InstanceStatusLost {
+		return model.HealthStateError
+	}
+	return versionHealth
 }
 
 // GetServiceMetricTimeSeries 获取服务时序指标数据
+// 指标名需登记在service_metrics表中，查询转换为Prometheus query_range
 func (s *Service) GetServiceMetricTimeSeries(ctx context.Context, serviceName, metricName string, query *model.MetricTimeSeriesQuery) (*model.PrometheusQueryRangeResponse, error) {
-	// TODO:这里应该调用实际的Prometheus或其他监控系统API
-	// 现在返回模拟数据
-
-	response := &model.PrometheusQueryRangeResponse{
-		Status: "success",
-		Data: model.PrometheusQueryRangeData{
-			ResultType: "matrix",
-			Result: []model.PrometheusTimeSeries{
-				{
-					Metric: map[string]string{
-						"__name__": metricName,
-						"service":  serviceName,
-						"instance": "instance-1",
-						"version":  query.Version,
-					},
-					Values: [][]any{
-						{1435781430.781, "1.2"},
-						{1435781445.781, "1.5"},
-						{1435781460.781, "1.1"},
-					},
-				},
-				{
-					Metric: map[string]string{
-						"__name__": metricName,
-						"service":  serviceName,
-						"instance": "instance-2",
-						"version":  query.Version,
-					},
-					Values: [][]any{
-						{1435781430.781, "0.8"},
-						{1435781445.781, "0.9"},
-						{1435781460.781, "1.0"},
-					},
-				},
-			},
-		},
-	}
-
-	return response, nil
+	rangeQuery, err := prometheus.BuildRangeQuery(serviceName, metricName, query.Version, query.Start, query.End, query.Granule)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := s.db.GetServiceMetricNames(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(metrics, metricName) {
+		return nil, ErrMetricNotAllowed
+	}
+
+	return s.prom.QueryRange(ctx, rangeQuery)
 }
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/service_manager/database/memory"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+func TestEstimateCompletionTime(t *testing.T) {
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	start := now.Add(-time.Hour)
+	end := now.Add(-10 * time.Minute)
+	future := now.Add(time.Hour)
+
+	cases := []struct {
+		name string
+		task model.VersionDeployTask
+		want time.Time
+	}{
+		{
+			name: "deploying with remaining batches",
+			task: model.VersionDeployTask{
+				ServiceDeployTask: model.ServiceDeployTask{StartTime: &start, DeployState: model.StatusDeploying, TotalBatches: 4, ObservationWindow: 600},
+				CompletedBatches:  1,
+			},
+			want: now.Add(30 * time.Minute),
+		},
+		{
+			name: "scheduled in the future",
+			task: model.VersionDeployTask{
+				ServiceDeployTask: model.ServiceDeployTask{StartTime: &future, DeployState: model.StatusUnrelease, TotalBatches: 2, ObservationWindow: 300},
+			},
+			want: future.Add(10 * time.Minute),
+		},
+		{
+			name: "completed uses end time",
+			task: model.VersionDeployTask{
+				ServiceDeployTask: model.ServiceDeployTask{StartTime: &start, EndTime: &end, DeployState: model.StatusCompleted, TotalBatches: 3, ObservationWindow: 300},
+				CompletedBatches:  3,
+			},
+			want: end,
+		},
+		{
+			name: "more finished batches than planned",
+			task: model.VersionDeployTask{
+				ServiceDeployTask: model.ServiceDeployTask{StartTime: &start, DeployState: model.StatusDeploying, TotalBatches: 1, ObservationWindow: 300},
+				CompletedBatches:  2,
+			},
+			want: now,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := estimateCompletionTime(&tc.task, now); !got.Equal(tc.want) {
+				t.Fatalf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDeployProgress(t *testing.T) {
+	cases := []struct {
+		name string
+		task model.VersionDeployTask
+		want float64
+	}{
+		{"not started", model.VersionDeployTask{ServiceDeployTask: model.ServiceDeployTask{TotalBatches: 4}}, 0},
+		{"halfway", model.VersionDeployTask{ServiceDeployTask: model.ServiceDeployTask{TotalBatches: 4}, CompletedBatches: 2}, 0.5},
+		{"extra batch events", model.VersionDeployTask{ServiceDeployTask: model.ServiceDeployTask{TotalBatches: 2}, CompletedBatches: 3}, 1},
+		{"missing total uses default", model.VersionDeployTask{CompletedBatches: 1}, 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := deployProgress(&tc.task); got != tc.want {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCompletedBatches(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	s := NewService(store, nil)
+	deployID, err := store.CreateDeployment(ctx, &model.CreateDeploymentRequest{Service: "storage", Version: "v1.0.0", TotalBatches: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 重试导致同一批次重复上报，超出总批次的序号也只计到总批次数
+	for i, batch := range []int{1, 1, 2, 2, 4, 5} {
+		if _, err := s.CreateDeployEvent(ctx, deployID, &model.CreateDeployEventRequest{Type: model.EventBatchFinish, Batch: &batch}); err != nil {
+			t.Fatal(err)
+		}
+		want := []int{1, 1, 2, 2, 3, 3}[i]
+		tasks, err := store.GetVersionDeployTasks(ctx, "storage", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tasks["v1.0.0"].CompletedBatches; got != want {
+			t.Fatalf("after batch %d: expected %d completed batches, got %d", batch, want, got)
+		}
+	}
+}
+
+// TestServiceHealthTakesWorstRegion 一个区域异常时服务整体视为异常，即使其他区域的正常状态上报得更晚
+func TestServiceHealthTakesWorstRegion(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	s := NewService(store, nil)
+	for _, svc := range []model.Service{{Name: "storage"}, {Name: "gateway", Deps: []string{"storage"}}} {
+		if err := s.CreateService(ctx, &svc); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	for _, name := range []string{"storage", "gateway"} {
+		store.PutServiceState(model.ServiceState{Service: name, Version: "v1.0.0", Region: "cn-east-1", ReportAt: now.Add(-time.Minute), HealthState: model.HealthStateError})
+		store.PutServiceState(model.ServiceState{Service: name, Version: "v1.0.0", Region: "cn-north-1", ReportAt: now, HealthState: model.HealthStateNormal})
+	}
+
+	for _, tc := range []struct {
+		region string
+		want   model.HealthState
+	}{
+		{"", model.HealthStateError},
+		{"cn-east-1", model.HealthStateError},
+		{"cn-north-1", model.HealthStateNormal},
+	} {
+		services, err := s.GetServicesResponse(ctx, tc.region)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range services.Items {
+			if item.Health != tc.want {
+				t.Errorf("region %q: service %s health = %s, want %s", tc.region, item.Name, item.Health, tc.want)
+			}
+		}
+		impact, err := s.GetServiceImpact(ctx, "storage", tc.region)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(impact.Items) != 1 || impact.Items[0].Health != tc.want {
+			t.Errorf("region %q: impact = %+v, want %s", tc.region, impact.Items, tc.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/region"
+	"github.com/qiniu/zeroops/internal/service_manager/consul"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+	"github.com/rs/zerolog/log"
+)
+
+// ===== 实例存活检测 =====
+
+// Alert 服务管理模块产生的告警，字段含义同Alertmanager告警，状态总是firing
+type Alert struct {
+	Labels      map[string]string
+	Annotations map[string]string
+	StartsAt    time.Time
+	Fingerprint string
+}
+
+// AlertIngester 告警接收入口，实例失联时通过它生成告警工单
+// 由main注入告警模块的实现，服务管理模块不依赖告警模块
+type AlertIngester interface {
+	IngestAlert(ctx context.Context, alert Alert) (bool, error)
+}
+
+// RunInstanceReaper 按interval扫描心跳超过timeout的实例并标记为lost，直到ctx结束
+// alerter为nil时只标记不告警
+func (s *Service) RunInstanceReaper(ctx context.Context, interval, timeout time.Duration, alerter AlertIngester) {
+	if interval <= 0 || timeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ReapLostInstances(ctx, time.Now(), timeout, alerter); err != nil {
+				log.Error().Err(err).Msg("failed to reap lost instances")
+			}
+		}
+	}
+}
+
+// ReapLostInstances 标记失联实例并为每个实例上报一条告警，返回本次标记的实例数
+// 标记与告警在同一事务内完成，告警上报失败时回滚标记，下次扫描重试
+func (s *Service) ReapLostInstances(ctx context.Context, now time.Time, timeout time.Duration, alerter AlertIngester) (int, error) {
+	var lost []model.ServiceInstance
+	err := s.db.InTx(ctx, func(ctx context.Context) error {
+		var err error
+		lost, err = s.db.MarkLostInstances(ctx, now.Add(-timeout))
+		if err != nil || alerter == nil {
+			return err
+		}
+		for i := range lost {
+			if _, err := alerter.IngestAlert(ctx, lostInstanceAlert(&lost[i], timeout, now)); err != nil {
+				return fmt.Errorf("raise instance lost alert for %s/%s: %w", lost[i].Service, lost[i].ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range lost {
+		log.Warn().
+			Str("service", lost[i].Service).
+			Str("instance", lost[i].ID).
+			Msg("instance heartbeat timed out, marked as lost")
+	}
+	return len(lost), nil
+}
+
+// lostInstanceAlert 构造实例失联告警，同一次失联的指纹和开始时间固定，重复上报会被接收端去重
+func lostInstanceAlert(instance *model.ServiceInstance, timeout time.Duration, now time.Time) Alert {
+	startsAt := now
+	if instance.LastHeartbeat != nil {
+		startsAt = instance.LastHeartbeat.Add(timeout)
+	}
+
+	sum := sha256.Sum256([]byte("InstanceLost/" + instance.Service + "/" + instance.ID))
+	labels := map[string]string{
+		"alertname":       "InstanceLost",
+		"severity":        "P1",
+		"service":         instance.Service,
+		"service_version": instance.Version,
+		"instance":        instance.ID,
+		"region":          instance.Region,
+	}
+	if instance.Host != "" {
+		labels["host"] = instance.Host
+	}
+	if instance.IP != "" {
+		labels["ip"] = instance.IP
+	}
+
+	return Alert{
+		Labels: labels,
+		Annotations: map[string]string{
+			"summary":     fmt.Sprintf("%s instance %s lost", instance.Service, instance.ID),
+			"description": fmt.Sprintf("no heartbeat received for %s", timeout),
+		},
+		StartsAt:    startsAt.UTC(),
+		Fingerprint: hex.EncodeToString(sum[:8]),
+	}
+}
+
+// ===== Consul服务目录同步 =====
+
+// RunConsulSync 按interval把Consul中已登记服务的实例同步到实例表，直到ctx结束
+func (s *Service) RunConsulSync(ctx context.Context, client *consul.Client, interval time.Duration) {
+	if client == nil || interval <= 0 {
+		return
+	}
+	sync := func() {
+		if n, err := s.SyncConsulInstances(ctx, client); err != nil {
+			log.Error().Err(err).Msg("failed to sync instances from consul")
+		} else {
+			log.Debug().Int("instances", n).Msg("synced instances from consul")
+		}
+	}
+
+	sync()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sync()
+		}
+	}
+}
+
+// SyncConsulInstances 对每个已登记服务拉取Consul实例并注册，同步即视为一次心跳
+// Consul中消失的实例不再刷新心跳，由存活检测标记为lost
+func (s *Service) SyncConsulInstances(ctx context.Context, client *consul.Client) (int, error) {
+	services, err := s.db.GetServices(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	for _, svc := range services {
+		entries, err := client.HealthService(ctx, svc.Name)
+		if err != nil {
+			log.Error().Err(err).Str("service", svc.Name).Msg("failed to query consul service")
+			continue
+		}
+		for i := range entries {
+			instance := consulInstance(svc.Name, &entries[i])
+			upserted, err := s.db.UpsertServiceInstance(ctx, instance)
+			if err != nil {
+				return synced, err
+			}
+			if upserted == nil {
+				log.Warn().Str("service", svc.Name).Str("instance", instance.ID).
+					Msg("consul instance id is registered by another service, skipped")
+				continue
+			}
+			synced++
+		}
+	}
+	return synced, nil
+}
+
+// consulInstance 将Consul实例映射为服务实例，critical检查视为error；
+// 区域取自Meta["region"]或数据中心，不合法时记为默认区域
+func consulInstance(serviceName string, entry *consul.ServiceEntry) *model.ServiceInstance {
+	instanceRegion := region.Normalize(entry.Region())
+	if region.Validate(instanceRegion) != nil {
+		instanceRegion = region.Default
+	}
+	ip := entry.Service.Address
+	if ip == "" {
+		ip = entry.Node.Address
+	}
+	status := model.InstanceStatusActive
+	if entry.Critical() {
+		status = model.InstanceStatusError
+	}
+	return &model.ServiceInstance{
+		ID:      entry.Service.ID,
+		Service: serviceName,
+		Region:  instanceRegion,
+		Version: entry.Version(),
+		Status:  status,
+		Host:    entry.Node.Node,
+		IP:      ip,
+		Port:    entry.Service.Port,
+		Source:  model.InstanceSourceConsul,
+	}
+}
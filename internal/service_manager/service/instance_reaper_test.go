@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/service_manager/consul"
+	"github.com/qiniu/zeroops/internal/service_manager/database/memory"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+func TestLostInstanceAlert(t *testing.T) {
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	last := now.Add(-5 * time.Minute)
+	instance := &model.ServiceInstance{ID: "storage-1", Service: "storage", Version: "v1.0.0", Host: "node-a", LastHeartbeat: &last}
+
+	a := lostInstanceAlert(instance, 90*time.Second, now)
+	if !a.StartsAt.Equal(last.Add(90 * time.Second)) {
+		t.Fatalf("expected startsAt at heartbeat deadline, got %s", a.StartsAt)
+	}
+	if a.Labels["alertname"] != "InstanceLost" || a.Labels["service"] != "storage" || a.Labels["instance"] != "storage-1" || a.Labels["host"] != "node-a" {
+		t.Fatalf("unexpected labels: %v", a.Labels)
+	}
+
+	// 同一实例的指纹稳定，不同实例不同
+	again := lostInstanceAlert(instance, 90*time.Second, now.Add(time.Minute))
+	if again.Fingerprint != a.Fingerprint || !again.StartsAt.Equal(a.StartsAt) {
+		t.Fatalf("expected repeated alert to be identical for dedup")
+	}
+	other := lostInstanceAlert(&model.ServiceInstance{ID: "storage-2", Service: "storage"}, 90*time.Second, now)
+	if other.Fingerprint == a.Fingerprint {
+		t.Fatalf("expected distinct fingerprints per instance")
+	}
+}
+
+func TestConsulInstance(t *testing.T) {
+	entry := &consul.ServiceEntry{
+		Node: consul.Node{Node: "node-a", Address: "10.0.0.1", Datacenter: "DC1"},
+		Service: consul.AgentService{
+			ID:   "metadata-service-10.0.0.2-8081",
+			Tags: []string{"mock-s3", "version=v1.2.0"},
+			Port: 8081,
+		},
+		Checks: []consul.HealthCheck{{Status: consul.HealthPassing}, {Status: consul.HealthCritical}},
+	}
+
+	instance := consulInstance("metadata-service", entry)
+	if instance.Version != "v1.2.0" {
+		t.Fatalf("expected version from tag, got %q", instance.Version)
+	}
+	if instance.IP != "10.0.0.1" || instance.Host != "node-a" {
+		t.Fatalf("expected node address fallback, got ip=%q host=%q", instance.IP, instance.Host)
+	}
+	if instance.Status != model.InstanceStatusError {
+		t.Fatalf("expected critical check to map to error, got %s", instance.Status)
+	}
+	if instance.Region != "dc1" {
+		t.Fatalf("expected region from datacenter, got %q", instance.Region)
+	}
+
+	entry.Service.Meta = map[string]string{"version": "v1.3.0", "region": "cn-east-1"}
+	entry.Service.Address = "10.0.0.2"
+	entry.Checks = nil
+	instance = consulInstance("metadata-service", entry)
+	if instance.Version != "v1.3.0" || instance.IP != "10.0.0.2" || instance.Status != model.InstanceStatusActive || instance.Region != "cn-east-1" {
+		t.Fatalf("unexpected instance: %+v", instance)
+	}
+}
+
+type recordingAlerter struct {
+	alerts []Alert
+	err    error
+}
+
+func (r *recordingAlerter) IngestAlert(ctx context.Context, alert Alert) (bool, error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	r.alerts = append(r.alerts, alert)
+	return true, nil
+}
+
+func TestReapLostInstances(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	store.SetClock(func() time.Time { return now })
+	s := NewService(store, nil)
+
+	if err := store.CreateService(ctx, &model.Service{Name: "storage"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"storage-1", "storage-2"} {
+		if _, err := s.RegisterServiceInstance(ctx, "storage", &model.RegisterInstanceRequest{ID: id, Version: "v1.0.0"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// storage-2 一分钟后仍有心跳，storage-1 超时
+	now = now.Add(time.Minute)
+	if _, err := s.HeartbeatServiceInstance(ctx, "storage", "storage-2", &model.InstanceHeartbeatRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Minute)
+
+	// 告警上报失败时实例保持原状态，下次扫描重新上报
+	failing := &recordingAlerter{err: errors.New("ingest alert: connection refused")}
+	if n, err := s.ReapLostInstances(ctx, now, 90*time.Second, failing); err == nil || n != 0 {
+		t.Fatalf("expected failed ingest to be returned, got n=%d err=%v", n, err)
+	}
+	if instance, _ := store.GetServiceInstance(ctx, "storage", "storage-1"); instance == nil || instance.Status == model.InstanceStatusLost {
+		t.Fatalf("expected storage-1 to stay unmarked after failed alert, got %+v", instance)
+	}
+
+	alerter := &recordingAlerter{}
+	n, err := s.ReapLostInstances(ctx, now, 90*time.Second, alerter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || len(alerter.alerts) != 1 || alerter.alerts[0].Labels["instance"] != "storage-1" {
+		t.Fatalf("expected only storage-1 reaped, got n=%d alerts=%v", n, alerter.alerts)
+	}
+
+	// 再次扫描不会重复告警
+	if n, _ := s.ReapLostInstances(ctx, now, 90*time.Second, alerter); n != 0 {
+		t.Fatalf("expected lost instance to be reaped once, got %d", n)
+	}
+
+	// 失联实例恢复心跳后重新变为active
+	instance, err := s.HeartbeatServiceInstance(ctx, "storage", "storage-1", &model.InstanceHeartbeatRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Status != model.InstanceStatusActive {
+		t.Fatalf("expected heartbeat to revive lost instance, got %s", instance.Status)
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// ===== 服务实例业务方法 =====
+
+// RegisterServiceInstance 注册服务实例，重复注册视为更新并刷新心跳；实例ID已属于其他服务时返回ErrInstanceConflict
+func (s *Service) RegisterServiceInstance(ctx context.Context, serviceName string, req *model.RegisterInstanceRequest) (*model.ServiceInstance, error) {
+	service, err := s.getService(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	instanceRegion, err := resolveRegion(service, req.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	status := req.Status
+	if status == "" {
+		status = model.InstanceStatusActive
+	}
+	if !isReportableInstanceStatus(status) {
+		return nil, fmt.Errorf("%w: status %q", ErrInvalidInstance, status)
+	}
+	if strings.TrimSpace(req.ID) == "" {
+		return nil, fmt.Errorf("%w: id is required", ErrInvalidInstance)
+	}
+	if err := validateVersionName(req.Version); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInstance, err)
+	}
+	if req.Port < 0 || req.Port > 65535 {
+		return nil, fmt.Errorf("%w: port out of range", ErrInvalidInstance)
+	}
+
+	instance, err := s.db.UpsertServiceInstance(ctx, &model.ServiceInstance{
+		ID:      strings.TrimSpace(req.ID),
+		Service: serviceName,
+		Region:  instanceRegion,
+		Version: req.Version,
+		Status:  status,
+		Host:    req.Host,
+		IP:      req.IP,
+		Port:    req.Port,
+		Source:  model.InstanceSourceAPI,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if instance == nil {
+		return nil, fmt.Errorf("%w: %s", ErrInstanceConflict, strings.TrimSpace(req.ID))
+	}
+	return instance, nil
+}
+
+// HeartbeatServiceInstance 记录实例心跳，失联实例恢复心跳后重新变为active
+func (s *Service) HeartbeatServiceInstance(ctx context.Context, serviceName, instanceID string, req *model.InstanceHeartbeatRequest) (*model.ServiceInstance, error) {
+	if req.Status != "" && !isReportableInstanceStatus(req.Status) {
+		return nil, fmt.Errorf("%w: status %q", ErrInvalidInstance, req.Status)
+	}
+	if req.Version != "" {
+		if err := validateVersionName(req.Version); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInstance, err)
+		}
+	}
+
+	instance, err := s.db.TouchServiceInstance(ctx, serviceName, instanceID, req.Version, req.Status)
+	if err != nil {
+		return nil, err
+	}
+	if instance == nil {
+		return nil, ErrInstanceNotFound
+	}
+	return instance, nil
+}
+
+// GetServiceInstances 获取服务实例列表，version、region不为空时只返回对应版本、区域的实例
+func (s *Service) GetServiceInstances(ctx context.Context, serviceName, version, regionName string) ([]model.ServiceInstance, error) {
+	regionName, err := regionFilter(regionName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureServiceExists(ctx, serviceName); err != nil {
+		return nil, err
+	}
+
+	instances, err := s.db.GetServiceInstances(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []model.ServiceInstance{}
+	for _, instance := range instances {
+		if version != "" && instance.Version != version {
+			continue
+		}
+		if regionName != "" && instance.Region != regionName {
+			continue
+		}
+		items = append(items, instance)
+	}
+	return items, nil
+}
+
+// DeregisterServiceInstance 注销服务实例
+func (s *Service) DeregisterServiceInstance(ctx context.Context, serviceName, instanceID string) error {
+	deleted, err := s.db.DeleteServiceInstance(ctx, serviceName, instanceID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrInstanceNotFound
+	}
+	return nil
+}
+
+func (s *Service) ensureServiceExists(ctx context.Context, serviceName string) error {
+	_, err := s.getService(ctx, serviceName)
+	return err
+}
+
+func (s *Service) getService(ctx context.Context, serviceName string) (*model.Service, error) {
+	service, err := s.db.GetServiceByName(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if service == nil {
+		return nil, ErrServiceNotFound
+	}
+	return service, nil
+}
+
+// isReportableInstanceStatus 实例可以主动上报的状态，lost只能由服务端判定
+func isReportableInstanceStatus(status model.InstanceStatus) bool {
+	switch status {
+	case model.InstanceStatusActive, model.InstanceStatusPending, model.InstanceStatusError:
+		return true
+	}
+	return false
+}
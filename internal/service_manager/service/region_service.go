@@ -0,0 +1,60 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/qiniu/zeroops/internal/region"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// ===== 区域相关辅助方法 =====
+
+// normalizeRegions 规范化并去重服务的部署区域，保持原有顺序
+func normalizeRegions(regions []string) ([]string, error) {
+	out := make([]string, 0, len(regions))
+	for _, name := range regions {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		name = region.Normalize(name)
+		if err := region.Validate(name); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRegion, err)
+		}
+		if !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+// resolveRegion 确定写入操作的目标区域：为空时取默认区域，
+// 服务限定了部署区域时目标区域必须在其中
+func resolveRegion(service *model.Service, name string) (string, error) {
+	name = region.Normalize(name)
+	if err := region.Validate(name); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRegion, err)
+	}
+	if len(service.Regions) > 0 && !slices.Contains(service.Regions, name) {
+		return "", fmt.Errorf("%w: service %s is not deployed in region %s", ErrInvalidRegion, service.Name, name)
+	}
+	return name, nil
+}
+
+// regionFilter 规范化查询条件中的区域，为空表示不限区域
+func regionFilter(name string) (string, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", nil
+	}
+	name = region.Normalize(name)
+	if err := region.Validate(name); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRegion, err)
+	}
+	return name, nil
+}
+
+// servesRegion 服务是否部署在指定区域，未限定区域的服务部署在所有区域
+func servesRegion(regions []string, name string) bool {
+	return name == "" || len(regions) == 0 || slices.Contains(regions, name)
+}
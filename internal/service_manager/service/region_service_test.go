@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/service_manager/database/memory"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+func TestResolveRegion(t *testing.T) {
+	restricted := &model.Service{Name: "storage", Regions: []string{"cn-east-1"}}
+	cases := []struct {
+		name    string
+		service *model.Service
+		region  string
+		want    string
+		err     bool
+	}{
+		{"empty is default", &model.Service{Name: "api"}, "", "default", false},
+		{"normalized", &model.Service{Name: "api"}, " CN-North-1 ", "cn-north-1", false},
+		{"invalid", &model.Service{Name: "api"}, "cn/north", "", true},
+		{"inside service regions", restricted, "cn-east-1", "cn-east-1", false},
+		{"outside service regions", restricted, "us-west-1", "", true},
+		{"default outside service regions", restricted, "", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := resolveRegion(tc.service, tc.region)
+			if tc.err {
+				if !errors.Is(err, ErrInvalidRegion) {
+					t.Fatalf("expected ErrInvalidRegion, got %v", err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("expected %q, got %q (%v)", tc.want, got, err)
+			}
+		})
+	}
+}
+
+func TestActiveVersionsByRegion(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	if err := store.CreateService(ctx, &model.Service{Name: "api"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range []model.ServiceInstance{
+		{ID: "a-1", Service: "api", Version: "v1", Region: "cn-east-1", Status: model.InstanceStatusActive},
+		{ID: "a-2", Service: "api", Version: "v1", Region: "cn-north-1", Status: model.InstanceStatusActive},
+	} {
+		if _, err := store.UpsertServiceInstance(ctx, &in); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	store.PutServiceState(model.ServiceState{Service: "api", Version: "v1", Region: "cn-east-1", HealthState: model.HealthStateWarning, ReportAt: now})
+	store.PutServiceState(model.ServiceState{Service: "api", Version: "v1", Region: "cn-north-1", HealthState: model.HealthStateError, ReportAt: now})
+
+	s := NewService(store, nil)
+	all, err := s.GetServiceActiveVersions(ctx, "api", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].Instances != 2 || all[0].Health != model.HealthStateError {
+		t.Fatalf("expected both instances with the worst health across regions, got %+v", all)
+	}
+
+	east, err := s.GetServiceActiveVersions(ctx, "api", "cn-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(east) != 1 || east[0].Instances != 1 || east[0].Health != model.HealthStateWarning {
+		t.Fatalf("expected one instance with the region's health, got %+v", east)
+	}
+}
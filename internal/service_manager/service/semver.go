@@ -0,0 +1,154 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+)
+
+// semver 语义化版本（兼容v前缀）
+type semver struct {
+	major, minor, patch int
+	pre                 []string
+}
+
+// parseSemver 解析 vMAJOR.MINOR.PATCH[-PRERELEASE][+BUILD]，非语义化版本返回false
+// 数字部分只能由数字组成且不能有前导零，不接受正负号
+func parseSemver(v string) (semver, bool) {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		v = v[:i]
+	}
+
+	var sv semver
+	core := v
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		core = v[:i]
+		sv.pre = strings.Split(v[i+1:], ".")
+		for _, ident := range sv.pre {
+			if !validPreIdent(ident) {
+				return semver{}, false
+			}
+		}
+	}
+
+	parts := strings.Split(core, ".")
+	if len(parts) != 3 {
+		return semver{}, false
+	}
+	nums := make([]int, 3)
+	for i, p := range parts {
+		if !isNumericIdent(p) {
+			return semver{}, false
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return semver{}, false
+		}
+		nums[i] = n
+	}
+	sv.major, sv.minor, sv.patch = nums[0], nums[1], nums[2]
+	return sv, true
+}
+
+// isNumericIdent 非空、只含数字且没有前导零
+func isNumericIdent(s string) bool {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validPreIdent 预发布标识由字母、数字和连字符组成，纯数字时不能有前导零
+func validPreIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	numeric := true
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-':
+			numeric = false
+		default:
+			return false
+		}
+	}
+	return !numeric || isNumericIdent(s)
+}
+
+// compareSemver 比较两个语义化版本，返回-1/0/1
+func compareSemver(a, b semver) int {
+	if c := compareInt(a.major, b.major); c != 0 {
+		return c
+	}
+	if c := compareInt(a.minor, b.minor); c != 0 {
+		return c
+	}
+	if c := compareInt(a.patch, b.patch); c != 0 {
+		return c
+	}
+
+	// 没有预发布标识的版本优先级更高
+	switch {
+	case len(a.pre) == 0 && len(b.pre) == 0:
+		return 0
+	case len(a.pre) == 0:
+		return 1
+	case len(b.pre) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(a.pre) && i < len(b.pre); i++ {
+		if c := comparePreIdent(a.pre[i], b.pre[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(a.pre), len(b.pre))
+}
+
+// comparePreIdent 数字标识按数值比较且低于字母标识，字母标识按字典序比较
+func comparePreIdent(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInt(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// CompareVersions 比较两个版本号：均为语义化版本时按语义化规则，
+// 语义化版本始终排在非语义化版本之后（更新），两者都不是时按字典序
+func CompareVersions(a, b string) int {
+	av, aok := parseSemver(a)
+	bv, bok := parseSemver(b)
+	switch {
+	case aok && bok:
+		return compareSemver(av, bv)
+	case aok:
+		return 1
+	case bok:
+		return -1
+	}
+	return strings.Compare(a, b)
+}
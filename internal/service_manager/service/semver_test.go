@@ -0,0 +1,60 @@
+package service
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.0", "v1.10.0", -1},
+		{"1.2.3", "v1.2.3", 0},
+		{"v2.0.0", "v1.99.99", 1},
+		{"v1.0.0-alpha", "v1.0.0", -1},
+		{"v1.0.0-alpha", "v1.0.0-alpha.1", -1},
+		{"v1.0.0-alpha.1", "v1.0.0-alpha.beta", -1},
+		{"v1.0.0-beta.2", "v1.0.0-beta.11", -1},
+		{"v1.0.0-rc.1", "v1.0.0-beta.11", 1},
+		{"v1.0.0+build.5", "v1.0.0+build.1", 0},
+		{"nightly", "v0.0.1", -1},
+		{"build-a", "build-b", -1},
+	}
+	for _, tc := range cases {
+		if got := CompareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestParseSemver(t *testing.T) {
+	cases := []struct {
+		v  string
+		ok bool
+	}{
+		{"v1.2.3", true},
+		{"1.2.3", true},
+		{"v0.0.0", true},
+		{"v10.20.30", true},
+		{"v1.0.0-alpha.0.x-y", true},
+		{"v1.0.0+build.01", true},
+		{"+1.2.3", false},
+		{"1.+2.3", false},
+		{"1.2.-3", false},
+		{"v01.2.3", false},
+		{"v1.02.3", false},
+		{"v1.2.03", false},
+		{"v1.2", false},
+		{"v1.2.3.4", false},
+		{"v1. 2.3", false},
+		{"v1.2.3-", false},
+		{"v1.2.3-alpha..1", false},
+		{"v1.2.3-01", false},
+		{"v1.2.3-beta_1", false},
+		{"v99999999999999999999.0.0", false},
+	}
+	for _, tc := range cases {
+		if _, ok := parseSemver(tc.v); ok != tc.ok {
+			t.Errorf("parseSemver(%q) ok = %v, want %v", tc.v, ok, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+	"github.com/rs/zerolog/log"
+)
+
+// packageCheckTimeout 校验版本包可访问性的超时时间
+const packageCheckTimeout = 5 * time.Second
+
+// ===== 服务版本业务方法 =====
+
+// GetServiceAvailableVersions 获取可用服务版本，按语义化版本从新到旧排序
+// versionType 为空时返回全部版本，否则按 unreleased/active/stable/rolledback/deprecated 过滤
+func (s *Service) GetServiceAvailableVersions(ctx context.Context, serviceName, versionType string) ([]model.ServiceVersion, error) {
+	filter, err := parseVersionType(versionType)
+	if err != nil {
+		return nil, err
+	}
+
+	versions, err := s.db.GetServiceVersions(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.db.GetVersionReleaseStats(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	running, err := s.db.GetRunningVersions(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]model.ServiceVersion, 0, len(versions))
+	for _, version := range versions {
+		version.Status = classifyVersion(&version, stats[version.Version], running[version.Version])
+		if filter != "" && version.Status != filter {
+			continue
+		}
+		result = append(result, version)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return CompareVersions(result[i].Version, result[j].Version) > 0
+	})
+
+	return result, nil
+}
+
+// parseVersionType 解析版本类型过滤参数，兼容旧的 unrelease 写法
+func parseVersionType(versionType string) (model.VersionStatus, error) {
+	switch t := model.VersionStatus(strings.ToLower(strings.TrimSpace(versionType))); t {
+	case "":
+		return "", nil
+	case "unrelease":
+		return model.VersionStatusUnreleased, nil
+	case model.VersionStatusUnreleased, model.VersionStatusActive, model.VersionStatusStable,
+		model.VersionStatusRolledBack, model.VersionStatusDeprecated:
+		return t, nil
+	default:
+		return "", ErrInvalidVersionType
+	}
+}
+
+// classifyVersion 计算版本状态，优先级：废弃 > 运行中 > 稳定 > 已回滚 > 未发布
+func classifyVersion(version *model.ServiceVersion, stat model.VersionReleaseStat, running bool) model.VersionStatus {
+	switch {
+	case version.DeprecatedAt != nil:
+		return model.VersionStatusDeprecated
+	case running:
+		return model.VersionStatusActive
+	case stat.Completed:
+		return model.VersionStatusStable
+	case stat.RolledBack:
+		return model.VersionStatusRolledBack
+	default:
+		return model.VersionStatusUnreleased
+	}
+}
+
+// CreateServiceVersion 注册服务版本
+func (s *Service) CreateServiceVersion(ctx context.Context, serviceName string, req *model.CreateServiceVersionRequest) (*model.ServiceVersion, error) {
+	if err := validateVersionName(req.Version); err != nil {
+		return nil, err
+	}
+	// 新注册的版本须为语义化版本，历史数据中的非语义化版本仍可查询，排在语义化版本之后
+	if _, ok := parseSemver(req.Version); !ok {
+		return nil, ErrInvalidVersion
+	}
+	checksum, err := normalizeChecksum(req.Checksum)
+	if err != nil {
+		return nil, err
+	}
+	packageURL, err := s.packages.Check(ctx, req.PackageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := s.db.GetServiceByName(ctx, serviceName)
+	if err != nil {
+		return nil, err
+	}
+	if service == nil {
+		return nil, ErrServiceNotFound
+	}
+
+	existing, err := s.db.GetServiceVersion(ctx, serviceName, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrVersionAlreadyExists
+	}
+
+	version := &model.ServiceVersion{
+		Version:    req.Version,
+		Service:    serviceName,
+		CreateTime: time.Now(),
+		PackageURL: packageURL,
+		Checksum:   checksum,
+		Changelog:  req.Changelog,
+		BuildMeta:  req.BuildMeta,
+		Status:     model.VersionStatusUnreleased,
+	}
+	if err := s.db.CreateServiceVersion(ctx, version); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("service", serviceName).
+		Str("version", req.Version).
+		Msg("service version registered successfully")
+
+	return version, nil
+}
+
+// DeprecateServiceVersion 废弃服务版本，仍有实例运行的版本不能废弃
+func (s *Service) DeprecateServiceVersion(ctx context.Context, serviceName, version string) error {
+	existing, err := s.db.GetServiceVersion(ctx, serviceName, version)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrVersionNotFound
+	}
+	if existing.DeprecatedAt != nil {
+		return nil
+	}
+
+	running, err := s.db.GetRunningVersions(ctx, serviceName)
+	if err != nil {
+		return err
+	}
+	if running[version] {
+		return ErrVersionInUse
+	}
+
+	if _, err := s.db.DeprecateServiceVersion(ctx, serviceName, version); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("service", serviceName).
+		Str("version", version).
+		Msg("service version deprecated successfully")
+
+	return nil
+}
+
+// validateVersionName 版本号不能为空、过长或包含空白和路径分隔符
+func validateVersionName(version string) error {
+	if version == "" || len(version) > 255 {
+		return ErrInvalidVersion
+	}
+	if strings.ContainsAny(version, " \t\r\n/\\?#") {
+		return ErrInvalidVersion
+	}
+	return nil
+}
+
+// normalizeChecksum 校验并统一为 sha256:<hex> 格式，为空时直接返回
+func normalizeChecksum(checksum string) (string, error) {
+	checksum = strings.TrimSpace(checksum)
+	if checksum == "" {
+		return "", nil
+	}
+	digest := strings.TrimPrefix(strings.ToLower(checksum), "sha256:")
+	if len(digest) != 64 {
+		return "", ErrInvalidChecksum
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", ErrInvalidChecksum
+	}
+	return "sha256:" + digest, nil
+}
+
+// PackageChecker 通过 HEAD 请求确认版本包存在，只请求白名单内的主机且不跟随重定向，
+// 避免调用方借注册版本让服务端访问内网地址或云元数据接口
+type PackageChecker struct {
+	hosts  map[string]bool
+	client *http.Client
+}
+
+// NewPackageChecker hosts 为允许的主机名（不含端口，不区分大小写），为空时返回nil，此时只校验URL格式
+func NewPackageChecker(hosts []string) *PackageChecker {
+	allowed := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			allowed[host] = true
+		}
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	return &PackageChecker{
+		hosts: allowed,
+		client: &http.Client{
+			Timeout: packageCheckTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// SetPackageChecker 设置注册版本时校验包地址使用的检查器，nil 表示只校验URL格式
+func (s *Service) SetPackageChecker(p *PackageChecker) {
+	s.packages = p
+}
+
+// Check 验证是否能通过URL找到包
+// 只允许不带用户信息的 http/https 地址；p 不为nil时主机必须在白名单内，并通过 HEAD 请求确认包存在
+// 返回规范化后的地址，注册版本时保存的就是校验过的这个地址
+func (p *PackageChecker) Check(ctx context.Context, packageURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(packageURL))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPackageURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("%w: unsupported scheme %q", ErrInvalidPackageURL, u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("%w: missing host", ErrInvalidPackageURL)
+	}
+	if u.User != nil {
+		return "", fmt.Errorf("%w: credentials in url are not allowed", ErrInvalidPackageURL)
+	}
+	if p == nil {
+		return u.String(), nil
+	}
+	if !p.hosts[strings.ToLower(u.Hostname())] {
+		return "", fmt.Errorf("%w: host %q is not allowed", ErrInvalidPackageURL, u.Hostname())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, packageCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPackageURL, err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPackageURL, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%w: package not found (status %d)", ErrInvalidPackageURL, resp.StatusCode)
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// AnalyticsQuery selects the issues of the analytics calls. From and To are inclusive
+// days like 2006-01-02 (UTC); empty means the last 7 days up to today. Empty filters
+// match every issue.
+type AnalyticsQuery struct {
+	From      string
+	To        string
+	Service   string
+	AlertName string
+	Level     string
+}
+
+func (q AnalyticsQuery) values() url.Values {
+	v := url.Values{}
+	for name, value := range map[string]string{"from": q.From, "to": q.To, "service": q.Service, "alertname": q.AlertName, "level": q.Level} {
+		if value != "" {
+			v.Set(name, value)
+		}
+	}
+	return v
+}
+
+// IssueAnalytics returns the issue statistics of q grouped by groupBy, a list of day,
+// service, alertname and level; none groups by service, alertname and level.
+func (c *Client) IssueAnalytics(ctx context.Context, q AnalyticsQuery, groupBy ...string) (*IssueStats, error) {
+	query := q.values()
+	if len(groupBy) > 0 {
+		query.Set("groupBy", strings.Join(groupBy, ","))
+	}
+	var out IssueStats
+	if err := c.do(ctx, http.MethodGet, "/v1/analytics/issues", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FlappingAlerts returns up to limit alert fingerprints with at least minIssues issues
+// in q, most issues first; zero means the server defaults of 3 issues and 10 alerts.
+func (c *Client) FlappingAlerts(ctx context.Context, q AnalyticsQuery, minIssues, limit int) ([]FlappingAlert, error) {
+	query := q.values()
+	if minIssues > 0 {
+		query.Set("minIssues", strconv.Itoa(minIssues))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out struct {
+		Items []FlappingAlert `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/analytics/flapping", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// NoisyRules returns up to limit alert rules of q, most issues first; a limit of 0
+// means 10.
+func (c *Client) NoisyRules(ctx context.Context, q AnalyticsQuery, limit int) ([]NoisyRule, error) {
+	query := q.values()
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out struct {
+		Items []NoisyRule `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/analytics/noisy-rules", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// RollUpAnalytics recomputes the stored daily rollups of the inclusive days from..to and
+// returns the number of days.
+func (c *Client) RollUpAnalytics(ctx context.Context, from, to string) (int, error) {
+	query := AnalyticsQuery{From: from, To: to}.values()
+	var out struct {
+		Days int `json:"days"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/analytics/rollups", query, nil, &out); err != nil {
+		return 0, err
+	}
+	return out.Days, nil
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListAnomalyDetectors returns the anomaly detectors of service, or of every service when
+// it is "".
+func (c *Client) ListAnomalyDetectors(ctx context.Context, service string) ([]AnomalyDetector, error) {
+	query := url.Values{}
+	if service != "" {
+		query.Set("service", service)
+	}
+	var out struct {
+		Items []AnomalyDetector `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/anomaly/detectors", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// PutAnomalyDetector sets the detector of a configured series for service.
+func (c *Client) PutAnomalyDetector(ctx context.Context, service, series string, params *AnomalyParams) (*AnomalyDetector, error) {
+	var out AnomalyDetector
+	if err := c.do(ctx, http.MethodPut, pathf("/v1/anomaly/detectors/%s/%s", service, series), nil, params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteAnomalyDetector stops anomaly detection of series for service.
+func (c *Client) DeleteAnomalyDetector(ctx context.Context, service, series string) error {
+	return c.do(ctx, http.MethodDelete, pathf("/v1/anomaly/detectors/%s/%s", service, series), nil, nil, nil)
+}
+
+// BacktestAnomalyDetector replays a detector over a past range.
+func (c *Client) BacktestAnomalyDetector(ctx context.Context, req *AnomalyBacktest) (*AnomalyBacktestResult, error) {
+	var out AnomalyBacktestResult
+	if err := c.do(ctx, http.MethodPost, "/v1/anomaly/backtest", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
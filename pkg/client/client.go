@@ -0,0 +1,178 @@
+// Package client is a typed Go client for the zeroops HTTP API.
+//
+// Request and response types are aliases of the structs the server encodes, so the
+// client and the handlers cannot drift apart; the contract tests run the client
+// against the real router and check every response against the OpenAPI document
+// served at /openapi.json.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/qiniu/zeroops/internal/apierror"
+)
+
+// Client calls one zeroops server. It is safe for concurrent use.
+type Client struct {
+	baseURL  string
+	http     *http.Client
+	token    string
+	operator string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// WithToken sends the token as a bearer Authorization header.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithOperator sends X-Operator, which the server records on deployment events.
+func WithOperator(operator string) Option {
+	return func(c *Client) { c.operator = operator }
+}
+
+// New returns a client for the server at baseURL, e.g. "http://zeroops:8080".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{baseURL: strings.TrimRight(baseURL, "/"), http: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is a non-2xx response. Code is the stable error code such as NOT_FOUND or
+// INVALID_STATE; RequestID identifies the request in the server logs.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    map[string]any
+	RequestID  string
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("zeroops: %d %s", e.StatusCode, e.Code)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if e.RequestID != "" {
+		msg += " (request " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// ErrorCode returns the error code of an *APIError in err's chain, or "".
+func ErrorCode(err error) string {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return ""
+}
+
+// IsNotFound reports whether err is a 404 from the server.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, in any) (*http.Request, error) {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.operator != "" {
+		req.Header.Set("X-Operator", c.operator)
+	}
+	return req, nil
+}
+
+// do sends a JSON request and decodes a 2xx JSON response into out, if non-nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	req, err := c.newRequest(ctx, method, path, query, in)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeError(resp)
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("zeroops: decode %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// decodeError reads the shared error body; anything else becomes the message.
+func decodeError(resp *http.Response) error {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Code:       http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get(apierror.RequestIDHeader),
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var body apierror.Response
+	if json.Unmarshal(data, &body) != nil || body.Error.Code == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+		return apiErr
+	}
+	apiErr.Code = string(body.Error.Code)
+	apiErr.Message = body.Error.Message
+	apiErr.Details = body.Error.Details
+	if body.Error.RequestID != "" {
+		apiErr.RequestID = body.Error.RequestID
+	}
+	return apiErr
+}
+
+func pathf(format string, params ...string) string {
+	escaped := make([]any, len(params))
+	for i, p := range params {
+		escaped[i] = url.PathEscape(p)
+	}
+	return fmt.Sprintf(format, escaped...)
+}
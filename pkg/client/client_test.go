@@ -0,0 +1,440 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/fox-gonic/fox"
+	alertapi "github.com/qiniu/zeroops/internal/alerting/api"
+	adb "github.com/qiniu/zeroops/internal/alerting/database"
+	adbmemory "github.com/qiniu/zeroops/internal/alerting/database/memory"
+	"github.com/qiniu/zeroops/internal/lifecycle"
+	"github.com/qiniu/zeroops/internal/observability"
+	"github.com/qiniu/zeroops/internal/openapi"
+	"github.com/qiniu/zeroops/internal/service_manager/api"
+	smmemory "github.com/qiniu/zeroops/internal/service_manager/database/memory"
+	"github.com/qiniu/zeroops/internal/service_manager/service"
+	"github.com/qiniu/zeroops/pkg/client"
+	"github.com/redis/go-redis/v9"
+)
+
+type env struct {
+	router *fox.Engine
+	server *httptest.Server
+	redis  *miniredis.Miniredis
+	issues *adbmemory.Store
+	doc    *openapi.Document
+	client *client.Client
+}
+
+// newEnv serves the same routes as cmd/zeroops on in-memory stores. Every response the
+// client receives is checked against the document served at /openapi.json.
+func newEnv(t *testing.T) *env {
+	t.Helper()
+	store := smmemory.New()
+	router := fox.New()
+	if _, err := api.NewApi(store, service.NewService(store, nil), router); err != nil {
+		t.Fatal(err)
+	}
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = rdb.Close() })
+	issues := adbmemory.New()
+	alertapi.NewApiWithDB(router, issues, rdb)
+	lifecycle.New(0).RegisterHealthRoutes(router)
+	observability.RegisterMetricsRoute(router)
+	openapi.RegisterRoute(router)
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var doc openapi.Document
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode /openapi.json: %v", err)
+	}
+
+	hc := &http.Client{Transport: &contractTransport{t: t, doc: &doc, next: http.DefaultTransport}}
+	return &env{
+		router: router,
+		server: srv,
+		redis:  mr,
+		issues: issues,
+		doc:    &doc,
+		client: client.New(srv.URL, client.WithHTTPClient(hc), client.WithOperator("alice")),
+	}
+}
+
+// contractTransport fails the test when a response is not described by the spec.
+type contractTransport struct {
+	t    *testing.T
+	doc  *openapi.Document
+	next http.RoundTripper
+}
+
+func (ct *contractTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := ct.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		if err := ct.doc.ValidateResponse(req.Method, req.URL.Path, resp.StatusCode, contentType, nil); err != nil {
+			ct.t.Errorf("contract: %v", err)
+		}
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err := ct.doc.ValidateResponse(req.Method, req.URL.Path, resp.StatusCode, contentType, body); err != nil {
+		ct.t.Errorf("contract: %v\nbody: %s", err, body)
+	}
+	return resp, nil
+}
+
+func TestSpecMatchesRoutes(t *testing.T) {
+	e := newEnv(t)
+
+	routes := map[string]bool{}
+	for _, r := range e.router.Routes() {
+		segs := strings.Split(r.Path, "/")
+		for i, seg := range segs {
+			if name, ok := strings.CutPrefix(seg, ":"); ok {
+				segs[i] = "{" + name + "}"
+			}
+		}
+		routes[r.Method+" "+strings.Join(segs, "/")] = true
+	}
+
+	documented := map[string]bool{}
+	for path, item := range e.doc.Paths {
+		for method := range *item {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	for route := range routes {
+		if !documented[route] {
+			t.Errorf("route %s is not in the OpenAPI document", route)
+		}
+	}
+	for route := range documented {
+		if !routes[route] {
+			t.Errorf("OpenAPI document describes %s, which is not registered", route)
+		}
+	}
+}
+
+func TestServices(t *testing.T) {
+	e := newEnv(t)
+	c, ctx := e.client, context.Background()
+
+	if err := c.CreateService(ctx, &client.Service{Name: "storage", Deps: []string{}}); err != nil {
+		t.Fatalf("create storage: %v", err)
+	}
+	if err := c.CreateService(ctx, &client.Service{Name: "api", Deps: []string{"storage"}}); err != nil {
+		t.Fatalf("create api: %v", err)
+	}
+
+	list, err := c.ListServices(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 || len(list.Relation["api"]) != 1 {
+		t.Fatalf("services = %+v", list)
+	}
+
+	topo, err := c.ServiceTopology(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topo.Order) != 2 || topo.Order[0] != "storage" {
+		t.Fatalf("topology order = %v", topo.Order)
+	}
+	impact, err := c.ServiceImpact(ctx, "storage", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(impact.Items) != 1 || impact.Items[0].Name != "api" {
+		t.Fatalf("impact = %+v", impact.Items)
+	}
+
+	// the server checks that the package exists before registering a version
+	pkgs := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer pkgs.Close()
+	if _, err := c.CreateVersion(ctx, "api", &client.CreateVersionRequest{Version: "v1.0.0", PackageURL: pkgs.URL + "/api-v1.0.0.tgz"}); err != nil {
+		t.Fatalf("create version: %v", err)
+	}
+	versions, err := c.AvailableVersions(ctx, "api", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].Version != "v1.0.0" {
+		t.Fatalf("versions = %+v", versions)
+	}
+
+	inst, err := c.RegisterInstance(ctx, "api", &client.RegisterInstanceRequest{ID: "api-1", Version: "v1.0.0", IP: "10.0.0.1", Port: 8080})
+	if err != nil {
+		t.Fatalf("register instance: %v", err)
+	}
+	if inst.Status != "active" {
+		t.Fatalf("instance status = %s", inst.Status)
+	}
+	if _, err := c.Heartbeat(ctx, "api", "api-1", nil); err != nil {
+		t.Fatalf("heartbeat: %v", err)
+	}
+	active, err := c.ActiveVersions(ctx, "api", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(active) != 1 || active[0].Instances != 1 {
+		t.Fatalf("active versions = %+v", active)
+	}
+	if err := c.DeregisterInstance(ctx, "api", "api-1"); err != nil {
+		t.Fatal(err)
+	}
+	instances, err := c.ListInstances(ctx, "api", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 0 {
+		t.Fatalf("instances after deregister = %+v", instances)
+	}
+
+	if err := c.DeprecateVersion(ctx, "api", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateService(ctx, &client.Service{Name: "api", Deps: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteService(ctx, "api"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ServiceImpact(ctx, "api", ""); !client.IsNotFound(err) {
+		t.Fatalf("impact of deleted service: err = %v, want not found", err)
+	}
+}
+
+func TestDeployments(t *testing.T) {
+	e := newEnv(t)
+	c, ctx := e.client, context.Background()
+
+	if err := c.CreateService(ctx, &client.Service{Name: "api", Deps: []string{}}); err != nil {
+		t.Fatal(err)
+	}
+	id, err := c.CreateDeployment(ctx, &client.CreateDeploymentRequest{Service: "api", Version: "v2.0.0", TotalBatches: 2})
+	if err != nil {
+		t.Fatalf("create deployment: %v", err)
+	}
+	if id == "" {
+		t.Fatal("empty deployment id")
+	}
+	_, err = c.CreateDeployment(ctx, &client.CreateDeploymentRequest{Service: "api", Version: "v2.0.0"})
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Fatalf("duplicate deployment: err = %v, want 409", err)
+	}
+
+	d, err := c.GetDeployment(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Status != client.StateDeploying {
+		t.Fatalf("status = %s, want deploying", d.Status)
+	}
+	list, err := c.ListDeployments(ctx, client.DeploymentListOptions{Service: "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != id {
+		t.Fatalf("deployments = %+v", list)
+	}
+
+	if err := c.PauseDeployment(ctx, id); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	if err := c.ContinueDeployment(ctx, id); err != nil {
+		t.Fatalf("continue: %v", err)
+	}
+	batch := 1
+	if _, err := c.CreateDeploymentEvent(ctx, id, &client.CreateDeployEventRequest{Type: "batch_finish", Batch: &batch}); err != nil {
+		t.Fatalf("create event: %v", err)
+	}
+	if err := c.RollbackDeployment(ctx, id); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+
+	events, err := c.ListDeploymentEvents(ctx, id, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// created, paused, continued, batch_finish, rolled back
+	if len(events) != 5 {
+		t.Fatalf("events = %+v", events)
+	}
+	if events[1].Operator != "alice" {
+		t.Fatalf("operator = %q, want alice", events[1].Operator)
+	}
+
+	// the stream replays the backlog and ends at the rollback
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	var streamed []client.DeployEvent
+	err = c.StreamDeploymentEvents(ctx, id, events[2].ID, func(ev client.DeployEvent) error {
+		streamed = append(streamed, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	if len(streamed) != 2 || streamed[1].ToState != client.StateRollback {
+		t.Fatalf("streamed = %+v", streamed)
+	}
+
+	// deleting a scheduled deployment ends a live stream
+	later := time.Now().Add(time.Hour)
+	scheduled, err := c.CreateDeployment(ctx, &client.CreateDeploymentRequest{Service: "api", Version: "v2.1.0", ScheduleTime: &later})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last client.DeployEvent
+	err = c.StreamDeploymentEvents(ctx, scheduled, 0, func(ev client.DeployEvent) error {
+		last = ev
+		if ev.ToState == client.StateUnrelease {
+			return c.DeleteDeployment(ctx, scheduled)
+		}
+		return nil
+	})
+	if err != nil || last.ToState != client.StateDeleted {
+		t.Fatalf("stream of deleted deployment: err = %v, last = %+v", err, last)
+	}
+
+	if _, err := c.GetDeployment(ctx, "missing"); !client.IsNotFound(err) {
+		t.Fatalf("missing deployment: err = %v, want not found", err)
+	}
+}
+
+func TestIssues(t *testing.T) {
+	e := newEnv(t)
+	c, ctx := e.client, context.Background()
+
+	record := `{"id":"issue-1","state":"Open","level":"P1","alertState":"InProcessing","title":"api latency",` +
+		`"labels":[{"key":"service","value":"api"}],"alertSince":"2025-05-05T11:00:00+08:00"}`
+	e.redis.Set("alert:issue:issue-1", record)
+	e.redis.SAdd("alert:index:open", "issue-1")
+	e.redis.SAdd("alert:index:svc:api:open", "issue-1")
+	if err := e.issues.InsertIssue(ctx, &adb.Issue{ID: "issue-1", State: "Open", Level: "P1", AlertState: "InProcessing"}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := c.ListIssues(ctx, client.IssueListOptions{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Labels[0].Value != "api" {
+		t.Fatalf("issues = %+v", list)
+	}
+
+	for _, opts := range []client.IssueListOptions{{Service: "storage"}, {Level: "P0"}} {
+		filtered, err := c.ListIssues(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(filtered.Items) != 0 {
+			t.Fatalf("%+v: issues = %+v", opts, filtered.Items)
+		}
+	}
+
+	if _, err := c.CommentIssue(ctx, "issue-1", "restarted api-1"); err != nil {
+		t.Fatalf("comment: %v", err)
+	}
+	_, err = c.CommentIssue(ctx, "issue-1", "restarted api-1")
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Fatalf("duplicate comment: err = %v, want 409", err)
+	}
+	acked, err := c.AckIssue(ctx, "issue-1")
+	if err != nil {
+		t.Fatalf("ack: %v", err)
+	}
+	if acked.AckedBy != "alice" || acked.AckedAt == "" {
+		t.Fatalf("acked issue = %+v", acked)
+	}
+
+	issue, err := c.GetIssue(ctx, "issue-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issue.AlertSince != "2025-05-05T03:00:00Z" || len(issue.Comments) != 1 || issue.AckedBy != "alice" {
+		t.Fatalf("issue = %+v", issue)
+	}
+
+	if _, err := c.GetIssue(ctx, "missing"); !client.IsNotFound(err) {
+		t.Fatalf("missing issue: err = %v, want not found", err)
+	}
+	_, err = c.ListIssues(ctx, client.IssueListOptions{Limit: 500})
+	if !errors.As(err, &apiErr) || apiErr.Code != "INVALID_PARAMETER" {
+		t.Fatalf("limit 500: err = %v, want INVALID_PARAMETER", err)
+	}
+}
+
+func TestSilences(t *testing.T) {
+	e := newEnv(t)
+	c, ctx := e.client, context.Background()
+
+	m, err := client.ParseMatcher("service=api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sil, err := c.CreateSilence(ctx, &client.CreateSilenceRequest{
+		Matchers: []client.SilenceMatcher{m},
+		Duration: "2h",
+		Comment:  "api maintenance",
+	})
+	if err != nil {
+		t.Fatalf("create silence: %v", err)
+	}
+	if sil.Status != "active" || sil.CreatedBy != "alice" {
+		t.Fatalf("silence = %+v", sil)
+	}
+	if _, err := c.CreateSilence(ctx, &client.CreateSilenceRequest{Matchers: []client.SilenceMatcher{m}, Comment: "no end"}); err == nil {
+		t.Fatal("silence without end accepted")
+	}
+
+	list, err := c.ListSilences(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != sil.ID {
+		t.Fatalf("silences = %+v", list)
+	}
+	if err := c.ExpireSilence(ctx, sil.ID); err != nil {
+		t.Fatalf("expire: %v", err)
+	}
+	if list, _ = c.ListSilences(ctx, false); len(list) != 0 {
+		t.Fatalf("silences after expire = %+v", list)
+	}
+	if list, _ = c.ListSilences(ctx, true); len(list) != 1 || list[0].Status != "expired" {
+		t.Fatalf("expired silences = %+v", list)
+	}
+	if err := c.ExpireSilence(ctx, "missing"); !client.IsNotFound(err) {
+		t.Fatalf("expire missing: err = %v, want not found", err)
+	}
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// DeploymentListOptions filters ListDeployments; zero fields do not filter.
+type DeploymentListOptions struct {
+	State   DeployState
+	Service string
+	Region  string
+	Limit   int
+}
+
+// CreateDeployment schedules a deployment and returns its ID.
+func (c *Client) CreateDeployment(ctx context.Context, req *CreateDeploymentRequest) (string, error) {
+	var out messageResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/deployments", nil, req, &out); err != nil {
+		return "", err
+	}
+	return out.ID, nil
+}
+
+// GetDeployment returns one deployment.
+func (c *Client) GetDeployment(ctx context.Context, id string) (*Deployment, error) {
+	var out Deployment
+	if err := c.do(ctx, http.MethodGet, pathf("/v1/deployments/%s", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListDeployments returns deployments matching opts.
+func (c *Client) ListDeployments(ctx context.Context, opts DeploymentListOptions) ([]Deployment, error) {
+	query := url.Values{}
+	if opts.State != "" {
+		query.Set("type", string(opts.State))
+	}
+	if opts.Service != "" {
+		query.Set("service", opts.Service)
+	}
+	if opts.Region != "" {
+		query.Set("region", opts.Region)
+	}
+	if opts.Limit > 0 {
+		query.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	var out struct {
+		Items []Deployment `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/deployments", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// UpdateDeployment changes the version or schedule of a deployment that has not started.
+func (c *Client) UpdateDeployment(ctx context.Context, id string, req *UpdateDeploymentRequest) error {
+	return c.do(ctx, http.MethodPost, pathf("/v1/deployments/%s", id), nil, req, nil)
+}
+
+// DeleteDeployment cancels a deployment that has not started.
+func (c *Client) DeleteDeployment(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, pathf("/v1/deployments/%s", id), nil, nil, nil)
+}
+
+// PauseDeployment pauses a running deployment.
+func (c *Client) PauseDeployment(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, pathf("/v1/deployments/%s/pause", id), nil, nil, nil)
+}
+
+// ContinueDeployment resumes a paused deployment.
+func (c *Client) ContinueDeployment(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, pathf("/v1/deployments/%s/continue", id), nil, nil, nil)
+}
+
+// RollbackDeployment rolls a deployment back.
+func (c *Client) RollbackDeployment(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodPost, pathf("/v1/deployments/%s/rollback", id), nil, nil, nil)
+}
+
+// ListDeploymentEvents returns the events of a deployment with an ID above after;
+// limit 0 returns all of them.
+func (c *Client) ListDeploymentEvents(ctx context.Context, id string, after int64, limit int) ([]DeployEvent, error) {
+	query := url.Values{}
+	if after > 0 {
+		query.Set("after", strconv.FormatInt(after, 10))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out struct {
+		Items []DeployEvent `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, pathf("/v1/deployments/%s/events", id), query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// CreateDeploymentEvent reports a batch, instance, canary or operator event.
+func (c *Client) CreateDeploymentEvent(ctx context.Context, id string, req *CreateDeployEventRequest) (*DeployEvent, error) {
+	var out DeployEvent
+	if err := c.do(ctx, http.MethodPost, pathf("/v1/deployments/%s/events", id), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// StreamDeploymentEvents calls fn for every event after the given ID, first the
+// backlog and then live events. It returns nil when the server ends the stream
+// because the deployment finished, was rolled back or was deleted, ctx's error when ctx is done,
+// and fn's error if fn fails.
+func (c *Client) StreamDeploymentEvents(ctx context.Context, id string, after int64, fn func(DeployEvent) error) error {
+	header := http.Header{}
+	if after > 0 {
+		header.Set("Last-Event-ID", strconv.FormatInt(after, 10))
+	}
+	// id and event fields repeat what is in data
+	return c.stream(ctx, pathf("/v1/deployments/%s/events/stream", id), nil, header, func(_, data string) error {
+		var event DeployEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("zeroops: decode deployment event: %w", err)
+		}
+		return fn(event)
+	})
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ListCredentials returns the active webhook credentials of integration, of every
+// integration when it is "", and expired ones too when includeExpired is set.
+func (c *Client) ListCredentials(ctx context.Context, integration string, includeExpired bool) ([]Credential, error) {
+	query := url.Values{}
+	if integration != "" {
+		query.Set("integration", integration)
+	}
+	if includeExpired {
+		query.Set("expired", "true")
+	}
+	var out struct {
+		Items []Credential `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/integrations/credentials", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// CreateCredential creates a webhook credential. The returned Secret cannot be read again.
+func (c *Client) CreateCredential(ctx context.Context, req *CreateCredentialRequest) (*Credential, error) {
+	var out Credential
+	if err := c.do(ctx, http.MethodPost, "/v1/integrations/credentials", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RotateCredential replaces a credential, keeping the old one valid for req's overlap,
+// and returns the successor with its secret. A nil req uses the default overlap.
+func (c *Client) RotateCredential(ctx context.Context, id string, req *RotateCredentialRequest) (*Credential, error) {
+	if req == nil {
+		req = &RotateCredentialRequest{}
+	}
+	var out Credential
+	if err := c.do(ctx, http.MethodPost, pathf("/v1/integrations/credentials/%s/rotate", id), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RevokeCredential makes a credential expire now.
+func (c *Client) RevokeCredential(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, pathf("/v1/integrations/credentials/%s", id), nil, nil, nil)
+}
+
+// AuditQuery filters ListAuditLogs; zero fields do not filter and a zero Limit means 100.
+type AuditQuery struct {
+	Action string
+	From   time.Time
+	To     time.Time
+	Limit  int
+}
+
+// ListAuditLogs returns audit log entries matching q, newest first.
+func (c *Client) ListAuditLogs(ctx context.Context, q AuditQuery) ([]AuditEntry, error) {
+	query := url.Values{}
+	if q.Action != "" {
+		query.Set("action", q.Action)
+	}
+	if !q.From.IsZero() {
+		query.Set("from", q.From.UTC().Format(time.RFC3339))
+	}
+	if !q.To.IsZero() {
+		query.Set("to", q.To.UTC().Format(time.RFC3339))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	var out struct {
+		Items []AuditEntry `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/audit-logs", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	alertapi "github.com/qiniu/zeroops/internal/alerting/api"
+)
+
+// IssueListOptions selects a page of issues.
+type IssueListOptions struct {
+	State   string // "Open" (default) or "Closed"
+	Service string
+	Region  string // filtered after paging when Service is set
+	Level   string // filtered after paging, so a page may be short
+	Start   string // IssueList.Next of the previous page
+	Limit   int    // 1-100; 0 means 20
+}
+
+// ListIssues returns one page of issues. Page through by passing IssueList.Next as
+// Start until it is empty.
+func (c *Client) ListIssues(ctx context.Context, opts IssueListOptions) (*IssueList, error) {
+	if opts.Limit == 0 {
+		opts.Limit = 20
+	}
+	query := url.Values{"limit": {strconv.Itoa(opts.Limit)}}
+	if opts.State != "" {
+		query.Set("state", opts.State)
+	}
+	if opts.Service != "" {
+		query.Set("service", opts.Service)
+	}
+	if opts.Region != "" {
+		query.Set("region", opts.Region)
+	}
+	if opts.Level != "" {
+		query.Set("level", opts.Level)
+	}
+	if opts.Start != "" {
+		query.Set("start", opts.Start)
+	}
+	var out IssueList
+	if err := c.do(ctx, http.MethodGet, "/v1/issues", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetIssue returns an issue with its comments.
+func (c *Client) GetIssue(ctx context.Context, id string) (*Issue, error) {
+	var out Issue
+	if err := c.do(ctx, http.MethodGet, pathf("/v1/issues/%s", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// AckIssue acknowledges an issue as the client's operator, which is required.
+func (c *Client) AckIssue(ctx context.Context, id string) (*Issue, error) {
+	var out Issue
+	if err := c.do(ctx, http.MethodPost, pathf("/v1/issues/%s/ack", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CommentIssue adds a comment to an issue.
+func (c *Client) CommentIssue(ctx context.Context, id, content string) (*IssueComment, error) {
+	var out IssueComment
+	in := &alertapi.CreateCommentRequest{Content: content}
+	if err := c.do(ctx, http.MethodPost, pathf("/v1/issues/%s/comments", id), nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListSilences returns pending and active silences, and expired ones too when
+// includeExpired is set.
+func (c *Client) ListSilences(ctx context.Context, includeExpired bool) ([]Silence, error) {
+	query := url.Values{}
+	if includeExpired {
+		query.Set("expired", "true")
+	}
+	var out struct {
+		Items []Silence `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/silences", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// CreateSilence mutes alerts matching every matcher in req.
+func (c *Client) CreateSilence(ctx context.Context, req *CreateSilenceRequest) (*Silence, error) {
+	var out Silence
+	if err := c.do(ctx, http.MethodPost, "/v1/silences", nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExpireSilence ends a silence now.
+func (c *Client) ExpireSilence(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, pathf("/v1/silences/%s", id), nil, nil, nil)
+}
+
+// IssueEvent is a change pushed by StreamIssues: Type is created, updated or closed.
+type IssueEvent struct {
+	Type  string        `json:"type"`
+	Issue IssueListItem `json:"issue"`
+}
+
+// IssueStreamOptions filters StreamIssues; zero fields do not filter.
+type IssueStreamOptions struct {
+	Service string
+	Level   string
+	Region  string
+}
+
+// StreamIssues calls fn for every issue change from now on. Changes are not replayed:
+// list issues after the stream starts, and again after it ends. It returns nil when the
+// server ends the stream because the client fell behind, ctx's error when ctx is done,
+// and fn's error if fn fails.
+func (c *Client) StreamIssues(ctx context.Context, opts IssueStreamOptions, fn func(IssueEvent) error) error {
+	query := url.Values{}
+	for name, value := range map[string]string{"service": opts.Service, "level": opts.Level, "region": opts.Region} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	return c.stream(ctx, "/v1/issues/stream", query, nil, func(event, data string) error {
+		e := IssueEvent{Type: event}
+		if err := json.Unmarshal([]byte(data), &e.Issue); err != nil {
+			return fmt.Errorf("zeroops: decode issue event: %w", err)
+		}
+		return fn(e)
+	})
+}
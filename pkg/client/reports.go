@@ -0,0 +1,56 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// IssueReport generates the postmortem report of an issue from its current history.
+// Report.Markdown renders it as the server does for ?format=markdown.
+func (c *Client) IssueReport(ctx context.Context, id string) (*Report, error) {
+	var out Report
+	if err := c.do(ctx, http.MethodGet, pathf("/v1/issues/%s/report", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SaveIssueReport generates and stores the report of an issue as the client's operator,
+// replacing an earlier one.
+func (c *Client) SaveIssueReport(ctx context.Context, id string) (*StoredReport, error) {
+	var out StoredReport
+	if err := c.do(ctx, http.MethodPost, pathf("/v1/issues/%s/report", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetReport returns the stored report of an issue.
+func (c *Client) GetReport(ctx context.Context, id string) (*StoredReport, error) {
+	var out StoredReport
+	if err := c.do(ctx, http.MethodGet, pathf("/v1/reports/%s", id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListReports returns up to limit stored reports of service, newest first; "" lists
+// those of every service and a limit of 0 means 50.
+func (c *Client) ListReports(ctx context.Context, service string, limit int) ([]ReportSummary, error) {
+	query := url.Values{}
+	if service != "" {
+		query.Set("service", service)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out struct {
+		Items []ReportSummary `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/reports", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// regionQuery is the query of endpoints that take only a region filter; an empty
+// region does not filter.
+func regionQuery(region string) url.Values {
+	query := url.Values{}
+	if region != "" {
+		query.Set("region", region)
+	}
+	return query
+}
+
+// ListServices returns every service with its health, deploy state and dependency
+// relation; a non-empty region limits them to the services deployed there.
+func (c *Client) ListServices(ctx context.Context, region string) (*ServicesResponse, error) {
+	var out ServicesResponse
+	if err := c.do(ctx, http.MethodGet, "/v1/services", regionQuery(region), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateService registers a service and its dependencies.
+func (c *Client) CreateService(ctx context.Context, svc *Service) error {
+	return c.do(ctx, http.MethodPost, "/v1/services", nil, svc, nil)
+}
+
+// UpdateService replaces the dependencies of svc.Name, its owner unless svc.Owner is
+// empty and its regions unless svc.Regions is nil.
+func (c *Client) UpdateService(ctx context.Context, svc *Service) error {
+	return c.do(ctx, http.MethodPut, pathf("/v1/services/%s", svc.Name), nil, svc, nil)
+}
+
+// DeleteService deletes a service that nothing depends on.
+func (c *Client) DeleteService(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, pathf("/v1/services/%s", name), nil, nil, nil)
+}
+
+// ServiceTopology returns the services in dependency order.
+func (c *Client) ServiceTopology(ctx context.Context) (*ServiceTopology, error) {
+	var out ServiceTopology
+	if err := c.do(ctx, http.MethodGet, "/v1/services/topology", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ServiceImpact returns the services that directly or transitively depend on name,
+// with their health in region, or across regions when it is empty.
+func (c *Client) ServiceImpact(ctx context.Context, name, region string) (*ServiceImpact, error) {
+	var out ServiceImpact
+	if err := c.do(ctx, http.MethodGet, pathf("/v1/services/%s/impact", name), regionQuery(region), nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ActiveVersions returns the versions of service that have running instances; a
+// non-empty region counts only that region.
+func (c *Client) ActiveVersions(ctx context.Context, service, region string) ([]ActiveVersion, error) {
+	var out struct {
+		Items []ActiveVersion `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, pathf("/v1/services/%s/activeVersions", service), regionQuery(region), nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// AvailableVersions returns the registered versions of service; an empty status
+// returns all of them.
+func (c *Client) AvailableVersions(ctx context.Context, service string, status VersionStatus) ([]ServiceVersion, error) {
+	query := url.Values{}
+	if status != "" {
+		query.Set("type", string(status))
+	}
+	var out struct {
+		Items []ServiceVersion `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, pathf("/v1/services/%s/availableVersions", service), query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// CreateVersion registers a version of service.
+func (c *Client) CreateVersion(ctx context.Context, service string, req *CreateVersionRequest) (*ServiceVersion, error) {
+	var out ServiceVersion
+	if err := c.do(ctx, http.MethodPost, pathf("/v1/services/%s/versions", service), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeprecateVersion marks a version so it can no longer be deployed.
+func (c *Client) DeprecateVersion(ctx context.Context, service, version string) error {
+	return c.do(ctx, http.MethodPost, pathf("/v1/services/%s/versions/%s/deprecate", service, version), nil, nil, nil)
+}
+
+// ListInstances returns the instances of service; a non-empty version or region
+// filters them.
+func (c *Client) ListInstances(ctx context.Context, service, version, region string) ([]Instance, error) {
+	query := regionQuery(region)
+	if version != "" {
+		query.Set("version", version)
+	}
+	var out struct {
+		Items []Instance `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, pathf("/v1/services/%s/instances", service), query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// RegisterInstance registers or re-registers an instance of service.
+func (c *Client) RegisterInstance(ctx context.Context, service string, req *RegisterInstanceRequest) (*Instance, error) {
+	var out Instance
+	if err := c.do(ctx, http.MethodPost, pathf("/v1/services/%s/instances", service), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Heartbeat reports that an instance is alive; req may be nil to keep its version
+// and status.
+func (c *Client) Heartbeat(ctx context.Context, service, instanceID string, req *HeartbeatRequest) (*Instance, error) {
+	var in any
+	if req != nil {
+		in = req
+	}
+	var out Instance
+	if err := c.do(ctx, http.MethodPost, pathf("/v1/services/%s/instances/%s/heartbeat", service, instanceID), nil, in, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeregisterInstance removes an instance of service.
+func (c *Client) DeregisterInstance(ctx context.Context, service, instanceID string) error {
+	return c.do(ctx, http.MethodDelete, pathf("/v1/services/%s/instances/%s", service, instanceID), nil, nil, nil)
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListSLOs returns the SLOs of service, or of every service when it is "".
+func (c *Client) ListSLOs(ctx context.Context, service string) ([]SLO, error) {
+	query := url.Values{}
+	if service != "" {
+		query.Set("service", service)
+	}
+	var out struct {
+		Items []SLO `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/slos", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
+
+// PutSLO creates or replaces the SLO name of service.
+func (c *Client) PutSLO(ctx context.Context, service, name string, req *PutSLORequest) (*SLO, error) {
+	var out SLO
+	if err := c.do(ctx, http.MethodPut, pathf("/v1/slos/%s/%s", service, name), nil, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteSLO removes an SLO.
+func (c *Client) DeleteSLO(ctx context.Context, service, name string) error {
+	return c.do(ctx, http.MethodDelete, pathf("/v1/slos/%s/%s", service, name), nil, nil, nil)
+}
+
+// SLORules returns the Prometheus rule file generated from every SLO.
+func (c *Client) SLORules(ctx context.Context) (*SLORuleFile, error) {
+	var out SLORuleFile
+	if err := c.do(ctx, http.MethodGet, "/v1/slos/rules", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SLOBudgets returns the remaining error budget of the SLOs of service, or of every
+// service when it is "".
+func (c *Client) SLOBudgets(ctx context.Context, service string) ([]SLOBudget, error) {
+	query := url.Values{}
+	if service != "" {
+		query.Set("service", service)
+	}
+	var out struct {
+		Items []SLOBudget `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/slos/budget", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Items, nil
+}
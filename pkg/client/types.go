@@ -0,0 +1,133 @@
+package client
+
+import (
+	alertapi "github.com/qiniu/zeroops/internal/alerting/api"
+	"github.com/qiniu/zeroops/internal/alerting/service/analysis"
+	"github.com/qiniu/zeroops/internal/alerting/service/analytics"
+	"github.com/qiniu/zeroops/internal/alerting/service/anomaly"
+	"github.com/qiniu/zeroops/internal/alerting/service/credential"
+	"github.com/qiniu/zeroops/internal/alerting/service/report"
+	"github.com/qiniu/zeroops/internal/alerting/service/silence"
+	"github.com/qiniu/zeroops/internal/alerting/service/slo"
+	"github.com/qiniu/zeroops/internal/openapi"
+	"github.com/qiniu/zeroops/internal/service_manager/model"
+)
+
+// Services and versions.
+type (
+	Service              = model.Service
+	ServiceItem          = model.ServiceItem
+	ServicesResponse     = model.ServicesResponse
+	ServiceTopology      = model.ServiceTopology
+	ServiceImpact        = model.ServiceImpactResponse
+	ServiceImpactItem    = model.ServiceImpactItem
+	ActiveVersion        = model.ActiveVersionItem
+	InstanceHealth       = model.InstanceHealth
+	ServiceVersion       = model.ServiceVersion
+	CreateVersionRequest = model.CreateServiceVersionRequest
+	HealthState          = model.HealthState
+	VersionStatus        = model.VersionStatus
+)
+
+// Instances.
+type (
+	Instance                = model.ServiceInstance
+	InstanceStatus          = model.InstanceStatus
+	RegisterInstanceRequest = model.RegisterInstanceRequest
+	HeartbeatRequest        = model.InstanceHeartbeatRequest
+)
+
+// Deployments.
+type (
+	Deployment               = model.Deployment
+	DeployState              = model.DeployState
+	CreateDeploymentRequest  = model.CreateDeploymentRequest
+	UpdateDeploymentRequest  = model.UpdateDeploymentRequest
+	DeployEvent              = model.DeployEvent
+	DeployEventType          = model.DeployEventType
+	CanaryVerdict            = model.CanaryVerdict
+	CreateDeployEventRequest = model.CreateDeployEventRequest
+)
+
+// Deployment states.
+const (
+	StateUnrelease = model.StatusUnrelease
+	StateDeploying = model.StatusDeploying
+	StateStop      = model.StatusStop
+	StateRollback  = model.StatusRollback
+	StateCompleted = model.StatusCompleted
+	StateDeleted   = model.StatusDeleted
+)
+
+// Issues.
+type (
+	Issue         = alertapi.IssueDetail
+	IssueList     = alertapi.IssueList
+	IssueListItem = alertapi.IssueListItem
+	IssueComment  = alertapi.IssueComment
+	IssueAnalysis = analysis.Result
+	Label         = alertapi.Label
+)
+
+// Silences.
+type (
+	Silence              = silence.Silence
+	SilenceMatcher       = silence.Matcher
+	CreateSilenceRequest = silence.CreateRequest
+)
+
+// ParseMatcher parses "name=value", "name!=value", "name=~regex" or "name!~regex".
+func ParseMatcher(s string) (SilenceMatcher, error) {
+	return silence.ParseMatcher(s)
+}
+
+// Service level objectives.
+type (
+	SLO           = slo.SLO
+	PutSLORequest = slo.PutRequest
+	SLOBudget     = slo.Budget
+	SLORuleFile   = slo.RuleFile
+)
+
+// Anomaly detection.
+type (
+	AnomalyDetector       = anomaly.Detector
+	AnomalyParams         = anomaly.Params
+	AnomalyBacktest       = anomaly.BacktestRequest
+	AnomalyBacktestResult = anomaly.BacktestResult
+)
+
+// Postmortem reports.
+type (
+	Report          = report.Report
+	StoredReport    = report.Stored
+	ReportSummary   = report.Summary
+	ReportFigures   = report.Figures
+	ReportEntry     = report.Entry
+	CorrelatedIssue = report.CorrelatedIssue
+)
+
+// Issue analytics.
+type (
+	IssueStats      = analytics.IssueStats
+	IssueStatsGroup = analytics.Group
+	AnalyticsStats  = analytics.Stats
+	FlappingAlert   = analytics.FlappingAlert
+	NoisyRule       = analytics.NoisyRule
+)
+
+// Webhook credentials and the audit log.
+type (
+	Credential              = credential.Credential
+	CreateCredentialRequest = credential.CreateRequest
+	RotateCredentialRequest = credential.RotateRequest
+	AuditEntry              = alertapi.AuditEntry
+)
+
+// SignWebhook returns the X-Zeroops-Signature of a webhook body sent at the Unix time
+// ts with an hmac credential's secret.
+func SignWebhook(secret string, ts int64, body []byte) string {
+	return credential.Sign(secret, ts, body)
+}
+
+type messageResponse = openapi.MessageResponse